@@ -51,6 +51,11 @@ func writeArtifactBundle(state *RunState) (string, error) {
 	if err := writeJSON(filepath.Join(bundleDir, "verification.json"), state.Verification); err != nil {
 		return "", err
 	}
+	if len(state.Commits) > 0 {
+		if err := writeJSON(filepath.Join(bundleDir, "commits.json"), state.Commits); err != nil {
+			return "", err
+		}
+	}
 
 	summary := renderArtifactSummary(state)
 	if err := os.WriteFile(filepath.Join(bundleDir, "summary.md"), []byte(summary), 0644); err != nil {
@@ -131,6 +136,22 @@ func renderArtifactSummary(state *RunState) string {
 		b.WriteString("\n")
 	}
 
+	if len(state.Commits) > 0 {
+		b.WriteString("## Commits\n")
+		for _, c := range state.Commits {
+			hash := c.Hash
+			if len(hash) > 12 {
+				hash = hash[:12]
+			}
+			amend := ""
+			if c.Amend {
+				amend = " (amend)"
+			}
+			b.WriteString(fmt.Sprintf("- `%s` %s%s — turn %d, %d file(s) on %s\n", hash, c.Subject, amend, c.Turn, len(c.Files), c.Branch))
+		}
+		b.WriteString("\n")
+	}
+
 	if strings.TrimSpace(state.LastAssistantResponse) != "" {
 		b.WriteString("## Final Response\n\n")
 		b.WriteString(state.LastAssistantResponse)
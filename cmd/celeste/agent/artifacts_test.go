@@ -1,12 +1,15 @@
 package agent
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools/builtin"
 )
 
 func TestWriteArtifactBundle(t *testing.T) {
@@ -33,3 +36,31 @@ func TestWriteArtifactBundle(t *testing.T) {
 	assert.Contains(t, string(summaryData), "Agent Run Summary")
 	assert.Contains(t, string(summaryData), "TASK_COMPLETE")
 }
+
+func TestWriteArtifactBundle_Commits(t *testing.T) {
+	state := NewRunState("commit goal", DefaultOptions())
+	state.Options.ArtifactDir = t.TempDir()
+	state.Turn = 3
+	recordCommit(state, builtin.GitCommit{
+		Hash:    "0123456789abcdef0123456789abcdef01234567",
+		Branch:  "main",
+		Subject: "feat: add parser",
+		Files:   []string{"parser.go", "parser_test.go"},
+	})
+	require.Len(t, state.Commits, 1)
+	assert.Equal(t, 3, state.Commits[0].Turn)
+	assert.Equal(t, "commit", state.Steps[len(state.Steps)-1].Type)
+
+	bundlePath, err := writeArtifactBundle(state)
+	require.NoError(t, err)
+
+	var commits []CommitRecord
+	data, err := os.ReadFile(filepath.Join(bundlePath, "commits.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &commits))
+	assert.Equal(t, state.Commits[0].Hash, commits[0].Hash)
+
+	summaryData, err := os.ReadFile(filepath.Join(bundlePath, "summary.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(summaryData), "## Commits\n- `0123456789ab` feat: add parser — turn 3, 2 file(s) on main")
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
 )
 
 type BenchmarkCase struct {
@@ -18,6 +20,7 @@ type BenchmarkCase struct {
 	MustContain    []string `json:"must_contain,omitempty"`
 	MustNotContain []string `json:"must_not_contain,omitempty"`
 	VerifyCommands []string `json:"verify_commands,omitempty"`
+	Rubric         string   `json:"rubric,omitempty"`
 }
 
 type BenchmarkSuite struct {
@@ -46,6 +49,7 @@ type BenchmarkReport struct {
 	PassedCases int               `json:"passed_cases"`
 	FailedCases int               `json:"failed_cases"`
 	Results     []BenchmarkResult `json:"results"`
+	Grading     *GradingSummary   `json:"grading,omitempty"`
 }
 
 type benchmarkIteration struct {
@@ -101,6 +105,14 @@ func (r *Runner) RunBenchmark(ctx context.Context, suite BenchmarkSuite) (*Bench
 		Results:     make([]BenchmarkResult, 0, len(suite.Cases)),
 	}
 
+	// Iterations are aggregated once every case has run, so rubric grading
+	// can go out as one batch.
+	type pendingGrade struct{ caseIdx, iter int }
+	var grading []llm.BatchRequest
+	var pending []pendingGrade
+	caseNames := make([]string, 0, len(suite.Cases))
+	caseRuns := make([][]benchmarkIteration, 0, len(suite.Cases))
+
 	for _, c := range suite.Cases {
 		caseName := strings.TrimSpace(c.Name)
 		if caseName == "" {
@@ -153,7 +165,13 @@ func (r *Runner) RunBenchmark(ctx context.Context, suite BenchmarkSuite) (*Bench
 				MustContain:    c.MustContain,
 				MustNotContain: c.MustNotContain,
 			}
-			passed, reason := evaluateCase(evalCase, state.Status, strings.TrimSpace(state.LastAssistantResponse))
+			finalText := strings.TrimSpace(state.LastAssistantResponse)
+			passed, reason := evaluateCase(evalCase, state.Status, finalText)
+			if passed && strings.TrimSpace(c.Rubric) != "" {
+				id := fmt.Sprintf("case-%d-iter-%d", len(caseRuns)+1, len(runs)+1)
+				grading = append(grading, gradeRequest(id, c.Goal, c.Rubric, finalText))
+				pending = append(pending, pendingGrade{len(caseRuns), len(runs)})
+			}
 			runs = append(runs, benchmarkIteration{
 				Passed:    passed,
 				Reason:    reason,
@@ -164,7 +182,21 @@ func (r *Runner) RunBenchmark(ctx context.Context, suite BenchmarkSuite) (*Bench
 			})
 		}
 
-		result := aggregateBenchmarkCase(caseName, runs)
+		caseNames = append(caseNames, caseName)
+		caseRuns = append(caseRuns, runs)
+	}
+
+	if len(grading) > 0 {
+		grades, summary := r.gradeRubrics(ctx, grading)
+		for i, p := range pending {
+			run := &caseRuns[p.caseIdx][p.iter]
+			run.Passed, run.Reason = grades[i].Passed, grades[i].Reason
+		}
+		report.Grading = summary
+	}
+
+	for i, runs := range caseRuns {
+		result := aggregateBenchmarkCase(caseNames[i], runs)
 		report.Results = append(report.Results, result)
 		if result.FailedIterations == 0 {
 			report.PassedCases++
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
 )
 
 type EvalCase struct {
@@ -14,6 +16,9 @@ type EvalCase struct {
 	MaxTurns       int      `json:"max_turns,omitempty"`
 	MustContain    []string `json:"must_contain,omitempty"`
 	MustNotContain []string `json:"must_not_contain,omitempty"`
+	// Rubric, when set, is graded by the model against the final answer
+	// once the text checks pass.
+	Rubric string `json:"rubric,omitempty"`
 }
 
 type EvalSuite struct {
@@ -26,6 +31,7 @@ type EvalResult struct {
 	Status   string
 	Passed   bool
 	Reason   string
+	Grade    *RubricGrade // nil unless the case has a rubric that was graded
 }
 
 func LoadEvalCases(path string) ([]EvalCase, error) {
@@ -48,6 +54,10 @@ func LoadEvalCases(path string) ([]EvalCase, error) {
 
 func (r *Runner) RunEval(ctx context.Context, cases []EvalCase) ([]EvalResult, error) {
 	results := make([]EvalResult, 0, len(cases))
+	// Rubric grading waits until every case has run so it can go out as
+	// one batch.
+	var grading []llm.BatchRequest
+	var graded []int
 	for _, c := range cases {
 		if strings.TrimSpace(c.Goal) == "" {
 			results = append(results, EvalResult{
@@ -83,6 +93,10 @@ func (r *Runner) RunEval(ctx context.Context, cases []EvalCase) ([]EvalResult, e
 
 		finalText := strings.TrimSpace(state.LastAssistantResponse)
 		passed, reason := evaluateCase(c, state.Status, finalText)
+		if passed && strings.TrimSpace(c.Rubric) != "" {
+			grading = append(grading, gradeRequest(fmt.Sprintf("case-%d", len(results)+1), c.Goal, c.Rubric, finalText))
+			graded = append(graded, len(results))
+		}
 		results = append(results, EvalResult{
 			CaseName: safeCaseName(c),
 			RunID:    state.RunID,
@@ -92,6 +106,13 @@ func (r *Runner) RunEval(ctx context.Context, cases []EvalCase) ([]EvalResult, e
 		})
 	}
 
+	if len(grading) > 0 {
+		grades, _ := r.gradeRubrics(ctx, grading)
+		for i, idx := range graded {
+			g := grades[i]
+			results[idx].Passed, results[idx].Reason, results[idx].Grade = g.Passed, g.Reason, &g
+		}
+	}
 	return results, nil
 }
 
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sessions"
+)
+
+// TranscriptFromRunState converts a checkpointed run into the transcript
+// shape the session exporters render. Assistant tool calls become tool_call
+// entries, tool messages become tool_result entries and an assistant
+// message's reasoning becomes a thinking entry ahead of it, so a run reads
+// the same as an interactive session; the plan and verification checks are
+// appended as system entries.
+func TranscriptFromRunState(state *RunState) *sessions.Transcript {
+	t := &sessions.Transcript{
+		ID:        state.RunID,
+		Source:    "agent_run",
+		Title:     state.Goal,
+		CreatedAt: state.CreatedAt,
+		UpdatedAt: state.UpdatedAt,
+		Metadata: map[string]string{
+			"status":     state.Status,
+			"turns":      fmt.Sprintf("%d", state.Turn),
+			"tool_calls": fmt.Sprintf("%d", state.ToolCallCount),
+		},
+	}
+	if state.Options.Model != "" {
+		t.Metadata["model"] = state.Options.Model
+	}
+	if state.Options.Workspace != "" {
+		t.Metadata["workspace"] = state.Options.Workspace
+	}
+	if state.Error != "" {
+		t.Metadata["error"] = state.Error
+	}
+
+	toolNames := make(map[string]string)
+	for _, msg := range state.Messages {
+		switch msg.Role {
+		case "tool":
+			name := msg.Name
+			if name == "" {
+				name = toolNames[msg.ToolCallID]
+			}
+			t.Entries = append(t.Entries, sessions.LogEntry{
+				Type:        "tool_result",
+				Content:     msg.Content,
+				ToolName:    name,
+				ToolID:      msg.ToolCallID,
+				Timestamp:   msg.Timestamp,
+				Attachments: msg.Attachments,
+			})
+		default:
+			if strings.TrimSpace(msg.Thinking) != "" {
+				t.Entries = append(t.Entries, sessions.LogEntry{
+					Type:      "thinking",
+					Content:   msg.Thinking,
+					Timestamp: msg.Timestamp,
+				})
+			}
+			if strings.TrimSpace(msg.Content) != "" {
+				t.Entries = append(t.Entries, sessions.LogEntry{
+					Type:        msg.Role,
+					Content:     msg.Content,
+					Timestamp:   msg.Timestamp,
+					Attachments: msg.Attachments,
+				})
+			}
+			for _, tc := range msg.ToolCalls {
+				toolNames[tc.ID] = tc.Name
+				t.Entries = append(t.Entries, sessions.LogEntry{
+					Type:      "tool_call",
+					Content:   tc.Arguments,
+					ToolName:  tc.Name,
+					ToolID:    tc.ID,
+					Timestamp: msg.Timestamp,
+				})
+			}
+		}
+	}
+
+	if len(state.Plan) > 0 {
+		var sb strings.Builder
+		sb.WriteString("Plan:\n")
+		for _, step := range state.Plan {
+			fmt.Fprintf(&sb, "%d. [%s] %s\n", step.Index, step.Status, step.Title)
+		}
+		t.Entries = append(t.Entries, sessions.LogEntry{Type: "system", Content: strings.TrimSpace(sb.String())})
+	}
+	for _, check := range state.Verification {
+		result := "passed"
+		if !check.Passed {
+			result = fmt.Sprintf("failed (exit %d)", check.ExitCode)
+		}
+		t.Entries = append(t.Entries, sessions.LogEntry{
+			Type:      "system",
+			Content:   fmt.Sprintf("Verification `%s` %s\n\n%s", check.Command, result, check.Output),
+			Timestamp: check.Timestamp,
+		})
+	}
+	return t
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+func TestTranscriptFromRunState(t *testing.T) {
+	state := NewRunState("rename the flag", DefaultOptions())
+	state.Status = StatusCompleted
+	state.Messages = append(state.Messages,
+		tui.ChatMessage{Role: "user", Content: "rename the flag"},
+		tui.ChatMessage{Role: "assistant", ToolCalls: []tui.ToolCallInfo{
+			{ID: "call_1", Name: "read_file", Arguments: `{"path":"main.go"}`},
+		}},
+		tui.ChatMessage{Role: "tool", ToolCallID: "call_1", Content: "package main"},
+		tui.ChatMessage{Role: "assistant", Content: "TASK_COMPLETE: renamed"},
+	)
+	state.Verification = []VerificationCheck{{Command: "go test ./...", Passed: true}}
+
+	tr := TranscriptFromRunState(state)
+	require.Len(t, tr.Entries, 5)
+	assert.Equal(t, "agent_run", tr.Source)
+	assert.Equal(t, "rename the flag", tr.Title)
+	assert.Equal(t, StatusCompleted, tr.Metadata["status"])
+
+	assert.Equal(t, "tool_call", tr.Entries[1].Type)
+	assert.Equal(t, "read_file", tr.Entries[1].ToolName)
+	// Tool results are labelled with the name of the call they answer.
+	assert.Equal(t, "tool_result", tr.Entries[2].Type)
+	assert.Equal(t, "read_file", tr.Entries[2].ToolName)
+	assert.Equal(t, "system", tr.Entries[4].Type)
+	assert.Contains(t, tr.Entries[4].Content, "go test ./...")
+}
+
+func TestTranscriptFromRunStateKeepsThinking(t *testing.T) {
+	state := NewRunState("why is CI red", DefaultOptions())
+	state.Messages = append(state.Messages,
+		tui.ChatMessage{Role: "user", Content: "why is CI red"},
+		tui.ChatMessage{Role: "assistant", Thinking: "The log shows a vet failure.", Content: "go vet fails in ./llm"},
+	)
+
+	tr := TranscriptFromRunState(state)
+	require.Len(t, tr.Entries, 3)
+	assert.Equal(t, "thinking", tr.Entries[1].Type)
+	assert.Equal(t, "The log shows a vet failure.", tr.Entries[1].Content)
+	assert.Equal(t, "assistant", tr.Entries[2].Type)
+}
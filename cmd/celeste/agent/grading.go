@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/costs"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// gradePollInterval is how often a provider batch of grading requests is
+// checked. Batches take minutes to hours; there is no point asking faster.
+const gradePollInterval = 30 * time.Second
+
+const graderSystemPrompt = `You grade the final answer of an autonomous coding agent against a rubric.
+Judge only what the answer shows. Work the answer claims without showing evidence does not count.
+Give the reason in one sentence.`
+
+// gradeFormat is the verdict a grader returns.
+var gradeFormat = &llm.ResponseFormat{
+	Name: "grade",
+	Schema: map[string]any{
+		"type":                 "object",
+		"required":             []string{"pass", "reason"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"pass":   map[string]any{"type": "boolean"},
+			"reason": map[string]any{"type": "string"},
+		},
+	},
+}
+
+// RubricGrade is the model's verdict on a final answer against a case's
+// rubric.
+type RubricGrade struct {
+	Passed  bool
+	Reason  string
+	Usage   *llm.TokenUsage
+	CostUSD float64
+	Batched bool // billed at batch pricing
+}
+
+// GradingSummary totals the rubric grading of an eval or benchmark run.
+type GradingSummary struct {
+	Via          string  `json:"via"`
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	Batched      bool    `json:"batched"`
+}
+
+// gradeRequest asks whether final satisfies rubric for goal.
+func gradeRequest(id, goal, rubric, final string) llm.BatchRequest {
+	prompt := fmt.Sprintf("## Task given to the agent\n%s\n\n## Rubric\n%s\n\n## Agent's final answer\n%s",
+		strings.TrimSpace(goal), strings.TrimSpace(rubric), strings.TrimSpace(final))
+	return llm.BatchRequest{
+		ID:        id,
+		System:    graderSystemPrompt,
+		Messages:  []tui.ChatMessage{{Role: "user", Content: prompt}},
+		Format:    gradeFormat,
+		MaxTokens: 1024,
+	}
+}
+
+// gradeRubrics grades reqs in one go, through the provider's batch API when
+// the runner was created with BatchGrading. A request that could not be
+// graded fails its case with the reason.
+func (r *Runner) gradeRubrics(ctx context.Context, reqs []llm.BatchRequest) ([]RubricGrade, *GradingSummary) {
+	grades := make([]RubricGrade, len(reqs))
+	if r.grader == nil {
+		for i := range grades {
+			grades[i].Reason = "rubric grading failed: runner has no grader"
+		}
+		return grades, &GradingSummary{Requests: len(reqs)}
+	}
+	summary := &GradingSummary{Via: r.grader.Name(), Requests: len(reqs), Batched: r.grader.Discounted()}
+	poll := r.gradePoll
+	if poll <= 0 {
+		poll = gradePollInterval
+	}
+
+	fmt.Fprintf(r.errOut, "[eval] grading %d answer(s) via %s\n", len(reqs), summary.Via)
+	lastState := ""
+	results, err := llm.RunBatch(ctx, r.grader, reqs, poll, func(s llm.BatchStatus) {
+		if s.State != lastState {
+			fmt.Fprintf(r.errOut, "[eval] grading batch %s: %s (%d/%d done)\n", s.ID, s.State, s.Succeeded+s.Failed, s.Total)
+			lastState = s.State
+		}
+	})
+	if err != nil {
+		for i := range grades {
+			grades[i].Reason = "rubric grading failed: " + err.Error()
+		}
+		return grades, summary
+	}
+
+	for i, res := range results {
+		g := RubricGrade{Usage: res.Usage, Batched: summary.Batched}
+		if res.Usage != nil {
+			summary.InputTokens += res.Usage.PromptTokens
+			summary.OutputTokens += res.Usage.CompletionTokens
+			if g.Batched {
+				g.CostUSD = costs.GetBatchCost(r.model, res.Usage.PromptTokens, res.Usage.CompletionTokens)
+			} else {
+				g.CostUSD = costs.GetCost(r.model, res.Usage.PromptTokens, res.Usage.CompletionTokens)
+			}
+			summary.CostUSD += g.CostUSD
+		}
+		if res.Err != nil {
+			g.Reason = "rubric grading failed: " + res.Err.Error()
+			grades[i] = g
+			continue
+		}
+		var verdict struct {
+			Pass   bool   `json:"pass"`
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal([]byte(res.Content), &verdict); err != nil {
+			g.Reason = "rubric grading failed: " + err.Error()
+		} else {
+			g.Passed, g.Reason = verdict.Pass, "rubric: "+strings.TrimSpace(verdict.Reason)
+		}
+		grades[i] = g
+	}
+	return grades, summary
+}
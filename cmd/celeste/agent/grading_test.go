@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm/batchtest"
+)
+
+func TestGradeRubricsThroughProviderBatch(t *testing.T) {
+	stand := batchtest.NewServer(func(r batchtest.Request) (string, error) {
+		if strings.Contains(r.Prompt, "tests pass") {
+			return `{"pass": true, "reason": "Shows the passing test output."}`, nil
+		}
+		return `{"pass": false, "reason": "No evidence the bug was fixed."}`, nil
+	})
+	defer stand.Close()
+	stand.PollsUntilDone = 1
+
+	grader, err := llm.NewBatcher(&llm.Config{APIKey: "k", BaseURL: stand.BaseURL(), Model: "claude-sonnet-4-6", BatchAPI: "anthropic"})
+	require.NoError(t, err)
+	r := &Runner{errOut: io.Discard, model: "claude-sonnet-4-6", grader: grader, gradePoll: time.Millisecond}
+
+	grades, summary := r.gradeRubrics(context.Background(), []llm.BatchRequest{
+		gradeRequest("case-1", "fix the bug", "must show test output", "Fixed. tests pass: ok 3/3"),
+		gradeRequest("case-2", "fix the bug", "must show test output", "I fixed it."),
+	})
+	require.Len(t, grades, 2)
+	assert.True(t, grades[0].Passed)
+	assert.Equal(t, "rubric: Shows the passing test output.", grades[0].Reason)
+	assert.False(t, grades[1].Passed)
+	assert.True(t, grades[1].Batched)
+
+	assert.Equal(t, "anthropic batch", summary.Via)
+	assert.Equal(t, 2, summary.Requests)
+	assert.Positive(t, summary.InputTokens)
+	assert.InDelta(t, grades[0].CostUSD+grades[1].CostUSD, summary.CostUSD, 1e-12)
+
+	sent := stand.Requests()
+	require.Len(t, sent, 2)
+	assert.Contains(t, sent[0].System, "grade the final answer")
+	assert.Contains(t, sent[0].Prompt, "## Rubric\nmust show test output")
+}
+
+func TestGradeRubricsFailsCasesWhenGradingFails(t *testing.T) {
+	stand := batchtest.NewServer(func(batchtest.Request) (string, error) { return "looks good to me", nil })
+	defer stand.Close()
+
+	grader, err := llm.NewBatcher(&llm.Config{APIKey: "k", BaseURL: stand.BaseURL(), Model: "gpt-4.1", BatchAPI: "openai"})
+	require.NoError(t, err)
+	r := &Runner{errOut: io.Discard, model: "gpt-4.1", grader: grader, gradePoll: time.Millisecond}
+
+	grades, _ := r.gradeRubrics(context.Background(), []llm.BatchRequest{gradeRequest("case-1", "g", "r", "done")})
+	require.Len(t, grades, 1)
+	assert.False(t, grades[0].Passed)
+	assert.Contains(t, grades[0].Reason, "rubric grading failed")
+}
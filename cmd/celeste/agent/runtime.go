@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,10 +21,15 @@ import (
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
 	ctxmgr "github.com/whykusanagi/celeste-cli/cmd/celeste/context"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/hooks"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/prompts"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/shell"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/testrun"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools/builtin"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
@@ -39,7 +45,16 @@ type Runner struct {
 	out      io.Writer
 	errOut   io.Writer
 	budget   *ctxmgr.TokenBudget
-	indexer  *codegraph.Indexer // code graph indexer, may be nil
+	indexer  *codegraph.Indexer      // code graph indexer, may be nil
+	shadow   *checkpoints.ShadowRepo // whole-workspace checkpoints, nil unless ShadowCheckpoints
+	skipped  []string                // nested repos the last shadow checkpoint left out
+	shells   *shell.Manager          // persistent shells and background jobs the run started
+	lsp      *lsp.Manager            // language servers for edit diagnostics
+	cache    *tools.ResultCache      // read-only result dedupe, nil with DisableToolCache
+
+	model     string        // resolved model, for pricing grading calls
+	grader    llm.Batcher   // runs eval/benchmark rubric grading
+	gradePoll time.Duration // 0 = gradePollInterval
 }
 
 // emitProgress calls r.options.OnProgress if it is set.
@@ -49,8 +64,14 @@ func (r *Runner) emitProgress(kind ProgressKind, text string, turn, maxTurns int
 	}
 }
 
-// Close releases resources held by the runner (e.g. code graph DB).
+// Close releases resources held by the runner (e.g. code graph DB), kills
+// any shells and background jobs the run left behind, listing the jobs on
+// errOut, and stops its language servers.
 func (r *Runner) Close() {
+	if r.shells != nil {
+		shell.ReportStopped(r.errOut, r.shells.Close())
+	}
+	r.lsp.Close()
 	if r.indexer != nil {
 		r.indexer.Close()
 	}
@@ -172,6 +193,24 @@ func NewRunner(cfg *config.Config, options Options, out io.Writer, errOut io.Wri
 	// Agent registry: register dev tools only (no configLoader = no skill tools).
 	registry := tools.NewRegistry()
 	builtin.RegisterAll(registry, options.Workspace, nil, fileTracker, snapshotMgr)
+	shells := shell.NewManager(options.Workspace)
+	builtin.RegisterShellTools(registry, options.Workspace, shells)
+	if gen, err := cfg.ImageGenerator(); err != nil {
+		fmt.Fprintf(errOut, "Warning: image generation disabled: %v\n", err)
+	} else if gen != nil {
+		if store, err := media.SessionStore(sessionID); err == nil {
+			builtin.RegisterImageTools(registry, gen, store, options.Workspace)
+		}
+	}
+
+	var shadow *checkpoints.ShadowRepo
+	if options.ShadowCheckpoints || cfg.CheckpointMode == config.CheckpointModeShadow {
+		if repo, err := checkpoints.NewShadowRepo(options.Workspace); err != nil {
+			fmt.Fprintf(errOut, "Warning: shadow checkpoints disabled: %v\n", err)
+		} else {
+			shadow = repo
+		}
+	}
 
 	// Initialize code graph for the workspace
 	var cgIndexer *codegraph.Indexer
@@ -185,6 +224,11 @@ func NewRunner(cfg *config.Config, options Options, out io.Writer, errOut io.Wri
 		cgIndexer = idx
 	}
 
+	// Project tools from .celeste/tools; they never replace a built-in.
+	if err := registry.LoadProjectTools(options.Workspace); err != nil {
+		fmt.Fprintf(errOut, "Warning: project tools: %v\n", err)
+	}
+
 	// Load permissions and set checker
 	agentHomeDir, _ := os.UserHomeDir()
 	permConfigPath := filepath.Join(agentHomeDir, ".celeste", "permissions.json")
@@ -200,9 +244,48 @@ func NewRunner(cfg *config.Config, options Options, out io.Writer, errOut io.Wri
 	if options.AutoApproveTools {
 		permConfig.Mode = permissions.ModeTrust
 	}
+	if options.Sandbox != "" {
+		permConfig.Sandbox.Mode = options.Sandbox
+	}
 	checker := permissions.NewChecker(*permConfig)
 	registry.SetPermissionChecker(checker)
 
+	// Language servers from the grimoire give edit tools diagnostics and
+	// add the lsp_* tools.
+	projectGrimoire, grimoireErr := grimoire.LoadAll(options.Workspace)
+	lspConfigs, lspErr := lsp.ConfigsFromGrimoire(projectGrimoire)
+	if lspErr != nil {
+		fmt.Fprintf(errOut, "Warning: .grimoire language servers: %v\n", lspErr)
+	}
+	languageServers := lsp.NewManager(options.Workspace, lspConfigs, permConfig.Sandbox)
+	builtin.RegisterLSPTools(registry, options.Workspace, languageServers)
+
+	// git_commit enforces the grimoire's commit convention and runs its
+	// PreCommit hooks, sandboxed like any other hook.
+	convention, conventionErr := builtin.CommitConventionFromGrimoire(projectGrimoire)
+	if conventionErr != nil {
+		fmt.Fprintf(errOut, "Warning: .grimoire commit convention: %v\n", conventionErr)
+	}
+	var preCommit *hooks.Executor
+	if parsedHooks := hooks.ParseFromGrimoire(projectGrimoire); len(parsedHooks) > 0 {
+		preCommit = hooks.NewExecutor(parsedHooks, options.Workspace)
+		preCommit.SetSandbox(permConfig.Sandbox)
+	}
+	builtin.ConfigureGitCommit(registry, convention, preCommit)
+	if backend, cache, err := cfg.ResearchBackend(); err != nil {
+		fmt.Fprintf(errOut, "Warning: web search: %v\n", err)
+	} else {
+		builtin.ConfigureResearch(registry, backend, cache)
+	}
+
+	// Agent runs never compact history, so a repeated read-only result can
+	// point back at the turn that carried it instead of being sent again.
+	var cache *tools.ResultCache
+	if !options.DisableToolCache {
+		cache = tools.NewResultCache(options.Workspace)
+		registry.SetResultCache(cache)
+	}
+
 	// Fail fast rather than no-opping. `celeste agent` never wires an
 	// interactive prompt, so every tool that resolves to Ask is denied — and the
 	// run still reports success with exit 0 after burning the whole turn budget.
@@ -240,9 +323,26 @@ func NewRunner(cfg *config.Config, options Options, out io.Writer, errOut io.Wri
 		GoogleUseADC:          cfg.GoogleUseADC,
 		Collections:           cfg.Collections,
 		XAIFeatures:           cfg.XAIFeatures,
+		Fallbacks:             llm.FallbacksFromConfig(cfg),
+		KeepAlive:             cfg.OllamaKeepAlive,
+		ContextLimit:          cfg.ContextLimit,
+		BuiltinTools:          cfg.OpenAIBuiltinTools,
+		NoPromptCache:         cfg.DisablePromptCache,
+		BatchAPI:              cfg.BatchAPI,
 	}
 	client := llm.NewClient(llmConfig, registry)
 
+	// Rubric grading is single-shot, so it can go through the provider's
+	// batch API at half price when the caller can wait for it.
+	grader := llm.NewDirectBatcher(llmConfig)
+	if options.BatchGrading {
+		if b, err := llm.NewBatcher(llmConfig); err != nil {
+			fmt.Fprintf(errOut, "⚠️  batch grading unavailable (%v); grading with ordinary requests at list price\n", err)
+		} else {
+			grader = b
+		}
+	}
+
 	// Build system prompt. The agent operational rules always come last so they
 	// take precedence over character voice. The persona (if enabled) sets tone
 	// only — tool-use rules in the agent prompt override any conflicting phrasing.
@@ -256,7 +356,7 @@ func NewRunner(cfg *config.Config, options Options, out io.Writer, errOut io.Wri
 	}
 
 	// Inject grimoire and git context into agent system prompt
-	if projectGrimoire, err := grimoire.LoadAll(options.Workspace); err == nil && projectGrimoire != nil && !projectGrimoire.IsEmpty() {
+	if grimoireErr == nil && projectGrimoire != nil && !projectGrimoire.IsEmpty() {
 		systemPrompt += "\n\n# Project Context (.grimoire)\n\n" + projectGrimoire.Render()
 	}
 	if gitSnap := grimoire.CaptureGitSnapshot(options.Workspace); gitSnap != nil {
@@ -283,9 +383,42 @@ func NewRunner(cfg *config.Config, options Options, out io.Writer, errOut io.Wri
 		errOut:   errOut,
 		budget:   budget,
 		indexer:  cgIndexer,
+		shadow:   shadow,
+		shells:   shells,
+		lsp:      languageServers,
+		cache:    cache,
+		model:    model,
+		grader:   grader,
 	}, nil
 }
 
+// servedBy reports which provider answered the turn's request, announcing a
+// failover so the run log explains a change of model mid-run.
+func (r *Runner) servedBy(turn int) llm.ServedBy {
+	served := r.client.LastServed()
+	if served.Fallback {
+		fmt.Fprintf(r.errOut, "[agent] turn %d served by fallback %s (%s)\n", turn, served.Provider, served.Model)
+	}
+	return served
+}
+
+// shadowCheckpoint snapshots the workspace at a turn boundary. Failures are
+// reported but never stop the run: checkpoints are a safety net, not a gate.
+func (r *Runner) shadowCheckpoint(state *RunState, label string) {
+	if r.shadow == nil {
+		return
+	}
+	cp, err := r.shadow.Checkpoint(state.RunID, state.Turn, label)
+	if err != nil {
+		fmt.Fprintf(r.errOut, "Warning: shadow checkpoint failed: %v\n", err)
+		return
+	}
+	if len(cp.Skipped) > 0 && !slices.Equal(cp.Skipped, r.skipped) {
+		fmt.Fprintf(r.errOut, "Warning: shadow checkpoints leave out nested git repositories: %s\n", strings.Join(cp.Skipped, ", "))
+	}
+	r.skipped = cp.Skipped
+}
+
 func (r *Runner) ListRuns(limit int) ([]RunSummary, error) {
 	return r.store.List(limit)
 }
@@ -332,6 +465,7 @@ func (r *Runner) runState(ctx context.Context, state *RunState) (*RunState, erro
 	}
 	normalizeStateOptions(state, r.options)
 	defer r.persistArtifacts(state)
+	defer r.shadowCheckpoint(state, "end of agent run")
 
 	if state.Phase == "" {
 		if state.Options.EnablePlanning {
@@ -376,8 +510,12 @@ func (r *Runner) runState(ctx context.Context, state *RunState) (*RunState, erro
 		}
 
 		state.Turn++
+		if r.cache != nil {
+			r.cache.SetTurn(state.Turn)
+		}
 		state.Status = StatusRunning
 		state.Phase = PhaseExecution
+		r.shadowCheckpoint(state, fmt.Sprintf("before agent turn %d", state.Turn))
 
 		if state.Options.Verbose {
 			fmt.Fprintf(r.out, "\n[agent] turn %d/%d\n", state.Turn, state.Options.MaxTurns)
@@ -397,8 +535,13 @@ func (r *Runner) runState(ctx context.Context, state *RunState) (*RunState, erro
 			switch event.Type {
 			case llm.EventContentDelta:
 				result.Content += event.ContentDelta
+			case llm.EventThinkingDelta:
+				result.Thinking += event.ContentDelta
 			case llm.EventToolUseStart, llm.EventToolUseInputDelta, llm.EventToolUseDone:
 				acc.HandleEvent(event)
+			case llm.EventServerToolUse:
+				// Already ran on the provider's side; nothing to execute.
+				tui.LogInfo(fmt.Sprintf("provider ran built-in tool %s: %s", event.ToolName, event.CompleteInput))
 			case llm.EventMessageDone:
 				result.Usage = event.Usage
 			}
@@ -435,11 +578,15 @@ func (r *Runner) runState(ctx context.Context, state *RunState) (*RunState, erro
 			}
 		}
 
+		served := r.servedBy(state.Turn)
+
 		if r.options.OnTurnStats != nil {
-			stats := TurnStats{Turn: state.Turn, MaxTurns: state.Options.MaxTurns, Elapsed: time.Since(turnStart)}
+			stats := TurnStats{Turn: state.Turn, MaxTurns: state.Options.MaxTurns, Elapsed: time.Since(turnStart),
+				Provider: served.Provider, Model: served.Model}
 			if result.Usage != nil {
 				stats.InputTokens = result.Usage.PromptTokens
 				stats.OutputTokens = result.Usage.CompletionTokens
+				stats.CachedTokens = result.Usage.CachedTokens
 			}
 			stats.Response = strings.TrimSpace(result.Content)
 			if len(result.ToolCalls) > 0 {
@@ -454,6 +601,7 @@ func (r *Runner) runState(ctx context.Context, state *RunState) (*RunState, erro
 		assistantMsg := tui.ChatMessage{
 			Role:      "assistant",
 			Content:   result.Content,
+			Thinking:  result.Thinking,
 			ToolCalls: convertToolCalls(result.ToolCalls),
 			Timestamp: time.Now(),
 		}
@@ -463,6 +611,8 @@ func (r *Runner) runState(ctx context.Context, state *RunState) (*RunState, erro
 			Turn:      state.Turn,
 			Type:      "assistant",
 			Content:   state.LastAssistantResponse,
+			Provider:  served.Provider,
+			Model:     served.Model,
 			Timestamp: time.Now(),
 		})
 
@@ -596,26 +746,41 @@ func (r *Runner) runPlanningPhase(ctx context.Context, state *RunState) error {
 	requestCtx, cancel := context.WithTimeout(ctx, state.Options.RequestTimeout)
 	planTurnStart := time.Now()
 
+	// The plan is requested as structured output, so no tools are offered:
+	// planning is the first turn and the history holds no tool calls yet.
 	var result llm.ChatCompletionResult
-	streamErr := r.client.SendMessageStreamEvents(requestCtx, state.Messages, r.client.GetSkills(), func(event llm.StreamEvent) {
+	streamErr := r.client.SendMessageStreamEvents(requestCtx, state.Messages, nil, func(event llm.StreamEvent) {
 		switch event.Type {
 		case llm.EventContentDelta:
 			result.Content += event.ContentDelta
+		case llm.EventThinkingDelta:
+			result.Thinking += event.ContentDelta
 		case llm.EventMessageDone:
 			result.Usage = event.Usage
 		}
-	})
+	}, llm.WithResponseFormat(planResponseFormat(state.Options.PlanMaxSteps)))
 	planTimedOut := errors.Is(requestCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
 	cancel()
+	var structuredErr *llm.StructuredOutputError
+	structured := streamErr == nil
+	if errors.As(streamErr, &structuredErr) {
+		// The model answered, just not in the requested shape; read the
+		// plan from its text the way unstructured replies always were.
+		tui.LogInfo(fmt.Sprintf("plan was not structured, parsing text: %v", structuredErr.Err))
+		streamErr = nil
+	}
 	if streamErr != nil {
 		return annotateTurnTimeout(streamErr, planTimedOut, state.Options.RequestTimeout)
 	}
 
+	served := r.servedBy(state.Turn)
 	if r.options.OnTurnStats != nil {
-		stats := TurnStats{Turn: state.Turn, MaxTurns: state.Options.MaxTurns, Elapsed: time.Since(planTurnStart)}
+		stats := TurnStats{Turn: state.Turn, MaxTurns: state.Options.MaxTurns, Elapsed: time.Since(planTurnStart),
+			Provider: served.Provider, Model: served.Model}
 		if result.Usage != nil {
 			stats.InputTokens = result.Usage.PromptTokens
 			stats.OutputTokens = result.Usage.CompletionTokens
+			stats.CachedTokens = result.Usage.CachedTokens
 		}
 		r.options.OnTurnStats(stats)
 	}
@@ -624,16 +789,23 @@ func (r *Runner) runPlanningPhase(ctx context.Context, state *RunState) error {
 	state.Messages = append(state.Messages, tui.ChatMessage{
 		Role:      "assistant",
 		Content:   planResponse,
+		Thinking:  result.Thinking,
 		Timestamp: time.Now(),
 	})
 	state.Steps = append(state.Steps, Step{
 		Turn:      state.Turn,
 		Type:      "plan",
 		Content:   truncateForStep(planResponse),
+		Provider:  served.Provider,
+		Model:     served.Model,
 		Timestamp: time.Now(),
 	})
 
-	state.Plan = parsePlanSteps(planResponse, state.Options.PlanMaxSteps)
+	if structured {
+		state.Plan, _ = decodePlanSteps(planResponse, state.Options.PlanMaxSteps)
+	} else {
+		state.Plan = parsePlanSteps(planResponse, state.Options.PlanMaxSteps)
+	}
 	if len(state.Plan) == 0 {
 		state.Plan = []PlanStep{{Index: 1, Title: "Complete the requested goal", Status: PlanStatusPending}}
 	}
@@ -725,6 +897,7 @@ func (r *Runner) executeToolCall(ctx context.Context, state *RunState, tc llm.To
 	argsJSON := tc.Arguments
 	resultContent := ""
 	argsValid := true
+	var attachments []media.Asset
 
 	if tc.ArgsError != "" {
 		argsValid = false
@@ -737,6 +910,12 @@ func (r *Runner) executeToolCall(ctx context.Context, state *RunState, tc llm.To
 			return r.client.ExecuteSkill(toolCtx, toolName, argsJSON)
 		})
 		resultContent = formatToolResult(toolName, execution, err)
+		if err == nil && execution != nil {
+			attachments = media.AssetsFrom(execution.Metadata)
+			if commit, ok := builtin.CommitFrom(execution.Metadata); ok {
+				recordCommit(state, commit)
+			}
+		}
 	}
 
 	state.Steps = append(state.Steps, Step{
@@ -753,18 +932,20 @@ func (r *Runner) executeToolCall(ctx context.Context, state *RunState, tc llm.To
 	// "user" role with a labelled result instead of the "tool" role.
 	if strings.HasPrefix(tc.ID, "text-tc-") {
 		return tui.ChatMessage{
-			Role:      "user",
-			Content:   fmt.Sprintf("[Tool Result: %s]\n%s", toolName, resultContent),
-			Timestamp: time.Now(),
+			Role:        "user",
+			Content:     fmt.Sprintf("[Tool Result: %s]\n%s", toolName, resultContent),
+			Timestamp:   time.Now(),
+			Attachments: attachments,
 		}, argsValid
 	}
 
 	return tui.ChatMessage{
-		Role:       "tool",
-		ToolCallID: tc.ID,
-		Name:       toolName,
-		Content:    resultContent,
-		Timestamp:  time.Now(),
+		Role:        "tool",
+		ToolCallID:  tc.ID,
+		Name:        toolName,
+		Content:     resultContent,
+		Timestamp:   time.Now(),
+		Attachments: attachments,
 	}, argsValid
 }
 
@@ -801,6 +982,29 @@ func executeVerificationCommand(parent context.Context, workspace, command strin
 		timeout = DefaultOptions().VerifyTimeout
 	}
 
+	// Test commands the testrun adapters understand run with a structured
+	// report, so a failure isn't lost to truncated output. If the runner
+	// can't be started that way, fall back to the shell.
+	if spec, ok := testrun.FromCommand(command); ok {
+		spec.Dir = workspace
+		spec.Timeout = timeout
+		if res, err := testrun.Run(parent, spec); err == nil {
+			output := res.Summary()
+			if len(output) > maxCommandOutput {
+				output = output[:maxCommandOutput]
+			}
+			return VerificationCheck{
+				Command:   command,
+				Passed:    res.OK(),
+				ExitCode:  res.ExitCode,
+				Output:    output,
+				TimedOut:  res.TimedOut,
+				Tests:     res,
+				Timestamp: time.Now(),
+			}
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
@@ -905,7 +1109,7 @@ func isCompletionResponse(content string, options Options) bool {
 }
 
 func buildPlanningPrompt(state *RunState) string {
-	return fmt.Sprintf("Create a concise execution plan for this goal with 3-7 numbered steps. Include technical validation steps. Goal: %s", state.Goal)
+	return fmt.Sprintf("Create a concise execution plan for this goal with 3-7 steps. Include technical validation steps. Goal: %s", state.Goal)
 }
 
 func buildExecutionKickoffPrompt(state *RunState) string {
@@ -935,6 +1139,12 @@ func buildVerificationFailurePrompt(checks []VerificationCheck, options Options)
 		if c.Passed {
 			continue
 		}
+		if c.Tests != nil {
+			// The summary already holds just the failures; don't cut it to a
+			// step preview.
+			failed = append(failed, fmt.Sprintf("- `%s`\n%s", c.Command, c.Output))
+			continue
+		}
 		failed = append(failed, fmt.Sprintf("- `%s` (exit=%d, timed_out=%v)\n%s", c.Command, c.ExitCode, c.TimedOut, truncateForStep(c.Output)))
 	}
 	return fmt.Sprintf("Verification failed. Fix the issues and continue execution. Re-run validations before completion.\n\nFailed checks:\n%s\n\nWhen complete, respond with '%s'.", strings.Join(failed, "\n"), options.CompletionMarker)
@@ -1137,6 +1347,11 @@ func stripStepPrefix(line string) string {
 	return strings.TrimSpace(line)
 }
 
+// extractStepDoneMarker returns n from a "STEP_DONE: n" line in an execution
+// turn's reply, or -1. Unlike the plan, this signal stays in the text: it
+// rides along with tool calls and prose in turns that must keep their tools,
+// and a response format on those turns would make every reply JSON and
+// route tool use through the structured repair loop, which rejects it.
 func extractStepDoneMarker(content string) int {
 	upper := strings.ToUpper(content)
 	idx := strings.Index(upper, "STEP_DONE:")
@@ -1241,6 +1456,7 @@ func convertToolCalls(calls []llm.ToolCallResult) []tui.ToolCallInfo {
 			Name:             c.Name,
 			Arguments:        c.Arguments,
 			ThoughtSignature: c.ThoughtSignature,
+			ReasoningState:   c.ReasoningState,
 		})
 	}
 	return result
@@ -1289,6 +1505,28 @@ func formatToolResult(toolName string, execution *llm.ExecutionResult, err error
 	}
 }
 
+// recordCommit notes a git_commit in the run state so the artifact bundle
+// traces every commit the agent made back to its turn.
+func recordCommit(state *RunState, commit builtin.GitCommit) {
+	now := time.Now()
+	state.Commits = append(state.Commits, CommitRecord{
+		Hash:      commit.Hash,
+		Branch:    commit.Branch,
+		Subject:   commit.Subject,
+		Files:     commit.Files,
+		Amend:     commit.Amend,
+		Turn:      state.Turn,
+		Timestamp: now,
+	})
+	state.Steps = append(state.Steps, Step{
+		Turn:      state.Turn,
+		Type:      "commit",
+		Name:      commit.Hash,
+		Content:   truncateForStep(commit.Subject),
+		Timestamp: now,
+	})
+}
+
 func truncateForStep(s string) string {
 	s = strings.TrimSpace(s)
 	if len(s) > 200 {
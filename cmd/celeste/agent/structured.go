@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// planResponseFormat is the structured shape the planning turn asks for.
+// Providers without a native schema mode still get it through the client's
+// validate-and-repair loop; a reply that never conforms falls back to
+// parsePlanSteps on the raw text.
+func planResponseFormat(maxSteps int) *llm.ResponseFormat {
+	if maxSteps <= 0 {
+		maxSteps = DefaultOptions().PlanMaxSteps
+	}
+	return &llm.ResponseFormat{
+		Name: "execution_plan",
+		Schema: map[string]any{
+			"type":                 "object",
+			"required":             []string{"steps"},
+			"additionalProperties": false,
+			"properties": map[string]any{
+				"steps": map[string]any{
+					"type":     "array",
+					"minItems": 1,
+					"maxItems": maxSteps,
+					"items": map[string]any{
+						"type":                 "object",
+						"required":             []string{"title"},
+						"additionalProperties": false,
+						"properties": map[string]any{
+							"title": map[string]any{"type": "string", "minLength": 1},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// decodePlanSteps reads a reply that validated against planResponseFormat.
+func decodePlanSteps(content string, maxSteps int) ([]PlanStep, error) {
+	var plan struct {
+		Steps []struct {
+			Title string `json:"title"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(content), &plan); err != nil {
+		return nil, err
+	}
+	if maxSteps <= 0 {
+		maxSteps = DefaultOptions().PlanMaxSteps
+	}
+	steps := make([]PlanStep, 0, len(plan.Steps))
+	for _, s := range plan.Steps {
+		title := strings.TrimSpace(s.Title)
+		if title == "" {
+			continue
+		}
+		steps = append(steps, PlanStep{Index: len(steps) + 1, Title: title, Status: PlanStatusPending})
+		if len(steps) >= maxSteps {
+			break
+		}
+	}
+	return steps, nil
+}
+
+// AskStructured puts one more question to the model on top of a run's
+// transcript and returns the answer as JSON conforming to format. It is how
+// callers get a machine-readable verdict out of a free-form agent run. The
+// run state is not modified. On a *llm.StructuredOutputError the model's
+// last reply is returned alongside the error.
+func (r *Runner) AskStructured(ctx context.Context, state *RunState, prompt string, format *llm.ResponseFormat) (string, error) {
+	msgs := append(state.Messages[:len(state.Messages):len(state.Messages)], tui.ChatMessage{
+		Role:      "user",
+		Content:   prompt,
+		Timestamp: time.Now(),
+	})
+	requestCtx, cancel := context.WithTimeout(ctx, state.Options.RequestTimeout)
+	defer cancel()
+	// The tools stay defined because some providers reject a history that
+	// holds tool calls otherwise; a reply that uses them is repaired.
+	res, err := r.client.SendMessageSync(requestCtx, msgs, r.client.GetSkills(), llm.WithResponseFormat(format))
+	if res == nil {
+		return "", err
+	}
+	return res.Content, err
+}
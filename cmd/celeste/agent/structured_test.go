@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/schema"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+func TestPlanResponseFormatBoundsSteps(t *testing.T) {
+	format := planResponseFormat(2)
+	assert.NoError(t, schema.ValidateJSON(format.Schema, []byte(`{"steps":[{"title":"read"},{"title":"fix"}]}`)))
+	assert.Error(t, schema.ValidateJSON(format.Schema, []byte(`{"steps":[]}`)))
+	assert.Error(t, schema.ValidateJSON(format.Schema, []byte(`{"steps":[{"title":"a"},{"title":"b"},{"title":"c"}]}`)))
+	assert.Error(t, schema.ValidateJSON(format.Schema, []byte(`1. read`)))
+}
+
+func TestDecodePlanSteps(t *testing.T) {
+	steps, err := decodePlanSteps(`{"steps":[{"title":" Read the code "},{"title":""},{"title":"Run tests"}]}`, 7)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, PlanStep{Index: 1, Title: "Read the code", Status: PlanStatusPending}, steps[0])
+	assert.Equal(t, 2, steps[1].Index)
+
+	_, err = decodePlanSteps("1. Read the code", 7)
+	assert.Error(t, err)
+}
+
+// TestPlanningTurnSendsNoTools pins down that the planning turn offers the
+// model no tools: the plan comes back as structured output, and on the first
+// turn there are no tool calls in the history that would need them defined.
+func TestPlanningTurnSendsNoTools(t *testing.T) {
+	var requests []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		requests = append(requests, body)
+		chunk, _ := json.Marshal(map[string]any{
+			"id": "chatcmpl-1", "object": "chat.completion.chunk", "model": "gpt-4.1-nano",
+			"choices": []map[string]any{{
+				"index":         0,
+				"finish_reason": "stop",
+				"delta": map[string]any{
+					"role":    "assistant",
+					"content": `{"steps":[{"title":"Read the code"},{"title":"Fix the bug"}]}`,
+				},
+			}},
+		})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", chunk)
+	}))
+	defer srv.Close()
+
+	opts := DefaultOptions()
+	opts.Workspace = t.TempDir()
+	opts.DisableCheckpoints = true
+	r, err := NewRunner(newTestConfig(srv.URL+"/v1", "gpt-4.1-nano"), opts, io.Discard, io.Discard)
+	require.NoError(t, err)
+	defer r.Close()
+	require.NotEmpty(t, r.client.GetSkills(), "precondition: the run has tools the planning turn could offer")
+
+	state := NewRunState("fix the bug", r.options)
+	state.Messages = append(state.Messages, tui.ChatMessage{Role: "user", Content: "fix the bug"})
+	require.NoError(t, r.runPlanningPhase(context.Background(), state))
+
+	require.Len(t, requests, 1)
+	assert.NotContains(t, requests[0], "tools")
+	require.Len(t, state.Plan, 2)
+	assert.Equal(t, "Fix the bug", state.Plan[1].Title)
+	assert.Equal(t, PhaseExecution, state.Phase)
+}
@@ -5,6 +5,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/testrun"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
 )
 
@@ -85,11 +87,20 @@ type Options struct {
 	// `celeste agent` CLI, which can offer -auto-approve as the remedy. Other
 	// callers (TUI /agent, orchestrator) leave it false so this does not change
 	// their behaviour; they share the same underlying gap, tracked separately.
-	FailOnBlockedTools bool   `json:"fail_on_blocked_tools"`
-	EmitArtifacts      bool   `json:"emit_artifacts"`
-	ArtifactDir        string `json:"artifact_dir,omitempty"`
-	DisableCheckpoints bool   `json:"disable_checkpoints"`
-	Verbose            bool   `json:"verbose"`
+	FailOnBlockedTools bool `json:"fail_on_blocked_tools"`
+	// Sandbox overrides the sandbox mode from ~/.celeste/permissions.json
+	// for this run. Empty keeps the configured mode.
+	Sandbox            sandbox.Mode `json:"sandbox,omitempty"`
+	EmitArtifacts      bool         `json:"emit_artifacts"`
+	ArtifactDir        string       `json:"artifact_dir,omitempty"`
+	DisableCheckpoints bool         `json:"disable_checkpoints"`
+	ShadowCheckpoints  bool         `json:"shadow_checkpoints,omitempty"` // whole-workspace git snapshots per turn; also via checkpoint_mode "shadow"
+	DisableToolCache   bool         `json:"disable_tool_cache,omitempty"` // always send read-only tool results in full
+	Verbose            bool         `json:"verbose"`
+	// BatchGrading sends eval and benchmark rubric grading through the
+	// provider's batch API (half price, results within hours) instead of
+	// one request per answer.
+	BatchGrading bool `json:"batch_grading,omitempty"`
 	// OnProgress is an optional callback invoked at key agent events.
 	// text is a human-readable label. turn/maxTurns are 0 for non-turn events.
 	// This field is not serialised to JSON (func types are not JSON-safe).
@@ -106,8 +117,11 @@ type TurnStats struct {
 	Elapsed      time.Duration
 	InputTokens  int
 	OutputTokens int
+	CachedTokens int      // input tokens the provider served from its prompt cache
 	Response     string   // full assistant content for this turn (may be empty for pure tool-call turns)
 	ToolCalls    []string // names of tools called this turn
+	Provider     string   // provider that served the turn (differs from the configured one after failover)
+	Model        string   // model that served the turn
 }
 
 func DefaultOptions() Options {
@@ -139,11 +153,25 @@ type PlanStep struct {
 }
 
 type VerificationCheck struct {
-	Command   string    `json:"command"`
-	Passed    bool      `json:"passed"`
-	ExitCode  int       `json:"exit_code"`
-	Output    string    `json:"output,omitempty"`
-	TimedOut  bool      `json:"timed_out,omitempty"`
+	Command  string `json:"command"`
+	Passed   bool   `json:"passed"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+	// Tests is the parsed result when the command is a test run testrun
+	// recognizes, such as go test or pytest; Output is then its summary.
+	Tests     *testrun.Result `json:"tests,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// CommitRecord is a commit the agent made with git_commit.
+type CommitRecord struct {
+	Hash      string    `json:"hash"`
+	Branch    string    `json:"branch,omitempty"`
+	Subject   string    `json:"subject"`
+	Files     []string  `json:"files,omitempty"`
+	Amend     bool      `json:"amend,omitempty"`
+	Turn      int       `json:"turn"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -153,6 +181,8 @@ type Step struct {
 	Name      string    `json:"name,omitempty"`
 	Content   string    `json:"content,omitempty"`
 	ToolCall  string    `json:"tool_call_id,omitempty"`
+	Provider  string    `json:"provider,omitempty"` // who served a model turn, for cost tracking across failover
+	Model     string    `json:"model,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -173,6 +203,7 @@ type RunState struct {
 	Plan                       []PlanStep          `json:"plan,omitempty"`
 	ActivePlanStep             int                 `json:"active_plan_step,omitempty"`
 	Verification               []VerificationCheck `json:"verification,omitempty"`
+	Commits                    []CommitRecord      `json:"commits,omitempty"`
 	LastAssistantResponse      string              `json:"last_assistant_response,omitempty"`
 	ArtifactBundlePath         string              `json:"artifact_bundle_path,omitempty"`
 	Error                      string              `json:"error,omitempty"`
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerificationCheckStoresTestResult(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not on PATH")
+	}
+	t.Setenv("GOTOOLCHAIN", "local")
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":    "module example.com/v\n\ngo 1.21\n",
+		"v_test.go": "package v\n\nimport \"testing\"\n\nfunc TestPass(t *testing.T) {}\n\nfunc TestFail(t *testing.T) {\n\tt.Error(\"boom\")\n}\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	check := executeVerificationCommand(context.Background(), dir, "go test ./...", time.Minute)
+	assert.False(t, check.Passed)
+	require.NotNil(t, check.Tests)
+	assert.Equal(t, 1, check.Tests.Passed)
+	require.Len(t, check.Tests.Failures, 1)
+	assert.Equal(t, "v_test.go:8", check.Tests.Failures[0].Location())
+	assert.Equal(t, check.Tests.Summary(), check.Output)
+
+	prompt := buildVerificationFailurePrompt([]VerificationCheck{check}, DefaultOptions())
+	assert.Contains(t, prompt, "FAIL example.com/v TestFail at v_test.go:8\n    v_test.go:8: boom")
+
+	// Shell syntax runs the command as written.
+	check = executeVerificationCommand(context.Background(), dir, "go test ./... && true", time.Minute)
+	assert.False(t, check.Passed)
+	assert.Nil(t, check.Tests)
+	assert.Contains(t, check.Output, "--- FAIL: TestFail")
+}
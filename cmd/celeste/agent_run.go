@@ -13,6 +13,7 @@ import (
 
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/agent"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 )
 
 type stringSliceFlag []string
@@ -39,6 +40,7 @@ func runAgentCommand(args []string) {
 	evalFile := fs.String("eval", "", "Run evaluation cases from JSON file")
 	benchmarkFile := fs.String("benchmark", "", "Run benchmark suite JSON file")
 	benchmarkOut := fs.String("benchmark-out", "", "Write benchmark report JSON to this path")
+	batchGrading := fs.Bool("batch", false, "Grade eval/benchmark rubrics through the provider's batch API (half price; results can take hours)")
 	workspace := fs.String("workspace", "", "Workspace root for agent development tools (defaults to current directory)")
 	artifactDir := fs.String("artifact-dir", "", "Directory where run artifact bundles are written")
 	maxTurns := fs.Int("max-turns", 0, "Maximum agent turns")
@@ -57,6 +59,9 @@ func runAgentCommand(args []string) {
 	noArtifacts := fs.Bool("no-artifacts", false, "Disable per-run artifact bundle output")
 	verbose := fs.Bool("verbose", true, "Print turn-by-turn output")
 	noCheckpoint := fs.Bool("no-checkpoint", false, "Disable checkpoint persistence for this run")
+	shadowCheckpoints := fs.Bool("shadow-checkpoints", false, "Snapshot the whole workspace at every turn (also enabled by checkpoint_mode \"shadow\" in config)")
+	noToolCache := fs.Bool("no-tool-cache", false, "Send every read-only tool result in full instead of referring back to an identical earlier result")
+	sandboxMode := fs.String("sandbox", "", "Sandbox for bash and custom tools: off, auto or required (default: the sandbox mode in ~/.celeste/permissions.json). Pair required with -auto-approve for untrusted repos")
 	autoApprove := fs.Bool("auto-approve", false, "Approve every tool without prompting. Required for unattended runs: `celeste agent` has no interactive prompt, so tools needing approval are otherwise denied")
 
 	_ = fs.Parse(args)
@@ -114,6 +119,14 @@ func runAgentCommand(args []string) {
 	opts.AutoApproveTools = *autoApprove
 	// Only the CLI can offer -auto-approve, so only the CLI refuses to start.
 	opts.FailOnBlockedTools = true
+	if *sandboxMode != "" {
+		mode, err := sandbox.ParseMode(*sandboxMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Sandbox = mode
+	}
 
 	// NewRunner stands the local planner down for conductor models. An
 	// explicitly passed -planner/-require-verify overrides that, which is what
@@ -132,7 +145,10 @@ func runAgentCommand(args []string) {
 	opts.ArtifactDir = strings.TrimSpace(*artifactDir)
 	opts.EmitArtifacts = !*noArtifacts
 	opts.DisableCheckpoints = *noCheckpoint
+	opts.ShadowCheckpoints = *shadowCheckpoints
+	opts.DisableToolCache = *noToolCache
 	opts.Verbose = *verbose
+	opts.BatchGrading = *batchGrading
 	if *maxTurns > 0 {
 		opts.MaxTurns = *maxTurns
 	}
@@ -175,6 +191,7 @@ func runAgentCommand(args []string) {
 		}
 
 		passed := 0
+		var grading agent.GradingSummary
 		for _, result := range results {
 			status := "FAIL"
 			if result.Passed {
@@ -182,8 +199,18 @@ func runAgentCommand(args []string) {
 				passed++
 			}
 			fmt.Printf("[%s] %s (%s) - %s\n", status, result.CaseName, result.Status, result.Reason)
+			if g := result.Grade; g != nil {
+				grading.Requests++
+				grading.Batched = g.Batched
+				grading.CostUSD += g.CostUSD
+				if g.Usage != nil {
+					grading.InputTokens += g.Usage.PromptTokens
+					grading.OutputTokens += g.Usage.CompletionTokens
+				}
+			}
 		}
 		fmt.Printf("\nEval Summary: %d/%d passed\n", passed, len(results))
+		printGradingSummary(&grading)
 		if passed != len(results) {
 			os.Exit(1)
 		}
@@ -217,6 +244,7 @@ func runAgentCommand(args []string) {
 			}
 		}
 		fmt.Printf("\nBenchmark Summary: cases passed %d/%d\n", report.PassedCases, report.TotalCases)
+		printGradingSummary(report.Grading)
 
 		if strings.TrimSpace(*benchmarkOut) != "" {
 			data, err := json.MarshalIndent(report, "", "  ")
@@ -308,3 +336,16 @@ func printRunSummary(state *agent.RunState) {
 		fmt.Printf("\nError: %s\n", state.Error)
 	}
 }
+
+// printGradingSummary reports what rubric grading cost, if any was done.
+func printGradingSummary(g *agent.GradingSummary) {
+	if g == nil || g.Requests == 0 {
+		return
+	}
+	pricing := "list price"
+	if g.Batched {
+		pricing = "batch price"
+	}
+	fmt.Printf("Rubric grading: %d request(s), %d in / %d out tokens, $%.4f at %s\n",
+		g.Requests, g.InputTokens, g.OutputTokens, g.CostUSD, pricing)
+}
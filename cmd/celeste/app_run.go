@@ -6,6 +6,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 )
 
 type commandRunner interface {
@@ -29,6 +31,7 @@ type commandRunner interface {
 	RunIndex(args []string)
 	RunServe(args []string)
 	RunCosts(args []string)
+	RunModels(args []string)
 	RunMemories(args []string)
 	RunRemember(args []string)
 	RunForget(args []string)
@@ -36,6 +39,10 @@ type commandRunner interface {
 	RunPlan(args []string)
 	RunRevert(args []string)
 	RunMCP(args []string)
+	RunSecrets(args []string)
+	RunBatch(args []string)
+	RunImage(args []string)
+	RunSandbox(args []string)
 }
 
 type defaultCommandRunner struct{}
@@ -62,6 +69,7 @@ func (defaultCommandRunner) RunGrimoire(args []string)      { runGrimoireCommand
 func (defaultCommandRunner) RunIndex(args []string)         { runIndexCommand(args) }
 func (defaultCommandRunner) RunServe(args []string)         { runServeCommand(args) }
 func (defaultCommandRunner) RunCosts(args []string)         { runCostsCommand(args) }
+func (defaultCommandRunner) RunModels(args []string)        { runModelsCommand(args) }
 func (defaultCommandRunner) RunMemories(args []string)      { runMemoriesCommand(args) }
 func (defaultCommandRunner) RunRemember(args []string)      { runRememberCommand(args) }
 func (defaultCommandRunner) RunForget(args []string)        { runForgetCommand(args) }
@@ -69,8 +77,15 @@ func (defaultCommandRunner) RunResume(args []string)        { runResumeCommand(a
 func (defaultCommandRunner) RunPlan(args []string)          { runPlanCommand(args) }
 func (defaultCommandRunner) RunRevert(args []string)        { runRevertCommand(args) }
 func (defaultCommandRunner) RunMCP(args []string)           { runMCPCommand(args) }
+func (defaultCommandRunner) RunSecrets(args []string)       { runSecretsCommand(args) }
+func (defaultCommandRunner) RunBatch(args []string)         { runBatchCommand(args) }
+func (defaultCommandRunner) RunImage(args []string)         { runImageCommand(args) }
+func (defaultCommandRunner) RunSandbox(args []string)       { runSandboxCommand(args) }
 
 func main() {
+	// A sandboxed command starts as a re-exec of celeste; this confines it
+	// and execs the command, never returning.
+	sandbox.Init()
 	os.Exit(run(os.Args[1:], defaultCommandRunner{}, os.Stdout, os.Stderr))
 }
 
@@ -128,6 +143,8 @@ func run(args []string, runner commandRunner, stdout, stderr io.Writer) int {
 		runner.RunServe(cmdArgs)
 	case "costs":
 		runner.RunCosts(cmdArgs)
+	case "models":
+		runner.RunModels(cmdArgs)
 	case "memories":
 		runner.RunMemories(cmdArgs)
 	case "remember":
@@ -142,6 +159,14 @@ func run(args []string, runner commandRunner, stdout, stderr io.Writer) int {
 		runner.RunRevert(cmdArgs)
 	case "mcp":
 		runner.RunMCP(cmdArgs)
+	case "secrets":
+		runner.RunSecrets(cmdArgs)
+	case "batch":
+		runner.RunBatch(cmdArgs)
+	case "image":
+		runner.RunImage(cmdArgs)
+	case "sandbox":
+		runner.RunSandbox(cmdArgs)
 	case "help", "-h", "--help":
 		runner.PrintUsage()
 	case "version", "-v", "--version":
@@ -86,6 +86,10 @@ func (f *fakeRunner) RunCosts(args []string) {
 	f.lastCall = "costs"
 	f.lastArgs = args
 }
+func (f *fakeRunner) RunModels(args []string) {
+	f.lastCall = "models"
+	f.lastArgs = args
+}
 func (f *fakeRunner) RunMemories(args []string) {
 	f.lastCall = "memories"
 	f.lastArgs = args
@@ -114,6 +118,22 @@ func (f *fakeRunner) RunMCP(args []string) {
 	f.lastCall = "mcp"
 	f.lastArgs = args
 }
+func (f *fakeRunner) RunSecrets(args []string) {
+	f.lastCall = "secrets"
+	f.lastArgs = args
+}
+func (f *fakeRunner) RunBatch(args []string) {
+	f.lastCall = "batch"
+	f.lastArgs = args
+}
+func (f *fakeRunner) RunImage(args []string) {
+	f.lastCall = "image"
+	f.lastArgs = args
+}
+func (f *fakeRunner) RunSandbox(args []string) {
+	f.lastCall = "sandbox"
+	f.lastArgs = args
+}
 
 func TestRun_NoArgs_LaunchesChatDirectly(t *testing.T) {
 	r := &fakeRunner{hasDefaultConfig: true}
@@ -155,6 +175,10 @@ func TestRun_DispatchesKnownCommands(t *testing.T) {
 		{name: "session", args: []string{"session", "--list"}, wantCall: "session", wantArgs: []string{"--list"}},
 		{name: "collections", args: []string{"collections", "list"}, wantCall: "collections", wantArgs: []string{"list"}},
 		{name: "agent", args: []string{"agent", "--goal", "do work"}, wantCall: "agent", wantArgs: []string{"--goal", "do work"}},
+		{name: "models", args: []string{"models", "show", "gpt-5"}, wantCall: "models", wantArgs: []string{"show", "gpt-5"}},
+		{name: "batch", args: []string{"batch", "status", "b1"}, wantCall: "batch", wantArgs: []string{"status", "b1"}},
+		{name: "image", args: []string{"image", "generate", "a fox"}, wantCall: "image", wantArgs: []string{"generate", "a fox"}},
+		{name: "sandbox", args: []string{"sandbox", "run", "touch x"}, wantCall: "sandbox", wantArgs: []string{"run", "touch x"}},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/costs"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// batchJob is one line of a `celeste batch submit` input file.
+type batchJob struct {
+	ID        string         `json:"id"`
+	Prompt    string         `json:"prompt"`
+	System    string         `json:"system,omitempty"`
+	Schema    map[string]any `json:"schema,omitempty"`
+	MaxTokens int            `json:"max_tokens,omitempty"`
+}
+
+// batchOutput is one line of a results file.
+type batchOutput struct {
+	ID           string `json:"id"`
+	Content      string `json:"content,omitempty"`
+	Error        string `json:"error,omitempty"`
+	InputTokens  int    `json:"input_tokens,omitempty"`
+	OutputTokens int    `json:"output_tokens,omitempty"`
+}
+
+// runBatchCommand handles `celeste batch <subcommand>`: bulk single-shot
+// prompts sent through the provider's batch API.
+func runBatchCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: celeste batch submit|status|results|cancel [args]")
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch args[0] {
+	case "submit":
+		err = runBatchSubmit(ctx, args[1:])
+	case "status":
+		err = runBatchStatus(ctx, args[1:])
+	case "results":
+		err = runBatchResults(ctx, args[1:])
+	case "cancel":
+		err = runBatchCancel(ctx, args[1:])
+	default:
+		err = fmt.Errorf("unknown batch subcommand %q. Try: submit, status, results, cancel", args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runBatchSubmit(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("batch submit", flag.ExitOnError)
+	wait := fs.Bool("wait", false, "Wait for the batch to finish and write its results")
+	out := fs.String("out", "", "Results file for -wait (JSONL; default stdout)")
+	poll := fs.Int("poll", 30, "Seconds between status checks with -wait")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: celeste batch submit [-wait] [-out results.jsonl] <jobs.jsonl|->")
+	}
+
+	reqs, err := readBatchJobs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, cfg, err := openBatcher(true)
+	if err != nil {
+		return err
+	}
+	if !*wait {
+		id, err := b.Submit(ctx, reqs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Submitted %d request(s) to the %s as %s\n", len(reqs), b.Name(), id)
+		fmt.Printf("Check on it with: celeste batch status %s\n", id)
+		return nil
+	}
+	results, err := llm.RunBatch(ctx, b, reqs, time.Duration(*poll)*time.Second, printBatchProgress)
+	if err != nil {
+		return err
+	}
+	return writeBatchOutputs(*out, results, cfg.Model, b.Discounted())
+}
+
+func runBatchStatus(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: celeste batch status <batch-id>")
+	}
+	b, _, err := openBatcher(true)
+	if err != nil {
+		return err
+	}
+	status, err := b.Status(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	printBatchProgress(status)
+	return nil
+}
+
+func runBatchResults(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("batch results", flag.ExitOnError)
+	out := fs.String("out", "", "Results file (JSONL; default stdout)")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: celeste batch results [-out results.jsonl] <batch-id>")
+	}
+	b, cfg, err := openBatcher(true)
+	if err != nil {
+		return err
+	}
+	status, err := b.Status(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !status.Done {
+		return fmt.Errorf("batch %s is still %s (%d/%d done); try again later", status.ID, status.State, status.Succeeded+status.Failed, status.Total)
+	}
+	results, err := b.Results(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeBatchOutputs(*out, results, cfg.Model, b.Discounted())
+}
+
+func runBatchCancel(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: celeste batch cancel <batch-id>")
+	}
+	b, _, err := openBatcher(true)
+	if err != nil {
+		return err
+	}
+	if err := b.Cancel(ctx, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Cancelling %s; finished requests keep their results\n", args[0])
+	return nil
+}
+
+// openBatcher loads the active profile and returns its provider batch API,
+// or, when provider is false, a batcher that sends requests directly.
+func openBatcher(provider bool) (llm.Batcher, *config.Config, error) {
+	cfg, err := config.LoadNamed(configName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading config: %w", err)
+	}
+	llmConfig := &llm.Config{
+		APIKey:                cfg.APIKey,
+		BaseURL:               cfg.BaseURL,
+		Model:                 cfg.Model,
+		Timeout:               cfg.GetTimeout(),
+		GoogleCredentialsFile: cfg.GoogleCredentialsFile,
+		GoogleUseADC:          cfg.GoogleUseADC,
+		Fallbacks:             llm.FallbacksFromConfig(cfg),
+		KeepAlive:             cfg.OllamaKeepAlive,
+		ContextLimit:          cfg.ContextLimit,
+		NoPromptCache:         cfg.DisablePromptCache,
+		BatchAPI:              cfg.BatchAPI,
+	}
+	if !provider {
+		return llm.NewDirectBatcher(llmConfig), cfg, nil
+	}
+	b, err := llm.NewBatcher(llmConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w (set \"batch_api\" in the profile if the endpoint speaks OpenAI's or Anthropic's batch protocol)", err)
+	}
+	return b, cfg, nil
+}
+
+// readBatchJobs parses a JSONL jobs file ("-" for stdin). Lines without an
+// id are numbered.
+func readBatchJobs(path string) ([]llm.BatchRequest, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var reqs []llm.BatchRequest
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var job batchJob
+		if err := json.Unmarshal([]byte(line), &job); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if strings.TrimSpace(job.Prompt) == "" {
+			return nil, fmt.Errorf("%s:%d: prompt is empty", path, n)
+		}
+		if job.ID == "" {
+			job.ID = fmt.Sprintf("job-%d", len(reqs)+1)
+		}
+		req := llm.BatchRequest{
+			ID:        job.ID,
+			System:    job.System,
+			Messages:  []tui.ChatMessage{{Role: "user", Content: job.Prompt}},
+			MaxTokens: job.MaxTokens,
+		}
+		if job.Schema != nil {
+			req.Format = &llm.ResponseFormat{Name: "result", Schema: job.Schema}
+		}
+		reqs = append(reqs, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%s has no jobs", path)
+	}
+	return reqs, nil
+}
+
+// writeBatchOutputs writes results as JSONL to path (stdout when empty) and
+// prints a summary with the cost to stderr.
+func writeBatchOutputs(path string, results []llm.BatchResult, model string, discounted bool) error {
+	w := os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		o := batchOutput{ID: r.ID, Content: r.Content}
+		if r.Err != nil {
+			o.Error = r.Err.Error()
+		}
+		if r.Usage != nil {
+			o.InputTokens, o.OutputTokens = r.Usage.PromptTokens, r.Usage.CompletionTokens
+		}
+		if err := enc.Encode(o); err != nil {
+			return err
+		}
+	}
+	printBatchSummary(results, model, discounted)
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "Results written: %s\n", path)
+	}
+	return nil
+}
+
+// printBatchSummary prints what a batch produced and what it cost.
+func printBatchSummary(results []llm.BatchResult, model string, discounted bool) {
+	var failed, in, out int
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+		if r.Usage != nil {
+			in += r.Usage.PromptTokens
+			out += r.Usage.CompletionTokens
+		}
+	}
+	cost, pricing := costs.GetCost(model, in, out), "list price"
+	if discounted {
+		cost, pricing = costs.GetBatchCost(model, in, out), "batch price"
+	}
+	fmt.Fprintf(os.Stderr, "%d succeeded, %d failed · %d in / %d out tokens · $%.4f at %s\n",
+		len(results)-failed, failed, in, out, cost, pricing)
+}
+
+func printBatchProgress(s llm.BatchStatus) {
+	fmt.Fprintf(os.Stderr, "%s: %s (%d succeeded, %d failed, %d total)\n", s.ID, s.State, s.Succeeded, s.Failed, s.Total)
+}
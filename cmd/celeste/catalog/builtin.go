@@ -0,0 +1,77 @@
+package catalog
+
+var yes, no = boolPtr(true), boolPtr(false)
+
+func boolPtr(v bool) *bool { return &v }
+
+// builtinModels is the table shipped with the binary. Provider listings and
+// config overrides refine it at runtime; entries here are what celeste knows
+// with no network access. Prices are USD per 1M tokens.
+var builtinModels = []Model{
+	// OpenAI — current generation (from /v1/models and the pricing page, 2026-04)
+	{ID: "gpt-4.1", Provider: "openai", ContextWindow: 1050000, MaxOutput: 32768, Tools: yes, Vision: yes, InputPrice: 2.50, OutputPrice: 15.00, CachedInputPrice: 0.625},
+	{ID: "gpt-4.1-mini", Provider: "openai", ContextWindow: 400000, MaxOutput: 32768, Tools: yes, Vision: yes, InputPrice: 0.75, OutputPrice: 4.50, CachedInputPrice: 0.1875},
+	{ID: "gpt-4.1-nano", Provider: "openai", ContextWindow: 400000, MaxOutput: 32768, Tools: yes, Vision: yes, InputPrice: 0.20, OutputPrice: 1.25, CachedInputPrice: 0.05},
+	{ID: "gpt-5.3-codex", Provider: "openai", ContextWindow: 1050000, MaxOutput: 128000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 2.50, OutputPrice: 15.00, CachedInputPrice: 0.25},
+	{ID: "gpt-5.4", Provider: "openai", ContextWindow: 1050000, MaxOutput: 128000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 2.50, OutputPrice: 15.00, CachedInputPrice: 0.25},
+	{ID: "gpt-5.4-mini", Provider: "openai", ContextWindow: 400000, MaxOutput: 128000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 0.75, OutputPrice: 4.50, CachedInputPrice: 0.075},
+	{ID: "gpt-5.4-nano", Provider: "openai", ContextWindow: 400000, MaxOutput: 128000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 0.20, OutputPrice: 1.25, CachedInputPrice: 0.02},
+	{ID: "gpt-5.4-pro", Provider: "openai", ContextWindow: 1050000, MaxOutput: 128000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 15.00, OutputPrice: 60.00},
+	{ID: "o3", Provider: "openai", ContextWindow: 1050000, MaxOutput: 100000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 2.50, OutputPrice: 15.00, CachedInputPrice: 0.625},
+	{ID: "o4-mini", Provider: "openai", ContextWindow: 400000, MaxOutput: 100000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 0.75, OutputPrice: 4.50, CachedInputPrice: 0.1875},
+	// OpenAI — previous generation, still in the model picker
+	{ID: "gpt-4-turbo", Provider: "openai", ContextWindow: 128000, MaxOutput: 4096, Tools: yes, Vision: yes},
+	{ID: "gpt-3.5-turbo", Provider: "openai", ContextWindow: 16385, MaxOutput: 4096, Tools: yes},
+
+	// Anthropic (current models, 2026-04). Cache reads bill at 0.1x input,
+	// five-minute cache writes at 1.25x.
+	{ID: "claude-opus-4-6", Provider: "anthropic", ContextWindow: 1000000, MaxOutput: 128000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 5.00, OutputPrice: 25.00, CachedInputPrice: 0.50, CacheWritePrice: 6.25},
+	{ID: "claude-sonnet-4-6", Provider: "anthropic", ContextWindow: 1000000, MaxOutput: 64000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 3.00, OutputPrice: 15.00, CachedInputPrice: 0.30, CacheWritePrice: 3.75},
+	{ID: "claude-haiku-4-5", Provider: "anthropic", ContextWindow: 200000, MaxOutput: 64000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 1.00, OutputPrice: 5.00, CachedInputPrice: 0.10, CacheWritePrice: 1.25},
+	{ID: "claude-sonnet-4-5-20250929", Provider: "anthropic", ContextWindow: 200000, MaxOutput: 64000, Tools: yes, Vision: yes, Reasoning: yes},
+	{ID: "claude-opus-4-5-20251101", Provider: "anthropic", ContextWindow: 200000, MaxOutput: 64000, Tools: yes, Vision: yes, Reasoning: yes},
+
+	// xAI Grok — current generation (from /v1/models and the pricing page, 2026-04)
+	// grok-build-0.1 is the grok code model. grok-4-1-fast is dead (migrated
+	// away on load) and kept for old logs.
+	{ID: "grok-build-0.1", Provider: "grok", ContextWindow: 256000, Tools: yes, Reasoning: yes, InputPrice: 1.00, OutputPrice: 2.00, CachedInputPrice: 0.20},
+	{ID: "grok-4-1-fast", Provider: "grok", ContextWindow: 2000000, InputPrice: 0.20, OutputPrice: 0.50, CachedInputPrice: 0.05},
+	{ID: "grok-4-1-fast-reasoning", Provider: "grok", ContextWindow: 2000000, Reasoning: yes, InputPrice: 0.20, OutputPrice: 0.50, CachedInputPrice: 0.05},
+	{ID: "grok-4-1-fast-non-reasoning", Provider: "grok", ContextWindow: 2000000, InputPrice: 0.20, OutputPrice: 0.50, CachedInputPrice: 0.05},
+	// grok-4.x family: 1M context, $1.25 in / $2.50 out per docs.x.ai (2026-06).
+	// NOTE: context corrected down from 2M — overstating the window let history
+	// grow past the real API limit.
+	{ID: "grok-4.3", Provider: "grok", ContextWindow: 1000000, Tools: yes, Reasoning: yes, InputPrice: 1.25, OutputPrice: 2.50},
+	{ID: "grok-4.20-0309-reasoning", Provider: "grok", ContextWindow: 1000000, Tools: yes, Vision: yes, Reasoning: yes, InputPrice: 1.25, OutputPrice: 2.50},
+	{ID: "grok-4.20-0309-non-reasoning", Provider: "grok", ContextWindow: 1000000, Tools: yes, Vision: yes, Reasoning: no, InputPrice: 1.25, OutputPrice: 2.50},
+	{ID: "grok-4.20-multi-agent-0309", Provider: "grok", ContextWindow: 1000000, Tools: yes, Reasoning: yes, InputPrice: 1.25, OutputPrice: 2.50},
+	{ID: "grok-code-fast-1", Provider: "grok", ContextWindow: 2000000, Tools: yes, Reasoning: yes, InputPrice: 0.20, OutputPrice: 0.50, CachedInputPrice: 0.02},
+	{ID: "grok-beta", Provider: "grok", ContextWindow: 131072, Tools: yes},
+	{ID: "grok-4-latest", Provider: "grok", ContextWindow: 131072},
+
+	// Google
+	{ID: "gemini-2.0-flash", Provider: "gemini", ContextWindow: 1048576, MaxOutput: 8192, Tools: yes, Vision: yes, InputPrice: 0.10, OutputPrice: 0.40, CachedInputPrice: 0.025},
+	{ID: "gemini-1.5-pro", Provider: "vertex", ContextWindow: 2000000, MaxOutput: 8192, Tools: yes, Vision: yes},
+	{ID: "gemini-1.5-flash", Provider: "vertex", ContextWindow: 1000000, MaxOutput: 8192, Tools: yes, Vision: yes},
+
+	// Sakana AI (from /v1/models, 2026-06). Fugu supports parallel tool calls.
+	{ID: "fugu", Provider: "sakana", ContextWindow: 1000000, Tools: yes, Reasoning: yes},
+	{ID: "fugu-ultra", Provider: "sakana", ContextWindow: 1000000, Tools: yes, Reasoning: yes},
+	{ID: "fugu-ultra-20260615", Provider: "sakana", ContextWindow: 1000000, Tools: yes, Reasoning: yes}, // dated alias pin of fugu-ultra
+	{ID: "fugu-ultra-v1.0", Provider: "sakana", ContextWindow: 1000000, Tools: yes, Reasoning: yes},
+	{ID: "fugu-ultra-v1.1", Provider: "sakana", ContextWindow: 1000000, Tools: yes, Reasoning: yes},
+
+	// Venice-unique models (from docs.venice.ai, 2026-04). Tool support comes
+	// from Venice's live catalog where this table leaves it unset.
+	{ID: "venice-uncensored", Provider: "venice", ContextWindow: 32000, Tools: no, InputPrice: 0.20, OutputPrice: 0.90},
+	{ID: "venice-uncensored-role-play", Provider: "venice", ContextWindow: 128000, InputPrice: 0.50, OutputPrice: 2.00},
+	{ID: "deepseek-v3.2", Provider: "venice", ContextWindow: 160000, InputPrice: 0.33, OutputPrice: 0.48},
+	{ID: "qwen3-coder-480b-a35b-instruct", Provider: "venice", ContextWindow: 256000, InputPrice: 0.75, OutputPrice: 3.00},
+	{ID: "qwen3-coder-480b-a35b-instruct-turbo", Provider: "venice", ContextWindow: 256000, InputPrice: 0.35, OutputPrice: 1.50},
+	{ID: "qwen3-235b-a22b-thinking-2507", Provider: "venice", ContextWindow: 128000, Reasoning: yes, InputPrice: 0.45, OutputPrice: 3.50},
+	{ID: "kimi-k2-5", Provider: "venice", ContextWindow: 256000, InputPrice: 0.56, OutputPrice: 3.50},
+	{ID: "zai-org-glm-4.7", Provider: "venice", ContextWindow: 198000, InputPrice: 0.55, OutputPrice: 2.65},
+	{ID: "mistral-small-3-2-24b-instruct", Provider: "venice", ContextWindow: 256000, InputPrice: 0.09, OutputPrice: 0.25},
+	{ID: "llama-3.3-70b", Provider: "venice", ContextWindow: 128000, Tools: yes, InputPrice: 0.70, OutputPrice: 2.80},
+	{ID: "minimax-m25", Provider: "venice", ContextWindow: 198000, InputPrice: 0.34, OutputPrice: 1.19},
+}
@@ -0,0 +1,393 @@
+// Package catalog is the one place celeste keeps what it knows about models:
+// context window, output limit, tool/vision/reasoning support and pricing.
+//
+// Three layers are merged field by field, later ones winning:
+//
+//   - the built-in table (builtin.go), shipped with the binary
+//   - provider listings, refreshed from the providers' model endpoints and
+//     cached in ~/.celeste/models.json, plus what a local server reports at
+//     runtime (Ollama's /api/show)
+//   - per-model overrides from the config ("model_overrides")
+//
+// Context budgeting (ctxmgr), costing (costs), tool gating and the model
+// selector (providers) all read from here, so adding a model is one entry.
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RefreshInterval is how old a provider's cached listing may get before it
+// is fetched again.
+const RefreshInterval = 24 * time.Hour
+
+// Model is what the catalog knows about one model. Zero values and nil
+// flags mean unknown, so a layer only overrides the fields it sets.
+type Model struct {
+	ID            string `json:"id"`
+	Provider      string `json:"provider,omitempty"`
+	Name          string `json:"name,omitempty"`
+	ContextWindow int    `json:"context_window,omitempty"` // tokens
+	MaxOutput     int    `json:"max_output,omitempty"`     // tokens per response
+	Tools         *bool  `json:"tools,omitempty"`
+	Vision        *bool  `json:"vision,omitempty"`
+	Reasoning     *bool  `json:"reasoning,omitempty"`
+
+	// Prices are USD per 1M tokens. Cache prices apply to the part of the
+	// prompt read from or written to the provider's prompt cache; when they
+	// are zero those tokens are charged at InputPrice.
+	InputPrice       float64 `json:"input_price,omitempty"`
+	OutputPrice      float64 `json:"output_price,omitempty"`
+	CachedInputPrice float64 `json:"cached_input_price,omitempty"`
+	CacheWritePrice  float64 `json:"cache_write_price,omitempty"`
+}
+
+// Priced reports whether the model has a known price.
+func (m Model) Priced() bool {
+	return m.InputPrice > 0 || m.OutputPrice > 0
+}
+
+// merge returns m with every field o sets.
+func (m Model) merge(o Model) Model {
+	if o.Provider != "" {
+		m.Provider = o.Provider
+	}
+	if o.Name != "" {
+		m.Name = o.Name
+	}
+	if o.ContextWindow > 0 {
+		m.ContextWindow = o.ContextWindow
+	}
+	if o.MaxOutput > 0 {
+		m.MaxOutput = o.MaxOutput
+	}
+	if o.Tools != nil {
+		m.Tools = o.Tools
+	}
+	if o.Vision != nil {
+		m.Vision = o.Vision
+	}
+	if o.Reasoning != nil {
+		m.Reasoning = o.Reasoning
+	}
+	if o.InputPrice > 0 {
+		m.InputPrice = o.InputPrice
+	}
+	if o.OutputPrice > 0 {
+		m.OutputPrice = o.OutputPrice
+	}
+	if o.CachedInputPrice > 0 {
+		m.CachedInputPrice = o.CachedInputPrice
+	}
+	if o.CacheWritePrice > 0 {
+		m.CacheWritePrice = o.CacheWritePrice
+	}
+	return m
+}
+
+// Catalog merges the built-in table, provider listings and overrides. It is
+// safe for concurrent use.
+type Catalog struct {
+	mu        sync.RWMutex
+	builtin   map[string]Model
+	listed    map[string]map[string]Model // provider -> id -> model
+	fetched   map[string]time.Time        // provider -> when its listing was fetched
+	overrides map[string]Model
+	path      string // cache file Save writes; set by Load
+}
+
+// New returns a catalog over the given built-in models.
+func New(builtin []Model) *Catalog {
+	c := &Catalog{
+		builtin:   make(map[string]Model, len(builtin)),
+		listed:    make(map[string]map[string]Model),
+		fetched:   make(map[string]time.Time),
+		overrides: make(map[string]Model),
+	}
+	for _, m := range builtin {
+		c.builtin[m.ID] = m
+	}
+	return c
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+)
+
+// Default returns the process-wide catalog. It starts with the built-in
+// table only; config loading adds the cache and the overrides.
+func Default() *Catalog {
+	defaultOnce.Do(func() { defaultCatalog = New(builtinModels) })
+	return defaultCatalog
+}
+
+// DefaultPath is where provider listings are cached: ~/.celeste/models.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(home, ".celeste", "models.json"), nil
+}
+
+// cacheFile is the on-disk form of the provider listings.
+type cacheFile struct {
+	Providers map[string]cachedListing `json:"providers"`
+}
+
+type cachedListing struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Models    []Model   `json:"models"`
+}
+
+// Load reads cached provider listings from path and remembers path for
+// Save. A missing file is not an error.
+func (c *Catalog) Load(path string) error {
+	c.mu.Lock()
+	c.path = path
+	c.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for provider, l := range f.Providers {
+		// Runtime discoveries made before the load stay on top.
+		models := make(map[string]Model, len(l.Models))
+		for _, m := range l.Models {
+			models[m.ID] = m
+		}
+		for id, m := range c.listed[provider] {
+			models[id] = models[id].merge(m)
+		}
+		c.listed[provider] = models
+		if c.fetched[provider].Before(l.FetchedAt) {
+			c.fetched[provider] = l.FetchedAt
+		}
+	}
+	return nil
+}
+
+// Save writes the provider listings to the file given to Load.
+func (c *Catalog) Save() error {
+	c.mu.RLock()
+	path := c.path
+	f := cacheFile{Providers: make(map[string]cachedListing, len(c.listed))}
+	for provider, models := range c.listed {
+		l := cachedListing{FetchedAt: c.fetched[provider]}
+		for _, m := range models {
+			l.Models = append(l.Models, m)
+		}
+		sort.Slice(l.Models, func(i, j int) bool { return l.Models[i].ID < l.Models[j].ID })
+		f.Providers[provider] = l
+	}
+	c.mu.RUnlock()
+
+	if path == "" {
+		return errors.New("catalog has no cache file; call Load first")
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SetListing replaces what provider's model endpoint reported and records
+// the time of the fetch.
+func (c *Catalog) SetListing(provider string, models []Model) {
+	listing := make(map[string]Model, len(models))
+	for _, m := range models {
+		if m.ID == "" {
+			continue
+		}
+		m.Provider = provider
+		listing[m.ID] = m
+	}
+	c.mu.Lock()
+	c.listed[provider] = listing
+	c.fetched[provider] = time.Now()
+	c.mu.Unlock()
+}
+
+// Add merges one model a provider reported at runtime (a local server's
+// context window, say) into its listing.
+func (c *Catalog) Add(provider string, m Model) {
+	if m.ID == "" {
+		return
+	}
+	m.Provider = provider
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.listed[provider] == nil {
+		c.listed[provider] = make(map[string]Model)
+	}
+	c.listed[provider][m.ID] = c.listed[provider][m.ID].merge(m)
+}
+
+// SetOverrides replaces the per-model overrides from the config.
+func (c *Catalog) SetOverrides(overrides map[string]Model) {
+	m := make(map[string]Model, len(overrides))
+	for id, o := range overrides {
+		o.ID = id
+		m[id] = o
+	}
+	c.mu.Lock()
+	c.overrides = m
+	c.mu.Unlock()
+}
+
+// FetchedAt returns when provider's listing was last fetched (zero if never).
+func (c *Catalog) FetchedAt(provider string) time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fetched[provider]
+}
+
+// Stale reports whether provider's listing is missing or older than
+// RefreshInterval.
+func (c *Catalog) Stale(provider string) bool {
+	fetched := c.FetchedAt(provider)
+	return fetched.IsZero() || time.Since(fetched) > RefreshInterval
+}
+
+// Lookup returns everything known about a model, from whichever provider
+// lists it. OpenRouter-style "vendor/model" and ":variant" forms fall back
+// to the bare model. ok is false when no layer knows the model.
+func (c *Catalog) Lookup(id string) (Model, bool) {
+	return c.lookup("", id)
+}
+
+// LookupFor is Lookup restricted to what is known about the model as
+// provider serves it. The same model ID can behave differently elsewhere
+// (an agent endpoint that runs tools server-side, say); overrides apply
+// regardless.
+func (c *Catalog) LookupFor(provider, id string) (Model, bool) {
+	return c.lookup(provider, id)
+}
+
+func (c *Catalog) lookup(provider, id string) (Model, bool) {
+	if id == "" {
+		return Model{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, key := range candidateIDs(id) {
+		m, found := Model{ID: id}, false
+		if b, ok := c.builtin[key]; ok && (provider == "" || b.Provider == "" || b.Provider == provider) {
+			m, found = m.merge(b), true
+		}
+		if l, ok := c.listedModel(provider, key); ok {
+			m, found = m.merge(l), true
+		}
+		if o, ok := c.overrides[key]; ok {
+			m, found = m.merge(o), true
+		}
+		if found {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// listedModel finds key in provider's listing, or in any listing (in
+// provider name order) when provider is empty.
+func (c *Catalog) listedModel(provider, key string) (Model, bool) {
+	if provider != "" {
+		m, ok := c.listed[provider][key]
+		return m, ok
+	}
+	names := make([]string, 0, len(c.listed))
+	for p := range c.listed {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	for _, p := range names {
+		if m, ok := c.listed[p][key]; ok {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// candidateIDs returns the keys tried for id, most specific first.
+func candidateIDs(id string) []string {
+	ids := []string{id}
+	add := func(s string) {
+		for _, have := range ids {
+			if have == s {
+				return
+			}
+		}
+		ids = append(ids, s)
+	}
+	lower := strings.ToLower(id)
+	add(lower)
+	if i := strings.IndexByte(lower, ':'); i > 0 {
+		lower = lower[:i]
+		add(lower)
+	}
+	// "vendor/model" as OpenRouter names them. Paths (local model servers
+	// name models after files) are left alone.
+	if strings.Count(lower, "/") == 1 && !strings.HasPrefix(lower, "/") && !strings.HasPrefix(lower, ".") {
+		add(lower[strings.IndexByte(lower, '/')+1:])
+	}
+	return ids
+}
+
+// Models returns every model known for provider (all providers when empty),
+// merged across layers and sorted by ID.
+func (c *Catalog) Models(provider string) []Model {
+	c.mu.RLock()
+	ids := make(map[string]bool)
+	for id, m := range c.builtin {
+		if provider == "" || m.Provider == provider {
+			ids[id] = true
+		}
+	}
+	for p, models := range c.listed {
+		if provider != "" && p != provider {
+			continue
+		}
+		for id := range models {
+			ids[id] = true
+		}
+	}
+	c.mu.RUnlock()
+
+	out := make([]Model, 0, len(ids))
+	for id := range ids {
+		if m, ok := c.lookup(provider, id); ok {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
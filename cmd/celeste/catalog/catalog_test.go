@@ -0,0 +1,91 @@
+package catalog
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupMergesLayers(t *testing.T) {
+	c := New([]Model{{ID: "m1", Provider: "p", ContextWindow: 8000, Tools: yes, InputPrice: 1, OutputPrice: 2}})
+	c.SetListing("p", []Model{{ID: "m1", ContextWindow: 32000, CachedInputPrice: 0.1}})
+	c.SetOverrides(map[string]Model{"m1": {Tools: no}})
+
+	m, ok := c.Lookup("m1")
+	require.True(t, ok)
+	assert.Equal(t, 32000, m.ContextWindow, "listing beats the built-in table")
+	assert.Equal(t, 1.0, m.InputPrice, "fields the listing leaves out are kept")
+	assert.Equal(t, 0.1, m.CachedInputPrice)
+	require.NotNil(t, m.Tools)
+	assert.False(t, *m.Tools, "override beats everything")
+
+	_, ok = c.Lookup("m2")
+	assert.False(t, ok)
+}
+
+func TestLookupCandidates(t *testing.T) {
+	c := New([]Model{{ID: "claude-sonnet-4-6", ContextWindow: 1000000}})
+	for _, id := range []string{"anthropic/claude-sonnet-4-6", "Claude-Sonnet-4-6", "claude-sonnet-4-6:beta"} {
+		m, ok := c.Lookup(id)
+		assert.True(t, ok, id)
+		assert.Equal(t, 1000000, m.ContextWindow, id)
+	}
+	_, ok := c.Lookup("/models/claude-sonnet-4-6")
+	assert.False(t, ok, "a model path is not vendor/model")
+}
+
+func TestLookupForProvider(t *testing.T) {
+	c := New([]Model{{ID: "gpt-4.1-nano", Provider: "openai", Tools: yes}})
+	_, ok := c.LookupFor("digitalocean", "gpt-4.1-nano")
+	assert.False(t, ok, "another provider's facts do not apply")
+
+	c.SetOverrides(map[string]Model{"gpt-4.1-nano": {ContextWindow: 1000}})
+	m, ok := c.LookupFor("digitalocean", "gpt-4.1-nano")
+	assert.True(t, ok, "overrides apply everywhere")
+	assert.Nil(t, m.Tools)
+	assert.Equal(t, 1000, m.ContextWindow)
+}
+
+func TestAddMergesRuntimeDiscoveries(t *testing.T) {
+	c := New(nil)
+	c.Add("ollama", Model{ID: "qwen3:8b", ContextWindow: 40960})
+	c.Add("ollama", Model{ID: "qwen3:8b", Tools: yes})
+	m, ok := c.LookupFor("ollama", "qwen3:8b")
+	require.True(t, ok)
+	assert.Equal(t, 40960, m.ContextWindow)
+	assert.True(t, *m.Tools)
+	assert.True(t, c.Stale("ollama"), "runtime discoveries are not a fetched listing")
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	c := New(nil)
+	require.NoError(t, c.Load(path), "a missing cache is not an error")
+	c.SetListing("openrouter", []Model{{ID: "x/y", ContextWindow: 4096, Vision: yes}})
+	require.NoError(t, c.Save())
+
+	loaded := New(nil)
+	require.NoError(t, loaded.Load(path))
+	m, ok := loaded.LookupFor("openrouter", "x/y")
+	require.True(t, ok)
+	assert.Equal(t, 4096, m.ContextWindow)
+	assert.True(t, *m.Vision)
+	assert.False(t, loaded.Stale("openrouter"))
+	assert.WithinDuration(t, time.Now(), loaded.FetchedAt("openrouter"), time.Minute)
+}
+
+func TestBuiltinTable(t *testing.T) {
+	seen := map[string]bool{}
+	for _, m := range builtinModels {
+		assert.False(t, seen[m.ID], "duplicate entry %s", m.ID)
+		seen[m.ID] = true
+		assert.NotEmpty(t, m.Provider, m.ID)
+		assert.Positive(t, m.ContextWindow, m.ID)
+		if m.CachedInputPrice > 0 {
+			assert.Less(t, m.CachedInputPrice, m.InputPrice, "%s: cache reads cost less than input", m.ID)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package checkpoints
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ShadowCheckpoint is one whole-workspace snapshot in a ShadowRepo.
+type ShadowCheckpoint struct {
+	ID        int       `json:"id"`
+	Commit    string    `json:"commit"`
+	Tree      string    `json:"tree"`
+	Label     string    `json:"label"`
+	Session   string    `json:"session,omitempty"`
+	Turn      int       `json:"turn,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Skipped lists nested git repositories, relative to the workspace,
+	// that the snapshot left out. Their contents are neither captured nor
+	// restored.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// ShadowRepo snapshots an entire workspace into a private git object store.
+//
+// Unlike SnapshotManager, which only copies files the edit tools name, a
+// shadow checkpoint captures everything on disk — including what bash
+// commands, code generators and package managers changed — so restoring a
+// turn really restores the workspace. The store is a separate git directory
+// under ~/.celeste/checkpoints/shadow/<workspace-hash>/ driven through
+// GIT_DIR/GIT_WORK_TREE, so the user's own repository, index and history are
+// never touched. Files excluded by .gitignore are neither captured nor
+// restored, and neither are nested git repositories: git would only record
+// the commit they have checked out, so they are left out and reported in
+// ShadowCheckpoint.Skipped instead.
+//
+// Several celeste processes can share a store (a chat session, an agent run
+// and `celeste revert` in the same workspace), so every operation holds a
+// lock file in the store as well as mu.
+type ShadowRepo struct {
+	workspace string
+	baseDir   string // ~/.celeste/checkpoints/shadow/<hash>/
+	gitDir    string // baseDir/git
+	mu        sync.Mutex
+}
+
+// NewShadowRepo opens (creating if needed) the shadow store for workspace.
+func NewShadowRepo(workspace string) (*ShadowRepo, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("checkpoints: home dir: %w", err)
+	}
+	abs, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoints: workspace path: %w", err)
+	}
+	h := sha256.Sum256([]byte(abs))
+	return newShadowRepoWithBase(abs, filepath.Join(home, ".celeste", "checkpoints", "shadow", fmt.Sprintf("%x", h[:8])))
+}
+
+// newShadowRepoWithBase is an internal constructor for testing with a custom base directory.
+func newShadowRepoWithBase(workspace, baseDir string) (*ShadowRepo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("checkpoints: shadow checkpoints need git on PATH: %w", err)
+	}
+	r := &ShadowRepo{
+		workspace: workspace,
+		baseDir:   baseDir,
+		gitDir:    filepath.Join(baseDir, "git"),
+	}
+	if err := os.MkdirAll(r.gitDir, 0700); err != nil {
+		return nil, fmt.Errorf("checkpoints: create shadow store: %w", err)
+	}
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if _, err := os.Stat(filepath.Join(r.gitDir, "HEAD")); os.IsNotExist(err) {
+		if _, err := r.git("init", "-q"); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// lock takes mu and the store's lock file, waiting for any other process
+// using the store, and returns the function that releases both.
+func (r *ShadowRepo) lock() (func(), error) {
+	r.mu.Lock()
+	f, err := os.OpenFile(filepath.Join(r.baseDir, "lock"), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("checkpoints: lock shadow store: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		r.mu.Unlock()
+		return nil, fmt.Errorf("checkpoints: lock shadow store: %w", err)
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+		r.mu.Unlock()
+	}, nil
+}
+
+// Workspace returns the directory this repo snapshots.
+func (r *ShadowRepo) Workspace() string {
+	return r.workspace
+}
+
+// Checkpoint snapshots the current workspace. session and turn identify the
+// turn boundary the snapshot belongs to; label is free text shown by List.
+// A workspace that has not changed since the previous checkpoint reuses its
+// commit, so checkpointing every turn costs almost nothing when idle.
+func (r *ShadowRepo) Checkpoint(session string, turn int, label string) (*ShadowCheckpoint, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.checkpointLocked(session, turn, label)
+}
+
+func (r *ShadowRepo) checkpointLocked(session string, turn int, label string) (*ShadowCheckpoint, error) {
+	tree, skipped, err := r.captureTree()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	cp := ShadowCheckpoint{
+		ID:        1,
+		Tree:      tree,
+		Label:     label,
+		Session:   session,
+		Turn:      turn,
+		Timestamp: time.Now(),
+		Skipped:   skipped,
+	}
+	var parent *ShadowCheckpoint
+	if len(list) > 0 {
+		parent = &list[len(list)-1]
+		cp.ID = parent.ID + 1
+	}
+
+	if parent != nil && parent.Tree == tree {
+		cp.Commit = parent.Commit
+	} else {
+		args := []string{"commit-tree", tree, "-m", fmt.Sprintf("checkpoint %d: %s", cp.ID, label)}
+		if parent != nil {
+			args = append(args, "-p", parent.Commit)
+		}
+		commit, err := r.git(args...)
+		if err != nil {
+			return nil, err
+		}
+		cp.Commit = commit
+		// Keep the chain reachable so a `git gc` in the store never prunes it.
+		if _, err := r.git("update-ref", "refs/heads/checkpoints", commit); err != nil {
+			return nil, err
+		}
+	}
+
+	list = append(list, cp)
+	if err := r.saveManifest(list); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// List returns every checkpoint, oldest first.
+func (r *ShadowRepo) List() ([]ShadowCheckpoint, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.loadManifest()
+}
+
+// Get returns the checkpoint with the given ID.
+func (r *ShadowRepo) Get(id int) (*ShadowCheckpoint, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.getLocked(id)
+}
+
+func (r *ShadowRepo) getLocked(id int) (*ShadowCheckpoint, error) {
+	list, err := r.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].ID == id {
+			return &list[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no checkpoint #%d", id)
+}
+
+// Diff returns a unified diff from checkpoint from to checkpoint to. A to of
+// 0 compares against the workspace as it is right now.
+func (r *ShadowRepo) Diff(from, to int) (string, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	a, b, err := r.resolvePair(from, to)
+	if err != nil {
+		return "", err
+	}
+	return r.git("diff", "--no-color", "--no-ext-diff", a, b)
+}
+
+// Changes returns per-file line stats between two checkpoints (to 0 = the
+// current workspace), in the same shape SnapshotManager.GetChanges reports.
+func (r *ShadowRepo) Changes(from, to int) ([]FileChange, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	a, b, err := r.resolvePair(from, to)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.git("diff", "--numstat", "--no-renames", a, b)
+	if err != nil {
+		return nil, err
+	}
+	added, err := r.git("diff", "--name-only", "--diff-filter=A", a, b)
+	if err != nil {
+		return nil, err
+	}
+	isNew := make(map[string]bool)
+	for _, p := range strings.Split(added, "\n") {
+		if p != "" {
+			isNew[p] = true
+		}
+	}
+
+	var changes []FileChange
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// Binary files report "-" for both counts.
+		ins, _ := strconv.Atoi(fields[0])
+		del, _ := strconv.Atoi(fields[1])
+		changes = append(changes, FileChange{
+			Path:       filepath.Join(r.workspace, fields[2]),
+			Insertions: ins,
+			Deletions:  del,
+			IsNew:      isNew[fields[2]],
+		})
+	}
+	return changes, nil
+}
+
+// Restore rewinds the workspace to checkpoint id: modified files are
+// rewritten, deleted files come back and files created since are removed.
+// Ignored files and nested repositories are left alone. The current state is checkpointed first and
+// returned, so a restore can itself be undone.
+func (r *ShadowRepo) Restore(id int) (*ShadowCheckpoint, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	target, err := r.getLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	// checkpointLocked leaves the shadow index matching the workspace, which
+	// is what lets read-tree work out which files to delete.
+	safety, err := r.checkpointLocked("", 0, fmt.Sprintf("before restore to #%d", id))
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint before restore: %w", err)
+	}
+	if _, err := r.git("read-tree", "--reset", "-u", target.Tree); err != nil {
+		return safety, fmt.Errorf("restore checkpoint #%d: %w", id, err)
+	}
+	return safety, nil
+}
+
+// resolvePair maps checkpoint IDs to tree hashes; to == 0 captures the
+// current workspace.
+func (r *ShadowRepo) resolvePair(from, to int) (string, string, error) {
+	a, err := r.getLocked(from)
+	if err != nil {
+		return "", "", err
+	}
+	if to == 0 {
+		tree, _, err := r.captureTree()
+		if err != nil {
+			return "", "", err
+		}
+		return a.Tree, tree, nil
+	}
+	b, err := r.getLocked(to)
+	if err != nil {
+		return "", "", err
+	}
+	return a.Tree, b.Tree, nil
+}
+
+// captureTree stages the whole workspace into the shadow index and writes
+// it as a tree object, returning the nested repositories it left out. The
+// index keeps stat data between calls, so only files that changed since the
+// last capture are re-hashed.
+func (r *ShadowRepo) captureTree() (string, []string, error) {
+	nested, err := r.nestedRepos()
+	if err != nil {
+		return "", nil, err
+	}
+	// Added as-is, a nested repository would become a gitlink, and one with
+	// no commits yet would fail the whole add.
+	args := []string{"add", "--all", "--", "."}
+	for _, dir := range nested {
+		args = append(args, ":(exclude,literal)"+dir)
+	}
+	if _, err := r.git(args...); err != nil {
+		return "", nil, err
+	}
+	tree, err := r.git("write-tree")
+	if err != nil {
+		return "", nil, err
+	}
+	return tree, nested, nil
+}
+
+// nestedRepos returns the untracked nested git repositories in the
+// workspace, which git lists as directories among the untracked files.
+func (r *ShadowRepo) nestedRepos() ([]string, error) {
+	out, err := r.git("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, p := range strings.Split(out, "\x00") {
+		if dir, ok := strings.CutSuffix(p, "/"); ok {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+func (r *ShadowRepo) manifestPath() string {
+	return filepath.Join(r.baseDir, "checkpoints.json")
+}
+
+func (r *ShadowRepo) loadManifest() ([]ShadowCheckpoint, error) {
+	data, err := os.ReadFile(r.manifestPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checkpoints: read manifest: %w", err)
+	}
+	var list []ShadowCheckpoint
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("checkpoints: parse manifest: %w", err)
+	}
+	return list, nil
+}
+
+func (r *ShadowRepo) saveManifest(list []ShadowCheckpoint) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("checkpoints: marshal manifest: %w", err)
+	}
+	tmp := r.manifestPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("checkpoints: write manifest: %w", err)
+	}
+	return os.Rename(tmp, r.manifestPath())
+}
+
+// git runs a git command against the shadow store with the workspace as its
+// work tree. Inherited GIT_* variables are dropped so a caller running inside
+// a git hook (GIT_DIR/GIT_INDEX_FILE set) cannot redirect writes into the
+// user's real repository.
+func (r *ShadowRepo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-c", "core.autocrlf=false", "-c", "core.quotepath=false"}, args...)...)
+	cmd.Dir = r.workspace
+
+	env := make([]string, 0, len(os.Environ())+6)
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "GIT_") {
+			continue
+		}
+		env = append(env, kv)
+	}
+	cmd.Env = append(env,
+		"GIT_DIR="+r.gitDir,
+		"GIT_WORK_TREE="+r.workspace,
+		"GIT_AUTHOR_NAME=celeste",
+		"GIT_AUTHOR_EMAIL=checkpoints@celeste.local",
+		"GIT_COMMITTER_NAME=celeste",
+		"GIT_COMMITTER_EMAIL=checkpoints@celeste.local",
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("checkpoints: git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,251 @@
+package checkpoints
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestShadowRepo(t *testing.T) (*ShadowRepo, string) {
+	t.Helper()
+	ws := t.TempDir()
+	repo, err := newShadowRepoWithBase(ws, t.TempDir())
+	if err != nil {
+		t.Skipf("git unavailable: %v", err)
+	}
+	return repo, ws
+}
+
+func TestShadowCheckpointCapturesUntrackedChanges(t *testing.T) {
+	repo, ws := newTestShadowRepo(t)
+	main := filepath.Join(ws, "main.go")
+	os.WriteFile(main, []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(ws, ".gitignore"), []byte("build/\n"), 0644)
+
+	first, err := repo.Checkpoint("s1", 1, "turn 1")
+	if err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+
+	// Simulate a bash tool: edit one file, create another, touch an ignored dir.
+	os.WriteFile(main, []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "gen.go"), []byte("package main\n"), 0644)
+	os.MkdirAll(filepath.Join(ws, "build"), 0755)
+	os.WriteFile(filepath.Join(ws, "build", "out.bin"), []byte("binary"), 0644)
+
+	changes, err := repo.Changes(first.ID, 0)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changed files, got %+v", changes)
+	}
+	for _, c := range changes {
+		if strings.HasSuffix(c.Path, "gen.go") && !c.IsNew {
+			t.Errorf("gen.go should be reported as new")
+		}
+	}
+
+	diff, err := repo.Diff(first.ID, 0)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !strings.Contains(diff, "+func main() {}") {
+		t.Errorf("diff missing edit:\n%s", diff)
+	}
+
+	safety, err := repo.Restore(first.ID)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if safety.ID != first.ID+1 {
+		t.Errorf("expected safety checkpoint #%d, got #%d", first.ID+1, safety.ID)
+	}
+
+	data, _ := os.ReadFile(main)
+	if string(data) != "package main\n" {
+		t.Errorf("main.go not restored: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "gen.go")); !os.IsNotExist(err) {
+		t.Error("file created after the checkpoint should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(ws, "build", "out.bin")); err != nil {
+		t.Error("ignored files must be left alone")
+	}
+
+	// The safety checkpoint brings the edits back.
+	if _, err := repo.Restore(safety.ID); err != nil {
+		t.Fatalf("Restore safety: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "gen.go")); err != nil {
+		t.Error("restoring the safety checkpoint should recreate gen.go")
+	}
+}
+
+func TestShadowCheckpointReusesUnchangedTree(t *testing.T) {
+	repo, ws := newTestShadowRepo(t)
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("a"), 0644)
+
+	first, err := repo.Checkpoint("s1", 1, "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Checkpoint("s1", 2, "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Commit != second.Commit {
+		t.Errorf("unchanged workspace should reuse commit: %s vs %s", first.Commit, second.Commit)
+	}
+
+	list, err := repo.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[1].Turn != 2 {
+		t.Fatalf("unexpected manifest: %+v", list)
+	}
+	if _, err := repo.Get(99); err == nil {
+		t.Error("expected error for unknown checkpoint")
+	}
+}
+
+// gitIn runs git in dir with the environment cleared of GIT_* overrides.
+func gitIn(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestShadowCheckpointSkipsNestedRepos(t *testing.T) {
+	repo, ws := newTestShadowRepo(t)
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("a"), 0644)
+	// A freshly cloned-into or init'ed dependency with no commits yet.
+	nested := filepath.Join(ws, "third_party", "lib")
+	os.MkdirAll(nested, 0755)
+	gitIn(t, nested, "init", "-q")
+	os.WriteFile(filepath.Join(nested, "lib.go"), []byte("package lib\n"), 0644)
+
+	first, err := repo.Checkpoint("s1", 1, "one")
+	if err != nil {
+		t.Fatalf("Checkpoint with a nested repo: %v", err)
+	}
+	if len(first.Skipped) != 1 || first.Skipped[0] != "third_party/lib" {
+		t.Errorf("expected third_party/lib to be skipped, got %v", first.Skipped)
+	}
+
+	os.WriteFile(filepath.Join(nested, "lib.go"), []byte("package lib\n\nvar X = 1\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("b"), 0644)
+	if _, err := repo.Restore(first.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "a.txt")); string(data) != "a" {
+		t.Errorf("a.txt not restored: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(nested, "lib.go")); !strings.Contains(string(data), "X = 1") {
+		t.Errorf("restore must leave the nested repo alone, got %q", data)
+	}
+}
+
+func TestShadowCheckpointLeavesUserRepoAlone(t *testing.T) {
+	repo, ws := newTestShadowRepo(t)
+	gitIn(t, ws, "init", "-q")
+	os.WriteFile(filepath.Join(ws, "tracked.txt"), []byte("one"), 0644)
+	gitIn(t, ws, "add", "tracked.txt")
+	gitIn(t, ws, "commit", "-q", "-m", "initial")
+	os.WriteFile(filepath.Join(ws, "tracked.txt"), []byte("two"), 0644)
+	gitIn(t, ws, "add", "tracked.txt") // a staged change the user is working on
+	os.WriteFile(filepath.Join(ws, "untracked.txt"), []byte("u"), 0644)
+
+	snapshot := func() map[string]string {
+		state := map[string]string{}
+		for _, name := range []string{"HEAD", "index"} {
+			data, err := os.ReadFile(filepath.Join(ws, ".git", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			state[name] = string(data)
+		}
+		state["rev"] = gitIn(t, ws, "rev-parse", "HEAD")
+		return state
+	}
+	before := snapshot()
+
+	first, err := repo.Checkpoint("s1", 1, "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(ws, "new.txt"), []byte("n"), 0644)
+	if _, err := repo.Checkpoint("s1", 2, "two"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Restore(first.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	after := snapshot()
+	for k, v := range before {
+		if after[k] != v {
+			t.Errorf("user's %s changed by Checkpoint/Restore", k)
+		}
+	}
+	if files, _ := repo.git("ls-tree", "-r", "--name-only", first.Tree); strings.Contains(files, ".git/") {
+		t.Errorf("the user's .git directory was captured:\n%s", files)
+	}
+}
+
+func TestShadowRepoLocksAcrossInstances(t *testing.T) {
+	ws, base := t.TempDir(), t.TempDir()
+	a, err := newShadowRepoWithBase(ws, base)
+	if err != nil {
+		t.Skipf("git unavailable: %v", err)
+	}
+	// A second instance stands in for another celeste process: it shares
+	// the store but not the mutex.
+	b, err := newShadowRepoWithBase(ws, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("a"), 0644)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 5; i++ {
+		for _, r := range []*ShadowRepo{a, b} {
+			wg.Add(1)
+			go func(r *ShadowRepo, i int) {
+				defer wg.Done()
+				_, err := r.Checkpoint("s", i, "concurrent")
+				errs <- err
+			}(r, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent Checkpoint: %v", err)
+		}
+	}
+
+	list, err := a.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 10 {
+		t.Fatalf("expected 10 checkpoints in the manifest, got %d", len(list))
+	}
+	for i, cp := range list {
+		if cp.ID != i+1 {
+			t.Errorf("checkpoint %d has ID %d: IDs collided", i, cp.ID)
+		}
+	}
+}
@@ -66,6 +66,12 @@ func bm25Idf(df, numDocs int) float64 {
 	// preserved under monotonic transforms).
 }
 
+// BM25IDF exposes the corpus IDF formula for callers that score small
+// in-memory corpora (e.g. memories) without a Store behind them.
+func BM25IDF(df, numDocs int) float64 {
+	return bm25Idf(df, numDocs)
+}
+
 // ComputeBM25Score computes the BM25 score for a single symbol against
 // a query.
 //
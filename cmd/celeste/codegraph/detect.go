@@ -11,6 +11,7 @@ var extensionToLanguage = map[string]string{
 	".go":    "go",
 	".py":    "python",
 	".pyi":   "python",
+	".ipynb": "python", // code cells; see NotebookScript
 	".js":    "javascript",
 	".jsx":   "javascript",
 	".mjs":   "javascript",
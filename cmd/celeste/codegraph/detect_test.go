@@ -15,6 +15,7 @@ func TestDetectLanguage(t *testing.T) {
 	}{
 		{"main.go", "go"},
 		{"app.py", "python"},
+		{"analysis.ipynb", "python"},
 		{"index.js", "javascript"},
 		{"server.ts", "typescript"},
 		{"component.tsx", "typescript"},
@@ -52,6 +53,7 @@ func TestIsIndexableFile(t *testing.T) {
 	assert.True(t, IsIndexableFile("main.go"))
 	assert.True(t, IsIndexableFile("app.py"))
 	assert.True(t, IsIndexableFile("server.ts"))
+	assert.True(t, IsIndexableFile("analysis.ipynb"))
 	assert.False(t, IsIndexableFile("image.png"))
 	assert.False(t, IsIndexableFile("data.bin"))
 	assert.False(t, IsIndexableFile(".gitignore"))
@@ -353,25 +353,39 @@ func (idx *Indexer) indexFileSymbols(relPath string) ([]RawEdge, error) {
 	absPath := filepath.Join(idx.workspace, relPath)
 	lang := DetectLanguage(relPath)
 
+	// Notebooks are indexed through their code cells' script.
+	parsePath := absPath
+	if IsNotebook(relPath) {
+		script, cleanup, err := notebookParsePath(absPath)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		if script == "" {
+			return nil, nil // not a Python notebook
+		}
+		parsePath = script
+	}
+
 	var result *ParseResult
 	var err error
 
 	if lang == "go" {
 		parser := NewGoParser()
-		result, err = parser.ParseFile(absPath)
-	} else if idx.tryMultiParser(absPath) {
+		result, err = parser.ParseFile(parsePath)
+	} else if idx.tryMultiParser(parsePath) {
 		if idx.multiParser == nil {
 			idx.multiParser = NewMultiLangParser()
 		}
-		result, err = idx.multiParser.ParseFile(absPath)
+		result, err = idx.multiParser.ParseFile(parsePath)
 	} else if lang == "typescript" {
 		if idx.tsParser == nil {
 			idx.tsParser = NewTSParser()
 		}
-		result, err = idx.tsParser.ParseFile(absPath)
+		result, err = idx.tsParser.ParseFile(parsePath)
 	} else if indexableLanguages[lang] {
 		parser := NewGenericParser(lang)
-		result, err = parser.ParseFile(absPath)
+		result, err = parser.ParseFile(parsePath)
 	} else {
 		return nil, nil // no parser for this language
 	}
@@ -380,7 +394,7 @@ func (idx *Indexer) indexFileSymbols(relPath string) ([]RawEdge, error) {
 		return nil, err
 	}
 
-	source, _ := os.ReadFile(absPath)
+	source, _ := os.ReadFile(parsePath)
 
 	for _, sym := range result.Symbols {
 		sym.File = relPath
@@ -439,28 +453,42 @@ func (idx *Indexer) indexFile(relPath string) error {
 	absPath := filepath.Join(idx.workspace, relPath)
 	lang := DetectLanguage(relPath)
 
+	// Notebooks are indexed through their code cells' script.
+	parsePath := absPath
+	if IsNotebook(relPath) {
+		script, cleanup, err := notebookParsePath(absPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if script == "" {
+			return nil // not a Python notebook
+		}
+		parsePath = script
+	}
+
 	var result *ParseResult
 	var err error
 
 	if lang == "go" {
 		// Go uses its own AST parser (go/parser, not tree-sitter)
 		parser := NewGoParser()
-		result, err = parser.ParseFile(absPath)
-	} else if idx.tryMultiParser(absPath) {
+		result, err = parser.ParseFile(parsePath)
+	} else if idx.tryMultiParser(parsePath) {
 		// Multi-language tree-sitter parser (Python, Rust, Java, C, C++, etc.)
 		if idx.multiParser == nil {
 			idx.multiParser = NewMultiLangParser()
 		}
-		result, err = idx.multiParser.ParseFile(absPath)
+		result, err = idx.multiParser.ParseFile(parsePath)
 	} else if lang == "typescript" {
 		// Dedicated TS parser (preserves existing behavior for TS-only builds)
 		if idx.tsParser == nil {
 			idx.tsParser = NewTSParser()
 		}
-		result, err = idx.tsParser.ParseFile(absPath)
+		result, err = idx.tsParser.ParseFile(parsePath)
 	} else if indexableLanguages[lang] {
 		parser := NewGenericParser(lang)
-		result, err = parser.ParseFile(absPath)
+		result, err = parser.ParseFile(parsePath)
 	} else {
 		return nil // no parser for this language
 	}
@@ -470,7 +498,7 @@ func (idx *Indexer) indexFile(relPath string) error {
 	}
 
 	// Read source for shingle generation
-	source, _ := os.ReadFile(absPath)
+	source, _ := os.ReadFile(parsePath)
 
 	// Store symbols and compute MinHash
 	symbolIDs := make(map[string]int64) // name -> ID
@@ -1056,7 +1084,7 @@ func (idx *Indexer) FindLazyRedirects(maxResults int, includeTests bool) ([]Lazy
 		if !filepath.IsAbs(absFile) {
 			absFile = filepath.Join(idx.workspace, absFile)
 		}
-		sourceData, _ := os.ReadFile(absFile)
+		sourceData, _ := readSource(absFile)
 		sym := Symbol{Name: c.Name, Line: c.Line}
 		body := ""
 		lowerBody := ""
@@ -1216,7 +1244,7 @@ func (idx *Indexer) FindCodeSmells(kinds []CodeSmellKind, maxResults int, includ
 		}
 		sourceData, cached := fileCache[absFile]
 		if !cached {
-			data, err := os.ReadFile(absFile)
+			data, err := readSource(absFile)
 			if err != nil {
 				// STUB detection still works without source (graph-only)
 				if wantAll || wantKind[SmellStub] {
@@ -405,3 +405,44 @@ func writeFile(t *testing.T, dir, name, content string) {
 	err := os.WriteFile(path, []byte(content), 0644)
 	require.NoError(t, err)
 }
+
+func TestIndexer_IndexesNotebookCodeCells(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "analysis.ipynb", `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# def not_code():\n"]},
+  {"cell_type": "code", "execution_count": 1, "metadata": {}, "outputs": [],
+   "source": ["%matplotlib inline\n", "import pandas as pd\n"]},
+  {"cell_type": "code", "execution_count": 2, "metadata": {}, "outputs": [],
+   "source": "def load_sales(path):\n    return pd.read_csv(path)\n"}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`)
+	writeFile(t, dir, "report.ipynb", `{"cells": [{"cell_type": "code", "source": "function r_only() end"}],
+ "metadata": {"language_info": {"name": "julia"}}, "nbformat": 4, "nbformat_minor": 5}`)
+
+	idx, err := NewIndexer(dir, filepath.Join(dir, "codegraph.db"))
+	require.NoError(t, err)
+	defer idx.Close()
+	require.NoError(t, idx.Build())
+
+	syms, err := idx.Store().GetSymbolsByFile("analysis.ipynb")
+	require.NoError(t, err)
+	byName := make(map[string]Symbol)
+	for _, s := range syms {
+		byName[s.Name] = s
+	}
+	require.Contains(t, byName, "load_sales")
+	assert.NotContains(t, byName, "not_code", "markdown cells are not code")
+	assert.Equal(t, 6, byName["load_sales"].Line, "lines refer to the notebook's script")
+
+	script, err := NotebookScript([]byte(`{"cells": [{"cell_type": "code", "source": ["!pip install x\n", "x = 1"]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "# %% [0]\n# !pip install x\nx = 1\n\n", string(script))
+
+	other, err := idx.Store().GetSymbolsByFile("report.ipynb")
+	require.NoError(t, err)
+	assert.Empty(t, other, "non-Python notebooks are skipped")
+}
@@ -0,0 +1,110 @@
+package codegraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsNotebook reports whether path is a Jupyter notebook.
+func IsNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// NotebookScript returns the code cells of a Python notebook as one script
+// in the "percent" format used by jupytext: each cell starts with a
+// "# %% [N]" marker, N being the cell's index in the notebook. IPython
+// magics and shell escapes are commented out so parsers see plain Python.
+// Symbol lines for notebooks refer to this script. A notebook whose kernel
+// is not Python yields nil.
+func NotebookScript(data []byte) ([]byte, error) {
+	var nb struct {
+		Metadata struct {
+			Kernelspec struct {
+				Language string `json:"language"`
+			} `json:"kernelspec"`
+			LanguageInfo struct {
+				Name string `json:"name"`
+			} `json:"language_info"`
+		} `json:"metadata"`
+		Cells []struct {
+			CellType string          `json:"cell_type"`
+			Source   json.RawMessage `json:"source"`
+		} `json:"cells"`
+	}
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("parse notebook: %w", err)
+	}
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.Kernelspec.Language
+	}
+	if lang != "" && !strings.EqualFold(lang, "python") {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	for i, cell := range nb.Cells {
+		if cell.CellType != "code" {
+			continue
+		}
+		fmt.Fprintf(&b, "# %%%% [%d]\n", i)
+		for _, line := range strings.Split(strings.TrimSuffix(notebookSource(cell.Source), "\n"), "\n") {
+			if t := strings.TrimSpace(line); strings.HasPrefix(t, "%") || strings.HasPrefix(t, "!") {
+				line = "# " + line
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// notebookSource decodes a cell source, which nbformat allows as a string
+// or a list of lines.
+func notebookSource(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var lines []string
+	_ = json.Unmarshal(raw, &lines)
+	return strings.Join(lines, "")
+}
+
+// readSource returns the source the indexer parsed for absPath: the file
+// itself, or a notebook's script.
+func readSource(absPath string) ([]byte, error) {
+	data, err := os.ReadFile(absPath)
+	if err != nil || !IsNotebook(absPath) {
+		return data, err
+	}
+	return NotebookScript(data)
+}
+
+// notebookParsePath writes a notebook's script to a temporary .py file so
+// the path-based parsers can read it. The returned cleanup removes it. A
+// non-Python notebook returns an empty path.
+func notebookParsePath(absPath string) (string, func(), error) {
+	script, err := readSource(absPath)
+	if err != nil || script == nil {
+		return "", func() {}, err
+	}
+	f, err := os.CreateTemp("", "celeste-notebook-*.py")
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	_, err = f.Write(script)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return f.Name(), cleanup, nil
+}
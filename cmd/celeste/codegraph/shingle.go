@@ -58,6 +58,26 @@ func ShinglesForSymbol(sym Symbol, source []byte, lang string) []string {
 	return deduped
 }
 
+// TextTokens tokenizes free-form prose (memories, prompts, file paths) with
+// the same identifier splitting and universal stop-word filter the symbol
+// shingles use, so text and code share one vocabulary. Unlike the shingle
+// helpers it keeps duplicates: BM25 callers need term frequencies.
+// Single-character tokens are dropped.
+func TextTokens(text string) []string {
+	var tokens []string
+	for _, word := range identifierRegex.FindAllString(text, -1) {
+		for _, t := range splitIdentifier(word) {
+			if len(t) > 1 {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	if stopWords != nil {
+		tokens = stopWords.Filter(tokens, "")
+	}
+	return tokens
+}
+
 // splitIdentifier splits a camelCase, PascalCase, or snake_case identifier
 // into its constituent words, all lowercased. Compound identifiers
 // registered in stopwords.json (e.g. "jquery", "github", "mysql") are
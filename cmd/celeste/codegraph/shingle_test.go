@@ -117,3 +117,20 @@ func TestDeduplicateLowercase(t *testing.T) {
 	assert.Contains(t, result, "go")
 	assert.Len(t, result, 3)
 }
+
+func TestTextTokens(t *testing.T) {
+	tokens := TextTokens("Use parseJSON in config/loader.go, parseJSON twice; a x")
+
+	assert.Contains(t, tokens, "parse")
+	assert.Contains(t, tokens, "json")
+	assert.Contains(t, tokens, "loader")
+	assert.NotContains(t, tokens, "x", "single-character tokens are dropped")
+
+	count := 0
+	for _, tok := range tokens {
+		if tok == "json" {
+			count++
+		}
+	}
+	assert.Equal(t, 2, count, "duplicates are kept so BM25 sees term frequency")
+}
@@ -468,10 +468,15 @@ func listAvailableModels(ctx *CommandContext, caps providers.ProviderCapabilitie
 			badge = "✓"
 		}
 
+		description := model.Description
+		if details := providers.ModelDetails(model); details != "" {
+			description += " · " + details
+		}
+
 		selectorItems[i] = SelectorItem{
 			ID:          model.ID,
 			DisplayName: model.Name,
-			Description: model.Description,
+			Description: description,
 			Badge:       badge,
 		}
 	}
@@ -599,7 +604,7 @@ func handleConfig(cmd *Command) *CommandResult {
 				Message: fmt.Sprintf("❌ Failed to load config: %v", err),
 			}
 		}
-		cfg.BaseURL = url
+		cfg.SetBaseURL(url)
 		if err := config.Save(cfg); err != nil {
 			return &CommandResult{
 				Success: false, ShouldRender: true,
@@ -854,6 +859,8 @@ Chat:
   /agents            List spawned subagents and their status
   /agents resume <id> Resume a failed subagent from its last checkpoint
   /agents kill <id|name> Cancel a specific in-flight subagent (id, task id, or on-screen name)
+  /jobs              List background jobs and persistent shells
+  /jobs kill <id>    Stop a background job
   /voice             Show ElevenLabs TTS config
   /voice list        List available ElevenLabs voices
   /voice set-key     Set ElevenLabs API key
@@ -867,6 +874,7 @@ Chat:
 
 Project:
   /memories          List project memories
+  /memories why      Explain which memories the last request used
   /grimoire          Show project grimoire
   /index             Show code graph status
   /index rebuild     Full re-index (populates LSH + BM25)
@@ -891,6 +899,7 @@ Settings:
 
 Tools:
   /tools             Browse available tools interactively
+  /preview [n]       Show the latest generated images inline
 
 Examples:
   /agent fix tests       → Run autonomous code-fix loop
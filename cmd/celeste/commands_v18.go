@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,12 +27,29 @@ func runCostsCommand(args []string) {
 	fmt.Printf("Session Costs:\n")
 	fmt.Printf("  Model:    %s\n", summary.Model)
 	fmt.Printf("  Input:    %d tokens\n", summary.TotalInput)
+	if summary.TotalCached > 0 {
+		fmt.Printf("  Cached:   %d tokens\n", summary.TotalCached)
+	}
 	fmt.Printf("  Output:   %d tokens\n", summary.TotalOutput)
 	fmt.Printf("  Cost:     $%.4f\n", summary.TotalCostUSD)
 	fmt.Printf("  Turns:    %d\n", summary.Turns)
+	if len(summary.ByProvider) > 1 {
+		fmt.Printf("\nBy provider:\n")
+		for _, u := range summary.ByProvider {
+			fmt.Printf("  %-12s %-28s %4d turns  $%.4f\n", u.Provider, u.Model, u.Turns, u.CostUSD)
+		}
+	}
 }
 
 func runMemoriesCommand(args []string) {
+	if len(args) > 0 && args[0] == "extract" {
+		runMemoriesExtractCommand(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "verify" {
+		runMemoriesVerifyCommand()
+		return
+	}
 	cwd, _ := os.Getwd()
 	store := memories.NewStore(cwd)
 	mems, err := store.List()
@@ -46,6 +64,49 @@ func runMemoriesCommand(args []string) {
 	}
 }
 
+// runMemoriesVerifyCommand checks every memory's code references against the
+// workspace and the code graph index, recording the outcome in frontmatter.
+func runMemoriesVerifyCommand() {
+	cwd, _ := os.Getwd()
+	store := memories.NewStore(cwd)
+
+	var graph *codegraph.Store
+	indexPath := codegraph.DefaultIndexPath(cwd)
+	if _, err := os.Stat(indexPath); err == nil {
+		if graph, err = codegraph.NewStore(indexPath); err == nil {
+			defer graph.Close()
+		}
+	}
+
+	results, err := memories.NewVerifier(cwd, graph).VerifyStore(store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error verifying memories: %v\n", err)
+		os.Exit(1)
+	}
+	if len(results) == 0 {
+		fmt.Println("No memories found for this project.")
+		return
+	}
+	for _, res := range results {
+		switch res.Status {
+		case memories.StatusVerified:
+			fmt.Printf("  ✓ %s\n", res.Memory.Name)
+		case memories.StatusStale, memories.StatusBroken:
+			fmt.Printf("  ✗ %s (%s)\n", res.Memory.Name, res.Status)
+			for _, ref := range res.Missing() {
+				if ref.Suggestion != "" {
+					fmt.Printf("      %s %s not found, did you mean %s?\n", ref.Kind, ref.Value, ref.Suggestion)
+				} else {
+					fmt.Printf("      %s %s not found\n", ref.Kind, ref.Value)
+				}
+			}
+		default:
+			fmt.Printf("  - %s (no code references)\n", res.Memory.Name)
+		}
+	}
+	fmt.Println("\nFix or delete flagged memories with /memories in the TUI.")
+}
+
 func runRememberCommand(args []string) {
 	if len(args) == 0 {
 		fmt.Fprintln(os.Stderr, "Usage: celeste remember \"<text>\"")
@@ -136,11 +197,22 @@ func runPlanCommand(args []string) {
 func runRevertCommand(args []string) {
 	if len(args) == 0 {
 		fmt.Fprintln(os.Stderr, "Usage: celeste revert <file-path>")
+		fmt.Fprintln(os.Stderr, "       celeste revert --list")
+		fmt.Fprintln(os.Stderr, "       celeste revert --diff <id> [<id>]")
+		fmt.Fprintln(os.Stderr, "       celeste revert --to <id>")
 		fmt.Fprintln(os.Stderr, "\nReverts a file to its most recent checkpoint (pre-edit snapshot).")
 		fmt.Fprintln(os.Stderr, "Checkpoints are created automatically before each write_file/patch_file.")
+		fmt.Fprintln(os.Stderr, "With \"checkpoint_mode\": \"shadow\", --list/--diff/--to work on whole-workspace")
+		fmt.Fprintln(os.Stderr, "checkpoints taken at every turn, including changes made by bash.")
 		os.Exit(1)
 	}
 
+	switch args[0] {
+	case "--list", "--diff", "--to":
+		runShadowRevertCommand(args)
+		return
+	}
+
 	filePath := args[0]
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
@@ -159,6 +231,81 @@ func runRevertCommand(args []string) {
 	fmt.Printf("Reverted: %s\n", filePath)
 }
 
+// runShadowRevertCommand handles the workspace-checkpoint forms of
+// `celeste revert` for the current directory.
+func runShadowRevertCommand(args []string) {
+	cwd, _ := os.Getwd()
+	repo, err := checkpoints.NewShadowRepo(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ids := make([]int, 0, 2)
+	for _, a := range args[1:] {
+		id, err := strconv.Atoi(strings.TrimPrefix(a, "#"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid checkpoint id: %s\n", a)
+			os.Exit(1)
+		}
+		ids = append(ids, id)
+	}
+
+	switch args[0] {
+	case "--list":
+		list, err := repo.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(list) == 0 {
+			fmt.Println("No workspace checkpoints for this directory.")
+			fmt.Println("Set \"checkpoint_mode\": \"shadow\" in your config (or run `celeste agent --shadow-checkpoints`).")
+			return
+		}
+		fmt.Printf("Workspace checkpoints for %s:\n\n", repo.Workspace())
+		for _, cp := range list {
+			fmt.Printf("  #%-4d %s  %s  %s\n", cp.ID, cp.Timestamp.Format("2006-01-02 15:04:05"), cp.Commit[:8], cp.Label)
+		}
+
+	case "--diff":
+		if len(ids) == 0 || len(ids) > 2 {
+			fmt.Fprintln(os.Stderr, "Usage: celeste revert --diff <id> [<id>]")
+			os.Exit(1)
+		}
+		to := 0
+		if len(ids) == 2 {
+			to = ids[1]
+		}
+		diff, err := repo.Diff(ids[0], to)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if diff == "" {
+			fmt.Println("No differences.")
+			return
+		}
+		fmt.Println(diff)
+
+	case "--to":
+		if len(ids) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: celeste revert --to <id>")
+			os.Exit(1)
+		}
+		safety, err := repo.Restore(ids[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored workspace to checkpoint #%d.\n", ids[0])
+		fmt.Printf("Previous state saved as checkpoint #%d (`celeste revert --to %d` to go back).\n", safety.ID, safety.ID)
+		if len(safety.Skipped) > 0 {
+			fmt.Printf("Nested git repositories were left as they are: %s\n", strings.Join(safety.Skipped, ", "))
+		}
+	}
+}
+
 func runIndexCommand(args []string) {
 	cwd, _ := os.Getwd()
 
@@ -10,7 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/research"
 )
 
 // TarotConfig holds tarot function configuration.
@@ -84,6 +87,14 @@ const (
 	DefaultClawMaxToolIterations = 25        // safety cap for tool loop turns
 )
 
+// Checkpoint modes. "files" backs up only the files the edit tools touch;
+// "shadow" also snapshots the whole workspace into a private git store at
+// every turn, which catches changes made by bash commands.
+const (
+	CheckpointModeFiles  = "files"
+	CheckpointModeShadow = "shadow"
+)
+
 // Config holds all configuration for Celeste CLI.
 type Config struct {
 	// API settings
@@ -102,13 +113,49 @@ type Config struct {
 	GoogleCredentialsFile string `json:"google_credentials_file,omitempty"` // Path to service account JSON file
 	GoogleUseADC          bool   `json:"google_use_adc,omitempty"`          // Use Application Default Credentials
 
-	// Runtime-detected provider (not persisted to config file)
-	Provider string `json:"-"` // Detected from BaseURL at runtime
+	// OllamaKeepAlive is how long Ollama keeps the model loaded after a
+	// request ("10m", "1h", "-1" = forever, "0" = unload). Empty uses the
+	// server default (5m).
+	OllamaKeepAlive string `json:"ollama_keep_alive,omitempty"`
+
+	// OpenAIBuiltinTools enables OpenAI's hosted tools ("web_search",
+	// "code_interpreter") for models served through the Responses API. They
+	// run on OpenAI's side; celeste only shows that they ran.
+	OpenAIBuiltinTools []string `json:"openai_builtin_tools,omitempty"`
+
+	// DisablePromptCache stops celeste from asking providers to cache the
+	// repeated prompt prefix (Anthropic breakpoints, Gemini context caches).
+	// Caching is on by default; cache writes cost slightly more than plain
+	// input on Anthropic and Gemini bills cache storage by the hour.
+	DisablePromptCache bool `json:"disable_prompt_cache,omitempty"`
+
+	// BatchAPI names the batch protocol ("openai" or "anthropic") for a
+	// base URL celeste does not recognise, such as a proxy in front of
+	// either provider. OpenAI and Anthropic themselves are detected.
+	BatchAPI string `json:"batch_api,omitempty"`
+
+	// ModelOverrides corrects or extends the model catalog per model ID:
+	// {"my-finetune": {"context_window": 32768, "tools": true,
+	// "input_price": 0.5, "output_price": 1.5}}. Fields left out keep the
+	// built-in or provider-reported value.
+	ModelOverrides map[string]catalog.Model `json:"model_overrides,omitempty"`
+
+	// Provider names the registry entry behind BaseURL when the URL alone
+	// does not say: "ollama" for a native Ollama server that is not on port
+	// 11434. Left empty, the provider is detected from BaseURL. It describes
+	// one URL, so SetBaseURL clears it when the URL changes.
+	Provider string `json:"provider,omitempty"`
 
 	// Default marks this named config as the one loaded when no -config flag
 	// is given. Exactly one file should set it; the first match wins.
 	Default bool `json:"default,omitempty"`
 
+	// Extends names the profile this one inherits from ("default" is
+	// config.json). The file then holds only overrides: top-level keys
+	// replace the parent's, and objects merge key by key. Default is
+	// never inherited.
+	Extends string `json:"extends,omitempty"`
+
 	// Persona settings
 	SkipPersonaPrompt bool `json:"skip_persona_prompt"`
 
@@ -128,12 +175,41 @@ type Config struct {
 	RuntimeMode           string `json:"runtime_mode,omitempty"`             // "classic" or "claw"
 	ClawMaxToolIterations int    `json:"claw_max_tool_iterations,omitempty"` // Safety cap for repeated tool loops in claw mode
 
+	// CheckpointMode is "files" (default) or "shadow". See CheckpointModeShadow.
+	CheckpointMode string `json:"checkpoint_mode,omitempty"`
+
+	// Memory retrieval: how many project memories are attached to a request
+	// and their combined token cap. 0 uses memories.DefaultRetrieveTopK /
+	// DefaultRetrieveMaxTokens.
+	MemoryTopK      int `json:"memory_top_k,omitempty"`
+	MemoryMaxTokens int `json:"memory_max_tokens,omitempty"`
+	// MemoryExtraction runs `celeste memories extract` in the background when
+	// a chat session ends, proposing memories with the configured model.
+	MemoryExtraction bool `json:"memory_extraction,omitempty"`
+
 	// Venice.ai settings (for NSFW mode)
 	VeniceAPIKey     string `json:"venice_api_key,omitempty"`
 	VeniceBaseURL    string `json:"venice_base_url,omitempty"`
 	VeniceModel      string `json:"venice_model,omitempty"`       // Chat model (venice-uncensored)
 	VeniceImageModel string `json:"venice_image_model,omitempty"` // Image model (lustify-sdxl)
 
+	// Image generation for the generate_image/edit_image tools and `celeste
+	// image`. ImageBackend is "openai" or "venice"; empty picks openai when
+	// the chat provider is OpenAI, else venice when a Venice key is set.
+	// ImageAPIKey and ImageBaseURL default to that backend's chat settings.
+	ImageBackend string `json:"image_backend,omitempty"`
+	ImageModel   string `json:"image_model,omitempty"`
+	ImageAPIKey  string `json:"image_api_key,omitempty"`
+	ImageBaseURL string `json:"image_base_url,omitempty"`
+
+	// Web search for the web_search and web_research tools. SearchBackend is
+	// "duckduckgo" (default), "searxng", "brave" or "local", which searches
+	// the pages web_research has already cached. SearchURL is the SearXNG
+	// instance, or overrides the DuckDuckGo/Brave endpoint.
+	SearchBackend string `json:"search_backend,omitempty"`
+	SearchURL     string `json:"search_url,omitempty"`
+	BraveAPIKey   string `json:"brave_api_key,omitempty"`
+
 	// Tarot settings
 	TarotFunctionURL string `json:"tarot_function_url,omitempty"`
 	TarotAuthToken   string `json:"tarot_auth_token,omitempty"`
@@ -188,6 +264,29 @@ type Config struct {
 
 	// Orchestrator settings
 	Orchestrator *OrchestratorConfig `json:"orchestrator,omitempty"`
+
+	// RedactPatterns are extra regular expressions stripped from session
+	// exports on top of the credentials in the config and the built-in
+	// key/token/email rules.
+	RedactPatterns []string `json:"redact_patterns,omitempty"`
+
+	// Fallbacks is an ordered failover chain tried when the primary provider
+	// stays unavailable after retries or no longer serves the model.
+	Fallbacks []FallbackConfig `json:"fallbacks,omitempty"`
+
+	// secretRefs records credentials loaded from vault:/env:/cmd: references
+	// so saving writes the reference back, not the resolved value.
+	secretRefs map[string]secretRef
+}
+
+// FallbackConfig is one provider+model pair in a failover chain. Provider
+// names a registry entry whose BaseURL and default model fill in whatever
+// is left empty; APIKey is per entry because chains usually cross providers.
+type FallbackConfig struct {
+	Provider string `json:"provider,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	Model    string `json:"model,omitempty"`
 }
 
 // CollectionsConfig holds collections settings
@@ -289,13 +388,18 @@ func Paths() (configDir, configFile, secretsFile, skillsFile string) {
 }
 
 // NamedConfigPath returns the path for a named config file.
-// If name is empty, returns the default config path.
+// If name is empty or "default" (as ListConfigs calls it), returns the
+// default config path. A name ValidateProfileName rejects returns "", which
+// every file operation refuses, so no name can reach outside ~/.celeste.
 func NamedConfigPath(name string) string {
 	homeDir, _ := os.UserHomeDir()
 	configDir := filepath.Join(homeDir, ".celeste")
-	if name == "" {
+	if name == "" || name == DefaultProfileName {
 		return filepath.Join(configDir, "config.json")
 	}
+	if ValidateProfileName(name) != nil {
+		return ""
+	}
 	return filepath.Join(configDir, fmt.Sprintf("config.%s.json", name))
 }
 
@@ -349,9 +453,10 @@ func SaveSkillsConfig(skillsConfig *Config) error {
 		BlockmonWebhookURL:          skillsConfig.BlockmonWebhookURL,
 		BlockmonDefaultNetwork:      skillsConfig.BlockmonDefaultNetwork,
 		BlockmonPollIntervalSeconds: skillsConfig.BlockmonPollIntervalSeconds,
+		secretRefs:                  skillsConfig.secretRefs,
 	}
 
-	data, err := json.MarshalIndent(skillsOnly, "", "  ")
+	data, err := marshalConfig(skillsOnly)
 	if err != nil {
 		return fmt.Errorf("failed to marshal skills config: %w", err)
 	}
@@ -363,27 +468,37 @@ func SaveSkillsConfig(skillsConfig *Config) error {
 // If name is empty, loads the default config.
 func LoadNamed(name string) (*Config, error) {
 	if name == "" {
-		// No explicit profile: honor a config.<name>.json flagged "default": true.
-		// Falls back to the legacy config.json when none is flagged.
-		if d := ResolveDefaultName(); d != "" {
-			name = d
-		} else {
+		// No explicit profile: honor the project's pin, then a
+		// config.<name>.json flagged "default": true. Falls back to the
+		// legacy config.json when neither applies.
+		wd, _ := os.Getwd()
+		if name, _ = SelectProfile(wd); name == "" {
 			return Load()
 		}
 	}
+	return loadNamed(name)
+}
 
+// loadNamed loads config.<name>.json over the profiles it extends, plus
+// the shared skills.json. "default" here is config.json alone.
+func loadNamed(name string) (*Config, error) {
 	config := DefaultConfig()
-	configPath := NamedConfigPath(name)
 
-	// Load named config file
-	data, err := os.ReadFile(configPath)
+	// Load the named config file over the profiles it extends, base first.
+	chain, err := profileChain(name)
 	if err != nil {
-		return nil, fmt.Errorf("config '%s' not found at %s: %w", name, configPath, err)
+		return nil, err
 	}
-
-	if err := json.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse config '%s': %w", name, err)
+	for _, data := range chain {
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse config '%s': %w", name, err)
+		}
+	}
+	var own struct {
+		Default bool `json:"default"`
 	}
+	_ = json.Unmarshal(chain[len(chain)-1], &own)
+	config.Default = own.Default
 
 	// Load shared json (for all skill configurations)
 	if skillsConfig, err := LoadSkillsConfig(); err == nil {
@@ -477,9 +592,24 @@ func LoadNamed(name string) (*Config, error) {
 		}
 	}
 
+	config.resolveSecrets()
+	config.markProviders()
+	config.applyModelCatalog()
 	return config, nil
 }
 
+// applyModelCatalog loads the cached provider model listings and this
+// config's overrides into the process-wide model catalog.
+func (c *Config) applyModelCatalog() {
+	cat := catalog.Default()
+	if path, err := catalog.DefaultPath(); err == nil {
+		if err := cat.Load(path); err != nil {
+			log.Printf("[config] ignoring model cache: %v", err)
+		}
+	}
+	cat.SetOverrides(c.ModelOverrides)
+}
+
 // ResolveDefaultName returns the name of the config.<name>.json file flagged
 // "default": true, or "" if none is flagged (or the dir is unreadable). Files
 // are scanned in directory order; the first match wins.
@@ -514,6 +644,9 @@ func SetDefaultProfile(name string) error {
 	if name == "" {
 		return fmt.Errorf("default profile must be a named config, not the bare default")
 	}
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
 	target := NamedConfigPath(name)
 	if _, err := os.Stat(target); err != nil {
 		return fmt.Errorf("config '%s' not found at %s: %w", name, target, err)
@@ -689,6 +822,10 @@ func Load() (*Config, error) {
 		}
 	}
 
+	config.resolveSecrets()
+	config.markProviders()
+	config.applyModelCatalog()
+
 	// Reconcile the model so the saved config reflects what's actually used (#51):
 	// an empty model falls back to the default, and models xAI no longer supports
 	// are migrated to their replacement. Persisted so the header, config file, and
@@ -775,11 +912,75 @@ func (c *Config) ResolveAgentModel() string {
 	return c.Model
 }
 
+// ResolveImageBackend returns the image generation backend: ImageBackend if
+// set, else OpenAI Images when chatting with OpenAI, else Venice when a
+// Venice key is configured. ok is false when none applies. Unset key, URL
+// and model fall back to that backend's chat settings.
+func (c *Config) ResolveImageBackend() (b media.ImageBackend, ok bool) {
+	chatOpenAI := providers.DetectProvider(c.BaseURL) == "openai"
+	name := c.ImageBackend
+	if name == "" {
+		switch {
+		case chatOpenAI:
+			name = "openai"
+		case c.VeniceAPIKey != "":
+			name = "venice"
+		default:
+			return media.ImageBackend{}, false
+		}
+	}
+	b = media.ImageBackend{Name: name, APIKey: c.ImageAPIKey, BaseURL: c.ImageBaseURL, Model: c.ImageModel}
+	switch name {
+	case "openai":
+		if b.APIKey == "" && chatOpenAI {
+			b.APIKey = c.APIKey
+			if b.BaseURL == "" {
+				b.BaseURL = c.BaseURL
+			}
+		}
+	case "venice":
+		if b.APIKey == "" {
+			b.APIKey = c.VeniceAPIKey
+		}
+		if b.BaseURL == "" {
+			b.BaseURL = c.VeniceBaseURL
+		}
+		if b.Model == "" {
+			b.Model = c.VeniceImageModel
+		}
+	}
+	return b, true
+}
+
+// ImageGenerator returns the generator ResolveImageBackend selects, or nil
+// and no error when image generation isn't set up.
+func (c *Config) ImageGenerator() (media.ImageGenerator, error) {
+	b, ok := c.ResolveImageBackend()
+	if !ok {
+		return nil, nil
+	}
+	return media.NewImageGenerator(b)
+}
+
+// ResearchBackend returns the search backend the web tools use and the
+// page cache web_research reads through.
+func (c *Config) ResearchBackend() (research.Backend, *research.Cache, error) {
+	cache, err := research.DefaultCache()
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := research.NewBackend(research.BackendConfig{Name: c.SearchBackend, URL: c.SearchURL, APIKey: c.BraveAPIKey}, cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, cache, nil
+}
+
 // Save saves configuration to file.
 func Save(config *Config) error {
 	_, configFile, _, _ := Paths()
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	data, err := marshalConfig(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -795,8 +996,17 @@ func SaveNamed(name string, config *Config) error {
 	if name == "" {
 		return Save(config)
 	}
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	var data []byte
+	var err error
+	if config.Extends != "" {
+		data, err = inheritedJSON(config)
+	} else {
+		data, err = marshalConfig(config)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -816,10 +1026,11 @@ func SaveSecrets(config *Config) error {
 	_, _, secretsFile, _ := Paths()
 
 	secrets := &Config{
-		APIKey: config.APIKey, // Only API key in secrets.json now
+		APIKey:     config.APIKey, // Only API key in secrets.json now
+		secretRefs: config.secretRefs,
 	}
 
-	data, err := json.MarshalIndent(secrets, "", "  ")
+	data, err := marshalConfig(secrets)
 	if err != nil {
 		return fmt.Errorf("failed to marshal secrets: %w", err)
 	}
@@ -1023,6 +1234,66 @@ func (l *ConfigLoader) GetWalletSecurityConfig() (WalletSecuritySettingsConfig,
 	}, nil
 }
 
+// SecretValues returns every non-empty credential in the config. Loading
+// registers them with secrets.Register so logs, tool output, session files
+// and exports never carry them.
+func (c *Config) SecretValues() []string {
+	var out []string
+	for _, f := range c.secretFields() {
+		if v := f.get(); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// ResolveFallbacks returns the failover chain with registry defaults filled
+// in. Entries that still lack a BaseURL or model are dropped.
+func (c *Config) ResolveFallbacks() []FallbackConfig {
+	var out []FallbackConfig
+	for _, fb := range c.Fallbacks {
+		if caps, ok := providers.GetProvider(fb.Provider); ok {
+			if fb.BaseURL == "" {
+				fb.BaseURL = caps.BaseURL
+			}
+			if fb.Model == "" {
+				fb.Model = caps.DefaultModel
+			}
+		}
+		if fb.Provider == "" {
+			fb.Provider = providers.DetectProvider(fb.BaseURL)
+		}
+		if fb.BaseURL == "" || fb.Model == "" {
+			continue
+		}
+		out = append(out, fb)
+	}
+	return out
+}
+
+// markProviders tells providers about base URLs the config names as native
+// Ollama, which detection by URL would otherwise miss.
+func (c *Config) markProviders() {
+	if c.Provider == "ollama" {
+		providers.MarkOllamaURL(c.BaseURL)
+	}
+	for _, fb := range c.Fallbacks {
+		if fb.Provider == "ollama" {
+			providers.MarkOllamaURL(fb.BaseURL)
+		}
+	}
+}
+
+// SetBaseURL points the config at a new endpoint. A Provider recorded for
+// the old URL is cleared, since a stale "ollama" would send the new URL down
+// the native Ollama path.
+func (c *Config) SetBaseURL(url string) {
+	if url != c.BaseURL {
+		c.Provider = ""
+	}
+	c.BaseURL = url
+}
+
 // GetTimeout returns the configured timeout as a duration.
 func (c *Config) GetTimeout() time.Duration {
 	if c.Timeout <= 0 {
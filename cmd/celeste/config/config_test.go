@@ -676,6 +676,30 @@ func TestResolveAgentModel(t *testing.T) {
 	}
 }
 
+func TestResolveImageBackend(t *testing.T) {
+	// Chatting with OpenAI reuses its key for OpenAI Images.
+	c := &Config{APIKey: "sk-chat", BaseURL: "https://api.openai.com/v1", VeniceAPIKey: "v"}
+	b, ok := c.ResolveImageBackend()
+	if !ok || b.Name != "openai" || b.APIKey != "sk-chat" {
+		t.Fatalf("OpenAI chat should pick openai with the chat key, got %+v %v", b, ok)
+	}
+	// Otherwise a Venice key selects Venice with its image model.
+	c = &Config{BaseURL: "https://api.x.ai/v1", VeniceAPIKey: "v", VeniceImageModel: "hidream"}
+	b, ok = c.ResolveImageBackend()
+	if !ok || b.Name != "venice" || b.APIKey != "v" || b.Model != "hidream" {
+		t.Fatalf("Venice key should pick venice, got %+v %v", b, ok)
+	}
+	// An explicit backend keeps its own key, which isn't the chat key.
+	c = &Config{BaseURL: "https://api.x.ai/v1", APIKey: "xai", ImageBackend: "openai", ImageAPIKey: "sk-img"}
+	b, _ = c.ResolveImageBackend()
+	if b.APIKey != "sk-img" || b.BaseURL != "" {
+		t.Fatalf("explicit openai backend should use image_api_key only, got %+v", b)
+	}
+	if _, ok := (&Config{BaseURL: "https://api.x.ai/v1"}).ResolveImageBackend(); ok {
+		t.Fatal("no image backend should be resolved without a key")
+	}
+}
+
 func TestReconcileMigratesAgentModel(t *testing.T) {
 	// A deprecated AgentModel (grok-4-1-* trap) is migrated to the safe default.
 	c := &Config{Model: "grok-4.20-0309-non-reasoning", AgentModel: "grok-4-1-fast"}
@@ -684,3 +708,19 @@ func TestReconcileMigratesAgentModel(t *testing.T) {
 		t.Fatalf("deprecated AgentModel should be migrated, still %q", c.AgentModel)
 	}
 }
+
+func TestResolveFallbacks(t *testing.T) {
+	openai, _ := providers.GetProvider("openai")
+	cfg := &Config{Fallbacks: []FallbackConfig{
+		{Provider: "openai", APIKey: "sk-fallback"},
+		{BaseURL: "https://api.anthropic.com/v1", Model: "claude-sonnet-4", APIKey: "sk-ant"},
+		{Provider: "no-such-provider"},
+	}}
+
+	got := cfg.ResolveFallbacks()
+	require.Len(t, got, 2, "entries without an endpoint and model are dropped")
+	assert.Equal(t, openai.BaseURL, got[0].BaseURL)
+	assert.Equal(t, openai.DefaultModel, got[0].Model)
+	assert.Equal(t, "anthropic", got[1].Provider)
+	assert.Contains(t, cfg.SecretValues(), "sk-ant")
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+)
+
+// DefaultProfileName refers to the bare config.json, in "extends" and in
+// project pins.
+const DefaultProfileName = "default"
+
+// maxExtendsDepth bounds a profile's "extends" chain.
+const maxExtendsDepth = 8
+
+// profileNamePattern is what a profile name may contain. Names arrive from
+// project pins and "extends", which a cloned repo controls, and become part
+// of a path under ~/.celeste.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateProfileName rejects a profile name that is not plain letters,
+// digits, '-' and '_'. Anything else could walk out of ~/.celeste to a file
+// the repo wrote, whose cmd: references loading would then run.
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// profileChain reads name's file and those of the profiles it extends,
+// base first.
+func profileChain(name string) ([][]byte, error) {
+	var chain [][]byte
+	seen := []string{}
+	for name != "" {
+		for _, s := range seen {
+			if s == name {
+				return nil, fmt.Errorf("profile inheritance cycle: %s → %s", strings.Join(seen, " → "), name)
+			}
+		}
+		if err := ValidateProfileName(name); err != nil {
+			if len(seen) > 0 {
+				return nil, fmt.Errorf("config '%s' extends: %w", seen[len(seen)-1], err)
+			}
+			return nil, err
+		}
+		if len(seen) == maxExtendsDepth {
+			return nil, fmt.Errorf("profile '%s' extends more than %d levels deep", seen[0], maxExtendsDepth)
+		}
+		path := NamedConfigPath(name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if len(seen) > 0 {
+				// Not %w: the profile itself exists, and callers treat
+				// os.ErrNotExist as "safe to create from defaults".
+				return nil, fmt.Errorf("config '%s' extends '%s', not found at %s: %v", seen[len(seen)-1], name, path, err)
+			}
+			return nil, fmt.Errorf("config '%s' not found at %s: %w", name, path, err)
+		}
+		var probe struct {
+			Extends string `json:"extends"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return nil, fmt.Errorf("failed to parse config '%s': %w", name, err)
+		}
+		chain = append([][]byte{data}, chain...)
+		seen = append(seen, name)
+		name = probe.Extends
+	}
+	return chain, nil
+}
+
+// inheritedJSON encodes c as only what differs from the profile it
+// extends, so a child profile stays a short list of overrides. A field the
+// parent sets and c clears is written as an explicit zero value.
+func inheritedJSON(c *Config) ([]byte, error) {
+	parent, err := loadNamed(c.Extends)
+	if err != nil {
+		return nil, err
+	}
+	own, err := jsonFields(c)
+	if err != nil {
+		return nil, err
+	}
+	base, err := jsonFields(parent)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]any{}
+	for k, v := range own {
+		if k == "extends" || k == "default" || !reflect.DeepEqual(v, base[k]) {
+			out[k] = v
+		}
+	}
+	for k, v := range base {
+		if _, ok := own[k]; !ok && k != "default" {
+			out[k] = zeroJSON(v)
+		}
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// jsonFields decodes c's on-disk encoding into a generic map.
+func jsonFields(c *Config) (map[string]any, error) {
+	data, err := marshalConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	return fields, json.Unmarshal(data, &fields)
+}
+
+func zeroJSON(v any) any {
+	switch v.(type) {
+	case string:
+		return ""
+	case float64:
+		return 0
+	case bool:
+		return false
+	case []any:
+		return []any{}
+	default:
+		return nil
+	}
+}
+
+// SelectProfile returns the profile a command without -config uses in dir
+// and why, in order:
+//
+//  1. .celeste/profile in dir or the nearest parent (one line: the name)
+//  2. "- profile: <name>" under ## Settings in the project's .grimoire
+//  3. the named profile flagged "default": true
+//
+// name is "" for the bare config.json, either because nothing applies or
+// because the project pins "default". source is empty when nothing applies.
+func SelectProfile(dir string) (name, source string) {
+	if name, source, ok := pinnedProfile(dir); ok {
+		if name == DefaultProfileName {
+			name = ""
+		}
+		return name, source
+	}
+	if d := ResolveDefaultName(); d != "" {
+		return d, "default profile"
+	}
+	return "", ""
+}
+
+// pinnedProfile finds a project's profile pin. ~/.celeste/profile is not a
+// pin: that directory is the global config dir, not a project.
+func pinnedProfile(dir string) (name, source string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+	home, _ := os.UserHomeDir()
+	for d := dir; ; {
+		if d != home {
+			path := filepath.Join(d, ".celeste", "profile")
+			if data, err := os.ReadFile(path); err == nil {
+				if name := strings.TrimSpace(string(data)); name != "" {
+					if err := ValidateProfileName(name); err != nil {
+						log.Printf("[config] %s: %v; ignoring the pin", path, err)
+						return "", "", false
+					}
+					return name, path, true
+				}
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	if g, err := grimoire.LoadAll(dir); err == nil {
+		if name := g.Setting("profile"); name != "" {
+			if err := ValidateProfileName(name); err != nil {
+				log.Printf("[config] .grimoire profile: %v; ignoring the pin", err)
+				return "", "", false
+			}
+			return name, ".grimoire", true
+		}
+	}
+	return "", "", false
+}
+
+// ProfileDiff is one setting that differs between two profiles. Secrets are
+// shown as a fingerprint, never their value.
+type ProfileDiff struct {
+	Key  string // JSON path, e.g. "base_url" or "fallbacks[0].api_key"
+	A, B string // "(not set)" when absent
+}
+
+// DiffProfiles lists the settings that differ between a and b, sorted by
+// key.
+func DiffProfiles(a, b *Config) ([]ProfileDiff, error) {
+	fa, err := flatProfile(a)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := flatProfile(b)
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]bool{}
+	for k := range fa {
+		keys[k] = true
+	}
+	for k := range fb {
+		keys[k] = true
+	}
+	var diffs []ProfileDiff
+	for k := range keys {
+		va, okA := fa[k]
+		vb, okB := fb[k]
+		if okA && okB && va == vb {
+			continue
+		}
+		if !okA {
+			va = "(not set)"
+		}
+		if !okB {
+			vb = "(not set)"
+		}
+		diffs = append(diffs, ProfileDiff{Key: k, A: va, B: vb})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Key < diffs[j].Key })
+	return diffs, nil
+}
+
+// flatProfile flattens c's resolved settings to path → display value.
+func flatProfile(c *Config) (map[string]string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	flattenJSON("", "", v, out)
+	return out, nil
+}
+
+func flattenJSON(path, key string, v any, out map[string]string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			flattenJSON(p, k, child, out)
+		}
+	case []any:
+		for i, child := range t {
+			flattenJSON(fmt.Sprintf("%s[%d]", path, i), key, child, out)
+		}
+	case string:
+		if secretJSONKeys[key] {
+			out[path] = maskSecret(t)
+		} else {
+			out[path] = t
+		}
+	default:
+		enc, _ := json.Marshal(t)
+		out[path] = string(enc)
+	}
+}
+
+// maskSecret identifies a credential without revealing it: the same key
+// gets the same fingerprint, so a diff still shows whether two match.
+func maskSecret(v string) string {
+	if v == "" {
+		return "(not set)"
+	}
+	sum := sha256.Sum256([]byte(v))
+	return "set, sha256:" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+)
+
+func writeProfile(t *testing.T, home, name, body string) {
+	t.Helper()
+	dir := filepath.Join(home, ".celeste")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config."+name+".json"), []byte(body), 0600))
+}
+
+func TestLoadNamedExtends(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	writeProfile(t, home, "base", `{"base_url": "https://api.anthropic.com/v1", "model": "claude-sonnet-4-6",
+		"api_key": "base-key", "timeout": 90, "default": true, "model_overrides": {"a": {"tools": true}}}`)
+	writeProfile(t, home, "client", `{"extends": "base", "api_key": "client-key",
+		"model_overrides": {"b": {"tools": true}}}`)
+
+	got, err := LoadNamed("client")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.anthropic.com/v1", got.BaseURL, "inherited")
+	assert.Equal(t, 90, got.Timeout, "inherited")
+	assert.Equal(t, "client-key", got.APIKey, "overridden")
+	assert.False(t, got.Default, "default is never inherited")
+	assert.Len(t, got.ModelOverrides, 2, "objects merge key by key")
+
+	// Saving writes only the overrides, so the base stays the one copy.
+	got.Model = "claude-opus-4-6"
+	got.Timeout = 0
+	require.NoError(t, SaveNamed("client", got))
+	data, err := os.ReadFile(NamedConfigPath("client"))
+	require.NoError(t, err)
+	var saved map[string]any
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, "base", saved["extends"])
+	assert.Equal(t, "client-key", saved["api_key"])
+	assert.Equal(t, "claude-opus-4-6", saved["model"])
+	assert.NotContains(t, saved, "base_url")
+	assert.Equal(t, float64(0), saved["timeout"], "a cleared field is written as zero")
+
+	again, err := LoadNamed("client")
+	require.NoError(t, err)
+	assert.Equal(t, "claude-opus-4-6", again.Model)
+	assert.Equal(t, 0, again.Timeout)
+}
+
+func TestLoadNamedMarksOllamaProvider(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	const url = "http://ollama-proxy.test:8080"
+	require.Equal(t, "unknown", providers.DetectProvider(url), "nothing in the URL says ollama")
+	writeProfile(t, home, "gpu", `{"base_url": "`+url+`", "model": "llama3.1:8b", "provider": "ollama"}`)
+
+	got, err := LoadNamed("gpu")
+	require.NoError(t, err)
+	assert.Equal(t, "ollama", got.Provider)
+	assert.Equal(t, "ollama", providers.DetectProvider(url))
+}
+
+func TestSetBaseURLDropsStaleProvider(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	writeProfile(t, home, "gpu", `{"base_url": "http://ollama-proxy.test:8080", "model": "llama3.1:8b", "provider": "ollama"}`)
+	cfg, err := LoadNamed("gpu")
+	require.NoError(t, err)
+
+	cfg.SetBaseURL("http://ollama-proxy.test:8080")
+	assert.Equal(t, "ollama", cfg.Provider, "same URL keeps the provider")
+
+	const next = "http://llm-gateway.test:8000"
+	cfg.SetBaseURL(next)
+	require.NoError(t, SaveNamed("gpu", cfg))
+
+	again, err := LoadNamed("gpu")
+	require.NoError(t, err)
+	assert.Equal(t, next, again.BaseURL)
+	assert.Empty(t, again.Provider)
+	assert.NotEqual(t, "ollama", providers.DetectProvider(next), "the new URL is not sent down the native Ollama path")
+}
+
+func TestLoadNamedExtendsErrors(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	writeProfile(t, home, "a", `{"extends": "b"}`)
+	writeProfile(t, home, "b", `{"extends": "a"}`)
+	_, err := LoadNamed("a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cycle")
+
+	writeProfile(t, home, "orphan", `{"extends": "missing"}`)
+	_, err = LoadNamed("orphan")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, os.ErrNotExist, "the profile itself exists")
+
+	_, err = LoadNamed("nope")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestProfileNameTraversalIsRefused(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	repo := filepath.Join(root, "repo")
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	writeProfile(t, home, "personal", `{"default": true}`)
+
+	// A cloned repo plants a profile whose key is a shell command, and pins
+	// a name that walks from ~/.celeste to it.
+	marker := filepath.Join(root, "ran")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".celeste"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "evil.json"),
+		[]byte(`{"api_key": "cmd:touch `+marker+`"}`), 0644))
+	evil := "x/../../../repo/evil"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".celeste", "profile"), []byte(evil+"\n"), 0644))
+
+	name, source := SelectProfile(repo)
+	assert.Equal(t, "personal", name, "the pin is ignored")
+	assert.Equal(t, "default profile", source)
+
+	_, err := LoadNamed(evil)
+	assert.ErrorContains(t, err, "invalid profile name")
+	assert.Empty(t, NamedConfigPath(evil))
+
+	writeProfile(t, home, "child", `{"extends": "`+evil+`"}`)
+	_, err = LoadNamed("child")
+	assert.ErrorContains(t, err, "invalid profile name")
+
+	assert.NoFileExists(t, marker, "no command from the repo ran")
+	assert.Error(t, SaveNamed(evil, DefaultConfig()))
+}
+
+func TestSelectProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	writeProfile(t, home, "personal", `{"default": true}`)
+
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "pkg", "deep")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	name, source := SelectProfile(sub)
+	assert.Equal(t, "personal", name)
+	assert.Equal(t, "default profile", source)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".grimoire"), []byte("## Settings\n- profile: internal-xai\n"), 0644))
+	name, source = SelectProfile(sub)
+	assert.Equal(t, "internal-xai", name)
+	assert.Equal(t, ".grimoire", source)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".celeste"), 0755))
+	pin := filepath.Join(repo, ".celeste", "profile")
+	require.NoError(t, os.WriteFile(pin, []byte("client-anthropic\n"), 0644))
+	name, source = SelectProfile(sub)
+	assert.Equal(t, "client-anthropic", name)
+	assert.Equal(t, pin, source)
+
+	require.NoError(t, os.WriteFile(pin, []byte("default"), 0644))
+	name, _ = SelectProfile(sub)
+	assert.Empty(t, name, `"default" pins the bare config.json`)
+
+	// ~/.celeste is the global config dir, not a project pin.
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".celeste", "profile"), []byte("x"), 0644))
+	name, _ = SelectProfile(home)
+	assert.Equal(t, "personal", name)
+}
+
+func TestDiffProfiles(t *testing.T) {
+	a := &Config{BaseURL: "https://api.anthropic.com/v1", Model: "m", APIKey: "sk-ant-secret",
+		Fallbacks: []FallbackConfig{{Provider: "openai", APIKey: "fb-secret"}}}
+	b := &Config{BaseURL: "https://api.x.ai/v1", Model: "m", APIKey: "xai-secret"}
+
+	diffs, err := DiffProfiles(a, b)
+	require.NoError(t, err)
+	byKey := map[string]ProfileDiff{}
+	for _, d := range diffs {
+		byKey[d.Key] = d
+		assert.NotContains(t, d.A+d.B, "secret", "secrets are masked: %s", d.Key)
+	}
+	assert.NotContains(t, byKey, "model")
+	assert.Equal(t, "https://api.x.ai/v1", byKey["base_url"].B)
+	assert.Regexp(t, `^set, sha256:[0-9a-f]{8}$`, byKey["api_key"].A)
+	assert.NotEqual(t, byKey["api_key"].A, byKey["api_key"].B)
+	assert.Equal(t, "(not set)", byKey["fallbacks[0].api_key"].B)
+	assert.Equal(t, "openai", byKey["fallbacks[0].provider"].A)
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
+)
+
+// secretField is one credential in Config, addressed by its JSON path so
+// references can be tracked and restored on save.
+type secretField struct {
+	key string
+	get func() string
+	set func(string)
+}
+
+// secretRef remembers the reference a credential was loaded from and the
+// value it resolved to, so Save writes the reference back instead of the
+// plaintext — unless the value has since been changed.
+type secretRef struct {
+	ref   string
+	value string
+}
+
+// secretJSONKeys are the config keys that hold credentials, at any depth.
+// Migration walks raw JSON with it; secretFields is the typed equivalent.
+var secretJSONKeys = map[string]bool{
+	"api_key": true, "elevenlabs_api_key": true, "venice_api_key": true, "image_api_key": true, "brave_api_key": true, "tarot_auth_token": true,
+	"twitter_bearer_token": true, "twitter_api_key": true, "twitter_api_secret": true,
+	"twitter_access_token": true, "twitter_access_token_secret": true,
+	"twitch_client_secret": true, "youtube_api_key": true,
+	"ipfs_api_key": true, "ipfs_api_secret": true, "alchemy_api_key": true, "blockmon_alchemy_api_key": true,
+	"xai_management_api_key": true, "primary_api_key": true, "reviewer_api_key": true,
+}
+
+func stringField(key string, p *string) secretField {
+	return secretField{key: key, get: func() string { return *p }, set: func(v string) { *p = v }}
+}
+
+// secretFields lists every credential in c.
+func (c *Config) secretFields() []secretField {
+	fields := []secretField{
+		stringField("api_key", &c.APIKey),
+		stringField("elevenlabs_api_key", &c.ElevenLabsAPIKey),
+		stringField("venice_api_key", &c.VeniceAPIKey),
+		stringField("image_api_key", &c.ImageAPIKey),
+		stringField("brave_api_key", &c.BraveAPIKey),
+		stringField("tarot_auth_token", &c.TarotAuthToken),
+		stringField("twitter_bearer_token", &c.TwitterBearerToken),
+		stringField("twitter_api_key", &c.TwitterAPIKey),
+		stringField("twitter_api_secret", &c.TwitterAPISecret),
+		stringField("twitter_access_token", &c.TwitterAccessToken),
+		stringField("twitter_access_token_secret", &c.TwitterAccessTokenSecret),
+		stringField("twitch_client_secret", &c.TwitchClientSecret),
+		stringField("youtube_api_key", &c.YouTubeAPIKey),
+		stringField("ipfs_api_key", &c.IPFSAPIKey),
+		stringField("ipfs_api_secret", &c.IPFSAPISecret),
+		stringField("alchemy_api_key", &c.AlchemyAPIKey),
+		stringField("blockmon_alchemy_api_key", &c.BlockmonAlchemyAPIKey),
+		stringField("xai_management_api_key", &c.XAIManagementAPIKey),
+	}
+	if c.Orchestrator != nil {
+		names := make([]string, 0, len(c.Orchestrator.Lanes))
+		for name := range c.Orchestrator.Lanes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lanes := c.Orchestrator.Lanes
+		for _, name := range names {
+			name := name
+			fields = append(fields,
+				secretField{
+					key: "orchestrator.lanes." + name + ".primary_api_key",
+					get: func() string { return lanes[name].PrimaryAPIKey },
+					set: func(v string) { l := lanes[name]; l.PrimaryAPIKey = v; lanes[name] = l },
+				},
+				secretField{
+					key: "orchestrator.lanes." + name + ".reviewer_api_key",
+					get: func() string { return lanes[name].ReviewerAPIKey },
+					set: func(v string) { l := lanes[name]; l.ReviewerAPIKey = v; lanes[name] = l },
+				})
+		}
+	}
+	for i := range c.Fallbacks {
+		fields = append(fields, stringField("fallbacks."+strconv.Itoa(i)+".api_key", &c.Fallbacks[i].APIKey))
+	}
+	return fields
+}
+
+// resolveSecrets replaces env:, cmd: and vault: references with their values
+// and registers every credential for redaction. A reference that cannot be
+// resolved leaves the field empty with a warning, so one locked secret does
+// not stop the rest of the config from loading.
+func (c *Config) resolveSecrets() {
+	for _, f := range c.secretFields() {
+		ref := f.get()
+		if !secrets.IsReference(ref) {
+			continue
+		}
+		val, err := secrets.Resolve(ref)
+		if err != nil {
+			log.Printf("[config] %s: %v", f.key, err)
+			val = ""
+		}
+		if c.secretRefs == nil {
+			c.secretRefs = make(map[string]secretRef)
+		}
+		c.secretRefs[f.key] = secretRef{ref: ref, value: val}
+		f.set(val)
+	}
+	secrets.Register(c.SecretValues()...)
+}
+
+// SecretRef returns the reference a credential was loaded from, if any. key
+// is the field's JSON path, e.g. "api_key".
+func (c *Config) SecretRef(key string) (string, bool) {
+	r, ok := c.secretRefs[key]
+	return r.ref, ok
+}
+
+// marshalConfig encodes c for disk with resolved credentials swapped back to
+// the references they came from. c itself is not modified.
+func marshalConfig(c *Config) ([]byte, error) {
+	if len(c.secretRefs) == 0 {
+		return json.MarshalIndent(c, "", "  ")
+	}
+	out := *c
+	if c.Orchestrator != nil {
+		orch := *c.Orchestrator
+		orch.Lanes = make(map[string]LaneConfig, len(c.Orchestrator.Lanes))
+		for k, v := range c.Orchestrator.Lanes {
+			orch.Lanes[k] = v
+		}
+		out.Orchestrator = &orch
+	}
+	out.Fallbacks = append([]FallbackConfig(nil), c.Fallbacks...)
+	for _, f := range out.secretFields() {
+		// An empty value counts too: a reference that could not be resolved
+		// this run (a locked vault, an unset variable) must survive a save.
+		if r, ok := c.secretRefs[f.key]; ok && f.get() == r.value {
+			f.set(r.ref)
+		}
+	}
+	return json.MarshalIndent(&out, "", "  ")
+}
+
+// MigrationResult lists what MigrateSecrets moved.
+type MigrationResult struct {
+	Files []string // config files rewritten
+	Moved []string // vault entry names created
+}
+
+// MigrateSecrets moves every plaintext credential in the config files under
+// ~/.celeste (config.json, named profiles, secrets.json and skills.json)
+// into vault as "<file>/<json path>" and rewrites each file to reference it.
+// Values that are already references are left alone. The vault is saved
+// before any file is rewritten, so an interrupted migration never loses a
+// key. With dryRun nothing is written.
+func MigrateSecrets(vault *secrets.Vault, dryRun bool) (*MigrationResult, error) {
+	configDir, configFile, secretsFile, skillsFile := Paths()
+	files := []string{configFile, secretsFile, skillsFile}
+	named, _ := filepath.Glob(filepath.Join(configDir, "config.*.json"))
+	files = append(files, named...)
+
+	res := &MigrationResult{}
+	rewritten := make(map[string][]byte)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		prefix := strings.TrimSuffix(filepath.Base(path), ".json")
+		var moved []string
+		migrateJSON(doc, "", func(jsonPath, value string) string {
+			name := prefix + "/" + jsonPath
+			vault.Set(name, value)
+			moved = append(moved, name)
+			return secrets.PrefixVault + name
+		})
+		if len(moved) == 0 {
+			continue
+		}
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		rewritten[path] = out
+		res.Files = append(res.Files, path)
+		res.Moved = append(res.Moved, moved...)
+	}
+
+	if dryRun || len(rewritten) == 0 {
+		return res, nil
+	}
+	if err := vault.Save(); err != nil {
+		return nil, err
+	}
+	for _, path := range res.Files {
+		if err := os.WriteFile(path, rewritten[path], 0600); err != nil {
+			return res, fmt.Errorf("rewrite %s: %w", path, err)
+		}
+	}
+	return res, nil
+}
+
+// migrateJSON walks a decoded JSON document and replaces each plaintext
+// credential under a secretJSONKeys key with what replace returns.
+func migrateJSON(node any, path string, replace func(jsonPath, value string) string) {
+	switch n := node.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			if s, ok := n[k].(string); ok {
+				if secretJSONKeys[k] && s != "" && !secrets.IsReference(s) {
+					n[k] = replace(child, s)
+				}
+				continue
+			}
+			migrateJSON(n[k], child, replace)
+		}
+	case []any:
+		for i, v := range n {
+			migrateJSON(v, path+"."+strconv.Itoa(i), replace)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
+)
+
+func TestLoadResolvesSecretReferencesAndSaveKeepsThem(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("CELESTE_TEST_XAI_KEY", "xai-resolved-from-env")
+	configDir := filepath.Join(home, ".celeste")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+
+	raw := `{"api_key": "env:CELESTE_TEST_XAI_KEY", "model": "grok-4.20-0309-non-reasoning",
+		"venice_api_key": "cmd:echo venice-from-cmd"}`
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(raw), 0644))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "xai-resolved-from-env", cfg.APIKey)
+	assert.Equal(t, "venice-from-cmd", cfg.VeniceAPIKey)
+	ref, ok := cfg.SecretRef("api_key")
+	assert.True(t, ok)
+	assert.Equal(t, "env:CELESTE_TEST_XAI_KEY", ref)
+	assert.Equal(t, "key [REDACTED]", secrets.Redact("key xai-resolved-from-env"))
+
+	// An unchanged credential is written back as its reference; one the user
+	// replaced is written as the new value.
+	cfg.VeniceAPIKey = "venice-new-plaintext"
+	require.NoError(t, Save(cfg))
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	require.NoError(t, err)
+	var saved map[string]any
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, "env:CELESTE_TEST_XAI_KEY", saved["api_key"])
+	assert.Equal(t, "venice-new-plaintext", saved["venice_api_key"])
+	assert.Equal(t, "xai-resolved-from-env", cfg.APIKey, "Save must not modify the in-memory config")
+}
+
+func TestSaveKeepsReferencesThatFailedToResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv(secrets.PassphraseEnv, "")
+	configDir := filepath.Join(home, ".celeste")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+
+	vaultPath := filepath.Join(configDir, "vault.json")
+	vault, err := secrets.OpenVault(vaultPath, "correct horse")
+	require.NoError(t, err)
+	vault.Set("xai", "xai-in-the-vault")
+	require.NoError(t, vault.Save())
+	prev := secrets.Default
+	secrets.Default = secrets.NewResolver(vaultPath)
+	t.Cleanup(func() { secrets.Default = prev })
+
+	raw := `{"api_key": "vault:xai", "venice_api_key": "env:CELESTE_TEST_UNSET_KEY", "model": "grok-4"}`
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(raw), 0644))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.APIKey, "the vault is locked")
+	assert.Empty(t, cfg.VeniceAPIKey)
+
+	cfg.Model = "grok-4-fast"
+	require.NoError(t, Save(cfg))
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	require.NoError(t, err)
+	var saved map[string]any
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, "vault:xai", saved["api_key"])
+	assert.Equal(t, "env:CELESTE_TEST_UNSET_KEY", saved["venice_api_key"])
+	assert.Equal(t, "grok-4-fast", saved["model"])
+
+	// Unlocked, the round-tripped reference resolves again.
+	t.Setenv(secrets.PassphraseEnv, "correct horse")
+	secrets.Default = secrets.NewResolver(vaultPath)
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "xai-in-the-vault", cfg.APIKey)
+}
+
+func TestMigrateSecrets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	configDir := filepath.Join(home, ".celeste")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+
+	write := func(name, body string) {
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, name), []byte(body), 0600))
+	}
+	write("config.json", `{"model": "m", "api_key": "env:ALREADY_A_REF"}`)
+	write("secrets.json", `{"api_key": "xai-plaintext-key"}`)
+	write("config.work.json", `{"api_key": "sk-work-key", "orchestrator": {"lanes": {"code": {"primary_api_key": "sk-lane-key"}}}}`)
+
+	vault, err := secrets.OpenVault(filepath.Join(configDir, "vault.json"), "pw")
+	require.NoError(t, err)
+
+	res, err := MigrateSecrets(vault, true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"secrets/api_key", "config.work/api_key", "config.work/orchestrator.lanes.code.primary_api_key"}, res.Moved)
+	data, _ := os.ReadFile(filepath.Join(configDir, "secrets.json"))
+	assert.Contains(t, string(data), "xai-plaintext-key", "dry run must not write")
+
+	res, err = MigrateSecrets(vault, false)
+	require.NoError(t, err)
+	assert.Len(t, res.Files, 2)
+
+	data, _ = os.ReadFile(filepath.Join(configDir, "config.work.json"))
+	assert.NotContains(t, string(data), "sk-work-key")
+	assert.Contains(t, string(data), "vault:config.work/orchestrator.lanes.code.primary_api_key")
+
+	reopened, err := secrets.OpenVault(filepath.Join(configDir, "vault.json"), "pw")
+	require.NoError(t, err)
+	got, ok := reopened.Get("secrets/api_key")
+	assert.True(t, ok)
+	assert.Equal(t, "xai-plaintext-key", got)
+}
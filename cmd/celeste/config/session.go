@@ -10,6 +10,9 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
 )
 
 // Session represents a saved conversation session.
@@ -32,9 +35,10 @@ type Session struct {
 
 // SessionMessage represents a message in a session.
 type SessionMessage struct {
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	Role        string        `json:"role"`
+	Content     string        `json:"content"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Attachments []media.Asset `json:"attachments,omitempty"`
 }
 
 // GenerateNameFromMessage creates a session name from first user message.
@@ -91,7 +95,8 @@ func (m *SessionManager) NewSession() *Session {
 	}
 }
 
-// Save saves a session to disk.
+// Save saves a session to disk. Registered secrets are redacted from the
+// file; the session in memory keeps them.
 func (m *SessionManager) Save(session *Session) error {
 	session.UpdatedAt = time.Now()
 	session.TokenCount = EstimateSessionTokens(session)
@@ -100,6 +105,7 @@ func (m *SessionManager) Save(session *Session) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
+	data = []byte(secrets.Redact(string(data)))
 
 	path := filepath.Join(m.sessionsDir, session.ID+".json")
 	if err := os.WriteFile(path, data, 0644); err != nil {
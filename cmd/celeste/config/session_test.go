@@ -8,6 +8,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
 )
 
 // TestNewSessionManager tests session manager creation
@@ -113,6 +115,30 @@ func TestSaveAndLoadSession(t *testing.T) {
 	assert.Equal(t, "Hi there!", loaded.Messages[1].Content)
 }
 
+// TestSaveRedactsSecrets tests that registered secrets never reach the session file
+func TestSaveRedactsSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("USERPROFILE", tmpDir)
+
+	const secret = "sk-session-save-0123456789"
+	secrets.Register(secret)
+
+	manager := NewSessionManager()
+	session := manager.NewSession()
+	session.Messages = []SessionMessage{
+		{Role: "user", Content: "my key is " + secret, Timestamp: time.Now()},
+	}
+	session.Metadata["last_command"] = "curl -H 'Authorization: Bearer " + secret + "'"
+	require.NoError(t, manager.Save(session))
+
+	data, err := os.ReadFile(filepath.Join(manager.sessionsDir, session.ID+".json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), secret)
+	assert.Contains(t, string(data), "my key is "+secrets.Redacted)
+	assert.Contains(t, session.Messages[0].Content, secret, "the session in memory is unchanged")
+}
+
 // TestAddMessage tests adding messages to session
 func TestAddMessage(t *testing.T) {
 	tmpDir := t.TempDir()
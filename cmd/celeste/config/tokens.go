@@ -9,10 +9,6 @@ import (
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
 )
 
-// ModelLimits is kept as an alias for backward compatibility.
-// Canonical data lives in ctxmgr.ModelLimits.
-var ModelLimits = ctxmgr.ModelLimits
-
 // EstimateTokens approximates token count (delegates to ctxmgr).
 func EstimateTokens(text string) int {
 	return ctxmgr.EstimateTokens(text)
@@ -108,12 +104,23 @@ func TruncateToLimit(messages []SessionMessage, model string, systemPromptTokens
 // profile inherits the seed default's model (fugu), so pointing it at a local
 // server produced a confident 1,000,000-token budget for a server that might
 // have 8k. celeste would never compact and the request would overflow.
+// Ollama's native API is asked instead (/api/show, cached per process).
 func ResolveContextLimit(baseURL, model string, override int) (limit int, known bool) {
 	if override > 0 {
 		return override, true
 	}
+	provider := providers.DetectProvider(baseURL)
+	if provider == "ollama" {
+		// The native API tells us the window outright (and registers it, so
+		// LookupModelLimit agrees from here on).
+		if n, ok := providers.OllamaContextLength(baseURL, model); ok {
+			return n, true
+		}
+		fallback, _ := LookupModelLimit("")
+		return fallback, false
+	}
 	limit, known = LookupModelLimit(model)
-	if known && providers.DetectProvider(baseURL) == "local" {
+	if known && provider == "local" {
 		// Fall back to the conservative default rather than a name collision.
 		fallback, _ := LookupModelLimit("")
 		return fallback, false
@@ -6,57 +6,14 @@ package ctxmgr
 import (
 	"fmt"
 	"sync"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
 )
 
-// ModelLimits maps model names to their context window sizes in tokens.
-// Migrated from config/tokens.go.
-var ModelLimits = map[string]int{
-	// OpenAI — current generation only (from /v1/models API, 2026-04)
-	"gpt-4.1":       1050000,
-	"gpt-4.1-mini":  400000,
-	"gpt-4.1-nano":  400000,
-	"gpt-5.3-codex": 1050000,
-	"gpt-5.4":       1050000,
-	"gpt-5.4-mini":  400000,
-	"gpt-5.4-nano":  400000,
-	"gpt-5.4-pro":   1050000,
-	"o3":            1050000,
-	"o4-mini":       400000,
-	// Anthropic (current models, 2026-04)
-	"claude-opus-4-6":   1000000,
-	"claude-sonnet-4-6": 1000000,
-	"claude-haiku-4-5":  200000,
-	// xAI Grok — current generation (from /v1/models API, 2026-04)
-	"grok-build-0.1":              256000,  // grok code model — 256K context
-	"grok-4-1-fast":               2000000, // dead (migrated away on load); kept for old logs
-	"grok-4-1-fast-reasoning":     2000000,
-	"grok-4-1-fast-non-reasoning": 2000000,
-	// grok-4.x family: 1M context per docs.x.ai (2026-06). NOTE: corrected down
-	// from 2M — overstating the window let history grow past the real API limit.
-	"grok-4.3":                     1000000,
-	"grok-4.20-0309-reasoning":     1000000,
-	"grok-4.20-0309-non-reasoning": 1000000,
-	"grok-4.20-multi-agent-0309":   1000000,
-	"grok-code-fast-1":             2000000,
-	// Sakana AI (from /v1/models, 2026-06)
-	"fugu":                1000000,
-	"fugu-ultra":          1000000,
-	"fugu-ultra-20260615": 1000000, // dated alias pin of fugu-ultra
-	// Venice-unique models (from docs.venice.ai/models/text, 2026-04)
-	"venice-uncensored":                    32000,
-	"venice-uncensored-role-play":          128000,
-	"deepseek-v3.2":                        160000,
-	"qwen3-coder-480b-a35b-instruct":       256000,
-	"qwen3-coder-480b-a35b-instruct-turbo": 256000,
-	"qwen3-235b-a22b-thinking-2507":        128000,
-	"kimi-k2-5":                            256000,
-	"zai-org-glm-4.7":                      198000,
-	"mistral-small-3-2-24b-instruct":       256000,
-	"llama-3.3-70b":                        128000,
-	"minimax-m25":                          198000,
-	// Default
-	"default": 8192,
-}
+// DefaultModelLimit is the context window assumed for a model the catalog
+// does not know. It is deliberately small: overstating a window lets history
+// grow past what the API accepts.
+const DefaultModelLimit = 8192
 
 // TokenBudget tracks token usage across all components of a conversation.
 // It provides fine-grained tracking beyond a simple current/max counter,
@@ -87,7 +44,7 @@ type TokenBudget struct {
 // that is sent with every request.
 func NewTokenBudget(modelLimit, systemPromptTokens, toolDefTokens int) *TokenBudget {
 	if modelLimit <= 0 {
-		modelLimit = ModelLimits["default"]
+		modelLimit = DefaultModelLimit
 	}
 	return &TokenBudget{
 		ModelLimit:           modelLimit,
@@ -97,7 +54,7 @@ func NewTokenBudget(modelLimit, systemPromptTokens, toolDefTokens int) *TokenBud
 }
 
 // NewTokenBudgetForModel creates a TokenBudget by looking up the model name
-// in ModelLimits. If the model is not found, the "default" limit is used.
+// in the model catalog. If the model is not found, DefaultModelLimit is used.
 func NewTokenBudgetForModel(model string, systemPromptTokens, toolDefTokens int) *TokenBudget {
 	limit := GetModelLimit(model)
 	return NewTokenBudget(limit, systemPromptTokens, toolDefTokens)
@@ -225,7 +182,7 @@ func (tb *TokenBudget) Summary() string {
 // --- Helpers migrated from config/tokens.go ---
 
 // GetModelLimit returns the token limit for a model name.
-// Falls back to the "default" entry if the model is not found.
+// Falls back to DefaultModelLimit if the catalog does not know the model.
 func GetModelLimit(model string) int {
 	limit, _ := LookupModelLimit(model)
 	return limit
@@ -235,13 +192,13 @@ func GetModelLimit(model string) int {
 // found. Callers that validate a user's configured window need the second
 // value: for an unrecognised model the returned limit is a conservative
 // fallback, not knowledge, and treating it as an upper bound would reject a
-// correct setting. Local models are never in the table — their window is
-// whatever the server was started with.
+// correct setting. Local models are known only once their server has
+// reported them (Ollama's /api/show feeds the catalog).
 func LookupModelLimit(model string) (int, bool) {
-	if limit, ok := ModelLimits[model]; ok {
-		return limit, true
+	if m, ok := catalog.Default().Lookup(model); ok && m.ContextWindow > 0 {
+		return m.ContextWindow, true
 	}
-	return ModelLimits["default"], false
+	return DefaultModelLimit, false
 }
 
 // GetModelLimitWithOverride returns the token limit for a model, using the
@@ -22,8 +22,8 @@ func TestNewTokenBudget(t *testing.T) {
 
 func TestNewTokenBudget_ZeroLimit(t *testing.T) {
 	tb := NewTokenBudget(0, 100, 50)
-	if tb.ModelLimit != ModelLimits["default"] {
-		t.Errorf("ModelLimit = %d, want default %d", tb.ModelLimit, ModelLimits["default"])
+	if tb.ModelLimit != DefaultModelLimit {
+		t.Errorf("ModelLimit = %d, want default %d", tb.ModelLimit, DefaultModelLimit)
 	}
 }
 
@@ -34,8 +34,8 @@ func TestNewTokenBudgetForModel(t *testing.T) {
 	}
 
 	tb2 := NewTokenBudgetForModel("unknown-model", 100, 50)
-	if tb2.ModelLimit != ModelLimits["default"] {
-		t.Errorf("ModelLimit for unknown = %d, want default %d", tb2.ModelLimit, ModelLimits["default"])
+	if tb2.ModelLimit != DefaultModelLimit {
+		t.Errorf("ModelLimit for unknown = %d, want default %d", tb2.ModelLimit, DefaultModelLimit)
 	}
 }
 
@@ -194,7 +194,7 @@ func TestGetModelLimit(t *testing.T) {
 	if GetModelLimit("grok-4-1-fast") != 2000000 {
 		t.Errorf("grok-4-1-fast limit wrong, got %d", GetModelLimit("grok-4-1-fast"))
 	}
-	if GetModelLimit("nonexistent") != ModelLimits["default"] {
+	if GetModelLimit("nonexistent") != DefaultModelLimit {
 		t.Errorf("fallback limit wrong")
 	}
 }
@@ -10,8 +10,8 @@ func TestLookupModelLimit_KnownVsUnknown(t *testing.T) {
 	if !known {
 		t.Fatal("fugu should be a known model")
 	}
-	if limit != ModelLimits["fugu"] {
-		t.Errorf("limit = %d, want %d", limit, ModelLimits["fugu"])
+	if limit != 1000000 {
+		t.Errorf("limit = %d, want %d", limit, 1000000)
 	}
 
 	// A local model's name is a filesystem path and is never in the table.
@@ -19,8 +19,8 @@ func TestLookupModelLimit_KnownVsUnknown(t *testing.T) {
 	if known {
 		t.Error("a local model path must not report as known")
 	}
-	if limit != ModelLimits["default"] {
-		t.Errorf("fallback = %d, want the default %d", limit, ModelLimits["default"])
+	if limit != DefaultModelLimit {
+		t.Errorf("fallback = %d, want the default %d", limit, DefaultModelLimit)
 	}
 
 	// Empty behaves the same way.
@@ -31,10 +31,10 @@ func TestLookupModelLimit_KnownVsUnknown(t *testing.T) {
 
 // GetModelLimit keeps its old signature and behaviour.
 func TestGetModelLimit_UnchangedBehaviour(t *testing.T) {
-	if got := GetModelLimit("fugu"); got != ModelLimits["fugu"] {
+	if got := GetModelLimit("fugu"); got != 1000000 {
 		t.Errorf("known model: got %d", got)
 	}
-	if got := GetModelLimit("nope"); got != ModelLimits["default"] {
+	if got := GetModelLimit("nope"); got != DefaultModelLimit {
 		t.Errorf("unknown model: got %d, want default", got)
 	}
 }
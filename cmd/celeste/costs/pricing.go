@@ -1,64 +1,55 @@
 // Package costs provides token cost tracking and pricing for LLM models.
+// Prices come from the model catalog (package catalog).
 package costs
 
-// ModelCost holds the per-1M-token pricing for a model.
-type ModelCost struct {
-	Input  float64 // USD per 1M input tokens
-	Output float64 // USD per 1M output tokens
-}
+import "github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
 
-// ModelPricing maps model identifiers to their costs.
-var ModelPricing = map[string]ModelCost{
-	// OpenAI — current generation (from pricing page, 2026-04)
-	"gpt-4.1":       {Input: 2.50, Output: 15.00},
-	"gpt-4.1-mini":  {Input: 0.75, Output: 4.50},
-	"gpt-4.1-nano":  {Input: 0.20, Output: 1.25},
-	"gpt-5.3-codex": {Input: 2.50, Output: 15.00},
-	"gpt-5.4":       {Input: 2.50, Output: 15.00},
-	"gpt-5.4-mini":  {Input: 0.75, Output: 4.50},
-	"gpt-5.4-nano":  {Input: 0.20, Output: 1.25},
-	"gpt-5.4-pro":   {Input: 15.00, Output: 60.00},
-	"o3":            {Input: 2.50, Output: 15.00},
-	"o4-mini":       {Input: 0.75, Output: 4.50},
-	// xAI Grok — current generation (from pricing page, 2026-04)
-	"grok-build-0.1":              {Input: 1.00, Output: 2.00}, // grok code model (cached input $0.20/1M not modeled here)
-	"grok-4-1-fast":               {Input: 0.20, Output: 0.50},
-	"grok-4-1-fast-reasoning":     {Input: 0.20, Output: 0.50},
-	"grok-4-1-fast-non-reasoning": {Input: 0.20, Output: 0.50},
-	// grok-4.x family: $1.25 in / $2.50 out per 1M (docs.x.ai, 2026-06)
-	"grok-4.3":                     {Input: 1.25, Output: 2.50},
-	"grok-4.20-0309-reasoning":     {Input: 1.25, Output: 2.50},
-	"grok-4.20-0309-non-reasoning": {Input: 1.25, Output: 2.50},
-	"grok-4.20-multi-agent-0309":   {Input: 1.25, Output: 2.50},
-	"grok-code-fast-1":             {Input: 0.20, Output: 0.50},
-	// Google
-	"gemini-2.0-flash": {Input: 0.10, Output: 0.40},
-	// Anthropic (current models, 2026-04)
-	"claude-opus-4-6":   {Input: 5.00, Output: 25.00},
-	"claude-sonnet-4-6": {Input: 3.00, Output: 15.00},
-	"claude-haiku-4-5":  {Input: 1.00, Output: 5.00},
-	// Venice-unique models (from docs.venice.ai, 2026-04)
-	"venice-uncensored":                    {Input: 0.20, Output: 0.90},
-	"venice-uncensored-role-play":          {Input: 0.50, Output: 2.00},
-	"deepseek-v3.2":                        {Input: 0.33, Output: 0.48},
-	"qwen3-coder-480b-a35b-instruct":       {Input: 0.75, Output: 3.00},
-	"qwen3-coder-480b-a35b-instruct-turbo": {Input: 0.35, Output: 1.50},
-	"qwen3-235b-a22b-thinking-2507":        {Input: 0.45, Output: 3.50},
-	"kimi-k2-5":                            {Input: 0.56, Output: 3.50},
-	"zai-org-glm-4.7":                      {Input: 0.55, Output: 2.65},
-	"mistral-small-3-2-24b-instruct":       {Input: 0.09, Output: 0.25},
-	"llama-3.3-70b":                        {Input: 0.70, Output: 2.80},
-	"minimax-m25":                          {Input: 0.34, Output: 1.19},
+// Usage is one request's token counts as the providers report them. Input
+// includes the Cached and CacheWrite tokens.
+type Usage struct {
+	Input      int
+	Output     int
+	Cached     int // read from the provider's prompt cache
+	CacheWrite int // written to the prompt cache (Anthropic)
 }
 
 // GetCost calculates the total USD cost for the given token counts.
-// Returns 0 if the model is not in the pricing table.
+// Returns 0 if the catalog has no price for the model.
 func GetCost(model string, inputTokens, outputTokens int) float64 {
-	mc, ok := ModelPricing[model]
-	if !ok {
+	return GetUsageCost(model, Usage{Input: inputTokens, Output: outputTokens})
+}
+
+// GetUsageCost is GetCost for a request that used the prompt cache: cached
+// and cache-write tokens are charged at the model's cache prices, or at the
+// input price when the catalog has none.
+func GetUsageCost(model string, u Usage) float64 {
+	m, ok := catalog.Default().Lookup(model)
+	if !ok || !m.Priced() {
 		return 0
 	}
-	inputCost := float64(inputTokens) / 1_000_000.0 * mc.Input
-	outputCost := float64(outputTokens) / 1_000_000.0 * mc.Output
-	return inputCost + outputCost
+	cachedPrice, writePrice := m.InputPrice, m.InputPrice
+	if m.CachedInputPrice > 0 {
+		cachedPrice = m.CachedInputPrice
+	}
+	if m.CacheWritePrice > 0 {
+		writePrice = m.CacheWritePrice
+	}
+	uncached := u.Input - u.Cached - u.CacheWrite
+	if uncached < 0 {
+		uncached = 0
+	}
+	cost := float64(uncached)*m.InputPrice +
+		float64(u.Cached)*cachedPrice +
+		float64(u.CacheWrite)*writePrice +
+		float64(u.Output)*m.OutputPrice
+	return cost / 1_000_000.0
+}
+
+// BatchDiscount is the share of list price OpenAI and Anthropic charge for
+// requests sent through their batch APIs.
+const BatchDiscount = 0.5
+
+// GetBatchCost is GetCost for requests served through a provider batch API.
+func GetBatchCost(model string, inputTokens, outputTokens int) float64 {
+	return GetCost(model, inputTokens, outputTokens) * BatchDiscount
 }
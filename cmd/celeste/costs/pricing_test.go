@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
 )
 
 func TestGetCost_KnownModel(t *testing.T) {
@@ -25,7 +26,11 @@ func TestGetCost_UnknownModel(t *testing.T) {
 }
 
 func TestGetCost_AllModels(t *testing.T) {
-	for model := range ModelPricing {
+	for _, m := range catalog.Default().Models("") {
+		if !m.Priced() {
+			continue
+		}
+		model := m.ID
 		cost := GetCost(model, 1000, 1000)
 		assert.True(t, cost > 0, "model %s should have non-zero cost", model)
 		assert.False(t, math.IsNaN(cost), "model %s cost should not be NaN", model)
@@ -36,3 +41,22 @@ func TestGetCost_ZeroTokens(t *testing.T) {
 	cost := GetCost("grok-4-1-fast", 0, 0)
 	assert.Equal(t, 0.0, cost)
 }
+
+func TestGetBatchCost_HalfPrice(t *testing.T) {
+	// claude-sonnet-4-6: $3/1M input, $15/1M output, halved
+	cost := GetBatchCost("claude-sonnet-4-6", 1_000_000, 1_000_000)
+	assert.InDelta(t, 9.00, cost, 0.001)
+}
+
+func TestGetUsageCost_CachedTokensAtCachePrice(t *testing.T) {
+	// claude-sonnet-4-6: $3 input, $0.30 cache read, $3.75 cache write, $15 output
+	cost := GetUsageCost("claude-sonnet-4-6", Usage{Input: 1_000_000, Cached: 600_000, CacheWrite: 100_000, Output: 100_000})
+	assert.InDelta(t, 0.9+0.18+0.375+1.5, cost, 0.0001)
+
+	// A model without cache prices charges cached tokens as input.
+	assert.InDelta(t, GetCost("grok-4.3", 1000, 0), GetUsageCost("grok-4.3", Usage{Input: 1000, Cached: 800}), 1e-12)
+}
+
+func TestGetCost_OpenRouterName(t *testing.T) {
+	assert.Equal(t, GetCost("claude-sonnet-4-6", 1000, 1000), GetCost("anthropic/claude-sonnet-4-6", 1000, 1000))
+}
@@ -3,6 +3,7 @@ package costs
 import (
 	"encoding/json"
 	"os"
+	"sort"
 	"sync"
 )
 
@@ -11,17 +12,33 @@ type CostSummary struct {
 	Model        string  `json:"model"`
 	TotalInput   int     `json:"total_input_tokens"`
 	TotalOutput  int     `json:"total_output_tokens"`
+	TotalCached  int     `json:"total_cached_tokens,omitempty"`
 	TotalCostUSD float64 `json:"total_cost_usd"`
 	Turns        int     `json:"turns"`
+	// ByProvider splits the totals by the provider+model that served each
+	// turn. It has more than one entry when a failover chain kicked in.
+	ByProvider []ProviderUsage `json:"by_provider,omitempty"`
+}
+
+// ProviderUsage is the part of a session served by one provider and model.
+type ProviderUsage struct {
+	Provider string  `json:"provider"`
+	Model    string  `json:"model"`
+	Input    int     `json:"input_tokens"`
+	Output   int     `json:"output_tokens"`
+	CostUSD  float64 `json:"cost_usd"`
+	Turns    int     `json:"turns"`
 }
 
 // SessionTracker accumulates token usage and cost across a session.
 type SessionTracker struct {
-	Model        string  `json:"model"`
-	TotalInput   int     `json:"total_input"`
-	TotalOutput  int     `json:"total_output"`
-	TotalCostUSD float64 `json:"total_cost_usd"`
-	Turns        int     `json:"turns"`
+	Model        string                    `json:"model"`
+	TotalInput   int                       `json:"total_input"`
+	TotalOutput  int                       `json:"total_output"`
+	TotalCached  int                       `json:"total_cached,omitempty"`
+	TotalCostUSD float64                   `json:"total_cost_usd"`
+	Turns        int                       `json:"turns"`
+	ByProvider   map[string]*ProviderUsage `json:"by_provider,omitempty"`
 	mu           sync.Mutex
 }
 
@@ -32,28 +49,71 @@ func NewSessionTracker() *SessionTracker {
 
 // RecordUsage adds a turn's token usage and computes the incremental cost.
 func (t *SessionTracker) RecordUsage(model string, inputTokens, outputTokens int) {
+	t.RecordTokens(model, Usage{Input: inputTokens, Output: outputTokens})
+}
+
+// RecordTokens is RecordUsage with the turn's prompt-cache counts, which
+// are charged at the model's cache prices.
+func (t *SessionTracker) RecordTokens(model string, u Usage) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	t.Model = model
-	t.TotalInput += inputTokens
-	t.TotalOutput += outputTokens
-	t.TotalCostUSD += GetCost(model, inputTokens, outputTokens)
+	t.TotalInput += u.Input
+	t.TotalOutput += u.Output
+	t.TotalCached += u.Cached
+	t.TotalCostUSD += GetUsageCost(model, u)
 	t.Turns++
 }
 
+// RecordServedUsage is RecordUsage for a turn whose serving provider is
+// known, so sessions that failed over show what each provider cost.
+func (t *SessionTracker) RecordServedUsage(provider, model string, inputTokens, outputTokens int) {
+	t.RecordServedTokens(provider, model, Usage{Input: inputTokens, Output: outputTokens})
+}
+
+// RecordServedTokens is RecordTokens for a turn whose serving provider is
+// known.
+func (t *SessionTracker) RecordServedTokens(provider, model string, u Usage) {
+	t.RecordTokens(model, u)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ByProvider == nil {
+		t.ByProvider = make(map[string]*ProviderUsage)
+	}
+	key := provider + "/" + model
+	pu := t.ByProvider[key]
+	if pu == nil {
+		pu = &ProviderUsage{Provider: provider, Model: model}
+		t.ByProvider[key] = pu
+	}
+	pu.Input += u.Input
+	pu.Output += u.Output
+	pu.CostUSD += GetUsageCost(model, u)
+	pu.Turns++
+}
+
 // GetSummary returns a snapshot of the current session cost state.
 func (t *SessionTracker) GetSummary() CostSummary {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	return CostSummary{
+	summary := CostSummary{
 		Model:        t.Model,
 		TotalInput:   t.TotalInput,
 		TotalOutput:  t.TotalOutput,
+		TotalCached:  t.TotalCached,
 		TotalCostUSD: t.TotalCostUSD,
 		Turns:        t.Turns,
 	}
+	for _, u := range t.ByProvider {
+		summary.ByProvider = append(summary.ByProvider, *u)
+	}
+	sort.Slice(summary.ByProvider, func(i, j int) bool {
+		return summary.ByProvider[i].Turns > summary.ByProvider[j].Turns
+	})
+	return summary
 }
 
 // Save serialises the tracker state to a JSON file.
@@ -54,3 +54,17 @@ func TestSessionTracker_LoadNotFound(t *testing.T) {
 	err := tracker.Load("/nonexistent/path.json")
 	assert.Error(t, err)
 }
+
+func TestSessionTracker_RecordServedUsage(t *testing.T) {
+	tracker := NewSessionTracker()
+	tracker.RecordServedUsage("anthropic", "claude-sonnet-4", 1000, 500)
+	tracker.RecordServedUsage("openai", "gpt-4o", 1000, 500)
+	tracker.RecordServedUsage("anthropic", "claude-sonnet-4", 1000, 500)
+
+	s := tracker.GetSummary()
+	assert.Equal(t, 3, s.Turns)
+	require.Len(t, s.ByProvider, 2)
+	assert.Equal(t, "anthropic", s.ByProvider[0].Provider)
+	assert.Equal(t, 2, s.ByProvider[0].Turns)
+	assert.InDelta(t, s.TotalCostUSD, s.ByProvider[0].CostUSD+s.ByProvider[1].CostUSD, 1e-9)
+}
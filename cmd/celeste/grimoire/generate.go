@@ -0,0 +1,103 @@
+package grimoire
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// generateReadmeLimit caps how much of the README goes into a generation
+// prompt; the opening sections carry the build and layout notes.
+const generateReadmeLimit = 6000
+
+// generateListingLimit caps the top-level entries listed in the prompt.
+const generateListingLimit = 80
+
+// GenerationSystemPrompt instructs a model writing a .grimoire.
+const GenerationSystemPrompt = `You write .grimoire files: project context that a coding agent reads before working in a repository.
+Reply with the file's markdown only, no code fences and no commentary.
+Keep the four sections of the starter: Bindings (facts and conventions), Rituals (commands to run and when),
+Incantations (@./path references to docs worth including) and Wards (what must not be modified).
+State only what the material shows. Prefer short bullets an agent can act on over description.`
+
+// GenerationPrompt describes the repository at dir for a model asked to
+// write its .grimoire: the detected starter template, the top-level
+// layout and the start of the README.
+func GenerationPrompt(dir string) (string, error) {
+	info, err := DetectProject(dir)
+	if err != nil {
+		return "", fmt.Errorf("project detection failed: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Write the .grimoire for the repository %q.\n\n", filepath.Base(dir))
+	sb.WriteString("## Starter generated from the manifests\n")
+	sb.WriteString(stripMeta(GenerateTemplate(info, dir)))
+
+	sb.WriteString("\n## Top-level layout\n")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") && e.Name() != ".github" {
+			continue
+		}
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > generateListingLimit {
+		names = append(names[:generateListingLimit], fmt.Sprintf("... and %d more", len(names)-generateListingLimit))
+	}
+	for _, n := range names {
+		fmt.Fprintf(&sb, "- %s\n", n)
+	}
+
+	for _, name := range []string{"README.md", "README", "readme.md", "README.rst"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		readme := string(data)
+		if len(readme) > generateReadmeLimit {
+			readme = readme[:generateReadmeLimit] + "\n[... truncated]"
+		}
+		fmt.Fprintf(&sb, "\n## %s\n%s\n", name, readme)
+		break
+	}
+	return sb.String(), nil
+}
+
+// GeneratedContent turns a model's reply into .grimoire file content: code
+// fences the model added anyway are removed and the metadata header for
+// dir is prepended.
+func GeneratedContent(dir, reply string) string {
+	body := strings.TrimSpace(reply)
+	if strings.HasPrefix(body, "```") {
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:]
+		}
+		body = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(body), "```"))
+	}
+	return GrimoireMeta(dir) + "\n" + stripMeta(body) + "\n"
+}
+
+// stripMeta removes a leading <!-- ... --> metadata block.
+func stripMeta(content string) string {
+	trimmed := strings.TrimLeft(content, "\n")
+	if !strings.HasPrefix(trimmed, "<!--") {
+		return content
+	}
+	end := strings.Index(trimmed, "-->")
+	if end < 0 {
+		return content
+	}
+	return strings.TrimLeft(trimmed[end+3:], "\n")
+}
@@ -0,0 +1,35 @@
+package grimoire
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerationPrompt(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/foo\n\ngo 1.26\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# foo\n\nRun `make gen` before building."), 0644)
+	os.Mkdir(filepath.Join(dir, "internal"), 0755)
+	os.Mkdir(filepath.Join(dir, ".git"), 0755)
+
+	prompt, err := GenerationPrompt(dir)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "- Module path: example.com/foo")
+	assert.Contains(t, prompt, "- internal/\n")
+	assert.NotContains(t, prompt, ".git/")
+	assert.Contains(t, prompt, "Run `make gen` before building.")
+	assert.NotContains(t, prompt, "<!--", "the starter's metadata header is noise to the model")
+}
+
+func TestGeneratedContent(t *testing.T) {
+	dir := t.TempDir()
+	content := GeneratedContent(dir, "```markdown\n# Grimoire: foo\n\n## Bindings\n- Go 1.26\n```")
+	assert.True(t, strings.HasPrefix(content, "<!--\nlast_updated: "))
+	assert.Contains(t, content, "-->\n\n# Grimoire: foo\n\n## Bindings\n- Go 1.26\n")
+	assert.NotContains(t, content, "```")
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // Parse parses a .grimoire markdown file into a Grimoire struct.
@@ -35,6 +36,10 @@ func Parse(content string, baseDir string) (*Grimoire, error) {
 			g.Incantations = parseIncantations(body)
 		case "Hooks":
 			g.Hooks = parseHooks(body)
+		case "Settings":
+			g.Settings = parseSettings(body)
+		case "Language Servers":
+			g.LanguageServers = parseLanguageServers(body)
 		default:
 			g.RawSections[name] = body
 		}
@@ -144,7 +149,8 @@ func parseMetadata(content string) GrimoireMetadata {
 	return meta
 }
 
-// parseHooks parses hook entries from ### PreToolUse / ### PostToolUse sub-sections.
+// parseHooks parses hook entries from ### PreToolUse / ### PostToolUse /
+// ### PreCommit sub-sections. PreCommit entries may omit the "tool:" prefix.
 func parseHooks(body string) []HookEntry {
 	var hooks []HookEntry
 	var currentPhase string
@@ -157,6 +163,10 @@ func parseHooks(body string) []HookEntry {
 		}
 		if currentPhase != "" && strings.HasPrefix(trimmed, "- ") {
 			entry := strings.TrimPrefix(trimmed, "- ")
+			if currentPhase == "PreCommit" && !hasHookToolPrefix(entry) {
+				hooks = append(hooks, HookEntry{Phase: currentPhase, Command: entry})
+				continue
+			}
 			colonIdx := strings.Index(entry, ":")
 			if colonIdx > 0 {
 				toolName := strings.TrimSpace(entry[:colonIdx])
@@ -172,3 +182,52 @@ func parseHooks(body string) []HookEntry {
 
 	return hooks
 }
+
+// hasHookToolPrefix reports whether a hook entry starts with "tool:" or
+// "*:" rather than being a bare command that happens to contain a colon.
+func hasHookToolPrefix(entry string) bool {
+	name, _, ok := strings.Cut(entry, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '*' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSettings parses "- key: value" entries. Keys are lowercased; values
+// keep their case.
+func parseSettings(body string) map[string]string {
+	settings := make(map[string]string)
+	for _, item := range parseListItems(body) {
+		key, value, ok := strings.Cut(item, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			continue
+		}
+		settings[key] = strings.TrimSpace(value)
+	}
+	return settings
+}
+
+// parseLanguageServers parses "- language: command" entries. The command is
+// optional ("- go" uses the default server for that language).
+func parseLanguageServers(body string) []LanguageServerEntry {
+	var servers []LanguageServerEntry
+	for _, item := range parseListItems(body) {
+		language, command, _ := strings.Cut(item, ":")
+		language = strings.TrimSpace(language)
+		if language == "" {
+			continue
+		}
+		servers = append(servers, LanguageServerEntry{
+			Language: language,
+			Command:  strings.TrimSpace(command),
+		})
+	}
+	return servers
+}
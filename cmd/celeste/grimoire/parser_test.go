@@ -110,3 +110,55 @@ func TestParse_HooksMultipleTools(t *testing.T) {
 	assert.Equal(t, "write_file", g.Hooks[1].ToolName)
 	assert.Equal(t, "PostToolUse", g.Hooks[2].Phase)
 }
+
+func TestParse_HooksPreCommit(t *testing.T) {
+	input := `## Hooks
+
+### PreCommit
+- go vet ./...
+- git_commit: make lint
+- echo "checked: $CELESTE_COMMIT_FILES"
+`
+	g, err := Parse(input, "/repo")
+	require.NoError(t, err)
+	require.Len(t, g.Hooks, 3)
+	assert.Equal(t, HookEntry{Phase: "PreCommit", Command: "go vet ./..."}, g.Hooks[0])
+	assert.Equal(t, HookEntry{Phase: "PreCommit", ToolName: "git_commit", Command: "make lint"}, g.Hooks[1])
+	assert.Equal(t, `echo "checked: $CELESTE_COMMIT_FILES"`, g.Hooks[2].Command, "a colon inside the command is not a tool prefix")
+}
+
+func TestParse_Settings(t *testing.T) {
+	input := `## Settings
+- Profile: client-anthropic
+- not a setting
+- commit_style: conventional
+
+## Bindings
+- Go
+`
+	g, err := Parse(input, "/repo")
+	require.NoError(t, err)
+	assert.Equal(t, "client-anthropic", g.Setting("profile"))
+	assert.Equal(t, "conventional", g.Setting("commit_style"))
+	assert.Len(t, g.Settings, 2)
+	assert.NotContains(t, g.Render(), "client-anthropic", "settings are not prompt content")
+
+	local, _ := Parse("## Settings\n- profile: internal-xai\n", "/repo")
+	assert.Equal(t, "internal-xai", Merge(g, local).Setting("profile"))
+}
+
+func TestParse_LanguageServers(t *testing.T) {
+	input := `## Language Servers
+- go
+- python: pyright-langserver --stdio
+- .lua .luau: lua-language-server
+`
+	g, err := Parse(input, "/repo")
+	require.NoError(t, err)
+	require.Len(t, g.LanguageServers, 3)
+	assert.Equal(t, LanguageServerEntry{Language: "go"}, g.LanguageServers[0])
+	assert.Equal(t, "pyright-langserver --stdio", g.LanguageServers[1].Command)
+	assert.Equal(t, ".lua .luau", g.LanguageServers[2].Language)
+	assert.False(t, g.IsEmpty())
+	assert.NotContains(t, g.Render(), "pyright", "language servers are not prompt content")
+}
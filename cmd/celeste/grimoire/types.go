@@ -25,8 +25,11 @@ type Grimoire struct {
 	Incantations []IncludeRef      // @path includes with resolved content
 	Wards        []string          // protected areas
 	Hooks        []HookEntry       // pre/post tool execution commands
+	Settings     map[string]string // "- key: value" entries under ## Settings (not rendered)
 	RawSections  map[string]string // unparsed section content by heading
 	Meta         GrimoireMetadata  // embedded metadata (last updated, git hash, etc.)
+
+	LanguageServers []LanguageServerEntry // ## Language Servers entries (not rendered)
 }
 
 // IncludeRef represents an @include directive with its resolved content.
@@ -39,11 +42,18 @@ type IncludeRef struct {
 
 // HookEntry represents a pre/post tool execution hook.
 type HookEntry struct {
-	Phase    string // "PreToolUse" or "PostToolUse"
+	Phase    string // "PreToolUse", "PostToolUse" or "PreCommit"
 	ToolName string // tool name pattern (e.g., "bash", "write_file")
 	Command  string // shell command to execute
 }
 
+// LanguageServerEntry configures a language server for diagnostics and the
+// lsp_* tools.
+type LanguageServerEntry struct {
+	Language string // "go", "python", "typescript", "rust", or extensions like ".lua .luau"
+	Command  string // server command line; empty means the language's default
+}
+
 // StalenessInfo returns how stale the grimoire is relative to current git state.
 // Returns a human-readable message, or "" if metadata is unavailable.
 func (g *Grimoire) StalenessInfo(currentDir string) string {
@@ -162,6 +172,11 @@ func (g *Grimoire) Render() string {
 	return sb.String()
 }
 
+// Setting returns the ## Settings value for key, or "" if unset.
+func (g *Grimoire) Setting(key string) string {
+	return g.Settings[key]
+}
+
 // TotalSize returns the total byte size of the rendered grimoire.
 func (g *Grimoire) TotalSize() int {
 	return len(g.Render())
@@ -171,14 +186,14 @@ func (g *Grimoire) TotalSize() int {
 func (g *Grimoire) IsEmpty() bool {
 	return len(g.Bindings) == 0 && len(g.Rituals) == 0 &&
 		len(g.Incantations) == 0 && len(g.Wards) == 0 && len(g.Hooks) == 0 &&
-		len(g.RawSections) == 0
+		len(g.LanguageServers) == 0 && len(g.RawSections) == 0
 }
 
 // MaxSize is the maximum total grimoire context size in bytes.
 const MaxSize = 25 * 1024 // 25KB
 
 // Merge combines multiple grimoires into one, preserving order.
-// Later grimoires take precedence for RawSections keys.
+// Later grimoires take precedence for RawSections and Settings keys.
 func Merge(grimoires ...*Grimoire) *Grimoire {
 	merged := &Grimoire{
 		RawSections: make(map[string]string),
@@ -193,9 +208,16 @@ func Merge(grimoires ...*Grimoire) *Grimoire {
 		merged.Incantations = append(merged.Incantations, g.Incantations...)
 		merged.Wards = append(merged.Wards, g.Wards...)
 		merged.Hooks = append(merged.Hooks, g.Hooks...)
+		merged.LanguageServers = append(merged.LanguageServers, g.LanguageServers...)
 		for k, v := range g.RawSections {
 			merged.RawSections[k] = v
 		}
+		for k, v := range g.Settings {
+			if merged.Settings == nil {
+				merged.Settings = make(map[string]string)
+			}
+			merged.Settings[k] = v
+		}
 	}
 	return merged
 }
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
 )
 
 // runInitCommand handles the "celeste init" subcommand.
@@ -27,6 +36,14 @@ func runInitCommand(args []string) {
 
 // runGrimoireCommand handles the "celeste grimoire" subcommand.
 func runGrimoireCommand(args []string) {
+	if len(args) > 0 && args[0] == "generate" {
+		if err := runGrimoireGenerate(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: cannot determine working directory: %v\n", err)
@@ -56,3 +73,75 @@ func runGrimoireCommand(args []string) {
 	// Show rendered grimoire
 	fmt.Print(g.Render())
 }
+
+// runGrimoireGenerate handles "celeste grimoire generate": the model writes
+// a .grimoire for each repository given, in one batch.
+func runGrimoireGenerate(args []string) error {
+	fs := flag.NewFlagSet("grimoire generate", flag.ExitOnError)
+	useBatch := fs.Bool("batch", false, "Send through the provider's batch API (half price; results can take hours)")
+	force := fs.Bool("force", false, "Replace an existing .grimoire")
+	poll := fs.Int("poll", 30, "Seconds between status checks with -batch")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("usage: celeste grimoire generate [-batch] [-force] <repo-dir>...")
+	}
+
+	var dirs []string
+	var reqs []llm.BatchRequest
+	for _, arg := range fs.Args() {
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".grimoire")); err == nil && !*force {
+			fmt.Fprintf(os.Stderr, "Skipping %s: .grimoire exists (use -force to replace it)\n", dir)
+			continue
+		}
+		prompt, err := grimoire.GenerationPrompt(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+		dirs = append(dirs, dir)
+		reqs = append(reqs, llm.BatchRequest{
+			ID:       fmt.Sprintf("repo-%d", len(reqs)+1),
+			System:   grimoire.GenerationSystemPrompt,
+			Messages: []tui.ChatMessage{{Role: "user", Content: prompt}},
+		})
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	b, cfg, err := openBatcher(*useBatch)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Generating %d grimoire(s) via %s\n", len(reqs), b.Name())
+	results, err := llm.RunBatch(ctx, b, reqs, time.Duration(*poll)*time.Second, printBatchProgress)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, res := range results {
+		if res.Err != nil || res.Content == "" {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: generation failed: %v\n", dirs[i], res.Err)
+			continue
+		}
+		path := filepath.Join(dirs[i], ".grimoire")
+		if err := os.WriteFile(path, []byte(grimoire.GeneratedContent(dirs[i], res.Content)), 0644); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+	printBatchSummary(results, cfg.Model, b.Discounted())
+	if failed > 0 {
+		return fmt.Errorf("%d of %d grimoire(s) failed", failed, len(results))
+	}
+	return nil
+}
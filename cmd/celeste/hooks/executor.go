@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 )
 
 // HookResult holds the outcome of running a hook.
@@ -16,10 +19,11 @@ type HookResult struct {
 	ExitCode int
 }
 
-// Executor runs pre/post tool-use hooks.
+// Executor runs pre/post tool-use and pre-commit hooks.
 type Executor struct {
 	hooks     []Hook
 	workspace string
+	policy    sandbox.Policy
 }
 
 // NewExecutor creates an Executor for the given hooks and workspace directory.
@@ -30,6 +34,13 @@ func NewExecutor(hooks []Hook, workspace string) *Executor {
 	}
 }
 
+// SetSandbox confines hook commands with p, the sandbox policy from the
+// permissions config. Hooks come from the project's .grimoire, so on an
+// untrusted repo they are as untrusted as the model's own commands.
+func (e *Executor) SetSandbox(p sandbox.Policy) {
+	e.policy = p
+}
+
 // RunPreToolUse runs all matching PreToolUse hooks.
 // Returns the first blocking result, or an approve result if all pass.
 func (e *Executor) RunPreToolUse(toolName string, input map[string]any) (*HookResult, error) {
@@ -41,7 +52,19 @@ func (e *Executor) RunPostToolUse(toolName string, input map[string]any) (*HookR
 	return e.runHooks("PostToolUse", toolName, input)
 }
 
-func (e *Executor) runHooks(event, toolName string, input map[string]any) (*HookResult, error) {
+// RunPreCommit runs the PreCommit hooks before git_commit creates a
+// commit. The hooks see the message and the staged files in
+// CELESTE_COMMIT_MESSAGE and CELESTE_COMMIT_FILES (one path per line)
+// rather than through template variables, since both come from the model.
+func (e *Executor) RunPreCommit(message string, files []string) (*HookResult, error) {
+	env := []string{
+		"CELESTE_COMMIT_MESSAGE=" + message,
+		"CELESTE_COMMIT_FILES=" + strings.Join(files, "\n"),
+	}
+	return e.runHooks("PreCommit", "git_commit", nil, env...)
+}
+
+func (e *Executor) runHooks(event, toolName string, input map[string]any, env ...string) (*HookResult, error) {
 	last := &HookResult{Decision: "approve"}
 	for _, h := range e.hooks {
 		if h.Event != event {
@@ -51,7 +74,7 @@ func (e *Executor) runHooks(event, toolName string, input map[string]any) (*Hook
 			continue
 		}
 
-		result, err := e.executeHook(h, toolName, input)
+		result, err := e.executeHook(h, toolName, input, env)
 		if err != nil {
 			return nil, fmt.Errorf("hook execution failed: %w", err)
 		}
@@ -63,24 +86,32 @@ func (e *Executor) runHooks(event, toolName string, input map[string]any) (*Hook
 	return last, nil
 }
 
-func (e *Executor) executeHook(h Hook, toolName string, input map[string]any) (*HookResult, error) {
+func (e *Executor) executeHook(h Hook, toolName string, input map[string]any, env []string) (*HookResult, error) {
 	cmd := expandTemplateVars(h.Command, e.workspace, toolName, input)
 
 	timeout := h.Timeout
 	if timeout <= 0 {
 		timeout = defaultHookTimeout
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), e.policy.Timeout(time.Duration(timeout)*time.Second))
 	defer cancel()
 
 	proc := exec.CommandContext(ctx, "sh", "-c", cmd)
 	proc.Dir = e.workspace
+	if len(env) > 0 {
+		proc.Env = append(os.Environ(), env...)
+	}
+	cleanup, err := e.policy.Wrap(proc)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
 
 	var stdout, stderr bytes.Buffer
 	proc.Stdout = &stdout
 	proc.Stderr = &stderr
 
-	err := proc.Run()
+	err = proc.Run()
 
 	exitCode := 0
 	if err != nil {
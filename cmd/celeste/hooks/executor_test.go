@@ -1,13 +1,22 @@
 package hooks
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 )
 
+func TestMain(m *testing.M) {
+	// A sandboxed hook re-executes the test binary as the helper.
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
 func skipOnWindows(t *testing.T) {
 	t.Helper()
 	if runtime.GOOS == "windows" {
@@ -109,3 +118,39 @@ func TestExecutor_NoMatchingHooks(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "approve", result.Decision)
 }
+
+func TestExecutor_Sandboxed(t *testing.T) {
+	if !sandbox.Probe().Sufficient() {
+		t.Skipf("sandbox unavailable: %s", sandbox.Probe())
+	}
+	workspace, outside := t.TempDir(), t.TempDir()
+	hooks := []Hook{
+		{Event: "PreToolUse", Tool: "*", Command: "touch ok && touch " + filepath.Join(outside, "escape"), Timeout: 5},
+	}
+	exec := NewExecutor(hooks, workspace)
+	exec.SetSandbox(sandbox.Policy{Mode: sandbox.ModeRequired})
+	result, err := exec.RunPreToolUse("bash", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "block", result.Decision, "the write outside the workspace fails")
+	assert.FileExists(t, filepath.Join(workspace, "ok"))
+	assert.NoFileExists(t, filepath.Join(outside, "escape"))
+}
+
+func TestExecutor_PreCommit(t *testing.T) {
+	skipOnWindows(t)
+	hooks := []Hook{
+		{Event: "PreToolUse", Tool: "*", Command: "exit 1", Timeout: 5},
+		{Event: "PreCommit", Tool: "*", Command: `case "$CELESTE_COMMIT_MESSAGE" in wip*) echo "no wip commits"; exit 1;; esac; echo "$CELESTE_COMMIT_FILES"`, Timeout: 5},
+	}
+	exec := NewExecutor(hooks, t.TempDir())
+
+	result, err := exec.RunPreCommit("fix: parser", []string{"a.go", "b.go"})
+	require.NoError(t, err)
+	assert.Equal(t, "approve", result.Decision, "PreToolUse hooks don't run")
+	assert.Equal(t, "a.go\nb.go", result.Output)
+
+	result, err = exec.RunPreCommit("wip: stuff", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "block", result.Decision)
+	assert.Equal(t, "no wip commits", result.Output)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/term"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
+)
+
+// runImageCommand handles `celeste image generate|edit`.
+func runImageCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: celeste image generate|edit [flags] \"<prompt>\"")
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch args[0] {
+	case "generate", "gen":
+		err = runImageGenerate(ctx, args[1:])
+	case "edit":
+		err = runImageEdit(ctx, args[1:])
+	default:
+		err = fmt.Errorf("unknown image subcommand %q. Try: generate, edit", args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// imageFlags are the flags generate and edit share.
+type imageFlags struct {
+	size, model, backend, out *string
+	preview                   *bool
+}
+
+func addImageFlags(fs *flag.FlagSet) imageFlags {
+	return imageFlags{
+		size:    fs.String("size", "", "Image size as WIDTHxHEIGHT (default: the backend's)"),
+		model:   fs.String("model", "", "Image model (default: image_model from the config)"),
+		backend: fs.String("backend", "", "openai or venice (default: image_backend from the config)"),
+		out:     fs.String("out", "", "Directory to save into (default: ~/.celeste/assets/cli)"),
+		preview: fs.Bool("preview", true, "Show the images inline when the terminal supports it"),
+	}
+}
+
+// open returns the generator and store the flags select.
+func (f imageFlags) open() (media.ImageGenerator, *media.Store, error) {
+	cfg, err := config.LoadNamed(configName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading config: %w", err)
+	}
+	if *f.backend != "" {
+		cfg.ImageBackend = *f.backend
+	}
+	gen, err := cfg.ImageGenerator()
+	if err != nil {
+		return nil, nil, err
+	}
+	if gen == nil {
+		return nil, nil, errors.New("no image backend configured: set image_backend and image_api_key, or use an OpenAI or Venice key")
+	}
+	if *f.out != "" {
+		return gen, media.NewStore(*f.out), nil
+	}
+	store, err := media.SessionStore("cli")
+	return gen, store, err
+}
+
+func runImageGenerate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("image generate", flag.ExitOnError)
+	f := addImageFlags(fs)
+	count := fs.Int("n", 1, "Number of variants (1-4)")
+	negative := fs.String("negative", "", "What to keep out of the image, where the backend supports it")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New(`usage: celeste image generate [-size WxH] [-n N] [-model m] [-backend b] [-out dir] "<prompt>"`)
+	}
+	gen, store, err := f.open()
+	if err != nil {
+		return err
+	}
+	req := media.ImageRequest{
+		Prompt:         fs.Arg(0),
+		Model:          *f.model,
+		Size:           *f.size,
+		Count:          min(max(*count, 1), 4),
+		NegativePrompt: *negative,
+	}
+	images, err := gen.Generate(ctx, req)
+	if err != nil {
+		return err
+	}
+	return saveAndShowImages(store, images, media.Asset{Prompt: req.Prompt, Backend: gen.Name(), Model: req.Model}, *f.preview)
+}
+
+func runImageEdit(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("image edit", flag.ExitOnError)
+	f := addImageFlags(fs)
+	imagePath := fs.String("image", "", "PNG to edit (required)")
+	maskPath := fs.String("mask", "", "PNG mask; its transparent pixels mark the area to change")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || *imagePath == "" {
+		return errors.New(`usage: celeste image edit -image <png> [-mask <png>] [-size WxH] [-out dir] "<prompt>"`)
+	}
+	req := media.ImageRequest{Prompt: fs.Arg(0), Model: *f.model, Size: *f.size}
+	var err error
+	if req.Image, err = os.ReadFile(*imagePath); err != nil {
+		return err
+	}
+	if *maskPath != "" {
+		if req.Mask, err = os.ReadFile(*maskPath); err != nil {
+			return err
+		}
+	}
+	gen, store, err := f.open()
+	if err != nil {
+		return err
+	}
+	images, err := gen.Edit(ctx, req)
+	if err != nil {
+		return err
+	}
+	source, _ := filepath.Abs(*imagePath)
+	return saveAndShowImages(store, images, media.Asset{Prompt: req.Prompt, Backend: gen.Name(), Model: req.Model, Source: source}, *f.preview)
+}
+
+// saveAndShowImages stores images, prints their paths and, on a terminal
+// with an image protocol, previews them.
+func saveAndShowImages(store *media.Store, images []media.Image, ref media.Asset, preview bool) error {
+	protocol := media.ProtocolNone
+	cols := 40
+	if preview && term.IsTerminal(int(os.Stdout.Fd())) {
+		protocol = media.DetectProtocol()
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			cols = min(cols, w)
+		}
+	}
+	for _, img := range images {
+		a := ref
+		if img.RevisedPrompt != "" {
+			a.Prompt = img.RevisedPrompt
+		}
+		saved, err := store.Save(img.Data, a)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s (%dx%d)\n", saved.Path, saved.Width, saved.Height)
+		if err := media.WritePreview(os.Stdout, img.Data, protocol, cols); err != nil {
+			fmt.Fprintf(os.Stderr, "preview: %v\n", err)
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
 )
 
@@ -32,7 +33,7 @@ func NewAnthropicBackend(config *Config) (*AnthropicBackend, error) {
 		option.WithAPIKey(config.APIKey),
 	}
 	if config.BaseURL != "" {
-		opts = append(opts, option.WithBaseURL(config.BaseURL))
+		opts = append(opts, option.WithBaseURL(anthropicBaseURL(config.BaseURL)))
 	}
 
 	client := anthropic.NewClient(opts...)
@@ -43,6 +44,14 @@ func NewAnthropicBackend(config *Config) (*AnthropicBackend, error) {
 	}, nil
 }
 
+// anthropicBaseURL drops a trailing /v1 from baseURL. The SDK's request
+// paths already start with v1/, while the provider registry's base URL
+// (shared with the OpenAI-compatible layer) ends with it; passed through
+// unchanged every request went to /v1/v1/messages.
+func anthropicBaseURL(baseURL string) string {
+	return strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+}
+
 // SetSystemPrompt sets the system prompt (Celeste persona).
 func (b *AnthropicBackend) SetSystemPrompt(prompt string) {
 	b.systemPrompt = prompt
@@ -72,15 +81,19 @@ func (b *AnthropicBackend) Close() error {
 // with no downside for normal chat turns (short responses don't consume
 // the budget, only the used tokens are billed).
 func (b *AnthropicBackend) maxTokens() int64 {
+	n := int64(32768) // default for non-thinking requests
 	if b.thinkingConfig.Enabled && b.thinkingConfig.Level != "off" {
-		budget := b.thinkingConfig.LevelToBudget()
-		if budget > 0 {
+		n = 65536 // sensible default when thinking is on
+		if budget := b.thinkingConfig.LevelToBudget(); budget > 0 {
 			// max_tokens must be > budget_tokens; add generous room for output
-			return int64(budget) + 16384
+			n = int64(budget) + 16384
 		}
-		return 65536 // sensible default when thinking is on
 	}
-	return 32768 // default for non-thinking requests
+	// Older and smaller models cap output lower and reject anything above.
+	if m, ok := catalog.Default().Lookup(b.config.Model); ok && m.MaxOutput > 0 && n > int64(m.MaxOutput) {
+		n = int64(m.MaxOutput)
+	}
+	return n
 }
 
 // buildParams constructs the MessageNewParams shared by sync and streaming requests.
@@ -107,15 +120,75 @@ func (b *AnthropicBackend) buildParams(messages []tui.ChatMessage, tools []tui.S
 
 	// Apply thinking config.
 	b.applyThinkingConfig(&params)
+	b.applyCacheBreakpoints(&params)
 
 	return params
 }
 
+// applyCacheBreakpoints marks the ends of the stable prefix: the tool
+// definitions and the history points from historyCachePoints. With the
+// system prompt's breakpoint that is the API's limit of four. The cache
+// covers tools, then system, then messages, so each breakpoint caches
+// everything before it.
+func (b *AnthropicBackend) applyCacheBreakpoints(params *anthropic.MessageNewParams) {
+	if b.config.NoPromptCache {
+		return
+	}
+	if n := len(params.Tools); n > 0 {
+		if cc := params.Tools[n-1].GetCacheControl(); cc != nil {
+			*cc = anthropic.NewCacheControlEphemeralParam()
+		}
+	}
+	roles := make([]string, len(params.Messages))
+	for i, m := range params.Messages {
+		roles[i] = string(m.Role)
+	}
+	for _, i := range historyCachePoints(roles) {
+		content := params.Messages[i].Content
+		// Thinking blocks cannot carry cache_control; mark the last block
+		// that can.
+		for j := len(content) - 1; j >= 0; j-- {
+			if cc := content[j].GetCacheControl(); cc != nil {
+				*cc = anthropic.NewCacheControlEphemeralParam()
+				break
+			}
+		}
+	}
+}
+
+// anthropicUsage folds one event's usage counters into prev. input_tokens
+// counts only the uncached part of the prompt, so cache reads and writes are
+// added back to report the whole prompt. Counters the event leaves at zero
+// keep their earlier value: message_start carries the prompt counts and
+// message_delta the final output count.
+func anthropicUsage(prev *TokenUsage, input, output, cacheWrite, cacheRead int64) *TokenUsage {
+	var u TokenUsage
+	if prev != nil {
+		u = *prev
+	}
+	if prompt := input + cacheWrite + cacheRead; prompt > 0 {
+		u.PromptTokens = int(prompt)
+		u.CachedTokens = int(cacheRead)
+		u.CacheWriteTokens = int(cacheWrite)
+	}
+	if output > 0 {
+		u.CompletionTokens = int(output)
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	if u.TotalTokens == 0 {
+		return prev
+	}
+	return &u
+}
+
 // buildSystemBlocks creates system prompt text blocks with prompt caching.
 // The system prompt is split: the first block gets cache_control for
 // Anthropic's prompt caching, so the static persona/grimoire stays cached
 // across turns.
 func (b *AnthropicBackend) buildSystemBlocks(prompt string) []anthropic.TextBlockParam {
+	if b.config.NoPromptCache {
+		return []anthropic.TextBlockParam{{Text: prompt}}
+	}
 	// Try to split on the separator used by CacheablePrompt.FullPrompt().
 	separator := "\n\n---\n\n"
 	if idx := strings.Index(prompt, separator); idx > 0 {
@@ -155,6 +228,11 @@ func (b *AnthropicBackend) applyThinkingConfig(params *anthropic.MessageNewParam
 	if budget <= 0 {
 		budget = 8192 // default budget
 	}
+	// max_tokens may have been capped at the model's output limit; the
+	// budget has to stay below it and leave room for the answer.
+	if limit := int(params.MaxTokens) - 4096; budget > limit && limit >= 1024 {
+		budget = limit
+	}
 
 	params.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(budget))
 }
@@ -162,6 +240,9 @@ func (b *AnthropicBackend) applyThinkingConfig(params *anthropic.MessageNewParam
 // SendMessageSync sends a message and returns the complete result.
 func (b *AnthropicBackend) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
 	params := b.buildParams(messages, tools)
+	if f := nativeResponseFormat(ctx, b.config.BaseURL); f != nil {
+		params.OutputConfig.Format = anthropic.JSONOutputFormatParam{Schema: f.Schema}
+	}
 
 	// Use streaming internally to accumulate the full response, matching
 	// the pattern used by the OpenAI backend for consistency.
@@ -201,6 +282,8 @@ func (b *AnthropicBackend) SendMessageSync(ctx context.Context, messages []tui.C
 			switch bs.blockType {
 			case "text":
 				result.Content += event.Delta.Text
+			case "thinking":
+				result.Thinking += event.Delta.Thinking
 			case "tool_use":
 				bs.inputJSON += event.Delta.PartialJSON
 			}
@@ -222,22 +305,12 @@ func (b *AnthropicBackend) SendMessageSync(ctx context.Context, messages []tui.C
 			if event.Delta.StopReason != "" {
 				result.FinishReason = mapStopReason(string(event.Delta.StopReason))
 			}
-			if event.Usage.OutputTokens > 0 || event.Usage.InputTokens > 0 {
-				result.Usage = &TokenUsage{
-					PromptTokens:     int(event.Usage.InputTokens),
-					CompletionTokens: int(event.Usage.OutputTokens),
-					TotalTokens:      int(event.Usage.InputTokens + event.Usage.OutputTokens),
-				}
-			}
+			result.Usage = anthropicUsage(result.Usage, event.Usage.InputTokens, event.Usage.OutputTokens,
+				event.Usage.CacheCreationInputTokens, event.Usage.CacheReadInputTokens)
 
 		case "message_start":
-			if event.Message.Usage.InputTokens > 0 {
-				result.Usage = &TokenUsage{
-					PromptTokens:     int(event.Message.Usage.InputTokens),
-					CompletionTokens: int(event.Message.Usage.OutputTokens),
-					TotalTokens:      int(event.Message.Usage.InputTokens + event.Message.Usage.OutputTokens),
-				}
-			}
+			u := event.Message.Usage
+			result.Usage = anthropicUsage(result.Usage, u.InputTokens, u.OutputTokens, u.CacheCreationInputTokens, u.CacheReadInputTokens)
 		}
 	}
 
@@ -252,6 +325,9 @@ func (b *AnthropicBackend) SendMessageSync(ctx context.Context, messages []tui.C
 // SendMessageStream sends a message with streaming callback.
 func (b *AnthropicBackend) SendMessageStream(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamCallback) error {
 	params := b.buildParams(messages, tools)
+	if f := nativeResponseFormat(ctx, b.config.BaseURL); f != nil {
+		params.OutputConfig.Format = anthropic.JSONOutputFormatParam{Schema: f.Schema}
+	}
 
 	stream := b.client.Messages.NewStreaming(ctx, params)
 
@@ -311,13 +387,8 @@ func (b *AnthropicBackend) SendMessageStream(ctx context.Context, messages []tui
 
 		case "message_delta":
 			// Update usage BEFORE sending final callback to avoid stale/nil usage.
-			if event.Usage.OutputTokens > 0 || event.Usage.InputTokens > 0 {
-				usage = &TokenUsage{
-					PromptTokens:     int(event.Usage.InputTokens),
-					CompletionTokens: int(event.Usage.OutputTokens),
-					TotalTokens:      int(event.Usage.InputTokens + event.Usage.OutputTokens),
-				}
-			}
+			usage = anthropicUsage(usage, event.Usage.InputTokens, event.Usage.OutputTokens,
+				event.Usage.CacheCreationInputTokens, event.Usage.CacheReadInputTokens)
 			if event.Delta.StopReason != "" {
 				finishReason := mapStopReason(string(event.Delta.StopReason))
 				callback(StreamChunk{
@@ -329,13 +400,8 @@ func (b *AnthropicBackend) SendMessageStream(ctx context.Context, messages []tui
 			}
 
 		case "message_start":
-			if event.Message.Usage.InputTokens > 0 {
-				usage = &TokenUsage{
-					PromptTokens:     int(event.Message.Usage.InputTokens),
-					CompletionTokens: int(event.Message.Usage.OutputTokens),
-					TotalTokens:      int(event.Message.Usage.InputTokens + event.Message.Usage.OutputTokens),
-				}
-			}
+			u := event.Message.Usage
+			usage = anthropicUsage(usage, u.InputTokens, u.OutputTokens, u.CacheCreationInputTokens, u.CacheReadInputTokens)
 		}
 	}
 
@@ -359,6 +425,9 @@ func (b *AnthropicBackend) SendMessageStream(ctx context.Context, messages []tui
 // SendMessageStreamEvents sends a message with granular streaming events.
 func (b *AnthropicBackend) SendMessageStreamEvents(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamEventCallback) error {
 	params := b.buildParams(messages, tools)
+	if f := nativeResponseFormat(ctx, b.config.BaseURL); f != nil {
+		params.OutputConfig.Format = anthropic.JSONOutputFormatParam{Schema: f.Schema}
+	}
 
 	stream := b.client.Messages.NewStreaming(ctx, params)
 
@@ -405,6 +474,13 @@ func (b *AnthropicBackend) SendMessageStreamEvents(ctx context.Context, messages
 						ContentDelta: event.Delta.Text,
 					})
 				}
+			case "thinking":
+				if event.Delta.Thinking != "" {
+					callback(StreamEvent{
+						Type:         EventThinkingDelta,
+						ContentDelta: event.Delta.Thinking,
+					})
+				}
 			case "tool_use":
 				if event.Delta.PartialJSON != "" {
 					bs.inputJSON += event.Delta.PartialJSON
@@ -434,22 +510,12 @@ func (b *AnthropicBackend) SendMessageStreamEvents(ctx context.Context, messages
 			if event.Delta.StopReason != "" {
 				finishReason = mapStopReason(string(event.Delta.StopReason))
 			}
-			if event.Usage.OutputTokens > 0 || event.Usage.InputTokens > 0 {
-				usage = &TokenUsage{
-					PromptTokens:     int(event.Usage.InputTokens),
-					CompletionTokens: int(event.Usage.OutputTokens),
-					TotalTokens:      int(event.Usage.InputTokens + event.Usage.OutputTokens),
-				}
-			}
+			usage = anthropicUsage(usage, event.Usage.InputTokens, event.Usage.OutputTokens,
+				event.Usage.CacheCreationInputTokens, event.Usage.CacheReadInputTokens)
 
 		case "message_start":
-			if event.Message.Usage.InputTokens > 0 {
-				usage = &TokenUsage{
-					PromptTokens:     int(event.Message.Usage.InputTokens),
-					CompletionTokens: int(event.Message.Usage.OutputTokens),
-					TotalTokens:      int(event.Message.Usage.InputTokens + event.Message.Usage.OutputTokens),
-				}
-			}
+			u := event.Message.Usage
+			usage = anthropicUsage(usage, u.InputTokens, u.OutputTokens, u.CacheCreationInputTokens, u.CacheReadInputTokens)
 		}
 	}
 
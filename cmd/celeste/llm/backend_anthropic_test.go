@@ -3,6 +3,7 @@ package llm
 import (
 	"testing"
 
+	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
@@ -189,6 +190,16 @@ func TestAnthropicMaxTokens(t *testing.T) {
 		// high = 16384 budget + 16384 output room = 32768
 		assert.Equal(t, int64(32768), backend.maxTokens())
 	})
+
+	t.Run("capped at the model's output limit", func(t *testing.T) {
+		backend := &AnthropicBackend{config: &Config{Model: "claude-haiku-4-5"}}
+		backend.thinkingConfig = ThinkingConfig{Enabled: true, Level: "max"}
+		assert.Equal(t, int64(64000), backend.maxTokens())
+
+		params := anthropic.MessageNewParams{MaxTokens: backend.maxTokens()}
+		backend.applyThinkingConfig(&params)
+		assert.Equal(t, int64(64000-4096), params.Thinking.OfEnabled.BudgetTokens)
+	})
 }
 
 func TestMapStopReason(t *testing.T) {
@@ -208,3 +219,64 @@ func TestDetectBackendTypeAnthropic(t *testing.T) {
 	assert.Equal(t, BackendTypeAnthropic, DetectBackendType("https://api.anthropic.com/v1"))
 	assert.Equal(t, BackendTypeAnthropic, DetectBackendType("https://api.anthropic.com"))
 }
+
+func countBreakpoints(params anthropic.MessageNewParams) (tools, system, messages int) {
+	for _, tool := range params.Tools {
+		if cc := tool.GetCacheControl(); cc != nil && cc.Type != "" {
+			tools++
+		}
+	}
+	for _, block := range params.System {
+		if block.CacheControl.Type != "" {
+			system++
+		}
+	}
+	for _, msg := range params.Messages {
+		for _, block := range msg.Content {
+			if cc := block.GetCacheControl(); cc != nil && cc.Type != "" {
+				messages++
+			}
+		}
+	}
+	return
+}
+
+func TestAnthropicCacheBreakpoints(t *testing.T) {
+	history := []tui.ChatMessage{
+		{Role: "user", Content: "fix the test"},
+		{Role: "assistant", ToolCalls: []tui.ToolCallInfo{{ID: "t1", Name: "read_file", Arguments: `{"path":"a.go"}`}}},
+		{Role: "tool", ToolCallID: "t1", Name: "read_file", Content: "package a"},
+	}
+	tools := []tui.SkillDefinition{
+		{Name: "read_file", Description: "read", Parameters: map[string]any{"type": "object"}},
+		{Name: "write_file", Description: "write", Parameters: map[string]any{"type": "object"}},
+	}
+
+	backend := &AnthropicBackend{config: &Config{Model: "claude-sonnet-4-5"}, systemPrompt: "persona"}
+	params := backend.buildParams(history, tools)
+	toolMarks, systemMarks, messageMarks := countBreakpoints(params)
+	assert.Equal(t, 1, toolMarks)
+	assert.Equal(t, 1, systemMarks)
+	assert.Equal(t, 2, messageMarks, "end of this request and end of the previous one")
+	assert.NotEmpty(t, params.Tools[1].GetCacheControl().Type, "breakpoint belongs on the last tool")
+	assert.LessOrEqual(t, toolMarks+systemMarks+messageMarks, 4)
+
+	backend.config.NoPromptCache = true
+	toolMarks, systemMarks, messageMarks = countBreakpoints(backend.buildParams(history, tools))
+	assert.Zero(t, toolMarks+systemMarks+messageMarks)
+}
+
+func TestAnthropicUsageCountsCachedPrompt(t *testing.T) {
+	// message_start: 120 uncached + 9000 read + 400 written.
+	usage := anthropicUsage(nil, 120, 1, 400, 9000)
+	// message_delta: output only.
+	usage = anthropicUsage(usage, 0, 250, 0, 0)
+	require.NotNil(t, usage)
+	assert.Equal(t, 9520, usage.PromptTokens)
+	assert.Equal(t, 9000, usage.CachedTokens)
+	assert.Equal(t, 400, usage.CacheWriteTokens)
+	assert.Equal(t, 250, usage.CompletionTokens)
+	assert.Equal(t, 9770, usage.TotalTokens)
+	assert.InDelta(t, 0.945, usage.CacheHitRate(), 0.001)
+	assert.Nil(t, anthropicUsage(nil, 0, 0, 0, 0))
+}
@@ -21,6 +21,7 @@ type GoogleBackend struct {
 	config         *Config
 	systemPrompt   string
 	thinkingConfig ThinkingConfig
+	cache          geminiContextCache
 }
 
 // googleAPIVersion picks the Google API version for a base URL.
@@ -131,6 +132,8 @@ func (b *GoogleBackend) SendMessageSync(ctx context.Context, messages []tui.Chat
 	}
 
 	b.applyThinkingConfig(genConfig)
+	b.applyResponseFormat(ctx, genConfig)
+	b.applyContextCache(ctx, genConfig)
 
 	// Generate content
 	modelName := b.config.Model
@@ -140,7 +143,7 @@ func (b *GoogleBackend) SendMessageSync(ctx context.Context, messages []tui.Chat
 	}
 
 	// Parse response
-	result := &ChatCompletionResult{}
+	result := &ChatCompletionResult{Usage: googleUsage(resp.UsageMetadata)}
 
 	if len(resp.Candidates) > 0 {
 		candidate := resp.Candidates[0]
@@ -148,6 +151,7 @@ func (b *GoogleBackend) SendMessageSync(ctx context.Context, messages []tui.Chat
 		// Extract text content
 		if candidate.Content != nil {
 			result.Content = extractText(candidate.Content)
+			result.Thinking = extractThoughts(candidate.Content)
 		}
 
 		// Extract tool calls (function calls)
@@ -196,6 +200,8 @@ func (b *GoogleBackend) SendMessageStream(ctx context.Context, messages []tui.Ch
 	}
 
 	b.applyThinkingConfig(genConfig)
+	b.applyResponseFormat(ctx, genConfig)
+	b.applyContextCache(ctx, genConfig)
 
 	// Stream the response
 	modelName := b.config.Model
@@ -203,6 +209,7 @@ func (b *GoogleBackend) SendMessageStream(ctx context.Context, messages []tui.Ch
 
 	var fullContent strings.Builder
 	var toolCalls []ToolCallResult
+	var usage *TokenUsage
 	isFirst := true
 	var lastFinishReason string
 
@@ -211,6 +218,9 @@ func (b *GoogleBackend) SendMessageStream(ctx context.Context, messages []tui.Ch
 		if err != nil {
 			return fmt.Errorf("Google AI stream error: %w", err)
 		}
+		if u := googleUsage(chunk.UsageMetadata); u != nil {
+			usage = u
+		}
 
 		// Process each candidate in the chunk
 		for _, candidate := range chunk.Candidates {
@@ -253,7 +263,7 @@ func (b *GoogleBackend) SendMessageStream(ctx context.Context, messages []tui.Ch
 		IsFinal:      true,
 		FinishReason: lastFinishReason,
 		ToolCalls:    toolCalls,
-		Usage:        nil, // Google GenAI SDK doesn't provide token usage in streaming yet
+		Usage:        usage,
 	})
 
 	return nil
@@ -285,22 +295,34 @@ func (b *GoogleBackend) SendMessageStreamEvents(ctx context.Context, messages []
 	}
 
 	b.applyThinkingConfig(genConfig)
+	b.applyResponseFormat(ctx, genConfig)
+	b.applyContextCache(ctx, genConfig)
 
 	// Stream the response
 	modelName := b.config.Model
 	streamIter := b.client.Models.GenerateContentStream(ctx, modelName, contents, genConfig)
 
 	var lastFinishReason string
+	var usage *TokenUsage
 
 	// Iterate over streaming chunks
 	for chunk, err := range streamIter {
 		if err != nil {
 			return fmt.Errorf("Google AI stream error: %w", err)
 		}
+		if u := googleUsage(chunk.UsageMetadata); u != nil {
+			usage = u
+		}
 
 		for _, candidate := range chunk.Candidates {
 			if candidate.Content != nil {
 				// Extract text content
+				if thought := extractThoughts(candidate.Content); thought != "" {
+					callback(StreamEvent{
+						Type:         EventThinkingDelta,
+						ContentDelta: thought,
+					})
+				}
 				text := extractText(candidate.Content)
 				if text != "" {
 					callback(StreamEvent{
@@ -346,7 +368,7 @@ func (b *GoogleBackend) SendMessageStreamEvents(ctx context.Context, messages []
 	}
 	callback(StreamEvent{
 		Type:         EventMessageDone,
-		Usage:        nil, // Google GenAI SDK doesn't provide token usage in streaming yet
+		Usage:        usage,
 		FinishReason: lastFinishReason,
 	})
 
@@ -370,9 +392,22 @@ func (b *GoogleBackend) applyThinkingConfig(genConfig *genai.GenerateContentConf
 	genConfig.ThinkingConfig = tc
 }
 
-// Close cleans up resources.
+// applyResponseFormat asks for JSON matching the call's ResponseFormat.
+// Older Gemini models reject a JSON response type alongside function
+// declarations, so requests that offer tools rely on validation alone.
+func (b *GoogleBackend) applyResponseFormat(ctx context.Context, genConfig *genai.GenerateContentConfig) {
+	f := nativeResponseFormat(ctx, b.config.BaseURL)
+	if f == nil || len(genConfig.Tools) > 0 {
+		return
+	}
+	genConfig.ResponseMIMEType = "application/json"
+	genConfig.ResponseJsonSchema = f.Schema
+}
+
+// Close cleans up resources. The SDK client needs none; a context cache is
+// deleted rather than left to be billed until it expires.
 func (b *GoogleBackend) Close() error {
-	// Google GenAI SDK client doesn't require explicit cleanup
+	b.cache.release(b.client)
 	return nil
 }
 
@@ -604,10 +639,22 @@ func extractText(content *genai.Content) string {
 	var text strings.Builder
 
 	for _, part := range content.Parts {
-		if part.Text != "" {
+		if part.Text != "" && !part.Thought {
 			text.WriteString(part.Text)
 		}
 	}
 
 	return text.String()
 }
+
+// extractThoughts returns the thought summaries IncludeThoughts asks for.
+// They arrive as text parts flagged Thought and are kept out of the reply.
+func extractThoughts(content *genai.Content) string {
+	var text strings.Builder
+	for _, part := range content.Parts {
+		if part.Thought && part.Text != "" {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
+}
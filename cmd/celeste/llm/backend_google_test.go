@@ -87,6 +87,16 @@ func TestGoogleFunctionCallCapturesThoughtSignature(t *testing.T) {
 		"signature from the enclosing Part must reach ToolCallResult")
 }
 
+func TestGoogleThoughtsAreSeparatedFromText(t *testing.T) {
+	content := &genai.Content{Parts: []*genai.Part{
+		{Text: "Check the imports first.", Thought: true},
+		{Text: "The import is unused."},
+	}}
+
+	assert.Equal(t, "The import is unused.", extractText(content), "thoughts must not leak into the reply")
+	assert.Equal(t, "Check the imports first.", extractThoughts(content))
+}
+
 func TestGoogleOutboundEchoesThoughtSignature(t *testing.T) {
 	backend := &GoogleBackend{}
 	sig := []byte("opaque-signature-bytes")
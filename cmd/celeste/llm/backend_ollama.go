@@ -0,0 +1,497 @@
+// Package llm provides the LLM client for Celeste CLI.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// OllamaBackend implements LLMBackend against Ollama's native /api/chat
+// streaming protocol. Compared with Ollama's OpenAI-compatible layer it sends
+// keep_alive and num_ctx, so the server's context window matches the budget
+// celeste computes, and it receives tool calls the server has already parsed
+// out of the model's template.
+type OllamaBackend struct {
+	client         *http.Client
+	config         *Config
+	systemPrompt   string
+	thinkingConfig ThinkingConfig
+	callSeq        atomic.Int64
+}
+
+// NewOllamaBackend creates a backend for the Ollama server at config.BaseURL.
+// No API key is needed; none is sent.
+func NewOllamaBackend(config *Config) *OllamaBackend {
+	// No client-level timeout: a stream may legitimately run for minutes on
+	// CPU. The caller's context (per-attempt timeout) bounds each request.
+	return &OllamaBackend{client: &http.Client{}, config: config}
+}
+
+// SetSystemPrompt sets the system prompt (Celeste persona).
+func (b *OllamaBackend) SetSystemPrompt(prompt string) {
+	b.systemPrompt = prompt
+}
+
+// SetThinkingConfig enables Ollama's think flag for reasoning models. Ollama
+// has no budget control, so the level only decides on or off.
+func (b *OllamaBackend) SetThinkingConfig(config ThinkingConfig) {
+	b.thinkingConfig = config
+}
+
+// Close cleans up resources (no-op for Ollama backend).
+func (b *OllamaBackend) Close() error {
+	return nil
+}
+
+// ollamaMessage is a message in /api/chat requests and responses.
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type ollamaChatRequest struct {
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Tools     []ollamaTool    `json:"tools,omitempty"`
+	Stream    bool            `json:"stream"`
+	Think     *bool           `json:"think,omitempty"`
+	KeepAlive json.RawMessage `json:"keep_alive,omitempty"`
+	Options   map[string]any  `json:"options,omitempty"`
+	Format    map[string]any  `json:"format,omitempty"`
+}
+
+// ollamaChatChunk is one NDJSON line of a streamed /api/chat response.
+type ollamaChatChunk struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// buildRequest converts the conversation into an /api/chat request.
+func (b *OllamaBackend) buildRequest(messages []tui.ChatMessage, tools []tui.SkillDefinition) ollamaChatRequest {
+	req := ollamaChatRequest{
+		Model:    b.config.Model,
+		Messages: b.convertMessages(messages),
+		Tools:    convertOllamaTools(tools),
+		Stream:   true,
+	}
+	if b.thinkingConfig.Enabled && b.thinkingConfig.Level != "off" {
+		think := true
+		req.Think = &think
+	}
+	req.KeepAlive = ollamaKeepAlive(b.config.KeepAlive)
+
+	// Send the window celeste budgets for. Without num_ctx Ollama allocates
+	// its own default and silently truncates the oldest tokens beyond it,
+	// while compaction believes there is room to spare.
+	numCtx := b.config.ContextLimit
+	if numCtx <= 0 {
+		numCtx, _ = providers.OllamaContextLength(b.config.BaseURL, b.config.Model)
+	}
+	if numCtx > 0 {
+		req.Options = map[string]any{"num_ctx": numCtx}
+	}
+	return req
+}
+
+// ollamaKeepAlive encodes keep_alive. Ollama takes a duration string ("10m")
+// or a number of seconds; "-1" and "0" only work as numbers.
+func ollamaKeepAlive(v string) json.RawMessage {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return json.RawMessage(strconv.Itoa(n))
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// convertMessages converts TUI messages to Ollama format. Tool results carry
+// the tool's name rather than a call ID, which Ollama does not track.
+func (b *OllamaBackend) convertMessages(messages []tui.ChatMessage) []ollamaMessage {
+	var result []ollamaMessage
+	if b.systemPrompt != "" {
+		result = append(result, ollamaMessage{Role: "system", Content: b.systemPrompt})
+	}
+
+	callNames := make(map[string]string)
+	for _, msg := range messages {
+		out := ollamaMessage{Role: msg.Role, Content: msg.Content}
+		if img := imageFromMetadata(msg.Metadata); img != "" {
+			out.Images = []string{img}
+		}
+		switch msg.Role {
+		case "assistant":
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Name
+				var call ollamaToolCall
+				call.Function.Name = tc.Name
+				call.Function.Arguments = json.RawMessage(tc.Arguments)
+				if !json.Valid(call.Function.Arguments) {
+					call.Function.Arguments = json.RawMessage("{}")
+				}
+				out.ToolCalls = append(out.ToolCalls, call)
+			}
+			if out.Content == "" && len(out.ToolCalls) == 0 {
+				continue
+			}
+		case "tool":
+			out.ToolName = callNames[msg.ToolCallID]
+			if out.ToolName == "" {
+				out.ToolName = msg.Name
+			}
+		}
+		result = append(result, out)
+	}
+	return result
+}
+
+// imageFromMetadata returns the base64 image attached to a message, if any.
+func imageFromMetadata(meta map[string]any) string {
+	if meta == nil {
+		return ""
+	}
+	if t, _ := meta["type"].(string); t != "image" {
+		return ""
+	}
+	b64, _ := meta["base64"].(string)
+	return b64
+}
+
+func convertOllamaTools(tools []tui.SkillDefinition) []ollamaTool {
+	var result []ollamaTool
+	for _, t := range tools {
+		var ot ollamaTool
+		ot.Type = "function"
+		ot.Function.Name = t.Name
+		ot.Function.Description = t.Description
+		ot.Function.Parameters = t.Parameters
+		if ot.Function.Parameters == nil {
+			ot.Function.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		result = append(result, ot)
+	}
+	return result
+}
+
+// chat runs one streamed /api/chat request. onContent receives text deltas,
+// except text that turns out to be a tool call the model wrote out as text;
+// those are parsed into result.ToolCalls instead.
+func (b *OllamaBackend) chat(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, onContent func(string)) (*ChatCompletionResult, error) {
+	chatReq := b.buildRequest(messages, tools)
+	if f := nativeResponseFormat(ctx, b.config.BaseURL); f != nil {
+		chatReq.Format = f.Schema
+	}
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, providers.OllamaAPIBase(b.config.BaseURL)+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		// "status code N" is what the retry classifier keys on.
+		return nil, fmt.Errorf("ollama: status code %d: %s", resp.StatusCode, msg)
+	}
+
+	hold := &textCallHold{enabled: len(tools) > 0, emit: onContent}
+	result := &ChatCompletionResult{}
+	var content, thinking strings.Builder
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var chunk ollamaChatChunk
+		if err := dec.Decode(&chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("ollama: stream ended before done")
+			}
+			return nil, fmt.Errorf("ollama: read stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		thinking.WriteString(chunk.Message.Thinking)
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			hold.write(chunk.Message.Content)
+		}
+		for i, tc := range chunk.Message.ToolCalls {
+			result.ToolCalls = append(result.ToolCalls, b.toolCallResult(tc, i))
+		}
+		if chunk.Done {
+			result.FinishReason = chunk.DoneReason
+			if chunk.PromptEvalCount > 0 || chunk.EvalCount > 0 {
+				result.Usage = &TokenUsage{
+					PromptTokens:     chunk.PromptEvalCount,
+					CompletionTokens: chunk.EvalCount,
+					TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+				}
+			}
+			break
+		}
+	}
+
+	result.Content = content.String()
+	result.Thinking = thinking.String()
+	if len(result.ToolCalls) == 0 && len(tools) > 0 {
+		if calls, rest := parseOllamaTextToolCalls(result.Content, tools); len(calls) > 0 {
+			for i, c := range calls {
+				c.ID = b.newCallID(i)
+				result.ToolCalls = append(result.ToolCalls, c)
+			}
+			result.Content = rest
+			if hold.holding {
+				hold.emitText(rest)
+			}
+		}
+	}
+	hold.flush()
+
+	switch {
+	case len(result.ToolCalls) > 0:
+		result.FinishReason = "tool_calls"
+	case result.FinishReason != "length":
+		result.FinishReason = "stop"
+	}
+	return result, nil
+}
+
+func (b *OllamaBackend) toolCallResult(tc ollamaToolCall, i int) ToolCallResult {
+	args := string(tc.Function.Arguments)
+	if args == "" || args == "null" {
+		args = "{}"
+	}
+	// Some models double-encode: arguments arrive as a JSON string.
+	var s string
+	if json.Unmarshal(tc.Function.Arguments, &s) == nil && json.Valid([]byte(s)) {
+		args = s
+	}
+	id := tc.ID
+	if id == "" {
+		id = b.newCallID(i)
+	}
+	return ToolCallResult{ID: id, Name: tc.Function.Name, Arguments: args}
+}
+
+// newCallID invents a tool call ID. Ollama does not use them, but celeste
+// links tool results to calls by ID, so they must be unique in the history.
+func (b *OllamaBackend) newCallID(i int) string {
+	return fmt.Sprintf("call_%x_%d_%d", time.Now().UnixNano(), b.callSeq.Add(1), i)
+}
+
+// textCallHold withholds streamed text that starts like a tool call ("{" or
+// "<tool_call>") until the response is complete, so a call the model wrote
+// as text is executed rather than printed. Anything else streams through.
+type textCallHold struct {
+	enabled bool
+	emit    func(string)
+	buf     strings.Builder
+	decided bool
+	holding bool
+}
+
+const toolCallTag = "<tool_call>"
+
+func (h *textCallHold) write(delta string) {
+	if h.emit == nil {
+		return
+	}
+	if !h.enabled || (h.decided && !h.holding) {
+		h.emit(delta)
+		return
+	}
+	h.buf.WriteString(delta)
+	if h.decided {
+		return
+	}
+	t := strings.TrimLeft(h.buf.String(), " \t\r\n")
+	if t == "" || (len(t) < len(toolCallTag) && strings.HasPrefix(toolCallTag, t)) {
+		return // not enough text to decide yet
+	}
+	h.decided = true
+	h.holding = t[0] == '{' || strings.HasPrefix(t, toolCallTag)
+	if !h.holding {
+		h.emit(h.buf.String())
+		h.buf.Reset()
+	}
+}
+
+// emitText replaces the held text with what remains after tool calls were
+// extracted from it.
+func (h *textCallHold) emitText(rest string) {
+	h.buf.Reset()
+	if h.emit != nil && strings.TrimSpace(rest) != "" {
+		h.emit(rest)
+	}
+}
+
+func (h *textCallHold) flush() {
+	if h.emit != nil && h.buf.Len() > 0 {
+		h.emit(h.buf.String())
+	}
+	h.buf.Reset()
+}
+
+// parseOllamaTextToolCalls recognizes tool calls a model emitted as text
+// instead of through its template: <tool_call>{...}</tool_call> blocks, or a
+// response that is nothing but {"name": ..., "arguments"|"parameters": {...}}.
+// Only names of offered tools count, so ordinary JSON answers are left alone.
+// It returns the calls and the content with them removed.
+func parseOllamaTextToolCalls(content string, tools []tui.SkillDefinition) ([]ToolCallResult, string) {
+	offered := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		offered[t.Name] = true
+	}
+	decode := func(s string) (ToolCallResult, bool) {
+		var call struct {
+			Name       string          `json:"name"`
+			Arguments  json.RawMessage `json:"arguments"`
+			Parameters json.RawMessage `json:"parameters"`
+		}
+		if json.Unmarshal([]byte(strings.TrimSpace(s)), &call) != nil || !offered[call.Name] {
+			return ToolCallResult{}, false
+		}
+		args := call.Arguments
+		if len(args) == 0 {
+			args = call.Parameters
+		}
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+		return ToolCallResult{Name: call.Name, Arguments: string(args)}, true
+	}
+
+	var calls []ToolCallResult
+	var rest strings.Builder
+	remaining := content
+	for {
+		start := strings.Index(remaining, toolCallTag)
+		if start < 0 {
+			break
+		}
+		after := remaining[start+len(toolCallTag):]
+		end := strings.Index(after, "</tool_call>")
+		if end < 0 {
+			break
+		}
+		if c, ok := decode(after[:end]); ok {
+			calls = append(calls, c)
+			rest.WriteString(remaining[:start])
+		} else {
+			rest.WriteString(remaining[:start+len(toolCallTag)+end+len("</tool_call>")])
+		}
+		remaining = after[end+len("</tool_call>"):]
+	}
+	if len(calls) > 0 {
+		rest.WriteString(remaining)
+		return calls, strings.TrimSpace(rest.String())
+	}
+
+	if c, ok := decode(content); ok {
+		return []ToolCallResult{c}, ""
+	}
+	return nil, content
+}
+
+// SendMessageSync sends a message and returns the complete result.
+func (b *OllamaBackend) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
+	return b.chat(ctx, messages, tools, nil)
+}
+
+// SendMessageStream sends a message with streaming callback.
+func (b *OllamaBackend) SendMessageStream(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamCallback) error {
+	isFirst := true
+	result, err := b.chat(ctx, messages, tools, func(delta string) {
+		callback(StreamChunk{Content: delta, IsFirst: isFirst})
+		isFirst = false
+	})
+	if err != nil {
+		return err
+	}
+	callback(StreamChunk{
+		IsFinal:      true,
+		FinishReason: result.FinishReason,
+		ToolCalls:    result.ToolCalls,
+		Usage:        result.Usage,
+	})
+	return nil
+}
+
+// SendMessageStreamEvents sends a message with granular streaming events.
+// Ollama delivers each tool call whole, so start and done are emitted
+// together with no input deltas in between. Thinking arrives as one event
+// once the reply is complete.
+func (b *OllamaBackend) SendMessageStreamEvents(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamEventCallback) error {
+	result, err := b.chat(ctx, messages, tools, func(delta string) {
+		callback(StreamEvent{Type: EventContentDelta, ContentDelta: delta})
+	})
+	if err != nil {
+		return err
+	}
+	if result.Thinking != "" {
+		callback(StreamEvent{Type: EventThinkingDelta, ContentDelta: result.Thinking})
+	}
+	for _, tc := range result.ToolCalls {
+		callback(StreamEvent{Type: EventToolUseStart, ToolUseID: tc.ID, ToolName: tc.Name})
+		callback(StreamEvent{Type: EventToolUseDone, ToolUseID: tc.ID, ToolName: tc.Name, CompleteInput: tc.Arguments})
+	}
+	callback(StreamEvent{
+		Type:         EventMessageDone,
+		Usage:        result.Usage,
+		FinishReason: result.FinishReason,
+	})
+	return nil
+}
@@ -0,0 +1,228 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// ollamaStandIn serves /api/show and a scripted /api/chat stream, recording
+// the last chat request.
+type ollamaStandIn struct {
+	lines []string
+	got   ollamaChatRequest
+}
+
+func (s *ollamaStandIn) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			fmt.Fprint(w, `{"parameters":"num_ctx 16384","model_info":{"llama.context_length":131072}}`)
+		case "/api/chat":
+			if err := json.NewDecoder(r.Body).Decode(&s.got); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+			for _, line := range s.lines {
+				fmt.Fprintln(w, line)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var ollamaTestTools = []tui.SkillDefinition{{
+	Name:        "read_file",
+	Description: "Read a file",
+	Parameters:  map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}}},
+}}
+
+func TestOllamaStreamsContentAndUsage(t *testing.T) {
+	stand := &ollamaStandIn{lines: []string{
+		`{"message":{"role":"assistant","content":"Hel"},"done":false}`,
+		`{"message":{"role":"assistant","content":"lo"},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":3}`,
+	}}
+	srv := stand.start(t)
+	b := NewOllamaBackend(&Config{BaseURL: srv.URL, Model: "llama3.1:8b", KeepAlive: "-1"})
+	b.SetSystemPrompt("persona")
+
+	var events []StreamEvent
+	err := b.SendMessageStreamEvents(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil, func(e StreamEvent) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].ContentDelta != "Hel" || events[1].ContentDelta != "lo" {
+		t.Fatalf("events = %+v", events)
+	}
+	done := events[2]
+	if done.Type != EventMessageDone || done.FinishReason != "stop" || done.Usage == nil || done.Usage.TotalTokens != 15 {
+		t.Errorf("done event = %+v", done)
+	}
+
+	if string(stand.got.KeepAlive) != "-1" {
+		t.Errorf("keep_alive = %s, want the number -1", stand.got.KeepAlive)
+	}
+	if n, _ := stand.got.Options["num_ctx"].(float64); n != 16384 {
+		t.Errorf("num_ctx = %v, want the Modelfile's 16384", stand.got.Options["num_ctx"])
+	}
+	if len(stand.got.Messages) != 2 || stand.got.Messages[0].Role != "system" || stand.got.Messages[0].Content != "persona" {
+		t.Errorf("messages = %+v", stand.got.Messages)
+	}
+}
+
+func TestOllamaThinkingIsKeptApartFromContent(t *testing.T) {
+	stand := &ollamaStandIn{lines: []string{
+		`{"message":{"role":"assistant","content":"","thinking":"The user "},"done":false}`,
+		`{"message":{"role":"assistant","content":"","thinking":"said hi."},"done":false}`,
+		`{"message":{"role":"assistant","content":"Hello"},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}`,
+	}}
+	srv := stand.start(t)
+	b := NewOllamaBackend(&Config{BaseURL: srv.URL, Model: "qwen3:8b"})
+
+	var content, thinking string
+	err := b.SendMessageStreamEvents(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil, func(e StreamEvent) {
+		switch e.Type {
+		case EventContentDelta:
+			content += e.ContentDelta
+		case EventThinkingDelta:
+			thinking += e.ContentDelta
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Hello" || thinking != "The user said hi." {
+		t.Errorf("content = %q, thinking = %q", content, thinking)
+	}
+
+	res, err := b.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != "Hello" || res.Thinking != "The user said hi." {
+		t.Errorf("sync result = %+v", res)
+	}
+}
+
+func TestOllamaNativeToolCalls(t *testing.T) {
+	stand := &ollamaStandIn{lines: []string{
+		`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"read_file","arguments":{"path":"go.mod"}}}]},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}`,
+	}}
+	srv := stand.start(t)
+	b := NewOllamaBackend(&Config{BaseURL: srv.URL, Model: "qwen3", ContextLimit: 8192})
+
+	history := []tui.ChatMessage{
+		{Role: "user", Content: "read it"},
+		{Role: "assistant", ToolCalls: []tui.ToolCallInfo{{ID: "call_1", Name: "read_file", Arguments: `{"path":"a"}`}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "contents"},
+	}
+	res, err := b.SendMessageSync(context.Background(), history, ollamaTestTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.ToolCalls) != 1 || res.ToolCalls[0].Name != "read_file" || res.ToolCalls[0].Arguments != `{"path":"go.mod"}` {
+		t.Fatalf("tool calls = %+v", res.ToolCalls)
+	}
+	if res.ToolCalls[0].ID == "" || res.FinishReason != "tool_calls" {
+		t.Errorf("id = %q, finish = %q", res.ToolCalls[0].ID, res.FinishReason)
+	}
+
+	if got := stand.got.Messages[2].ToolName; got != "read_file" {
+		t.Errorf("tool result tool_name = %q, want read_file", got)
+	}
+	if n, _ := stand.got.Options["num_ctx"].(float64); n != 8192 {
+		t.Errorf("configured context_limit should win, num_ctx = %v", stand.got.Options["num_ctx"])
+	}
+	if len(stand.got.Tools) != 1 || stand.got.Tools[0].Function.Name != "read_file" {
+		t.Errorf("tools = %+v", stand.got.Tools)
+	}
+}
+
+func TestOllamaTextToolCallIsParsedNotPrinted(t *testing.T) {
+	stand := &ollamaStandIn{lines: []string{
+		`{"message":{"role":"assistant","content":"{\"name\": \"read_file\", "},"done":false}`,
+		`{"message":{"role":"assistant","content":"\"parameters\": {\"path\": \"main.go\"}}"},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}`,
+	}}
+	srv := stand.start(t)
+	b := NewOllamaBackend(&Config{BaseURL: srv.URL, Model: "llama3.2"})
+
+	var printed strings.Builder
+	var calls []string
+	err := b.SendMessageStreamEvents(context.Background(), []tui.ChatMessage{{Role: "user", Content: "read main.go"}}, ollamaTestTools, func(e StreamEvent) {
+		switch e.Type {
+		case EventContentDelta:
+			printed.WriteString(e.ContentDelta)
+		case EventToolUseDone:
+			calls = append(calls, e.ToolName+" "+e.CompleteInput)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printed.Len() != 0 {
+		t.Errorf("tool call JSON was printed: %q", printed.String())
+	}
+	if len(calls) != 1 || calls[0] != `read_file {"path": "main.go"}` {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestParseOllamaTextToolCallsIgnoresUnofferedNames(t *testing.T) {
+	calls, rest := parseOllamaTextToolCalls(`{"name": "Alice", "age": 3}`, ollamaTestTools)
+	if len(calls) != 0 || rest != `{"name": "Alice", "age": 3}` {
+		t.Errorf("plain JSON answer treated as a tool call: %+v", calls)
+	}
+
+	calls, rest = parseOllamaTextToolCalls("Checking.\n<tool_call>{\"name\":\"read_file\",\"arguments\":{\"path\":\"x\"}}</tool_call>", ollamaTestTools)
+	if len(calls) != 1 || rest != "Checking." {
+		t.Errorf("calls = %+v, rest = %q", calls, rest)
+	}
+}
+
+func TestOllamaErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/show" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"nope\" not found, try pulling it first"}`)
+	}))
+	defer srv.Close()
+
+	b := NewOllamaBackend(&Config{BaseURL: srv.URL, Model: "nope"})
+	_, err := b.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status code 404") || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestDetectBackendTypeOllama(t *testing.T) {
+	for url, want := range map[string]BackendType{
+		"http://localhost:11434":     BackendTypeOllama,
+		"http://gpu-box:11434/api":   BackendTypeOllama,
+		"https://ollama.internal":    BackendTypeOpenAI, // a host name alone is not proof
+		"http://localhost:11434/v1":  BackendTypeOpenAI, // OpenAI-compatible layer
+		"http://127.0.0.1:8080/v1":   BackendTypeOpenAI,
+		"https://api.anthropic.com/": BackendTypeAnthropic,
+	} {
+		if got := DetectBackendType(url); got != want {
+			t.Errorf("DetectBackendType(%q) = %s, want %s", url, got, want)
+		}
+	}
+}
@@ -73,6 +73,7 @@ func (b *OpenAIBackend) SendMessageSync(ctx context.Context, messages []tui.Chat
 	}
 
 	b.applyThinkingConfig(&req)
+	b.applyResponseFormat(ctx, &req)
 
 	// Create streaming request
 	stream, err := b.client.CreateChatCompletionStream(ctx, req)
@@ -96,15 +97,12 @@ func (b *OpenAIBackend) SendMessageSync(ctx context.Context, messages []tui.Chat
 
 		// Capture usage from the final usage-only chunk (sent by OpenAI when IncludeUsage is true).
 		if response.Usage != nil {
-			result.Usage = &TokenUsage{
-				PromptTokens:     response.Usage.PromptTokens,
-				CompletionTokens: response.Usage.CompletionTokens,
-				TotalTokens:      response.Usage.TotalTokens,
-			}
+			result.Usage = openAIUsage(response.Usage)
 		}
 
 		for _, choice := range response.Choices {
 			// Handle content delta
+			result.Thinking += choice.Delta.ReasoningContent
 			if choice.Delta.Content != "" {
 				result.Content += choice.Delta.Content
 			}
@@ -173,6 +171,7 @@ func (b *OpenAIBackend) SendMessageStream(ctx context.Context, messages []tui.Ch
 	}
 
 	b.applyThinkingConfig(&req)
+	b.applyResponseFormat(ctx, &req)
 
 	// Create streaming request
 	stream, err := b.client.CreateChatCompletionStream(ctx, req)
@@ -203,11 +202,7 @@ func (b *OpenAIBackend) SendMessageStream(ctx context.Context, messages []tui.Ch
 
 		// Capture usage data from response (only in final chunk with StreamOptions)
 		if response.Usage != nil {
-			usage = &TokenUsage{
-				PromptTokens:     response.Usage.PromptTokens,
-				CompletionTokens: response.Usage.CompletionTokens,
-				TotalTokens:      response.Usage.TotalTokens,
-			}
+			usage = openAIUsage(response.Usage)
 		}
 
 		for _, choice := range response.Choices {
@@ -296,6 +291,7 @@ func (b *OpenAIBackend) SendMessageStreamEvents(ctx context.Context, messages []
 	}
 
 	b.applyThinkingConfig(&req)
+	b.applyResponseFormat(ctx, &req)
 
 	// Create streaming request
 	stream, err := b.client.CreateChatCompletionStream(ctx, req)
@@ -325,14 +321,19 @@ func (b *OpenAIBackend) SendMessageStreamEvents(ctx context.Context, messages []
 
 		// Capture usage from the final usage-only chunk
 		if response.Usage != nil {
-			usage = &TokenUsage{
-				PromptTokens:     response.Usage.PromptTokens,
-				CompletionTokens: response.Usage.CompletionTokens,
-				TotalTokens:      response.Usage.TotalTokens,
-			}
+			usage = openAIUsage(response.Usage)
 		}
 
 		for _, choice := range response.Choices {
+			// Reasoning models behind OpenAI-compatible servers stream
+			// their thinking separately from the reply.
+			if choice.Delta.ReasoningContent != "" {
+				callback(StreamEvent{
+					Type:         EventThinkingDelta,
+					ContentDelta: choice.Delta.ReasoningContent,
+				})
+			}
+
 			// Handle content delta
 			if choice.Delta.Content != "" {
 				callback(StreamEvent{
@@ -448,6 +449,36 @@ func (b *OpenAIBackend) applyThinkingConfig(req *openai.ChatCompletionRequest) {
 	}
 }
 
+// applyResponseFormat requests a json_schema response when the call carries
+// a ResponseFormat the provider enforces natively.
+func (b *OpenAIBackend) applyResponseFormat(ctx context.Context, req *openai.ChatCompletionRequest) {
+	f := nativeResponseFormat(ctx, b.config.BaseURL)
+	if f == nil {
+		return
+	}
+	req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   f.formatName(),
+			Schema: jsonSchema(f.Schema),
+		},
+	}
+}
+
+// openAIUsage converts a usage block, including the prompt tokens OpenAI
+// (and compatible providers that report it) served from its prompt cache.
+func openAIUsage(u *openai.Usage) *TokenUsage {
+	usage := &TokenUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	return usage
+}
+
 // Close cleans up resources (no-op for OpenAI backend).
 func (b *OpenAIBackend) Close() error {
 	return nil
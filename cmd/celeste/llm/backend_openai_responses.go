@@ -0,0 +1,617 @@
+// Package llm provides the LLM client for Celeste CLI.
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// OpenAIResponsesBackend implements LLMBackend against OpenAI's /v1/responses
+// streaming protocol. Chat Completions drops a reasoning model's reasoning
+// after every request, so across a tool loop the model re-derives its plan
+// each turn. Here the reasoning items come back (encrypted) with the response
+// and are returned alongside the tool results, and consecutive turns are
+// chained with previous_response_id so only the new items are uploaded.
+type OpenAIResponsesBackend struct {
+	client         *http.Client
+	config         *Config
+	systemPrompt   string
+	thinkingConfig ThinkingConfig
+	cacheKey       string
+
+	mu    sync.Mutex
+	chain responsesChain
+}
+
+// responsesChain remembers the last response so the next request can send
+// previous_response_id and only the items added since. It is used only when
+// the new history provably extends the one the response was generated from;
+// anything else (a trimmed tool result, a compacted history, another
+// conversation sharing the client) falls back to sending everything.
+type responsesChain struct {
+	id      string
+	model   string
+	covered int      // the response answered messages[:covered]
+	digest  [32]byte // of the input items converted from messages[:covered]
+	calls   []string // call IDs the response asked for, in order
+	content string   // text the response produced
+}
+
+// NewOpenAIResponsesBackend creates a Responses API backend for config.
+func NewOpenAIResponsesBackend(config *Config) *OpenAIResponsesBackend {
+	// No client-level timeout: reasoning can take minutes before the first
+	// token. The caller's context (per-attempt timeout) bounds each request.
+	b := &OpenAIResponsesBackend{client: &http.Client{}, config: config}
+	if !config.NoPromptCache {
+		b.cacheKey = newCacheKey()
+	}
+	return b
+}
+
+// SetSystemPrompt sets the system prompt (Celeste persona). It is sent as
+// instructions on every request; instructions do not carry over through
+// previous_response_id.
+func (b *OpenAIResponsesBackend) SetSystemPrompt(prompt string) {
+	b.systemPrompt = prompt
+}
+
+// SetThinkingConfig maps the thinking level to reasoning.effort.
+func (b *OpenAIResponsesBackend) SetThinkingConfig(config ThinkingConfig) {
+	b.thinkingConfig = config
+}
+
+// Close cleans up resources (no-op for the Responses backend).
+func (b *OpenAIResponsesBackend) Close() error {
+	return nil
+}
+
+type responsesRequest struct {
+	Model              string              `json:"model"`
+	Instructions       string              `json:"instructions,omitempty"`
+	Input              []json.RawMessage   `json:"input"`
+	Tools              []json.RawMessage   `json:"tools,omitempty"`
+	PreviousResponseID string              `json:"previous_response_id,omitempty"`
+	Reasoning          *responsesReasoning `json:"reasoning,omitempty"`
+	Include            []string            `json:"include,omitempty"`
+	Text               *responsesText      `json:"text,omitempty"`
+	PromptCacheKey     string              `json:"prompt_cache_key,omitempty"`
+	Stream             bool                `json:"stream"`
+}
+
+type responsesText struct {
+	Format responsesTextFormat `json:"format"`
+}
+
+type responsesTextFormat struct {
+	Type   string         `json:"type"`
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+type responsesReasoning struct {
+	Effort string `json:"effort"`
+}
+
+type responsesFunctionTool struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  any    `json:"parameters"`
+}
+
+// responsesItem is an output item as it appears in stream events.
+type responsesItem struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id"`
+	CallID    string          `json:"call_id"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Action    json.RawMessage `json:"action"` // web_search_call
+	Code      string          `json:"code"`   // code_interpreter_call
+}
+
+type responsesObject struct {
+	ID    string `json:"id"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Usage *struct {
+		InputTokens        int `json:"input_tokens"`
+		OutputTokens       int `json:"output_tokens"`
+		TotalTokens        int `json:"total_tokens"`
+		InputTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+	} `json:"usage"`
+}
+
+// responsesStreamEvent is the data payload of one SSE event. Every payload
+// carries its own type, so the "event:" lines are not needed.
+type responsesStreamEvent struct {
+	Type     string           `json:"type"`
+	Delta    string           `json:"delta"`
+	ItemID   string           `json:"item_id"`
+	Item     json.RawMessage  `json:"item"`
+	Response *responsesObject `json:"response"`
+	Code     string           `json:"code"`
+	Message  string           `json:"message"`
+}
+
+// responsesAPIError is a non-200 reply. Code is OpenAI's error code, e.g.
+// "previous_response_not_found".
+type responsesAPIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *responsesAPIError) Error() string {
+	// "status code N" is what the retry classifier keys on.
+	return fmt.Sprintf("openai responses: status code %d: %s", e.Status, e.Message)
+}
+
+// buildRequest assembles a full (unchained) request for the given items.
+func (b *OpenAIResponsesBackend) buildRequest(items []json.RawMessage, tools []tui.SkillDefinition) responsesRequest {
+	req := responsesRequest{
+		Model:        b.config.Model,
+		Instructions: b.systemPrompt,
+		Input:        items,
+		Tools:        convertResponsesTools(tools, b.config.BuiltinTools),
+		// Encrypted reasoning is what makes an unchained resend (after the
+		// chain breaks) still carry the model's earlier reasoning.
+		Include: []string{"reasoning.encrypted_content"},
+		// Routes this conversation's requests to where its prefix is cached,
+		// which matters most for full resends after the chain breaks.
+		PromptCacheKey: b.cacheKey,
+		Stream:         true,
+	}
+	if b.thinkingConfig.Enabled && b.thinkingConfig.Level != "off" {
+		switch b.thinkingConfig.Level {
+		case "low", "medium":
+			req.Reasoning = &responsesReasoning{Effort: b.thinkingConfig.Level}
+		case "high", "max":
+			req.Reasoning = &responsesReasoning{Effort: "high"}
+		}
+	}
+	return req
+}
+
+// convertInput converts TUI messages to Responses API input items. ends[i]
+// is the number of items produced by messages[:i+1], so the items for any
+// prefix of the history are items[:ends[i]].
+func (b *OpenAIResponsesBackend) convertInput(messages []tui.ChatMessage) ([]json.RawMessage, []int) {
+	var items []json.RawMessage
+	add := func(v any) {
+		if data, err := json.Marshal(v); err == nil {
+			items = append(items, data)
+		}
+	}
+	ends := make([]int, len(messages))
+
+	for i, msg := range messages {
+		switch msg.Role {
+		case "assistant":
+			// Reasoning precedes the output it led to, as in the response.
+			for _, tc := range msg.ToolCalls {
+				var reasoning []json.RawMessage
+				if len(tc.ReasoningState) > 0 && json.Unmarshal(tc.ReasoningState, &reasoning) == nil {
+					items = append(items, reasoning...)
+				}
+			}
+			if msg.Content != "" {
+				add(map[string]any{"role": "assistant", "content": msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				args := tc.Arguments
+				if args == "" {
+					args = "{}"
+				}
+				add(map[string]any{"type": "function_call", "call_id": tc.ID, "name": tc.Name, "arguments": args})
+			}
+
+		case "tool":
+			add(map[string]any{"type": "function_call_output", "call_id": msg.ToolCallID, "output": msg.Content})
+			// Images from tool results go in a following user message, as
+			// with Chat Completions.
+			if img := imageFromMetadata(msg.Metadata); img != "" {
+				filename, _ := msg.Metadata["filename"].(string)
+				add(responsesImageMessage(fmt.Sprintf("[Attached image from tool result: %s]", filename), img, msg.Metadata))
+			}
+
+		default:
+			role := msg.Role
+			if role == "system" {
+				role = "developer"
+			}
+			if img := imageFromMetadata(msg.Metadata); img != "" {
+				m := responsesImageMessage(msg.Content, img, msg.Metadata)
+				m["role"] = role
+				add(m)
+			} else if msg.Content != "" {
+				add(map[string]any{"role": role, "content": msg.Content})
+			}
+		}
+		ends[i] = len(items)
+	}
+	return items, ends
+}
+
+// responsesImageMessage builds a user message carrying text and an image.
+func responsesImageMessage(text, b64 string, meta map[string]any) map[string]any {
+	format, _ := meta["format"].(string)
+	if format == "" {
+		format = "png"
+	}
+	return map[string]any{
+		"role": "user",
+		"content": []map[string]any{
+			{"type": "input_text", "text": text},
+			{"type": "input_image", "image_url": fmt.Sprintf("data:image/%s;base64,%s", format, b64)},
+		},
+	}
+}
+
+// convertResponsesTools converts skill definitions to Responses API function
+// tools and appends the configured built-in tools.
+func convertResponsesTools(tools []tui.SkillDefinition, builtin []string) []json.RawMessage {
+	var result []json.RawMessage
+	for _, t := range tools {
+		data, err := json.Marshal(responsesFunctionTool{Type: "function", Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+		if err != nil {
+			tui.LogInfo(fmt.Sprintf("Skipping invalid tool '%s': failed to marshal parameters: %v", t.Name, err))
+			continue
+		}
+		result = append(result, data)
+	}
+	for _, name := range builtin {
+		spec := map[string]any{"type": name}
+		if name == "code_interpreter" {
+			spec["container"] = map[string]string{"type": "auto"}
+		}
+		data, _ := json.Marshal(spec)
+		result = append(result, data)
+	}
+	return result
+}
+
+// itemsDigest hashes a sequence of input items.
+func itemsDigest(items []json.RawMessage) [32]byte {
+	h := sha256.New()
+	for _, item := range items {
+		h.Write(item)
+		h.Write([]byte{'\n'})
+	}
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// chainFrom returns the previous response ID and the index of the first item
+// to send when messages continue the remembered response, or "" when the
+// full history has to be sent.
+func (b *OpenAIResponsesBackend) chainFrom(messages []tui.ChatMessage, items []json.RawMessage, ends []int) (string, int) {
+	b.mu.Lock()
+	c := b.chain
+	b.mu.Unlock()
+
+	if c.id == "" || c.model != b.config.Model || c.covered == 0 || c.covered >= len(messages) {
+		return "", 0
+	}
+	// messages[c.covered] must be the response itself, exactly as the
+	// server holds it: a client-side edit (dropped calls, rewritten text)
+	// would leave the server with calls that never get outputs.
+	reply := messages[c.covered]
+	if reply.Role != "assistant" || len(reply.ToolCalls) != len(c.calls) {
+		return "", 0
+	}
+	for i, tc := range reply.ToolCalls {
+		if tc.ID != c.calls[i] {
+			return "", 0
+		}
+	}
+	if len(c.calls) == 0 && reply.Content != c.content {
+		return "", 0
+	}
+	if itemsDigest(items[:ends[c.covered-1]]) != c.digest {
+		return "", 0
+	}
+	from := ends[c.covered]
+	if from >= len(items) {
+		return "", 0
+	}
+	return c.id, from
+}
+
+func (b *OpenAIResponsesBackend) remember(c responsesChain) {
+	b.mu.Lock()
+	b.chain = c
+	b.mu.Unlock()
+}
+
+// respond runs one request, chained onto the previous response when
+// possible. emit, when non-nil, receives content, tool and server-tool
+// events as they stream; EventMessageDone is left to the caller.
+func (b *OpenAIResponsesBackend) respond(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, emit func(StreamEvent)) (*ChatCompletionResult, error) {
+	if emit == nil {
+		emit = func(StreamEvent) {}
+	}
+	items, ends := b.convertInput(messages)
+	req := b.buildRequest(items, tools)
+	if f := nativeResponseFormat(ctx, b.config.BaseURL); f != nil {
+		req.Text = &responsesText{Format: responsesTextFormat{Type: "json_schema", Name: f.formatName(), Schema: f.Schema}}
+	}
+
+	var (
+		result     *ChatCompletionResult
+		responseID string
+		err        error
+	)
+	if prev, from := b.chainFrom(messages, items, ends); prev != "" {
+		chained := req
+		chained.PreviousResponseID = prev
+		chained.Input = items[from:]
+		result, responseID, err = b.stream(ctx, chained, emit)
+		var apiErr *responsesAPIError
+		if errors.As(err, &apiErr) && apiErr.Code == "previous_response_not_found" {
+			// Stored responses expire; the full history still works.
+			tui.LogInfo("openai responses: previous response expired, resending full history")
+			result, responseID, err = b.stream(ctx, req, emit)
+		}
+	} else {
+		result, responseID, err = b.stream(ctx, req, emit)
+	}
+	if err != nil {
+		b.remember(responsesChain{})
+		return nil, err
+	}
+
+	next := responsesChain{
+		id:      responseID,
+		model:   b.config.Model,
+		covered: len(messages),
+		digest:  itemsDigest(items),
+		content: result.Content,
+	}
+	for _, tc := range result.ToolCalls {
+		next.calls = append(next.calls, tc.ID)
+	}
+	b.remember(next)
+	return result, nil
+}
+
+// stream sends one request and reads its event stream. It returns the result
+// and the response ID.
+func (b *OpenAIResponsesBackend) stream(ctx context.Context, body responsesRequest, emit func(StreamEvent)) (*ChatCompletionResult, string, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("marshal request: %w", err)
+	}
+	url := strings.TrimRight(b.config.BaseURL, "/") + "/responses"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if b.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.config.APIKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		apiErr := &responsesAPIError{Status: resp.StatusCode, Message: strings.TrimSpace(string(raw))}
+		var e struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(raw, &e) == nil && e.Error.Message != "" {
+			apiErr.Code, apiErr.Message = e.Error.Code, e.Error.Message
+		}
+		return nil, "", apiErr
+	}
+
+	result := &ChatCompletionResult{}
+	var (
+		responseID  string
+		content     strings.Builder
+		reasoning   []json.RawMessage // items not yet attached to a call
+		callsByItem = make(map[string]responsesItem)
+		finished    bool
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20) // encrypted reasoning lines are large
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "" || payload == "[DONE]" {
+			continue
+		}
+		var ev responsesStreamEvent
+		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+			return nil, "", fmt.Errorf("openai responses: parse event: %w", err)
+		}
+
+		switch ev.Type {
+		case "response.created":
+			if ev.Response != nil {
+				responseID = ev.Response.ID
+			}
+
+		case "response.output_text.delta":
+			content.WriteString(ev.Delta)
+			emit(StreamEvent{Type: EventContentDelta, ContentDelta: ev.Delta})
+
+		case "response.output_item.added":
+			var item responsesItem
+			if json.Unmarshal(ev.Item, &item) == nil && item.Type == "function_call" {
+				callsByItem[item.ID] = item
+				emit(StreamEvent{Type: EventToolUseStart, ToolUseID: item.CallID, ToolName: item.Name})
+			}
+
+		case "response.function_call_arguments.delta":
+			if item, ok := callsByItem[ev.ItemID]; ok {
+				emit(StreamEvent{Type: EventToolUseInputDelta, ToolUseID: item.CallID, InputDelta: ev.Delta})
+			}
+
+		case "response.output_item.done":
+			var item responsesItem
+			if err := json.Unmarshal(ev.Item, &item); err != nil {
+				continue
+			}
+			switch {
+			case item.Type == "reasoning":
+				reasoning = append(reasoning, ev.Item)
+			case item.Type == "function_call":
+				args := item.Arguments
+				if args == "" {
+					args = "{}"
+				}
+				call := ToolCallResult{ID: item.CallID, Name: item.Name, Arguments: args}
+				if len(reasoning) > 0 {
+					call.ReasoningState, _ = json.Marshal(reasoning)
+					reasoning = nil
+				}
+				result.ToolCalls = append(result.ToolCalls, call)
+				emit(StreamEvent{
+					Type:           EventToolUseDone,
+					ToolUseID:      call.ID,
+					ToolName:       call.Name,
+					CompleteInput:  call.Arguments,
+					ReasoningState: call.ReasoningState,
+				})
+			case strings.HasSuffix(item.Type, "_call"):
+				input := string(item.Action)
+				if input == "" && item.Code != "" {
+					code, _ := json.Marshal(map[string]string{"code": item.Code})
+					input = string(code)
+				}
+				if input == "" {
+					input = "{}"
+				}
+				emit(StreamEvent{
+					Type:          EventServerToolUse,
+					ToolUseID:     item.ID,
+					ToolName:      strings.TrimSuffix(item.Type, "_call"),
+					CompleteInput: input,
+				})
+			}
+
+		case "response.completed", "response.incomplete":
+			finished = true
+			if r := ev.Response; r != nil {
+				if r.ID != "" {
+					responseID = r.ID
+				}
+				if r.Usage != nil {
+					result.Usage = &TokenUsage{
+						PromptTokens:     r.Usage.InputTokens,
+						CompletionTokens: r.Usage.OutputTokens,
+						TotalTokens:      r.Usage.TotalTokens,
+						CachedTokens:     r.Usage.InputTokensDetails.CachedTokens,
+					}
+				}
+				if r.IncompleteDetails != nil {
+					result.FinishReason = "length"
+					if r.IncompleteDetails.Reason == "content_filter" {
+						result.FinishReason = "content_filter"
+					}
+				}
+			}
+
+		case "response.failed":
+			msg := "response failed"
+			if ev.Response != nil && ev.Response.Error != nil {
+				msg = ev.Response.Error.Code + ": " + ev.Response.Error.Message
+			}
+			return nil, "", fmt.Errorf("openai responses: %s", msg)
+
+		case "error":
+			return nil, "", fmt.Errorf("openai responses: %s: %s", ev.Code, ev.Message)
+		}
+		if finished {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", fmt.Errorf("openai responses: read stream: %w", err)
+	}
+	if !finished {
+		return nil, "", errors.New("openai responses: stream ended before the response completed")
+	}
+
+	result.Content = content.String()
+	switch {
+	case len(result.ToolCalls) > 0:
+		result.FinishReason = "tool_calls"
+	case result.FinishReason == "":
+		result.FinishReason = "stop"
+	}
+	return result, responseID, nil
+}
+
+// SendMessageSync sends a message and returns the complete result.
+func (b *OpenAIResponsesBackend) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
+	return b.respond(ctx, messages, tools, nil)
+}
+
+// SendMessageStream sends a message with streaming callback.
+func (b *OpenAIResponsesBackend) SendMessageStream(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamCallback) error {
+	isFirst := true
+	result, err := b.respond(ctx, messages, tools, func(e StreamEvent) {
+		if e.Type == EventContentDelta {
+			callback(StreamChunk{Content: e.ContentDelta, IsFirst: isFirst})
+			isFirst = false
+		}
+	})
+	if err != nil {
+		return err
+	}
+	callback(StreamChunk{
+		IsFinal:      true,
+		FinishReason: result.FinishReason,
+		ToolCalls:    result.ToolCalls,
+		Usage:        result.Usage,
+	})
+	return nil
+}
+
+// SendMessageStreamEvents sends a message with granular streaming events.
+// Built-in tool calls the provider ran are reported as EventServerToolUse.
+func (b *OpenAIResponsesBackend) SendMessageStreamEvents(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamEventCallback) error {
+	result, err := b.respond(ctx, messages, tools, callback)
+	if err != nil {
+		return err
+	}
+	callback(StreamEvent{
+		Type:         EventMessageDone,
+		Usage:        result.Usage,
+		FinishReason: result.FinishReason,
+	})
+	return nil
+}
@@ -0,0 +1,255 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// responsesStandIn serves /responses from a queue of scripted SSE streams,
+// recording every request body. A script that starts with "status " is
+// returned as an error reply instead.
+type responsesStandIn struct {
+	scripts  [][]string
+	requests []responsesRequest
+}
+
+func (s *responsesStandIn) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/responses" {
+			http.NotFound(w, r)
+			return
+		}
+		var req responsesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		s.requests = append(s.requests, req)
+		if len(s.scripts) == 0 {
+			t.Errorf("unexpected request %d", len(s.requests))
+			return
+		}
+		script := s.scripts[0]
+		s.scripts = s.scripts[1:]
+		if strings.HasPrefix(script[0], "status ") {
+			var code int
+			fmt.Sscanf(script[0], "status %d", &code)
+			w.WriteHeader(code)
+			fmt.Fprint(w, script[1])
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range script {
+			var typed struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(data), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, data)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// toolTurn is a response that reasons, then calls read_file.
+func toolTurn(id string) []string {
+	return []string{
+		`{"type":"response.created","response":{"id":"` + id + `"}}`,
+		`{"type":"response.output_item.done","item":{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"gAAA-opaque"}}`,
+		`{"type":"response.output_item.added","item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"read_file","arguments":""}}`,
+		`{"type":"response.function_call_arguments.delta","item_id":"fc_1","delta":"{\"path\":"}`,
+		`{"type":"response.function_call_arguments.delta","item_id":"fc_1","delta":"\"go.mod\"}"}`,
+		`{"type":"response.output_item.done","item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"read_file","arguments":"{\"path\":\"go.mod\"}"}}`,
+		`{"type":"response.completed","response":{"id":"` + id + `","usage":{"input_tokens":20,"output_tokens":8,"total_tokens":28}}}`,
+	}
+}
+
+func textTurn(id, text string) []string {
+	return []string{
+		`{"type":"response.created","response":{"id":"` + id + `"}}`,
+		`{"type":"response.output_text.delta","item_id":"msg_1","delta":"` + text + `"}`,
+		`{"type":"response.completed","response":{"id":"` + id + `"}}`,
+	}
+}
+
+// collectTurn streams one request and returns the events plus the assistant
+// message the runtime would record from them.
+func collectTurn(t *testing.T, b LLMBackend, history []tui.ChatMessage) ([]StreamEvent, tui.ChatMessage) {
+	t.Helper()
+	var events []StreamEvent
+	acc := NewToolUseAccumulator()
+	msg := tui.ChatMessage{Role: "assistant"}
+	err := b.SendMessageStreamEvents(context.Background(), history, ollamaTestTools, func(e StreamEvent) {
+		events = append(events, e)
+		switch {
+		case e.Type == EventContentDelta:
+			msg.Content += e.ContentDelta
+		case e.IsToolEvent():
+			acc.HandleEvent(e)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range acc.CompletedCalls() {
+		msg.ToolCalls = append(msg.ToolCalls, tui.ToolCallInfo{ID: c.ID, Name: c.Name, Arguments: c.Arguments, ReasoningState: c.ReasoningState})
+	}
+	return events, msg
+}
+
+func TestResponsesToolCallCarriesReasoning(t *testing.T) {
+	stand := &responsesStandIn{scripts: [][]string{toolTurn("resp_1")}}
+	srv := stand.start(t)
+	b := NewOpenAIResponsesBackend(&Config{BaseURL: srv.URL + "/v1", APIKey: "k", Model: "o3"})
+	b.SetSystemPrompt("persona")
+	b.SetThinkingConfig(ThinkingConfig{Enabled: true, Level: "max"})
+
+	events, msg := collectTurn(t, b, []tui.ChatMessage{{Role: "user", Content: "read go.mod"}})
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Arguments != `{"path":"go.mod"}` {
+		t.Fatalf("tool calls = %+v", msg.ToolCalls)
+	}
+	if !strings.Contains(string(msg.ToolCalls[0].ReasoningState), "gAAA-opaque") {
+		t.Errorf("reasoning state = %s", msg.ToolCalls[0].ReasoningState)
+	}
+	done := events[len(events)-1]
+	if done.Type != EventMessageDone || done.FinishReason != "tool_calls" || done.Usage == nil || done.Usage.TotalTokens != 28 {
+		t.Errorf("done event = %+v", done)
+	}
+
+	got := stand.requests[0]
+	if got.Instructions != "persona" || got.Reasoning == nil || got.Reasoning.Effort != "high" {
+		t.Errorf("request = %+v", got)
+	}
+	if len(got.Include) != 1 || got.Include[0] != "reasoning.encrypted_content" {
+		t.Errorf("include = %v", got.Include)
+	}
+	if len(got.Tools) != 1 || !strings.Contains(string(got.Tools[0]), `"name":"read_file"`) {
+		t.Errorf("tools = %s", got.Tools)
+	}
+}
+
+func TestResponsesChainsOntoPreviousResponse(t *testing.T) {
+	stand := &responsesStandIn{scripts: [][]string{toolTurn("resp_1"), textTurn("resp_2", "done"), textTurn("resp_3", "again")}}
+	srv := stand.start(t)
+	b := NewOpenAIResponsesBackend(&Config{BaseURL: srv.URL + "/v1", Model: "gpt-5"})
+
+	history := []tui.ChatMessage{{Role: "user", Content: "read go.mod"}}
+	_, reply := collectTurn(t, b, history)
+	history = append(history, reply, tui.ChatMessage{Role: "tool", ToolCallID: "call_1", Content: "module x"})
+	collectTurn(t, b, history)
+
+	second := stand.requests[1]
+	if second.PreviousResponseID != "resp_1" {
+		t.Fatalf("previous_response_id = %q, want resp_1", second.PreviousResponseID)
+	}
+	if len(second.Input) != 1 || !strings.Contains(string(second.Input[0]), `"function_call_output"`) {
+		t.Errorf("chained input should be just the tool output, got %s", second.Input)
+	}
+
+	// An edited history no longer matches what the server holds.
+	history[2].Content = "module x (trimmed)"
+	history = append(history, tui.ChatMessage{Role: "assistant", Content: "done"}, tui.ChatMessage{Role: "user", Content: "again"})
+	collectTurn(t, b, history)
+	third := stand.requests[2]
+	if third.PreviousResponseID != "" {
+		t.Errorf("edited history must not chain, previous_response_id = %q", third.PreviousResponseID)
+	}
+	// The full resend carries the reasoning item ahead of its function call.
+	var types []string
+	for _, item := range third.Input {
+		var v struct {
+			Type string `json:"type"`
+			Role string `json:"role"`
+		}
+		_ = json.Unmarshal(item, &v)
+		types = append(types, v.Type+v.Role)
+	}
+	want := "user reasoning function_call function_call_output assistant user"
+	if strings.Join(types, " ") != want {
+		t.Errorf("input items = %v, want %s", types, want)
+	}
+}
+
+func TestResponsesExpiredChainResendsFullHistory(t *testing.T) {
+	stand := &responsesStandIn{scripts: [][]string{
+		toolTurn("resp_1"),
+		{"status 400", `{"error":{"code":"previous_response_not_found","message":"Previous response with id 'resp_1' not found."}}`},
+		textTurn("resp_2", "ok"),
+	}}
+	srv := stand.start(t)
+	b := NewOpenAIResponsesBackend(&Config{BaseURL: srv.URL + "/v1", Model: "o4-mini"})
+
+	history := []tui.ChatMessage{{Role: "user", Content: "read go.mod"}}
+	_, reply := collectTurn(t, b, history)
+	history = append(history, reply, tui.ChatMessage{Role: "tool", ToolCallID: "call_1", Content: "module x"})
+	_, final := collectTurn(t, b, history)
+
+	if final.Content != "ok" || len(stand.requests) != 3 {
+		t.Fatalf("content = %q after %d requests", final.Content, len(stand.requests))
+	}
+	if retry := stand.requests[2]; retry.PreviousResponseID != "" || len(retry.Input) != 4 {
+		t.Errorf("retry should send the full history unchained: %+v", retry)
+	}
+}
+
+func TestResponsesBuiltinToolIsReportedNotExecuted(t *testing.T) {
+	stand := &responsesStandIn{scripts: [][]string{{
+		`{"type":"response.created","response":{"id":"resp_1"}}`,
+		`{"type":"response.output_item.done","item":{"type":"web_search_call","id":"ws_1","status":"completed","action":{"type":"search","query":"go 1.26 release"}}}`,
+		`{"type":"response.output_text.delta","delta":"Released."}`,
+		`{"type":"response.completed","response":{"id":"resp_1"}}`,
+	}}}
+	srv := stand.start(t)
+	b := NewOpenAIResponsesBackend(&Config{BaseURL: srv.URL + "/v1", Model: "gpt-5", BuiltinTools: []string{"web_search"}})
+
+	events, msg := collectTurn(t, b, []tui.ChatMessage{{Role: "user", Content: "news?"}})
+	if len(msg.ToolCalls) != 0 || msg.Content != "Released." {
+		t.Fatalf("message = %+v", msg)
+	}
+	var server []StreamEvent
+	for _, e := range events {
+		if e.Type == EventServerToolUse {
+			server = append(server, e)
+		}
+	}
+	if len(server) != 1 || server[0].ToolName != "web_search" || !strings.Contains(server[0].CompleteInput, "go 1.26 release") {
+		t.Errorf("server tool events = %+v", server)
+	}
+	if tools := stand.requests[0].Tools; len(tools) != 2 || string(tools[1]) != `{"type":"web_search"}` {
+		t.Errorf("tools = %s", tools)
+	}
+}
+
+func TestSelectBackendTypeByModel(t *testing.T) {
+	for _, tc := range []struct {
+		baseURL, model string
+		want           BackendType
+	}{
+		{"https://api.openai.com/v1", "o3", BackendTypeOpenAIResponses},
+		{"https://api.openai.com/v1", "gpt-5-mini", BackendTypeOpenAIResponses},
+		{"https://api.openai.com/v1", "gpt-4.1-nano", BackendTypeOpenAI},
+		{"https://openrouter.ai/api/v1", "o3", BackendTypeOpenAI},
+		{"https://api.x.ai/v1", "grok-4", BackendTypeXAI},
+	} {
+		if got := SelectBackendType(&Config{BaseURL: tc.baseURL, Model: tc.model}); got != tc.want {
+			t.Errorf("SelectBackendType(%s, %s) = %s, want %s", tc.baseURL, tc.model, got, tc.want)
+		}
+	}
+}
+
+func TestTranslateHistoryDropsReasoningStateElsewhere(t *testing.T) {
+	history := []tui.ChatMessage{{Role: "assistant", ToolCalls: []tui.ToolCallInfo{{ID: "call_1", Name: "x", ReasoningState: []byte(`[{}]`)}}}}
+	if out := translateHistory(history, BackendTypeAnthropic); out[0].ToolCalls[0].ReasoningState != nil {
+		t.Error("reasoning state leaked to another backend")
+	}
+	if out := translateHistory(history, BackendTypeOpenAIResponses); out[0].ToolCalls[0].ReasoningState == nil {
+		t.Error("reasoning state dropped for the Responses backend")
+	}
+}
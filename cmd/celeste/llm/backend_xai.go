@@ -27,6 +27,7 @@ type XAIBackend struct {
 	systemPrompt   string
 	registry       *tools.Registry
 	thinkingConfig ThinkingConfig
+	cacheKey       string // sent as x-grok-conv-id
 }
 
 // NewXAIBackend creates a new xAI backend with Collections support.
@@ -40,14 +41,18 @@ func NewXAIBackend(config *Config, registry *tools.Registry) (*XAIBackend, error
 		baseURL = "https://api.x.ai/v1"
 	}
 
-	return &XAIBackend{
+	b := &XAIBackend{
 		apiKey:     config.APIKey,
 		baseURL:    baseURL,
 		model:      config.Model,
 		config:     config,
 		registry:   registry,
 		httpClient: &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
-	}, nil
+	}
+	if !config.NoPromptCache {
+		b.cacheKey = newCacheKey()
+	}
+	return b, nil
 }
 
 // SetSystemPrompt sets the system prompt (Celeste persona).
@@ -148,6 +153,18 @@ type xAIChatCompletionRequest struct {
 	Temperature     float32           `json:"temperature,omitempty"`
 	MaxTokens       int               `json:"max_tokens,omitempty"`
 	ReasoningEffort string            `json:"reasoning_effort,omitempty"` // "low", "medium", "high"
+	ResponseFormat  *xAIJSONFormat    `json:"response_format,omitempty"`
+}
+
+// xAIJSONFormat is the OpenAI-style json_schema response format.
+type xAIJSONFormat struct {
+	Type       string        `json:"type"`
+	JSONSchema xAIJSONSchema `json:"json_schema"`
+}
+
+type xAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
 }
 
 // xAIStreamChunk represents a streaming response chunk
@@ -170,9 +187,22 @@ type xAIStreamChunk struct {
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
 		NumSourcesUsed   int `json:"num_sources_used,omitempty"` // xAI Collections indicator
+
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details,omitempty"`
 	} `json:"usage,omitempty"`
 }
 
+// setCacheHeader pins the conversation to one cache: xAI caches prompt
+// prefixes per server, and x-grok-conv-id routes requests that share it to
+// the same one.
+func (b *XAIBackend) setCacheHeader(req *http.Request) {
+	if b.cacheKey != "" {
+		req.Header.Set("x-grok-conv-id", b.cacheKey)
+	}
+}
+
 // SendMessageStream sends a message with streaming callback.
 func (b *XAIBackend) SendMessageStream(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamCallback) error {
 	// Convert messages to xAI format
@@ -200,6 +230,9 @@ func (b *XAIBackend) SendMessageStream(ctx context.Context, messages []tui.ChatM
 	}
 
 	b.applyThinkingConfig(&req)
+	if f := nativeResponseFormat(ctx, b.baseURL); f != nil {
+		req.ResponseFormat = &xAIJSONFormat{Type: "json_schema", JSONSchema: xAIJSONSchema{Name: f.formatName(), Schema: f.Schema}}
+	}
 
 	// Marshal request
 	jsonData, err := json.Marshal(req)
@@ -220,6 +253,7 @@ func (b *XAIBackend) SendMessageStream(ctx context.Context, messages []tui.ChatM
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+b.apiKey)
+	b.setCacheHeader(httpReq)
 
 	// Send request
 	resp, err := b.httpClient.Do(httpReq)
@@ -267,6 +301,9 @@ func (b *XAIBackend) SendMessageStream(ctx context.Context, messages []tui.ChatM
 				CompletionTokens: chunk.Usage.CompletionTokens,
 				TotalTokens:      chunk.Usage.TotalTokens,
 			}
+			if d := chunk.Usage.PromptTokensDetails; d != nil {
+				usage.CachedTokens = d.CachedTokens
+			}
 			if chunk.Usage.NumSourcesUsed > 0 {
 				tui.LogInfo(fmt.Sprintf("✅ xAI Collections: %d sources used in response", chunk.Usage.NumSourcesUsed))
 			}
@@ -363,6 +400,9 @@ func (b *XAIBackend) SendMessageStreamEvents(ctx context.Context, messages []tui
 	}
 
 	b.applyThinkingConfig(&req)
+	if f := nativeResponseFormat(ctx, b.baseURL); f != nil {
+		req.ResponseFormat = &xAIJSONFormat{Type: "json_schema", JSONSchema: xAIJSONSchema{Name: f.formatName(), Schema: f.Schema}}
+	}
 
 	// Marshal request
 	jsonData, err := json.Marshal(req)
@@ -383,6 +423,7 @@ func (b *XAIBackend) SendMessageStreamEvents(ctx context.Context, messages []tui
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+b.apiKey)
+	b.setCacheHeader(httpReq)
 
 	// Send request
 	resp, err := b.httpClient.Do(httpReq)
@@ -433,6 +474,9 @@ func (b *XAIBackend) SendMessageStreamEvents(ctx context.Context, messages []tui
 				CompletionTokens: chunk.Usage.CompletionTokens,
 				TotalTokens:      chunk.Usage.TotalTokens,
 			}
+			if d := chunk.Usage.PromptTokensDetails; d != nil {
+				usage.CachedTokens = d.CachedTokens
+			}
 		}
 
 		// Process choices
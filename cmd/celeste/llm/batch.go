@@ -0,0 +1,290 @@
+// Package llm provides the LLM client for Celeste CLI.
+// This file implements batch execution: single-shot prompts submitted
+// together through a provider's asynchronous batch API, which OpenAI and
+// Anthropic bill at half the list price in exchange for results that can
+// take up to a day.
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// BatchRequest is one single-shot prompt in a batch: no tools, and no
+// conversation beyond Messages.
+type BatchRequest struct {
+	ID        string // custom_id; results are matched back on it
+	System    string
+	Messages  []tui.ChatMessage
+	Format    *ResponseFormat // validated on return; a batch has no repair turn
+	MaxTokens int             // batch APIs only; 0 = the backend's default
+}
+
+// BatchResult is the outcome of one BatchRequest. Err is set when the
+// provider failed the request or the reply did not match its Format.
+type BatchResult struct {
+	ID      string
+	Content string
+	Usage   *TokenUsage
+	Err     error
+}
+
+// BatchStatus reports the progress of a submitted batch.
+type BatchStatus struct {
+	ID        string
+	State     string // the provider's own status word
+	Done      bool   // no further results will arrive
+	Total     int
+	Succeeded int
+	Failed    int
+}
+
+// Batcher runs BatchRequests through one provider.
+type Batcher interface {
+	// Submit sends reqs as one batch and returns its ID.
+	Submit(ctx context.Context, reqs []BatchRequest) (string, error)
+	// Status reports the batch's progress.
+	Status(ctx context.Context, id string) (BatchStatus, error)
+	// Results returns what the batch produced so far, in no particular order.
+	Results(ctx context.Context, id string) ([]BatchResult, error)
+	// Cancel stops a batch; requests already finished keep their results.
+	Cancel(ctx context.Context, id string) error
+	// Name describes where requests go, for progress output.
+	Name() string
+	// Discounted reports whether results are billed at batch pricing.
+	Discounted() bool
+}
+
+// ErrBatchUnsupported is returned by NewBatcher for providers without a
+// batch API.
+var ErrBatchUnsupported = errors.New("provider has no batch API")
+
+// NewBatcher returns a Batcher for config's provider batch API. The
+// protocol is the provider's BatchAPI, or config.BatchAPI when set (a proxy
+// or stand-in that speaks OpenAI's or Anthropic's batch protocol).
+func NewBatcher(config *Config) (Batcher, error) {
+	api := strings.ToLower(strings.TrimSpace(config.BatchAPI))
+	provider := providers.DetectProvider(config.BaseURL)
+	if api == "" {
+		if caps, ok := providers.GetProvider(provider); ok {
+			api = caps.BatchAPI
+		}
+	}
+	switch api {
+	case "openai":
+		return newOpenAIBatcher(config), nil
+	case "anthropic":
+		return newAnthropicBatcher(config)
+	case "":
+		return nil, fmt.Errorf("%w: %s", ErrBatchUnsupported, provider)
+	default:
+		return nil, fmt.Errorf("unknown batch_api %q (want openai or anthropic)", api)
+	}
+}
+
+// batchIDPattern is the stricter of the two providers' custom_id rules
+// (Anthropic's), so a request list valid for one is valid for both.
+var batchIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// checkBatchRequests rejects batches the provider would refuse as a whole.
+func checkBatchRequests(reqs []BatchRequest) error {
+	if len(reqs) == 0 {
+		return errors.New("batch has no requests")
+	}
+	seen := make(map[string]bool, len(reqs))
+	for _, r := range reqs {
+		if !batchIDPattern.MatchString(r.ID) {
+			return fmt.Errorf("batch request ID %q: use 1-64 letters, digits, _ or -", r.ID)
+		}
+		if seen[r.ID] {
+			return fmt.Errorf("batch request ID %q is used twice", r.ID)
+		}
+		seen[r.ID] = true
+	}
+	return nil
+}
+
+// batchMessages is what a request sends: its messages, with the schema
+// instruction added when it asks for structured output.
+func batchMessages(r BatchRequest) []tui.ChatMessage {
+	if r.Format == nil {
+		return r.Messages
+	}
+	return withFormatInstruction(r.Messages, r.Format)
+}
+
+// RunBatch submits reqs through b, waits for the batch to end and returns
+// one result per request, in the order of reqs. Replies to requests with a
+// Format are validated; one that does not conform has a
+// *StructuredOutputError. If ctx ends first the batch keeps running on the
+// provider and the error names it, so its results can be fetched later.
+func RunBatch(ctx context.Context, b Batcher, reqs []BatchRequest, poll time.Duration, progress func(BatchStatus)) ([]BatchResult, error) {
+	id, err := b.Submit(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	results, err := WaitBatch(ctx, b, id, poll, progress)
+	if err != nil {
+		return nil, err
+	}
+	return MatchBatchResults(reqs, results), nil
+}
+
+// WaitBatch polls a submitted batch every poll until it ends and returns
+// its results.
+func WaitBatch(ctx context.Context, b Batcher, id string, poll time.Duration, progress func(BatchStatus)) ([]BatchResult, error) {
+	if poll <= 0 {
+		poll = 30 * time.Second
+	}
+	for {
+		status, err := b.Status(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("batch %s: %w", id, err)
+		}
+		if progress != nil {
+			progress(status)
+		}
+		if status.Done {
+			return b.Results(ctx, id)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("batch %s is still %s on the provider: %w", id, status.State, ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+// MatchBatchResults orders results to match reqs and validates structured
+// replies. A request with no result gets an error saying so.
+func MatchBatchResults(reqs []BatchRequest, results []BatchResult) []BatchResult {
+	byID := make(map[string]BatchResult, len(results))
+	for _, r := range results {
+		byID[r.ID] = r
+	}
+	out := make([]BatchResult, len(reqs))
+	for i, req := range reqs {
+		res, ok := byID[req.ID]
+		if !ok {
+			out[i] = BatchResult{ID: req.ID, Err: errors.New("the batch returned no result for this request")}
+			continue
+		}
+		if res.Err == nil && req.Format != nil {
+			content, err := decodeStructured(&ChatCompletionResult{Content: res.Content}, req.Format)
+			if err != nil {
+				res.Err = &StructuredOutputError{Raw: res.Content, Attempts: 1, Err: err}
+			} else {
+				res.Content = content
+			}
+		}
+		out[i] = res
+	}
+	return out
+}
+
+// directBatcher runs each request immediately through the ordinary chat
+// backend, at list price. It stands in for providers without a batch API so
+// callers have one code path either way.
+type directBatcher struct {
+	config *Config
+
+	mu      sync.Mutex
+	next    int
+	results map[string][]BatchResult
+}
+
+// directBatchWorkers bounds the requests a directBatcher has in flight.
+const directBatchWorkers = 4
+
+// NewDirectBatcher returns a Batcher that sends each request as it is
+// submitted, through config's usual backend and failover chain. Submit
+// returns once every request has been answered.
+func NewDirectBatcher(config *Config) Batcher {
+	return &directBatcher{config: config, results: make(map[string][]BatchResult)}
+}
+
+func (b *directBatcher) Name() string     { return "direct" }
+func (b *directBatcher) Discounted() bool { return false }
+
+func (b *directBatcher) Submit(ctx context.Context, reqs []BatchRequest) (string, error) {
+	if err := checkBatchRequests(reqs); err != nil {
+		return "", err
+	}
+	results := make([]BatchResult, len(reqs))
+	sem := make(chan struct{}, directBatchWorkers)
+	var wg sync.WaitGroup
+	for i, r := range reqs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = b.send(ctx, r)
+		}()
+	}
+	wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next++
+	id := fmt.Sprintf("direct-%d", b.next)
+	b.results[id] = results
+	return id, nil
+}
+
+// send runs one request. Each gets its own client because the system prompt
+// differs per request.
+func (b *directBatcher) send(ctx context.Context, r BatchRequest) BatchResult {
+	client := NewClient(b.config, nil)
+	defer client.backend.Close()
+	client.SetSystemPrompt(r.System)
+
+	var opts []SendOption
+	if r.Format != nil {
+		opts = append(opts, WithResponseFormat(r.Format))
+	}
+	res, err := client.SendMessageSync(ctx, r.Messages, nil, opts...)
+	out := BatchResult{ID: r.ID, Err: err}
+	if res != nil {
+		out.Content, out.Usage = res.Content, res.Usage
+	}
+	return out
+}
+
+func (b *directBatcher) Status(ctx context.Context, id string) (BatchStatus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	results, ok := b.results[id]
+	if !ok {
+		return BatchStatus{}, fmt.Errorf("unknown batch %s", id)
+	}
+	status := BatchStatus{ID: id, State: "ended", Done: true, Total: len(results)}
+	for _, r := range results {
+		if r.Err != nil {
+			status.Failed++
+		} else {
+			status.Succeeded++
+		}
+	}
+	return status, nil
+}
+
+func (b *directBatcher) Results(ctx context.Context, id string) ([]BatchResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	results, ok := b.results[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown batch %s", id)
+	}
+	return results, nil
+}
+
+func (b *directBatcher) Cancel(ctx context.Context, id string) error { return nil }
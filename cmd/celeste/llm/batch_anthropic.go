@@ -0,0 +1,130 @@
+// Package llm provides the LLM client for Celeste CLI.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// anthropicBatcher submits Messages requests through Anthropic's Message
+// Batches API. Requests go inline with the create call; results are
+// streamed back as JSONL once the batch has ended.
+type anthropicBatcher struct {
+	backend *AnthropicBackend
+}
+
+func newAnthropicBatcher(config *Config) (*anthropicBatcher, error) {
+	backend, err := NewAnthropicBackend(config)
+	if err != nil {
+		return nil, err
+	}
+	return &anthropicBatcher{backend: backend}, nil
+}
+
+func (b *anthropicBatcher) Name() string     { return "anthropic batch" }
+func (b *anthropicBatcher) Discounted() bool { return true }
+
+func (b *anthropicBatcher) Submit(ctx context.Context, reqs []BatchRequest) (string, error) {
+	if err := checkBatchRequests(reqs); err != nil {
+		return "", err
+	}
+	params := anthropic.MessageBatchNewParams{Requests: make([]anthropic.MessageBatchNewParamsRequest, 0, len(reqs))}
+	for _, r := range reqs {
+		params.Requests = append(params.Requests, anthropic.MessageBatchNewParamsRequest{
+			CustomID: r.ID,
+			Params:   b.request(r),
+		})
+	}
+	batch, err := b.backend.client.Messages.Batches.New(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("create anthropic batch: %w", err)
+	}
+	return batch.ID, nil
+}
+
+// request builds the same Messages body a synchronous call would send,
+// minus tools. Cache breakpoints are kept: requests in a batch that share a
+// system prompt can read each other's cache entries.
+func (b *anthropicBatcher) request(r BatchRequest) anthropic.MessageBatchNewParamsRequestParams {
+	backend := *b.backend
+	backend.systemPrompt = r.System
+	p := backend.buildParams(batchMessages(r), nil)
+	if r.MaxTokens > 0 && p.Thinking.OfEnabled == nil {
+		p.MaxTokens = int64(r.MaxTokens)
+	}
+	if r.Format != nil {
+		ctx := context.WithValue(context.Background(), responseFormatKey{}, r.Format)
+		if f := nativeResponseFormat(ctx, b.backend.config.BaseURL); f != nil {
+			p.OutputConfig.Format = anthropic.JSONOutputFormatParam{Schema: f.Schema}
+		}
+	}
+	return anthropic.MessageBatchNewParamsRequestParams{
+		Model:        p.Model,
+		MaxTokens:    p.MaxTokens,
+		Messages:     p.Messages,
+		System:       p.System,
+		Thinking:     p.Thinking,
+		OutputConfig: p.OutputConfig,
+		Metadata:     p.Metadata,
+	}
+}
+
+func (b *anthropicBatcher) Status(ctx context.Context, id string) (BatchStatus, error) {
+	batch, err := b.backend.client.Messages.Batches.Get(ctx, id)
+	if err != nil {
+		return BatchStatus{}, err
+	}
+	counts := batch.RequestCounts
+	return BatchStatus{
+		ID:        batch.ID,
+		State:     string(batch.ProcessingStatus),
+		Done:      batch.ProcessingStatus == anthropic.MessageBatchProcessingStatusEnded,
+		Total:     int(counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired),
+		Succeeded: int(counts.Succeeded),
+		Failed:    int(counts.Errored + counts.Canceled + counts.Expired),
+	}, nil
+}
+
+func (b *anthropicBatcher) Results(ctx context.Context, id string) ([]BatchResult, error) {
+	stream := b.backend.client.Messages.Batches.ResultsStreaming(ctx, id)
+	defer stream.Close()
+
+	var results []BatchResult
+	for stream.Next() {
+		results = append(results, anthropicBatchResult(stream.Current()))
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("anthropic batch %s results: %w", id, err)
+	}
+	return results, nil
+}
+
+func anthropicBatchResult(line anthropic.MessageBatchIndividualResponse) BatchResult {
+	res := BatchResult{ID: line.CustomID}
+	switch line.Result.Type {
+	case "succeeded":
+		msg := line.Result.Message
+		var text strings.Builder
+		for _, block := range msg.Content {
+			if block.Type == "text" {
+				text.WriteString(block.Text)
+			}
+		}
+		res.Content = text.String()
+		u := msg.Usage
+		res.Usage = anthropicUsage(nil, u.InputTokens, u.OutputTokens, u.CacheCreationInputTokens, u.CacheReadInputTokens)
+	case "errored":
+		res.Err = fmt.Errorf("%s: %s", line.Result.Error.Error.Type, line.Result.Error.Error.Message)
+	default:
+		res.Err = fmt.Errorf("request %s", line.Result.Type)
+	}
+	return res
+}
+
+func (b *anthropicBatcher) Cancel(ctx context.Context, id string) error {
+	_, err := b.backend.client.Messages.Batches.Cancel(ctx, id)
+	return err
+}
@@ -0,0 +1,175 @@
+// Package llm provides the LLM client for Celeste CLI.
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// openAIBatcher submits Chat Completions requests through OpenAI's Batch
+// API: the requests are uploaded as a JSONL file, the batch reads it, and
+// the replies come back as an output file (and failures as an error file).
+type openAIBatcher struct {
+	backend *OpenAIBackend
+}
+
+func newOpenAIBatcher(config *Config) *openAIBatcher {
+	return &openAIBatcher{backend: NewOpenAIBackend(config)}
+}
+
+func (b *openAIBatcher) Name() string     { return "openai batch" }
+func (b *openAIBatcher) Discounted() bool { return true }
+
+func (b *openAIBatcher) Submit(ctx context.Context, reqs []BatchRequest) (string, error) {
+	if err := checkBatchRequests(reqs); err != nil {
+		return "", err
+	}
+	upload := openai.UploadBatchFileRequest{FileName: "celeste-batch.jsonl"}
+	for _, r := range reqs {
+		upload.AddChatCompletion(r.ID, b.request(r))
+	}
+	batch, err := b.backend.client.CreateBatchWithUploadFile(ctx, openai.CreateBatchWithUploadFileRequest{
+		Endpoint:               openai.BatchEndpointChatCompletions,
+		CompletionWindow:       "24h",
+		Metadata:               map[string]any{"source": "celeste"},
+		UploadBatchFileRequest: upload,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create openai batch: %w", err)
+	}
+	return batch.ID, nil
+}
+
+// request builds the same Chat Completions body a synchronous call would
+// send, minus streaming and tools.
+func (b *openAIBatcher) request(r BatchRequest) openai.ChatCompletionRequest {
+	backend := *b.backend
+	backend.systemPrompt = r.System
+	req := openai.ChatCompletionRequest{
+		Model:    b.backend.config.Model,
+		Messages: backend.convertMessages(batchMessages(r)),
+	}
+	if r.MaxTokens > 0 {
+		req.MaxCompletionTokens = r.MaxTokens
+	}
+	backend.applyThinkingConfig(&req)
+	if r.Format != nil {
+		backend.applyResponseFormat(context.WithValue(context.Background(), responseFormatKey{}, r.Format), &req)
+	}
+	return req
+}
+
+func (b *openAIBatcher) Status(ctx context.Context, id string) (BatchStatus, error) {
+	batch, err := b.backend.client.RetrieveBatch(ctx, id)
+	if err != nil {
+		return BatchStatus{}, err
+	}
+	status := BatchStatus{
+		ID:        batch.ID,
+		State:     batch.Status,
+		Total:     batch.RequestCounts.Total,
+		Succeeded: batch.RequestCounts.Completed,
+		Failed:    batch.RequestCounts.Failed,
+	}
+	switch batch.Status {
+	case "completed", "failed", "expired", "cancelled":
+		status.Done = true
+	}
+	return status, nil
+}
+
+// openAIBatchLine is one line of a batch output or error file.
+type openAIBatchLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (b *openAIBatcher) Results(ctx context.Context, id string) ([]BatchResult, error) {
+	batch, err := b.backend.client.RetrieveBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var results []BatchResult
+	for _, fileID := range []*string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == nil || *fileID == "" {
+			continue
+		}
+		lines, err := b.readFile(ctx, *fileID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, lines...)
+	}
+	if len(results) == 0 && batch.Status == "failed" && batch.Errors != nil && len(batch.Errors.Data) > 0 {
+		return nil, fmt.Errorf("openai batch %s failed: %s", id, batch.Errors.Data[0].Message)
+	}
+	return results, nil
+}
+
+func (b *openAIBatcher) readFile(ctx context.Context, fileID string) ([]BatchResult, error) {
+	content, err := b.backend.client.GetFileContent(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("download batch file %s: %w", fileID, err)
+	}
+	defer content.Close()
+
+	var results []BatchResult
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line openAIBatchLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("batch file %s: %w", fileID, err)
+		}
+		results = append(results, openAIBatchResult(line))
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("batch file %s: %w", fileID, err)
+	}
+	return results, nil
+}
+
+func openAIBatchResult(line openAIBatchLine) BatchResult {
+	res := BatchResult{ID: line.CustomID}
+	switch {
+	case line.Error != nil:
+		res.Err = fmt.Errorf("%s: %s", line.Error.Code, line.Error.Message)
+	case line.Response == nil:
+		res.Err = fmt.Errorf("no response")
+	case line.Response.StatusCode != 200:
+		// Same wording as the SDK's errors, so the retry classifier and
+		// users read it the same way.
+		res.Err = fmt.Errorf("error, status code %d: %s", line.Response.StatusCode, line.Response.Body)
+	default:
+		var resp openai.ChatCompletionResponse
+		if err := json.Unmarshal(line.Response.Body, &resp); err != nil {
+			res.Err = err
+			break
+		}
+		if len(resp.Choices) > 0 {
+			res.Content = resp.Choices[0].Message.Content
+		}
+		res.Usage = openAIUsage(&resp.Usage)
+	}
+	return res
+}
+
+func (b *openAIBatcher) Cancel(ctx context.Context, id string) error {
+	_, err := b.backend.client.CancelBatch(ctx, id)
+	return err
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm/batchtest"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+func TestBatchRoundTripThroughStandIn(t *testing.T) {
+	for _, api := range []string{"openai", "anthropic"} {
+		t.Run(api, func(t *testing.T) {
+			stand := batchtest.NewServer(func(r batchtest.Request) (string, error) {
+				switch {
+				case strings.Contains(r.Prompt, "refuse"):
+					return "", errors.New("prompt is too long")
+				case strings.Contains(r.Prompt, "JSON Schema"):
+					return "```json\n{\"approved\": true}\n```", nil
+				}
+				return "summary of " + r.Prompt, nil
+			})
+			defer stand.Close()
+			stand.PollsUntilDone = 2
+
+			b, err := NewBatcher(&Config{APIKey: "k", BaseURL: stand.BaseURL(), Model: "m1", BatchAPI: api})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !b.Discounted() {
+				t.Error("provider batches are billed at batch pricing")
+			}
+			reqs := []BatchRequest{
+				{ID: "verdict", System: "You review code.", Messages: []tui.ChatMessage{{Role: "user", Content: "review it"}}, Format: verdictFormat},
+				{ID: "repo-a", System: "You summarise.", Messages: []tui.ChatMessage{{Role: "user", Content: "repo a"}}},
+				{ID: "repo-b", Messages: []tui.ChatMessage{{Role: "user", Content: "refuse this"}}},
+			}
+			var polls int
+			results, err := RunBatch(context.Background(), b, reqs, time.Millisecond, func(BatchStatus) { polls++ })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if polls != 3 {
+				t.Errorf("status checks = %d, want 3", polls)
+			}
+			if len(results) != 3 {
+				t.Fatalf("results = %+v", results)
+			}
+			if results[0].ID != "verdict" || results[0].Err != nil || results[0].Content != `{"approved":true}` {
+				t.Errorf("structured result = %+v", results[0])
+			}
+			if results[1].Content != "summary of repo a" || results[1].Usage == nil || results[1].Usage.CompletionTokens == 0 {
+				t.Errorf("plain result = %+v", results[1])
+			}
+			if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "prompt is too long") {
+				t.Errorf("failed result = %+v", results[2])
+			}
+
+			sent := stand.Requests()
+			if len(sent) != 3 || sent[1].System != "You summarise." || sent[1].Model != "m1" {
+				t.Errorf("stand-in received %+v", sent)
+			}
+		})
+	}
+}
+
+func TestMatchBatchResults(t *testing.T) {
+	reqs := []BatchRequest{{ID: "a", Format: verdictFormat}, {ID: "b"}, {ID: "c"}}
+	results := MatchBatchResults(reqs, []BatchResult{
+		{ID: "c", Content: "third"},
+		{ID: "a", Content: `{"approved": "maybe"}`},
+	})
+	var serr *StructuredOutputError
+	if !errors.As(results[0].Err, &serr) || serr.Raw != `{"approved": "maybe"}` {
+		t.Errorf("invalid reply should fail its format: %+v", results[0])
+	}
+	if results[1].ID != "b" || results[1].Err == nil {
+		t.Errorf("missing result = %+v", results[1])
+	}
+	if results[2].Content != "third" {
+		t.Errorf("results out of order: %+v", results)
+	}
+}
+
+func TestCheckBatchRequests(t *testing.T) {
+	if err := checkBatchRequests([]BatchRequest{{ID: "eval-1"}, {ID: "eval_2"}}); err != nil {
+		t.Error(err)
+	}
+	for _, bad := range [][]BatchRequest{
+		nil,
+		{{ID: "case one"}},
+		{{ID: "a"}, {ID: "a"}},
+	} {
+		if checkBatchRequests(bad) == nil {
+			t.Errorf("%+v should be rejected", bad)
+		}
+	}
+}
+
+func TestNewBatcherFollowsProvider(t *testing.T) {
+	if _, err := NewBatcher(&Config{APIKey: "k", BaseURL: "http://127.0.0.1:8080/v1"}); !errors.Is(err, ErrBatchUnsupported) {
+		t.Errorf("local server: err = %v", err)
+	}
+	b, err := NewBatcher(&Config{APIKey: "k", BaseURL: "https://api.anthropic.com/v1"})
+	if err != nil || b.Name() != "anthropic batch" {
+		t.Errorf("anthropic: %v, %v", b, err)
+	}
+	b, err = NewBatcher(&Config{APIKey: "k", BaseURL: "https://api.openai.com/v1"})
+	if err != nil || b.Name() != "openai batch" {
+		t.Errorf("openai: %v, %v", b, err)
+	}
+}
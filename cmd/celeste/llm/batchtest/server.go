@@ -0,0 +1,413 @@
+// Package batchtest is a local stand-in for the OpenAI and Anthropic batch
+// APIs, so batch execution can be tested without a provider account or a
+// day's wait. Point a profile or llm.Config at Server.BaseURL with
+// batch_api set to "openai" or "anthropic".
+package batchtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is one batched request as the stand-in received it.
+type Request struct {
+	API      string // "openai" or "anthropic"
+	CustomID string
+	Model    string
+	System   string
+	Prompt   string         // text of the last user message
+	Body     map[string]any // the request body as sent
+}
+
+// Reply answers one request. An error fails that request alone.
+type Reply func(Request) (string, error)
+
+// Echo replies with the request's prompt.
+func Echo(r Request) (string, error) { return r.Prompt, nil }
+
+// Server serves both batch protocols under /v1. Requests are answered when
+// the batch is created; the batch reports itself in progress for
+// PollsUntilDone status checks before it ends.
+type Server struct {
+	*httptest.Server
+
+	// PollsUntilDone is the number of status checks that see a batch still
+	// running. Zero ends batches on the first check.
+	PollsUntilDone int
+
+	mu       sync.Mutex
+	reply    Reply
+	seq      int
+	files    map[string][]byte
+	batches  map[string]*batch
+	requests []Request
+}
+
+type batch struct {
+	id       string
+	api      string
+	polls    int
+	canceled bool
+	inputID  string
+	lines    []resultLine
+}
+
+type resultLine struct {
+	req     Request
+	content string
+	err     error
+}
+
+// NewServer starts a stand-in that answers with reply (Echo when nil).
+// Close it when done.
+func NewServer(reply Reply) *Server {
+	if reply == nil {
+		reply = Echo
+	}
+	s := &Server{reply: reply, files: map[string][]byte{}, batches: map[string]*batch{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/files", s.uploadFile)
+	mux.HandleFunc("GET /v1/files/{id}/content", s.fileContent)
+	mux.HandleFunc("POST /v1/batches", s.createOpenAI)
+	mux.HandleFunc("GET /v1/batches/{id}", s.getOpenAI)
+	mux.HandleFunc("POST /v1/batches/{id}/cancel", s.cancel)
+	mux.HandleFunc("POST /v1/messages/batches", s.createAnthropic)
+	mux.HandleFunc("GET /v1/messages/batches/{id}", s.getAnthropic)
+	mux.HandleFunc("GET /v1/messages/batches/{id}/results", s.anthropicResults)
+	mux.HandleFunc("POST /v1/messages/batches/{id}/cancel", s.cancel)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// BaseURL is the base URL to configure, for either protocol.
+func (s *Server) BaseURL() string { return s.URL + "/v1" }
+
+// Requests returns every request submitted so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s%d", prefix, s.seq)
+}
+
+// answer records req and runs the reply function on it.
+func (s *Server) answer(req Request) resultLine {
+	s.requests = append(s.requests, req)
+	content, err := s.reply(req)
+	return resultLine{req: req, content: content, err: err}
+}
+
+// poll counts a status check and reports whether the batch has ended.
+func (s *Server) poll(b *batch) bool {
+	b.polls++
+	return b.canceled || b.polls > s.PollsUntilDone
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *batch {
+	b := s.batches[r.PathValue("id")]
+	if b == nil {
+		http.Error(w, `{"error":{"message":"no such batch"}}`, http.StatusNotFound)
+	}
+	return b
+}
+
+func (s *Server) cancel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.lookup(w, r)
+	if b == nil {
+		return
+	}
+	b.canceled = true
+	if b.api == "openai" {
+		writeJSON(w, s.openAIBatch(b, true))
+	} else {
+		writeJSON(w, s.anthropicBatch(b, true))
+	}
+}
+
+// --- OpenAI: files + /batches ---
+
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, _ := io.ReadAll(file)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID("file-")
+	s.files[id] = data
+	writeJSON(w, map[string]any{
+		"id": id, "object": "file", "purpose": r.FormValue("purpose"),
+		"filename": header.Filename, "bytes": len(data), "created_at": time.Now().Unix(),
+	})
+}
+
+func (s *Server) fileContent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[r.PathValue("id")]
+	if !ok {
+		http.Error(w, `{"error":{"message":"no such file"}}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jsonl")
+	_, _ = w.Write(data)
+}
+
+func (s *Server) createOpenAI(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		InputFileID string `json:"input_file_id"`
+		Endpoint    string `json:"endpoint"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	input, ok := s.files[body.InputFileID]
+	if !ok {
+		http.Error(w, `{"error":{"message":"no such input file"}}`, http.StatusBadRequest)
+		return
+	}
+	b := &batch{id: s.nextID("batch_"), api: "openai", inputID: body.InputFileID}
+	for _, raw := range strings.Split(strings.TrimSpace(string(input)), "\n") {
+		var line struct {
+			CustomID string         `json:"custom_id"`
+			Body     map[string]any `json:"body"`
+		}
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":{"message":%q}}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		req := Request{API: "openai", CustomID: line.CustomID, Body: line.Body}
+		req.Model, _ = line.Body["model"].(string)
+		msgs, _ := line.Body["messages"].([]any)
+		for _, m := range msgs {
+			msg, _ := m.(map[string]any)
+			switch msg["role"] {
+			case "system":
+				req.System = text(msg["content"])
+			case "user":
+				req.Prompt = text(msg["content"])
+			}
+		}
+		b.lines = append(b.lines, s.answer(req))
+	}
+	s.batches[b.id] = b
+	writeJSON(w, s.openAIBatch(b, false))
+}
+
+func (s *Server) getOpenAI(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.lookup(w, r)
+	if b == nil {
+		return
+	}
+	writeJSON(w, s.openAIBatch(b, s.poll(b)))
+}
+
+func (s *Server) openAIBatch(b *batch, done bool) map[string]any {
+	obj := map[string]any{
+		"id": b.id, "object": "batch", "endpoint": "/v1/chat/completions",
+		"input_file_id": b.inputID, "completion_window": "24h",
+		"status": "in_progress", "created_at": time.Now().Unix(),
+		"request_counts": map[string]int{"total": len(b.lines), "completed": 0, "failed": 0},
+	}
+	if !done {
+		return obj
+	}
+	if b.canceled {
+		obj["status"] = "cancelled"
+		return obj
+	}
+	var output, errs []string
+	for i, l := range b.lines {
+		tokens := usage(l)
+		line := map[string]any{"id": fmt.Sprintf("batch_req_%d", i+1), "custom_id": l.req.CustomID, "error": nil}
+		if l.err != nil {
+			line["response"] = map[string]any{"status_code": 400, "body": map[string]any{
+				"error": map[string]any{"message": l.err.Error(), "type": "invalid_request_error"}}}
+			errs = append(errs, mustJSON(line))
+			continue
+		}
+		line["response"] = map[string]any{"status_code": 200, "body": map[string]any{
+			"id": fmt.Sprintf("chatcmpl-%d", i+1), "object": "chat.completion", "model": l.req.Model,
+			"choices": []any{map[string]any{"index": 0, "finish_reason": "stop",
+				"message": map[string]any{"role": "assistant", "content": l.content}}},
+			"usage": map[string]int{"prompt_tokens": tokens[0], "completion_tokens": tokens[1], "total_tokens": tokens[0] + tokens[1]},
+		}}
+		output = append(output, mustJSON(line))
+	}
+	obj["status"] = "completed"
+	obj["request_counts"] = map[string]int{"total": len(b.lines), "completed": len(output), "failed": len(errs)}
+	if len(output) > 0 {
+		obj["output_file_id"] = s.storeFile(b.id+"-output", output)
+	}
+	if len(errs) > 0 {
+		obj["error_file_id"] = s.storeFile(b.id+"-errors", errs)
+	}
+	return obj
+}
+
+func (s *Server) storeFile(id string, lines []string) string {
+	s.files[id] = []byte(strings.Join(lines, "\n") + "\n")
+	return id
+}
+
+// --- Anthropic: /messages/batches ---
+
+func (s *Server) createAnthropic(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Requests []struct {
+			CustomID string         `json:"custom_id"`
+			Params   map[string]any `json:"params"`
+		} `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &batch{id: s.nextID("msgbatch_"), api: "anthropic"}
+	for _, item := range body.Requests {
+		req := Request{API: "anthropic", CustomID: item.CustomID, Body: item.Params}
+		req.Model, _ = item.Params["model"].(string)
+		req.System = text(item.Params["system"])
+		msgs, _ := item.Params["messages"].([]any)
+		for _, m := range msgs {
+			if msg, _ := m.(map[string]any); msg["role"] == "user" {
+				req.Prompt = text(msg["content"])
+			}
+		}
+		b.lines = append(b.lines, s.answer(req))
+	}
+	s.batches[b.id] = b
+	writeJSON(w, s.anthropicBatch(b, false))
+}
+
+func (s *Server) getAnthropic(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.lookup(w, r)
+	if b == nil {
+		return
+	}
+	writeJSON(w, s.anthropicBatch(b, s.poll(b)))
+}
+
+func (s *Server) anthropicBatch(b *batch, done bool) map[string]any {
+	counts := map[string]int{"processing": len(b.lines), "succeeded": 0, "errored": 0, "canceled": 0, "expired": 0}
+	obj := map[string]any{
+		"id": b.id, "type": "message_batch", "processing_status": "in_progress",
+		"created_at":     time.Now().UTC().Format(time.RFC3339),
+		"expires_at":     time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339),
+		"request_counts": counts,
+	}
+	if !done {
+		return obj
+	}
+	counts["processing"] = 0
+	for _, l := range b.lines {
+		switch {
+		case b.canceled:
+			counts["canceled"]++
+		case l.err != nil:
+			counts["errored"]++
+		default:
+			counts["succeeded"]++
+		}
+	}
+	obj["processing_status"] = "ended"
+	obj["ended_at"] = time.Now().UTC().Format(time.RFC3339)
+	obj["results_url"] = s.URL + "/v1/messages/batches/" + b.id + "/results"
+	return obj
+}
+
+func (s *Server) anthropicResults(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.lookup(w, r)
+	if b == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-jsonl")
+	for i, l := range b.lines {
+		var result map[string]any
+		switch {
+		case b.canceled:
+			result = map[string]any{"type": "canceled"}
+		case l.err != nil:
+			result = map[string]any{"type": "errored", "error": map[string]any{
+				"type": "error", "error": map[string]any{"type": "invalid_request_error", "message": l.err.Error()}}}
+		default:
+			tokens := usage(l)
+			result = map[string]any{"type": "succeeded", "message": map[string]any{
+				"id": fmt.Sprintf("msg_%d", i+1), "type": "message", "role": "assistant", "model": l.req.Model,
+				"content":     []any{map[string]any{"type": "text", "text": l.content}},
+				"stop_reason": "end_turn",
+				"usage":       map[string]int{"input_tokens": tokens[0], "output_tokens": tokens[1]},
+			}}
+		}
+		fmt.Fprintln(w, mustJSON(map[string]any{"custom_id": l.req.CustomID, "result": result}))
+	}
+}
+
+// --- helpers ---
+
+// text flattens a message content field: a string, or a list of text blocks.
+func text(content any) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []any:
+		var parts []string
+		for _, block := range c {
+			if m, _ := block.(map[string]any); m != nil {
+				if t, ok := m["text"].(string); ok {
+					parts = append(parts, t)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+// usage estimates prompt and completion tokens at four bytes a token.
+func usage(l resultLine) [2]int {
+	prompt := (len(l.req.System)+len(l.req.Prompt))/4 + 1
+	return [2]int{prompt, len(l.content)/4 + 1}
+}
+
+func mustJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -1,6 +1,11 @@
 // Package llm provides the LLM client for Celeste CLI.
 package llm
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
 // CacheablePrompt separates the system prompt into a static prefix (suitable
 // for provider-side prompt caching) and a dynamic suffix that changes between
 // turns.  The static prefix typically contains the persona definition, tool
@@ -22,3 +27,48 @@ func (cp CacheablePrompt) FullPrompt() string {
 	}
 	return cp.StaticPrefix + "\n\n---\n\n" + cp.DynamicSuffix
 }
+
+// CacheHitRate is the share of prompt tokens served from the provider's
+// prompt cache, or 0 when none were.
+func (u *TokenUsage) CacheHitRate() float64 {
+	if u == nil || u.PromptTokens <= 0 || u.CachedTokens <= 0 {
+		return 0
+	}
+	return float64(u.CachedTokens) / float64(u.PromptTokens)
+}
+
+// historyCachePoints picks where a backend with explicit breakpoints ends
+// the cached prefix of the conversation, given the roles of the messages it
+// is about to send. It returns up to two indexes: the last message, so the
+// whole request is written to the cache for the next turn to read back, and
+// the last message before the latest assistant turn, which is where the
+// previous request ended. The second point keeps the previous entry
+// reachable when a turn adds more blocks than the provider looks back over
+// (many parallel tool results).
+func historyCachePoints(roles []string) []int {
+	if len(roles) == 0 {
+		return nil
+	}
+	last := len(roles) - 1
+	points := []int{last}
+	for i := last; i > 0; i-- {
+		if roles[i] == "assistant" {
+			if i-1 != last {
+				points = append(points, i-1)
+			}
+			break
+		}
+	}
+	return points
+}
+
+// newCacheKey returns a random key identifying one conversation to the
+// provider's cache routing, so consecutive requests land where their
+// prefix is already cached.
+func newCacheKey() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return "celeste-" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,197 @@
+// Package llm provides the LLM client for Celeste CLI.
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	genai "google.golang.org/genai"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+const (
+	// geminiMinCacheTokens is roughly the smallest prefix Gemini accepts
+	// for an explicit cache; below it the create call fails anyway.
+	geminiMinCacheTokens = 2048
+	// geminiCacheTTL is how long an idle cache lives. Storage is billed by
+	// the hour, so it is kept short and extended while requests use it.
+	geminiCacheTTL = 15 * time.Minute
+	// geminiCacheRefresh is how close to expiry a cache is extended rather
+	// than trusted for another request.
+	geminiCacheRefresh = 2 * time.Minute
+)
+
+// geminiContextCache is the explicit context cache holding the stable part
+// of every request: the system instruction and the tool declarations, which
+// in an agent run are most of the prompt. Gemini does not allow extending a
+// cache with history, so the conversation itself relies on Gemini's
+// implicit caching.
+type geminiContextCache struct {
+	mu      sync.Mutex
+	key     string // digest of the model, system instruction and tools
+	name    string // cachedContents/...; empty when nothing is cached
+	expires time.Time
+	refused string // key the API would not cache; not retried
+}
+
+// applyContextCache moves the system instruction and tools into an explicit
+// cache and points the request at it.
+func (b *GoogleBackend) applyContextCache(ctx context.Context, genConfig *genai.GenerateContentConfig) {
+	if b.config.NoPromptCache || (genConfig.SystemInstruction == nil && len(genConfig.Tools) == 0) {
+		return
+	}
+	stable, err := json.Marshal(struct {
+		Model             string
+		SystemInstruction *genai.Content
+		Tools             []*genai.Tool
+		ToolConfig        *genai.ToolConfig
+	}{b.config.Model, genConfig.SystemInstruction, genConfig.Tools, genConfig.ToolConfig})
+	if err != nil || len(stable)/4 < geminiMinCacheTokens {
+		return
+	}
+	sum := sha256.Sum256(stable)
+	name := b.cache.lookup(ctx, b.client, b.config.Model, hex.EncodeToString(sum[:]), genConfig)
+	if name == "" {
+		return
+	}
+	// A request that names a cache must not repeat what the cache holds.
+	genConfig.CachedContent = name
+	genConfig.SystemInstruction = nil
+	genConfig.Tools = nil
+	genConfig.ToolConfig = nil
+}
+
+// lookup returns the name of a live cache for key, creating or extending one
+// as needed, or "" when the request should go uncached. c.mu guards only the
+// bookkeeping; it is not held across the Create, Update and Delete calls, so
+// a slow cache API does not stall every other request on the backend.
+func (c *geminiContextCache) lookup(ctx context.Context, client *genai.Client, model, key string, genConfig *genai.GenerateContentConfig) string {
+	c.mu.Lock()
+	if key == c.refused {
+		c.mu.Unlock()
+		return ""
+	}
+	current, name, expires := c.key, c.name, c.expires
+	c.mu.Unlock()
+
+	now := time.Now()
+	if key == current && name != "" {
+		if now.Before(expires.Add(-geminiCacheRefresh)) {
+			return name
+		}
+		if now.Before(expires) {
+			updated, err := client.Caches.Update(ctx, name, &genai.UpdateCachedContentConfig{TTL: geminiCacheTTL})
+			if err == nil {
+				c.mu.Lock()
+				if c.name == name {
+					c.expires = expiryOf(updated, now)
+				}
+				c.mu.Unlock()
+				return name
+			}
+		}
+	}
+
+	created, err := client.Caches.Create(ctx, model, &genai.CreateCachedContentConfig{
+		TTL:               geminiCacheTTL,
+		DisplayName:       "celeste",
+		SystemInstruction: genConfig.SystemInstruction,
+		Tools:             genConfig.Tools,
+		ToolConfig:        genConfig.ToolConfig,
+	})
+	if err != nil || created.Name == "" {
+		if geminiCacheRefused(err) {
+			c.mu.Lock()
+			c.refused = key
+			c.mu.Unlock()
+			tui.LogInfo(fmt.Sprintf("Gemini will not cache this prompt for %s, sending it uncached: %v", model, err))
+		} else {
+			tui.LogInfo(fmt.Sprintf("Gemini context cache unavailable for %s, sending the prompt uncached this time: %v", model, err))
+		}
+		return ""
+	}
+
+	c.mu.Lock()
+	if c.key == key && c.name != "" && c.name != name {
+		// Another request created a cache for the same prefix while this
+		// one was waiting; keep theirs and drop the duplicate.
+		winner := c.name
+		c.mu.Unlock()
+		_, _ = client.Caches.Delete(ctx, created.Name, nil)
+		return winner
+	}
+	stale := c.name
+	c.key, c.name = key, created.Name
+	c.expires = expiryOf(created, now)
+	c.mu.Unlock()
+	if stale != "" {
+		// The tools or prompt changed (or the cache lapsed); the old entry
+		// is no use to anyone and is billed until it expires.
+		_, _ = client.Caches.Delete(ctx, stale, nil)
+	}
+	return created.Name
+}
+
+// geminiCacheRefused reports whether a Create error means Gemini will never
+// cache this prefix: it is below the model's minimum size, or the model does
+// not support explicit caching. Anything else — a timeout, a 429, a 5xx —
+// may pass, so the next request tries again.
+func geminiCacheRefused(err error) bool {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Message)
+	switch apiErr.Code {
+	case http.StatusBadRequest:
+		return strings.Contains(msg, "too small") ||
+			strings.Contains(msg, "min_total_token_count") ||
+			strings.Contains(msg, "not supported")
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return strings.Contains(msg, "not supported") || strings.Contains(msg, "unsupported")
+	}
+	return false
+}
+
+func expiryOf(cc *genai.CachedContent, now time.Time) time.Time {
+	if cc != nil && !cc.ExpireTime.IsZero() {
+		return cc.ExpireTime
+	}
+	return now.Add(geminiCacheTTL)
+}
+
+// release deletes the cache, if any. Called on Close.
+func (c *geminiContextCache) release(client *genai.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.name == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = client.Caches.Delete(ctx, c.name, nil)
+	c.name = ""
+}
+
+// googleUsage converts Gemini's usage metadata. The prompt count includes
+// tokens served from a context cache; thinking tokens are billed as output.
+func googleUsage(md *genai.GenerateContentResponseUsageMetadata) *TokenUsage {
+	if md == nil || md.TotalTokenCount == 0 {
+		return nil
+	}
+	return &TokenUsage{
+		PromptTokens:     int(md.PromptTokenCount),
+		CompletionTokens: int(md.CandidatesTokenCount + md.ThoughtsTokenCount),
+		TotalTokens:      int(md.TotalTokenCount),
+		CachedTokens:     int(md.CachedContentTokenCount),
+	}
+}
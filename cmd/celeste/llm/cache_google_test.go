@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// geminiStandIn serves cachedContents and generateContent, recording what
+// each generateContent request carried.
+type geminiStandIn struct {
+	creates  int
+	deletes  int
+	requests []map[string]json.RawMessage
+	// createErrors are returned, in order, by the next cachedContents
+	// creates before any succeeds.
+	createErrors []geminiError
+}
+
+type geminiError struct {
+	code    int
+	message string
+}
+
+func (s *geminiStandIn) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/cachedContents"):
+			if len(s.createErrors) > 0 {
+				e := s.createErrors[0]
+				s.createErrors = s.createErrors[1:]
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(e.code)
+				fmt.Fprintf(w, `{"error":{"code":%d,"message":%q,"status":"ERROR"}}`, e.code, e.message)
+				return
+			}
+			s.creates++
+			fmt.Fprintf(w, `{"name":"cachedContents/c%d","model":"models/gemini-test"}`, s.creates)
+		case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/cachedContents/"):
+			s.deletes++
+			fmt.Fprint(w, `{}`)
+		case strings.HasSuffix(r.URL.Path, ":generateContent"):
+			var body map[string]json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+			s.requests = append(s.requests, body)
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],`+
+				`"usageMetadata":{"promptTokenCount":6000,"cachedContentTokenCount":5200,"candidatesTokenCount":4,"totalTokenCount":6004}}`)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGeminiContextCacheHoldsSystemAndTools(t *testing.T) {
+	stand := &geminiStandIn{}
+	srv := stand.start(t)
+	b, err := NewGoogleBackend(&Config{BaseURL: srv.URL, APIKey: "k", Model: "gemini-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetSystemPrompt(strings.Repeat("You are a careful agent. ", 800))
+	tools := []tui.SkillDefinition{{Name: "read_file", Description: "Read a file", Parameters: map[string]any{"type": "object"}}}
+	history := []tui.ChatMessage{{Role: "user", Content: "hi"}}
+
+	for i := 0; i < 2; i++ {
+		res, err := b.SendMessageSync(context.Background(), history, tools)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Usage == nil || res.Usage.CachedTokens != 5200 || res.Usage.PromptTokens != 6000 {
+			t.Fatalf("usage = %+v", res.Usage)
+		}
+	}
+	if stand.creates != 1 {
+		t.Errorf("cache created %d times, want once", stand.creates)
+	}
+	for _, req := range stand.requests {
+		if string(req["cachedContent"]) != `"cachedContents/c1"` {
+			t.Errorf("cachedContent = %s", req["cachedContent"])
+		}
+		if req["systemInstruction"] != nil || req["tools"] != nil {
+			t.Errorf("cached parts were resent: %v", req)
+		}
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stand.deletes != 1 {
+		t.Errorf("cache deleted %d times on close, want once", stand.deletes)
+	}
+}
+
+func TestGeminiSmallPromptIsNotCached(t *testing.T) {
+	stand := &geminiStandIn{}
+	srv := stand.start(t)
+	b, err := NewGoogleBackend(&Config{BaseURL: srv.URL, APIKey: "k", Model: "gemini-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetSystemPrompt("short persona")
+
+	if _, err := b.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stand.creates != 0 || stand.requests[0]["systemInstruction"] == nil {
+		t.Errorf("creates = %d, request = %v", stand.creates, stand.requests[0])
+	}
+}
+
+func TestGeminiContextCacheRetriesAfterTransientFailure(t *testing.T) {
+	stand := &geminiStandIn{createErrors: []geminiError{{http.StatusServiceUnavailable, "The service is currently unavailable."}}}
+	srv := stand.start(t)
+	b, err := NewGoogleBackend(&Config{BaseURL: srv.URL, APIKey: "k", Model: "gemini-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetSystemPrompt(strings.Repeat("You are a careful agent. ", 800))
+	history := []tui.ChatMessage{{Role: "user", Content: "hi"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.SendMessageSync(context.Background(), history, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stand.creates != 1 {
+		t.Fatalf("cache created %d times after a 503, want once", stand.creates)
+	}
+	if stand.requests[0]["systemInstruction"] == nil {
+		t.Errorf("first request lost its system instruction: %v", stand.requests[0])
+	}
+	if string(stand.requests[1]["cachedContent"]) != `"cachedContents/c1"` {
+		t.Errorf("second request cachedContent = %s", stand.requests[1]["cachedContent"])
+	}
+}
+
+func TestGeminiContextCacheRefusalIsNotRetried(t *testing.T) {
+	stand := &geminiStandIn{createErrors: []geminiError{
+		{http.StatusBadRequest, "Cached content is too small. total_token_count=2100, min_total_token_count=4096"},
+	}}
+	srv := stand.start(t)
+	b, err := NewGoogleBackend(&Config{BaseURL: srv.URL, APIKey: "k", Model: "gemini-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetSystemPrompt(strings.Repeat("You are a careful agent. ", 800))
+	history := []tui.ChatMessage{{Role: "user", Content: "hi"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.SendMessageSync(context.Background(), history, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stand.creates != 0 || len(stand.createErrors) != 0 {
+		t.Errorf("creates = %d, unused errors = %d", stand.creates, len(stand.createErrors))
+	}
+	for _, req := range stand.requests {
+		if req["cachedContent"] != nil || req["systemInstruction"] == nil {
+			t.Errorf("refused prompt was not sent uncached: %v", req)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package llm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHistoryCachePoints(t *testing.T) {
+	for _, tc := range []struct {
+		roles []string
+		want  []int
+	}{
+		{nil, nil},
+		{[]string{"user"}, []int{0}},
+		{[]string{"user", "assistant", "user"}, []int{2, 0}},
+		{[]string{"user", "assistant", "user", "assistant", "user"}, []int{4, 2}},
+		{[]string{"user", "assistant"}, []int{1, 0}},
+	} {
+		got := historyCachePoints(tc.roles)
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("historyCachePoints(%v) = %v, want %v", tc.roles, got, tc.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
@@ -21,6 +22,14 @@ type Client struct {
 	registry     *tools.Registry
 	backendType  BackendType
 	systemPrompt string
+	thinking     *ThinkingConfig // replayed onto fallback backends created later
+
+	// Failover chain (see failover.go). The primary is backend/config above.
+	fallbacks    []*chainLink
+	mu           sync.Mutex
+	primaryDown  time.Time // primary is skipped until then after failing over
+	served       ServedBy
+	sleepBackoff func(time.Duration) // nil = time.Sleep; replaced in tests
 }
 
 // Config holds LLM client configuration.
@@ -40,15 +49,44 @@ type Config struct {
 	// Collections (xAI only)
 	Collections *config.CollectionsConfig
 	XAIFeatures *config.XAIFeaturesConfig
+
+	// Fallbacks are tried in order when this provider is unavailable.
+	Fallbacks []FallbackTarget
+
+	// Ollama (native API only)
+	KeepAlive    string // keep_alive sent with each request; empty = server default
+	ContextLimit int    // options.num_ctx; 0 = the window /api/show reports
+
+	// OpenAI Responses API only: hosted tools offered alongside celeste's own
+	BuiltinTools []string
+
+	// NoPromptCache turns off cache breakpoints, Gemini context caches and
+	// cache routing hints. Providers that cache automatically still do.
+	NoPromptCache bool
+
+	// BatchAPI names the batch protocol ("openai" or "anthropic") for a
+	// provider the registry does not know, such as a proxy. Empty = detect.
+	BatchAPI string
 }
 
 // NewClient creates a new LLM client with automatic backend selection.
 // It detects whether to use OpenAI SDK, Google GenAI SDK, or xAI SDK based on the base URL.
 func NewClient(config *Config, registry *tools.Registry) *Client {
-	// Detect which backend to use
-	backendType := DetectBackendType(config.BaseURL)
+	backend, backendType := newBackend(config, registry)
+	return &Client{
+		backend:     backend,
+		config:      config,
+		registry:    registry,
+		backendType: backendType,
+		fallbacks:   newFallbackChain(config),
+	}
+}
+
+// newBackend creates the backend for config's base URL. Native backends that
+// fail to initialize fall back to the OpenAI-compatible SDK.
+func newBackend(config *Config, registry *tools.Registry) (LLMBackend, BackendType) {
+	backendType := SelectBackendType(config)
 
-	var backend LLMBackend
 	switch backendType {
 	case BackendTypeXAI:
 		// Use native xAI SDK for Grok with Collections support
@@ -56,12 +94,10 @@ func NewClient(config *Config, registry *tools.Registry) *Client {
 		if err != nil {
 			// Fallback to OpenAI backend if xAI backend fails
 			fmt.Fprintf(os.Stderr, "Warning: Failed to create xAI backend: %v\nFalling back to OpenAI SDK\n", err)
-			backendType = BackendTypeOpenAI
-			backend = NewOpenAIBackend(config)
-		} else {
-			backend = xaiBackend
-			tui.LogInfo("Using xAI backend with Collections support")
+			return NewOpenAIBackend(config), BackendTypeOpenAI
 		}
+		tui.LogInfo("Using xAI backend with Collections support")
+		return xaiBackend, backendType
 
 	case BackendTypeGoogle:
 		// Use Google GenAI SDK for Gemini/Vertex AI
@@ -69,11 +105,9 @@ func NewClient(config *Config, registry *tools.Registry) *Client {
 		if err != nil {
 			// Fallback to OpenAI backend if Google backend fails
 			fmt.Fprintf(os.Stderr, "Warning: Failed to create Google backend: %v\nFalling back to OpenAI SDK\n", err)
-			backendType = BackendTypeOpenAI
-			backend = NewOpenAIBackend(config)
-		} else {
-			backend = googleBackend
+			return NewOpenAIBackend(config), BackendTypeOpenAI
 		}
+		return googleBackend, backendType
 
 	case BackendTypeAnthropic:
 		// Use native Anthropic SDK for Claude models
@@ -81,29 +115,36 @@ func NewClient(config *Config, registry *tools.Registry) *Client {
 		if err != nil {
 			// Fallback to OpenAI backend if Anthropic backend fails
 			fmt.Fprintf(os.Stderr, "Warning: Failed to create Anthropic backend: %v\nFalling back to OpenAI SDK\n", err)
-			backendType = BackendTypeOpenAI
-			backend = NewOpenAIBackend(config)
-		} else {
-			backend = anthropicBackend
-			tui.LogInfo("Using native Anthropic backend with prompt caching")
+			return NewOpenAIBackend(config), BackendTypeOpenAI
 		}
+		tui.LogInfo("Using native Anthropic backend with prompt caching")
+		return anthropicBackend, backendType
+
+	case BackendTypeOllama:
+		tui.LogInfo("Using native Ollama backend")
+		return NewOllamaBackend(config), backendType
+
+	case BackendTypeOpenAIResponses:
+		tui.LogInfo(fmt.Sprintf("Using OpenAI Responses API backend for %s", config.Model))
+		return NewOpenAIResponsesBackend(config), backendType
 
 	default:
 		// Use OpenAI SDK for OpenAI, Venice, etc.
-		backend = NewOpenAIBackend(config)
-	}
-
-	return &Client{
-		backend:     backend,
-		config:      config,
-		registry:    registry,
-		backendType: backendType,
+		return NewOpenAIBackend(config), BackendTypeOpenAI
 	}
 }
 
 // SetSystemPrompt sets the system prompt (Celeste persona).
 func (c *Client) SetSystemPrompt(prompt string) {
+	// Under c.mu, so a fallback being created meanwhile sees the new value.
+	c.mu.Lock()
 	c.systemPrompt = prompt
+	for _, link := range c.fallbacks {
+		if link.backend != nil {
+			link.backend.SetSystemPrompt(prompt)
+		}
+	}
+	c.mu.Unlock()
 	if c.backend != nil {
 		c.backend.SetSystemPrompt(prompt)
 	}
@@ -111,6 +152,14 @@ func (c *Client) SetSystemPrompt(prompt string) {
 
 // SetThinkingConfig configures extended thinking / reasoning effort.
 func (c *Client) SetThinkingConfig(config ThinkingConfig) {
+	c.mu.Lock()
+	c.thinking = &config
+	for _, link := range c.fallbacks {
+		if link.backend != nil {
+			link.backend.SetThinkingConfig(config)
+		}
+	}
+	c.mu.Unlock()
 	if c.backend != nil {
 		c.backend.SetThinkingConfig(config)
 	}
@@ -121,62 +170,27 @@ func (c *Client) SetThinkingConfig(config ThinkingConfig) {
 func (c *Client) UpdateConfig(config *Config) {
 	c.config = config
 
-	// Detect if backend type changed
-	newBackendType := DetectBackendType(config.BaseURL)
-
-	if newBackendType != c.backendType {
+	// Detect if backend type changed (the model can change it too: see
+	// SelectBackendType)
+	if SelectBackendType(config) != c.backendType {
 		// Backend type changed - recreate backend
 		if c.backend != nil {
 			c.backend.Close()
 		}
+		c.backend, c.backendType = newBackend(config, c.registry)
 
-		switch newBackendType {
-		case BackendTypeXAI:
-			xaiBackend, err := NewXAIBackend(config, c.registry)
-			if err != nil {
-				// Fallback to OpenAI if xAI fails
-				fmt.Fprintf(os.Stderr, "Warning: Failed to create xAI backend: %v\nFalling back to OpenAI SDK\n", err)
-				newBackendType = BackendTypeOpenAI
-				c.backend = NewOpenAIBackend(config)
-			} else {
-				c.backend = xaiBackend
-				tui.LogInfo("Switched to xAI backend with Collections support")
-			}
-
-		case BackendTypeGoogle:
-			googleBackend, err := NewGoogleBackend(config)
-			if err != nil {
-				// Fallback to OpenAI if Google fails
-				fmt.Fprintf(os.Stderr, "Warning: Failed to create Google backend: %v\nFalling back to OpenAI SDK\n", err)
-				newBackendType = BackendTypeOpenAI
-				c.backend = NewOpenAIBackend(config)
-			} else {
-				c.backend = googleBackend
-			}
-
-		case BackendTypeAnthropic:
-			anthropicBackend, err := NewAnthropicBackend(config)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to create Anthropic backend: %v\nFalling back to OpenAI SDK\n", err)
-				newBackendType = BackendTypeOpenAI
-				c.backend = NewOpenAIBackend(config)
-			} else {
-				c.backend = anthropicBackend
-				tui.LogInfo("Switched to native Anthropic backend")
-			}
-
-		default:
-			c.backend = NewOpenAIBackend(config)
-		}
-
-		c.backendType = newBackendType
-
-		// Restore system prompt
+		// Restore system prompt and thinking config
 		if c.systemPrompt != "" {
 			c.backend.SetSystemPrompt(c.systemPrompt)
 		}
+		if c.thinking != nil {
+			c.backend.SetThinkingConfig(*c.thinking)
+		}
 	}
 	// Note: Config changes within same backend type are handled by passing config to methods
+
+	c.closeFallbacks()
+	c.fallbacks = newFallbackChain(config)
 }
 
 // GetConfig returns the current configuration.
@@ -187,6 +201,7 @@ func (c *Client) GetConfig() *Config {
 // ChatCompletionResult holds the result of a chat completion.
 type ChatCompletionResult struct {
 	Content      string
+	Thinking     string // reasoning the model streamed before Content, if any
 	ToolCalls    []ToolCallResult
 	FinishReason string
 	Error        error
@@ -205,21 +220,36 @@ type ToolCallResult struct {
 	// ThoughtSignature carries Gemini 3.x's opaque per-call token through to the
 	// assistant message so the next turn can echo it back. Empty elsewhere.
 	ThoughtSignature []byte
+	// ReasoningState carries the Responses API reasoning items that preceded
+	// this call (set on the first call of a turn only), so they can be sent
+	// back with the tool results. Empty for every other backend.
+	ReasoningState []byte
 }
 
 // SendMessageSync sends a message synchronously and returns the result.
-// This delegates to the appropriate backend (OpenAI or Google).
-func (c *Client) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
+// This delegates to the appropriate backend (OpenAI or Google), moving down
+// the fallback chain when the provider is unavailable.
+func (c *Client) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, opts ...SendOption) (*ChatCompletionResult, error) {
+	if o := collectSendOptions(opts); o.format != nil {
+		return sendStructured(ctx, messages, o.format, func(ctx context.Context, msgs []tui.ChatMessage) (*ChatCompletionResult, error) {
+			return c.sendSync(ctx, msgs, tools)
+		})
+	}
+	return c.sendSync(ctx, messages, tools)
+}
+
+func (c *Client) sendSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
 	var res *ChatCompletionResult
-	msgs := messages
-	err := withRetry(ctx, retryOpts{
-		timeout:   c.perAttemptTimeout(),
-		beforeTry: c.trimHook(&msgs),
-	}, func(reqCtx context.Context) error {
-		var e error
-		res, e = c.backend.SendMessageSync(reqCtx, msgs, tools)
-		return e
-	}, func(d time.Duration) { time.Sleep(d) })
+	err := c.withFailover(ctx, messages, func(backend LLMBackend, msgs []tui.ChatMessage) error {
+		return withRetry(ctx, retryOpts{
+			timeout:   c.perAttemptTimeout(),
+			beforeTry: c.trimHook(&msgs),
+		}, func(reqCtx context.Context) error {
+			var e error
+			res, e = backend.SendMessageSync(reqCtx, msgs, tools)
+			return e
+		}, c.sleep)
+	})
 	return res, err
 }
 
@@ -255,6 +285,8 @@ type TokenUsage struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
+	CachedTokens     int // Prompt tokens read from the provider's prompt cache (included in PromptTokens)
+	CacheWriteTokens int // Prompt tokens written to the cache this request (Anthropic only)
 }
 
 type StreamChunk struct {
@@ -269,37 +301,52 @@ type StreamChunk struct {
 // SendMessageStream sends a message with streaming callback.
 // This delegates to the appropriate backend (OpenAI or Google).
 func (c *Client) SendMessageStream(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamCallback) error {
-	msgs := messages
-	return withRetry(ctx, retryOpts{
-		timeout:   c.perAttemptTimeout(),
-		beforeTry: c.trimHook(&msgs),
-	}, func(reqCtx context.Context) error {
-		started := false
-		wrapped := func(chunk StreamChunk) { started = true; callback(chunk) }
-		err := c.backend.SendMessageStream(reqCtx, msgs, tools, wrapped)
-		if err != nil && started {
-			return fatalErr(err)
-		}
-		return err
-	}, func(d time.Duration) { time.Sleep(d) })
+	return c.withFailover(ctx, messages, func(backend LLMBackend, msgs []tui.ChatMessage) error {
+		return withRetry(ctx, retryOpts{
+			timeout:   c.perAttemptTimeout(),
+			beforeTry: c.trimHook(&msgs),
+		}, func(reqCtx context.Context) error {
+			started := false
+			wrapped := func(chunk StreamChunk) { started = true; callback(chunk) }
+			err := backend.SendMessageStream(reqCtx, msgs, tools, wrapped)
+			if err != nil && started {
+				return fatalErr(err)
+			}
+			return err
+		}, c.sleep)
+	})
 }
 
 // SendMessageStreamEvents sends a message with granular streaming events.
 // This delegates to the appropriate backend.
-func (c *Client) SendMessageStreamEvents(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamEventCallback) error {
-	msgs := messages
-	return withRetry(ctx, retryOpts{
-		timeout:   c.perAttemptTimeout(),
-		beforeTry: c.trimHook(&msgs),
-	}, func(reqCtx context.Context) error {
-		started := false
-		wrapped := func(ev StreamEvent) { started = true; callback(ev) }
-		err := c.backend.SendMessageStreamEvents(reqCtx, msgs, tools, wrapped)
-		if err != nil && started {
-			return fatalErr(err)
+func (c *Client) SendMessageStreamEvents(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamEventCallback, opts ...SendOption) error {
+	if o := collectSendOptions(opts); o.format != nil {
+		// Partial JSON is no use to anyone: validate first, then deliver.
+		res, err := c.SendMessageSync(ctx, messages, tools, opts...)
+		if res == nil {
+			return err
 		}
+		if res.Thinking != "" {
+			callback(StreamEvent{Type: EventThinkingDelta, ContentDelta: res.Thinking})
+		}
+		callback(StreamEvent{Type: EventContentDelta, ContentDelta: res.Content})
+		callback(StreamEvent{Type: EventMessageDone, Usage: res.Usage, FinishReason: res.FinishReason})
 		return err
-	}, func(d time.Duration) { time.Sleep(d) })
+	}
+	return c.withFailover(ctx, messages, func(backend LLMBackend, msgs []tui.ChatMessage) error {
+		return withRetry(ctx, retryOpts{
+			timeout:   c.perAttemptTimeout(),
+			beforeTry: c.trimHook(&msgs),
+		}, func(reqCtx context.Context) error {
+			started := false
+			wrapped := func(ev StreamEvent) { started = true; callback(ev) }
+			err := backend.SendMessageStreamEvents(reqCtx, msgs, tools, wrapped)
+			if err != nil && started {
+				return fatalErr(err)
+			}
+			return err
+		}, c.sleep)
+	})
 }
 
 // GetSkills returns skill definitions for the TUI.
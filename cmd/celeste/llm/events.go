@@ -30,6 +30,19 @@ const (
 	// EventMessageDone is emitted when the entire LLM response is complete.
 	// Contains usage statistics and finish reason.
 	EventMessageDone
+
+	// EventServerToolUse is emitted when the provider ran one of its own
+	// built-in tools (web search, code interpreter) while generating the
+	// response. It is informational: the call already happened server-side
+	// and must not be executed locally. ToolUseID, ToolName and
+	// CompleteInput (the call's JSON) are set.
+	EventServerToolUse
+
+	// EventThinkingDelta is emitted when the model streams reasoning
+	// (Anthropic thinking blocks, Gemini thoughts, Ollama's thinking field).
+	// The text is in ContentDelta; it is kept for the transcript and never
+	// sent back to the model.
+	EventThinkingDelta
 )
 
 // String returns a human-readable name for the event type.
@@ -45,6 +58,10 @@ func (t StreamEventType) String() string {
 		return "ToolUseDone"
 	case EventMessageDone:
 		return "MessageDone"
+	case EventServerToolUse:
+		return "ServerToolUse"
+	case EventThinkingDelta:
+		return "ThinkingDelta"
 	default:
 		return fmt.Sprintf("Unknown(%d)", t)
 	}
@@ -57,7 +74,8 @@ type StreamEvent struct {
 	// Type identifies which kind of event this is.
 	Type StreamEventType
 
-	// ContentDelta contains the text delta (only for EventContentDelta).
+	// ContentDelta contains the text delta (for EventContentDelta and
+	// EventThinkingDelta).
 	ContentDelta string
 
 	// ToolUseID is the unique identifier for the tool call
@@ -85,6 +103,11 @@ type StreamEvent struct {
 	// thought_signature".
 	ThoughtSignature []byte
 
+	// ReasoningState carries the Responses API reasoning items that preceded
+	// this call (set for EventToolUseDone on the first call of a turn). Like
+	// ThoughtSignature it must ride on the event to reach the history.
+	ReasoningState []byte
+
 	// Usage contains token usage statistics (only for EventMessageDone).
 	Usage *TokenUsage
 
@@ -186,6 +209,7 @@ func (a *ToolUseAccumulator) HandleEvent(event StreamEvent) {
 			Name:             name,
 			Arguments:        input,
 			ThoughtSignature: signature,
+			ReasoningState:   event.ReasoningState,
 		})
 		delete(a.pending, event.ToolUseID)
 	}
@@ -0,0 +1,331 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/sashabaranov/go-openai"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+	"google.golang.org/genai"
+)
+
+// failoverCooldown is how long a provider that just failed over is skipped
+// before requests try it first again. Without it every turn of a long agent
+// run would sit through the full retry schedule of a provider that is down.
+const failoverCooldown = 5 * time.Minute
+
+// FallbackTarget is one entry of a failover chain: a provider+model pair with
+// its own endpoint and key.
+type FallbackTarget struct {
+	Name    string // label for logs and cost tracking; defaults to the detected provider
+	APIKey  string
+	BaseURL string
+	Model   string
+}
+
+// FallbacksFromConfig converts the config's failover chain for Config.Fallbacks.
+func FallbacksFromConfig(cfg *config.Config) []FallbackTarget {
+	var out []FallbackTarget
+	for _, fb := range cfg.ResolveFallbacks() {
+		out = append(out, FallbackTarget{Name: fb.Provider, APIKey: fb.APIKey, BaseURL: fb.BaseURL, Model: fb.Model})
+	}
+	return out
+}
+
+// ServedBy identifies the provider and model that answered a request.
+type ServedBy struct {
+	Provider string
+	Model    string
+	Fallback bool // a fallback answered because the primary was unavailable
+}
+
+// chainLink is a fallback whose backend is created on first use, so an
+// unused chain never opens connections.
+type chainLink struct {
+	name        string
+	config      *Config
+	backend     LLMBackend
+	backendType BackendType
+	downUntil   time.Time
+}
+
+// newFallbackChain builds the links for config.Fallbacks. Each inherits the
+// primary's settings and overrides the endpoint, key and model.
+func newFallbackChain(config *Config) []*chainLink {
+	var links []*chainLink
+	for _, fb := range config.Fallbacks {
+		if fb.BaseURL == "" || fb.Model == "" {
+			continue
+		}
+		cfg := *config
+		cfg.APIKey, cfg.BaseURL, cfg.Model = fb.APIKey, fb.BaseURL, fb.Model
+		cfg.Fallbacks = nil
+		links = append(links, &chainLink{
+			name:        providerLabel(fb.Name, fb.BaseURL),
+			config:      &cfg,
+			backendType: SelectBackendType(&cfg),
+		})
+	}
+	return links
+}
+
+// providerLabel names a provider for logs and cost records.
+func providerLabel(name, baseURL string) string {
+	if name != "" {
+		return name
+	}
+	if p := providers.DetectProvider(baseURL); p != "" {
+		return p
+	}
+	return baseURL
+}
+
+// LastServed reports which provider and model answered the most recent
+// successful request.
+func (c *Client) LastServed() ServedBy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.served.Model == "" && c.config != nil {
+		return ServedBy{Provider: providerLabel("", c.config.BaseURL), Model: c.config.Model}
+	}
+	return c.served
+}
+
+// withFailover runs send against the primary backend and, when the provider
+// is unavailable, against each fallback in turn. send is expected to retry
+// transient errors itself; the chain only advances once those retries are
+// exhausted or the model is gone. Providers that failed recently are tried
+// after the healthy ones.
+func (c *Client) withFailover(ctx context.Context, messages []tui.ChatMessage, send func(backend LLMBackend, msgs []tui.ChatMessage) error) error {
+	if len(c.fallbacks) == 0 {
+		err := send(c.backend, messages)
+		if err == nil {
+			c.recordServed(ServedBy{Provider: providerLabel("", c.config.BaseURL), Model: c.config.Model})
+		}
+		return err
+	}
+
+	type attempt struct {
+		link    *chainLink // nil for the primary
+		primary bool
+	}
+	now := time.Now()
+	c.mu.Lock()
+	var healthy, cooling []attempt
+	if now.Before(c.primaryDown) {
+		cooling = append(cooling, attempt{primary: true})
+	} else {
+		healthy = append(healthy, attempt{primary: true})
+	}
+	for _, link := range c.fallbacks {
+		if now.Before(link.downUntil) {
+			cooling = append(cooling, attempt{link: link})
+		} else {
+			healthy = append(healthy, attempt{link: link})
+		}
+	}
+	c.mu.Unlock()
+
+	var errs []string
+	var lastErr error
+	for _, a := range append(healthy, cooling...) {
+		backend, backendType, served := c.backend, c.backendType, ServedBy{Provider: providerLabel("", c.config.BaseURL), Model: c.config.Model}
+		msgs := messages
+		if !a.primary {
+			backend, backendType = c.fallbackBackend(a.link)
+			served = ServedBy{Provider: a.link.name, Model: a.link.config.Model, Fallback: true}
+			msgs = translateHistory(messages, backendType)
+		}
+
+		err := send(backend, msgs)
+		if err == nil {
+			c.recordServed(served)
+			return nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !shouldFailover(err) {
+			return err
+		}
+
+		c.mu.Lock()
+		if a.primary {
+			c.primaryDown = time.Now().Add(failoverCooldown)
+		} else {
+			a.link.downUntil = time.Now().Add(failoverCooldown)
+		}
+		c.mu.Unlock()
+		errs = append(errs, fmt.Sprintf("%s/%s: %v", served.Provider, served.Model, err))
+		tui.LogInfo(fmt.Sprintf("provider %s (%s) unavailable, trying next in chain: %v", served.Provider, served.Model, err))
+	}
+	if len(errs) > 1 {
+		return fmt.Errorf("all providers in the fallback chain failed: %s: %w", strings.Join(errs, "; "), lastErr)
+	}
+	return lastErr
+}
+
+func (c *Client) recordServed(s ServedBy) {
+	c.mu.Lock()
+	c.served = s
+	c.mu.Unlock()
+}
+
+// fallbackBackend creates a link's backend on first use and brings it up to
+// date with the client's system prompt and thinking settings. It holds c.mu
+// so concurrent requests failing over together create one backend, not one
+// each.
+func (c *Client) fallbackBackend(link *chainLink) (LLMBackend, BackendType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if link.backend == nil {
+		link.backend, link.backendType = newBackend(link.config, c.registry)
+		if c.systemPrompt != "" {
+			link.backend.SetSystemPrompt(c.systemPrompt)
+		}
+		if c.thinking != nil {
+			link.backend.SetThinkingConfig(*c.thinking)
+		}
+	}
+	return link.backend, link.backendType
+}
+
+func (c *Client) closeFallbacks() {
+	for _, link := range c.fallbacks {
+		if link.backend != nil {
+			link.backend.Close()
+		}
+	}
+}
+
+// sleep waits between retries; tests replace it to skip the backoff.
+func (c *Client) sleep(d time.Duration) {
+	if c.sleepBackoff != nil {
+		c.sleepBackoff(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// modelUnavailableCodes are provider error codes for a model that is gone —
+// retired, renamed or never existed — which no retry will fix but another
+// provider might. They are matched as whole identifiers, since some
+// providers (Groq's decommissioned models) report them with a 400.
+var modelUnavailableCodes = regexp.MustCompile(`\b(model_not_found|not_found_error|model_decommissioned|model_deprecated)\b`)
+
+// statusPattern finds the HTTP status in an error's text: "status code N"
+// from our own backends and the batch paths (what the retry classifier keys
+// on), "status code: N" from go-openai and "(status N)" from the xAI
+// backend.
+var statusPattern = regexp.MustCompile(`(?i)\bstatus(?: code)?:? \(?(\d{3})\b`)
+
+// httpStatus returns the HTTP status err carries, or 0 when it has none,
+// e.g. a connection failure. SDK errors expose the status as a field;
+// the rest say it in their text.
+func httpStatus(err error) int {
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) {
+		return openaiErr.HTTPStatusCode
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.HTTPStatusCode
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode
+	}
+	var googleErr genai.APIError
+	if errors.As(err, &googleErr) {
+		return googleErr.Code
+	}
+	if m := statusPattern.FindStringSubmatch(err.Error()); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
+
+// shouldFailover reports whether err means the provider cannot serve the
+// request: rate limits, server errors and network failures that survived
+// every retry, or a missing model (404, 410 or a model-unavailable error
+// code). Other client errors mean the request itself is bad and would fail
+// anywhere. Failures after output started streaming never fail over, since
+// the caller has already shown part of a response; neither do our own
+// per-request timeouts, which mean the model needed more time rather than
+// that the provider is down.
+func shouldFailover(err error) bool {
+	if _, ok := err.(nonRetryable); ok {
+		return false
+	}
+	if strings.Contains(strings.ToLower(err.Error()), "per-request timeout") {
+		return false
+	}
+	if modelUnavailableCodes.MatchString(err.Error()) {
+		return true
+	}
+	switch status := httpStatus(err); {
+	case status == 0:
+		return classifyError(err).Retryable
+	case status == http.StatusNotFound, status == http.StatusGone, status == http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= 500
+	}
+}
+
+// toolCallIDPattern is the strictest tool call ID format among the backends
+// (Anthropic's).
+var toolCallIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// translateHistory adapts a history produced by one backend for another.
+// Messages are provider-neutral except for tool call plumbing: Gemini's
+// thought signatures and Responses API reasoning items mean nothing
+// elsewhere, and Gemini's per-name call IDs repeat within a turn, which
+// Anthropic and OpenAI reject. IDs are made unique and valid and the
+// matching tool results follow them. The input is never modified.
+func translateHistory(messages []tui.ChatMessage, target BackendType) []tui.ChatMessage {
+	out := make([]tui.ChatMessage, len(messages))
+	copy(out, messages)
+
+	used := make(map[string]bool)
+	pending := make(map[string][]string) // original ID -> rewritten IDs awaiting their tool result
+	for i := range out {
+		msg := &out[i]
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]tui.ToolCallInfo, len(msg.ToolCalls))
+			copy(calls, msg.ToolCalls)
+			for j := range calls {
+				if target != BackendTypeGoogle {
+					calls[j].ThoughtSignature = nil
+				}
+				if target != BackendTypeOpenAIResponses {
+					calls[j].ReasoningState = nil
+				}
+				id := calls[j].ID
+				if !toolCallIDPattern.MatchString(id) || used[id] {
+					id = fmt.Sprintf("call_%d_%d", i, j)
+				}
+				used[id] = true
+				pending[calls[j].ID] = append(pending[calls[j].ID], id)
+				calls[j].ID = id
+			}
+			msg.ToolCalls = calls
+		}
+		if msg.Role == "tool" {
+			orig := msg.ToolCallID
+			if ids := pending[orig]; len(ids) > 0 {
+				msg.ToolCallID = ids[0]
+				pending[orig] = ids[1:]
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/sashabaranov/go-openai"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+	"google.golang.org/genai"
+)
+
+// scriptedBackend answers SendMessageSync with err (if set) or content, and
+// records what it was sent.
+type scriptedBackend struct {
+	content string
+	err     error
+	calls   int
+	got     []tui.ChatMessage
+	prompt  string
+}
+
+func (b *scriptedBackend) SendMessageStream(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamCallback) error {
+	return b.err
+}
+
+func (b *scriptedBackend) SendMessageStreamEvents(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition, callback StreamEventCallback) error {
+	b.calls++
+	if b.err != nil {
+		return b.err
+	}
+	callback(StreamEvent{Type: EventContentDelta, ContentDelta: "partial"})
+	return errors.New("status code 503: connection dropped mid-stream")
+}
+
+func (b *scriptedBackend) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
+	b.calls++
+	b.got = messages
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &ChatCompletionResult{Content: b.content}, nil
+}
+
+func (b *scriptedBackend) SetSystemPrompt(prompt string)           { b.prompt = prompt }
+func (b *scriptedBackend) SetThinkingConfig(config ThinkingConfig) {}
+func (b *scriptedBackend) Close() error                            { return nil }
+
+func newChainClient(primary *scriptedBackend, fallbacks ...*scriptedBackend) *Client {
+	c := &Client{
+		backend:      primary,
+		config:       &Config{BaseURL: "https://api.anthropic.com/v1", Model: "primary-model"},
+		backendType:  BackendTypeAnthropic,
+		sleepBackoff: func(time.Duration) {},
+	}
+	for i, fb := range fallbacks {
+		c.fallbacks = append(c.fallbacks, &chainLink{
+			name:        "fallback" + string(rune('A'+i)),
+			config:      &Config{BaseURL: "https://api.openai.com/v1", Model: "fallback-model"},
+			backend:     fb,
+			backendType: BackendTypeOpenAI,
+		})
+	}
+	return c
+}
+
+func TestFailoverAfterRetriesExhausted(t *testing.T) {
+	primary := &scriptedBackend{err: errors.New("status code 503: service unavailable")}
+	fallback := &scriptedBackend{content: "ok"}
+	c := newChainClient(primary, fallback)
+	c.SetSystemPrompt("persona")
+
+	res, err := c.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Content != "ok" {
+		t.Fatalf("content = %q, want ok", res.Content)
+	}
+	if primary.calls != 1+maxAttempts(errorClass{Retryable: true, Kind: kindServer}) {
+		t.Errorf("primary calls = %d, want retries exhausted first", primary.calls)
+	}
+	served := c.LastServed()
+	if !served.Fallback || served.Provider != "fallbackA" || served.Model != "fallback-model" {
+		t.Errorf("served = %+v", served)
+	}
+	if fallback.prompt != "persona" {
+		t.Errorf("fallback system prompt = %q", fallback.prompt)
+	}
+
+	// The primary is cooling down, so the next request goes straight to the
+	// fallback.
+	primary.calls = 0
+	if _, err := c.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "again"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls != 0 {
+		t.Errorf("primary retried during cooldown: %d calls", primary.calls)
+	}
+}
+
+// downBackend is a primary that is always unavailable. Unlike
+// scriptedBackend it keeps no state, so parallel requests can share it.
+type downBackend struct{ scriptedBackend }
+
+func (*downBackend) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
+	return nil, errors.New("status code 503: service unavailable")
+}
+
+func TestParallelFailoverSharesOneFallbackBackend(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/chat" {
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"ok"},"done":true,"done_reason":"stop"}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	t.Cleanup(srv.Close)
+	providers.MarkOllamaURL(srv.URL)
+
+	c := newChainClient(nil)
+	c.backend = &downBackend{}
+	c.fallbacks = newFallbackChain(&Config{Fallbacks: []FallbackTarget{{Name: "local", BaseURL: srv.URL, Model: "llama3.1:8b"}}})
+	link := c.fallbacks[0]
+	if link.backend != nil {
+		t.Fatal("fallback backend created before first use")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Go(func() {
+			res, err := c.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil)
+			if err == nil && res.Content != "ok" {
+				err = fmt.Errorf("content = %q", res.Content)
+			}
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if _, ok := link.backend.(*OllamaBackend); !ok || link.backendType != BackendTypeOllama {
+		t.Errorf("fallback backend = %T (%v)", link.backend, link.backendType)
+	}
+}
+
+func TestFailoverOnModelNotFound(t *testing.T) {
+	primary := &scriptedBackend{err: errors.New(`{"type":"not_found_error","message":"model: claude-2 does not exist"}`)}
+	fallback := &scriptedBackend{content: "ok"}
+	c := newChainClient(primary, fallback)
+
+	if _, err := c.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls != 1 {
+		t.Errorf("model-not-found should not be retried, got %d calls", primary.calls)
+	}
+}
+
+func TestShouldFailover(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want bool
+	}{
+		{&openai.APIError{HTTPStatusCode: 404, Message: "The model `gpt-3` does not exist"}, true},
+		{&anthropic.Error{StatusCode: 404, Request: httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil), Response: &http.Response{StatusCode: 404}}, true},
+		{fmt.Errorf("Google AI request failed: %w", genai.APIError{Code: 404, Status: "NOT_FOUND"}), true},
+		{errors.New("ollama: status code 404: model 'llama9' not found"), true},
+		{errors.New(`xAI API error (status 410): {"error":"gone"}`), true},
+		{errors.New(`status code 400: {"error":{"code":"model_decommissioned"}}`), true},
+		{errors.New("status code 429: too many requests"), true},
+		{errors.New("openai responses: status code 503: overloaded"), true},
+		{errors.New("read tcp: connection reset by peer"), true},
+
+		// Words and numbers in an error body say nothing about the model.
+		{errors.New("status code 400: tool call_404abc does not exist in this conversation"), false},
+		{errors.New("status code 400: 'functions' is deprecated, use 'tools'"), false},
+		{errors.New("status code 400: prompt is 4049 tokens, the limit is 4000"), false},
+		{&openai.APIError{HTTPStatusCode: 400, Message: "file-404 does not exist"}, false},
+		{errors.New("status code 401: invalid api key"), false},
+		{fatalErr(errors.New("status code 503: stream dropped")), false},
+	} {
+		if got := shouldFailover(c.err); got != c.want {
+			t.Errorf("shouldFailover(%q) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestNoFailoverOnFatalError(t *testing.T) {
+	primary := &scriptedBackend{err: errors.New("status code 400: invalid tool schema")}
+	fallback := &scriptedBackend{content: "ok"}
+	c := newChainClient(primary, fallback)
+
+	if _, err := c.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil); err == nil {
+		t.Fatal("expected the request error to surface")
+	}
+	if fallback.calls != 0 {
+		t.Error("a bad request must not be replayed against the fallback")
+	}
+}
+
+func TestNoFailoverAfterStreamStarted(t *testing.T) {
+	primary := &scriptedBackend{} // streams a delta, then fails
+	fallback := &scriptedBackend{}
+	c := newChainClient(primary, fallback)
+
+	err := c.SendMessageStreamEvents(context.Background(), nil, nil, func(StreamEvent) {})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if fallback.calls != 0 {
+		t.Error("fallback must not run once output has streamed")
+	}
+}
+
+func TestFailoverChainExhausted(t *testing.T) {
+	primary := &scriptedBackend{err: errors.New("status code 502")}
+	fallback := &scriptedBackend{err: errors.New("connection reset by peer")}
+	c := newChainClient(primary, fallback)
+
+	_, err := c.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "hi"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "all providers in the fallback chain failed") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestTranslateHistoryForNonGoogleBackend(t *testing.T) {
+	history := []tui.ChatMessage{
+		{Role: "user", Content: "read both"},
+		{Role: "assistant", ToolCalls: []tui.ToolCallInfo{
+			{ID: "call_read_file", Name: "read_file", ThoughtSignature: []byte("sig")},
+			{ID: "call_read_file", Name: "read_file", ThoughtSignature: []byte("sig")},
+		}},
+		{Role: "tool", ToolCallID: "call_read_file", Content: "a"},
+		{Role: "tool", ToolCallID: "call_read_file", Content: "b"},
+	}
+
+	out := translateHistory(history, BackendTypeAnthropic)
+	calls := out[1].ToolCalls
+	if calls[0].ID == calls[1].ID {
+		t.Fatalf("duplicate tool call IDs survived: %q", calls[0].ID)
+	}
+	if out[2].ToolCallID != calls[0].ID || out[3].ToolCallID != calls[1].ID {
+		t.Errorf("tool results not re-linked: %q %q", out[2].ToolCallID, out[3].ToolCallID)
+	}
+	if calls[0].ThoughtSignature != nil {
+		t.Error("thought signature should be dropped for non-Google backends")
+	}
+	if history[1].ToolCalls[1].ID != "call_read_file" || history[1].ToolCalls[0].ThoughtSignature == nil {
+		t.Error("input history was modified")
+	}
+}
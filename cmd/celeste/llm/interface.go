@@ -4,6 +4,7 @@ package llm
 import (
 	"context"
 
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
 )
 
@@ -58,6 +59,13 @@ const (
 
 	// BackendTypeAnthropic uses the native Anthropic SDK (Claude models)
 	BackendTypeAnthropic BackendType = "anthropic"
+
+	// BackendTypeOllama speaks Ollama's native /api/chat protocol
+	BackendTypeOllama BackendType = "ollama"
+
+	// BackendTypeOpenAIResponses speaks OpenAI's /v1/responses protocol, used
+	// for the models a provider lists in ResponsesAPIModels
+	BackendTypeOpenAIResponses BackendType = "openai-responses"
 )
 
 // DetectBackendType determines which backend to use based on the base URL.
@@ -71,9 +79,27 @@ func DetectBackendType(baseURL string) BackendType {
 	if isAnthropicProvider(baseURL) {
 		return BackendTypeAnthropic
 	}
+	if providers.IsOllamaURL(baseURL) {
+		return BackendTypeOllama
+	}
 	return BackendTypeOpenAI
 }
 
+// SelectBackendType refines DetectBackendType with the model: an
+// OpenAI-compatible provider that serves config.Model through its Responses
+// API gets the Responses backend.
+func SelectBackendType(config *Config) BackendType {
+	backendType := DetectBackendType(config.BaseURL)
+	if backendType != BackendTypeOpenAI {
+		return backendType
+	}
+	caps, ok := providers.GetProvider(providers.DetectProvider(config.BaseURL))
+	if ok && caps.UsesResponsesAPI(config.Model) {
+		return BackendTypeOpenAIResponses
+	}
+	return backendType
+}
+
 // isAnthropicProvider checks if a base URL belongs to Anthropic.
 func isAnthropicProvider(baseURL string) bool {
 	if baseURL == "" {
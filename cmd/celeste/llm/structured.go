@@ -0,0 +1,210 @@
+// Package llm provides the LLM client for Celeste CLI.
+// This file implements structured output: responses constrained to a JSON
+// schema, enforced natively where the provider supports it and by a
+// validate-and-repair loop everywhere.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/schema"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// ResponseFormat asks for a response that is a single JSON value conforming
+// to Schema. The result's Content is that value, compacted, with any prose or
+// code fences around it removed.
+type ResponseFormat struct {
+	// Name identifies the format to providers that require one
+	// (letters, digits, _ and -).
+	Name   string
+	Schema map[string]any
+}
+
+// SendOption adjusts a single SendMessageSync/SendMessageStreamEvents call.
+type SendOption func(*sendOptions)
+
+type sendOptions struct {
+	format *ResponseFormat
+}
+
+// WithResponseFormat requests a response conforming to format. A response
+// that calls tools or does not validate is sent back to the model with the
+// validation error, up to maxStructuredRepairs times; if it still does not
+// conform the call returns a *StructuredOutputError carrying the last reply.
+// With SendMessageStreamEvents the content is delivered as one delta once it
+// has validated, not as it streams.
+func WithResponseFormat(format *ResponseFormat) SendOption {
+	return func(o *sendOptions) { o.format = format }
+}
+
+func collectSendOptions(opts []SendOption) sendOptions {
+	var o sendOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// maxStructuredRepairs bounds the repair requests after the first attempt.
+const maxStructuredRepairs = 2
+
+// StructuredOutputError is returned when no reply conformed to the requested
+// ResponseFormat. Raw is the last reply, for callers with a text fallback.
+type StructuredOutputError struct {
+	Raw      string
+	Attempts int
+	Err      error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("structured output invalid after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error { return e.Err }
+
+// The format reaches backends through the request context so LLMBackend's
+// signatures stay the same for every implementation.
+type responseFormatKey struct{}
+
+// nativeResponseFormat returns the request's ResponseFormat when the provider
+// at baseURL enforces schemas itself, or nil.
+func nativeResponseFormat(ctx context.Context, baseURL string) *ResponseFormat {
+	f, _ := ctx.Value(responseFormatKey{}).(*ResponseFormat)
+	if f == nil {
+		return nil
+	}
+	caps, ok := providers.GetProvider(providers.DetectProvider(baseURL))
+	if !ok || !caps.StructuredOutput {
+		return nil
+	}
+	return f
+}
+
+// jsonSchema adapts a schema map to APIs that take a json.Marshaler.
+type jsonSchema map[string]any
+
+func (s jsonSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any(s))
+}
+
+// formatName is f.Name, or a placeholder for providers that require a name.
+func (f *ResponseFormat) formatName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return "response"
+}
+
+// sendStructured runs send until the reply validates against format.
+func sendStructured(ctx context.Context, messages []tui.ChatMessage, format *ResponseFormat,
+	send func(context.Context, []tui.ChatMessage) (*ChatCompletionResult, error)) (*ChatCompletionResult, error) {
+	ctx = context.WithValue(ctx, responseFormatKey{}, format)
+	msgs := withFormatInstruction(messages, format)
+
+	var usage *TokenUsage
+	for attempt := 0; ; attempt++ {
+		res, err := send(ctx, msgs)
+		if err != nil {
+			return nil, err
+		}
+		if res.Usage != nil {
+			if usage == nil {
+				usage = &TokenUsage{}
+			}
+			usage.PromptTokens += res.Usage.PromptTokens
+			usage.CompletionTokens += res.Usage.CompletionTokens
+			usage.TotalTokens += res.Usage.TotalTokens
+			usage.CachedTokens += res.Usage.CachedTokens
+			usage.CacheWriteTokens += res.Usage.CacheWriteTokens
+		}
+		res.Usage = usage
+
+		value, verr := decodeStructured(res, format)
+		if verr == nil {
+			res.Content = value
+			return res, nil
+		}
+		if attempt == maxStructuredRepairs {
+			return res, &StructuredOutputError{Raw: res.Content, Attempts: attempt + 1, Err: verr}
+		}
+		tui.LogInfo(fmt.Sprintf("structured output %q invalid (%v); asking for a repair", format.formatName(), verr))
+		msgs = append(msgs[:len(msgs):len(msgs)],
+			tui.ChatMessage{Role: "assistant", Content: res.Content},
+			tui.ChatMessage{Role: "user", Content: fmt.Sprintf(
+				"That reply is not valid: %v\n\nReply again with only the corrected JSON value — no prose, no code fences, no tool calls.", verr)},
+		)
+	}
+}
+
+// withFormatInstruction states the schema in the prompt. Providers with a
+// native mode do not need it, but it costs little and it is all the others
+// get. The caller's slice is not modified.
+func withFormatInstruction(messages []tui.ChatMessage, format *ResponseFormat) []tui.ChatMessage {
+	schemaJSON, _ := json.MarshalIndent(format.Schema, "", "  ")
+	instruction := "Respond with only a JSON value — no prose, no code fences — that conforms to this JSON Schema:\n" + string(schemaJSON)
+
+	out := make([]tui.ChatMessage, len(messages), len(messages)+1)
+	copy(out, messages)
+	if n := len(out); n > 0 && out[n-1].Role == "user" {
+		out[n-1].Content = strings.TrimRight(out[n-1].Content, "\n") + "\n\n" + instruction
+		return out
+	}
+	return append(out, tui.ChatMessage{Role: "user", Content: instruction})
+}
+
+// decodeStructured extracts the JSON value from a reply and validates it.
+func decodeStructured(res *ChatCompletionResult, format *ResponseFormat) (string, error) {
+	if len(res.ToolCalls) > 0 {
+		return "", errors.New("the reply called tools instead of answering")
+	}
+	raw, err := extractJSON(res.Content)
+	if err != nil {
+		return "", err
+	}
+	if err := schema.ValidateJSON(format.Schema, raw); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// extractJSON finds the JSON value in a reply: the whole reply, the inside of
+// a ``` fence, or the first object or array embedded in prose.
+func extractJSON(content string) ([]byte, error) {
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return nil, errors.New("the reply was empty")
+	}
+	if json.Valid([]byte(text)) {
+		return []byte(text), nil
+	}
+	if start := strings.Index(text, "```"); start >= 0 {
+		body := text[start+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:] // drop the language tag line
+		}
+		if end := strings.Index(body, "```"); end >= 0 {
+			if inner := strings.TrimSpace(body[:end]); json.Valid([]byte(inner)) {
+				return []byte(inner), nil
+			}
+		}
+	}
+	if i := strings.IndexAny(text, "{["); i >= 0 {
+		dec := json.NewDecoder(strings.NewReader(text[i:]))
+		var v json.RawMessage
+		if dec.Decode(&v) == nil {
+			return v, nil
+		}
+	}
+	return nil, errors.New("the reply does not contain a JSON value")
+}
@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// queuedBackend replies to SendMessageSync with replies in order and records
+// each request's messages and the ResponseFormat its backend would enforce.
+type queuedBackend struct {
+	scriptedBackend
+	replies []string
+	sent    [][]tui.ChatMessage
+	native  []*ResponseFormat
+}
+
+func (b *queuedBackend) SendMessageSync(ctx context.Context, messages []tui.ChatMessage, tools []tui.SkillDefinition) (*ChatCompletionResult, error) {
+	b.sent = append(b.sent, messages)
+	b.native = append(b.native, nativeResponseFormat(ctx, "https://api.anthropic.com/v1"))
+	reply := b.replies[0]
+	b.replies = b.replies[1:]
+	return &ChatCompletionResult{Content: reply, Usage: &TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}}, nil
+}
+
+var verdictFormat = &ResponseFormat{
+	Name: "verdict",
+	Schema: map[string]any{
+		"type":     "object",
+		"required": []string{"approved"},
+		"properties": map[string]any{
+			"approved": map[string]any{"type": "boolean"},
+		},
+	},
+}
+
+func TestStructuredRepairsInvalidReply(t *testing.T) {
+	backend := &queuedBackend{replies: []string{`{"approved": "yes"}`, "Sure!\n```json\n{\"approved\": true}\n```"}}
+	c := newChainClient(&scriptedBackend{})
+	c.backend = backend
+
+	res, err := c.SendMessageSync(context.Background(), []tui.ChatMessage{{Role: "user", Content: "review it"}}, nil, WithResponseFormat(verdictFormat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != `{"approved":true}` {
+		t.Errorf("content = %q", res.Content)
+	}
+	if res.Usage.TotalTokens != 24 {
+		t.Errorf("usage should cover both attempts, got %+v", res.Usage)
+	}
+	if len(backend.sent) != 2 {
+		t.Fatalf("requests = %d, want 2", len(backend.sent))
+	}
+	first := backend.sent[0]
+	if len(first) != 1 || !strings.Contains(first[0].Content, "JSON Schema") {
+		t.Errorf("schema instruction missing from %+v", first)
+	}
+	repair := backend.sent[1][len(backend.sent[1])-1]
+	if !strings.Contains(repair.Content, "$.approved: expected boolean") {
+		t.Errorf("repair prompt = %q", repair.Content)
+	}
+	if backend.native[0] != verdictFormat {
+		t.Error("format did not reach the backend")
+	}
+}
+
+func TestStructuredGivesUpWithRawReply(t *testing.T) {
+	backend := &queuedBackend{replies: []string{"no", "still no", "never"}}
+	c := newChainClient(&scriptedBackend{})
+	c.backend = backend
+
+	var events []StreamEvent
+	err := c.SendMessageStreamEvents(context.Background(), []tui.ChatMessage{{Role: "user", Content: "review it"}}, nil,
+		func(e StreamEvent) { events = append(events, e) }, WithResponseFormat(verdictFormat))
+	var serr *StructuredOutputError
+	if !errors.As(err, &serr) {
+		t.Fatalf("err = %v, want StructuredOutputError", err)
+	}
+	if serr.Raw != "never" || serr.Attempts != 1+maxStructuredRepairs {
+		t.Errorf("error = %+v", serr)
+	}
+	if len(events) != 2 || events[0].ContentDelta != "never" || events[1].Type != EventMessageDone {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestNativeResponseFormatNeedsCapableProvider(t *testing.T) {
+	ctx := context.WithValue(context.Background(), responseFormatKey{}, verdictFormat)
+	if nativeResponseFormat(ctx, "https://api.openai.com/v1") != verdictFormat {
+		t.Error("openai should enforce the schema natively")
+	}
+	if nativeResponseFormat(ctx, "http://127.0.0.1:8080/v1") != nil {
+		t.Error("a generic local server should rely on validation")
+	}
+	if nativeResponseFormat(context.Background(), "https://api.openai.com/v1") != nil {
+		t.Error("no format requested")
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{`{"a":1}`, `{"a":1}`},
+		{"```json\n[1, 2]\n```", `[1, 2]`},
+		{`Here you go: {"a": {"b": 2}} hope that helps`, `{"a": {"b": 2}}`},
+	} {
+		got, err := extractJSON(tc.in)
+		if err != nil || string(got) != tc.want {
+			t.Errorf("extractJSON(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+	if _, err := extractJSON("no json here"); err == nil {
+		t.Error("expected an error for a reply without JSON")
+	}
+}
@@ -0,0 +1,440 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+const (
+	// initializeTimeout bounds a server's startup handshake.
+	initializeTimeout = 30 * time.Second
+	// shutdownTimeout bounds each step of a graceful shutdown before the
+	// process is killed.
+	shutdownTimeout = 2 * time.Second
+	// stderrTail is how much of a server's stderr is kept for errors.
+	stderrTail = 4 * 1024
+)
+
+// Client is a running language server for one workspace.
+type Client struct {
+	cfg     ServerConfig
+	root    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	conn    *conn
+	stderr  *tailBuffer
+	exited  chan struct{}
+	cleanup func()
+
+	syncMu sync.Mutex // orders didOpen/didChange per client
+	mu     sync.Mutex
+	docs   map[string]*document   // open documents by URI
+	diags  map[string]*diagnostic // latest publish by URI
+	seq    uint64                 // publish counter
+	// changed is closed and replaced on every publish.
+	changed chan struct{}
+
+	closeOnce sync.Once
+}
+
+type document struct {
+	version int
+	text    string
+}
+
+type diagnostic struct {
+	version    int
+	hasVersion bool
+	seq        uint64
+	items      []Diagnostic
+}
+
+// Start launches the server for cfg in root, confined by policy, and
+// completes the initialize handshake.
+func Start(ctx context.Context, cfg ServerConfig, root string, policy sandbox.Policy) (*Client, error) {
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("%s language server has no command", cfg.Name)
+	}
+	cmd := exec.Command(cfg.Command[0], cfg.Command[1:]...)
+	cmd.Dir = root
+	cmd.WaitDelay = shutdownTimeout
+	cleanup, err := policy.Wrap(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		cfg:     cfg,
+		root:    root,
+		cmd:     cmd,
+		stderr:  &tailBuffer{max: stderrTail},
+		exited:  make(chan struct{}),
+		cleanup: cleanup,
+		docs:    map[string]*document{},
+		diags:   map[string]*diagnostic{},
+		changed: make(chan struct{}),
+	}
+	cmd.Stderr = c.stderr
+	if c.stdin, err = cmd.StdinPipe(); err != nil {
+		cleanup()
+		return nil, err
+	}
+	// stdout is a plain pipe rather than StdoutPipe so that Wait, which
+	// runs as soon as the process exits, cannot race the reader.
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	cmd.Stdout = stdoutW
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		stdoutW.Close()
+		cleanup()
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s language server %q is not installed or not on PATH", cfg.Name, cfg.Command[0])
+		}
+		return nil, fmt.Errorf("starting %s language server: %w", cfg.Name, err)
+	}
+	stdoutW.Close()
+	go func() {
+		_ = cmd.Wait()
+		close(c.exited)
+	}()
+	c.conn = newConn(stdout, c.stdin, c.handle)
+
+	initCtx, cancel := context.WithTimeout(ctx, initializeTimeout)
+	defer cancel()
+	if err := c.initialize(initCtx); err != nil {
+		c.Close()
+		if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+			err = fmt.Errorf("%w\n%s", err, tail)
+		}
+		return nil, fmt.Errorf("initializing %s language server: %w", cfg.Name, err)
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	rootURI := PathToURI(c.root)
+	params := map[string]any{
+		"processId":  os.Getpid(),
+		"clientInfo": map[string]any{"name": "celeste"},
+		"rootUri":    rootURI,
+		"rootPath":   c.root,
+		"workspaceFolders": []map[string]any{
+			{"uri": rootURI, "name": filepath.Base(c.root)},
+		},
+		"capabilities": map[string]any{
+			"workspace": map[string]any{
+				"configuration":    true,
+				"workspaceFolders": true,
+				"workspaceEdit":    map[string]any{"documentChanges": true},
+			},
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": true},
+				"publishDiagnostics": map[string]any{"versionSupport": true},
+				"hover":              map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
+				"definition":         map[string]any{"linkSupport": true},
+				"rename":             map[string]any{"prepareSupport": false},
+			},
+			"window": map[string]any{"workDoneProgress": true},
+		},
+	}
+	if err := c.conn.Call(ctx, "initialize", params, nil); err != nil {
+		return err
+	}
+	return c.conn.Notify("initialized", map[string]any{})
+}
+
+// handle answers the server's requests and records its diagnostics.
+func (c *Client) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p publishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err == nil {
+			c.publish(p)
+		}
+		return nil, nil
+	case "workspace/configuration":
+		// No settings: every item gets the server's defaults.
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "workspace/workspaceFolders":
+		return []map[string]any{{"uri": PathToURI(c.root), "name": filepath.Base(c.root)}}, nil
+	case "workspace/applyEdit":
+		// Edits go through celeste's own tools so they are snapshotted.
+		return map[string]any{"applied": false, "failureReason": "client applies edits itself"}, nil
+	case "client/registerCapability", "client/unregisterCapability",
+		"window/workDoneProgress/create", "window/showMessageRequest",
+		"window/logMessage", "window/showMessage", "$/progress", "telemetry/event":
+		return nil, nil
+	}
+	return nil, &RPCError{Code: codeMethodNotFound, Message: "method not supported: " + method}
+}
+
+func (c *Client) publish(p publishDiagnosticsParams) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	d := &diagnostic{seq: c.seq, items: p.Diagnostics}
+	if p.Version != nil {
+		d.version, d.hasVersion = *p.Version, true
+	}
+	c.diags[p.URI] = d
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Alive reports whether the server process is still running.
+func (c *Client) Alive() bool {
+	select {
+	case <-c.exited:
+		return false
+	case <-c.conn.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Sync makes the server's copy of path match text, opening the document
+// if needed, and tells it the file was saved. It returns the document's
+// version, the publish counter before the change (for WaitDiagnostics),
+// and whether anything changed.
+func (c *Client) Sync(path, text string) (version int, since uint64, changed bool, err error) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	uri := PathToURI(path)
+	c.mu.Lock()
+	since = c.seq
+	doc, open := c.docs[uri]
+	if open && doc.text == text {
+		c.mu.Unlock()
+		return doc.version, since, false, nil
+	}
+	if !open {
+		doc = &document{}
+		c.docs[uri] = doc
+	}
+	doc.version++
+	doc.text = text
+	version = doc.version
+	c.mu.Unlock()
+
+	if !open {
+		err = c.conn.Notify("textDocument/didOpen", map[string]any{
+			"textDocument": textDocumentItem{URI: uri, LanguageID: c.cfg.languageID(path), Version: version, Text: text},
+		})
+	} else {
+		err = c.conn.Notify("textDocument/didChange", map[string]any{
+			"textDocument":   versionedTextDocumentIdentifier{URI: uri, Version: &version},
+			"contentChanges": []map[string]any{{"text": text}},
+		})
+	}
+	if err != nil {
+		return version, since, true, err
+	}
+	err = c.conn.Notify("textDocument/didSave", map[string]any{"textDocument": textDocumentIdentifier{URI: uri}})
+	return version, since, true, err
+}
+
+// Forget closes path's document, e.g. after the file was deleted.
+func (c *Client) Forget(path string) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	uri := PathToURI(path)
+	c.mu.Lock()
+	_, open := c.docs[uri]
+	delete(c.docs, uri)
+	delete(c.diags, uri)
+	c.mu.Unlock()
+	if open {
+		_ = c.conn.Notify("textDocument/didClose", map[string]any{"textDocument": textDocumentIdentifier{URI: uri}})
+	}
+}
+
+// Diagnostics returns the latest diagnostics published for every document.
+func (c *Client) Diagnostics() map[string][]Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string][]Diagnostic, len(c.diags))
+	for uri, d := range c.diags {
+		out[uri] = d.items
+	}
+	return out
+}
+
+// WaitDiagnostics waits until the server publishes diagnostics for path
+// at version or later (or, for servers that don't send versions, any
+// publish after since), then until settle passes with no further publish
+// for path, since servers often publish syntax errors before type errors.
+// It returns the latest diagnostics and false if ctx ended first.
+func (c *Client) WaitDiagnostics(ctx context.Context, path string, version int, since uint64, settle time.Duration) ([]Diagnostic, bool) {
+	uri := PathToURI(path)
+	var (
+		quiet    <-chan time.Time
+		lastSeq  uint64
+		received bool
+	)
+	for {
+		c.mu.Lock()
+		d := c.diags[uri]
+		changed := c.changed
+		var items []Diagnostic
+		if d != nil && d.seq > since && (!d.hasVersion || d.version >= version) {
+			received = true
+			items = d.items
+			if d.seq != lastSeq {
+				lastSeq = d.seq
+				quiet = time.After(settle)
+			}
+		} else if d != nil {
+			items = d.items
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-quiet:
+			return items, true
+		case <-ctx.Done():
+			return items, received
+		case <-c.conn.done:
+			return items, received
+		}
+	}
+}
+
+// Hover returns the hover text at pos in path.
+func (c *Client) Hover(ctx context.Context, path string, pos Position) (string, error) {
+	var result *struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	err := c.conn.Call(ctx, "textDocument/hover", textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: PathToURI(path)},
+		Position:     pos,
+	}, &result)
+	if err != nil || result == nil {
+		return "", err
+	}
+	return strings.TrimSpace(hoverText(result.Contents)), nil
+}
+
+// Definition returns where the symbol at pos in path is defined.
+func (c *Client) Definition(ctx context.Context, path string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	err := c.conn.Call(ctx, "textDocument/definition", textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: PathToURI(path)},
+		Position:     pos,
+	}, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return parseLocations(raw)
+}
+
+// parseLocations accepts Location, Location[] and LocationLink[].
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "{") {
+		raw = json.RawMessage("[" + string(raw) + "]")
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	locs := make([]Location, 0, len(items))
+	for _, item := range items {
+		var link locationLink
+		if json.Unmarshal(item, &link) == nil && link.TargetURI != "" {
+			locs = append(locs, Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+			continue
+		}
+		var loc Location
+		if err := json.Unmarshal(item, &loc); err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// Rename asks the server for the edits that rename the symbol at pos in
+// path to newName. Nothing is applied.
+func (c *Client) Rename(ctx context.Context, path string, pos Position, newName string) (*WorkspaceEdit, error) {
+	var edit *WorkspaceEdit
+	err := c.conn.Call(ctx, "textDocument/rename", map[string]any{
+		"textDocument": textDocumentIdentifier{URI: PathToURI(path)},
+		"position":     pos,
+		"newName":      newName,
+	}, &edit)
+	if err != nil {
+		return nil, err
+	}
+	if edit == nil {
+		return nil, fmt.Errorf("%s language server found nothing to rename there", c.cfg.Name)
+	}
+	return edit, nil
+}
+
+// Close shuts the server down, killing it if it doesn't exit promptly.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		if c.Alive() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			if c.conn.Call(ctx, "shutdown", nil, nil) == nil {
+				_ = c.conn.Notify("exit", nil)
+			}
+			cancel()
+		}
+		_ = c.stdin.Close()
+		select {
+		case <-c.exited:
+		case <-time.After(shutdownTimeout):
+			_ = c.cmd.Process.Kill()
+			<-c.exited
+		}
+		c.cleanup()
+	})
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
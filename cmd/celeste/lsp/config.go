@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+)
+
+// ServerConfig says how to launch a language server and which files it
+// handles.
+type ServerConfig struct {
+	Name       string   // language name, used in status and errors
+	Command    []string // argv; the server speaks LSP on stdin/stdout
+	Extensions []string // lowercase, with the dot
+	// LanguageIDs maps extensions to the languageId sent in didOpen.
+	// Extensions missing from it use Name.
+	LanguageIDs map[string]string
+}
+
+// languageID returns the languageId for path.
+func (c ServerConfig) languageID(path string) string {
+	if id, ok := c.LanguageIDs[strings.ToLower(filepath.Ext(path))]; ok {
+		return id
+	}
+	return c.Name
+}
+
+// handles reports whether path has one of the config's extensions.
+func (c ServerConfig) handles(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range c.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownServers are the languages a .grimoire can name without giving a
+// command.
+var KnownServers = map[string]ServerConfig{
+	"go": {
+		Name:       "go",
+		Command:    []string{"gopls"},
+		Extensions: []string{".go"},
+	},
+	"python": {
+		Name:       "python",
+		Command:    []string{"pyright-langserver", "--stdio"},
+		Extensions: []string{".py", ".pyi"},
+	},
+	"typescript": {
+		Name:       "typescript",
+		Command:    []string{"typescript-language-server", "--stdio"},
+		Extensions: []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"},
+		LanguageIDs: map[string]string{
+			".tsx": "typescriptreact",
+			".js":  "javascript",
+			".mjs": "javascript",
+			".cjs": "javascript",
+			".jsx": "javascriptreact",
+		},
+	},
+	"rust": {
+		Name:       "rust",
+		Command:    []string{"rust-analyzer"},
+		Extensions: []string{".rs"},
+	},
+}
+
+// languageAliases maps other names people write to KnownServers keys.
+var languageAliases = map[string]string{
+	"golang":        "go",
+	"gopls":         "go",
+	"py":            "python",
+	"pyright":       "python",
+	"ts":            "typescript",
+	"tsserver":      "typescript",
+	"javascript":    "typescript",
+	"js":            "typescript",
+	"rust-analyzer": "rust",
+	"rs":            "rust",
+}
+
+// ParseConfigs turns .grimoire Language Servers entries into server configs.
+// An entry's language is a known language name or a list of extensions
+// (".lua .luau"); its command defaults to the known server. A command of
+// "off" disables the language. Later entries for the same language replace
+// earlier ones, so a project .grimoire overrides a global one.
+func ParseConfigs(entries []grimoire.LanguageServerEntry) ([]ServerConfig, error) {
+	var configs []ServerConfig
+	index := map[string]int{}
+	for _, entry := range entries {
+		cfg, err := parseEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[cfg.Name]; ok {
+			configs[i] = cfg
+			continue
+		}
+		index[cfg.Name] = len(configs)
+		configs = append(configs, cfg)
+	}
+
+	enabled := configs[:0]
+	for _, cfg := range configs {
+		if len(cfg.Command) > 0 {
+			enabled = append(enabled, cfg)
+		}
+	}
+	return enabled, nil
+}
+
+// ConfigsFromGrimoire is ParseConfigs for a loaded grimoire, which may be nil.
+func ConfigsFromGrimoire(g *grimoire.Grimoire) ([]ServerConfig, error) {
+	if g == nil {
+		return nil, nil
+	}
+	return ParseConfigs(g.LanguageServers)
+}
+
+func parseEntry(entry grimoire.LanguageServerEntry) (ServerConfig, error) {
+	language := strings.ToLower(strings.TrimSpace(entry.Language))
+	command := strings.Fields(entry.Command)
+
+	var cfg ServerConfig
+	if strings.HasPrefix(language, ".") {
+		cfg = ServerConfig{Name: language}
+		for _, ext := range strings.FieldsFunc(language, func(r rune) bool { return r == ' ' || r == ',' }) {
+			if !strings.HasPrefix(ext, ".") {
+				return ServerConfig{}, fmt.Errorf("language server %q: %q is not an extension", entry.Language, ext)
+			}
+			cfg.Extensions = append(cfg.Extensions, ext)
+		}
+		if len(command) == 0 {
+			return ServerConfig{}, fmt.Errorf("language server %q needs a command", entry.Language)
+		}
+	} else {
+		if alias, ok := languageAliases[language]; ok {
+			language = alias
+		}
+		known, ok := KnownServers[language]
+		if !ok {
+			return ServerConfig{}, fmt.Errorf("unknown language %q for language server (use go, python, typescript, rust or a list of extensions like .lua)", entry.Language)
+		}
+		cfg = known
+	}
+
+	switch {
+	case len(command) == 1 && strings.EqualFold(command[0], "off"):
+		cfg.Command = nil
+	case len(command) > 0:
+		cfg.Command = command
+	}
+	return cfg, nil
+}
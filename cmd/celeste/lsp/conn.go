@@ -0,0 +1,236 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes used here.
+const (
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+// message is any JSON-RPC 2.0 message: a request has an id and method, a
+// notification a method only, a response an id and result or error.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is an error response from the other side.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// errConnClosed is returned for calls pending or made after the connection
+// ended.
+var errConnClosed = errors.New("language server connection closed")
+
+// handler answers requests and receives notifications from the other side.
+// For a notification the result is ignored.
+type handler func(method string, params json.RawMessage) (any, error)
+
+// conn speaks LSP's Content-Length framed JSON-RPC over a byte stream.
+type conn struct {
+	w       io.Writer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *message
+	err     error // set once the read loop ends
+
+	done chan struct{}
+}
+
+// newConn starts reading r and dispatching to h until r ends.
+func newConn(r io.Reader, w io.Writer, h handler) *conn {
+	c := &conn{w: w, pending: map[string]chan *message{}, done: make(chan struct{})}
+	go c.readLoop(bufio.NewReader(r), h)
+	return c
+}
+
+// Call sends a request and decodes the response's result into result,
+// which may be nil.
+func (c *conn) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := strconv.FormatInt(c.nextID, 10)
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.send(&message{ID: json.RawMessage(id), Method: method}, params); err != nil {
+		c.forget(id)
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp == nil {
+			return c.closedErr()
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		c.forget(id)
+		// Tell the server to stop working on it; servers may ignore this.
+		_ = c.Notify("$/cancelRequest", map[string]any{"id": json.RawMessage(id)})
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification.
+func (c *conn) Notify(method string, params any) error {
+	return c.send(&message{Method: method}, params)
+}
+
+func (c *conn) forget(id string) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *conn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return errConnClosed
+}
+
+func (c *conn) send(msg *message, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	return c.write(msg)
+}
+
+func (c *conn) write(msg *message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+func (c *conn) readLoop(r *bufio.Reader, h handler) {
+	var err error
+	for {
+		var msg *message
+		if msg, err = readMessage(r); err != nil {
+			break
+		}
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			// Requests are answered off the read loop so a slow handler
+			// cannot hold up responses to our own calls.
+			go c.answer(msg, h)
+		case msg.Method != "":
+			_, _ = h(msg.Method, msg.Params)
+		case len(msg.ID) > 0:
+			c.mu.Lock()
+			ch, ok := c.pending[string(msg.ID)]
+			delete(c.pending, string(msg.ID))
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		}
+	}
+
+	c.mu.Lock()
+	if errors.Is(err, io.EOF) {
+		err = errConnClosed
+	}
+	c.err = err
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *conn) answer(req *message, h handler) {
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	result, err := h(req.Method, req.Params)
+	if err != nil {
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &RPCError{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &RPCError{Code: codeInternalError, Message: err.Error()}
+		} else {
+			resp.Result = raw
+		}
+	}
+	_ = c.write(resp)
+}
+
+// readMessage reads one framed message.
+func readMessage(r *bufio.Reader) (*message, error) {
+	tp := textproto.NewReader(r)
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("bad Content-Length header %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("bad message: %w", err)
+	}
+	return &msg, nil
+}
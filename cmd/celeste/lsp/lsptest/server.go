@@ -0,0 +1,421 @@
+// Package lsptest is a scripted stand-in for a language server. It speaks
+// enough LSP over stdin/stdout for the lsp client to be tested without
+// gopls or pyright installed: diagnostics come from substring rules,
+// hover text from a table, and definitions and renames from whole-word
+// matches across the workspace.
+//
+// The server runs as a re-executed test binary. Call Init first thing in
+// TestMain, then use Config to get a ServerConfig that launches it.
+package lsptest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unicode"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp"
+)
+
+// serveArg marks a re-executed test binary as the stand-in server; the
+// script path follows it.
+const serveArg = "-celeste-lsp-stub"
+
+// Script drives the stand-in server.
+type Script struct {
+	// Diagnostics are published for every document that contains a rule's
+	// Match, at its first occurrence.
+	Diagnostics []Rule `json:"diagnostics,omitempty"`
+	// Hover maps a word to the hover text shown for it.
+	Hover map[string]string `json:"hover,omitempty"`
+	// Silent stops the server from publishing diagnostics at all.
+	Silent bool `json:"silent,omitempty"`
+	// Unversioned publishes diagnostics without a document version.
+	Unversioned bool `json:"unversioned,omitempty"`
+}
+
+// Rule is a diagnostic rule.
+type Rule struct {
+	Match    string       `json:"match"`
+	Message  string       `json:"message"`
+	Severity lsp.Severity `json:"severity,omitempty"`
+}
+
+// Init turns this process into the stand-in server when Config's command
+// started it, and returns immediately otherwise.
+func Init() {
+	if len(os.Args) < 3 || os.Args[1] != serveArg {
+		return
+	}
+	data, err := os.ReadFile(os.Args[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := Serve(os.Stdin, os.Stdout, script); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Config writes script to a temp file and returns a server config that
+// runs the stand-in for files with the given extensions.
+func Config(t testing.TB, script Script, extensions ...string) lsp.ServerConfig {
+	t.Helper()
+	data, err := json.Marshal(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lsp.ServerConfig{
+		Name:       "stub",
+		Command:    []string{exe, serveArg, path},
+		Extensions: extensions,
+	}
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *lsp.RPCError   `json:"error,omitempty"`
+}
+
+type server struct {
+	script Script
+	out    io.Writer
+	mu     sync.Mutex
+	root   string
+	docs   map[string]string // open documents by URI
+}
+
+// Serve runs the stand-in on r and w until the client sends exit or r
+// ends.
+func Serve(r io.Reader, w io.Writer, script Script) error {
+	s := &server{script: script, out: w, docs: map[string]string{}}
+	br := bufio.NewReader(r)
+	for {
+		msg, err := read(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, rpcErr := s.handle(msg)
+		if len(msg.ID) == 0 || msg.Method == "" {
+			continue
+		}
+		resp := &message{ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			resp.Result = result
+			if result == nil {
+				resp.Result = json.RawMessage("null")
+			}
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(msg *message) (any, *lsp.RPCError) {
+	switch msg.Method {
+	case "initialize":
+		var p struct {
+			RootURI string `json:"rootUri"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		s.root = lsp.URIToPath(p.RootURI)
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1,
+				"hoverProvider":      true,
+				"definitionProvider": true,
+				"renameProvider":     true,
+			},
+			"serverInfo": map[string]any{"name": "lsptest"},
+		}, nil
+	case "shutdown", "initialized", "textDocument/didSave", "$/cancelRequest":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+				Text    string `json:"text"`
+			} `json:"textDocument"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		s.update(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		if n := len(p.ContentChanges); n > 0 {
+			s.update(p.TextDocument.URI, p.TextDocument.Version, p.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		s.mu.Lock()
+		delete(s.docs, p.TextDocument.URI)
+		s.mu.Unlock()
+		return nil, nil
+	case "textDocument/hover", "textDocument/definition", "textDocument/rename":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			Position lsp.Position `json:"position"`
+			NewName  string       `json:"newName"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		word := s.wordAt(p.TextDocument.URI, p.Position)
+		if word == "" {
+			return nil, nil
+		}
+		switch msg.Method {
+		case "textDocument/hover":
+			text, ok := s.script.Hover[word]
+			if !ok {
+				return nil, nil
+			}
+			return map[string]any{"contents": map[string]any{"kind": "markdown", "value": text}}, nil
+		case "textDocument/definition":
+			occ := s.occurrences(word)
+			if len(occ) == 0 {
+				return nil, nil
+			}
+			return []any{occ[0]}, nil
+		default:
+			return s.rename(word, p.NewName), nil
+		}
+	}
+	if len(msg.ID) > 0 {
+		return nil, &lsp.RPCError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	return nil, nil
+}
+
+// update stores a document and publishes its diagnostics.
+func (s *server) update(uri string, version int, text string) {
+	s.mu.Lock()
+	s.docs[uri] = text
+	s.mu.Unlock()
+	if s.script.Silent {
+		return
+	}
+	diags := []map[string]any{}
+	for _, rule := range s.script.Diagnostics {
+		idx := strings.Index(text, rule.Match)
+		if idx < 0 {
+			continue
+		}
+		severity := rule.Severity
+		if severity == 0 {
+			severity = lsp.SeverityError
+		}
+		diags = append(diags, map[string]any{
+			"range":    rangeAt(text, idx, len(rule.Match)),
+			"severity": severity,
+			"source":   "lsptest",
+			"message":  rule.Message,
+		})
+	}
+	params := map[string]any{"uri": uri, "diagnostics": diags}
+	if !s.script.Unversioned {
+		params["version"] = version
+	}
+	_ = s.write(&message{Method: "textDocument/publishDiagnostics", Params: mustJSON(params)})
+}
+
+// text returns a document's open contents, or what's on disk.
+func (s *server) text(uri string) string {
+	s.mu.Lock()
+	text, ok := s.docs[uri]
+	s.mu.Unlock()
+	if ok {
+		return text
+	}
+	data, _ := os.ReadFile(lsp.URIToPath(uri))
+	return string(data)
+}
+
+func (s *server) wordAt(uri string, pos lsp.Position) string {
+	lines := strings.Split(s.text(uri), "\n")
+	if pos.Line >= len(lines) {
+		return ""
+	}
+	line := []rune(lines[pos.Line])
+	col := len([]rune(lines[pos.Line][:lsp.ByteOffset(lines[pos.Line], pos.Character)]))
+	start, end := col, col
+	for start > 0 && isWord(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWord(line[end]) {
+		end++
+	}
+	return string(line[start:end])
+}
+
+// occurrences finds whole-word uses of word in the workspace's files,
+// sorted by path and position.
+func (s *server) occurrences(word string) []lsp.Location {
+	var locs []lsp.Location
+	var paths []string
+	_ = filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != s.root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	for _, path := range paths {
+		uri := lsp.PathToURI(path)
+		text := s.text(uri)
+		for i := 0; ; {
+			idx := strings.Index(text[i:], word)
+			if idx < 0 {
+				break
+			}
+			idx += i
+			end := idx + len(word)
+			if (idx == 0 || !isWordByte(text[idx-1])) && (end == len(text) || !isWordByte(text[end])) {
+				locs = append(locs, lsp.Location{URI: uri, Range: rangeAt(text, idx, len(word))})
+			}
+			i = end
+		}
+	}
+	return locs
+}
+
+func (s *server) rename(word, newName string) any {
+	byURI := map[string][]lsp.TextEdit{}
+	var uris []string
+	for _, loc := range s.occurrences(word) {
+		if _, ok := byURI[loc.URI]; !ok {
+			uris = append(uris, loc.URI)
+		}
+		byURI[loc.URI] = append(byURI[loc.URI], lsp.TextEdit{Range: loc.Range, NewText: newName})
+	}
+	changes := []any{}
+	for _, uri := range uris {
+		changes = append(changes, map[string]any{
+			"textDocument": map[string]any{"uri": uri, "version": nil},
+			"edits":        byURI[uri],
+		})
+	}
+	return map[string]any{"documentChanges": changes}
+}
+
+func rangeAt(text string, offset, length int) lsp.Range {
+	return lsp.Range{Start: positionAt(text, offset), End: positionAt(text, offset+length)}
+}
+
+func positionAt(text string, offset int) lsp.Position {
+	line := strings.Count(text[:offset], "\n")
+	start := strings.LastIndex(text[:offset], "\n") + 1
+	return lsp.Position{Line: line, Character: lsp.UTF16Column(text[start:], offset-start)}
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 0x80 || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+func mustJSON(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func (s *server) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = s.out.Write(body)
+	return err
+}
+
+func read(r *bufio.Reader) (*message, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
@@ -0,0 +1,529 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+const (
+	// DefaultWait is how long Diagnose waits for a server to publish
+	// diagnostics for an edited file.
+	DefaultWait = 5 * time.Second
+	// DefaultSettle is how long Diagnose keeps listening after the first
+	// publish, for the type errors that follow syntax errors.
+	DefaultSettle = 250 * time.Millisecond
+	// MaxDiagnostics caps the diagnostics in one report.
+	MaxDiagnostics = 20
+	// maxRestarts is how many times a crashed server is restarted.
+	maxRestarts = 3
+)
+
+// Manager runs the configured language servers for a workspace. Servers
+// start on first use of a file they handle and stop on Close. It is safe
+// for concurrent use.
+type Manager struct {
+	workspace string
+	policy    sandbox.Policy
+	servers   []*server
+
+	// Wait and Settle tune Diagnose; see DefaultWait and DefaultSettle.
+	Wait   time.Duration
+	Settle time.Duration
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type server struct {
+	cfg      ServerConfig
+	mu       sync.Mutex
+	client   *Client
+	err      error // why the server can't be started; not retried
+	restarts int
+}
+
+// NewManager returns a manager for configs in workspace. Servers run
+// confined by policy, since a project's .grimoire chooses their commands.
+func NewManager(workspace string, configs []ServerConfig, policy sandbox.Policy) *Manager {
+	m := &Manager{workspace: workspace, policy: policy, Wait: DefaultWait, Settle: DefaultSettle}
+	for _, cfg := range configs {
+		m.servers = append(m.servers, &server{cfg: cfg})
+	}
+	return m
+}
+
+// Enabled reports whether any server is configured.
+func (m *Manager) Enabled() bool {
+	return m != nil && len(m.servers) > 0
+}
+
+// Handles reports whether a server is configured for path.
+func (m *Manager) Handles(path string) bool {
+	return m.serverFor(path) != nil
+}
+
+// Languages lists the configured server names.
+func (m *Manager) Languages() []string {
+	names := make([]string, len(m.servers))
+	for i, s := range m.servers {
+		names[i] = s.cfg.Name
+	}
+	return names
+}
+
+func (m *Manager) serverFor(path string) *server {
+	if m == nil {
+		return nil
+	}
+	for _, s := range m.servers {
+		if s.cfg.handles(path) {
+			return s
+		}
+	}
+	return nil
+}
+
+// client returns path's running server, starting or restarting it.
+func (m *Manager) client(ctx context.Context, path string) (*Client, error) {
+	s := m.serverFor(path)
+	if s == nil {
+		ext := filepath.Ext(path)
+		if ext == "" {
+			ext = filepath.Base(path)
+		}
+		return nil, fmt.Errorf("no language server configured for %s files (add one under ## Language Servers in .grimoire)", ext)
+	}
+
+	m.mu.Lock()
+	closed := m.closed
+	m.mu.Unlock()
+	if closed {
+		return nil, errors.New("language servers are shut down")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		if s.client.Alive() {
+			return s.client, nil
+		}
+		s.client.Close()
+		s.client = nil
+		s.restarts++
+		if s.restarts > maxRestarts {
+			s.err = fmt.Errorf("%s language server keeps exiting; giving up after %d restarts", s.cfg.Name, maxRestarts)
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	c, err := Start(ctx, s.cfg, m.workspace, m.policy)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.err = err
+		}
+		return nil, err
+	}
+	s.client = c
+	return c, nil
+}
+
+// FileDiagnostic is a diagnostic located in a workspace file.
+type FileDiagnostic struct {
+	Path     string // relative to the workspace
+	Line     int    // 1-based
+	Column   int    // 1-based, in characters
+	Severity Severity
+	Message  string
+	Source   string
+	// New is set when the problem wasn't reported before the edit.
+	New bool
+}
+
+func (d FileDiagnostic) String() string {
+	s := fmt.Sprintf("%s:%d:%d: %s: %s", d.Path, d.Line, d.Column, d.Severity, d.Message)
+	if d.Source != "" {
+		s += " (" + d.Source + ")"
+	}
+	return s
+}
+
+// Report is the outcome of Diagnose.
+type Report struct {
+	// Diagnostics holds errors and warnings in the checked files, and new
+	// errors the change caused in other files, errors first.
+	Diagnostics []FileDiagnostic
+	// Omitted counts diagnostics beyond MaxDiagnostics.
+	Omitted int
+	// Pending lists checked files whose server hadn't answered in time.
+	Pending []string
+	// Errors describes servers that could not be started.
+	Errors []string
+}
+
+// Diagnose sends the current contents of paths to their servers and
+// collects the diagnostics they publish. Paths without a configured server
+// are skipped; deleted files are closed.
+func (m *Manager) Diagnose(ctx context.Context, paths ...string) Report {
+	var (
+		mu     sync.Mutex
+		report Report
+		wg     sync.WaitGroup
+	)
+	seen := map[*Client]map[string]bool{}
+	before := map[*Client]map[string][]Diagnostic{}
+	errs := map[string]bool{}
+
+	for _, path := range paths {
+		if !m.Handles(path) {
+			continue
+		}
+		c, err := m.client(ctx, path)
+		if err != nil {
+			if msg := err.Error(); !errs[msg] {
+				errs[msg] = true
+				report.Errors = append(report.Errors, msg)
+			}
+			continue
+		}
+		if seen[c] == nil {
+			seen[c] = map[string]bool{}
+			before[c] = c.Diagnostics()
+		}
+		uri := PathToURI(path)
+		if seen[c][uri] {
+			continue
+		}
+		seen[c][uri] = true
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			c.Forget(path)
+			continue
+		}
+		version, since, changed, err := c.Sync(path, string(data))
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s language server: %v", c.cfg.Name, err))
+			continue
+		}
+
+		prev, reported := before[c][uri]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			items, ok := prev, true
+			if changed {
+				// Some servers skip publishing when a file's diagnostics
+				// didn't change, so for a file they already reported on,
+				// silence after a shorter wait means the old report stands.
+				wait := m.Wait
+				if reported {
+					wait /= 3
+				}
+				waitCtx, cancel := context.WithTimeout(ctx, wait)
+				items, ok = c.WaitDiagnostics(waitCtx, path, version, since, m.Settle)
+				cancel()
+				if !ok && reported && ctx.Err() == nil {
+					items, ok = prev, true
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !ok {
+				report.Pending = append(report.Pending, m.rel(path))
+			}
+			for _, d := range items {
+				if d.Severity == 0 || d.Severity <= SeverityWarning {
+					report.Diagnostics = append(report.Diagnostics, m.locate(path, string(data), d, prev))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Errors the change caused elsewhere, e.g. callers of a function
+	// whose signature changed.
+	for c, checked := range seen {
+		for uri, items := range c.Diagnostics() {
+			if checked[uri] {
+				continue
+			}
+			path := URIToPath(uri)
+			var text string
+			if data, err := os.ReadFile(path); err == nil {
+				text = string(data)
+			}
+			for _, d := range items {
+				if d.Severity != 0 && d.Severity != SeverityError {
+					continue
+				}
+				if fd := m.locate(path, text, d, before[c][uri]); fd.New {
+					report.Diagnostics = append(report.Diagnostics, fd)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(report.Diagnostics, func(i, j int) bool {
+		a, b := report.Diagnostics[i], report.Diagnostics[j]
+		if a.Severity != b.Severity {
+			return effectiveSeverity(a.Severity) < effectiveSeverity(b.Severity)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	if n := len(report.Diagnostics); n > MaxDiagnostics {
+		report.Omitted = n - MaxDiagnostics
+		report.Diagnostics = report.Diagnostics[:MaxDiagnostics]
+	}
+	sort.Strings(report.Pending)
+	return report
+}
+
+func effectiveSeverity(s Severity) Severity {
+	if s == 0 {
+		return SeverityError
+	}
+	return s
+}
+
+// locate converts d to a FileDiagnostic, marking it new unless before
+// already had the same problem.
+func (m *Manager) locate(path, text string, d Diagnostic, before []Diagnostic) FileDiagnostic {
+	line, col := lineColumn(text, d.Range.Start)
+	fd := FileDiagnostic{
+		Path:     m.rel(path),
+		Line:     line,
+		Column:   col,
+		Severity: effectiveSeverity(d.Severity),
+		Message:  strings.TrimSpace(d.Message),
+		Source:   d.Source,
+		New:      true,
+	}
+	for _, b := range before {
+		if b.Message == d.Message && effectiveSeverity(b.Severity) == fd.Severity {
+			fd.New = false
+			break
+		}
+	}
+	return fd
+}
+
+func (m *Manager) rel(path string) string {
+	if rel, err := filepath.Rel(m.workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// syncForQuery opens path at its current contents on its server and
+// converts a 1-based line and character column to an LSP position.
+func (m *Manager) syncForQuery(ctx context.Context, path string, line, column int) (*Client, Position, error) {
+	c, err := m.client(ctx, path)
+	if err != nil {
+		return nil, Position{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, Position{}, err
+	}
+	text := string(data)
+	pos, err := position(text, line, column)
+	if err != nil {
+		return nil, Position{}, err
+	}
+	if _, _, _, err := c.Sync(path, text); err != nil {
+		return nil, Position{}, err
+	}
+	return c, pos, nil
+}
+
+// Hover returns the hover text for the symbol at line and column (both
+// 1-based) in path.
+func (m *Manager) Hover(ctx context.Context, path string, line, column int) (string, error) {
+	c, pos, err := m.syncForQuery(ctx, path, line, column)
+	if err != nil {
+		return "", err
+	}
+	return c.Hover(ctx, path, pos)
+}
+
+// FileLocation is a 1-based position in a file.
+type FileLocation struct {
+	Path   string // absolute
+	Line   int
+	Column int
+}
+
+// Definition returns where the symbol at line and column in path is
+// defined.
+func (m *Manager) Definition(ctx context.Context, path string, line, column int) ([]FileLocation, error) {
+	c, pos, err := m.syncForQuery(ctx, path, line, column)
+	if err != nil {
+		return nil, err
+	}
+	locs, err := c.Definition(ctx, path, pos)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]FileLocation, 0, len(locs))
+	for _, loc := range locs {
+		target := URIToPath(loc.URI)
+		var text string
+		if data, err := os.ReadFile(target); err == nil {
+			text = string(data)
+		}
+		l, col := lineColumn(text, loc.Range.Start)
+		out = append(out, FileLocation{Path: target, Line: l, Column: col})
+	}
+	return out, nil
+}
+
+// Rename returns the edits that rename the symbol at line and column in
+// path to newName, keyed by absolute path. Nothing is written.
+func (m *Manager) Rename(ctx context.Context, path string, line, column int, newName string) (map[string][]TextEdit, error) {
+	c, pos, err := m.syncForQuery(ctx, path, line, column)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := c.Rename(ctx, path, pos, newName)
+	if err != nil {
+		return nil, err
+	}
+	if len(edit.Unsupported) > 0 {
+		return nil, fmt.Errorf("rename needs file operations celeste does not apply: %s", strings.Join(edit.Unsupported, ", "))
+	}
+	out := make(map[string][]TextEdit, len(edit.Edits))
+	for uri, edits := range edit.Edits {
+		out[URIToPath(uri)] = edits
+	}
+	return out, nil
+}
+
+// Close shuts down every running server.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, s := range m.servers {
+		s.mu.Lock()
+		c := s.client
+		s.client = nil
+		s.mu.Unlock()
+		if c != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Close()
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// position converts a 1-based line and character column in text to an
+// LSP position.
+func position(text string, line, column int) (Position, error) {
+	lines := strings.Split(text, "\n")
+	if line < 1 || line > len(lines) {
+		return Position{}, fmt.Errorf("line %d is out of range (file has %d lines)", line, len(lines))
+	}
+	l := strings.TrimSuffix(lines[line-1], "\r")
+	if column < 1 || column > utf8.RuneCountInString(l)+1 {
+		return Position{}, fmt.Errorf("column %d is out of range (line %d has %d characters)", column, line, utf8.RuneCountInString(l))
+	}
+	offset := len(l)
+	for i := range l {
+		if column == 1 {
+			offset = i
+			break
+		}
+		column--
+	}
+	return Position{Line: line - 1, Character: UTF16Column(l, offset)}, nil
+}
+
+// lineColumn converts an LSP position to a 1-based line and character
+// column, using text (which may be empty) to count characters.
+func lineColumn(text string, pos Position) (int, int) {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return pos.Line + 1, pos.Character + 1
+	}
+	l := lines[pos.Line]
+	return pos.Line + 1, utf8.RuneCountInString(l[:ByteOffset(l, pos.Character)]) + 1
+}
+
+// ApplyEdits applies LSP text edits to text.
+func ApplyEdits(text string, edits []TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(p Position) (int, error) {
+		if p.Line >= len(lineStarts) {
+			if p.Line == len(lineStarts) && p.Character == 0 {
+				return len(text), nil
+			}
+			return 0, fmt.Errorf("edit position %d:%d is past the end of the file", p.Line+1, p.Character+1)
+		}
+		start := lineStarts[p.Line]
+		end := len(text)
+		if p.Line+1 < len(lineStarts) {
+			end = lineStarts[p.Line+1] - 1
+		}
+		return start + ByteOffset(text[start:end], p.Character), nil
+	}
+
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, err := offset(e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := offset(e.Range.End)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", fmt.Errorf("edit range %d:%d-%d:%d is reversed", e.Range.Start.Line+1, e.Range.Start.Character+1, e.Range.End.Line+1, e.Range.End.Character+1)
+		}
+		spans = append(spans, span{start, end, e.NewText})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var sb strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			return "", errors.New("edits overlap")
+		}
+		sb.WriteString(text[last:s.start])
+		sb.WriteString(s.text)
+		last = s.end
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), nil
+}
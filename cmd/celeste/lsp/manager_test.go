@@ -0,0 +1,154 @@
+package lsp_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp/lsptest"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+func TestMain(m *testing.M) {
+	// The stand-in language server is this test binary re-executed.
+	lsptest.Init()
+	os.Exit(m.Run())
+}
+
+func newManager(t *testing.T, script lsptest.Script) (*lsp.Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m := lsp.NewManager(dir, []lsp.ServerConfig{lsptest.Config(t, script, ".go")}, sandbox.Policy{})
+	t.Cleanup(m.Close)
+	return m, dir
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestDiagnose(t *testing.T) {
+	m, dir := newManager(t, lsptest.Script{Diagnostics: []lsptest.Rule{
+		{Match: "undefinedThing", Message: "undefined: undefinedThing"},
+		{Match: "unusedVar", Message: "declared and not used", Severity: lsp.SeverityWarning},
+		{Match: "TODO", Message: "todo", Severity: lsp.SeverityHint},
+	}})
+	ctx := context.Background()
+	a := filepath.Join(dir, "pkg", "a.go")
+	write(t, a, "package pkg\n\n// TODO\nfunc A() { unusedVar := 1 }\n")
+
+	report := m.Diagnose(ctx, a, filepath.Join(dir, "notes.txt"))
+	require.Empty(t, report.Errors)
+	require.Empty(t, report.Pending)
+	require.Len(t, report.Diagnostics, 1, "hints are left out")
+	assert.Equal(t, "pkg/a.go:4:12: warning: declared and not used (lsptest)", report.Diagnostics[0].String())
+
+	write(t, a, "package pkg\n\n// TODO\nfunc A() { unusedVar := 1; println(undefinedThing) }\n")
+	report = m.Diagnose(ctx, a)
+	require.Len(t, report.Diagnostics, 2)
+	first := report.Diagnostics[0]
+	assert.Equal(t, lsp.SeverityError, first.Severity, "errors sort first")
+	assert.Equal(t, 4, first.Line)
+	assert.Equal(t, 36, first.Column)
+	assert.True(t, first.New)
+	assert.False(t, report.Diagnostics[1].New, "the warning was already reported")
+}
+
+func TestDiagnosePendingWhenServerIsSilent(t *testing.T) {
+	m, dir := newManager(t, lsptest.Script{Silent: true})
+	m.Wait = 100 * time.Millisecond
+	a := filepath.Join(dir, "a.go")
+	write(t, a, "package a\n")
+
+	report := m.Diagnose(context.Background(), a)
+	assert.Equal(t, []string{"a.go"}, report.Pending)
+	assert.Empty(t, report.Diagnostics)
+}
+
+func TestDiagnoseMissingServer(t *testing.T) {
+	dir := t.TempDir()
+	m := lsp.NewManager(dir, []lsp.ServerConfig{{Name: "go", Command: []string{"celeste-no-such-server"}, Extensions: []string{".go"}}}, sandbox.Policy{})
+	defer m.Close()
+	a := filepath.Join(dir, "a.go")
+	write(t, a, "package a\n")
+
+	report := m.Diagnose(context.Background(), a, a)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], "not installed")
+}
+
+func TestHoverDefinitionRename(t *testing.T) {
+	m, dir := newManager(t, lsptest.Script{Hover: map[string]string{"Greet": "func Greet(name string) string"}})
+	ctx := context.Background()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	write(t, a, "package p\n\nfunc Greet(name string) string { return name }\n")
+	write(t, b, "package p\n\n// héllo\nvar x = Greet(\"hi\") + Greet(\"yo\")\n")
+
+	text, err := m.Hover(ctx, b, 4, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "func Greet(name string) string", text)
+
+	locs, err := m.Definition(ctx, b, 4, 10)
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+	assert.Equal(t, lsp.FileLocation{Path: a, Line: 3, Column: 6}, locs[0])
+
+	edits, err := m.Rename(ctx, b, 4, 10, "Welcome")
+	require.NoError(t, err)
+	require.Len(t, edits, 2)
+	data, _ := os.ReadFile(b)
+	out, err := lsp.ApplyEdits(string(data), edits[b])
+	require.NoError(t, err)
+	assert.Equal(t, "package p\n\n// héllo\nvar x = Welcome(\"hi\") + Welcome(\"yo\")\n", out)
+
+	_, err = m.Hover(ctx, b, 9, 1)
+	assert.ErrorContains(t, err, "out of range")
+	_, err = m.Hover(ctx, filepath.Join(dir, "x.py"), 1, 1)
+	assert.ErrorContains(t, err, "no language server configured for .py")
+}
+
+func TestApplyEditsUTF16(t *testing.T) {
+	// "😀" is two UTF-16 code units.
+	text := "a😀b\nc\n"
+	out, err := lsp.ApplyEdits(text, []lsp.TextEdit{
+		{Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 3}, End: lsp.Position{Line: 0, Character: 4}}, NewText: "B"},
+		{Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 0}, End: lsp.Position{Line: 1, Character: 1}}, NewText: "C"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a😀B\nC\n", out)
+
+	_, err = lsp.ApplyEdits(text, []lsp.TextEdit{
+		{Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 0}, End: lsp.Position{Line: 0, Character: 2}}},
+		{Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 1}, End: lsp.Position{Line: 0, Character: 3}}},
+	})
+	assert.ErrorContains(t, err, "overlap")
+}
+
+func TestParseConfigs(t *testing.T) {
+	configs, err := lsp.ParseConfigs([]grimoire.LanguageServerEntry{
+		{Language: "go"},
+		{Language: "Python", Command: "pylsp"},
+		{Language: "ts", Command: "off"},
+		{Language: ".lua .luau", Command: "lua-language-server --stdio"},
+		{Language: "go", Command: "gopls -remote=auto"},
+	})
+	require.NoError(t, err)
+	require.Len(t, configs, 3)
+	assert.Equal(t, []string{"gopls", "-remote=auto"}, configs[0].Command, "a later entry overrides")
+	assert.Equal(t, []string{"pylsp"}, configs[1].Command)
+	assert.Equal(t, []string{".lua", ".luau"}, configs[2].Extensions)
+
+	_, err = lsp.ParseConfigs([]grimoire.LanguageServerEntry{{Language: "cobol"}})
+	assert.ErrorContains(t, err, "unknown language")
+	_, err = lsp.ParseConfigs([]grimoire.LanguageServerEntry{{Language: ".zig"}})
+	assert.ErrorContains(t, err, "needs a command")
+}
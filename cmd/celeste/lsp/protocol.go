@@ -0,0 +1,233 @@
+package lsp
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The subset of the LSP 3.17 protocol the client uses.
+
+// Position is a zero-based line and UTF-16 code unit offset.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a half-open span between two positions.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// locationLink is the alternative definition result form.
+type locationLink struct {
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+// Severity of a diagnostic.
+type Severity int
+
+const (
+	SeverityError       Severity = 1
+	SeverityWarning     Severity = 2
+	SeverityInformation Severity = 3
+	SeverityHint        Severity = 4
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	}
+	return "error"
+}
+
+// Diagnostic is a problem the server reported in a document.
+type Diagnostic struct {
+	Range    Range           `json:"range"`
+	Severity Severity        `json:"severity,omitempty"`
+	Code     json.RawMessage `json:"code,omitempty"`
+	Source   string          `json:"source,omitempty"`
+	Message  string          `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type versionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+type textDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// TextEdit replaces Range with NewText.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit is a set of edits across documents, as returned by rename.
+// Edits maps document URIs to their edits; Unsupported lists resource
+// operations (file creates, renames, deletes) the client does not apply.
+type WorkspaceEdit struct {
+	Edits       map[string][]TextEdit
+	Unsupported []string
+}
+
+// UnmarshalJSON accepts both the changes map and documentChanges forms.
+func (w *WorkspaceEdit) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Changes         map[string][]TextEdit `json:"changes"`
+		DocumentChanges []json.RawMessage     `json:"documentChanges"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	w.Edits = map[string][]TextEdit{}
+	for uri, edits := range raw.Changes {
+		w.Edits[uri] = append(w.Edits[uri], edits...)
+	}
+	for _, change := range raw.DocumentChanges {
+		var doc struct {
+			Kind         string                 `json:"kind"`
+			URI          string                 `json:"uri"`
+			OldURI       string                 `json:"oldUri"`
+			TextDocument textDocumentIdentifier `json:"textDocument"`
+			Edits        []TextEdit             `json:"edits"`
+		}
+		if err := json.Unmarshal(change, &doc); err != nil {
+			return err
+		}
+		if doc.Kind != "" {
+			uri := doc.URI
+			if uri == "" {
+				uri = doc.OldURI
+			}
+			w.Unsupported = append(w.Unsupported, doc.Kind+" "+uri)
+			continue
+		}
+		w.Edits[doc.TextDocument.URI] = append(w.Edits[doc.TextDocument.URI], doc.Edits...)
+	}
+	return nil
+}
+
+// hoverText flattens the three shapes a hover's contents can take
+// (MarkupContent, MarkedString, MarkedString[]) to text.
+func hoverText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var markup struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if json.Unmarshal(raw, &markup) == nil && markup.Value != "" {
+		if markup.Language != "" {
+			return "```" + markup.Language + "\n" + markup.Value + "\n```"
+		}
+		return markup.Value
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			if text := hoverText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+	return ""
+}
+
+// PathToURI returns the file:// URI for an absolute path.
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if runtime.GOOS == "windows" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// URIToPath returns the path for a file:// URI, or the URI unchanged if it
+// is not one.
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path)
+}
+
+// UTF16Column converts a zero-based byte offset within line to the UTF-16
+// code unit offset LSP positions use.
+func UTF16Column(line string, byteOffset int) int {
+	if byteOffset > len(line) {
+		byteOffset = len(line)
+	}
+	n := 0
+	for _, r := range line[:byteOffset] {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// ByteOffset converts a UTF-16 code unit offset within line back to a byte
+// offset, clamped to the line's length.
+func ByteOffset(line string, utf16Column int) int {
+	units := 0
+	for i, r := range line {
+		if units >= utf16Column {
+			return i
+		}
+		if r == utf8.RuneError {
+			units++
+			continue
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
@@ -29,12 +29,15 @@ import (
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/hooks"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/memories"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/monitor"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/prompts"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/server"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/shell"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/subagents"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools/builtin"
@@ -80,6 +83,7 @@ Commands:
   chat                    Launch interactive TUI mode
   message <text>          Send a single message and exit
   config                  View/modify configuration
+  config diff <a> <b>     Compare two profiles (secrets shown as fingerprints)
   skills                  List and manage skills
   providers               List and query AI providers
   agent                   Run autonomous agent loops for complex tasks
@@ -89,16 +93,37 @@ Commands:
   export                  Export session data
   init                    Create a starter .grimoire for the current project
   grimoire                Show the resolved project grimoire (all layers merged)
+  grimoire generate [-batch] <repo>...
+                          Have the model write .grimoire files for repositories
   index [status|rebuild|reset]  Manage code graph index
   serve                   Start MCP server (stdio or SSE transport)
   wallet-monitor          Manage wallet security monitoring daemon
   costs                   Show session cost breakdown
+  models [list [provider]|show <id>|refresh]
+                          Show or refresh the model catalog (context, prices, tools)
   memories                List memories for current project
+  memories extract        Propose memories from the last chat session with the model
+  memories verify         Check memories' code references against the workspace
   remember "<text>"       Save a memory
   forget <name>           Delete a memory
   resume [session-id]     Resume a previous session
   plan [show]             Show current plan from .celeste/plan.md
   revert <file>           Revert a file from checkpoint
+  revert --list|--diff|--to <id>
+                          List, diff or restore workspace checkpoints
+  secrets set <name> [value]  Store a credential in the encrypted vault
+  secrets get|list|delete     Read, list (masked) or remove vault entries
+  secrets migrate [--dry-run] Move plaintext keys from ~/.celeste into the vault
+  batch submit [-wait] <jobs.jsonl>
+                          Send prompts through the provider's batch API (half price)
+  batch status|results|cancel <batch-id>
+                          Check on, collect or cancel a submitted batch
+  image generate [-size WxH] [-n N] "<prompt>"
+                          Generate images (OpenAI Images or Venice), previewed inline
+  image edit -image <png> [-mask <png>] "<prompt>"
+                          Edit an image, optionally only where the mask is transparent
+  sandbox [status]        Show which sandbox layers this system supports and the policy
+  sandbox run "<command>" Run a command under the sandbox policy to see what it may do
   help                    Show this help message
   version                 Show version information
 
@@ -110,6 +135,7 @@ Interactive Commands (in chat mode):
   /agent <goal>           Run autonomous task loop
   /orch <goal>            Multi-model orchestrated run
   /memories               List project memories
+  /memories why           Explain which memories the last request used
   /costs                  Show session costs
   /context                Show context/token usage
   /grimoire               Show project grimoire
@@ -158,6 +184,9 @@ Sessions:
   celeste session --list                 List saved sessions
   celeste session --load <id>            Load a session
   celeste session --clear                Clear all sessions
+  celeste sessions export <id> [--format md|html|json] [--out <file>]
+                                          Export a session log or agent run
+                                          (keys, tokens and emails redacted)
 
 Agent:
   celeste agent --goal "<task>"          Run autonomous task loop
@@ -165,6 +194,8 @@ Agent:
   celeste agent --list-runs              List recent runs
   celeste agent --eval <cases.json>      Run eval harness cases
   celeste agent --benchmark <suite.json> Run benchmark suite scaffolding
+  celeste agent --eval <cases.json> -batch
+                                          Grade rubrics through the batch API
   celeste agent --planner=true --verify-cmd "go test ./..." --require-verify
                                           Enable plan->execute->verify gating
 
@@ -206,9 +237,11 @@ func runChatTUI() {
 		cfg.ClawMaxToolIterations = config.DefaultClawMaxToolIterations
 	}
 
-	// Show which config is being used
+	// Show which config is being used, and why when the project picked it
 	if configName != "" {
 		fmt.Fprintf(os.Stderr, "Using config: %s\n", configName)
+	} else if note := profileSelectionNote(); note != "" {
+		fmt.Fprintf(os.Stderr, "Using config: %s\n", note)
 	}
 
 	// Validate API key
@@ -222,6 +255,9 @@ func runChatTUI() {
 		os.Exit(1)
 	}
 
+	// Keep the model catalog's listing for this provider current.
+	refreshModelCatalogInBackground(cfg)
+
 	// Initialize file checkpointing for stale detection and undo support
 	fileTracker := checkpoints.NewFileTracker()
 	snapshotMgr := checkpoints.NewSnapshotManager(fmt.Sprintf("tui-%d", os.Getpid()))
@@ -233,7 +269,17 @@ func runChatTUI() {
 	cwd, _ := os.Getwd()
 	builtin.RegisterAll(registry, cwd, configLoader, fileTracker, snapshotMgr)
 	builtin.RegisterCollectionsTools(registry, cfg)
-	_ = registry.LoadCustomTools(filepath.Join(homeDir, ".celeste", "skills"))
+	configureWebResearch(registry, cfg)
+	if err := registry.LoadCustomTools(filepath.Join(homeDir, ".celeste", "skills")); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: custom tools: %v\n", err)
+	}
+
+	// Persistent shells and background jobs live as long as the session;
+	// everything they started is killed when the TUI exits, and any job
+	// still running is listed so it is not stopped silently.
+	shells := shell.NewManager(cwd)
+	defer func() { shell.ReportStopped(os.Stderr, shells.Close()) }()
+	builtin.RegisterShellTools(registry, cwd, shells)
 
 	// Register subagent spawning tool — available in all modes so chat
 	// users can delegate subtasks and parameterize subagent persona.
@@ -251,6 +297,12 @@ func runChatTUI() {
 		tools.ModeAgent, tools.ModeClaw, tools.ModeChat,
 	)
 
+	// Project tools from .celeste/tools load last so they never replace a
+	// built-in.
+	if err := registry.LoadProjectTools(cwd); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: project tools: %v\n", err)
+	}
+
 	// Load permissions and set checker
 	permConfigPath := filepath.Join(homeDir, ".celeste", "permissions.json")
 	permConfig, err := permissions.LoadConfig(permConfigPath)
@@ -287,17 +339,7 @@ func runChatTUI() {
 	}
 
 	// Initialize LLM client
-	llmConfig := &llm.Config{
-		APIKey:            cfg.APIKey,
-		BaseURL:           cfg.BaseURL,
-		Model:             cfg.Model,
-		Timeout:           cfg.GetTimeout(),
-		SkipPersonaPrompt: cfg.SkipPersonaPrompt,
-		SimulateTyping:    cfg.SimulateTyping,
-		TypingSpeed:       cfg.TypingSpeed,
-		Collections:       cfg.Collections,
-		XAIFeatures:       cfg.XAIFeatures,
-	}
+	llmConfig := chatClientConfig(cfg)
 	client := llm.NewClient(llmConfig, registry)
 
 	// Load project grimoire and git snapshot for system prompt context
@@ -352,15 +394,19 @@ func runChatTUI() {
 		}
 	}
 
-	// Load project memories
+	// Project memories are retrieved per request (see memoryRecall) rather
+	// than injected wholesale, so large memory stores don't eat the context
+	// budget. The system prompt only says they exist.
 	memStore := memories.NewStore(cwd)
-	memIndex, memIdxErr := memories.LoadIndex(filepath.Join(memStore.BaseDir(), "MEMORY.md"))
-	if memIdxErr == nil && len(memIndex.Entries()) > 0 {
-		memoryContent := memIndex.Render()
+	recall := &memoryRecall{
+		store: memStore,
+		opts:  memories.RetrieveOptions{TopK: cfg.MemoryTopK, MaxTokens: cfg.MemoryMaxTokens},
+	}
+	if mems, _ := memStore.List(); len(mems) > 0 {
 		if grimoireContent != "" {
 			grimoireContent += "\n\n"
 		}
-		grimoireContent += "# Project Memories\n\n" + memoryContent
+		grimoireContent += fmt.Sprintf("# Project Memories\n\n%d memories are saved for this project. The ones relevant to a request are attached to the user's message in a <%s> block; treat them as context from earlier sessions and verify anything that may have changed.", len(mems), memoryContextTag)
 	}
 
 	var gitSnapshotContent string
@@ -403,6 +449,14 @@ func runChatTUI() {
 		codeGraphSummary = indexer.ProjectSummary()
 	}
 
+	// Memories are checked against the code before they are injected; the
+	// graph resolves identifiers when it is available.
+	if indexer != nil {
+		recall.verifier = memories.NewVerifier(cwd, indexer.Store())
+	} else {
+		recall.verifier = memories.NewVerifier(cwd, nil)
+	}
+
 	// Set system prompt with project context if not skipping
 	var projectContext string
 	if grimoireContent != "" {
@@ -463,14 +517,33 @@ func runChatTUI() {
 		}()
 	}
 
-	// Wire grimoire hooks into the tool registry
+	// Wire grimoire hooks into the tool registry, and the commit convention
+	// and PreCommit hooks into git_commit.
+	var preCommit *hooks.Executor
 	if projectGrimoire != nil {
 		parsedHooks := hooks.ParseFromGrimoire(projectGrimoire)
 		if len(parsedHooks) > 0 {
 			executor := hooks.NewExecutor(parsedHooks, cwd)
+			executor.SetSandbox(permConfig.Sandbox)
 			registry.SetHookRunner(&hookRunnerAdapter{executor: executor})
+			preCommit = executor
 		}
 	}
+	convention, err := builtin.CommitConventionFromGrimoire(projectGrimoire)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: .grimoire commit convention: %v\n", err)
+	}
+	builtin.ConfigureGitCommit(registry, convention, preCommit)
+
+	// Language servers from the grimoire give edit tools diagnostics and
+	// add the lsp_* tools; each server starts on first use.
+	lspConfigs, err := lsp.ConfigsFromGrimoire(projectGrimoire)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: .grimoire language servers: %v\n", err)
+	}
+	languageServers := lsp.NewManager(cwd, lspConfigs, permConfig.Sandbox)
+	defer languageServers.Close()
+	builtin.RegisterLSPTools(registry, cwd, languageServers)
 
 	// Create TUI client adapter
 	tuiClient := &TUIClientAdapter{
@@ -479,6 +552,15 @@ func runChatTUI() {
 		baseConfig:  cfg,
 		costTracker: costs.NewSessionTracker(),
 		subMgr:      subMgr,
+		recall:      recall,
+		shells:      shells,
+	}
+	if cfg.CheckpointMode == config.CheckpointModeShadow {
+		if repo, err := checkpoints.NewShadowRepo(cwd); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: shadow checkpoints disabled: %v\n", err)
+		} else {
+			tuiClient.shadow = &shadowTurns{repo: repo, session: fmt.Sprintf("tui-%d", os.Getpid())}
+		}
 	}
 
 	// Initialize logging for skill calls
@@ -498,6 +580,16 @@ func runChatTUI() {
 	fmt.Fprintln(os.Stderr, "📝 Starting new session")
 	currentSession = sessionManager.NewSession()
 
+	// Image tools save into the session's asset directory, so they are
+	// registered once the session ID is known.
+	if gen, err := cfg.ImageGenerator(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: image generation disabled: %v\n", err)
+	} else if gen != nil {
+		if store, err := media.SessionStore(currentSession.ID); err == nil {
+			builtin.RegisterImageTools(registry, gen, store, cwd)
+		}
+	}
+
 	// Create TUI with session management
 	app := tui.NewApp(tuiClient)
 
@@ -524,9 +616,10 @@ func runChatTUI() {
 		tuiMessages := make([]tui.ChatMessage, len(currentSession.Messages))
 		for i, msg := range currentSession.Messages {
 			tuiMessages[i] = tui.ChatMessage{
-				Role:      msg.Role,
-				Content:   msg.Content,
-				Timestamp: msg.Timestamp,
+				Role:        msg.Role,
+				Content:     msg.Content,
+				Timestamp:   msg.Timestamp,
+				Attachments: msg.Attachments,
 			}
 		}
 		app = app.WithMessages(tuiMessages)
@@ -672,9 +765,19 @@ func runChatTUI() {
 
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+		shell.ReportStopped(os.Stderr, shells.Close())
+		languageServers.Close()
 		os.Exit(1)
 	}
 
+	// The TUI may have switched sessions (/new, /resume), so extract from
+	// whichever one was saved last rather than the one we started with.
+	if cfg.MemoryExtraction {
+		if last, err := sessionManager.LoadLatest(); err == nil {
+			startBackgroundMemoryExtraction(last)
+		}
+	}
+
 	// Print log path on exit
 	if logPath := tui.GetLogPath(); logPath != "" {
 		fmt.Printf("\nSkill call log: %s\n", logPath)
@@ -709,6 +812,9 @@ type TUIClientAdapter struct {
 	baseConfig  *config.Config // Store base config for loading named configs
 	costTracker *costs.SessionTracker
 	subMgr      *subagents.Manager // exposed for /agents TUI command
+	shadow      *shadowTurns       // per-turn workspace checkpoints, nil unless checkpoint_mode is "shadow"
+	recall      *memoryRecall      // per-request memory retrieval
+	shells      *shell.Manager     // persistent shells and background jobs, for /jobs
 }
 
 // SendMessage implements tui.LLMClient.
@@ -748,6 +854,11 @@ func (a *TUIClientAdapter) sendMessageWithCtx(ctx context.Context, cancel contex
 		defer cancel()
 		defer close(ch)
 
+		// Snapshot before the model can act, so the turn's tool calls — bash
+		// included — land after the checkpoint and /undo can rewind them.
+		a.shadow.checkpointUserTurn(messages)
+		messages = a.recall.inject(messages)
+
 		currentConfig := a.client.GetConfig()
 		tui.LogInfo(fmt.Sprintf("→ Sending request to: %s (model: %s)", currentConfig.BaseURL, currentConfig.Model))
 		tui.LogLLMRequest(len(messages), len(tools))
@@ -791,6 +902,9 @@ func (a *TUIClientAdapter) sendMessageWithCtx(ctx context.Context, cancel contex
 				isFirst = false
 			case llm.EventToolUseStart, llm.EventToolUseInputDelta, llm.EventToolUseDone:
 				acc.HandleEvent(event)
+			case llm.EventServerToolUse:
+				// Already ran on the provider's side; nothing to execute.
+				tui.LogInfo(fmt.Sprintf("provider ran built-in tool %s: %s", event.ToolName, event.CompleteInput))
 			case llm.EventMessageDone:
 				usage = event.Usage
 				finishReason = event.FinishReason
@@ -831,6 +945,7 @@ func (a *TUIClientAdapter) sendMessageWithCtx(ctx context.Context, cancel contex
 					Name:             t.Name,
 					Arguments:        t.Arguments,
 					ThoughtSignature: t.ThoughtSignature,
+					ReasoningState:   t.ReasoningState,
 				}
 				callRequests[i] = tui.SkillCallRequest{
 					Call: tui.FunctionCall{
@@ -861,8 +976,18 @@ func (a *TUIClientAdapter) sendMessageWithCtx(ctx context.Context, cancel contex
 				PromptTokens:     usage.PromptTokens,
 				CompletionTokens: usage.CompletionTokens,
 				TotalTokens:      usage.TotalTokens,
+				CachedTokens:     usage.CachedTokens,
+			}
+			served := a.client.LastServed()
+			a.costTracker.RecordServedTokens(served.Provider, served.Model, costs.Usage{
+				Input:      usage.PromptTokens,
+				Output:     usage.CompletionTokens,
+				Cached:     usage.CachedTokens,
+				CacheWrite: usage.CacheWriteTokens,
+			})
+			if served.Fallback {
+				tui.LogInfo(fmt.Sprintf("Turn served by fallback %s (%s)", served.Provider, served.Model))
 			}
-			a.costTracker.RecordUsage(currentConfig.Model, usage.PromptTokens, usage.CompletionTokens)
 			summary := a.costTracker.GetSummary()
 			if summary.TotalCostUSD > 0 {
 				tui.LogInfo(fmt.Sprintf("Session cost: $%.4f (%d turns)", summary.TotalCostUSD, summary.Turns))
@@ -985,7 +1110,7 @@ func (a *TUIClientAdapter) SwitchEndpoint(endpoint string) error {
 			skillsConfig, err := config.LoadSkillsConfig()
 			if err == nil && skillsConfig.VeniceAPIKey != "" {
 				cfg.APIKey = skillsConfig.VeniceAPIKey
-				cfg.BaseURL = skillsConfig.VeniceBaseURL
+				cfg.SetBaseURL(skillsConfig.VeniceBaseURL)
 				if skillsConfig.VeniceModel != "" {
 					cfg.Model = skillsConfig.VeniceModel
 				}
@@ -1001,9 +1126,9 @@ func (a *TUIClientAdapter) SwitchEndpoint(endpoint string) error {
 
 				// Check for custom base URL
 				if envURL := os.Getenv("VENICE_API_BASE_URL"); envURL != "" {
-					cfg.BaseURL = envURL
+					cfg.SetBaseURL(envURL)
 				} else {
-					cfg.BaseURL = "https://api.venice.ai/api/v1"
+					cfg.SetBaseURL("https://api.venice.ai/api/v1")
 				}
 			}
 		} else {
@@ -1016,7 +1141,7 @@ func (a *TUIClientAdapter) SwitchEndpoint(endpoint string) error {
 			}
 
 			if url, ok := endpointURLs[endpoint]; ok {
-				cfg.BaseURL = url
+				cfg.SetBaseURL(url)
 				tui.LogInfo(fmt.Sprintf("Using fallback URL for %s: %s", endpoint, url))
 			} else {
 				tui.LogInfo(fmt.Sprintf("Warning: Unknown endpoint '%s', keeping current URL", endpoint))
@@ -1027,17 +1152,7 @@ func (a *TUIClientAdapter) SwitchEndpoint(endpoint string) error {
 	}
 
 	// Update LLM client configuration
-	llmConfig := &llm.Config{
-		APIKey:            cfg.APIKey,
-		BaseURL:           cfg.BaseURL,
-		Model:             cfg.Model,
-		Timeout:           cfg.GetTimeout(),
-		SkipPersonaPrompt: cfg.SkipPersonaPrompt,
-		SimulateTyping:    cfg.SimulateTyping,
-		TypingSpeed:       cfg.TypingSpeed,
-		Collections:       cfg.Collections,
-		XAIFeatures:       cfg.XAIFeatures,
-	}
+	llmConfig := chatClientConfig(cfg)
 
 	a.client.UpdateConfig(llmConfig)
 
@@ -1069,6 +1184,31 @@ func (a *TUIClientAdapter) SwitchEndpoint(endpoint string) error {
 	return nil
 }
 
+// chatClientConfig is the client configuration the chat TUI resolves from
+// cfg. Commands acting on a chat session afterwards, such as memory
+// extraction, build their client from it too so they reach the same
+// provider with the same credentials.
+func chatClientConfig(cfg *config.Config) *llm.Config {
+	return &llm.Config{
+		APIKey:                cfg.APIKey,
+		BaseURL:               cfg.BaseURL,
+		Model:                 cfg.Model,
+		Timeout:               cfg.GetTimeout(),
+		SkipPersonaPrompt:     cfg.SkipPersonaPrompt,
+		SimulateTyping:        cfg.SimulateTyping,
+		TypingSpeed:           cfg.TypingSpeed,
+		GoogleCredentialsFile: cfg.GoogleCredentialsFile,
+		GoogleUseADC:          cfg.GoogleUseADC,
+		Collections:           cfg.Collections,
+		XAIFeatures:           cfg.XAIFeatures,
+		Fallbacks:             llm.FallbacksFromConfig(cfg),
+		KeepAlive:             cfg.OllamaKeepAlive,
+		ContextLimit:          cfg.ContextLimit,
+		BuiltinTools:          cfg.OpenAIBuiltinTools,
+		NoPromptCache:         cfg.DisablePromptCache,
+	}
+}
+
 // ChangeModel changes the model for the current endpoint.
 func (a *TUIClientAdapter) ChangeModel(model string) error {
 	currentConfig := a.client.GetConfig()
@@ -1082,6 +1222,11 @@ func (a *TUIClientAdapter) ChangeModel(model string) error {
 		TypingSpeed:       currentConfig.TypingSpeed,
 		Collections:       currentConfig.Collections,
 		XAIFeatures:       currentConfig.XAIFeatures,
+		Fallbacks:         currentConfig.Fallbacks,
+		KeepAlive:         currentConfig.KeepAlive,
+		ContextLimit:      currentConfig.ContextLimit,
+		BuiltinTools:      currentConfig.BuiltinTools,
+		NoPromptCache:     currentConfig.NoPromptCache,
 	}
 
 	a.client.UpdateConfig(newConfig)
@@ -1134,6 +1279,42 @@ func (a *TUIClientAdapter) KillSubagent(id string) bool {
 	return a.subMgr.Kill(id)
 }
 
+// ListJobs implements tui.JobLister.
+func (a *TUIClientAdapter) ListJobs() []tui.JobInfo {
+	if a.shells == nil {
+		return nil
+	}
+	jobs := a.shells.Jobs()
+	infos := make([]tui.JobInfo, len(jobs))
+	for i, j := range jobs {
+		infos[i] = tui.JobInfo{
+			ID:       j.ID,
+			Command:  j.Command,
+			PID:      j.PID,
+			Status:   j.Status,
+			ExitCode: j.ExitCode,
+			Elapsed:  j.Elapsed,
+		}
+	}
+	return infos
+}
+
+// ListShells implements tui.JobLister.
+func (a *TUIClientAdapter) ListShells() []string {
+	if a.shells == nil {
+		return nil
+	}
+	return a.shells.Shells()
+}
+
+// KillJob implements tui.JobKiller.
+func (a *TUIClientAdapter) KillJob(id string) bool {
+	if a.shells == nil {
+		return false
+	}
+	return a.shells.Kill(id)
+}
+
 // ResumeSubagent implements tui.SubagentResumer.
 // It continues a previously-failed subagent from its last saved checkpoint.
 func (a *TUIClientAdapter) ResumeSubagent(ctx context.Context, checkpointID string) (string, error) {
@@ -1174,6 +1355,10 @@ func parseArgs(argsJSON string) (map[string]any, error) {
 
 // runConfigCommand handles configuration commands.
 func runConfigCommand(args []string) {
+	if len(args) > 0 && args[0] == "diff" {
+		runConfigDiff(args[1:])
+		return
+	}
 	fs := flag.NewFlagSet("config", flag.ExitOnError)
 	showConfig := fs.Bool("show", false, "Show current configuration")
 	listConfigs := fs.Bool("list", false, "List all config profiles")
@@ -1183,6 +1368,7 @@ func runConfigCommand(args []string) {
 	setURL := fs.String("set-url", "", "Set API URL")
 	setModel := fs.String("set-model", "", "Set model")
 	setMode := fs.String("set-mode", "", "Set runtime mode (classic|claw)")
+	extends := fs.String("extends", "", "Inherit from another profile and store only the overrides ('none' to stop inheriting)")
 	setClawMaxIterations := fs.Int("set-claw-max-iterations", -1, "Set claw max tool-loop iterations")
 	setContextLimit := fs.Int("set-context-limit", -1, "Set the context window in tokens (0 clears it and uses the model default). Required for local models, whose window celeste cannot know")
 	setManagementKey := fs.String("set-management-key", "", "Set xAI Management API key for Collections")
@@ -1215,6 +1401,11 @@ func runConfigCommand(args []string) {
 			os.Exit(1)
 		}
 		defaultName := config.ResolveDefaultName() // "" when no profile is flagged
+		wd, _ := os.Getwd()
+		selected, source := config.SelectProfile(wd)
+		if selected == "" {
+			selected = config.DefaultProfileName
+		}
 		fmt.Println("Available config profiles:")
 		for _, c := range configs {
 			path := config.NamedConfigPath(c)
@@ -1225,6 +1416,9 @@ func runConfigCommand(args []string) {
 			if c == defaultName {
 				marker = "  ← default (no -config needed)"
 			}
+			if c == selected && source != "" && source != "default profile" {
+				marker = fmt.Sprintf("  ← pinned for this project by %s", source)
+			}
 			fmt.Printf("  • %s (%s)%s\n", c, path, marker)
 		}
 		if defaultName == "" {
@@ -1262,9 +1456,10 @@ func runConfigCommand(args []string) {
 	// edits exactly what a no-flag `celeste chat` bills against. Falls back to the
 	// bare config.json when nothing is flagged.
 	if configName == "" {
-		if d := config.ResolveDefaultName(); d != "" {
+		wd, _ := os.Getwd()
+		if d, source := config.SelectProfile(wd); d != "" {
 			configName = d
-			fmt.Printf("(no -config given; using default profile '%s')\n", d)
+			fmt.Printf("(no -config given; using profile '%s' from %s)\n", d, source)
 		}
 	}
 
@@ -1284,6 +1479,13 @@ func runConfigCommand(args []string) {
 		// settings with defaults.
 		if err != nil && errors.Is(err, os.ErrNotExist) {
 			cfg, err = config.DefaultConfig(), nil
+			// A new child profile starts as its parent, so only what the
+			// --set-* flags change is stored as overrides.
+			if *extends != "" && *extends != "none" {
+				if cfg, err = config.LoadNamed(*extends); err == nil {
+					cfg.Default = false
+				}
+			}
 		}
 	}
 	if err != nil {
@@ -1303,7 +1505,7 @@ func runConfigCommand(args []string) {
 		fmt.Println("API key updated")
 	}
 	if *setURL != "" {
-		cfg.BaseURL = *setURL
+		cfg.SetBaseURL(*setURL)
 		changed = true
 		fmt.Printf("API URL set to: %s\n", *setURL)
 	}
@@ -1312,6 +1514,20 @@ func runConfigCommand(args []string) {
 		changed = true
 		fmt.Printf("Model set to: %s\n", *setModel)
 	}
+	if *extends != "" {
+		if configName == "" {
+			fmt.Fprintln(os.Stderr, "Error: --extends needs a named profile; config.json is the base others extend")
+			os.Exit(1)
+		}
+		if *extends == "none" {
+			cfg.Extends = ""
+			fmt.Println("Profile no longer inherits; it now stores every setting")
+		} else {
+			cfg.Extends = *extends
+			fmt.Printf("Profile now extends '%s' and stores only its overrides\n", *extends)
+		}
+		changed = true
+	}
 	if *setMode != "" {
 		mode := strings.ToLower(strings.TrimSpace(*setMode))
 		if !config.IsValidRuntimeMode(mode) {
@@ -1478,6 +1694,9 @@ func runConfigCommand(args []string) {
 		fmt.Printf("  Simulate Typing:   %v\n", cfg.SimulateTyping)
 		fmt.Printf("  Typing Speed:      %d chars/sec\n", cfg.TypingSpeed)
 		fmt.Printf("  Runtime Mode:      %s\n", cfg.RuntimeMode)
+		if cfg.Extends != "" {
+			fmt.Printf("  Extends:           %s\n", cfg.Extends)
+		}
 		if providers.OrchestratesServerSide(providers.DetectProvider(cfg.BaseURL), cfg.Model) {
 			fmt.Printf("  Planning:          %s (server-side)\n", cfg.Model)
 		} else {
@@ -1688,8 +1907,10 @@ func runSkillExecuteCommand(args []string) {
 	clAdapter := newBuiltinConfigAdapter(config.NewConfigLoader(cfg))
 	execCwd, _ := os.Getwd()
 	builtin.RegisterAll(registry, execCwd, clAdapter, nil, nil)
+	configureWebResearch(registry, cfg)
 	homeDir, _ := os.UserHomeDir()
 	_ = registry.LoadCustomTools(filepath.Join(homeDir, ".celeste", "skills"))
+	_ = registry.LoadProjectTools(execCwd)
 
 	// Execute skill
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -1741,6 +1962,7 @@ func runSkillsCommand(args []string) {
 	builtin.RegisterAll(registry, skillsCwd, clAdapter, nil, nil)
 	homeDir, _ := os.UserHomeDir()
 	_ = registry.LoadCustomTools(filepath.Join(homeDir, ".celeste", "skills"))
+	_ = registry.LoadProjectTools(skillsCwd)
 
 	// Execute skill if --exec provided
 	if *exec != "" {
@@ -1793,6 +2015,7 @@ func runSkillsCommand(args []string) {
 		registry = tools.NewRegistry()
 		builtin.RegisterAll(registry, skillsCwd, clAdapter, nil, nil)
 		_ = registry.LoadCustomTools(filepath.Join(homeDir, ".celeste", "skills"))
+		_ = registry.LoadProjectTools(skillsCwd)
 		fmt.Printf("Reloaded %d skills from disk\n", registry.Count())
 		return
 	}
@@ -1811,6 +2034,11 @@ func runSkillsCommand(args []string) {
 
 // runSessionCommand handles session-related commands.
 func runSessionCommand(args []string) {
+	if len(args) > 0 && args[0] == "export" {
+		runSessionExportCommand(args[1:])
+		return
+	}
+
 	fs := flag.NewFlagSet("session", flag.ExitOnError)
 	list := fs.Bool("list", false, "List saved sessions")
 	load := fs.String("load", "", "Load a session by ID")
@@ -1914,6 +2142,11 @@ func runSingleMessage(message string) {
 		SkipPersonaPrompt: cfg.SkipPersonaPrompt,
 		Collections:       cfg.Collections,
 		XAIFeatures:       cfg.XAIFeatures,
+		Fallbacks:         llm.FallbacksFromConfig(cfg),
+		KeepAlive:         cfg.OllamaKeepAlive,
+		ContextLimit:      cfg.ContextLimit,
+		BuiltinTools:      cfg.OpenAIBuiltinTools,
+		NoPromptCache:     cfg.DisablePromptCache,
 	}
 	client := llm.NewClient(llmConfig, nil)
 
@@ -2282,3 +2515,14 @@ func runServeCommand(args []string) {
 		os.Exit(1)
 	}
 }
+
+// configureWebResearch points web_search and web_research at the configured
+// search backend. A misconfigured backend is reported and DuckDuckGo kept.
+func configureWebResearch(registry *tools.Registry, cfg *config.Config) {
+	backend, cache, err := cfg.ResearchBackend()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: web search: %v\n", err)
+		return
+	}
+	builtin.ConfigureResearch(registry, backend, cache)
+}
@@ -0,0 +1,93 @@
+// Package media generates and edits images through a provider-neutral
+// interface, stores what it produces per session, and previews images in
+// terminals that speak the kitty, iTerm2 or sixel graphics protocols.
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ImageRequest describes an image to generate, or an edit when Image is set.
+type ImageRequest struct {
+	Prompt         string
+	Model          string // "" = the backend's default
+	Size           string // "WIDTHxHEIGHT"; "" = the backend's default
+	Count          int    // variants; 0 = 1
+	NegativePrompt string // backends without negative prompts ignore it
+	Seed           int    // 0 = random; backends without seeds ignore it
+
+	// Image is the PNG to edit. Mask, when set, is a PNG of the same size
+	// whose fully transparent pixels mark the area to change.
+	Image []byte
+	Mask  []byte
+
+	// Extra carries backend-specific parameters (e.g. Venice's "steps").
+	Extra map[string]any
+}
+
+// Image is one generated image.
+type Image struct {
+	Data          []byte
+	RevisedPrompt string // the prompt the backend actually used, when it says
+}
+
+// ImageGenerator is an image generation backend.
+type ImageGenerator interface {
+	// Name identifies the backend ("openai", "venice").
+	Name() string
+	// Generate creates images from req.Prompt.
+	Generate(ctx context.Context, req ImageRequest) ([]Image, error)
+	// Edit changes req.Image as req.Prompt describes.
+	Edit(ctx context.Context, req ImageRequest) ([]Image, error)
+}
+
+// ErrMaskUnsupported is returned by Edit when the backend cannot take a mask.
+var ErrMaskUnsupported = errors.New("this image backend does not support masks")
+
+// ImageBackend selects and configures an ImageGenerator.
+type ImageBackend struct {
+	Name    string // "openai" or "venice"
+	APIKey  string
+	BaseURL string // "" = the service's public endpoint
+	Model   string // default model; ImageRequest.Model overrides it
+}
+
+// NewImageGenerator returns the generator for b.Name.
+func NewImageGenerator(b ImageBackend) (ImageGenerator, error) {
+	if b.APIKey == "" {
+		return nil, fmt.Errorf("image backend %q has no API key", b.Name)
+	}
+	switch strings.ToLower(b.Name) {
+	case "openai":
+		return newOpenAIImages(b), nil
+	case "venice":
+		return newVeniceImages(b), nil
+	default:
+		return nil, fmt.Errorf("unknown image backend %q (want openai or venice)", b.Name)
+	}
+}
+
+// parseSize splits "WIDTHxHEIGHT". ok is false for "" and malformed sizes.
+func parseSize(size string) (width, height int, ok bool) {
+	w, h, found := strings.Cut(strings.ToLower(strings.TrimSpace(size)), "x")
+	if !found {
+		return 0, 0, false
+	}
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+func (r ImageRequest) count() int {
+	if r.Count <= 0 {
+		return 1
+	}
+	return r.Count
+}
@@ -0,0 +1,164 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media/mediatest"
+)
+
+func solidPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func TestOpenAIGenerate(t *testing.T) {
+	srv := mediatest.NewServer()
+	defer srv.Close()
+	g, err := NewImageGenerator(ImageBackend{Name: "openai", APIKey: "k", BaseURL: srv.BaseURL()})
+	require.NoError(t, err)
+
+	images, err := g.Generate(context.Background(), ImageRequest{Prompt: "a fox", Size: "32x16", Count: 2})
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+	assert.Equal(t, image.Rect(0, 0, 32, 16), decode(t, images[0].Data).Bounds())
+	assert.Equal(t, "a fox", images[0].RevisedPrompt)
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, openAIImagesModel, reqs[0].Model)
+	assert.Equal(t, 2, reqs[0].Count)
+}
+
+func TestOpenAIEditWithMask(t *testing.T) {
+	srv := mediatest.NewServer()
+	defer srv.Close()
+	g, err := NewImageGenerator(ImageBackend{Name: "openai", APIKey: "k", BaseURL: srv.BaseURL(), Model: "gpt-image-1-mini"})
+	require.NoError(t, err)
+
+	// Mask keeps the left half (opaque) and frees the right half.
+	mask := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 4; x++ {
+			mask.Set(x, y, color.NRGBA{A: 0xff})
+		}
+	}
+	var maskPNG bytes.Buffer
+	require.NoError(t, png.Encode(&maskPNG, mask))
+	white := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+
+	images, err := g.Edit(context.Background(), ImageRequest{Prompt: "paint it", Image: solidPNG(t, 8, 8, white), Mask: maskPNG.Bytes()})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	out := decode(t, images[0].Data)
+	assert.Equal(t, white, color.NRGBAModel.Convert(out.At(1, 1)), "masked area is kept")
+	assert.Equal(t, mediatest.Colour("paint it", 0), color.NRGBAModel.Convert(out.At(6, 1)), "transparent area is edited")
+
+	req := srv.Requests()[0]
+	assert.True(t, req.Edit)
+	assert.Equal(t, "gpt-image-1-mini", req.Model)
+	assert.NotEmpty(t, req.Mask)
+}
+
+func TestOpenAIError(t *testing.T) {
+	srv := mediatest.NewServer()
+	defer srv.Close()
+	g, _ := NewImageGenerator(ImageBackend{Name: "openai", APIKey: "k", BaseURL: srv.BaseURL()})
+	_, err := g.Generate(context.Background(), ImageRequest{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prompt is required")
+}
+
+func TestVeniceGenerateAndEdit(t *testing.T) {
+	srv := mediatest.NewServer()
+	defer srv.Close()
+	g, err := NewImageGenerator(ImageBackend{Name: "venice", APIKey: "k", BaseURL: srv.BaseURL(), Model: "hidream"})
+	require.NoError(t, err)
+
+	images, err := g.Generate(context.Background(), ImageRequest{Prompt: "a castle", Size: "24x12", Extra: map[string]any{"steps": float64(20)}})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, image.Rect(0, 0, 24, 12), decode(t, images[0].Data).Bounds())
+	assert.Equal(t, "hidream", srv.Requests()[0].Model)
+
+	edited, err := g.Edit(context.Background(), ImageRequest{Prompt: "at night", Image: images[0].Data})
+	require.NoError(t, err)
+	assert.Equal(t, mediatest.Colour("at night", 0), color.NRGBAModel.Convert(decode(t, edited[0].Data).At(0, 0)))
+
+	_, err = g.Edit(context.Background(), ImageRequest{Prompt: "x", Image: images[0].Data, Mask: images[0].Data})
+	assert.ErrorIs(t, err, ErrMaskUnsupported)
+}
+
+func TestNewImageGeneratorErrors(t *testing.T) {
+	_, err := NewImageGenerator(ImageBackend{Name: "openai"})
+	assert.Error(t, err, "no API key")
+	_, err = NewImageGenerator(ImageBackend{Name: "midjourney", APIKey: "k"})
+	assert.Error(t, err)
+}
+
+func TestStoreSave(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "assets"))
+	data := solidPNG(t, 5, 3, color.Black)
+
+	a, err := s.Save(data, Asset{Prompt: "p", Backend: "openai"})
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", a.MIME)
+	assert.Equal(t, 5, a.Width)
+	assert.Equal(t, 3, a.Height)
+	assert.Equal(t, "p", a.Prompt)
+	assert.Regexp(t, `001-[0-9a-f]{8}\.png$`, a.Path)
+	saved, err := os.ReadFile(a.Path)
+	require.NoError(t, err)
+	assert.Equal(t, data, saved)
+
+	b, err := s.Save(data, Asset{})
+	require.NoError(t, err)
+	assert.Regexp(t, `002-[0-9a-f]{8}\.png$`, b.Path)
+}
+
+func TestAssetsFrom(t *testing.T) {
+	assets := []Asset{{Path: "/a.png", MIME: "image/png", Width: 2}}
+	assert.Equal(t, assets, AssetsFrom(map[string]any{MetadataKey: assets}))
+
+	raw, _ := json.Marshal(map[string]any{MetadataKey: assets})
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, assets, AssetsFrom(decoded))
+
+	assert.Nil(t, AssetsFrom(nil))
+}
+
+func TestParseSize(t *testing.T) {
+	w, h, ok := parseSize("1024x1536")
+	assert.True(t, ok)
+	assert.Equal(t, 1024, w)
+	assert.Equal(t, 1536, h)
+	for _, bad := range []string{"", "auto", "0x10", "10x"} {
+		_, _, ok := parseSize(bad)
+		assert.False(t, ok, bad)
+	}
+}
@@ -0,0 +1,282 @@
+// Package mediatest is a local stand-in for the OpenAI Images and Venice
+// image APIs. It draws small deterministic PNGs instead of calling a model,
+// so image generation and editing can be tested, and prompts iterated on,
+// without a provider account. Point an image backend's base URL at
+// Server.BaseURL.
+package mediatest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultSize is the width and height of images generated without a size.
+const DefaultSize = 64
+
+// Request is one image request as the stand-in received it.
+type Request struct {
+	API    string // "openai" or "venice"
+	Edit   bool
+	Model  string
+	Prompt string
+	Width  int
+	Height int
+	Count  int
+	Image  []byte // edit input
+	Mask   []byte // edit mask
+}
+
+// Server serves both protocols under /v1.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewServer starts a stand-in. Close it when done.
+func NewServer() *Server {
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/images/generations", s.openAIGenerate)
+	mux.HandleFunc("POST /v1/images/edits", s.openAIEdit)
+	mux.HandleFunc("POST /v1/image/generate", s.veniceGenerate)
+	mux.HandleFunc("POST /v1/image/edit", s.veniceEdit)
+	s.Server = httptest.NewServer(requireAuth(mux))
+	return s
+}
+
+// BaseURL is the base URL to configure, for either protocol.
+func (s *Server) BaseURL() string { return s.URL + "/v1" }
+
+// Requests returns every request received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) record(r Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+}
+
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			writeError(w, http.StatusUnauthorized, "missing API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// --- OpenAI ---
+
+func (s *Server) openAIGenerate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+		N      int    `json:"n"`
+		Size   string `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := Request{API: "openai", Model: body.Model, Prompt: body.Prompt, Count: max(body.N, 1)}
+	req.Width, req.Height = parseSize(body.Size)
+	s.openAIReply(w, req)
+}
+
+func (s *Server) openAIEdit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := Request{API: "openai", Edit: true, Model: r.FormValue("model"), Prompt: r.FormValue("prompt")}
+	req.Count, _ = strconv.Atoi(r.FormValue("n"))
+	req.Count = max(req.Count, 1)
+	req.Width, req.Height = parseSize(r.FormValue("size"))
+	req.Image = formFile(r, "image")
+	req.Mask = formFile(r, "mask")
+	if req.Image == nil {
+		writeError(w, http.StatusBadRequest, "image is required")
+		return
+	}
+	s.openAIReply(w, req)
+}
+
+func (s *Server) openAIReply(w http.ResponseWriter, req Request) {
+	if req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, "prompt is required")
+		return
+	}
+	s.record(req)
+	var data []map[string]any
+	for i := 0; i < req.Count; i++ {
+		img, err := render(req, i)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		data = append(data, map[string]any{
+			"b64_json":       base64.StdEncoding.EncodeToString(img),
+			"revised_prompt": req.Prompt,
+		})
+	}
+	writeJSON(w, map[string]any{"created": 0, "data": data})
+}
+
+// --- Venice ---
+
+func (s *Server) veniceGenerate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model    string `json:"model"`
+		Prompt   string `json:"prompt"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
+		Variants int    `json:"variants"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := Request{API: "venice", Model: body.Model, Prompt: body.Prompt, Width: body.Width, Height: body.Height, Count: max(body.Variants, 1)}
+	if req.Width == 0 || req.Height == 0 {
+		req.Width, req.Height = DefaultSize, DefaultSize
+	}
+	s.record(req)
+	var images []string
+	for i := 0; i < req.Count; i++ {
+		img, _ := render(req, i)
+		images = append(images, base64.StdEncoding.EncodeToString(img))
+	}
+	writeJSON(w, map[string]any{"id": "img-1", "images": images})
+}
+
+func (s *Server) veniceEdit(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Prompt string `json:"prompt"`
+		Image  string `json:"image"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(body.Image)
+	if err != nil || len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "image must be base64")
+		return
+	}
+	req := Request{API: "venice", Edit: true, Prompt: body.Prompt, Count: 1, Image: data}
+	img, err := render(req, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.record(req)
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(img)
+}
+
+// --- drawing ---
+
+// Colour returns the colour the stand-in paints for prompt's variant i.
+func Colour(prompt string, i int) color.NRGBA {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", prompt, i)))
+	return color.NRGBA{R: sum[0], G: sum[1], B: sum[2], A: 0xff}
+}
+
+// render draws variant i: a solid Colour for a generation; for an edit, the
+// input image with the area the mask leaves transparent (all of it without
+// a mask) painted in Colour.
+func render(req Request, i int) ([]byte, error) {
+	fill := image.NewUniform(Colour(req.Prompt, i))
+	var out *image.NRGBA
+	if !req.Edit {
+		w, h := req.Width, req.Height
+		if w == 0 || h == 0 {
+			w, h = DefaultSize, DefaultSize
+		}
+		out = image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(out, out.Bounds(), fill, image.Point{}, draw.Src)
+	} else {
+		src, err := png.Decode(bytes.NewReader(req.Image))
+		if err != nil {
+			return nil, fmt.Errorf("image must be a PNG: %v", err)
+		}
+		out = image.NewNRGBA(src.Bounds())
+		draw.Draw(out, out.Bounds(), src, src.Bounds().Min, draw.Src)
+		var mask image.Image
+		if req.Mask != nil {
+			if mask, err = png.Decode(bytes.NewReader(req.Mask)); err != nil {
+				return nil, fmt.Errorf("mask must be a PNG: %v", err)
+			}
+			if mask.Bounds().Size() != src.Bounds().Size() {
+				return nil, fmt.Errorf("mask and image sizes differ")
+			}
+		}
+		b := out.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if mask != nil {
+					if _, _, _, a := mask.At(x-b.Min.X+mask.Bounds().Min.X, y-b.Min.Y+mask.Bounds().Min.Y).RGBA(); a != 0 {
+						continue
+					}
+				}
+				out.Set(x, y, fill.C)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func parseSize(size string) (int, int) {
+	w, h, ok := strings.Cut(size, "x")
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if !ok || err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return DefaultSize, DefaultSize
+	}
+	return width, height
+}
+
+func formFile(r *http.Request, field string) []byte {
+	f, _, err := r.FormFile(field)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	return data
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": msg}})
+}
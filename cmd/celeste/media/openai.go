@@ -0,0 +1,183 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	openAIImagesURL   = "https://api.openai.com/v1"
+	openAIImagesModel = "gpt-image-1"
+)
+
+// openAIImages speaks the OpenAI Images API: /images/generations and the
+// multipart /images/edits.
+type openAIImages struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+func newOpenAIImages(b ImageBackend) *openAIImages {
+	g := &openAIImages{apiKey: b.APIKey, baseURL: b.BaseURL, model: b.Model, client: &http.Client{Timeout: 5 * time.Minute}}
+	if g.baseURL == "" {
+		g.baseURL = openAIImagesURL
+	}
+	if g.model == "" {
+		g.model = openAIImagesModel
+	}
+	g.baseURL = strings.TrimRight(g.baseURL, "/")
+	return g
+}
+
+func (g *openAIImages) Name() string { return "openai" }
+
+func (g *openAIImages) modelFor(req ImageRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return g.model
+}
+
+func (g *openAIImages) Generate(ctx context.Context, req ImageRequest) ([]Image, error) {
+	model := g.modelFor(req)
+	payload := map[string]any{"model": model, "prompt": req.Prompt, "n": req.count()}
+	if req.Size != "" {
+		payload["size"] = req.Size
+	}
+	// gpt-image models always return base64 and reject response_format;
+	// DALL·E returns URLs unless asked.
+	if strings.HasPrefix(model, "dall-e") {
+		payload["response_format"] = "b64_json"
+	}
+	for k, v := range req.Extra {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return g.do(ctx, "/images/generations", "application/json", bytes.NewReader(body))
+}
+
+func (g *openAIImages) Edit(ctx context.Context, req ImageRequest) ([]Image, error) {
+	if len(req.Image) == 0 {
+		return nil, fmt.Errorf("edit needs an input image")
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	fields := map[string]string{"model": g.modelFor(req), "prompt": req.Prompt, "n": strconv.Itoa(req.count())}
+	if req.Size != "" {
+		fields["size"] = req.Size
+	}
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	files := map[string][]byte{"image": req.Image}
+	if len(req.Mask) > 0 {
+		files["mask"] = req.Mask
+	}
+	for field, data := range files {
+		part, err := w.CreateFormFile(field, field+".png")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return g.do(ctx, "/images/edits", w.FormDataContentType(), &buf)
+}
+
+func (g *openAIImages) do(ctx context.Context, path, contentType string, body io.Reader) ([]Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openai images: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("openai images: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			msg = apiErr.Error.Message
+		}
+		return nil, fmt.Errorf("openai images: %s: %s", resp.Status, msg)
+	}
+
+	var result struct {
+		Data []struct {
+			B64JSON       string `json:"b64_json"`
+			URL           string `json:"url"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("openai images: parse response: %w", err)
+	}
+	images := make([]Image, 0, len(result.Data))
+	for _, d := range result.Data {
+		img := Image{RevisedPrompt: d.RevisedPrompt}
+		switch {
+		case d.B64JSON != "":
+			if img.Data, err = base64.StdEncoding.DecodeString(d.B64JSON); err != nil {
+				return nil, fmt.Errorf("openai images: decode image: %w", err)
+			}
+		case d.URL != "":
+			if img.Data, err = g.download(ctx, d.URL); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("openai images: no image data in response")
+	}
+	return images, nil
+}
+
+func (g *openAIImages) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openai images: download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai images: download: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
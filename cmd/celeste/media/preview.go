@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+)
+
+// Protocol is a terminal inline-graphics protocol.
+type Protocol int
+
+const (
+	ProtocolNone Protocol = iota
+	ProtocolKitty
+	ProtocolITerm
+	ProtocolSixel
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolKitty:
+		return "kitty"
+	case ProtocolITerm:
+		return "iterm"
+	case ProtocolSixel:
+		return "sixel"
+	default:
+		return "none"
+	}
+}
+
+// DetectProtocol guesses the terminal's graphics protocol from the
+// environment. CELESTE_IMAGE_PROTOCOL (kitty, iterm, sixel, none) overrides
+// the guess, which is also how to opt in under tmux: tmux swallows the
+// escape sequences unless allow-passthrough is on.
+func DetectProtocol() Protocol {
+	return detectProtocol(os.Getenv)
+}
+
+func detectProtocol(getenv func(string) string) Protocol {
+	switch strings.ToLower(getenv("CELESTE_IMAGE_PROTOCOL")) {
+	case "kitty":
+		return ProtocolKitty
+	case "iterm", "iterm2":
+		return ProtocolITerm
+	case "sixel":
+		return ProtocolSixel
+	case "none":
+		return ProtocolNone
+	}
+	if getenv("TMUX") != "" {
+		return ProtocolNone
+	}
+	term, program := getenv("TERM"), getenv("TERM_PROGRAM")
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "", term == "xterm-kitty", term == "xterm-ghostty", program == "ghostty":
+		return ProtocolKitty
+	case program == "iTerm.app", program == "WezTerm", getenv("LC_TERMINAL") == "iTerm2":
+		return ProtocolITerm
+	case strings.Contains(term, "sixel"), strings.HasPrefix(term, "foot"), term == "mlterm":
+		return ProtocolSixel
+	}
+	return ProtocolNone
+}
+
+// WritePreview writes an image (PNG, JPEG or GIF) to w as an inline
+// picture about cols terminal cells wide, followed by a newline. It writes
+// nothing for ProtocolNone.
+func WritePreview(w io.Writer, data []byte, p Protocol, cols int) error {
+	if cols <= 0 {
+		cols = 60
+	}
+	switch p {
+	case ProtocolKitty:
+		return writeKitty(w, data, cols)
+	case ProtocolITerm:
+		_, err := fmt.Fprintf(w, "\x1b]1337;File=inline=1;size=%d;width=%d;preserveAspectRatio=1:%s\a\n",
+			len(data), cols, base64.StdEncoding.EncodeToString(data))
+		return err
+	case ProtocolSixel:
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("media: decode image: %w", err)
+		}
+		// Sixel works in pixels; assume the common ~10px cell width.
+		if err := writeSixel(w, img, cols*10); err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "\n")
+		return err
+	default:
+		return nil
+	}
+}
+
+// kittyChunk is the largest base64 payload the kitty protocol accepts in
+// one escape sequence.
+const kittyChunk = 4096
+
+// writeKitty transmits and displays a PNG with the kitty graphics protocol
+// (a=T, f=100), re-encoding other formats to PNG first. q=2 keeps the
+// terminal from answering on stdin.
+func writeKitty(w io.Writer, data []byte, cols int) error {
+	if !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("media: decode image: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	payload := base64.StdEncoding.EncodeToString(data)
+	for first := true; first || payload != ""; first = false {
+		chunk := payload
+		if len(chunk) > kittyChunk {
+			chunk = chunk[:kittyChunk]
+		}
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		var err error
+		if first {
+			_, err = fmt.Fprintf(w, "\x1b_Ga=T,f=100,q=2,c=%d,m=%d;%s\x1b\\", cols, more, chunk)
+		} else {
+			_, err = fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
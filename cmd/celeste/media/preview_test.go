@@ -0,0 +1,77 @@
+package media
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectProtocol(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want Protocol
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, ProtocolKitty},
+		{map[string]string{"KITTY_WINDOW_ID": "1", "TERM": "xterm-256color"}, ProtocolKitty},
+		{map[string]string{"TERM_PROGRAM": "ghostty"}, ProtocolKitty},
+		{map[string]string{"TERM_PROGRAM": "iTerm.app"}, ProtocolITerm},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, ProtocolITerm},
+		{map[string]string{"TERM": "foot"}, ProtocolSixel},
+		{map[string]string{"TERM": "xterm-256color"}, ProtocolNone},
+		{map[string]string{"TERM": "xterm-kitty", "TMUX": "/tmp/tmux"}, ProtocolNone},
+		{map[string]string{"TMUX": "/tmp/tmux", "CELESTE_IMAGE_PROTOCOL": "sixel"}, ProtocolSixel},
+		{map[string]string{"TERM": "xterm-kitty", "CELESTE_IMAGE_PROTOCOL": "none"}, ProtocolNone},
+	}
+	for _, tt := range tests {
+		got := detectProtocol(func(k string) string { return tt.env[k] })
+		assert.Equal(t, tt.want, got, "%v", tt.env)
+	}
+}
+
+func TestWritePreviewKittyChunks(t *testing.T) {
+	// Noise-free but large enough to need several chunks once base64'd.
+	data := solidPNG(t, 300, 300, color.NRGBA{R: 1, A: 0xff})
+	data = append(data, make([]byte, 8000)...) // trailing bytes decoders ignore
+	var buf bytes.Buffer
+	require.NoError(t, WritePreview(&buf, data, ProtocolKitty, 40))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "\x1b_Ga=T,f=100,q=2,c=40,m=1;"))
+	assert.Contains(t, out, "\x1b_Gm=0;")
+	for _, seq := range strings.Split(out, "\x1b\\") {
+		if _, payload, ok := strings.Cut(seq, ";"); ok {
+			assert.LessOrEqual(t, len(payload), kittyChunk)
+		}
+	}
+}
+
+func TestWritePreviewITerm(t *testing.T) {
+	data := solidPNG(t, 2, 2, color.White)
+	var buf bytes.Buffer
+	require.NoError(t, WritePreview(&buf, data, ProtocolITerm, 20))
+	assert.True(t, strings.HasPrefix(buf.String(), "\x1b]1337;File=inline=1;"))
+	assert.Contains(t, buf.String(), "width=20;")
+}
+
+func TestWritePreviewSixel(t *testing.T) {
+	data := solidPNG(t, 400, 12, color.NRGBA{R: 0xff, A: 0xff})
+	var buf bytes.Buffer
+	require.NoError(t, WritePreview(&buf, data, ProtocolSixel, 10))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "\x1bP0;1;0q\"1;1;100;3"), "scaled to 10 cells of 10px")
+	// Pure red is colour (5,0,0) in the cube.
+	assert.Contains(t, out, "#180;2;100;0;0")
+	assert.Contains(t, out, "!100")
+	assert.True(t, strings.HasSuffix(out, "\x1b\\\n"))
+}
+
+func TestWritePreviewNone(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WritePreview(&buf, []byte("x"), ProtocolNone, 10))
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,108 @@
+package media
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+)
+
+// writeSixel encodes img as sixel graphics no wider than maxWidth pixels,
+// scaling down with nearest-neighbour sampling and quantising to a fixed
+// 6×6×6 colour cube. Mostly transparent pixels are left unpainted.
+func writeSixel(w io.Writer, img image.Image, maxWidth int) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width == 0 || height == 0 {
+		return nil
+	}
+	if width > maxWidth {
+		height = height * maxWidth / width
+		width = maxWidth
+		if height == 0 {
+			height = 1
+		}
+	}
+
+	// Palette index per pixel; -1 = transparent.
+	pixels := make([]int, width*height)
+	used := make([]bool, 216)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx := b.Min.X + x*b.Dx()/width
+			sy := b.Min.Y + y*b.Dy()/height
+			r, g, bl, a := img.At(sx, sy).RGBA()
+			idx := -1
+			if a >= 0x8000 {
+				idx = cubeLevel(r)*36 + cubeLevel(g)*6 + cubeLevel(bl)
+				used[idx] = true
+			}
+			pixels[y*width+x] = idx
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	// P2=1: pixels no colour paints keep the background.
+	fmt.Fprintf(bw, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for i, ok := range used {
+		if ok {
+			fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, i/36*20, i/6%6*20, i%6*20)
+		}
+	}
+	row := make([]byte, width)
+	for top := 0; top < height; top += 6 {
+		first := true
+		for c := range used {
+			if !used[c] {
+				continue
+			}
+			any := false
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if pixels[(top+dy)*width+x] == c {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = 63 + bits
+				any = any || bits != 0
+			}
+			if !any {
+				continue
+			}
+			if !first {
+				bw.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(bw, "#%d", c)
+			writeSixelRun(bw, row)
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
+
+// cubeLevel maps a 16-bit channel to one of six levels.
+func cubeLevel(v uint32) int {
+	return int((v*5 + 0x7fff) / 0xffff)
+}
+
+// writeSixelRun writes one colour's row of sixels, run-length encoding
+// repeats.
+func writeSixelRun(w *bufio.Writer, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, row[i])
+		} else {
+			for k := 0; k < n; k++ {
+				w.WriteByte(row[i])
+			}
+		}
+		i = j
+	}
+}
@@ -0,0 +1,123 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // register decoders for image.DecodeConfig
+	_ "image/jpeg" // register decoders for image.DecodeConfig
+	_ "image/png"  // register decoders for image.DecodeConfig
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MetadataKey is the tools.ToolResult metadata key under which a tool
+// reports the assets it produced, as []Asset.
+const MetadataKey = "assets"
+
+// Asset is a generated file and the reference to it that chat messages and
+// session logs keep. The file itself stays in the session's asset directory.
+type Asset struct {
+	Path    string `json:"path"`
+	MIME    string `json:"mime"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Prompt  string `json:"prompt,omitempty"`
+	Backend string `json:"backend,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Source  string `json:"source,omitempty"` // the image an edit started from
+}
+
+// Store writes generated assets into one directory.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore returns a Store that writes into dir, creating it on first save.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// SessionStore returns the Store for a chat session:
+// ~/.celeste/assets/<sessionID>.
+func SessionStore(sessionID string) (*Store, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(filepath.Join(home, ".celeste", "assets", sessionID)), nil
+}
+
+// Dir returns the directory assets are written to.
+func (s *Store) Dir() string { return s.dir }
+
+// Save writes data as the store's next asset and returns a's reference
+// completed with the path, MIME type and, for images, dimensions. Files are
+// numbered in the order they were made: 001-<hash>.png, 002-<hash>.png, ...
+func (s *Store) Save(data []byte, a Asset) (Asset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return Asset{}, fmt.Errorf("media: create asset dir: %w", err)
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return Asset{}, fmt.Errorf("media: read asset dir: %w", err)
+	}
+
+	a.MIME = http.DetectContentType(data)
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		a.Width, a.Height = cfg.Width, cfg.Height
+	}
+	sum := sha256.Sum256(data)
+	name := fmt.Sprintf("%03d-%s%s", len(entries)+1, hex.EncodeToString(sum[:4]), extensionFor(a.MIME))
+	a.Path = filepath.Join(s.dir, name)
+	if err := os.WriteFile(a.Path, data, 0644); err != nil {
+		return Asset{}, fmt.Errorf("media: write asset: %w", err)
+	}
+	return a, nil
+}
+
+func extensionFor(mime string) string {
+	switch mime {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".bin"
+	}
+}
+
+// AssetsFrom returns the assets recorded in tool result metadata. It accepts
+// the []Asset a tool stores and the []any the same value becomes after a
+// JSON round trip.
+func AssetsFrom(metadata map[string]any) []Asset {
+	switch v := metadata[MetadataKey].(type) {
+	case []Asset:
+		return v
+	case []any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var assets []Asset
+		if json.Unmarshal(raw, &assets) != nil {
+			return nil
+		}
+		return assets
+	default:
+		return nil
+	}
+}
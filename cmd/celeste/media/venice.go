@@ -0,0 +1,76 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/venice"
+)
+
+const veniceImagesURL = "https://api.venice.ai/api/v1"
+
+// veniceImages generates through Venice's /image/generate and edits through
+// /image/edit.
+type veniceImages struct {
+	config venice.Config
+}
+
+func newVeniceImages(b ImageBackend) *veniceImages {
+	cfg := venice.Config{APIKey: b.APIKey, BaseURL: b.BaseURL, Model: b.Model}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = veniceImagesURL
+	}
+	return &veniceImages{config: cfg}
+}
+
+func (g *veniceImages) Name() string { return "venice" }
+
+func (g *veniceImages) Generate(ctx context.Context, req ImageRequest) ([]Image, error) {
+	params := map[string]interface{}{"variants": req.count()}
+	for k, v := range req.Extra {
+		// Tool arguments arrive as JSON numbers; the Venice client wants ints.
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			v = int(f)
+		}
+		params[k] = v
+	}
+	if req.Model != "" {
+		params["model"] = req.Model
+	}
+	if w, h, ok := parseSize(req.Size); ok {
+		params["width"], params["height"] = w, h
+	}
+	if req.NegativePrompt != "" {
+		params["negative_prompt"] = req.NegativePrompt
+	}
+	if req.Seed != 0 {
+		params["seed"] = req.Seed
+	}
+	data, err := venice.GenerateImageData(ctx, g.config, req.Prompt, params)
+	if err != nil {
+		return nil, fmt.Errorf("venice images: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("venice images: no image data in response")
+	}
+	images := make([]Image, len(data))
+	for i, d := range data {
+		images[i] = Image{Data: d}
+	}
+	return images, nil
+}
+
+func (g *veniceImages) Edit(ctx context.Context, req ImageRequest) ([]Image, error) {
+	if len(req.Image) == 0 {
+		return nil, fmt.Errorf("edit needs an input image")
+	}
+	if len(req.Mask) > 0 {
+		return nil, ErrMaskUnsupported
+	}
+	data, err := venice.EditImage(ctx, g.config, req.Image, req.Prompt)
+	if err != nil {
+		return nil, fmt.Errorf("venice images: %w", err)
+	}
+	return []Image{{Data: data}}, nil
+}
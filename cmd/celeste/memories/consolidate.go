@@ -0,0 +1,343 @@
+package memories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	ctxmgr "github.com/whykusanagi/celeste-cli/cmd/celeste/context"
+)
+
+// SourceExtracted marks memories proposed by the model rather than saved by
+// the user or the save_memory tool.
+const SourceExtracted = "extracted"
+
+// Similarity thresholds for consolidating a candidate into the existing
+// store. Duplicates are judged on Jaccard, merges on the overlap coefficient
+// (shared tokens over the smaller set) so a longer restatement that adds
+// detail to a short memory still folds into it.
+const (
+	duplicateThreshold = 0.7 // Jaccard: same fact restated, drop the candidate
+	mergeThreshold     = 0.6 // overlap: same topic, fold the candidate into the existing memory
+	minMergeOverlap    = 3   // shared tokens required before a merge is considered
+)
+
+// maxExtractionTranscript caps the transcript sent to the model. The tail is
+// kept because corrections and decisions tend to come late in a session.
+const maxExtractionTranscript = 60000
+
+// ExtractionMessage is one turn of a finished session.
+type ExtractionMessage struct {
+	Role    string
+	Content string
+}
+
+const extractionSystemPrompt = `You curate long-term memory for a coding assistant. Read a finished session and propose memories worth keeping for future sessions in the same project.
+
+Memory types:
+- user: who the user is, their role, expertise and preferences
+- feedback: corrections or guidance on how the assistant should work, with the reason
+- project: decisions, constraints and ongoing work not derivable from the code or git history
+- reference: pointers to external resources (URLs, dashboards, tickets)
+
+Only propose durable facts. Skip anything that only mattered to this session, anything already recorded in the code, and anything already covered by an existing memory unless the session adds to it.
+
+If a proposal contradicts an existing memory, set "contradicts" to that memory's name.
+
+Reply with a JSON array only, no prose:
+[{"type": "feedback", "name": "short-kebab-slug", "description": "one-line summary", "content": "the fact, with the why", "contradicts": ""}]
+Reply with [] if nothing is worth remembering.`
+
+// ProposeMemories asks the model for memories worth keeping from a finished
+// session. Existing memories are listed in the prompt so the model can skip
+// what is already known and name what it contradicts. Proposals with an
+// unknown type or no content are dropped.
+func ProposeMemories(ctx context.Context, client ctxmgr.LLMClient, messages []ExtractionMessage, existing []*Memory) ([]MemoryCandidate, error) {
+	var transcript strings.Builder
+	for _, m := range messages {
+		if strings.TrimSpace(m.Content) == "" || (m.Role != "user" && m.Role != "assistant") {
+			continue
+		}
+		fmt.Fprintf(&transcript, "[%s]: %s\n\n", m.Role, m.Content)
+	}
+	if transcript.Len() == 0 {
+		return nil, nil
+	}
+	text := transcript.String()
+	if len(text) > maxExtractionTranscript {
+		text = "...\n" + text[len(text)-maxExtractionTranscript:]
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Existing memories:\n")
+	if len(existing) == 0 {
+		prompt.WriteString("(none)\n")
+	}
+	for _, m := range existing {
+		fmt.Fprintf(&prompt, "- %s [%s]: %s\n", m.Name, m.Type, m.Description)
+	}
+	prompt.WriteString("\nSession:\n\n")
+	prompt.WriteString(text)
+
+	reply, err := client.SendSummarizationRequest(ctx, extractionSystemPrompt, prompt.String())
+	if err != nil {
+		return nil, fmt.Errorf("memory extraction request failed: %w", err)
+	}
+	return parseProposals(reply)
+}
+
+// parseProposals decodes the model's JSON reply, tolerating code fences and
+// surrounding prose.
+func parseProposals(reply string) ([]MemoryCandidate, error) {
+	start := strings.Index(reply, "[")
+	end := strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("memory extraction: reply is not a JSON array")
+	}
+	var raw []MemoryCandidate
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("memory extraction: parse reply: %w", err)
+	}
+
+	var out []MemoryCandidate
+	for _, c := range raw {
+		c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+		c.Content = strings.TrimSpace(c.Content)
+		c.Name = sanitizeFilename(c.Name)
+		if !IsValidType(c.Type) || c.Content == "" {
+			continue
+		}
+		if c.Name == "" {
+			c.Name = sanitizeFilename(truncateWords(c.Content, 5))
+		}
+		if c.Description == "" {
+			c.Description = truncateWords(c.Content, 12)
+		}
+		c.Reason = "proposed by model extraction"
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// ConsolidationReport summarizes what Consolidate did with each candidate.
+type ConsolidationReport struct {
+	Added      []string // new memories
+	Merged     []string // existing memories that absorbed a candidate
+	Duplicates []string // candidates dropped as restatements of an existing memory
+	Conflicts  []string // new memories saved pending review because they contradict one
+}
+
+// Summary renders the report as a single line.
+func (r *ConsolidationReport) Summary() string {
+	return fmt.Sprintf("%d added, %d merged, %d duplicates skipped, %d conflicts flagged for review",
+		len(r.Added), len(r.Merged), len(r.Duplicates), len(r.Conflicts))
+}
+
+// Consolidate folds candidates into the store. Each one is compared with the
+// existing memories by token-set similarity: near-identical candidates are
+// dropped, close ones of the same type are merged into the existing memory,
+// and the rest are saved as new memories. A candidate that contradicts an
+// existing memory is saved with Conflicts set so the memory manager can ask
+// the user which one to keep; until then retrieval leaves it out. With
+// dryRun the report is computed but nothing is written.
+func Consolidate(store *Store, candidates []MemoryCandidate, project string, dryRun bool) (*ConsolidationReport, error) {
+	existing, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*Memory, len(existing))
+	for _, m := range existing {
+		byName[m.Name] = m
+	}
+
+	report := &ConsolidationReport{}
+	var index *Index
+	if !dryRun {
+		if index, err = LoadIndex(filepath.Join(store.BaseDir(), "MEMORY.md")); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range candidates {
+		if target := byName[sanitizeFilename(c.Contradicts)]; c.Contradicts != "" && target != nil {
+			mem := NewMemory(uniqueName(c.Name, byName), c.Description, c.Type, project, c.Content)
+			mem.Source = SourceExtracted
+			mem.Conflicts = target.Name
+			if err := saveIndexed(store, index, mem); err != nil {
+				return report, err
+			}
+			byName[mem.Name] = mem
+			existing = append(existing, mem)
+			report.Conflicts = append(report.Conflicts, mem.Name)
+			continue
+		}
+
+		best, sim := mostSimilar(c, existing)
+		switch {
+		case best != nil && sim.jaccard >= duplicateThreshold:
+			report.Duplicates = append(report.Duplicates, c.Name)
+		case best != nil && sim.overlap >= mergeThreshold && sim.shared >= minMergeOverlap && best.Type == c.Type:
+			if !strings.Contains(best.Content, c.Content) {
+				best.Content = strings.TrimSpace(best.Content + "\n\n" + c.Content)
+			}
+			if err := saveIndexed(store, index, best); err != nil {
+				return report, err
+			}
+			report.Merged = append(report.Merged, best.Name)
+		default:
+			mem := NewMemory(uniqueName(c.Name, byName), c.Description, c.Type, project, c.Content)
+			mem.Source = SourceExtracted
+			if err := saveIndexed(store, index, mem); err != nil {
+				return report, err
+			}
+			byName[mem.Name] = mem
+			existing = append(existing, mem)
+			report.Added = append(report.Added, mem.Name)
+		}
+	}
+
+	if index != nil {
+		if err := index.Save(); err != nil {
+			return report, fmt.Errorf("failed to save memory index: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// Conflict resolutions offered by the memory manager.
+const (
+	ResolveReplace  = "replace"   // keep the new memory, delete the one it contradicts
+	ResolveKeepBoth = "keep-both" // keep both, clearing the conflict flag
+	ResolveDiscard  = "discard"   // delete the new memory
+)
+
+// ResolveConflict settles a memory saved with Conflicts set.
+func ResolveConflict(store *Store, name, resolution string) error {
+	mem, err := store.Load(name)
+	if err != nil {
+		return err
+	}
+	if mem.Conflicts == "" {
+		return fmt.Errorf("memory '%s' has no pending conflict", name)
+	}
+	index, err := LoadIndex(filepath.Join(store.BaseDir(), "MEMORY.md"))
+	if err != nil {
+		return err
+	}
+
+	switch resolution {
+	case ResolveReplace:
+		if err := store.Delete(mem.Conflicts); err != nil {
+			return err
+		}
+		_ = index.Remove(mem.Conflicts)
+		mem.Conflicts = ""
+		if err := store.Save(mem); err != nil {
+			return err
+		}
+	case ResolveKeepBoth:
+		mem.Conflicts = ""
+		if err := store.Save(mem); err != nil {
+			return err
+		}
+	case ResolveDiscard:
+		if err := store.Delete(mem.Name); err != nil {
+			return err
+		}
+		_ = index.Remove(mem.Name)
+	default:
+		return fmt.Errorf("unknown conflict resolution '%s'", resolution)
+	}
+	return index.Save()
+}
+
+// similarity compares two token sets.
+type similarity struct {
+	jaccard float64 // shared / union
+	overlap float64 // shared / smaller set
+	shared  int
+}
+
+// mostSimilar returns the existing memory closest to c by Jaccard, breaking
+// ties on overlap. Memories still awaiting conflict review are not merge
+// targets.
+func mostSimilar(c MemoryCandidate, existing []*Memory) (*Memory, similarity) {
+	want := tokenSetOf(c.Name + " " + c.Description + " " + c.Content)
+	var best *Memory
+	var bestSim similarity
+	for _, m := range existing {
+		if m.Conflicts != "" {
+			continue
+		}
+		sim := compareSets(want, tokenSetOf(memoryText(m)))
+		if sim.jaccard > bestSim.jaccard || (sim.jaccard == bestSim.jaccard && sim.overlap > bestSim.overlap) {
+			best, bestSim = m, sim
+		}
+	}
+	return best, bestSim
+}
+
+func tokenSetOf(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range retrievalTokens(text) {
+		set[t] = true
+	}
+	return set
+}
+
+// compareSets computes exact set similarity. Consolidation compares one
+// candidate with a handful of memories, so the MinHash estimate the
+// retriever uses is unnecessary here.
+func compareSets(a, b map[string]bool) similarity {
+	if len(a) == 0 || len(b) == 0 {
+		return similarity{}
+	}
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	smaller := len(a)
+	if len(b) < smaller {
+		smaller = len(b)
+	}
+	return similarity{
+		jaccard: float64(shared) / float64(len(a)+len(b)-shared),
+		overlap: float64(shared) / float64(smaller),
+		shared:  shared,
+	}
+}
+
+func saveIndexed(store *Store, index *Index, mem *Memory) error {
+	if index == nil {
+		return nil // dry run
+	}
+	if err := store.Save(mem); err != nil {
+		return err
+	}
+	return index.Add(IndexEntry{Name: mem.Name, File: sanitizeFilename(mem.Name) + ".md", Description: mem.Description})
+}
+
+// uniqueName suffixes name until it does not collide with an existing memory.
+func uniqueName(name string, taken map[string]*Memory) string {
+	if taken[name] == nil {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if taken[candidate] == nil {
+			return candidate
+		}
+	}
+}
+
+func truncateWords(s string, n int) string {
+	words := strings.Fields(s)
+	if len(words) > n {
+		words = words[:n]
+	}
+	return strings.Join(words, " ")
+}
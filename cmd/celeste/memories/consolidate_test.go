@@ -0,0 +1,124 @@
+package memories
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExtractionClient struct {
+	reply      string
+	userPrompt string
+}
+
+func (f *fakeExtractionClient) SendSummarizationRequest(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	f.userPrompt = userPrompt
+	return f.reply, nil
+}
+
+func TestProposeMemoriesParsesReply(t *testing.T) {
+	client := &fakeExtractionClient{reply: "Here you go:\n```json\n" + `[
+		{"type": "feedback", "name": "No Mocks In Integration Tests", "description": "Use a real database", "content": "Integration tests must hit a real database; mocks hid a broken migration."},
+		{"type": "bogus", "name": "x", "content": "dropped"},
+		{"type": "project", "name": "empty", "content": ""}
+	]` + "\n```"}
+	existing := []*Memory{NewMemory("release-process", "Releases are cut from main", "project", "", "")}
+
+	got, err := ProposeMemories(context.Background(), client, []ExtractionMessage{
+		{Role: "user", Content: "stop mocking the database"},
+		{Role: "tool", Content: "ignored"},
+		{Role: "assistant", Content: "Understood."},
+	}, existing)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "no-mocks-in-integration-tests", got[0].Name)
+	assert.Contains(t, client.userPrompt, "release-process")
+	assert.NotContains(t, client.userPrompt, "ignored")
+}
+
+func TestProposeMemoriesRejectsProse(t *testing.T) {
+	_, err := ProposeMemories(context.Background(), &fakeExtractionClient{reply: "nothing to add"},
+		[]ExtractionMessage{{Role: "user", Content: "hi"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestConsolidateDedupesMergesAndFlagsConflicts(t *testing.T) {
+	store := NewStoreWithBase(t.TempDir())
+	require.NoError(t, store.Save(NewMemory("tabs-in-makefiles", "Indent Makefile recipes with tabs", "feedback", "", "Indent Makefile recipes with tabs.")))
+	require.NoError(t, store.Save(NewMemory("postgres-version", "Production runs Postgres 14", "project", "", "Production runs Postgres 14.")))
+
+	report, err := Consolidate(store, []MemoryCandidate{
+		{Type: "feedback", Name: "makefile-tabs", Description: "Indent Makefile recipes with tabs", Content: "Indent Makefile recipes with tabs."},
+		{Type: "feedback", Name: "makefile-tab-width", Description: "Makefile recipes use tabs, shown at width 8", Content: "Makefile recipes use tabs; editors should render them at width 8."},
+		{Type: "project", Name: "postgres-16", Description: "Production runs Postgres 16", Content: "Production was upgraded to Postgres 16.", Contradicts: "postgres-version"},
+		{Type: "reference", Name: "grafana-board", Description: "Latency dashboard", Content: "Latency dashboard lives at grafana.internal/d/latency."},
+	}, "/proj", false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"makefile-tabs"}, report.Duplicates)
+	assert.Equal(t, []string{"tabs-in-makefiles"}, report.Merged)
+	assert.Equal(t, []string{"postgres-16"}, report.Conflicts)
+	assert.Equal(t, []string{"grafana-board"}, report.Added)
+
+	merged, err := store.Load("tabs-in-makefiles")
+	require.NoError(t, err)
+	assert.Contains(t, merged.Content, "width 8")
+
+	flagged, err := store.Load("postgres-16")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres-version", flagged.Conflicts)
+	assert.Equal(t, SourceExtracted, flagged.Source)
+
+	// Pending conflicts are kept out of retrieval.
+	all, _ := store.List()
+	assert.Equal(t, len(all)-1, NewRetriever(all).Len())
+
+	idx, err := LoadIndex(filepath.Join(store.BaseDir(), "MEMORY.md"))
+	require.NoError(t, err)
+	assert.Len(t, idx.Entries(), 3)
+}
+
+func TestConsolidateDryRunWritesNothing(t *testing.T) {
+	store := NewStoreWithBase(t.TempDir())
+	report, err := Consolidate(store, []MemoryCandidate{
+		{Type: "user", Name: "go-expert", Description: "User is a Go expert", Content: "Deep Go experience."},
+	}, "", true)
+	require.NoError(t, err)
+	assert.Len(t, report.Added, 1)
+
+	all, _ := store.List()
+	assert.Empty(t, all)
+}
+
+func TestResolveConflict(t *testing.T) {
+	setup := func(t *testing.T) *Store {
+		store := NewStoreWithBase(t.TempDir())
+		require.NoError(t, store.Save(NewMemory("old", "Old fact", "project", "", "old")))
+		mem := NewMemory("new", "New fact", "project", "", "new")
+		mem.Conflicts = "old"
+		require.NoError(t, store.Save(mem))
+		return store
+	}
+
+	store := setup(t)
+	require.NoError(t, ResolveConflict(store, "new", ResolveReplace))
+	_, err := store.Load("old")
+	assert.Error(t, err)
+	mem, err := store.Load("new")
+	require.NoError(t, err)
+	assert.Empty(t, mem.Conflicts)
+
+	store = setup(t)
+	require.NoError(t, ResolveConflict(store, "new", ResolveDiscard))
+	_, err = store.Load("new")
+	assert.Error(t, err)
+	_, err = store.Load("old")
+	assert.NoError(t, err)
+
+	store = setup(t)
+	require.NoError(t, ResolveConflict(store, "new", ResolveKeepBoth))
+	assert.Error(t, ResolveConflict(store, "new", ResolveKeepBoth), "conflict already settled")
+}
@@ -6,9 +6,12 @@ import (
 
 // MemoryCandidate represents a potential memory extracted from conversation.
 type MemoryCandidate struct {
-	Type    string `json:"type"`    // feedback, project, user, reference
-	Content string `json:"content"` // the extracted content
-	Reason  string `json:"reason"`  // why this was flagged
+	Type        string `json:"type"`                  // feedback, project, user, reference
+	Content     string `json:"content"`               // the extracted content
+	Reason      string `json:"reason"`                // why this was flagged
+	Name        string `json:"name,omitempty"`        // proposed slug (model extraction only)
+	Description string `json:"description,omitempty"` // one-line summary (model extraction only)
+	Contradicts string `json:"contradicts,omitempty"` // existing memory this one contradicts, if any
 }
 
 // correctionPatterns match user corrections that should become feedback memories.
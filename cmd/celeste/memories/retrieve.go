@@ -0,0 +1,341 @@
+package memories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/codegraph"
+	ctxmgr "github.com/whykusanagi/celeste-cli/cmd/celeste/context"
+)
+
+// Retrieval defaults, used when RetrieveOptions leaves a field at zero.
+const (
+	DefaultRetrieveTopK      = 8
+	DefaultRetrieveMaxTokens = 1500
+
+	// minRetrieveJaccard matches the codegraph semantic search floor: below
+	// it a MinHash estimate is indistinguishable from noise.
+	minRetrieveJaccard = 0.05
+)
+
+// RetrieveOptions bounds what Retrieve selects for injection.
+type RetrieveOptions struct {
+	TopK      int      // max memories injected
+	MaxTokens int      // max estimated tokens across injected memories
+	Files     []string // workspace paths in play this turn; their names join the query
+}
+
+// ScoredMemory is one candidate memory with the signals that ranked it.
+type ScoredMemory struct {
+	Memory   *Memory
+	Rank     int      // 1-based position in the reciprocal-rank fusion of BM25 and Jaccard
+	BM25     float64  // BM25 score of the memory against the query
+	Jaccard  float64  // MinHash Jaccard estimate between query and memory tokens
+	Matched  []string // query terms that occur in the memory
+	Tokens   int      // estimated tokens the memory costs when injected
+	Included bool
+	Reason   string // why a candidate was left out, empty when Included
+}
+
+// Retrieval is the outcome of ranking a store's memories against one query.
+// It is kept around after injection so /memories why can explain it.
+type Retrieval struct {
+	Query      string
+	Files      []string
+	Total      int            // memories considered
+	Candidates []ScoredMemory // memories matching the query at all, best first
+	Tokens     int            // estimated tokens of the included memories
+	TopK       int
+	MaxTokens  int
+}
+
+// Retriever ranks memories against a prompt with the same BM25 and MinHash
+// machinery the code graph uses for symbols, so only the handful relevant to
+// the current request are injected instead of the whole index.
+type Retriever struct {
+	memories []*Memory
+	tf       []map[string]int
+	lengths  []int
+	sigs     []codegraph.MinHashSignature
+	idf      map[string]float64
+	avgLen   float64
+	hasher   *codegraph.MinHasher
+}
+
+// NewRetriever builds the corpus statistics for mems. It is cheap enough to
+// rebuild every turn, which keeps memories saved mid-session retrievable.
+// Memories awaiting conflict review are left out until the user settles them,
+// as are memories verification found broken.
+func NewRetriever(all []*Memory) *Retriever {
+	var mems []*Memory
+	for _, m := range all {
+		if m.Conflicts == "" && m.Status != StatusBroken {
+			mems = append(mems, m)
+		}
+	}
+	r := &Retriever{
+		memories: mems,
+		tf:       make([]map[string]int, len(mems)),
+		lengths:  make([]int, len(mems)),
+		sigs:     make([]codegraph.MinHashSignature, len(mems)),
+		idf:      make(map[string]float64),
+		hasher:   codegraph.NewMinHasher(codegraph.DefaultNumHashes),
+	}
+
+	df := make(map[string]int)
+	total := 0
+	for i, m := range mems {
+		tokens := retrievalTokens(memoryText(m))
+		counts := make(map[string]int, len(tokens))
+		for _, t := range tokens {
+			counts[t]++
+		}
+		for t := range counts {
+			df[t]++
+		}
+		r.tf[i] = counts
+		r.lengths[i] = len(tokens)
+		r.sigs[i] = r.hasher.Signature(tokenSet(counts))
+		total += len(tokens)
+	}
+	if len(mems) > 0 {
+		r.avgLen = float64(total) / float64(len(mems))
+	}
+	for t, n := range df {
+		r.idf[t] = codegraph.BM25IDF(n, len(mems))
+	}
+	return r
+}
+
+// Len returns the number of memories in the corpus.
+func (r *Retriever) Len() int {
+	return len(r.memories)
+}
+
+// Retrieve ranks every memory against query plus the names of opts.Files and
+// marks the best ones, up to TopK and MaxTokens, as Included. Memories that
+// share nothing with the query are never injected, however few there are.
+func (r *Retriever) Retrieve(query string, opts RetrieveOptions) *Retrieval {
+	if opts.TopK <= 0 {
+		opts.TopK = DefaultRetrieveTopK
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultRetrieveMaxTokens
+	}
+	res := &Retrieval{
+		Query:     query,
+		Files:     opts.Files,
+		Total:     len(r.memories),
+		TopK:      opts.TopK,
+		MaxTokens: opts.MaxTokens,
+	}
+
+	queryCounts := make(map[string]int)
+	for _, t := range retrievalTokens(query + " " + strings.Join(opts.Files, " ")) {
+		queryCounts[t]++
+	}
+	if len(queryCounts) == 0 || len(r.memories) == 0 {
+		return res
+	}
+	queryTokens := tokenSet(queryCounts)
+	querySig := r.hasher.Signature(queryTokens)
+
+	scored := make(map[int64]*ScoredMemory)
+	var byBM25, byJaccard []int64
+	for i, m := range r.memories {
+		bm := codegraph.ComputeBM25Score(queryTokens, r.tf[i], r.lengths[i], r.idf, r.avgLen)
+		jac := codegraph.JaccardSimilarity(querySig, r.sigs[i])
+		if bm <= 0 && jac < minRetrieveJaccard {
+			continue
+		}
+		var matched []string
+		for _, t := range queryTokens {
+			if r.tf[i][t] > 0 {
+				matched = append(matched, t)
+			}
+		}
+		id := int64(i)
+		scored[id] = &ScoredMemory{Memory: m, BM25: bm, Jaccard: jac, Matched: matched}
+		if bm > 0 {
+			byBM25 = append(byBM25, id)
+		}
+		if jac >= minRetrieveJaccard {
+			byJaccard = append(byJaccard, id)
+		}
+	}
+
+	sort.SliceStable(byBM25, func(a, b int) bool { return scored[byBM25[a]].BM25 > scored[byBM25[b]].BM25 })
+	sort.SliceStable(byJaccard, func(a, b int) bool { return scored[byJaccard[a]].Jaccard > scored[byJaccard[b]].Jaccard })
+	fused := codegraph.ComputeFusedRanking(rankMap(byJaccard), rankMap(byBM25))
+
+	included := 0
+	for rank, id := range fused {
+		sm := scored[id]
+		sm.Rank = rank + 1
+		sm.Tokens = ctxmgr.EstimateTokens(renderMemory(sm.Memory))
+		switch {
+		case included >= opts.TopK:
+			sm.Reason = fmt.Sprintf("below top-%d", opts.TopK)
+		case res.Tokens+sm.Tokens > opts.MaxTokens:
+			sm.Reason = "over token budget"
+		default:
+			sm.Included = true
+			included++
+			res.Tokens += sm.Tokens
+		}
+		res.Candidates = append(res.Candidates, *sm)
+	}
+	return res
+}
+
+// Selected returns the memories chosen for injection, best first.
+func (res *Retrieval) Selected() []*Memory {
+	var out []*Memory
+	for _, c := range res.Candidates {
+		if c.Included {
+			out = append(out, c.Memory)
+		}
+	}
+	return out
+}
+
+// Render formats the selected memories for injection into a request.
+// Returns "" when nothing was selected.
+func (res *Retrieval) Render() string {
+	selected := res.Selected()
+	if len(selected) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Relevant Memories\n\n%d of %d project memories matched this request.\n", len(selected), res.Total)
+	for _, m := range selected {
+		sb.WriteString("\n")
+		sb.WriteString(renderMemory(m))
+	}
+	return sb.String()
+}
+
+// Explain describes why each candidate was or was not injected, for
+// /memories why.
+func (res *Retrieval) Explain() string {
+	var sb strings.Builder
+	query := strings.ReplaceAll(strings.TrimSpace(res.Query), "\n", " ")
+	if len(query) > 80 {
+		query = query[:80] + "..."
+	}
+	fmt.Fprintf(&sb, "Memory retrieval for: %q\n", query)
+	if len(res.Files) > 0 {
+		fmt.Fprintf(&sb, "Workspace files: %s\n", strings.Join(res.Files, ", "))
+	}
+	fmt.Fprintf(&sb, "Injected %d of %d memories (~%d/%d tokens, top-%d)\n",
+		len(res.Selected()), res.Total, res.Tokens, res.MaxTokens, res.TopK)
+	if len(res.Candidates) == 0 {
+		sb.WriteString("\nNo memory shares any terms with this request.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("\n")
+	for _, c := range res.Candidates {
+		mark := "✓"
+		if !c.Included {
+			mark = "✗"
+		}
+		fmt.Fprintf(&sb, "%s #%d %s  bm25 %.2f  jaccard %.2f  ~%d tok",
+			mark, c.Rank, c.Memory.Name, c.BM25, c.Jaccard, c.Tokens)
+		if c.Reason != "" {
+			fmt.Fprintf(&sb, "  (%s)", c.Reason)
+		}
+		sb.WriteString("\n")
+		if len(c.Matched) > 0 {
+			fmt.Fprintf(&sb, "    matched: %s\n", strings.Join(c.Matched, ", "))
+		}
+	}
+	if skipped := res.Total - len(res.Candidates); skipped > 0 {
+		fmt.Fprintf(&sb, "\n%d other memories share no terms with this request.\n", skipped)
+	}
+	return sb.String()
+}
+
+// proseStopWords are English function words. The codegraph stop-word list
+// targets identifiers, so prose like "fix the build" needs this extra pass
+// or every memory would match on "the".
+var proseStopWords = map[string]bool{
+	"about": true, "after": true, "all": true, "also": true, "an": true, "and": true,
+	"any": true, "are": true, "as": true, "at": true, "be": true, "been": true,
+	"before": true, "but": true, "by": true, "can": true, "could": true, "did": true,
+	"do": true, "does": true, "for": true, "from": true, "had": true, "has": true,
+	"have": true, "how": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "its": true, "just": true, "me": true, "my": true, "no": true,
+	"not": true, "of": true, "on": true, "or": true, "our": true, "please": true,
+	"should": true, "so": true, "some": true, "than": true, "that": true, "the": true,
+	"their": true, "them": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "to": true, "us": true, "was": true, "we": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "while": true, "who": true,
+	"why": true, "will": true, "with": true, "would": true, "you": true, "your": true,
+}
+
+// retrievalTokens tokenizes text for memory retrieval: codegraph's
+// identifier-aware tokenizer, minus prose stop words, with plurals folded
+// so "migrations" matches "migration".
+func retrievalTokens(text string) []string {
+	raw := codegraph.TextTokens(text)
+	out := raw[:0]
+	for _, t := range raw {
+		if proseStopWords[t] {
+			continue
+		}
+		if len(t) > 3 && strings.HasSuffix(t, "s") && !strings.HasSuffix(t, "ss") {
+			t = t[:len(t)-1]
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// memoryText is the text a memory is indexed under.
+func memoryText(m *Memory) string {
+	return m.Name + " " + m.Description + " " + m.Content
+}
+
+// renderMemory formats one memory for injection, flagging old ones so the
+// model verifies them before relying on them.
+func renderMemory(m *Memory) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s (%s)\n", m.Name, m.Type)
+	if m.Description != "" {
+		sb.WriteString(m.Description + "\n")
+	}
+	if _, warning := CheckStaleness(m); warning != "" {
+		sb.WriteString("_Note: " + warning + "._\n")
+	}
+	if m.Status == StatusStale && len(m.MissingRefs) > 0 {
+		sb.WriteString("_Note: no longer found in the code: " + strings.Join(m.MissingRefs, ", ") + "._\n")
+	}
+	if m.Status == StatusUnverified && len(m.MissingRefs) > 0 {
+		sb.WriteString("_Note: possibly outdated, these were not found in the code: " + strings.Join(m.MissingRefs, ", ") + "._\n")
+	}
+	if m.Content != "" && m.Content != m.Description {
+		sb.WriteString("\n" + m.Content + "\n")
+	}
+	return sb.String()
+}
+
+func tokenSet(counts map[string]int) []string {
+	out := make([]string, 0, len(counts))
+	for t := range counts {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// rankMap turns an ordered ID list into the 1-based rank map
+// codegraph.ComputeFusedRanking expects.
+func rankMap(ids []int64) map[int64]int {
+	m := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		m[id] = i + 1
+	}
+	return m
+}
@@ -0,0 +1,68 @@
+package memories
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func retrievalCorpus() []*Memory {
+	mems := []*Memory{
+		NewMemory("postgres-migrations", "Run migrations with goose before tests", "project", "", "The postgres schema is managed by goose migrations in db/migrations."),
+		NewMemory("tabs-not-spaces", "User prefers tabs in Makefiles", "feedback", "", "Always indent Makefile recipes with tabs."),
+		NewMemory("release-process", "Releases are cut from the release branch", "project", "", "Tag releases with goreleaser after the changelog is updated."),
+	}
+	for i := 0; i < 50; i++ {
+		mems = append(mems, NewMemory(fmt.Sprintf("filler-%d", i), "Unrelated note", "reference", "", fmt.Sprintf("Dashboard link number %d for the marketing team.", i)))
+	}
+	return mems
+}
+
+func TestRetrieveRanksRelevantMemoryFirst(t *testing.T) {
+	r := NewRetriever(retrievalCorpus())
+	res := r.Retrieve("the postgres migrations fail in CI", RetrieveOptions{})
+
+	require.NotEmpty(t, res.Candidates)
+	assert.Equal(t, "postgres-migrations", res.Candidates[0].Memory.Name)
+	assert.Contains(t, res.Candidates[0].Matched, "migration", "plurals fold to one term")
+	assert.Equal(t, 53, res.Total)
+
+	for _, m := range res.Selected() {
+		assert.NotContains(t, m.Name, "filler", "memories unrelated to the query must not be injected")
+	}
+	assert.Contains(t, res.Render(), "## postgres-migrations (project)")
+}
+
+func TestRetrieveUsesWorkspaceFiles(t *testing.T) {
+	r := NewRetriever(retrievalCorpus())
+	res := r.Retrieve("fix the indentation", RetrieveOptions{Files: []string{"Makefile"}})
+
+	require.NotEmpty(t, res.Selected())
+	assert.Equal(t, "tabs-not-spaces", res.Selected()[0].Name)
+}
+
+func TestRetrieveHonoursTopKAndTokenCap(t *testing.T) {
+	r := NewRetriever(retrievalCorpus())
+
+	res := r.Retrieve("dashboard link marketing", RetrieveOptions{TopK: 3})
+	assert.Len(t, res.Selected(), 3)
+	assert.Greater(t, len(res.Candidates), 3)
+	assert.Contains(t, res.Candidates[len(res.Candidates)-1].Reason, "top-3")
+
+	res = r.Retrieve("dashboard link marketing", RetrieveOptions{TopK: 50, MaxTokens: 40})
+	assert.LessOrEqual(t, res.Tokens, 40)
+	assert.Less(t, len(res.Selected()), 50)
+	assert.Contains(t, res.Explain(), "over token budget")
+}
+
+func TestRetrieveNoMatch(t *testing.T) {
+	r := NewRetriever(retrievalCorpus())
+	res := r.Retrieve("kubernetes", RetrieveOptions{})
+
+	assert.Empty(t, res.Selected())
+	assert.Empty(t, res.Render())
+	assert.True(t, strings.Contains(res.Explain(), "No memory shares any terms"))
+}
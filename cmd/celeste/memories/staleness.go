@@ -26,8 +26,12 @@ func CheckStaleness(memory *Memory) (int, string) {
 	return days, fmt.Sprintf("memory '%s' is %d days old and may be outdated, consider refreshing or deleting it", memory.Name, days)
 }
 
-// ShouldVerify returns true if a memory is older than 7 days.
+// ShouldVerify returns true if a memory is older than 7 days and has not been
+// verified against the code in that time.
 func ShouldVerify(memory *Memory) bool {
+	if verified, err := time.Parse(time.RFC3339, memory.VerifiedAt); err == nil {
+		return time.Since(verified).Hours() > 7*24
+	}
 	created, err := time.Parse(time.RFC3339, memory.Created)
 	if err != nil {
 		return false
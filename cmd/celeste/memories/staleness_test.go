@@ -62,3 +62,11 @@ func TestShouldVerifyInvalidDate(t *testing.T) {
 	m := &Memory{Created: "invalid"}
 	assert.False(t, ShouldVerify(m))
 }
+
+func TestShouldVerifyRecentlyVerified(t *testing.T) {
+	m := &Memory{
+		Created:    time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339),
+		VerifiedAt: time.Now().Add(-24 * time.Hour).Format(time.RFC3339),
+	}
+	assert.False(t, ShouldVerify(m))
+}
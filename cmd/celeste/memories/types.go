@@ -19,7 +19,15 @@ type Memory struct {
 	Type        string `yaml:"type"` // user, feedback, project, reference
 	Created     string `yaml:"created"`
 	Project     string `yaml:"project"`
-	Content     string `yaml:"-"` // everything after frontmatter
+	Source      string `yaml:"source,omitempty"`    // "extracted" when proposed by the model from a session
+	Conflicts   string `yaml:"conflicts,omitempty"` // name of a memory this one contradicts, pending review
+
+	// Verification against the codebase, written by Verifier.
+	Status      string   `yaml:"status,omitempty"`       // verified, stale, broken or unverified
+	VerifiedAt  string   `yaml:"verified_at,omitempty"`  // RFC3339 time of the last check
+	MissingRefs []string `yaml:"missing_refs,omitempty"` // code references that no longer resolve
+
+	Content string `yaml:"-"` // everything after frontmatter
 }
 
 // ValidTypes lists the allowed memory types.
@@ -0,0 +1,378 @@
+package memories
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/codegraph"
+)
+
+// Verification statuses recorded in a memory's frontmatter.
+const (
+	StatusVerified = "verified" // every code reference still resolves
+	StatusStale    = "stale"    // some references no longer resolve
+	StatusBroken   = "broken"   // none of the references resolve
+	// StatusUnverified is what an automatic check records instead of
+	// broken: reference extraction is a heuristic, so only a verification
+	// the user runs may mark a memory broken and drop it from retrieval.
+	StatusUnverified = "unverified"
+)
+
+// Reference kinds a memory can mention.
+const (
+	RefPath   = "path"
+	RefSymbol = "symbol"
+	RefFlag   = "flag"
+)
+
+// maxVerifyFileSize skips large files when scanning sources for flags and
+// symbols the code graph does not know about.
+const maxVerifyFileSize = 1 << 20
+
+// Reference is a file path, identifier or command-line flag a memory names.
+type Reference struct {
+	Kind       string
+	Value      string
+	Found      bool
+	Suggestion string // likely new name when Found is false, if one was found
+}
+
+// VerificationResult is the outcome of checking one memory.
+type VerificationResult struct {
+	Memory *Memory
+	Refs   []Reference
+	Status string // empty when the memory names nothing checkable
+}
+
+// Missing returns the references that no longer resolve.
+func (r *VerificationResult) Missing() []Reference {
+	var out []Reference
+	for _, ref := range r.Refs {
+		if !ref.Found {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+var (
+	backtickRef   = regexp.MustCompile("`([^`\n]{2,120})`")
+	pathRef       = regexp.MustCompile(`(?:^|[\s(])((?:[\w.-]+/)+[\w.-]+\.\w{1,5}|[\w-]+\.(?:go|py|ts|tsx|js|jsx|rs|java|rb|c|h|cpp|cs|kt|swift|sh|md|json|ya?ml|toml|sql))\b`)
+	flagRef       = regexp.MustCompile(`(?:^|\s)(--?[a-z][a-z0-9]*(?:-[a-z0-9]+)+|--[a-z][a-z0-9]+)\b`)
+	callRef       = regexp.MustCompile(`\b([A-Za-z_][\w.]*)\(\)`)
+	identifierRef = regexp.MustCompile(`^[A-Za-z_][\w]*(?:\.[A-Za-z_][\w]*)*(?:\(\))?$`)
+)
+
+// ExtractReferences finds the paths, identifiers and flags a memory names.
+// Identifiers count only when backticked or written as a call; bare
+// CamelCase and snake_case words are too often product names and prose.
+// URLs are ignored.
+func ExtractReferences(text string) []Reference {
+	seen := make(map[string]bool)
+	var refs []Reference
+	add := func(kind, value string) {
+		value = strings.Trim(value, ".,;:'\"")
+		if value == "" || seen[kind+":"+value] {
+			return
+		}
+		seen[kind+":"+value] = true
+		refs = append(refs, Reference{Kind: kind, Value: value})
+	}
+
+	var prose strings.Builder
+	for _, field := range strings.Fields(text) {
+		if strings.Contains(field, "://") {
+			continue
+		}
+		prose.WriteString(field + " ")
+	}
+	text = prose.String()
+
+	for _, m := range backtickRef.FindAllStringSubmatch(text, -1) {
+		span := strings.TrimSpace(m[1])
+		switch {
+		case strings.HasPrefix(span, "-"):
+			add(RefFlag, strings.Fields(span)[0])
+		case strings.ContainsAny(span, "/\\") || pathRef.MatchString(" "+span):
+			add(RefPath, span)
+		case identifierRef.MatchString(span):
+			add(RefSymbol, symbolName(span))
+		}
+	}
+	text = backtickRef.ReplaceAllString(text, " ")
+
+	for _, m := range pathRef.FindAllStringSubmatch(text, -1) {
+		add(RefPath, m[1])
+	}
+	for _, m := range flagRef.FindAllStringSubmatch(text, -1) {
+		add(RefFlag, m[1])
+	}
+	for _, m := range callRef.FindAllStringSubmatch(pathRef.ReplaceAllString(text, " "), -1) {
+		add(RefSymbol, symbolName(m[1]))
+	}
+	return refs
+}
+
+// symbolName reduces "pkg.Type.Method()" to "Method", the name the code
+// graph indexes.
+func symbolName(s string) string {
+	s = strings.TrimSuffix(s, "()")
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
+
+// Verifier checks memory references against a workspace. With a code graph
+// store, identifiers are resolved through the symbol table; without one, or
+// for names the graph does not index (constants, flags), source files are
+// scanned instead.
+type Verifier struct {
+	workspace string
+	graph     *codegraph.Store // may be nil
+	files     []string         // workspace-relative source files, loaded lazily
+	now       func() time.Time
+}
+
+// NewVerifier creates a verifier for workspace. graph may be nil.
+func NewVerifier(workspace string, graph *codegraph.Store) *Verifier {
+	return &Verifier{workspace: workspace, graph: graph, now: time.Now}
+}
+
+// Verify checks every reference in m and derives its status. It does not
+// modify m; see Apply.
+func (v *Verifier) Verify(m *Memory) *VerificationResult {
+	res := &VerificationResult{Memory: m}
+	for _, ref := range ExtractReferences(m.Description + "\n" + m.Content) {
+		switch ref.Kind {
+		case RefPath:
+			ref.Found = v.pathExists(ref.Value)
+			if !ref.Found {
+				ref.Suggestion = v.suggestPath(ref.Value)
+			}
+		case RefSymbol:
+			ref.Found = v.symbolExists(ref.Value)
+			if !ref.Found {
+				ref.Suggestion = v.suggestSymbol(ref.Value)
+			}
+		case RefFlag:
+			ref.Found = v.sourceContains(`"` + strings.TrimLeft(ref.Value, "-") + `"`)
+		}
+		res.Refs = append(res.Refs, ref)
+	}
+
+	missing := len(res.Missing())
+	switch {
+	case len(res.Refs) == 0:
+	case missing == 0:
+		res.Status = StatusVerified
+	case missing == len(res.Refs):
+		res.Status = StatusBroken
+	default:
+		res.Status = StatusStale
+	}
+	return res
+}
+
+// Unconfirmed downgrades a broken result to unverified, for checks that
+// run without the user looking at the outcome.
+func (r *VerificationResult) Unconfirmed() {
+	if r.Status == StatusBroken {
+		r.Status = StatusUnverified
+	}
+}
+
+// Apply records a result in the memory's frontmatter. Memories without
+// checkable references keep whatever status they had.
+func (v *Verifier) Apply(res *VerificationResult) {
+	if res.Status == "" {
+		return
+	}
+	m := res.Memory
+	m.Status = res.Status
+	m.VerifiedAt = v.now().Format(time.RFC3339)
+	m.MissingRefs = nil
+	for _, ref := range res.Missing() {
+		m.MissingRefs = append(m.MissingRefs, ref.Value)
+	}
+}
+
+// VerifyStore verifies and saves every memory in store.
+func (v *Verifier) VerifyStore(store *Store) ([]*VerificationResult, error) {
+	mems, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*VerificationResult, 0, len(mems))
+	for _, m := range mems {
+		res := v.Verify(m)
+		v.Apply(res)
+		if res.Status != "" {
+			if err := store.Save(m); err != nil {
+				return results, err
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// ApplyFixes rewrites missing references that have a suggested replacement
+// and reports whether anything changed. The caller re-verifies and saves.
+func ApplyFixes(res *VerificationResult) bool {
+	changed := false
+	m := res.Memory
+	for _, ref := range res.Missing() {
+		if ref.Suggestion == "" {
+			continue
+		}
+		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(ref.Value) + `\b`)
+		if ref.Kind == RefPath {
+			pattern = regexp.MustCompile(regexp.QuoteMeta(ref.Value))
+		}
+		content := pattern.ReplaceAllLiteralString(m.Content, ref.Suggestion)
+		desc := pattern.ReplaceAllLiteralString(m.Description, ref.Suggestion)
+		if content != m.Content || desc != m.Description {
+			m.Content, m.Description = content, desc
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (v *Verifier) pathExists(p string) bool {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(v.workspace, p)
+	}
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func (v *Verifier) symbolExists(name string) bool {
+	if v.graph != nil {
+		if _, ok := v.graph.GetSymbolIDByName(name); ok {
+			return true
+		}
+	}
+	// The graph indexes declarations only; constants, fields and unindexed
+	// languages still count if the name appears as a whole word in a source.
+	return v.sourceMatches(regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`))
+}
+
+// suggestPath looks for a file with the same base name elsewhere in the
+// workspace — the common shape of a move.
+func (v *Verifier) suggestPath(p string) string {
+	base := filepath.Base(p)
+	var matches []string
+	for _, f := range v.sourceFiles() {
+		if filepath.Base(f) == base {
+			matches = append(matches, filepath.ToSlash(f))
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0]
+	}
+	return ""
+}
+
+// suggestSymbol proposes the closest indexed name sharing a word with a
+// missing identifier, e.g. LoadConfig → LoadConfigFile.
+func (v *Verifier) suggestSymbol(name string) string {
+	if v.graph == nil {
+		return ""
+	}
+	best, bestDist := "", len(name)/2+1
+	for _, word := range codegraph.TextTokens(name) {
+		if len(word) < 3 {
+			continue
+		}
+		syms, err := v.graph.SearchSymbolsByName(word)
+		if err != nil {
+			continue
+		}
+		for _, s := range syms {
+			if d := editDistance(strings.ToLower(name), strings.ToLower(s.Name)); d < bestDist {
+				best, bestDist = s.Name, d
+			}
+		}
+	}
+	return best
+}
+
+func (v *Verifier) sourceContains(needle string) bool {
+	return v.sourceMatches(regexp.MustCompile(regexp.QuoteMeta(needle)))
+}
+
+func (v *Verifier) sourceMatches(re *regexp.Regexp) bool {
+	for _, f := range v.sourceFiles() {
+		data, err := os.ReadFile(filepath.Join(v.workspace, f))
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+		if re.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceFiles lists the workspace files worth scanning: the code graph's
+// file table when available, else a walk that skips what the indexer skips.
+func (v *Verifier) sourceFiles() []string {
+	if v.files != nil {
+		return v.files
+	}
+	v.files = []string{}
+	if v.graph != nil {
+		if recs, err := v.graph.GetAllFiles(); err == nil && len(recs) > 0 {
+			for _, r := range recs {
+				v.files = append(v.files, r.Path)
+			}
+			sort.Strings(v.files)
+			return v.files
+		}
+	}
+	_ = filepath.Walk(v.workspace, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(v.workspace, path)
+		if codegraph.ShouldSkipPath(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && info.Size() <= maxVerifyFileSize {
+			v.files = append(v.files, rel)
+		}
+		return nil
+	})
+	return v.files
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,116 @@
+package memories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/codegraph"
+)
+
+func writeWorkspaceFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestExtractReferences(t *testing.T) {
+	refs := ExtractReferences("Config lives in `internal/config/load.go` and is read by `config.LoadConfig()`. " +
+		"Pass --dry-run to preview; see https://example.com/docs/setup.md. Call retryBudget() to cap attempts.")
+
+	got := make(map[string]string)
+	for _, r := range refs {
+		got[r.Value] = r.Kind
+	}
+	assert.Equal(t, RefPath, got["internal/config/load.go"])
+	assert.Equal(t, RefSymbol, got["LoadConfig"])
+	assert.Equal(t, RefFlag, got["--dry-run"])
+	assert.Equal(t, RefSymbol, got["retryBudget"])
+	assert.NotContains(t, got, "example.com/docs/setup.md", "URLs are not code references")
+}
+
+func TestExtractReferences_IgnoresProse(t *testing.T) {
+	refs := ExtractReferences("The team ships to PayPal and GitHub from their MacBook; " +
+		"the on_call rotation and follow_up notes live in JavaScript land.")
+	assert.Empty(t, refs, "bare CamelCase and snake_case words are prose, not code")
+}
+
+func TestVerifierStatuses(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "pkg/server/server.go", "package server\n\nfunc StartServer() {}\n\nvar port = flag.Int(\"listen-port\", 80, \"\")\n")
+	v := NewVerifier(root, nil)
+
+	verified := v.Verify(&Memory{Name: "a", Content: "`StartServer` in pkg/server/server.go honours --listen-port."})
+	assert.Equal(t, StatusVerified, verified.Status)
+
+	stale := v.Verify(&Memory{Name: "b", Content: "`StartServer` is wrapped by `RunGateway`."})
+	assert.Equal(t, StatusStale, stale.Status)
+	require.Len(t, stale.Missing(), 1)
+	assert.Equal(t, "RunGateway", stale.Missing()[0].Value)
+
+	broken := v.Verify(&Memory{Name: "c", Content: "Routes are in `api/routes.go`."})
+	assert.Equal(t, StatusBroken, broken.Status)
+
+	prose := v.Verify(&Memory{Name: "d", Content: "The user prefers short answers about OpenAI and the on_call rotation."})
+	assert.Empty(t, prose.Status)
+
+	broken.Unconfirmed()
+	assert.Equal(t, StatusUnverified, broken.Status)
+	stale.Unconfirmed()
+	assert.Equal(t, StatusStale, stale.Status, "only broken is downgraded")
+}
+
+func TestVerifierSuggestsMovedFilesAndRenamedSymbols(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "internal/auth/session.go", "package auth\n\nfunc NewSessionStore() {}\n")
+
+	graph, err := codegraph.NewStore(filepath.Join(t.TempDir(), "index.db"))
+	require.NoError(t, err)
+	defer graph.Close()
+	require.NoError(t, graph.UpsertFile(codegraph.FileRecord{Path: "internal/auth/session.go", Language: "go"}))
+	_, err = graph.UpsertSymbol(codegraph.Symbol{Name: "NewSessionStore", Kind: codegraph.SymbolFunction, Package: "auth", File: "internal/auth/session.go"})
+	require.NoError(t, err)
+
+	mem := &Memory{Name: "sessions", Content: "Sessions are created by `NewSessionStor` in `auth/session.go`."}
+	v := NewVerifier(root, graph)
+	res := v.Verify(mem)
+	assert.Equal(t, StatusBroken, res.Status)
+
+	suggestions := make(map[string]string)
+	for _, ref := range res.Missing() {
+		suggestions[ref.Value] = ref.Suggestion
+	}
+	assert.Equal(t, "internal/auth/session.go", suggestions["auth/session.go"])
+	assert.Equal(t, "NewSessionStore", suggestions["NewSessionStor"])
+
+	require.True(t, ApplyFixes(res))
+	assert.Equal(t, "Sessions are created by `NewSessionStore` in `internal/auth/session.go`.", mem.Content)
+	assert.Equal(t, StatusVerified, v.Verify(mem).Status)
+}
+
+func TestVerifyStoreRecordsFrontmatter(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "main.go", "package main\n\nfunc main() {}\n")
+	store := NewStoreWithBase(t.TempDir())
+	require.NoError(t, store.Save(NewMemory("entry", "Entry point", "project", "", "Execution starts in main.go and calls `bootstrapApp`.")))
+
+	_, err := NewVerifier(root, nil).VerifyStore(store)
+	require.NoError(t, err)
+
+	mem, err := store.Load("entry")
+	require.NoError(t, err)
+	assert.Equal(t, StatusStale, mem.Status)
+	assert.Equal(t, []string{"bootstrapApp"}, mem.MissingRefs)
+	assert.NotEmpty(t, mem.VerifiedAt)
+	assert.False(t, ShouldVerify(mem))
+
+	// Broken memories drop out of retrieval; unverified ones stay.
+	mem.Status = StatusBroken
+	assert.Equal(t, 0, NewRetriever([]*Memory{mem}).Len())
+	mem.Status = StatusUnverified
+	assert.Equal(t, 1, NewRetriever([]*Memory{mem}).Len())
+}
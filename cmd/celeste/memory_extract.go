@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/memories"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// memoryExtractTimeout bounds one extraction pass, request included.
+const memoryExtractTimeout = 3 * time.Minute
+
+// minExtractionUserTurns is the smallest session worth an extraction pass.
+const minExtractionUserTurns = 2
+
+// promptClient adapts llm.Client to the one-shot system+user interface
+// ctxmgr.LLMClient describes, which the memories package consumes.
+type promptClient struct {
+	client *llm.Client
+}
+
+// SendSummarizationRequest implements ctxmgr.LLMClient.
+func (p *promptClient) SendSummarizationRequest(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	p.client.SetSystemPrompt(systemPrompt)
+	result, err := p.client.SendMessageSync(ctx, []tui.ChatMessage{{
+		Role:      "user",
+		Content:   userPrompt,
+		Timestamp: time.Now(),
+	}}, nil)
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// runMemoriesExtractCommand implements `celeste memories extract`: it asks
+// the configured model for memories worth keeping from a finished chat
+// session and consolidates them into the project's store.
+func runMemoriesExtractCommand(args []string) {
+	fs := flag.NewFlagSet("memories extract", flag.ExitOnError)
+	sessionID := fs.String("session", "", "Chat session to extract from (defaults to the most recent)")
+	dryRun := fs.Bool("dry-run", false, "Show what would be added, merged or flagged without writing")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadNamed(configName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.APIKey == "" && !cfg.GoogleUseADC && strings.TrimSpace(cfg.GoogleCredentialsFile) == "" {
+		fmt.Fprintln(os.Stderr, "No API key or Google ADC credentials configured.")
+		os.Exit(1)
+	}
+
+	sm := config.NewSessionManager()
+	var session *config.Session
+	if *sessionID != "" {
+		session, err = sm.Load(*sessionID)
+	} else {
+		session, err = sm.LoadLatest()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading session: %v\n", err)
+		os.Exit(1)
+	}
+
+	messages := make([]memories.ExtractionMessage, 0, len(session.Messages))
+	for _, msg := range session.Messages {
+		messages = append(messages, memories.ExtractionMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	cwd, _ := os.Getwd()
+	store := memories.NewStore(cwd)
+	existing, _ := store.List()
+
+	client := &promptClient{client: llm.NewClient(chatClientConfig(cfg), nil)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), memoryExtractTimeout)
+	defer cancel()
+
+	fmt.Printf("Extracting memories from session %s (%d messages)...\n", session.ID, len(session.Messages))
+	candidates, err := memories.ProposeMemories(ctx, client, messages, existing)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	report, err := memories.Consolidate(store, candidates, cwd, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		fmt.Print("Dry run: ")
+	}
+	fmt.Println(report.Summary())
+	for _, name := range report.Added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range report.Merged {
+		fmt.Printf("  ~ %s\n", name)
+	}
+	for _, name := range report.Conflicts {
+		fmt.Printf("  ⚡ %s (review with /memories)\n", name)
+	}
+}
+
+// startBackgroundMemoryExtraction runs `celeste memories extract` for the
+// session that just ended in a detached process, so quitting the TUI never
+// waits on the model. Output goes to ~/.celeste/logs/memory-extract.log.
+func startBackgroundMemoryExtraction(session *config.Session) {
+	userTurns := 0
+	for _, msg := range session.Messages {
+		if msg.Role == "user" {
+			userTurns++
+		}
+	}
+	if userTurns < minExtractionUserTurns {
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	logPath := filepath.Join(homeDir, ".celeste", "logs", "memory-extract.log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return
+	}
+	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer logFile.Close()
+
+	args := []string{"memories", "extract", "--session", session.ID}
+	if configName != "" {
+		args = append([]string{"-config", configName}, args...)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: memory extraction not started: %v\n", err)
+		return
+	}
+	_ = cmd.Process.Release()
+	fmt.Printf("🧠 Extracting memories from this session in the background (log: %s)\n", logPath)
+}
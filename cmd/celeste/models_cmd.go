@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
+)
+
+// runModelsCommand handles `celeste models [list|show|refresh]`: the model
+// catalog behind context budgeting, costing and tool gating.
+func runModelsCommand(args []string) {
+	cfg, err := config.LoadNamed(configName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	provider := providers.DetectProvider(cfg.BaseURL)
+
+	sub := "list"
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+	switch sub {
+	case "list":
+		if len(args) > 0 {
+			provider = args[0]
+		}
+		printCatalogModels(provider)
+	case "show":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: celeste models show <model-id>")
+			os.Exit(1)
+		}
+		m, ok := catalog.Default().Lookup(args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s is not in the model catalog. Add it under \"model_overrides\" in the config.\n", args[0])
+			os.Exit(1)
+		}
+		data, _ := json.MarshalIndent(m, "", "  ")
+		fmt.Println(string(data))
+	case "refresh":
+		n, err := refreshModelCatalog(context.Background(), cfg, provider)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cached %d %s model(s)\n", n, provider)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown models subcommand %q. Try: list, show, refresh\n", sub)
+		os.Exit(1)
+	}
+}
+
+// refreshModelCatalog fetches provider's model listing into the catalog and
+// saves the cache.
+func refreshModelCatalog(ctx context.Context, cfg *config.Config, provider string) (int, error) {
+	n, err := providers.NewModelService(cfg.APIKey, cfg.BaseURL, provider).RefreshCatalog(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return n, catalog.Default().Save()
+}
+
+// refreshModelCatalogInBackground refreshes the active provider's listing
+// when the cached one is older than catalog.RefreshInterval. Failures are
+// ignored: the cache and the built-in table still apply.
+func refreshModelCatalogInBackground(cfg *config.Config) {
+	provider := providers.DetectProvider(cfg.BaseURL)
+	caps, ok := providers.Registry[provider]
+	if !ok || !caps.SupportsModelListing && provider != "gemini" || !catalog.Default().Stale(provider) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_, _ = refreshModelCatalog(ctx, cfg, provider)
+	}()
+}
+
+func printCatalogModels(provider string) {
+	models := catalog.Default().Models(provider)
+	if len(models) == 0 {
+		fmt.Printf("No models in the catalog for %s. Try: celeste models refresh\n", provider)
+		return
+	}
+	if fetched := catalog.Default().FetchedAt(provider); !fetched.IsZero() {
+		fmt.Printf("%s models (listing fetched %s)\n\n", provider, fetched.Local().Format("2006-01-02 15:04"))
+	} else {
+		fmt.Printf("%s models (built-in table)\n\n", provider)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tCONTEXT\tMAX OUT\tFEATURES\tINPUT $/1M\tOUTPUT $/1M\tCACHED $/1M")
+	for _, m := range models {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", m.ID,
+			tokenCount(m.ContextWindow), tokenCount(m.MaxOutput), modelFeatures(m),
+			price(m.InputPrice), price(m.OutputPrice), price(m.CachedInputPrice))
+	}
+	_ = w.Flush()
+}
+
+func modelFeatures(m catalog.Model) string {
+	var f []string
+	for _, c := range []struct {
+		name string
+		v    *bool
+	}{{"tools", m.Tools}, {"vision", m.Vision}, {"reasoning", m.Reasoning}} {
+		if c.v != nil && *c.v {
+			f = append(f, c.name)
+		}
+	}
+	if len(f) == 0 {
+		return "-"
+	}
+	return strings.Join(f, ",")
+}
+
+func tokenCount(n int) string {
+	switch {
+	case n <= 0:
+		return "-"
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	default:
+		return fmt.Sprintf("%dK", n/1000)
+	}
+}
+
+func price(p float64) string {
+	if p <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", p)
+}
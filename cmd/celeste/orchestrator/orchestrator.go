@@ -3,11 +3,13 @@ package orchestrator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
 )
 
 // AgentRunner is the interface the orchestrator uses to execute a goal.
@@ -16,6 +18,16 @@ type AgentRunner interface {
 	RunGoal(ctx context.Context, goal string) (string, error)
 }
 
+// StructuredRunner is an AgentRunner that can also return a run's result as
+// JSON conforming to a schema. After running goal it asks question on the
+// same transcript and returns the free-form output and the structured
+// answer. A *llm.StructuredOutputError means the answer never conformed; the
+// reply is still returned for a text fallback.
+type StructuredRunner interface {
+	AgentRunner
+	RunGoalStructured(ctx context.Context, goal, question string, format *llm.ResponseFormat) (output, structured string, err error)
+}
+
 // RunnerFactory creates an AgentRunner for the given model name.
 type RunnerFactory func(model string) AgentRunner
 
@@ -142,14 +154,14 @@ func (o *Orchestrator) runDebate(ctx context.Context, goal, primaryOutput string
 	for round := 1; round <= o.debateRounds; round++ {
 		o.onEvent(OrchestratorEvent{Kind: EventDebateStart, Model: assignment.Reviewer, Text: fmt.Sprintf("round %d", round)})
 
-		reviewOutput, reviewElapsed, reviewIn, reviewOut, err := o.runGoalAccumStats(ctx, reviewer, reviewPrompt)
+		reviewOutput, issues, reviewElapsed, reviewIn, reviewOut, err := o.runReview(ctx, reviewer, reviewPrompt)
 		if err != nil {
 			return nil, fmt.Errorf("reviewer round %d failed: %w", round, err)
 		}
 		dm.AddTurn(DebateTurn{Round: round, Role: RoleReviewer, Input: reviewPrompt, Output: reviewOutput})
 
-		// Parse issues before emitting so the action feed shows a readable summary.
-		lastIssues = parseIssues(reviewOutput)
+		// Issues are known before emitting so the action feed shows a readable summary.
+		lastIssues = issues
 		var reviewSummary string
 		if len(lastIssues) == 0 {
 			reviewSummary = "no issues found"
@@ -200,6 +212,14 @@ func (o *Orchestrator) runDebate(ctx context.Context, goal, primaryOutput string
 // accumulate total token counts and elapsed time. All events are still forwarded
 // to the original o.onEvent so the TUI receives per-turn progress in real time.
 func (o *Orchestrator) runGoalAccumStats(ctx context.Context, runner AgentRunner, goal string) (output string, elapsed time.Duration, totalIn, totalOut int, err error) {
+	elapsed, totalIn, totalOut = o.accumStats(func() {
+		output, err = runner.RunGoal(ctx, goal)
+	})
+	return
+}
+
+// accumStats runs fn with the event interception runGoalAccumStats describes.
+func (o *Orchestrator) accumStats(fn func()) (elapsed time.Duration, totalIn, totalOut int) {
 	saved := o.onEvent
 	o.onEvent = func(e OrchestratorEvent) {
 		totalIn += e.InputTokens
@@ -207,12 +227,70 @@ func (o *Orchestrator) runGoalAccumStats(ctx context.Context, runner AgentRunner
 		saved(e)
 	}
 	start := time.Now()
-	output, err = runner.RunGoal(ctx, goal)
+	fn()
 	elapsed = time.Since(start)
 	o.onEvent = saved
 	return
 }
 
+// issuesFormat is the structured shape a reviewer reports its findings in.
+var issuesFormat = &llm.ResponseFormat{
+	Name: "review_issues",
+	Schema: map[string]any{
+		"type":     "object",
+		"required": []string{"issues"},
+		"properties": map[string]any{
+			"issues": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":     "object",
+					"required": []string{"severity", "description"},
+					"properties": map[string]any{
+						"file":        map[string]any{"type": "string"},
+						"line":        map[string]any{"type": "integer", "minimum": 0},
+						"severity":    map[string]any{"type": "string", "enum": []string{"low", "medium", "high"}},
+						"description": map[string]any{"type": "string", "minLength": 1},
+					},
+				},
+			},
+		},
+	},
+}
+
+const issuesQuestion = "Report every issue your review found. An empty list means the output is approved."
+
+// runReview runs one reviewer round and returns its output and issues.
+// Reviewers that support structured output report issues against
+// issuesFormat; others, and answers that never conform, fall back to
+// parseIssues on the review text.
+func (o *Orchestrator) runReview(ctx context.Context, reviewer AgentRunner, prompt string) (output string, issues []Issue, elapsed time.Duration, totalIn, totalOut int, err error) {
+	sr, ok := reviewer.(StructuredRunner)
+	if !ok {
+		output, elapsed, totalIn, totalOut, err = o.runGoalAccumStats(ctx, reviewer, prompt)
+		return output, parseIssues(output), elapsed, totalIn, totalOut, err
+	}
+
+	var structured string
+	elapsed, totalIn, totalOut = o.accumStats(func() {
+		output, structured, err = sr.RunGoalStructured(ctx, prompt, issuesQuestion, issuesFormat)
+	})
+
+	var structuredErr *llm.StructuredOutputError
+	if errors.As(err, &structuredErr) {
+		return output, parseIssues(structured + "\n" + output), elapsed, totalIn, totalOut, nil
+	}
+	if err != nil {
+		return output, nil, elapsed, totalIn, totalOut, err
+	}
+	var report struct {
+		Issues []Issue `json:"issues"`
+	}
+	if err := json.Unmarshal([]byte(structured), &report); err != nil {
+		return output, parseIssues(output), elapsed, totalIn, totalOut, nil
+	}
+	return output, report.Issues, elapsed, totalIn, totalOut, nil
+}
+
 // parseIssues extracts Issue structs from a JSON array in the reviewer's response.
 // Returns empty slice on parse failure (non-fatal).
 func parseIssues(text string) []Issue {
@@ -2,11 +2,13 @@ package orchestrator_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/orchestrator"
 )
 
@@ -54,3 +56,63 @@ func TestOrchestratorClassifiesAndRoutesGoal(t *testing.T) {
 	}
 	assert.True(t, completed)
 }
+
+// structuredReviewer is a reviewer that reports issues as structured output.
+type structuredReviewer struct {
+	fakeRunner
+	structured string
+	err        error
+	questions  []string
+}
+
+func (f *structuredReviewer) RunGoalStructured(_ context.Context, _, question string, format *llm.ResponseFormat) (string, string, error) {
+	f.questions = append(f.questions, question)
+	return f.response, f.structured, f.err
+}
+
+func reviewedConfig() *config.Config {
+	return &config.Config{Model: "test-model", Orchestrator: &config.OrchestratorConfig{
+		DebateRounds: 1,
+		Lanes:        map[string]config.LaneConfig{"code": {Primary: "primary", Reviewer: "reviewer"}},
+	}}
+}
+
+func TestDebateUsesStructuredReviewerIssues(t *testing.T) {
+	reviewer := &structuredReviewer{
+		fakeRunner: fakeRunner{response: "Looks mostly fine, one concern about the nil check."},
+		structured: `{"issues":[{"file":"auth.go","line":12,"severity":"high","description":"nil check missing"}]}`,
+	}
+	o := orchestrator.New(reviewedConfig(), orchestrator.WithRunnerFactory(func(model string) orchestrator.AgentRunner {
+		if model == "reviewer" {
+			return reviewer
+		}
+		return &fakeRunner{response: "TASK_COMPLETE: fixed"}
+	}))
+
+	result, err := o.Run(context.Background(), "fix the broken test in auth.go")
+	require.NoError(t, err)
+	require.NotNil(t, result.Verdict)
+	require.Len(t, result.Verdict.Issues, 1)
+	assert.Equal(t, orchestrator.Issue{File: "auth.go", Line: 12, Severity: "high", Description: "nil check missing"}, result.Verdict.Issues[0])
+	assert.Len(t, reviewer.questions, 1)
+}
+
+func TestDebateFallsBackToReviewTextWhenStructuredFails(t *testing.T) {
+	reviewer := &structuredReviewer{
+		fakeRunner: fakeRunner{response: `Issues: [{"file":"a.go","line":1,"severity":"low","description":"typo"}]`},
+		structured: "I could not format that.",
+		err:        &llm.StructuredOutputError{Raw: "I could not format that.", Attempts: 3, Err: errors.New("no JSON")},
+	}
+	o := orchestrator.New(reviewedConfig(), orchestrator.WithRunnerFactory(func(model string) orchestrator.AgentRunner {
+		if model == "reviewer" {
+			return reviewer
+		}
+		return &fakeRunner{response: "TASK_COMPLETE: fixed"}
+	}))
+
+	result, err := o.Run(context.Background(), "fix the broken test in auth.go")
+	require.NoError(t, err)
+	require.NotNil(t, result.Verdict)
+	require.Len(t, result.Verdict.Issues, 1)
+	assert.Equal(t, "typo", result.Verdict.Issues[0].Description)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/agent"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/llm"
 )
 
 type realAgentRunner struct {
@@ -18,6 +19,18 @@ type realAgentRunner struct {
 }
 
 func (r *realAgentRunner) RunGoal(ctx context.Context, goal string) (string, error) {
+	output, _, err := r.run(ctx, goal, "", nil)
+	return output, err
+}
+
+// RunGoalStructured runs the goal, then asks for the result in format.
+func (r *realAgentRunner) RunGoalStructured(ctx context.Context, goal, question string, format *llm.ResponseFormat) (string, string, error) {
+	return r.run(ctx, goal, question, format)
+}
+
+// run executes the goal and, when format is set, follows up with question
+// on the same transcript for a structured answer.
+func (r *realAgentRunner) run(ctx context.Context, goal, question string, format *llm.ResponseFormat) (string, string, error) {
 	cfg := *r.cfg
 	cfg.Model = r.model
 	opts := agent.DefaultOptions()
@@ -96,14 +109,18 @@ func (r *realAgentRunner) RunGoal(ctx context.Context, goal string) (string, err
 
 	runner, err := agent.NewRunner(&cfg, opts, io.Discard, io.Discard)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer runner.Close()
 	state, err := runner.RunGoal(ctx, goal)
-	if state != nil {
-		return state.LastAssistantResponse, err
+	if state == nil {
+		return "", "", err
+	}
+	if err != nil || format == nil {
+		return state.LastAssistantResponse, "", err
 	}
-	return "", err
+	structured, err := runner.AskStructured(ctx, state, question, format)
+	return state.LastAssistantResponse, structured, err
 }
 
 // defaultRunnerFactory creates a RunnerFactory that forwards agent progress
@@ -4,6 +4,8 @@ package permissions
 import (
 	"fmt"
 	"sync"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 )
 
 // ToolInfo is the minimal interface the Checker needs from a tool.
@@ -29,6 +31,8 @@ type Checker struct {
 	patternRules []Rule
 	mode         PermissionMode
 	configPath   string // path to persist rule additions; empty = no persistence
+	sandbox      sandbox.Policy
+	git          GitPolicy
 }
 
 // NewChecker creates a Checker from a PermissionConfig.
@@ -43,6 +47,8 @@ func NewChecker(config PermissionConfig) *Checker {
 		alwaysAllow:  config.AlwaysAllow,
 		patternRules: config.PatternRules,
 		mode:         mode,
+		sandbox:      config.Sandbox,
+		git:          config.Git,
 	}
 }
 
@@ -96,10 +102,26 @@ func (c *Checker) snapshotConfigLocked() (PermissionConfig, string) {
 		AlwaysAllow:  append([]Rule(nil), c.alwaysAllow...),
 		AlwaysDeny:   append([]Rule(nil), c.alwaysDeny...),
 		PatternRules: append([]Rule(nil), c.patternRules...),
+		Sandbox:      c.sandbox,
+		Git:          c.git,
 	}
 	return cfg, c.configPath
 }
 
+// Sandbox returns the policy for commands run by tools the checker allows.
+func (c *Checker) Sandbox() sandbox.Policy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sandbox
+}
+
+// Git returns the git operations the user allows beyond the default.
+func (c *Checker) Git() GitPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.git
+}
+
 // Check evaluates whether the given tool invocation is permitted.
 //
 // The tool parameter provides tool metadata (name, read-only status).
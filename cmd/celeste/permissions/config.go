@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 )
 
 // PermissionConfig holds the persistent permission configuration.
@@ -25,15 +27,24 @@ type PermissionConfig struct {
 	// mode fallthrough. They allow fine-grained control over specific tool/input
 	// combinations.
 	PatternRules []Rule `json:"pattern_rules,omitempty"`
+
+	// Sandbox confines the commands the bash tool, custom tools and hooks
+	// run. Off unless configured.
+	Sandbox sandbox.Policy `json:"sandbox,omitzero"`
+
+	// Git allows history rewrites and force pushes. Off unless configured.
+	Git GitPolicy `json:"git,omitzero"`
 }
 
 // configJSON is the on-disk JSON representation. We use a separate struct
 // to control serialization (e.g., Decision is stored as string, not int).
 type configJSON struct {
-	Mode         string     `json:"mode"`
-	AlwaysAllow  []ruleJSON `json:"always_allow,omitempty"`
-	AlwaysDeny   []ruleJSON `json:"always_deny,omitempty"`
-	PatternRules []ruleJSON `json:"pattern_rules,omitempty"`
+	Mode         string         `json:"mode"`
+	AlwaysAllow  []ruleJSON     `json:"always_allow,omitempty"`
+	AlwaysDeny   []ruleJSON     `json:"always_deny,omitempty"`
+	PatternRules []ruleJSON     `json:"pattern_rules,omitempty"`
+	Sandbox      sandbox.Policy `json:"sandbox,omitzero"`
+	Git          GitPolicy      `json:"git,omitzero"`
 }
 
 type ruleJSON struct {
@@ -98,11 +109,18 @@ func LoadConfig(path string) (*PermissionConfig, error) {
 		mode = parsed
 	}
 
+	policy := raw.Sandbox
+	if policy.Mode, err = sandbox.ParseMode(string(policy.Mode)); err != nil {
+		return nil, fmt.Errorf("permissions config: %w", err)
+	}
+
 	cfg := &PermissionConfig{
 		Mode:         mode,
 		AlwaysAllow:  convertRulesFromJSON(raw.AlwaysAllow, Allow),
 		AlwaysDeny:   convertRulesFromJSON(raw.AlwaysDeny, Deny),
 		PatternRules: convertRulesFromJSON(raw.PatternRules, Ask),
+		Sandbox:      policy,
+		Git:          raw.Git,
 	}
 
 	return cfg, nil
@@ -120,6 +138,8 @@ func SaveConfig(path string, config *PermissionConfig) error {
 		AlwaysAllow:  convertRulesToJSON(config.AlwaysAllow),
 		AlwaysDeny:   convertRulesToJSON(config.AlwaysDeny),
 		PatternRules: convertRulesToJSON(config.PatternRules),
+		Sandbox:      config.Sandbox,
+		Git:          config.Git,
 	}
 
 	data, err := json.MarshalIndent(raw, "", "  ")
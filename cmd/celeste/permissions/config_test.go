@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -117,6 +118,54 @@ func TestLoadConfig_InvalidMode(t *testing.T) {
 	assert.Error(t, err, "invalid mode should return error")
 }
 
+func TestLoadConfig_Sandbox(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "permissions.json")
+	err := os.WriteFile(path, []byte(`{"mode":"trust","sandbox":{"mode":"required","writable":["~/.cache/go-build"],"memory_mb":2048}}`), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, sandbox.ModeRequired, cfg.Sandbox.Mode)
+	assert.Equal(t, []string{"~/.cache/go-build"}, cfg.Sandbox.Writable)
+	assert.Equal(t, 2048, cfg.Sandbox.MemoryMB)
+	assert.Equal(t, sandbox.ModeRequired, NewChecker(*cfg).Sandbox().Mode)
+
+	// Persisting a prompt decision keeps the sandbox section.
+	checker := NewChecker(*cfg)
+	checker.SetConfigPath(path)
+	require.NoError(t, checker.AddPersistentAllow(Rule{ToolPattern: "bash"}))
+	cfg, err = LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, sandbox.ModeRequired, cfg.Sandbox.Mode)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"sandbox":{"mode":"sometimes"}}`), 0644))
+	_, err = LoadConfig(path)
+	assert.Error(t, err, "invalid sandbox mode should return error")
+}
+
+func TestLoadConfig_Git(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "permissions.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"mode":"trust","git":{"allow_history_rewrite":true}}`), 0644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, GitPolicy{AllowHistoryRewrite: true}, cfg.Git)
+
+	// Persisting a prompt decision keeps the git section.
+	checker := NewChecker(*cfg)
+	checker.SetConfigPath(path)
+	require.NoError(t, checker.AddPersistentAllow(Rule{ToolPattern: "git_commit"}))
+	cfg, err = LoadConfig(path)
+	require.NoError(t, err)
+	assert.True(t, NewChecker(*cfg).Git().AllowHistoryRewrite)
+	assert.False(t, cfg.Git.AllowForcePush)
+
+	// Trust mode alone allows neither.
+	assert.Equal(t, GitPolicy{}, NewChecker(PermissionConfig{Mode: ModeTrust}).Git())
+}
+
 func TestSaveConfig_CreatesParentDirs(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "nested", "deep", "permissions.json")
@@ -155,6 +204,7 @@ func TestConfigJSON_Format(t *testing.T) {
 	assert.Contains(t, content, `"always_deny"`)
 	assert.Contains(t, content, `"read_file"`)
 	assert.Contains(t, content, `"bash(sudo *)"`)
+	assert.NotContains(t, content, `"sandbox"`, "an off sandbox is not written")
 }
 
 func TestDefaultConfigPath(t *testing.T) {
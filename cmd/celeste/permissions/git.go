@@ -0,0 +1,29 @@
+// cmd/celeste/permissions/git.go
+package permissions
+
+import "context"
+
+// GitPolicy allows the git operations that can lose committed or published
+// work. The git tools and bash refuse them unless the user opts in here;
+// trust mode does not imply either.
+type GitPolicy struct {
+	// AllowHistoryRewrite permits amending commits, rebasing, resetting a
+	// branch and force-deleting unmerged branches.
+	AllowHistoryRewrite bool `json:"allow_history_rewrite,omitempty"`
+	// AllowForcePush permits git push --force and +refspecs.
+	AllowForcePush bool `json:"allow_force_push,omitempty"`
+}
+
+type gitPolicyKey struct{}
+
+// WithGitPolicy attaches p to ctx for the tools the registry runs.
+func WithGitPolicy(ctx context.Context, p GitPolicy) context.Context {
+	return context.WithValue(ctx, gitPolicyKey{}, p)
+}
+
+// GitPolicyFromContext returns the policy attached to ctx, or the zero
+// policy, which allows neither.
+func GitPolicyFromContext(ctx context.Context) GitPolicy {
+	p, _ := ctx.Value(gitPolicyKey{}).(GitPolicy)
+	return p
+}
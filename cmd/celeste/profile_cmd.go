@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+)
+
+// runConfigDiff handles `celeste config diff <a> <b>`. "default" is
+// config.json.
+func runConfigDiff(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: celeste config diff <profile-a> <profile-b>")
+		os.Exit(1)
+	}
+	profiles := make([]*config.Config, 2)
+	for i, name := range args {
+		var err error
+		if name == config.DefaultProfileName {
+			profiles[i], err = config.Load()
+		} else {
+			profiles[i], err = config.LoadNamed(name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	diffs, err := config.DiffProfiles(profiles[0], profiles[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(diffs) == 0 {
+		fmt.Printf("'%s' and '%s' resolve to the same settings\n", args[0], args[1])
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "SETTING\t%s\t%s\n", args[0], args[1])
+	for _, d := range diffs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Key, d.A, d.B)
+	}
+	_ = tw.Flush()
+}
+
+// profileSelectionNote describes the profile a command without -config
+// uses in the working directory, e.g. "client (pinned by /repo/.grimoire)",
+// or "" when it is the bare config.json.
+func profileSelectionNote() string {
+	wd, _ := os.Getwd()
+	name, source := config.SelectProfile(wd)
+	switch {
+	case source == "":
+		return ""
+	case name == "":
+		name = config.DefaultProfileName
+	}
+	if source == "default profile" {
+		return name + " (default profile)"
+	}
+	return fmt.Sprintf("%s (pinned by %s)", name, source)
+}
@@ -0,0 +1,271 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
+)
+
+// RefreshCatalog fetches the provider's model listing and stores what it
+// reports in the model catalog, replacing the previous listing. OpenRouter,
+// Venice, Gemini and Ollama report context windows, capabilities and (the
+// first two) prices; other OpenAI-compatible endpoints only list IDs. The
+// caller saves the catalog.
+func (s *ModelService) RefreshCatalog(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var models []catalog.Model
+	var err error
+	switch s.provider {
+	case "openrouter":
+		models, err = s.fetchListing(ctx, openRouterModelsURL, parseOpenRouterCatalog)
+	case "venice":
+		models, err = s.fetchListing(ctx, veniceModelsURL, parseVeniceCatalog)
+	case "gemini":
+		models, err = s.fetchListing(ctx, strings.TrimRight(s.geminiBaseURL(), "/")+"/models?pageSize=1000", parseGeminiCatalog)
+	case "ollama":
+		var infos []ModelInfo
+		if infos, err = s.listOllamaModels(ctx); err == nil {
+			for _, info := range infos {
+				if m, ok := catalog.Default().LookupFor("ollama", info.ID); ok {
+					models = append(models, m)
+				}
+			}
+		}
+	default:
+		caps, ok := Registry[s.provider]
+		if !ok || !caps.SupportsModelListing || !caps.IsOpenAICompatible {
+			return 0, fmt.Errorf("%s has no model listing endpoint; the built-in catalog applies", s.provider)
+		}
+		list, lerr := s.client.ListModels(ctx)
+		if lerr != nil {
+			return 0, lerr
+		}
+		for _, m := range list.Models {
+			models = append(models, catalog.Model{ID: m.ID})
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(models) == 0 {
+		return 0, fmt.Errorf("%s listed no models", s.provider)
+	}
+	catalog.Default().SetListing(s.provider, models)
+	return len(models), nil
+}
+
+func (s *ModelService) geminiBaseURL() string {
+	if s.baseURL != "" {
+		return s.baseURL
+	}
+	return Registry["gemini"].BaseURL
+}
+
+// fetchListing GETs url and parses the body with parse.
+func (s *ModelService) fetchListing(ctx context.Context, url string, parse func([]byte) ([]catalog.Model, error)) ([]catalog.Model, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.apiKey != "" {
+		if s.provider == "gemini" {
+			req.Header.Set("x-goog-api-key", s.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+s.apiKey)
+		}
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return parse(body)
+}
+
+// perMillion converts OpenRouter's per-token USD price string. Negative
+// prices (routers priced per request) and unparsable values are unknown.
+func perMillion(perToken string) float64 {
+	v, err := strconv.ParseFloat(perToken, 64)
+	if err != nil || v <= 0 {
+		return 0
+	}
+	return v * 1_000_000
+}
+
+func boolRef(v bool) *bool { return &v }
+
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOpenRouterCatalog parses OpenRouter's /models response. Pure + testable.
+func parseOpenRouterCatalog(body []byte) ([]catalog.Model, error) {
+	var payload struct {
+		Data []struct {
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			ContextLength int    `json:"context_length"`
+			Architecture  struct {
+				InputModalities []string `json:"input_modalities"`
+			} `json:"architecture"`
+			Pricing struct {
+				Prompt          string `json:"prompt"`
+				Completion      string `json:"completion"`
+				InputCacheRead  string `json:"input_cache_read"`
+				InputCacheWrite string `json:"input_cache_write"`
+			} `json:"pricing"`
+			TopProvider struct {
+				MaxCompletionTokens int `json:"max_completion_tokens"`
+			} `json:"top_provider"`
+			SupportedParameters []string `json:"supported_parameters"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("parse OpenRouter models: %w", err)
+	}
+	models := make([]catalog.Model, 0, len(payload.Data))
+	for _, d := range payload.Data {
+		models = append(models, catalog.Model{
+			ID:               d.ID,
+			Name:             d.Name,
+			ContextWindow:    d.ContextLength,
+			MaxOutput:        d.TopProvider.MaxCompletionTokens,
+			Tools:            boolRef(hasString(d.SupportedParameters, "tools")),
+			Vision:           boolRef(hasString(d.Architecture.InputModalities, "image")),
+			Reasoning:        boolRef(hasString(d.SupportedParameters, "reasoning")),
+			InputPrice:       perMillion(d.Pricing.Prompt),
+			OutputPrice:      perMillion(d.Pricing.Completion),
+			CachedInputPrice: perMillion(d.Pricing.InputCacheRead),
+			CacheWritePrice:  perMillion(d.Pricing.InputCacheWrite),
+		})
+	}
+	return models, nil
+}
+
+// parseVeniceCatalog parses Venice's /models response. Pure + testable.
+func parseVeniceCatalog(body []byte) ([]catalog.Model, error) {
+	var payload struct {
+		Data []struct {
+			ID        string `json:"id"`
+			ModelSpec struct {
+				Name                   string `json:"name"`
+				AvailableContextTokens int    `json:"availableContextTokens"`
+				Capabilities           struct {
+					SupportsFunctionCalling bool `json:"supportsFunctionCalling"`
+					SupportsVision          bool `json:"supportsVision"`
+					SupportsReasoning       bool `json:"supportsReasoning"`
+				} `json:"capabilities"`
+				Pricing struct {
+					Input struct {
+						USD float64 `json:"usd"`
+					} `json:"input"`
+					Output struct {
+						USD float64 `json:"usd"`
+					} `json:"output"`
+				} `json:"pricing"`
+			} `json:"model_spec"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("parse Venice models: %w", err)
+	}
+	models := make([]catalog.Model, 0, len(payload.Data))
+	for _, d := range payload.Data {
+		spec := d.ModelSpec
+		models = append(models, catalog.Model{
+			ID:            d.ID,
+			Name:          spec.Name,
+			ContextWindow: spec.AvailableContextTokens,
+			Tools:         boolRef(spec.Capabilities.SupportsFunctionCalling),
+			Vision:        boolRef(spec.Capabilities.SupportsVision),
+			Reasoning:     boolRef(spec.Capabilities.SupportsReasoning),
+			InputPrice:    spec.Pricing.Input.USD,
+			OutputPrice:   spec.Pricing.Output.USD,
+		})
+	}
+	return models, nil
+}
+
+// parseGeminiCatalog parses the Gemini API's /models response, keeping the
+// models that generate content. Pure + testable.
+func parseGeminiCatalog(body []byte) ([]catalog.Model, error) {
+	var payload struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			DisplayName                string   `json:"displayName"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			OutputTokenLimit           int      `json:"outputTokenLimit"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			Thinking                   bool     `json:"thinking"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("parse Gemini models: %w", err)
+	}
+	var models []catalog.Model
+	for _, d := range payload.Models {
+		if !hasString(d.SupportedGenerationMethods, "generateContent") {
+			continue
+		}
+		models = append(models, catalog.Model{
+			ID:            strings.TrimPrefix(d.Name, "models/"),
+			Name:          d.DisplayName,
+			ContextWindow: d.InputTokenLimit,
+			MaxOutput:     d.OutputTokenLimit,
+			Reasoning:     boolRef(d.Thinking),
+		})
+	}
+	return models, nil
+}
+
+// ModelDetails summarises a model's catalog facts for the model picker,
+// e.g. "1.0M context · $3.00/$15.00 per 1M". Empty when nothing is known.
+func ModelDetails(m ModelInfo) string {
+	var parts []string
+	if m.ContextWindow >= 1_000_000 {
+		parts = append(parts, fmt.Sprintf("%.1fM context", float64(m.ContextWindow)/1_000_000))
+	} else if m.ContextWindow > 0 {
+		parts = append(parts, fmt.Sprintf("%dK context", m.ContextWindow/1000))
+	}
+	if m.InputPrice > 0 || m.OutputPrice > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f/$%.2f per 1M", m.InputPrice, m.OutputPrice))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// annotateModels fills the fields of a model list that come from elsewhere:
+// context window and prices from the catalog, and OrchestratesServerSide.
+func annotateModels(models []ModelInfo) []ModelInfo {
+	for i := range models {
+		m := &models[i]
+		if c, ok := catalog.Default().Lookup(m.ID); ok {
+			if c.ContextWindow > 0 {
+				m.ContextWindow = c.ContextWindow
+			}
+			m.InputPrice, m.OutputPrice = c.InputPrice, c.OutputPrice
+		}
+		m.OrchestratesServerSide = OrchestratesServerSide(m.Provider, m.ID)
+	}
+	return models
+}
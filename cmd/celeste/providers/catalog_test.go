@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
+)
+
+func TestParseOpenRouterCatalog(t *testing.T) {
+	body := []byte(`{"data":[
+		{"id":"anthropic/claude-x","name":"Claude X","context_length":200000,
+		 "architecture":{"input_modalities":["text","image"]},
+		 "pricing":{"prompt":"0.000003","completion":"0.000015","input_cache_read":"0.0000003"},
+		 "top_provider":{"max_completion_tokens":64000},
+		 "supported_parameters":["tools","reasoning"]},
+		{"id":"openrouter/auto","context_length":2000000,"pricing":{"prompt":"-1","completion":"-1"}}
+	]}`)
+	models, err := parseOpenRouterCatalog(body)
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+
+	m := models[0]
+	assert.Equal(t, 200000, m.ContextWindow)
+	assert.Equal(t, 64000, m.MaxOutput)
+	assert.True(t, *m.Tools)
+	assert.True(t, *m.Vision)
+	assert.True(t, *m.Reasoning)
+	assert.InDelta(t, 3.0, m.InputPrice, 1e-9)
+	assert.InDelta(t, 15.0, m.OutputPrice, 1e-9)
+	assert.InDelta(t, 0.3, m.CachedInputPrice, 1e-9)
+
+	assert.False(t, models[1].Priced(), "per-request router prices are unknown, not negative")
+	assert.False(t, *models[1].Tools)
+}
+
+func TestParseVeniceCatalog(t *testing.T) {
+	body := []byte(`{"data":[{"id":"venice-uncensored-1-2","model_spec":{"name":"Venice Uncensored 1.2",
+		"availableContextTokens":32768,
+		"capabilities":{"supportsFunctionCalling":true,"supportsVision":false,"supportsReasoning":false},
+		"pricing":{"input":{"usd":0.2,"vcu":2},"output":{"usd":0.9,"vcu":9}}}}]}`)
+	models, err := parseVeniceCatalog(body)
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, 32768, models[0].ContextWindow)
+	assert.True(t, *models[0].Tools)
+	assert.False(t, *models[0].Vision)
+	assert.Equal(t, 0.2, models[0].InputPrice)
+	assert.Equal(t, 0.9, models[0].OutputPrice)
+}
+
+func TestRefreshCatalogFromGemini(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+		_, _ = w.Write([]byte(`{"models":[
+			{"name":"models/gemini-refresh-test","displayName":"Gemini Refresh Test","inputTokenLimit":1048576,
+			 "outputTokenLimit":65536,"supportedGenerationMethods":["generateContent","countTokens"],"thinking":true},
+			{"name":"models/text-embedding-refresh-test","inputTokenLimit":2048,"supportedGenerationMethods":["embedContent"]}
+		]}`))
+	}))
+	defer srv.Close()
+
+	s := NewModelService("test-key", srv.URL+"/v1beta", "gemini")
+	n, err := s.RefreshCatalog(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "embedding models are not chat models")
+
+	m, ok := catalog.Default().LookupFor("gemini", "gemini-refresh-test")
+	require.True(t, ok)
+	assert.Equal(t, 1048576, m.ContextWindow)
+	assert.Equal(t, 65536, m.MaxOutput)
+	assert.True(t, *m.Reasoning)
+	assert.False(t, catalog.Default().Stale("gemini"))
+}
+
+func TestSupportsToolsPrefersCatalog(t *testing.T) {
+	// gpt-5.4 does not match the name heuristic; the catalog knows better.
+	assert.True(t, NewModelDetection("openai").SupportsTools("gpt-5.4"))
+	// The same ID on an agent endpoint is not OpenAI's model.
+	assert.False(t, NewModelDetection("digitalocean").SupportsTools("gpt-5.4"))
+}
+
+func TestModelDetails(t *testing.T) {
+	assert.Equal(t, "1.0M context · $3.00/$15.00 per 1M", ModelDetails(ModelInfo{ContextWindow: 1000000, InputPrice: 3, OutputPrice: 15}))
+	assert.Equal(t, "128K context", ModelDetails(ModelInfo{ContextWindow: 128000}))
+	assert.Empty(t, ModelDetails(ModelInfo{}))
+}
@@ -4,6 +4,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"time"
@@ -15,9 +16,12 @@ import (
 
 // ModelService handles model listing and metadata.
 type ModelService struct {
-	client   *openai.Client
-	provider string
-	detector *ModelDetection
+	client     *openai.Client
+	httpClient *http.Client // native (non-OpenAI) listing APIs
+	apiKey     string
+	baseURL    string
+	provider   string
+	detector   *ModelDetection
 }
 
 // NewModelService creates a new model service for a provider.
@@ -28,9 +32,12 @@ func NewModelService(apiKey, baseURL, provider string) *ModelService {
 	}
 
 	return &ModelService{
-		client:   openai.NewClientWithConfig(config),
-		provider: provider,
-		detector: NewModelDetection(provider),
+		client:     openai.NewClientWithConfig(config),
+		httpClient: http.DefaultClient,
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		provider:   provider,
+		detector:   NewModelDetection(provider),
 	}
 }
 
@@ -47,6 +54,16 @@ func (s *ModelService) ListModels(ctx context.Context) ([]ModelInfo, error) {
 		return s.getStaticModels(), nil
 	}
 
+	// Ollama's native API has no /v1/models; it lists with /api/tags and
+	// reports context windows through /api/show.
+	if s.provider == "ollama" {
+		models, err := s.listOllamaModels(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Ollama model listing failed: %w", err)
+		}
+		return annotateModels(models), nil
+	}
+
 	// Add timeout to context
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -74,7 +91,7 @@ func (s *ModelService) ListModels(ctx context.Context) ([]ModelInfo, error) {
 	// Sort: Tool-capable models first
 	sortModelsByCapability(result)
 
-	return result, nil
+	return annotateModels(result), nil
 }
 
 // GetBestToolModel returns the recommended model for function calling.
@@ -106,7 +123,8 @@ func (s *ModelService) ValidateModel(ctx context.Context, modelID string) (Model
 	return ModelInfo{}, fmt.Errorf("model %s not found for provider %s", modelID, s.provider)
 }
 
-// getStaticModels returns hardcoded model list when API isn't available.
+// getStaticModels returns the curated model list offered when the API can't
+// list models. Context windows and prices come from the catalog.
 func (s *ModelService) getStaticModels() []ModelInfo {
 	switch s.provider {
 	case "grok":
@@ -116,7 +134,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Grok 4.20 (non-reasoning)",
 				Provider:      "grok",
 				SupportsTools: true,
-				ContextWindow: 1000000,
 				Description:   "Default — reliable tool calling, no reasoning-token burn, never routes to the cost-prohibitive grok-4.3 (#51)",
 			},
 			{
@@ -124,7 +141,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Grok Build 0.1",
 				Provider:      "grok",
 				SupportsTools: true,
-				ContextWindow: 256000,
 				Description:   "Grok code variant (256K). Note: still emits reasoning tokens.",
 			},
 			{
@@ -132,7 +148,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Grok Beta",
 				Provider:      "grok",
 				SupportsTools: true,
-				ContextWindow: 131072,
 				Description:   "Beta version with tool calling",
 			},
 			{
@@ -140,14 +155,10 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Grok 4 Latest",
 				Provider:      "grok",
 				SupportsTools: false, // Not optimized for tools
-				ContextWindow: 131072,
 				Description:   "Latest general model (limited tool support)",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	case "openai":
 		models := []ModelInfo{
@@ -156,7 +167,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "GPT-4o Mini",
 				Provider:      "openai",
 				SupportsTools: true,
-				ContextWindow: 128000,
 				Description:   "Fast, affordable, smart for everyday tasks",
 			},
 			{
@@ -164,7 +174,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "GPT-4o",
 				Provider:      "openai",
 				SupportsTools: true,
-				ContextWindow: 128000,
 				Description:   "High intelligence flagship model",
 			},
 			{
@@ -172,7 +181,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "GPT-4 Turbo",
 				Provider:      "openai",
 				SupportsTools: true,
-				ContextWindow: 128000,
 				Description:   "Previous flagship with vision and tools",
 			},
 			{
@@ -180,14 +188,10 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "GPT-3.5 Turbo",
 				Provider:      "openai",
 				SupportsTools: true,
-				ContextWindow: 16385,
 				Description:   "Fast and affordable legacy model",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	case "venice":
 		models := []ModelInfo{
@@ -213,10 +217,7 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Description:   "Large open model with function calling",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	case "anthropic":
 		models := []ModelInfo{
@@ -225,7 +226,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Claude Sonnet 4.5",
 				Provider:      "anthropic",
 				SupportsTools: true,
-				ContextWindow: 200000,
 				Description:   "Latest Sonnet with advanced tool use",
 			},
 			{
@@ -233,14 +233,10 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Claude Opus 4.5",
 				Provider:      "anthropic",
 				SupportsTools: true,
-				ContextWindow: 200000,
 				Description:   "Most capable Claude model",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	case "vertex":
 		models := []ModelInfo{
@@ -249,7 +245,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Gemini 1.5 Pro",
 				Provider:      "vertex",
 				SupportsTools: true,
-				ContextWindow: 2000000,
 				Description:   "Google's flagship with function calling",
 			},
 			{
@@ -257,14 +252,10 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Gemini 1.5 Flash",
 				Provider:      "vertex",
 				SupportsTools: true,
-				ContextWindow: 1000000,
 				Description:   "Fast and efficient with tools",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	case "openrouter":
 		models := []ModelInfo{
@@ -283,10 +274,7 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Description:   "Claude via OpenRouter",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	case "sakana":
 		models := []ModelInfo{
@@ -295,7 +283,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Fugu",
 				Provider:      "sakana",
 				SupportsTools: true,
-				ContextWindow: 1000000,
 				Description:   "Default — 1M context, high-effort deep reasoning",
 			},
 			{
@@ -303,7 +290,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Fugu Ultra",
 				Provider:      "sakana",
 				SupportsTools: true,
-				ContextWindow: 1000000,
 				Description:   "1M context, deep reasoning with reasoning summaries",
 			},
 			{
@@ -311,7 +297,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Fugu Ultra (2026-06-15)",
 				Provider:      "sakana",
 				SupportsTools: true,
-				ContextWindow: 1000000,
 				Description:   "Dated alias pin of fugu-ultra",
 			},
 			{
@@ -319,7 +304,6 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Fugu Ultra v1.0",
 				Provider:      "sakana",
 				SupportsTools: true,
-				ContextWindow: 1000000,
 				Description:   "Versioned Ultra release — high/xhigh reasoning effort",
 			},
 			{
@@ -327,14 +311,10 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Name:          "Fugu Ultra v1.1",
 				Provider:      "sakana",
 				SupportsTools: true,
-				ContextWindow: 1000000,
 				Description:   "Latest Ultra — adds 'max' reasoning effort above xhigh",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	case "digitalocean":
 		models := []ModelInfo{
@@ -346,10 +326,7 @@ func (s *ModelService) getStaticModels() []ModelInfo {
 				Description:   "Agent endpoint (no local skills)",
 			},
 		}
-		for i := range models {
-			models[i].OrchestratesServerSide = OrchestratesServerSide(models[i].Provider, models[i].ID)
-		}
-		return models
+		return annotateModels(models)
 
 	default:
 		return []ModelInfo{}
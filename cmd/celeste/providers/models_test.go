@@ -405,7 +405,7 @@ func TestOpenAIStaticModels(t *testing.T) {
 	for _, model := range models {
 		if model.ID == "gpt-4.1-nano" {
 			found = true
-			assert.Equal(t, 128000, model.ContextWindow, "Should have 128k context")
+			assert.Equal(t, 400000, model.ContextWindow, "Should have the 400k window budgeting uses")
 		}
 	}
 	assert.True(t, found, "Should have gpt-4.1-nano")
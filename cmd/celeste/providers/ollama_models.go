@@ -0,0 +1,289 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
+)
+
+// OllamaDefaultBaseURL is where `ollama serve` listens by default. Note there
+// is no /v1: that suffix selects Ollama's OpenAI-compatible layer, which
+// stays on the generic "local" provider.
+const OllamaDefaultBaseURL = "http://localhost:11434"
+
+const ollamaDefaultPort = "11434"
+
+// ollamaShowTimeout bounds /api/show. It is called on the startup path to
+// size the context budget, and a local server that takes longer than this is
+// still loading; the conservative default is the right answer then.
+const ollamaShowTimeout = 3 * time.Second
+
+// ollamaURLs holds base URLs a config declared native Ollama with
+// "provider": "ollama", keyed by OllamaAPIBase.
+var ollamaURLs sync.Map
+
+// MarkOllamaURL records that baseURL serves Ollama's native API. Config
+// loading calls it for profiles that say so, which is how a server on another
+// port or behind a proxy is recognised.
+func MarkOllamaURL(baseURL string) {
+	if strings.TrimSpace(baseURL) == "" {
+		return
+	}
+	ollamaURLs.Store(OllamaAPIBase(baseURL), struct{}{})
+}
+
+// IsOllamaURL reports whether baseURL points at Ollama's native API: Ollama's
+// default port, or a URL marked with MarkOllamaURL, without the
+// OpenAI-compatible /v1 suffix. A host name proves nothing — "ollama" in it
+// may just as well front the /v1 layer or another server entirely.
+func IsOllamaURL(baseURL string) bool {
+	u := strings.TrimRight(baseURL, "/")
+	if u == "" || strings.HasSuffix(u, "/v1") {
+		return false
+	}
+	if _, ok := ollamaURLs.Load(OllamaAPIBase(u)); ok {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	return err == nil && parsed.Port() == ollamaDefaultPort
+}
+
+// OllamaAPIBase normalizes a configured base URL to the server root, so
+// "http://host:11434", ".../" and ".../api" all address the same endpoints.
+func OllamaAPIBase(baseURL string) string {
+	u := strings.TrimRight(baseURL, "/")
+	u = strings.TrimSuffix(u, "/api")
+	if u == "" {
+		return OllamaDefaultBaseURL
+	}
+	return u
+}
+
+// OllamaModelDetails is the subset of /api/show celeste uses.
+type OllamaModelDetails struct {
+	ContextLength int
+	Capabilities  []string // "completion", "tools", "vision", "thinking", ...
+	Family        string
+	ParameterSize string
+	Quantization  string
+}
+
+// SupportsTools reports whether the model's template accepts tools. Servers
+// too old to report capabilities are assumed to, as for "local".
+func (d OllamaModelDetails) SupportsTools() bool {
+	if len(d.Capabilities) == 0 {
+		return true
+	}
+	for _, c := range d.Capabilities {
+		if c == "tools" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCapability reports whether the server listed c. Servers too old to
+// report capabilities say nothing either way.
+func (d OllamaModelDetails) hasCapability(c string) *bool {
+	if len(d.Capabilities) == 0 {
+		return nil
+	}
+	has := false
+	for _, have := range d.Capabilities {
+		if have == c {
+			has = true
+			break
+		}
+	}
+	return &has
+}
+
+// catalogModel is what the server reported about model, for the catalog.
+func (d OllamaModelDetails) catalogModel(model string) catalog.Model {
+	return catalog.Model{
+		ID:            model,
+		ContextWindow: d.ContextLength,
+		Tools:         d.hasCapability("tools"),
+		Vision:        d.hasCapability("vision"),
+		Reasoning:     d.hasCapability("thinking"),
+	}
+}
+
+// parseOllamaShow extracts OllamaModelDetails from an /api/show response.
+// The context length comes from the Modelfile's num_ctx when set — that is
+// what the server allocates — and otherwise from the model's trained window
+// ("<arch>.context_length" in model_info). Pure + testable.
+func parseOllamaShow(body []byte) (OllamaModelDetails, error) {
+	var payload struct {
+		Parameters   string         `json:"parameters"`
+		ModelInfo    map[string]any `json:"model_info"`
+		Capabilities []string       `json:"capabilities"`
+		Details      struct {
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return OllamaModelDetails{}, err
+	}
+	d := OllamaModelDetails{
+		Capabilities:  payload.Capabilities,
+		Family:        payload.Details.Family,
+		ParameterSize: payload.Details.ParameterSize,
+		Quantization:  payload.Details.QuantizationLevel,
+	}
+	for _, line := range strings.Split(payload.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 {
+				d.ContextLength = n
+			}
+		}
+	}
+	if d.ContextLength == 0 {
+		for k, v := range payload.ModelInfo {
+			if !strings.HasSuffix(k, ".context_length") {
+				continue
+			}
+			if n, ok := v.(float64); ok && n > 0 {
+				d.ContextLength = int(n)
+			}
+		}
+	}
+	return d, nil
+}
+
+// ollamaRequest issues a request against the native API and returns the body
+// of a 200 response.
+func ollamaRequest(ctx context.Context, client *http.Client, method, url string, payload any) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama %s: status code %d: %s", url, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// FetchOllamaModelDetails calls /api/show for one model.
+func FetchOllamaModelDetails(ctx context.Context, client *http.Client, baseURL, model string) (OllamaModelDetails, error) {
+	data, err := ollamaRequest(ctx, client, http.MethodPost, OllamaAPIBase(baseURL)+"/api/show", map[string]string{"model": model})
+	if err != nil {
+		return OllamaModelDetails{}, err
+	}
+	return parseOllamaShow(data)
+}
+
+var ollamaContextCache sync.Map // baseURL+"\x00"+model -> int (0 = lookup failed)
+
+// OllamaContextLength returns the context window the Ollama server at baseURL
+// uses for model, adding it to the model catalog so budgeting sees it. The
+// answer is cached per process, including failures, so an unreachable server
+// costs one timeout rather than one per caller.
+func OllamaContextLength(baseURL, model string) (int, bool) {
+	if model == "" {
+		return 0, false
+	}
+	key := OllamaAPIBase(baseURL) + "\x00" + model
+	if v, ok := ollamaContextCache.Load(key); ok {
+		n := v.(int)
+		return n, n > 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaShowTimeout)
+	defer cancel()
+	d, err := FetchOllamaModelDetails(ctx, http.DefaultClient, baseURL, model)
+	n := 0
+	if err == nil {
+		n = d.ContextLength
+	}
+	ollamaContextCache.Store(key, n)
+	if err == nil {
+		catalog.Default().Add("ollama", d.catalogModel(model))
+	}
+	return n, n > 0
+}
+
+// listOllamaModels lists installed models via /api/tags and enriches each
+// with /api/show. A model whose details cannot be read is still listed.
+func (s *ModelService) listOllamaModels(ctx context.Context) ([]ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	data, err := ollamaRequest(ctx, s.httpClient, http.MethodGet, OllamaAPIBase(s.baseURL)+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	var tags struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("parse /api/tags: %w", err)
+	}
+
+	var result []ModelInfo
+	for _, m := range tags.Models {
+		id := m.Name
+		if id == "" {
+			id = m.Model
+		}
+		info := ModelInfo{ID: id, Name: id, Provider: s.provider, SupportsTools: true, Description: "Local model"}
+		if d, err := FetchOllamaModelDetails(ctx, s.httpClient, s.baseURL, id); err == nil {
+			info.SupportsTools = d.SupportsTools()
+			info.ContextWindow = d.ContextLength
+			info.Description = ollamaDescription(d)
+			catalog.Default().Add("ollama", d.catalogModel(id))
+			ollamaContextCache.Store(OllamaAPIBase(s.baseURL)+"\x00"+id, d.ContextLength)
+		}
+		result = append(result, info)
+	}
+	sortModelsByCapability(result)
+	return result, nil
+}
+
+func ollamaDescription(d OllamaModelDetails) string {
+	var parts []string
+	for _, p := range []string{d.Family, d.ParameterSize, d.Quantization} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return "Local model"
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ctxmgr "github.com/whykusanagi/celeste-cli/cmd/celeste/context"
+)
+
+func TestParseOllamaShow(t *testing.T) {
+	d, err := parseOllamaShow([]byte(`{
+		"parameters": "stop \"<|eot_id|>\"\nnum_ctx 32768",
+		"model_info": {"general.architecture": "llama", "llama.context_length": 131072},
+		"capabilities": ["completion", "tools"],
+		"details": {"family": "llama", "parameter_size": "8.0B", "quantization_level": "Q4_K_M"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, 32768, d.ContextLength, "the Modelfile's num_ctx is what the server allocates")
+	assert.True(t, d.SupportsTools())
+	assert.Equal(t, "llama · 8.0B · Q4_K_M", ollamaDescription(d))
+
+	d, err = parseOllamaShow([]byte(`{"model_info": {"qwen2.context_length": 40960}, "capabilities": ["completion"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, 40960, d.ContextLength)
+	assert.False(t, d.SupportsTools())
+}
+
+func TestOllamaListModelsRegistersContextLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			fmt.Fprint(w, `{"models":[{"name":"celeste-test-a:8b"},{"name":"celeste-test-b:3b"}]}`)
+		case "/api/show":
+			var req struct{ Model string }
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Model == "celeste-test-a:8b" {
+				fmt.Fprint(w, `{"model_info":{"llama.context_length":131072},"capabilities":["completion","tools"]}`)
+				return
+			}
+			fmt.Fprint(w, `{"model_info":{"gemma.context_length":8192},"capabilities":["completion"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc := NewModelService("", srv.URL, "ollama")
+	models, err := svc.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, "celeste-test-a:8b", models[0].ID, "tool-capable models sort first")
+	assert.True(t, models[0].SupportsTools)
+	assert.Equal(t, 131072, models[0].ContextWindow)
+	assert.False(t, models[1].SupportsTools)
+
+	limit, known := ctxmgr.LookupModelLimit("celeste-test-b:3b")
+	assert.True(t, known)
+	assert.Equal(t, 8192, limit)
+
+	n, ok := OllamaContextLength(srv.URL+"/", "celeste-test-a:8b")
+	assert.True(t, ok)
+	assert.Equal(t, 131072, n)
+}
+
+func TestDetectProviderOllama(t *testing.T) {
+	assert.Equal(t, "ollama", DetectProvider("http://localhost:11434"))
+	assert.Equal(t, "ollama", DetectProvider("http://gpu-box:11434/"))
+	assert.Equal(t, "local", DetectProvider("http://localhost:11434/v1"), "the /v1 layer is OpenAI-compatible")
+	assert.Equal(t, "unknown", DetectProvider("https://ollama.example.com"), "a host name is not proof")
+	assert.Equal(t, "unknown", DetectProvider("http://box:8080/my-ollama-proxy"))
+	assert.Equal(t, "local", DetectProvider("http://localhost:8080?note=114340"))
+
+	MarkOllamaURL("http://ollama-box.test:8080/")
+	assert.Equal(t, "ollama", DetectProvider("http://ollama-box.test:8080"))
+	assert.Equal(t, "ollama", DetectProvider("http://ollama-box.test:8080/api"))
+	assert.False(t, IsOllamaURL("http://ollama-box.test:8080/v1"))
+}
@@ -1,7 +1,12 @@
 // Package providers handles LLM provider capabilities and model management.
 package providers
 
-import "sort"
+import (
+	"sort"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/catalog"
+)
 
 // ProviderCapabilities defines what a provider supports.
 type ProviderCapabilities struct {
@@ -9,12 +14,30 @@ type ProviderCapabilities struct {
 	BaseURL                 string
 	SupportsFunctionCalling bool
 	SupportsModelListing    bool
-	SupportsTokenTracking   bool // Returns usage data with stream_options
+	SupportsTokenTracking   bool   // Returns usage data with stream_options
+	StructuredOutput        bool   // Constrains responses to a JSON schema natively
+	BatchAPI                string // Batch protocol ("openai", "anthropic"); empty = no batch API
 	DefaultModel            string
 	PreferredToolModel      string // Best model for function calling
 	RequiresAPIKey          bool
 	IsOpenAICompatible      bool
-	Notes                   string
+	// ResponsesAPIModels lists model ID prefixes that are sent to the
+	// provider's Responses API instead of Chat Completions. Reasoning models
+	// keep their reasoning between tool turns only there.
+	ResponsesAPIModels []string
+	Notes              string
+}
+
+// UsesResponsesAPI reports whether model is served through the provider's
+// Responses API rather than Chat Completions.
+func (c ProviderCapabilities) UsesResponsesAPI(model string) bool {
+	m := strings.ToLower(model)
+	for _, prefix := range c.ResponsesAPIModels {
+		if strings.HasPrefix(m, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // ModelInfo represents metadata about a model.
@@ -25,6 +48,10 @@ type ModelInfo struct {
 	ContextWindow int
 	Description   string
 	Provider      string
+	// InputPrice and OutputPrice are USD per 1M tokens from the model
+	// catalog; zero when unknown.
+	InputPrice  float64
+	OutputPrice float64
 	// OrchestratesServerSide mirrors OrchestratesServerSide(Provider, ID) so
 	// display surfaces (/config, the model picker) can show it without
 	// recomputing. The function stays the single source of truth.
@@ -42,11 +69,16 @@ var Registry = map[string]ProviderCapabilities{
 		SupportsFunctionCalling: true,
 		SupportsModelListing:    true,
 		SupportsTokenTracking:   true, // Full support via stream_options
+		StructuredOutput:        true,
+		BatchAPI:                "openai",
 		DefaultModel:            "gpt-4.1-nano",
 		PreferredToolModel:      "gpt-4.1-nano",
 		RequiresAPIKey:          true,
 		IsOpenAICompatible:      true,
-		Notes:                   "Native function calling support. Gold standard implementation.",
+		// Reasoning models go through /v1/responses so their reasoning
+		// items survive tool turns instead of being discarded each request.
+		ResponsesAPIModels: []string{"o1", "o3", "o4", "gpt-5", "codex"},
+		Notes:              "Native function calling support. Gold standard implementation.",
 	},
 
 	"grok": {
@@ -55,6 +87,7 @@ var Registry = map[string]ProviderCapabilities{
 		SupportsFunctionCalling: true,
 		SupportsModelListing:    true,
 		SupportsTokenTracking:   true, // OpenAI-compatible token tracking
+		StructuredOutput:        true,
 		DefaultModel:            "grok-4.20-0309-non-reasoning",
 		PreferredToolModel:      "grok-4.20-0309-non-reasoning", // non-reasoning: reliable tool use, no reasoning burn, no grok-4.3 routing (#51)
 		RequiresAPIKey:          true,
@@ -83,6 +116,8 @@ var Registry = map[string]ProviderCapabilities{
 		SupportsFunctionCalling: true,
 		SupportsModelListing:    false, // Anthropic has fixed model list
 		SupportsTokenTracking:   false, // Uses native API with different usage format
+		StructuredOutput:        true,
+		BatchAPI:                "anthropic",
 		DefaultModel:            "claude-sonnet-4-5-20250929",
 		PreferredToolModel:      "claude-sonnet-4-5-20250929",
 		RequiresAPIKey:          true,
@@ -96,6 +131,7 @@ var Registry = map[string]ProviderCapabilities{
 		SupportsFunctionCalling: true,
 		SupportsModelListing:    false,
 		SupportsTokenTracking:   true,
+		StructuredOutput:        true,
 		// gemini-flash-latest is a Google-maintained alias that currently
 		// resolves to gemini-3.6-flash. Pinning an alias rather than a version
 		// is deliberate: the previous default (gemini-2.0-flash) was retired
@@ -114,6 +150,7 @@ var Registry = map[string]ProviderCapabilities{
 		SupportsFunctionCalling: true,
 		SupportsModelListing:    false,
 		SupportsTokenTracking:   true,
+		StructuredOutput:        true,
 		// UNVERIFIED (2026-08-08): gemini-2.0-flash was retired on AI Studio and
 		// its default moved to the gemini-flash-latest alias. Vertex is a
 		// separate service on aiplatform.googleapis.com with its own model
@@ -147,12 +184,32 @@ var Registry = map[string]ProviderCapabilities{
 		Notes:                 "Any OpenAI-compatible server on localhost (mlx-vlm, Ollama, LM Studio, llama.cpp). Set the model to whatever the server expects — mlx-vlm wants the full filesystem path. No API key required.",
 	},
 
+	// Ollama's native API (/api/chat). Selected by the default port, or by
+	// "provider": "ollama" in the config, without a /v1 suffix;
+	// http://localhost:11434/v1 is the OpenAI-compatible layer and stays
+	// "local". The native API lists models,
+	// reports each model's context window, and parses tool calls server-side.
+	"ollama": {
+		Name:                    "Ollama (native)",
+		BaseURL:                 OllamaDefaultBaseURL,
+		SupportsFunctionCalling: true,
+		SupportsModelListing:    true, // /api/tags + /api/show
+		SupportsTokenTracking:   true, // prompt_eval_count / eval_count
+		StructuredOutput:        true,
+		DefaultModel:            "",
+		PreferredToolModel:      "",
+		RequiresAPIKey:          false,
+		IsOpenAICompatible:      false,
+		Notes:                   "Native Ollama API at http://localhost:11434 (no /v1). Lists installed models and sizes the context budget from /api/show. Set ollama_keep_alive to control how long the model stays loaded. No API key required.",
+	},
+
 	"openrouter": {
 		Name:                    "OpenRouter",
 		BaseURL:                 "https://openrouter.ai/api/v1",
 		SupportsFunctionCalling: true,
 		SupportsModelListing:    true,
 		SupportsTokenTracking:   true, // OpenAI-compatible
+		StructuredOutput:        true,
 		DefaultModel:            "openai/gpt-4.1-nano",
 		PreferredToolModel:      "openai/gpt-4.1-nano",
 		RequiresAPIKey:          true,
@@ -271,6 +328,8 @@ func DetectProvider(baseURL string) string {
 		return "digitalocean"
 	case contains(baseURL, "elevenlabs.io"):
 		return "elevenlabs"
+	case IsOllamaURL(baseURL):
+		return "ollama"
 	// Local servers, checked LAST so a hosted provider can never be shadowed.
 	// Matching on host substrings only: an empty baseURL contains none of them
 	// and still falls through to "unknown".
@@ -307,6 +366,13 @@ func NewModelDetection(provider string) *ModelDetection {
 
 // SupportsTools determines if a model supports function calling.
 func (d *ModelDetection) SupportsTools(modelID string) bool {
+	// The catalog knows per model: built in, reported by the provider's
+	// listing or overridden in config. The cases below are heuristics for
+	// models it has not heard of.
+	if m, ok := catalog.Default().LookupFor(d.provider, modelID); ok && m.Tools != nil {
+		return *m.Tools
+	}
+
 	switch d.provider {
 	case "openai":
 		// All gpt-4* and gpt-3.5-turbo* support tools
@@ -348,6 +414,11 @@ func (d *ModelDetection) SupportsTools(modelID string) bool {
 		// tools array at all and the model improvises an unparseable text block.
 		return true
 
+	case "ollama":
+		// Same reasoning as "local". ListModels refines this per model from
+		// the server's reported capabilities.
+		return true
+
 	case "openrouter":
 		// Prefer OpenRouter's live catalog (authoritative per-model capability:
 		// supported_parameters includes "tools"). Falls through to a name
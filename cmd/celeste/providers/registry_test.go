@@ -19,7 +19,7 @@ func TestProviderCount(t *testing.T) {
 		"openai", "grok", "venice",
 		"anthropic", "gemini", "vertex",
 		"openrouter", "digitalocean", "elevenlabs",
-		"sakana", "local",
+		"sakana", "local", "ollama",
 	}
 
 	assert.Equal(t, len(expectedProviders), len(Registry),
@@ -65,7 +65,7 @@ func TestListProviders(t *testing.T) {
 	providers := ListProviders()
 
 	assert.NotEmpty(t, providers, "ListProviders should return providers")
-	assert.Equal(t, 11, len(providers), "Should return all 11 providers")
+	assert.Equal(t, 12, len(providers), "Should return all 12 providers")
 	assert.Equal(t, []string{
 		"anthropic",
 		"digitalocean",
@@ -73,6 +73,7 @@ func TestListProviders(t *testing.T) {
 		"gemini",
 		"grok",
 		"local",
+		"ollama",
 		"openai",
 		"openrouter",
 		"sakana",
@@ -103,6 +104,7 @@ func TestGetToolCallingProviders(t *testing.T) {
 		"gemini",
 		"grok",
 		"local",
+		"ollama",
 		"openai",
 		"openrouter",
 		"sakana",
@@ -208,8 +210,9 @@ func TestProviderCapabilities(t *testing.T) {
 
 			// If provider supports function calling, it should have a preferred tool
 			// model. "local" is exempt: the model name is whatever the user's
-			// server expects, and mlx-vlm wants a full filesystem path.
-			if caps.SupportsFunctionCalling && name != "local" {
+			// server expects, and mlx-vlm wants a full filesystem path. "ollama"
+			// likewise serves only what the user has pulled.
+			if caps.SupportsFunctionCalling && name != "local" && name != "ollama" {
 				assert.NotEmpty(t, caps.PreferredToolModel,
 					"Tool-capable provider should have a preferred tool model")
 			}
@@ -218,7 +221,7 @@ func TestProviderCapabilities(t *testing.T) {
 			// ElevenLabs, and "local"). Inventing a default for a local server
 			// would be actively harmful: mlx-vlm treats an unrecognised model
 			// name as a HuggingFace repo id and 404s.
-			if name != "elevenlabs" && name != "local" {
+			if name != "elevenlabs" && name != "local" && name != "ollama" {
 				assert.NotEmpty(t, caps.DefaultModel, "Provider should have a default model")
 			}
 
@@ -247,6 +250,19 @@ func TestOpenAIProvider(t *testing.T) {
 	assert.NotEmpty(t, caps.PreferredToolModel)
 }
 
+func TestUsesResponsesAPI(t *testing.T) {
+	caps, _ := GetProvider("openai")
+	for _, model := range []string{"o3", "o4-mini", "gpt-5", "GPT-5-mini", "codex-mini-latest"} {
+		assert.True(t, caps.UsesResponsesAPI(model), model)
+	}
+	for _, model := range []string{"gpt-4.1-nano", "gpt-4o", ""} {
+		assert.False(t, caps.UsesResponsesAPI(model), model)
+	}
+
+	grok, _ := GetProvider("grok")
+	assert.False(t, grok.UsesResponsesAPI("o3"), "only providers that list prefixes use the Responses API")
+}
+
 // TestGrokProvider specifically tests the Grok provider
 func TestGrokProvider(t *testing.T) {
 	caps, ok := GetProvider("grok")
@@ -0,0 +1,271 @@
+package research
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Backend names accepted by NewBackend.
+const (
+	BackendDuckDuckGo = "duckduckgo"
+	BackendSearXNG    = "searxng"
+	BackendBrave      = "brave"
+	BackendLocal      = "local"
+)
+
+// userAgent identifies celeste to search engines and sites.
+const userAgent = "Mozilla/5.0 (compatible; CelesteCLI/1.8)"
+
+// maxSearchResponse caps the search response body read.
+const maxSearchResponse = 2 * 1024 * 1024
+
+// Result is one search hit.
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// Backend is a web search engine.
+type Backend interface {
+	Name() string
+	// Search returns up to n results for query, best first.
+	Search(ctx context.Context, query string, n int) ([]Result, error)
+}
+
+// BackendConfig selects and configures a backend.
+type BackendConfig struct {
+	Name   string // one of the Backend* names; "" is duckduckgo
+	URL    string // SearXNG instance, or an override of the engine's endpoint
+	APIKey string // Brave Search subscription token
+}
+
+// NewBackend builds the backend cfg names. The local backend searches
+// pages already in cache, so it works offline.
+func NewBackend(cfg BackendConfig, cache *Cache) (Backend, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	switch strings.ToLower(cfg.Name) {
+	case "", BackendDuckDuckGo:
+		return &DuckDuckGo{Endpoint: cfg.URL, Client: client}, nil
+	case BackendSearXNG:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("the searxng backend needs search_url set to the instance URL")
+		}
+		return &SearXNG{URL: cfg.URL, Client: client}, nil
+	case BackendBrave:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the brave backend needs brave_api_key")
+		}
+		return &Brave{APIKey: cfg.APIKey, Endpoint: cfg.URL, Client: client}, nil
+	case BackendLocal:
+		if cache == nil {
+			return nil, fmt.Errorf("the local backend needs a page cache")
+		}
+		return &Local{Cache: cache}, nil
+	default:
+		return nil, fmt.Errorf("unknown search backend %q (use %s, %s, %s or %s)",
+			cfg.Name, BackendDuckDuckGo, BackendSearXNG, BackendBrave, BackendLocal)
+	}
+}
+
+// getJSON issues a GET and decodes a JSON response into v.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, header http.Header, v any) error {
+	body, err := get(ctx, client, endpoint, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+func get(ctx context.Context, client *http.Client, endpoint string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSearchResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// DuckDuckGo scrapes DuckDuckGo's HTML results page. It needs no key.
+type DuckDuckGo struct {
+	Endpoint string // "" = https://html.duckduckgo.com/html/
+	Client   *http.Client
+}
+
+func (d *DuckDuckGo) Name() string { return BackendDuckDuckGo }
+
+func (d *DuckDuckGo) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	endpoint := d.Endpoint
+	if endpoint == "" {
+		endpoint = "https://html.duckduckgo.com/html/"
+	}
+	body, err := get(ctx, d.Client, endpoint+"?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	return limitResults(ParseDuckDuckGo(string(body)), n), nil
+}
+
+var (
+	ddgTitleRe   = regexp.MustCompile(`class="result__a"[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	ddgSnippetRe = regexp.MustCompile(`class="result__snippet"[^>]*>(.*?)</(?:a|td|span)`)
+	tagRe        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseDuckDuckGo extracts up to 10 results from DuckDuckGo's HTML page.
+// Result links go through a redirect; the real URL is its uddg parameter.
+func ParseDuckDuckGo(html string) []Result {
+	var results []Result
+	titles := ddgTitleRe.FindAllStringSubmatch(html, 10)
+	snippets := ddgSnippetRe.FindAllStringSubmatch(html, 10)
+	for i, m := range titles {
+		rawURL := m[1]
+		if u, err := url.Parse(rawURL); err == nil {
+			if actual := u.Query().Get("uddg"); actual != "" {
+				rawURL = actual
+			}
+		}
+		snippet := ""
+		if i < len(snippets) {
+			snippet = StripTags(snippets[i][1])
+		}
+		results = append(results, Result{Title: StripTags(m[2]), URL: rawURL, Snippet: snippet})
+	}
+	return results
+}
+
+// StripTags removes HTML tags and surrounding space from s.
+func StripTags(s string) string {
+	return strings.TrimSpace(tagRe.ReplaceAllString(s, ""))
+}
+
+// SearXNG queries a SearXNG instance's JSON API. The instance must allow
+// format=json in its settings.
+type SearXNG struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *SearXNG) Name() string { return BackendSearXNG }
+
+func (s *SearXNG) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	endpoint := strings.TrimSuffix(s.URL, "/") + "/search?format=json&q=" + url.QueryEscape(query)
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, s.Client, endpoint, nil, &resp); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, r := range resp.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: StripTags(r.Content)})
+	}
+	return limitResults(results, n), nil
+}
+
+// Brave queries the Brave Search API.
+type Brave struct {
+	APIKey   string
+	Endpoint string // "" = https://api.search.brave.com/res/v1/web/search
+	Client   *http.Client
+}
+
+func (b *Brave) Name() string { return BackendBrave }
+
+func (b *Brave) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	endpoint := b.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.search.brave.com/res/v1/web/search"
+	}
+	endpoint += "?q=" + url.QueryEscape(query) + fmt.Sprintf("&count=%d", max(n, 1))
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	header.Set("X-Subscription-Token", b.APIKey)
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := getJSON(ctx, b.Client, endpoint, header, &resp); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, r := range resp.Web.Results {
+		results = append(results, Result{Title: StripTags(r.Title), URL: r.URL, Snippet: StripTags(r.Description)})
+	}
+	return limitResults(results, n), nil
+}
+
+// Local stands in for a search engine by ranking the pages already in the
+// cache. It needs no network, which suits offline work and tests.
+type Local struct {
+	Cache *Cache
+}
+
+func (l *Local) Name() string { return BackendLocal }
+
+func (l *Local) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	pages, err := l.Cache.Pages()
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]string, len(pages))
+	for i, p := range pages {
+		docs[i] = p.Title + "\n" + p.Content
+	}
+	var results []Result
+	for _, i := range rankDocs(query, docs) {
+		results = append(results, Result{Title: pages[i].Title, URL: pages[i].URL, Snippet: snippet(pages[i].Content)})
+	}
+	return limitResults(results, n), nil
+}
+
+func limitResults(results []Result, n int) []Result {
+	if n > 0 && len(results) > n {
+		return results[:n]
+	}
+	return results
+}
+
+// snippet is the start of content, for results that have none.
+func snippet(content string) string {
+	s := []rune(strings.Join(strings.Fields(content), " "))
+	if len(s) > 200 {
+		return strings.TrimSpace(string(s[:200])) + "…"
+	}
+	return string(s)
+}
@@ -0,0 +1,163 @@
+package research
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Document is a page's title and main content.
+type Document struct {
+	Title   string
+	Content string // markdown
+}
+
+// boilerplateTags never hold the content a reader came for.
+const boilerplateTags = "script, style, noscript, template, iframe, svg, canvas, form, button, " +
+	"input, select, nav, header, footer, aside, dialog, " +
+	"[role=navigation], [role=banner], [role=contentinfo], [role=complementary], " +
+	"[role=dialog], [role=alertdialog], [aria-hidden=true], [hidden]"
+
+var (
+	// unlikelyRe matches the class or id of menus, banners, cookie notices
+	// and the like.
+	unlikelyRe = regexp.MustCompile(`(?i)cookie|consent|gdpr|\b(banner|breadcrumbs?|sidebar|footer|masthead|share|sharing|social|related|promo|advert|ads?|newsletter|subscribe|popup|modal|skip|toc|menu|nav|navbar|navigation|comments?)\b`)
+	// maybeRe rescues an unlikely-looking element that names itself content.
+	maybeRe = regexp.MustCompile(`(?i)\b(article|main|content|post|entry|markdown|prose|body)\b`)
+)
+
+// minMainText is the text a semantic <article> or <main> must hold to be
+// taken as the content without scoring.
+const minMainText = 200
+
+// Extract pulls the main content out of an HTML page, readability-style:
+// boilerplate elements and those whose class or id marks them as menus,
+// banners or cookie notices are dropped, then the element holding the most
+// paragraph text with the fewest links wins. The result is markdown with
+// links resolved against base.
+func Extract(base *url.URL, body []byte) (*Document, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	title := pageTitle(doc)
+
+	doc.Find(boilerplateTags).Remove()
+	doc.Find("[class], [id]").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "body" || goquery.NodeName(s) == "article" || goquery.NodeName(s) == "main" {
+			return
+		}
+		attrs := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if unlikelyRe.MatchString(attrs) && !maybeRe.MatchString(attrs) {
+			s.Remove()
+		}
+	})
+
+	main := mainContent(doc)
+	if base != nil {
+		resolveLinks(main, base)
+	}
+	// Unescaped, so identifiers like max_retries read as the page wrote them.
+	converter := md.NewConverter("", true, &md.Options{EscapeMode: "disabled"})
+	content := strings.TrimSpace(converter.Convert(main))
+	return &Document{Title: title, Content: content}, nil
+}
+
+// resolveLinks makes link and image URLs under s absolute.
+func resolveLinks(s *goquery.Selection, base *url.URL) {
+	for _, attr := range []string{"href", "src"} {
+		s.Find("[" + attr + "]").Each(func(_ int, el *goquery.Selection) {
+			if ref, err := url.Parse(el.AttrOr(attr, "")); err == nil {
+				el.SetAttr(attr, base.ResolveReference(ref).String())
+			}
+		})
+	}
+}
+
+func pageTitle(doc *goquery.Document) string {
+	if t, ok := doc.Find(`meta[property="og:title"]`).Attr("content"); ok && strings.TrimSpace(t) != "" {
+		return strings.TrimSpace(t)
+	}
+	if t := strings.TrimSpace(doc.Find("title").First().Text()); t != "" {
+		return t
+	}
+	return strings.TrimSpace(doc.Find("h1").First().Text())
+}
+
+// mainContent picks the element holding the page's content: a substantial
+// <article>, <main> or role=main element if there is one, else the best
+// scoring container, else the body.
+func mainContent(doc *goquery.Document) *goquery.Selection {
+	var best *goquery.Selection
+	bestLen := 0
+	doc.Find(`article, main, [role=main]`).Each(func(_ int, s *goquery.Selection) {
+		if n := textLen(s); n > bestLen {
+			best, bestLen = s, n
+		}
+	})
+	if best != nil && bestLen >= minMainText {
+		return best
+	}
+
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node // candidates in document order, for stable ties
+	add := func(n *html.Node, score float64) {
+		if _, ok := scores[n]; !ok {
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+	doc.Find("p, pre, td, blockquote, li").Each(func(_ int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		parent := s.Parent()
+		if parent.Length() == 0 {
+			return
+		}
+		add(parent.Get(0), score)
+		if grand := parent.Parent(); grand.Length() > 0 {
+			add(grand.Get(0), score/2)
+		}
+	})
+
+	var bestNode *html.Node
+	bestScore := 0.0
+	for _, node := range order {
+		score := scores[node] * (1 - linkDensity(doc.FindNodes(node)))
+		if score > bestScore {
+			bestNode, bestScore = node, score
+		}
+	}
+	if bestNode != nil {
+		return doc.FindNodes(bestNode)
+	}
+	if body := doc.Find("body"); body.Length() > 0 {
+		return body
+	}
+	return doc.Selection
+}
+
+func textLen(s *goquery.Selection) int {
+	return len(strings.Join(strings.Fields(s.Text()), " "))
+}
+
+// linkDensity is the share of s's text that sits inside links.
+func linkDensity(s *goquery.Selection) float64 {
+	total := textLen(s)
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		links += textLen(a)
+	})
+	return min(float64(links)/float64(total), 1)
+}
@@ -0,0 +1,222 @@
+package research
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultFreshFor is how long NewFetcher serves a cached page without
+// asking the site whether it changed.
+const DefaultFreshFor = 15 * time.Minute
+
+// maxPageBytes caps the body read from one page.
+const maxPageBytes = 2 * 1024 * 1024
+
+// Page is a fetched page reduced to its main content.
+type Page struct {
+	URL          string    `json:"url"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"` // markdown
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	// FromCache is set when the content came from the cache, fresh, after a
+	// 304, or because the site could not be reached.
+	FromCache bool `json:"-"`
+}
+
+// Cache keeps extracted pages on disk, one JSON file per URL.
+type Cache struct {
+	dir string
+}
+
+// NewCache returns a cache in dir, created on first write.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// DefaultCache is the cache under ~/.celeste/cache/web.
+func DefaultCache() (*Cache, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewCache(filepath.Join(home, ".celeste", "cache", "web")), nil
+}
+
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// Get returns the cached page for url.
+func (c *Cache) Get(url string) (*Page, bool) {
+	data, err := os.ReadFile(c.path(url))
+	if err != nil {
+		return nil, false
+	}
+	var p Page
+	if err := json.Unmarshal(data, &p); err != nil || p.URL != url {
+		return nil, false
+	}
+	return &p, true
+}
+
+// Put stores p, replacing any earlier copy.
+func (c *Cache) Put(p *Page) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	path := c.path(p.URL)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Pages returns every cached page, most recently fetched first.
+func (c *Cache) Pages() ([]*Page, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var pages []*Page
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var p Page
+		if json.Unmarshal(data, &p) == nil && p.URL != "" {
+			pages = append(pages, &p)
+		}
+	}
+	sort.Slice(pages, func(a, b int) bool { return pages[a].FetchedAt.After(pages[b].FetchedAt) })
+	return pages, nil
+}
+
+// Fetcher downloads pages, extracts their main content and caches it.
+type Fetcher struct {
+	Client *http.Client
+	Cache  *Cache // nil = no caching
+	// FreshFor is how long a cached page is used without revalidation. 0
+	// revalidates on every fetch.
+	FreshFor time.Duration
+}
+
+// NewFetcher returns a fetcher that caches in cache, which may be nil.
+func NewFetcher(cache *Cache) *Fetcher {
+	return &Fetcher{
+		Client:   &http.Client{Timeout: 20 * time.Second},
+		Cache:    cache,
+		FreshFor: DefaultFreshFor,
+	}
+}
+
+// Fetch returns the main content of rawURL. A cached copy is revalidated
+// with If-None-Match and If-Modified-Since, and is served as is when the
+// site answers 304 or can't be reached.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = "https://" + rawURL
+	}
+	var cached *Page
+	if f.Cache != nil {
+		if p, ok := f.Cache.Get(rawURL); ok {
+			cached = p
+			cached.FromCache = true
+			if f.FreshFor > 0 && time.Since(p.FetchedAt) < f.FreshFor {
+				return cached, nil
+			}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		if cached != nil && ctx.Err() == nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cached.FetchedAt = time.Now()
+		f.store(cached)
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		URL:          rawURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		doc, err := Extract(resp.Request.URL, body)
+		if err != nil {
+			return nil, err
+		}
+		page.Title, page.Content = doc.Title, doc.Content
+	case strings.HasPrefix(mediaType, "text/"):
+		page.Content = string(body)
+	default:
+		return nil, fmt.Errorf("unsupported content type %s", mediaType)
+	}
+	f.store(page)
+	return page, nil
+}
+
+// store caches p. The cache is an optimisation, so a failed write is not
+// reported.
+func (f *Fetcher) store(p *Page) {
+	if f.Cache != nil {
+		_ = f.Cache.Put(p)
+	}
+}
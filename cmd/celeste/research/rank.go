@@ -0,0 +1,180 @@
+package research
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/codegraph"
+)
+
+// Passage sizes, in bytes. Paragraphs are merged until a passage reaches
+// targetPassage; a single paragraph over maxPassage is split.
+const (
+	targetPassage = 800
+	maxPassage    = 2000
+)
+
+var headingRe = regexp.MustCompile(`^#{1,6}\s+`)
+
+// Chunk splits markdown into passages of a few paragraphs each. A heading
+// starts a new passage and is kept as its Heading; fenced code stays whole
+// unless it is very long.
+func Chunk(content string) []Passage {
+	var out []Passage
+	var heading string
+	var buf strings.Builder
+	flush := func() {
+		text := strings.TrimSpace(buf.String())
+		buf.Reset()
+		for _, part := range splitLong(text) {
+			out = append(out, Passage{Heading: heading, Text: part})
+		}
+	}
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && headingRe.MatchString(trimmed) {
+			flush()
+			heading = strings.TrimSpace(headingRe.ReplaceAllString(trimmed, ""))
+			continue
+		}
+		if !inFence && trimmed == "" && buf.Len() >= targetPassage {
+			flush()
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	flush()
+	return out
+}
+
+// splitLong breaks text over maxPassage at line breaks, and a line over
+// maxPassage at spaces.
+func splitLong(text string) []string {
+	if text == "" {
+		return nil
+	}
+	if len(text) <= maxPassage {
+		return []string{text}
+	}
+	var parts []string
+	var buf strings.Builder
+	emit := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" {
+			parts = append(parts, s)
+		}
+		buf.Reset()
+	}
+	for _, line := range strings.Split(text, "\n") {
+		for len(line) > maxPassage {
+			cut := strings.LastIndexByte(line[:maxPassage], ' ')
+			if cut <= 0 {
+				cut = maxPassage
+				for cut > 0 && !isRuneStart(line[cut]) {
+					cut--
+				}
+			}
+			emit()
+			buf.WriteString(line[:cut])
+			emit()
+			line = strings.TrimLeft(line[cut:], " ")
+		}
+		if buf.Len()+len(line) > maxPassage {
+			emit()
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	emit()
+	return parts
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
+
+// Rank scores passages against query with BM25 over the passages
+// themselves and returns the best limit of them, dropping passages that
+// share no terms with the query and repeats of the same text.
+func Rank(query string, passages []Passage, limit int) []Passage {
+	docs := make([]string, len(passages))
+	for i, p := range passages {
+		docs[i] = p.Heading + "\n" + p.Text
+	}
+	scores := bm25Scores(query, docs)
+	seen := make(map[string]bool)
+	var ranked []Passage
+	for i, p := range passages {
+		if scores[i] <= 0 || seen[p.Text] {
+			continue
+		}
+		seen[p.Text] = true
+		p.Score = scores[i]
+		ranked = append(ranked, p)
+	}
+	sortPassages(ranked)
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// rankDocs returns the indices of docs that match query, best first.
+func rankDocs(query string, docs []string) []int {
+	scores := bm25Scores(query, docs)
+	var out []int
+	for i, s := range scores {
+		if s > 0 {
+			out = append(out, i)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return scores[out[a]] > scores[out[b]] })
+	return out
+}
+
+// bm25Scores scores each doc against query, with IDFs from docs. Tokens are
+// the codegraph text tokens, so the stop-word list is shared with memory
+// retrieval and code search.
+func bm25Scores(query string, docs []string) []float64 {
+	scores := make([]float64, len(docs))
+	seen := make(map[string]bool)
+	var queryTokens []string
+	for _, t := range codegraph.TextTokens(query) {
+		if !seen[t] {
+			seen[t] = true
+			queryTokens = append(queryTokens, t)
+		}
+	}
+	if len(queryTokens) == 0 || len(docs) == 0 {
+		return scores
+	}
+
+	tfs := make([]map[string]int, len(docs))
+	lengths := make([]int, len(docs))
+	df := make(map[string]int)
+	total := 0
+	for i, doc := range docs {
+		tokens := codegraph.TextTokens(doc)
+		tf := make(map[string]int, len(tokens))
+		for _, t := range tokens {
+			tf[t]++
+		}
+		for t := range tf {
+			df[t]++
+		}
+		tfs[i], lengths[i] = tf, len(tokens)
+		total += len(tokens)
+	}
+	avgLen := float64(total) / float64(len(docs))
+	idf := make(map[string]float64, len(queryTokens))
+	for _, t := range queryTokens {
+		idf[t] = codegraph.BM25IDF(df[t], len(docs))
+	}
+	for i := range docs {
+		scores[i] = codegraph.ComputeBM25Score(queryTokens, tfs[i], lengths[i], idf, avgLen)
+	}
+	return scores
+}
@@ -0,0 +1,151 @@
+// Package research answers a query from the web without a browser: it asks a
+// search backend for the top results, fetches them concurrently, keeps each
+// page's main content (readability-style, so nav menus and cookie banners
+// don't reach the model), splits it into passages and ranks the passages
+// against the query with the codegraph BM25 scorer. Every passage carries
+// the number of the source it came from, so answers can cite [1], [2], ...
+//
+// Extracted pages are cached on disk and revalidated with their ETag or
+// Last-Modified validators, so a page read again costs a 304 at most.
+package research
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Defaults for a Pipeline left zero.
+const (
+	DefaultMaxPages    = 5
+	DefaultMaxPassages = 8
+	maxPages           = 10
+	fetchConcurrency   = 4
+)
+
+// Source is one page the report drew on. ID is the citation number.
+type Source struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Snippet   string `json:"snippet,omitempty"`
+	FromCache bool   `json:"from_cache,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Passage is a ranked chunk of a source's main content.
+type Passage struct {
+	Source  int     `json:"source"`
+	Heading string  `json:"heading,omitempty"`
+	Text    string  `json:"text"`
+	Score   float64 `json:"score"`
+}
+
+// Report is the outcome of one research query.
+type Report struct {
+	Query    string    `json:"query"`
+	Backend  string    `json:"backend"`
+	Sources  []Source  `json:"sources"`
+	Passages []Passage `json:"passages"`
+}
+
+// Pipeline runs research queries.
+type Pipeline struct {
+	Backend     Backend
+	Fetcher     *Fetcher
+	MaxPages    int // pages fetched per query; 0 = DefaultMaxPages
+	MaxPassages int // passages returned; 0 = DefaultMaxPassages
+}
+
+// Run searches for query, reads the top results and returns the passages
+// that best answer it. A page that fails to load is listed with its error
+// and skipped; Run fails only when the search itself does.
+func (p *Pipeline) Run(ctx context.Context, query string) (*Report, error) {
+	pages := p.MaxPages
+	if pages <= 0 {
+		pages = DefaultMaxPages
+	}
+	pages = min(pages, maxPages)
+	results, err := p.Backend.Search(ctx, query, pages)
+	if err != nil {
+		return nil, fmt.Errorf("%s search: %w", p.Backend.Name(), err)
+	}
+	results = dedupeResults(results)
+	if len(results) > pages {
+		results = results[:pages]
+	}
+
+	report := &Report{Query: query, Backend: p.Backend.Name(), Sources: make([]Source, len(results))}
+	fetched := make([]*Page, len(results))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fetchConcurrency)
+	for i, r := range results {
+		report.Sources[i] = Source{ID: i + 1, Title: r.Title, URL: r.URL, Snippet: r.Snippet}
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				report.Sources[i].Error = ctx.Err().Error()
+				return
+			}
+			page, err := p.Fetcher.Fetch(ctx, url)
+			if err != nil {
+				report.Sources[i].Error = err.Error()
+				return
+			}
+			fetched[i] = page
+		}(i, r.URL)
+	}
+	wg.Wait()
+
+	var chunks []Passage
+	for i, page := range fetched {
+		if page == nil {
+			continue
+		}
+		if page.Title != "" {
+			report.Sources[i].Title = page.Title
+		}
+		report.Sources[i].FromCache = page.FromCache
+		for _, c := range Chunk(page.Content) {
+			c.Source = i + 1
+			chunks = append(chunks, c)
+		}
+	}
+
+	limit := p.MaxPassages
+	if limit <= 0 {
+		limit = DefaultMaxPassages
+	}
+	report.Passages = Rank(query, chunks, limit)
+	return report, nil
+}
+
+// dedupeResults drops repeated URLs, keeping the first.
+func dedupeResults(results []Result) []Result {
+	seen := make(map[string]bool, len(results))
+	out := results[:0]
+	for _, r := range results {
+		if r.URL == "" || seen[r.URL] {
+			continue
+		}
+		seen[r.URL] = true
+		out = append(out, r)
+	}
+	return out
+}
+
+// sortPassages orders passages best first, breaking ties by source so the
+// output is stable.
+func sortPassages(ps []Passage) {
+	sort.SliceStable(ps, func(a, b int) bool {
+		if ps[a].Score != ps[b].Score {
+			return ps[a].Score > ps[b].Score
+		}
+		return ps[a].Source < ps[b].Source
+	})
+}
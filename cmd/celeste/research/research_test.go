@@ -0,0 +1,242 @@
+package research
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const docsPage = `<!doctype html>
+<html><head><title>Configuring retries | Widget Docs</title></head>
+<body>
+  <div class="cookie-banner">We use cookies to improve your experience. Accept all cookies?</div>
+  <nav><a href="/">Home</a> <a href="/guide">Guide</a> <a href="/api">API reference</a></nav>
+  <div id="sidebar"><ul><li><a href="/a">Installing the widget client library</a></li><li><a href="/b">Upgrading the widget client library</a></li></ul></div>
+  <div class="content">
+    <h1>Configuring retries</h1>
+    <p>The widget client retries failed requests with exponential backoff, starting at 100 milliseconds and doubling each attempt.</p>
+    <p>Set max_retries in the client options to change the retry limit, or set it to zero to disable retries entirely.</p>
+    <p>See the <a href="/api/options">options reference</a> for every setting.</p>
+  </div>
+  <footer>Copyright Widget Inc. All rights reserved.</footer>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	base, _ := url.Parse("https://docs.example.com/retries")
+	doc, err := Extract(base, []byte(docsPage))
+	require.NoError(t, err)
+	assert.Equal(t, "Configuring retries | Widget Docs", doc.Title)
+	assert.Contains(t, doc.Content, "exponential backoff")
+	assert.Contains(t, doc.Content, "max_retries")
+	assert.Contains(t, doc.Content, "(https://docs.example.com/api/options)", "links resolve against the page")
+	for _, boilerplate := range []string{"cookies", "API reference", "Upgrading", "Copyright"} {
+		assert.NotContains(t, doc.Content, boilerplate)
+	}
+}
+
+func TestExtract_PrefersArticle(t *testing.T) {
+	page := `<html><body><div class="menu">Products Pricing Blog</div><article><p>` +
+		strings.Repeat("The article body explains the feature in detail. ", 10) +
+		`</p></article><div><p>A short aside that is not part of the story at all, really.</p></div></body></html>`
+	doc, err := Extract(nil, []byte(page))
+	require.NoError(t, err)
+	assert.Contains(t, doc.Content, "The article body")
+	assert.NotContains(t, doc.Content, "aside")
+	assert.NotContains(t, doc.Content, "Pricing")
+}
+
+func TestChunk(t *testing.T) {
+	long := strings.Repeat("word ", 500)
+	content := "intro line\n\n# Setup\n\nfirst para\n\n```\ncode\n\n# not a heading\n```\n\n## Usage\n\n" + long
+	chunks := Chunk(content)
+	require.GreaterOrEqual(t, len(chunks), 4)
+	assert.Equal(t, Passage{Text: "intro line"}, chunks[0])
+	assert.Equal(t, "Setup", chunks[1].Heading)
+	assert.Contains(t, chunks[1].Text, "# not a heading", "headings inside code fences stay text")
+	for _, c := range chunks[2:] {
+		assert.Equal(t, "Usage", c.Heading)
+		assert.LessOrEqual(t, len(c.Text), maxPassage)
+	}
+}
+
+func TestRank(t *testing.T) {
+	passages := []Passage{
+		{Source: 1, Text: "Install the package with pip."},
+		{Source: 2, Text: "Retries use exponential backoff; set max retries to change the limit."},
+		{Source: 3, Text: "Retries use exponential backoff; set max retries to change the limit."},
+		{Source: 3, Heading: "Backoff", Text: "The retry delay doubles."},
+	}
+	ranked := Rank("retry backoff", passages, 5)
+	require.Len(t, ranked, 2, "unrelated passages and repeated text are dropped")
+	assert.Equal(t, "Backoff", ranked[0].Heading, "the short passage matching both terms wins")
+	assert.Equal(t, 2, ranked[1].Source)
+	assert.Greater(t, ranked[0].Score, ranked[1].Score)
+}
+
+func TestFetcher_RevalidatesWithETag(t *testing.T) {
+	var requests, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, docsPage)
+	}))
+	defer srv.Close()
+
+	cache := NewCache(t.TempDir())
+	f := NewFetcher(cache)
+	f.FreshFor = 0
+	ctx := context.Background()
+
+	page, err := f.Fetch(ctx, srv.URL+"/retries")
+	require.NoError(t, err)
+	assert.False(t, page.FromCache)
+	assert.Equal(t, `"v1"`, page.ETag)
+
+	again, err := f.Fetch(ctx, srv.URL+"/retries")
+	require.NoError(t, err)
+	assert.True(t, again.FromCache)
+	assert.Equal(t, page.Content, again.Content)
+	assert.Equal(t, int32(1), notModified.Load(), "the second fetch is a conditional request")
+
+	f.FreshFor = DefaultFreshFor
+	_, err = f.Fetch(ctx, srv.URL+"/retries")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load(), "a fresh cached page needs no request")
+
+	srv.Close()
+	f.FreshFor = 0
+	offline, err := f.Fetch(ctx, srv.URL+"/retries")
+	require.NoError(t, err, "an unreachable site falls back to the cached copy")
+	assert.True(t, offline.FromCache)
+
+	_, err = f.Fetch(ctx, srv.URL+"/never-fetched")
+	assert.Error(t, err)
+}
+
+func TestBackends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search": // SearXNG
+			assert.Equal(t, "json", r.URL.Query().Get("format"))
+			json.NewEncoder(w).Encode(map[string]any{"results": []map[string]string{
+				{"title": "One", "url": "https://one.example", "content": "first <b>hit</b>"},
+				{"title": "Two", "url": "https://two.example", "content": "second"},
+			}})
+		case "/brave":
+			assert.Equal(t, "key", r.Header.Get("X-Subscription-Token"))
+			assert.Equal(t, "1", r.URL.Query().Get("count"))
+			json.NewEncoder(w).Encode(map[string]any{"web": map[string]any{"results": []map[string]string{
+				{"title": "<strong>Brave</strong> hit", "url": "https://brave.example", "description": "desc"},
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	searx, err := NewBackend(BackendConfig{Name: "searxng", URL: srv.URL + "/"}, nil)
+	require.NoError(t, err)
+	results, err := searx.Search(ctx, "q", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Title: "One", URL: "https://one.example", Snippet: "first hit"}}, results)
+
+	brave, err := NewBackend(BackendConfig{Name: "brave", URL: srv.URL + "/brave", APIKey: "key"}, nil)
+	require.NoError(t, err)
+	results, err = brave.Search(ctx, "q", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Title: "Brave hit", URL: "https://brave.example", Snippet: "desc"}}, results)
+
+	_, err = NewBackend(BackendConfig{Name: "brave"}, nil)
+	assert.ErrorContains(t, err, "brave_api_key")
+	_, err = NewBackend(BackendConfig{Name: "searxng"}, nil)
+	assert.ErrorContains(t, err, "search_url")
+	_, err = NewBackend(BackendConfig{Name: "bing"}, nil)
+	assert.ErrorContains(t, err, "unknown search backend")
+}
+
+func TestParseDuckDuckGo(t *testing.T) {
+	html := `
+	<a class="result__a" href="https://duckduckgo.com/l/?uddg=https%3A%2F%2Fexample.com&amp;rut=abc">Example Title</a>
+	<td class="result__snippet">This is a snippet about the example.</td>
+	<a class="result__a" href="https://duckduckgo.com/l/?uddg=https%3A%2F%2Fother.com&amp;rut=xyz"><b>Other</b> Result</a>
+	<td class="result__snippet">Another snippet here.</td>
+	`
+	results := ParseDuckDuckGo(html)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Example Title", results[0].Title)
+	assert.Equal(t, "https://example.com", results[0].URL)
+	assert.Equal(t, "This is a snippet about the example.", results[0].Snippet)
+	assert.Equal(t, "Other Result", results[1].Title)
+	assert.Equal(t, "https://other.com", results[1].URL)
+}
+
+func TestStripTags(t *testing.T) {
+	assert.Equal(t, "hello world", StripTags("<b>hello</b> <i>world</i>"))
+	assert.Equal(t, "plain", StripTags("plain"))
+}
+
+// staticBackend returns fixed results.
+type staticBackend []Result
+
+func (s staticBackend) Name() string { return "static" }
+func (s staticBackend) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	return s, nil
+}
+
+func TestPipeline_Run(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/retries":
+			fmt.Fprint(w, docsPage)
+		case "/install":
+			fmt.Fprint(w, `<html><head><title>Install</title></head><body><p>Install the widget client with pip install widget, then import it in your application code.</p></body></html>`)
+		default:
+			http.Error(w, "gone", http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+
+	cache := NewCache(t.TempDir())
+	p := &Pipeline{
+		Backend: staticBackend{
+			{Title: "Install", URL: srv.URL + "/install"},
+			{Title: "Gone", URL: srv.URL + "/gone"},
+			{Title: "Retries", URL: srv.URL + "/retries"},
+			{Title: "Retries again", URL: srv.URL + "/retries"},
+		},
+		Fetcher: NewFetcher(cache),
+	}
+	report, err := p.Run(context.Background(), "how do I change the retry limit")
+	require.NoError(t, err)
+	assert.Equal(t, "static", report.Backend)
+	require.Len(t, report.Sources, 3, "duplicate URLs are read once")
+	assert.Equal(t, "status 410", report.Sources[1].Error)
+	assert.Equal(t, "Configuring retries | Widget Docs", report.Sources[2].Title, "titles come from the page")
+	require.NotEmpty(t, report.Passages)
+	assert.Equal(t, 3, report.Passages[0].Source, "the retries page answers the query")
+	assert.Contains(t, report.Passages[0].Text, "max_retries")
+
+	// The pages are now cached, so the local backend can find them offline.
+	local, err := NewBackend(BackendConfig{Name: BackendLocal}, cache)
+	require.NoError(t, err)
+	results, err := local.Search(context.Background(), "retry limit", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, srv.URL+"/retries", results[0].URL)
+}
@@ -0,0 +1,236 @@
+// Package sandbox confines the shell commands celeste runs on the model's
+// behalf: the bash tool, custom tools and grimoire hooks.
+//
+// On Linux a sandboxed command runs in its own user, PID and (unless the
+// policy allows network) network namespace; Landlock makes everything but
+// the workspace and a private temp dir read-only, seccomp blocks syscalls
+// that could escape or reach the kernel, and rlimits cap CPU time and
+// memory. Other platforms have no sandbox: Mode "auto" runs commands as
+// before and Mode "required" refuses to run them.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mode selects whether commands are sandboxed.
+type Mode string
+
+const (
+	// ModeOff runs commands directly, guarded only by the bash denylist.
+	ModeOff Mode = "off"
+	// ModeAuto sandboxes with whatever the kernel supports and runs
+	// commands unconfined where it supports nothing.
+	ModeAuto Mode = "auto"
+	// ModeRequired refuses to run a command unless at least Landlock and
+	// seccomp can be applied. Use it for unattended runs on untrusted code.
+	ModeRequired Mode = "required"
+)
+
+// ParseMode parses a mode name; "" is ModeOff.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeAuto, ModeRequired:
+		return m, nil
+	}
+	return ModeOff, fmt.Errorf("invalid sandbox mode %q (use off, auto or required)", s)
+}
+
+// Default per-command limits, used when the policy leaves them zero.
+const (
+	DefaultCPUSeconds     = 600
+	DefaultMemoryMB       = 4096
+	DefaultTimeoutSeconds = 300
+	// DefaultJobTimeoutSeconds bounds a background job under an enabled
+	// policy; jobs are meant to outlive one command, not the session.
+	DefaultJobTimeoutSeconds = 3600
+)
+
+// Policy is the sandbox section of ~/.celeste/permissions.json.
+type Policy struct {
+	Mode Mode `json:"mode,omitempty"`
+	// Network lets commands reach the network. Off by default.
+	Network bool `json:"network,omitempty"`
+	// Writable lists paths writable besides the command's working
+	// directory, e.g. "~/.cache/go-build". A leading ~ is the home dir.
+	Writable []string `json:"writable,omitempty"`
+	// CPUSeconds caps each process's CPU time (RLIMIT_CPU).
+	CPUSeconds int `json:"cpu_seconds,omitempty"`
+	// MemoryMB caps each process's heap and private mappings (RLIMIT_DATA).
+	MemoryMB int `json:"memory_mb,omitempty"`
+	// TimeoutSeconds caps a command's wall-clock time, whatever timeout
+	// the model asks for.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// JobTimeoutSeconds caps a background job's wall-clock time. It
+	// applies with the sandbox off too when set.
+	JobTimeoutSeconds int `json:"job_timeout_seconds,omitempty"`
+}
+
+// Enabled reports whether commands run under the policy are sandboxed.
+func (p Policy) Enabled() bool {
+	return p.Mode == ModeAuto || p.Mode == ModeRequired
+}
+
+// IsZero reports whether p is the default off policy, so configs that
+// never set one don't gain an empty "sandbox" section when saved.
+func (p Policy) IsZero() bool {
+	return (p.Mode == "" || p.Mode == ModeOff) && !p.Network && len(p.Writable) == 0 &&
+		p.CPUSeconds == 0 && p.MemoryMB == 0 && p.TimeoutSeconds == 0 && p.JobTimeoutSeconds == 0
+}
+
+// Limits returns the CPU, memory and wall-clock limits with defaults
+// filled in.
+func (p Policy) Limits() (cpuSeconds, memoryMB int, timeout time.Duration) {
+	cpuSeconds, memoryMB, timeoutSeconds := p.CPUSeconds, p.MemoryMB, p.TimeoutSeconds
+	if cpuSeconds <= 0 {
+		cpuSeconds = DefaultCPUSeconds
+	}
+	if memoryMB <= 0 {
+		memoryMB = DefaultMemoryMB
+	}
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = DefaultTimeoutSeconds
+	}
+	return cpuSeconds, memoryMB, time.Duration(timeoutSeconds) * time.Second
+}
+
+// Timeout caps a caller's timeout at the policy's wall-clock limit. d <= 0
+// means the caller has none. The result is d unchanged when the policy is
+// off.
+func (p Policy) Timeout(d time.Duration) time.Duration {
+	if !p.Enabled() {
+		return d
+	}
+	_, _, limit := p.Limits()
+	if d <= 0 || d > limit {
+		return limit
+	}
+	return d
+}
+
+// JobTimeout returns the wall-clock limit for a background job, 0 for none.
+// An enabled policy always has one; with the policy off only an explicit
+// JobTimeoutSeconds applies.
+func (p Policy) JobTimeout() time.Duration {
+	seconds := p.JobTimeoutSeconds
+	if seconds <= 0 {
+		if !p.Enabled() {
+			return 0
+		}
+		seconds = DefaultJobTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Describe summarizes the policy for tool results and status output, e.g.
+// "landlock, seccomp, namespaces; network off".
+func (p Policy) Describe() string {
+	if !p.Enabled() {
+		return "off"
+	}
+	layers := Probe().Layers()
+	if layers == "" {
+		layers = "unavailable"
+	}
+	network := "network off"
+	if p.Network {
+		network = "network on"
+	}
+	return layers + "; " + network
+}
+
+// writablePaths returns the absolute paths a command run in dir may write.
+func (p Policy) writablePaths(dir, tmp string) []string {
+	paths := []string{dir, tmp, os.DevNull}
+	home, _ := os.UserHomeDir()
+	for _, w := range p.Writable {
+		if w == "~" || strings.HasPrefix(w, "~/") {
+			w = filepath.Join(home, strings.TrimPrefix(w, "~"))
+		}
+		if abs, err := filepath.Abs(w); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+	return paths
+}
+
+type policyKey struct{}
+
+// WithPolicy attaches p to ctx so tools run under it can sandbox their
+// commands.
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+// PolicyFromContext returns the policy attached to ctx, or the zero
+// (off) policy.
+func PolicyFromContext(ctx context.Context) Policy {
+	p, _ := ctx.Value(policyKey{}).(Policy)
+	return p
+}
+
+// Status reports which sandbox layers this system supports.
+type Status struct {
+	Namespaces bool   // unprivileged user namespaces
+	Landlock   int    // Landlock ABI version; 0 when unavailable
+	Seccomp    bool   // seccomp filters
+	Detail     string // why a layer is missing, when one is
+}
+
+// Sufficient reports whether the filesystem and syscall layers that
+// ModeRequired insists on are available.
+func (s Status) Sufficient() bool {
+	return s.Landlock > 0 && s.Seccomp
+}
+
+// Layers lists the available layers, e.g. "landlock, seccomp, namespaces".
+func (s Status) Layers() string {
+	var layers []string
+	if s.Landlock > 0 {
+		layers = append(layers, "landlock")
+	}
+	if s.Seccomp {
+		layers = append(layers, "seccomp")
+	}
+	if s.Namespaces {
+		layers = append(layers, "namespaces")
+	}
+	return strings.Join(layers, ", ")
+}
+
+func (s Status) String() string {
+	out := fmt.Sprintf("namespaces: %s, landlock: %s, seccomp: %s",
+		yesNo(s.Namespaces), landlockString(s.Landlock), yesNo(s.Seccomp))
+	if s.Detail != "" {
+		out += " (" + s.Detail + ")"
+	}
+	return out
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func landlockString(abi int) string {
+	if abi == 0 {
+		return "no"
+	}
+	return fmt.Sprintf("ABI %d", abi)
+}
+
+// unavailableError is returned by Wrap under ModeRequired when the system
+// lacks a required layer.
+func unavailableError(s Status) error {
+	return fmt.Errorf("sandbox required but unavailable on this system: %s. "+
+		"Set \"sandbox\": {\"mode\": \"auto\"} in ~/.celeste/permissions.json to run unconfined where needed", s)
+}
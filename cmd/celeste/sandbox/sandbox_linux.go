@@ -0,0 +1,305 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// helperArg marks a re-exec of celeste as the sandbox helper: it confines
+// itself as spec describes, then execs the real command.
+const helperArg = "__sandbox-exec"
+
+// specEnv carries the helper's spec. The helper removes it before exec.
+const specEnv = "CELESTE_SANDBOX_SPEC"
+
+// helperSpec is what the helper needs to confine itself. Only layers the
+// parent found available are requested, so a failure to apply one is an
+// error rather than a silent downgrade.
+type helperSpec struct {
+	Writable   []string `json:"writable"`
+	Network    bool     `json:"network"`
+	CPUSeconds int      `json:"cpu_seconds"`
+	MemoryMB   int      `json:"memory_mb"`
+	Landlock   int      `json:"landlock"`
+	Seccomp    bool     `json:"seccomp"`
+}
+
+var (
+	probeOnce   sync.Once
+	probeStatus Status
+)
+
+// Probe reports which sandbox layers this system supports. The result is
+// computed once per process.
+func Probe() Status {
+	probeOnce.Do(func() { probeStatus = probe() })
+	return probeStatus
+}
+
+func probe() Status {
+	var s Status
+	var missing []string
+
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno == 0 {
+		s.Landlock = int(abi)
+	} else {
+		missing = append(missing, "landlock: "+errno.Error())
+	}
+
+	if !seccompArchSupported {
+		missing = append(missing, "seccomp: unsupported on "+runtime.GOARCH)
+	} else if _, err := unix.PrctlRetInt(unix.PR_GET_SECCOMP, 0, 0, 0, 0); err != nil {
+		missing = append(missing, "seccomp: "+err.Error())
+	} else {
+		s.Seccomp = true
+	}
+
+	// The only reliable test for user namespaces is to make one: distros
+	// gate them behind sysctls, AppArmor and container seccomp profiles.
+	cmd := exec.Command("/bin/true")
+	cmd.SysProcAttr = namespaceAttr(false)
+	if err := cmd.Run(); err != nil {
+		missing = append(missing, "namespaces: "+err.Error())
+	} else {
+		s.Namespaces = true
+	}
+
+	if len(missing) > 0 {
+		s.Detail = joinDetail(missing)
+	}
+	return s
+}
+
+func joinDetail(parts []string) string {
+	out := parts[0]
+	for _, p := range parts[1:] {
+		out += "; " + p
+	}
+	return out
+}
+
+// namespaceAttr puts a command in new user, PID and IPC namespaces, and a
+// network namespace with only a downed loopback when network is off. The
+// caller's uid and gid map to themselves, so file ownership looks as usual.
+func namespaceAttr(network bool) *syscall.SysProcAttr {
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC)
+	if !network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	return &syscall.SysProcAttr{
+		Cloneflags:  flags,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+}
+
+// Wrap rewrites cmd, not yet started, to run under p. The working
+// directory (cmd.Dir, or the current one) stays writable and TMPDIR points
+// at a private temp dir that cleanup removes; call cleanup once cmd has
+// exited. When p is off, Wrap leaves cmd alone.
+func (p Policy) Wrap(cmd *exec.Cmd) (cleanup func(), err error) {
+	cleanup = func() {}
+	if !p.Enabled() {
+		return cleanup, nil
+	}
+	status := Probe()
+	if !status.Sufficient() {
+		if p.Mode == ModeRequired {
+			return cleanup, unavailableError(status)
+		}
+		if status.Layers() == "" {
+			return cleanup, nil
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return cleanup, fmt.Errorf("sandbox: locating celeste: %w", err)
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return cleanup, fmt.Errorf("sandbox: %w", err)
+		}
+	}
+	tmp, err := os.MkdirTemp("", "celeste-sandbox-")
+	if err != nil {
+		return cleanup, fmt.Errorf("sandbox: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(tmp) }
+
+	cpu, mem, _ := p.Limits()
+	spec, err := json.Marshal(helperSpec{
+		Writable:   p.writablePaths(dir, tmp),
+		Network:    p.Network,
+		CPUSeconds: cpu,
+		MemoryMB:   mem,
+		Landlock:   status.Landlock,
+		Seccomp:    status.Seccomp,
+	})
+	if err != nil {
+		cleanup()
+		return func() {}, err
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(scrubEnv(env), "TMPDIR="+tmp, specEnv+"="+string(spec))
+	cmd.Args = append([]string{exe, helperArg, cmd.Path}, cmd.Args...)
+	cmd.Path = exe
+	if status.Namespaces {
+		attr := namespaceAttr(p.Network)
+		if prev := cmd.SysProcAttr; prev != nil {
+			// Keep the caller's settings, e.g. Setpgid for killing a job's
+			// process group.
+			merged := *prev
+			merged.Cloneflags |= attr.Cloneflags
+			merged.UidMappings, merged.GidMappings = attr.UidMappings, attr.GidMappings
+			merged.Pdeathsig = attr.Pdeathsig
+			attr = &merged
+		}
+		cmd.SysProcAttr = attr
+	}
+	return cleanup, nil
+}
+
+// scrubEnv returns a copy of env without the variables that point a
+// command at the user's agents: SSH_AUTH_SOCK and the D-Bus addresses.
+func scrubEnv(env []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if name == "SSH_AUTH_SOCK" || strings.HasPrefix(name, "DBUS_") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// Init turns this process into the sandbox helper when Wrap started it,
+// and returns immediately otherwise. Call it first thing in main (and in
+// TestMain of tests that sandbox commands).
+func Init() {
+	if len(os.Args) < 4 || os.Args[1] != helperArg {
+		return
+	}
+	if err := runHelper(os.Args[2], os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "celeste sandbox: %v\n", err)
+		os.Exit(126)
+	}
+}
+
+// runHelper confines this process and execs path with argv. It only
+// returns on failure.
+func runHelper(path string, argv []string) error {
+	raw, ok := os.LookupEnv(specEnv)
+	if !ok {
+		return errors.New("missing sandbox spec")
+	}
+	os.Unsetenv(specEnv)
+	var spec helperSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return fmt.Errorf("bad sandbox spec: %w", err)
+	}
+
+	// Landlock and seccomp bind to the calling thread, and the thread that
+	// applies them must be the one that execs.
+	runtime.LockOSThread()
+
+	if err := setLimits(spec.CPUSeconds, spec.MemoryMB); err != nil {
+		return err
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("no_new_privs: %w", err)
+	}
+	if spec.Landlock > 0 {
+		if err := restrictFilesystem(spec.Landlock, spec.Writable, spec.Network); err != nil {
+			return fmt.Errorf("landlock: %w", err)
+		}
+	}
+	if spec.Seccomp {
+		if err := installSeccomp(spec.Network); err != nil {
+			return fmt.Errorf("seccomp: %w", err)
+		}
+	}
+	if os.Getpid() == 1 {
+		// In a new PID namespace the command would be its init, which
+		// ignores SIGTERM and never reaps orphans. Stay init instead.
+		os.Exit(runInit(path, argv))
+	}
+	return unix.Exec(path, argv, os.Environ())
+}
+
+// runInit runs the command as a child (forked from this locked thread, so
+// it inherits the restrictions), forwards termination signals to it, and
+// reaps whatever else gets reparented here. The command's exit ends the
+// namespace and everything left in it.
+func runInit(path string, argv []string) int {
+	cmd := &exec.Cmd{Path: path, Args: argv, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "celeste sandbox: %v\n", err)
+		return 127
+	}
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, unix.SIGTERM, unix.SIGINT, unix.SIGHUP, unix.SIGQUIT)
+	go func() {
+		for sig := range sigs {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+	for {
+		var ws unix.WaitStatus
+		pid, err := unix.Wait4(-1, &ws, 0, nil)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 1
+		}
+		if pid == cmd.Process.Pid {
+			if ws.Signaled() {
+				return 128 + int(ws.Signal())
+			}
+			return ws.ExitStatus()
+		}
+	}
+}
+
+func setLimits(cpuSeconds, memoryMB int) error {
+	if err := lowerLimit(unix.RLIMIT_CPU, uint64(cpuSeconds)); err != nil {
+		return fmt.Errorf("cpu limit: %w", err)
+	}
+	if err := lowerLimit(unix.RLIMIT_DATA, uint64(memoryMB)<<20); err != nil {
+		return fmt.Errorf("memory limit: %w", err)
+	}
+	return nil
+}
+
+// lowerLimit sets both limits of resource to v, or leaves them where they
+// are if they are already lower: an unprivileged process cannot raise them.
+func lowerLimit(resource int, v uint64) error {
+	var cur unix.Rlimit
+	if err := unix.Getrlimit(resource, &cur); err != nil {
+		return err
+	}
+	v = min(v, cur.Max)
+	return unix.Setrlimit(resource, &unix.Rlimit{Cur: v, Max: v})
+}
@@ -0,0 +1,157 @@
+package sandbox
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runSandboxed runs script with bash in dir under p and returns its
+// combined output.
+func runSandboxed(t *testing.T, p Policy, dir, script string) (string, error) {
+	t.Helper()
+	if !Probe().Sufficient() {
+		t.Skipf("sandbox unavailable: %s", Probe())
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Dir = dir
+	cleanup, err := p.Wrap(cmd)
+	require.NoError(t, err)
+	defer cleanup()
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestWrapConfinesWrites(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+
+	out, err := runSandboxed(t, Policy{Mode: ModeRequired}, workspace, fmt.Sprintf(`
+		echo ok > in.txt && mkdir sub && echo ok > "$TMPDIR/scratch" && echo ok > /dev/null || exit 3
+		echo bad > %q/escape.txt || echo denied`, outside))
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "denied")
+	assert.FileExists(t, filepath.Join(workspace, "in.txt"))
+	assert.NoFileExists(t, filepath.Join(outside, "escape.txt"))
+}
+
+func TestWrapWritableExtra(t *testing.T) {
+	workspace := t.TempDir()
+	cache := t.TempDir()
+	out, err := runSandboxed(t, Policy{Mode: ModeRequired, Writable: []string{cache}}, workspace,
+		fmt.Sprintf(`echo ok > %q/hit`, cache))
+	require.NoError(t, err, out)
+	assert.FileExists(t, filepath.Join(cache, "hit"))
+}
+
+func TestWrapNetwork(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+	script := fmt.Sprintf(`(exec 3<>/dev/tcp/127.0.0.1/%d) 2>/dev/null && echo connected || echo refused`, port)
+
+	out, err := runSandboxed(t, Policy{Mode: ModeRequired}, t.TempDir(), script)
+	require.NoError(t, err, out)
+	assert.Equal(t, "refused", strings.TrimSpace(out), "network is off by default")
+
+	out, err = runSandboxed(t, Policy{Mode: ModeRequired, Network: true}, t.TempDir(), script)
+	require.NoError(t, err, out)
+	assert.Equal(t, "connected", strings.TrimSpace(out))
+}
+
+func TestWrapHostUnixSocket(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	// Stands in for docker.sock or an ssh-agent: a path-named socket on the
+	// host that the network namespace does not hide.
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path="+sock)
+	script := fmt.Sprintf(`echo "${SSH_AUTH_SOCK:-unset} ${DBUS_SESSION_BUS_ADDRESS:-unset}"
+		python3 -c 'import socket; socket.socket(socket.AF_UNIX).connect(%q)' 2>/dev/null && echo connected || echo refused`, sock)
+
+	out, err := runSandboxed(t, Policy{Mode: ModeRequired}, t.TempDir(), script)
+	require.NoError(t, err, out)
+	assert.Equal(t, []string{"unset", "unset", "refused"}, strings.Fields(out), "network is off by default")
+
+	out, err = runSandboxed(t, Policy{Mode: ModeRequired, Network: true}, t.TempDir(), script)
+	require.NoError(t, err, out)
+	assert.Equal(t, []string{"unset", "unset", "connected"}, strings.Fields(out))
+}
+
+func TestWrapDeniedSyscalls(t *testing.T) {
+	if _, err := exec.LookPath("unshare"); err != nil {
+		t.Skip("unshare not installed")
+	}
+	out, err := runSandboxed(t, Policy{Mode: ModeRequired}, t.TempDir(), `unshare -U true 2>/dev/null && echo escaped || echo blocked`)
+	require.NoError(t, err, out)
+	assert.Equal(t, "blocked", strings.TrimSpace(out))
+}
+
+func TestWrapLimits(t *testing.T) {
+	out, err := runSandboxed(t, Policy{Mode: ModeRequired, CPUSeconds: 3, MemoryMB: 256}, t.TempDir(), `ulimit -t; ulimit -d`)
+	require.NoError(t, err, out)
+	assert.Equal(t, []string{"3", fmt.Sprint(256 << 10)}, strings.Fields(out))
+}
+
+func TestWrapOffLeavesCommand(t *testing.T) {
+	cmd := exec.Command("true")
+	cleanup, err := Policy{}.Wrap(cmd)
+	require.NoError(t, err)
+	cleanup()
+	assert.Equal(t, []string{"true"}, cmd.Args)
+	assert.Nil(t, cmd.SysProcAttr)
+}
+
+func TestWrapCleanupRemovesTempDir(t *testing.T) {
+	if !Probe().Sufficient() {
+		t.Skip("sandbox unavailable")
+	}
+	cmd := exec.Command("true")
+	cleanup, err := Policy{Mode: ModeAuto}.Wrap(cmd)
+	require.NoError(t, err)
+	var tmp string
+	for _, kv := range cmd.Env {
+		if v, ok := strings.CutPrefix(kv, "TMPDIR="); ok {
+			tmp = v
+		}
+	}
+	require.DirExists(t, tmp)
+	require.NoError(t, cmd.Run())
+	cleanup()
+	_, err = os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
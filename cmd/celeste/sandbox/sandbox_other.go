@@ -0,0 +1,23 @@
+//go:build !linux
+
+package sandbox
+
+import "os/exec"
+
+// Probe reports which sandbox layers this system supports: none, outside
+// Linux.
+func Probe() Status {
+	return Status{Detail: "the sandbox is Linux-only"}
+}
+
+// Wrap leaves cmd alone outside Linux, or refuses it under ModeRequired.
+func (p Policy) Wrap(cmd *exec.Cmd) (cleanup func(), err error) {
+	cleanup = func() {}
+	if p.Mode == ModeRequired {
+		return cleanup, unavailableError(Probe())
+	}
+	return cleanup, nil
+}
+
+// Init does nothing outside Linux.
+func Init() {}
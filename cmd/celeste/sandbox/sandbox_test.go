@@ -0,0 +1,55 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	// Wrap re-executes the test binary as the helper.
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]Mode{"": ModeOff, "off": ModeOff, "Auto": ModeAuto, " required ": ModeRequired} {
+		got, err := ParseMode(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := ParseMode("on")
+	assert.Error(t, err)
+}
+
+func TestPolicyTimeout(t *testing.T) {
+	off := Policy{}
+	assert.Equal(t, 20*time.Second, off.Timeout(20*time.Second))
+	assert.Equal(t, time.Duration(0), off.Timeout(0), "off keeps no timeout")
+
+	p := Policy{Mode: ModeAuto, TimeoutSeconds: 60}
+	assert.Equal(t, 20*time.Second, p.Timeout(20*time.Second))
+	assert.Equal(t, time.Minute, p.Timeout(5*time.Minute))
+	assert.Equal(t, time.Minute, p.Timeout(0))
+
+	_, _, d := Policy{Mode: ModeAuto}.Limits()
+	assert.Equal(t, DefaultTimeoutSeconds*time.Second, d)
+}
+
+func TestPolicyJobTimeout(t *testing.T) {
+	assert.Equal(t, time.Duration(0), Policy{}.JobTimeout(), "off has no job limit by default")
+	assert.Equal(t, 10*time.Minute, Policy{JobTimeoutSeconds: 600}.JobTimeout())
+	assert.Equal(t, DefaultJobTimeoutSeconds*time.Second, Policy{Mode: ModeAuto}.JobTimeout())
+	assert.Equal(t, 30*time.Second, Policy{Mode: ModeRequired, JobTimeoutSeconds: 30}.JobTimeout())
+	assert.False(t, Policy{JobTimeoutSeconds: 30}.IsZero())
+}
+
+func TestPolicyFromContext(t *testing.T) {
+	assert.False(t, PolicyFromContext(context.Background()).Enabled())
+	ctx := WithPolicy(context.Background(), Policy{Mode: ModeRequired})
+	assert.Equal(t, ModeRequired, PolicyFromContext(ctx).Mode)
+}
@@ -0,0 +1,175 @@
+//go:build linux && (amd64 || arm64)
+
+package sandbox
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const seccompArchSupported = true
+
+// deniedSyscalls fail with EPERM inside the sandbox: debugging or reading
+// other processes, loading kernel code, changing mounts or namespaces, and
+// io_uring, whose operations bypass seccomp.
+var deniedSyscalls = []uint32{
+	unix.SYS_PTRACE,
+	unix.SYS_PROCESS_VM_READV,
+	unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_KEXEC_FILE_LOAD,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_BPF,
+	unix.SYS_PERF_EVENT_OPEN,
+	unix.SYS_USERFAULTFD,
+	unix.SYS_KEYCTL,
+	unix.SYS_ADD_KEY,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_MOUNT,
+	unix.SYS_UMOUNT2,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_FSOPEN,
+	unix.SYS_FSMOUNT,
+	unix.SYS_FSPICK,
+	unix.SYS_FSCONFIG,
+	unix.SYS_MOVE_MOUNT,
+	unix.SYS_OPEN_TREE,
+	unix.SYS_MOUNT_SETATTR,
+	unix.SYS_UNSHARE,
+	unix.SYS_SETNS,
+	unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_NAME_TO_HANDLE_AT,
+	unix.SYS_SWAPON,
+	unix.SYS_SWAPOFF,
+	unix.SYS_REBOOT,
+	unix.SYS_ACCT,
+	unix.SYS_IO_URING_SETUP,
+	unix.SYS_IO_URING_ENTER,
+	unix.SYS_IO_URING_REGISTER,
+}
+
+// Offsets into struct seccomp_data.
+const (
+	seccompNr   = 0
+	seccompArch = 4
+	seccompArg0 = 16 // low word on little-endian amd64 and arm64
+)
+
+// installSeccomp filters this thread's syscalls, and those of what it
+// execs, through seccompFilter.
+func installSeccomp(network bool) error {
+	filter, err := seccompFilter(network)
+	if err != nil {
+		return err
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	return unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0)
+}
+
+// seccompFilter builds the BPF program: kill on a foreign architecture,
+// EPERM for deniedSyscalls and, with network off, for IPv4, IPv6 and unix
+// sockets; allow the rest. Unix sockets go too because the network
+// namespace only hides abstract ones: a socket with a path, such as
+// /var/run/docker.sock or an ssh-agent's, is reachable from any namespace,
+// and seccomp cannot see the path connect is given. socketpair still works,
+// so pipes between a command's own processes are unaffected.
+func seccompFilter(network bool) ([]unix.SockFilter, error) {
+	var b bpfBuilder
+	b.load(seccompArch)
+	b.jumpIfEqual(auditArch, "arch-ok", "")
+	b.ret(unix.SECCOMP_RET_KILL_PROCESS)
+	b.label("arch-ok")
+	b.load(seccompNr)
+	if x32Bit != 0 {
+		b.jumpIfSet(x32Bit, "deny", "")
+	}
+	for _, nr := range deniedSyscalls {
+		b.jumpIfEqual(nr, "deny", "")
+	}
+	if !network {
+		b.jumpIfEqual(unix.SYS_SOCKET, "", "allow")
+		b.load(seccompArg0)
+		b.jumpIfEqual(unix.AF_INET, "deny", "")
+		b.jumpIfEqual(unix.AF_INET6, "deny", "")
+		b.jumpIfEqual(unix.AF_UNIX, "deny", "")
+	}
+	b.label("allow")
+	b.ret(unix.SECCOMP_RET_ALLOW)
+	b.label("deny")
+	b.ret(unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM))
+	return b.assemble()
+}
+
+// bpfBuilder assembles a classic BPF program with named jump targets.
+type bpfBuilder struct {
+	prog   []unix.SockFilter
+	labels map[string]int
+	jumps  []bpfJump
+}
+
+type bpfJump struct {
+	at     int
+	jt, jf string // "" falls through to the next instruction
+}
+
+func (b *bpfBuilder) load(offset uint32) {
+	b.prog = append(b.prog, unix.SockFilter{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offset})
+}
+
+func (b *bpfBuilder) ret(action uint32) {
+	b.prog = append(b.prog, unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: action})
+}
+
+func (b *bpfBuilder) jumpIfEqual(k uint32, jt, jf string) {
+	b.jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, k, jt, jf)
+}
+
+func (b *bpfBuilder) jumpIfSet(k uint32, jt, jf string) {
+	b.jump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, k, jt, jf)
+}
+
+func (b *bpfBuilder) jump(code uint16, k uint32, jt, jf string) {
+	b.jumps = append(b.jumps, bpfJump{at: len(b.prog), jt: jt, jf: jf})
+	b.prog = append(b.prog, unix.SockFilter{Code: code, K: k})
+}
+
+func (b *bpfBuilder) label(name string) {
+	if b.labels == nil {
+		b.labels = map[string]int{}
+	}
+	b.labels[name] = len(b.prog)
+}
+
+// assemble resolves labels to the relative offsets BPF jumps take.
+func (b *bpfBuilder) assemble() ([]unix.SockFilter, error) {
+	offset := func(from int, label string) (uint8, error) {
+		if label == "" {
+			return 0, nil
+		}
+		to, ok := b.labels[label]
+		if !ok {
+			return 0, fmt.Errorf("bpf: undefined label %q", label)
+		}
+		rel := to - from - 1
+		if rel < 0 || rel > 255 {
+			return 0, fmt.Errorf("bpf: jump to %q out of range", label)
+		}
+		return uint8(rel), nil
+	}
+	for _, j := range b.jumps {
+		jt, err := offset(j.at, j.jt)
+		if err != nil {
+			return nil, err
+		}
+		jf, err := offset(j.at, j.jf)
+		if err != nil {
+			return nil, err
+		}
+		b.prog[j.at].Jt, b.prog[j.at].Jf = jt, jf
+	}
+	return b.prog, nil
+}
@@ -0,0 +1,9 @@
+package sandbox
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+// x32Bit marks x32-ABI syscall numbers, which would otherwise slip past a
+// filter written for the x86-64 numbers.
+const x32Bit = 0x40000000
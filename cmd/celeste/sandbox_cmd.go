@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+// runSandboxCommand handles `celeste sandbox [status]` and
+// `celeste sandbox run "<command>"`.
+func runSandboxCommand(args []string) {
+	var err error
+	switch {
+	case len(args) == 0 || args[0] == "status":
+		err = runSandboxStatus()
+	case args[0] == "run":
+		err = runSandboxRun(args[1:])
+	default:
+		err = fmt.Errorf("unknown sandbox subcommand %q. Try: status, run", args[0])
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// sandboxPolicy loads the sandbox policy from ~/.celeste/permissions.json.
+func sandboxPolicy() (sandbox.Policy, error) {
+	cfg, err := permissions.LoadConfig(permissions.DefaultConfigPath())
+	if err != nil {
+		return sandbox.Policy{}, err
+	}
+	return cfg.Sandbox, nil
+}
+
+func runSandboxStatus() error {
+	policy, err := sandboxPolicy()
+	if err != nil {
+		return err
+	}
+	status := sandbox.Probe()
+	fmt.Printf("System:  %s\n", status)
+	mode := policy.Mode
+	if mode == "" {
+		mode = sandbox.ModeOff
+	}
+	fmt.Printf("Mode:    %s (%s)\n", mode, permissions.DefaultConfigPath())
+	if !policy.Enabled() {
+		fmt.Println(`Enable with "sandbox": {"mode": "auto"} or {"mode": "required"} in permissions.json.`)
+		return nil
+	}
+	cpu, mem, timeout := policy.Limits()
+	fmt.Printf("Applies: %s\n", policy.Describe())
+	fmt.Printf("Limits:  %ds CPU, %d MB memory, %s wall clock per command, %s per background job\n",
+		cpu, mem, timeout, policy.JobTimeout())
+	if len(policy.Writable) > 0 {
+		fmt.Printf("Writable besides the workspace: %s\n", strings.Join(policy.Writable, ", "))
+	}
+	if policy.Mode == sandbox.ModeRequired && !status.Sufficient() {
+		fmt.Println("Commands will be refused: this system lacks Landlock or seccomp.")
+	}
+	return nil
+}
+
+// runSandboxRun runs a shell command in the current directory under the
+// configured policy ("auto" if it is off), to check what a tool would be
+// allowed to do.
+func runSandboxRun(args []string) error {
+	if len(args) == 0 {
+		return errors.New(`usage: celeste sandbox run "<command>"`)
+	}
+	policy, err := sandboxPolicy()
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled() {
+		policy.Mode = sandbox.ModeAuto
+	}
+	cmd := exec.Command("sh", "-c", strings.Join(args, " "))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cleanup, err := policy.Wrap(cmd)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return cmd.Run()
+}
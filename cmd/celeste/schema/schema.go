@@ -0,0 +1,307 @@
+// Package schema validates JSON values against the subset of JSON Schema
+// celeste's tool parameters and structured outputs use: type, properties,
+// required, additionalProperties, items, enum, const, anyOf/oneOf, and the
+// length, count and range bounds. Unknown keywords are ignored, as JSON
+// Schema specifies.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a validation failure at Path ("$" is the root value).
+type Error struct {
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Validate checks value, as produced by json.Unmarshal into an `any`, against
+// s. It reports the first violation found.
+func Validate(s map[string]any, value any) error {
+	return validate(s, value, "$")
+}
+
+// ValidateJSON decodes data and validates it against s.
+func ValidateJSON(s map[string]any, data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &Error{Path: "$", Message: "invalid JSON: " + err.Error()}
+	}
+	return Validate(s, value)
+}
+
+func validate(s map[string]any, value any, path string) error {
+	if s == nil {
+		return nil
+	}
+	fail := func(format string, args ...any) error {
+		return &Error{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if t, ok := s["type"]; ok && !matchesAnyType(t, value) {
+		return fail("expected %s, got %s", describeType(t), typeOf(value))
+	}
+	if enum, ok := anyList(s["enum"]); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("must be one of %s", compact(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !equal(c, value) {
+		return fail("must be %s", compact(c))
+	}
+	if err := validateAlternatives(s, value, path); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return validateObject(s, v, path)
+	case []any:
+		if n, ok := number(s["minItems"]); ok && float64(len(v)) < n {
+			return fail("must have at least %g items, got %d", n, len(v))
+		}
+		if n, ok := number(s["maxItems"]); ok && float64(len(v)) > n {
+			return fail("must have at most %g items, got %d", n, len(v))
+		}
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		n := float64(utf8.RuneCountInString(v))
+		if m, ok := number(s["minLength"]); ok && n < m {
+			return fail("must be at least %g characters", m)
+		}
+		if m, ok := number(s["maxLength"]); ok && n > m {
+			return fail("must be at most %g characters", m)
+		}
+	case float64:
+		if m, ok := number(s["minimum"]); ok && v < m {
+			return fail("must be >= %g", m)
+		}
+		if m, ok := number(s["maximum"]); ok && v > m {
+			return fail("must be <= %g", m)
+		}
+	}
+	return nil
+}
+
+func validateObject(s map[string]any, obj map[string]any, path string) error {
+	for _, name := range stringList(s["required"]) {
+		if _, ok := obj[name]; !ok {
+			return &Error{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+		}
+	}
+	props, _ := s["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sub := path + "." + k
+		if ps, ok := props[k].(map[string]any); ok {
+			if err := validate(ps, obj[k], sub); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := s["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return &Error{Path: path, Message: fmt.Sprintf("unexpected property %q", k)}
+			}
+		case map[string]any:
+			if err := validate(extra, obj[k], sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateAlternatives handles anyOf (at least one) and oneOf (exactly one).
+func validateAlternatives(s map[string]any, value any, path string) error {
+	for _, kw := range []string{"anyOf", "oneOf"} {
+		alts, ok := s[kw].([]any)
+		if !ok {
+			continue
+		}
+		matched := 0
+		var firstErr error
+		for _, a := range alts {
+			as, _ := a.(map[string]any)
+			if err := validate(as, value, path); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			matched++
+		}
+		switch {
+		case matched == 0:
+			return &Error{Path: path, Message: fmt.Sprintf("matches none of the %s alternatives (first: %v)", kw, firstErr)}
+		case kw == "oneOf" && matched > 1:
+			return &Error{Path: path, Message: fmt.Sprintf("matches %d oneOf alternatives, want exactly one", matched)}
+		}
+	}
+	return nil
+}
+
+func matchesAnyType(t any, value any) bool {
+	if s, ok := t.(string); ok {
+		return matchesType(s, value)
+	}
+	list, ok := anyList(t)
+	if !ok {
+		return true
+	}
+	for _, one := range list {
+		if s, ok := one.(string); ok && matchesType(s, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, value any) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func describeType(t any) string {
+	if list, ok := anyList(t); ok {
+		parts := make([]string, 0, len(list))
+		for _, p := range list {
+			parts = append(parts, fmt.Sprint(p))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// number reads a numeric keyword; schemas built in Go may hold ints.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// stringList reads "required", which schemas built in Go often hold as
+// []string rather than the []any json.Unmarshal produces.
+func stringList(v any) []string {
+	switch l := v.(type) {
+	case []string:
+		return l
+	case []any:
+		out := make([]string, 0, len(l))
+		for _, s := range l {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// anyList reads a list keyword held as []any or, from Go, []string.
+func anyList(v any) ([]any, bool) {
+	switch l := v.(type) {
+	case []any:
+		return l, true
+	case []string:
+		out := make([]any, len(l))
+		for i, s := range l {
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// equal compares JSON values, treating Go ints in a schema as numbers.
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compact(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var planSchema = map[string]any{
+	"type":     "object",
+	"required": []string{"steps"},
+	"properties": map[string]any{
+		"steps": map[string]any{
+			"type":     "array",
+			"minItems": 1,
+			"maxItems": 3,
+			"items": map[string]any{
+				"type":                 "object",
+				"required":             []string{"title"},
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"title":    map[string]any{"type": "string", "minLength": 1},
+					"severity": map[string]any{"enum": []string{"low", "high"}},
+					"line":     map[string]any{"type": "integer", "minimum": 0},
+				},
+			},
+		},
+	},
+}
+
+func TestValidateAccepts(t *testing.T) {
+	require.NoError(t, ValidateJSON(planSchema, []byte(`{"steps":[{"title":"a","severity":"low","line":3}],"extra":true}`)))
+}
+
+func TestValidateReportsPath(t *testing.T) {
+	for doc, want := range map[string]string{
+		`[]`:                                   `$: expected object, got array`,
+		`{}`:                                   `$: missing required property "steps"`,
+		`{"steps":[]}`:                         `$.steps: must have at least 1 items, got 0`,
+		`{"steps":[{"title":""}]}`:             `$.steps[0].title: must be at least 1 characters`,
+		`{"steps":[{"title":"a","line":1.5}]}`: `$.steps[0].line: expected integer, got number`,
+		`{"steps":[{"title":"a","severity":"x"}]}`:                            `$.steps[0].severity: must be one of ["low","high"]`,
+		`{"steps":[{"title":"a","why":"b"}]}`:                                 `$.steps[0]: unexpected property "why"`,
+		`{"steps":[{"title":"a"},{"title":"b"},{"title":"c"},{"title":"d"}]}`: `$.steps: must have at most 3 items, got 4`,
+	} {
+		err := ValidateJSON(planSchema, []byte(doc))
+		if assert.Error(t, err, doc) {
+			assert.Equal(t, want, err.Error(), doc)
+		}
+	}
+}
+
+func TestValidateAlternatives(t *testing.T) {
+	s := map[string]any{"anyOf": []any{
+		map[string]any{"type": "string"},
+		map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	}}
+	assert.NoError(t, ValidateJSON(s, []byte(`"a"`)))
+	assert.NoError(t, ValidateJSON(s, []byte(`["a"]`)))
+	assert.Error(t, ValidateJSON(s, []byte(`3`)))
+
+	nullable := map[string]any{"type": []any{"string", "null"}}
+	assert.NoError(t, ValidateJSON(nullable, []byte(`null`)))
+	assert.EqualError(t, ValidateJSON(nullable, []byte(`true`)), "$: expected string or null, got boolean")
+}
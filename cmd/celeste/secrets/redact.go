@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces known secret values in logs and tool output.
+const Redacted = "[REDACTED]"
+
+// minRedactLen is the shortest value Register accepts. Shorter "secrets"
+// (a project ID, a 4-digit PIN) would shred ordinary text.
+const minRedactLen = 8
+
+var known struct {
+	sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// Register adds credential values to the set Redact strips. Config loading
+// registers every credential it resolves.
+func Register(values ...string) {
+	known.Lock()
+	defer known.Unlock()
+	if known.values == nil {
+		known.values = make(map[string]bool)
+	}
+	changed := false
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minRedactLen || IsReference(v) || known.values[v] {
+			continue
+		}
+		known.values[v] = true
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	// Longest first so a secret that contains another is replaced whole.
+	sorted := make([]string, 0, len(known.values))
+	for v := range known.values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, v := range sorted {
+		pairs = append(pairs, v, Redacted)
+	}
+	known.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces every registered secret value in s.
+func Redact(s string) string {
+	known.RLock()
+	r := known.replacer
+	known.RUnlock()
+	if r == nil || s == "" {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// resetKnown clears the registry (tests only).
+func resetKnown() {
+	known.Lock()
+	known.values, known.replacer = nil, nil
+	known.Unlock()
+}
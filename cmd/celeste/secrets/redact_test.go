@@ -0,0 +1,18 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	resetKnown()
+	t.Cleanup(resetKnown)
+
+	assert.Equal(t, "nothing registered", Redact("nothing registered"))
+
+	Register("xai-abcdef123456", "short", "vault:xai", "xai-abcdef123456-long")
+	out := Redact("key=xai-abcdef123456 other=xai-abcdef123456-long pin=short")
+	assert.Equal(t, "key=[REDACTED] other=[REDACTED] pin=short", out)
+}
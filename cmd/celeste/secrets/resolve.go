@@ -0,0 +1,163 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reference prefixes accepted wherever config expects a credential.
+const (
+	PrefixVault = "vault:" // vault:<name> — entry in the encrypted vault
+	PrefixEnv   = "env:"   // env:<VAR> — environment variable
+	PrefixCmd   = "cmd:"   // cmd:<command> — stdout of a shell command, e.g. cmd:pass show celeste/xai
+)
+
+// PassphraseEnv names the environment variable checked for the vault
+// passphrase before prompting.
+const PassphraseEnv = "CELESTE_VAULT_PASSPHRASE"
+
+// cmdTimeout bounds a cmd: lookup so a hung password manager cannot stall
+// startup indefinitely.
+const cmdTimeout = 15 * time.Second
+
+// PassphraseFunc supplies the vault passphrase when PassphraseEnv is unset.
+// The CLI installs a terminal prompt; left nil, vault references fail with
+// an error that names PassphraseEnv.
+var PassphraseFunc func() (string, error)
+
+// IsReference reports whether v is a secret reference rather than a value.
+func IsReference(v string) bool {
+	return strings.HasPrefix(v, PrefixVault) || strings.HasPrefix(v, PrefixEnv) || strings.HasPrefix(v, PrefixCmd)
+}
+
+// Passphrase returns the vault passphrase from the environment or
+// PassphraseFunc.
+func Passphrase() (string, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+	if PassphraseFunc != nil {
+		return PassphraseFunc()
+	}
+	return "", fmt.Errorf("secrets: vault is locked; set %s or run interactively", PassphraseEnv)
+}
+
+// Resolver turns references into values. The vault is opened on first use
+// and cmd: results are cached, so each is paid for once per process.
+type Resolver struct {
+	vaultPath string
+
+	mu       sync.Mutex
+	vault    *Vault
+	vaultErr error
+	cmdCache map[string]string
+}
+
+// NewResolver creates a resolver for the vault at vaultPath (empty means
+// DefaultVaultPath).
+func NewResolver(vaultPath string) *Resolver {
+	return &Resolver{vaultPath: vaultPath, cmdCache: make(map[string]string)}
+}
+
+// Default is the process-wide resolver config loading uses.
+var Default = NewResolver("")
+
+// Resolve is Default.Resolve.
+func Resolve(ref string) (string, error) {
+	return Default.Resolve(ref)
+}
+
+// Resolve returns the value a reference points to. Plain values are
+// returned unchanged, so callers can pass any credential field through.
+func (r *Resolver) Resolve(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, PrefixEnv):
+		name := strings.TrimPrefix(ref, PrefixEnv)
+		val, ok := os.LookupEnv(name)
+		if !ok || val == "" {
+			return "", fmt.Errorf("secrets: environment variable %s is not set", name)
+		}
+		return val, nil
+
+	case strings.HasPrefix(ref, PrefixCmd):
+		return r.runCmd(strings.TrimSpace(strings.TrimPrefix(ref, PrefixCmd)))
+
+	case strings.HasPrefix(ref, PrefixVault):
+		name := strings.TrimPrefix(ref, PrefixVault)
+		v, err := r.Vault()
+		if err != nil {
+			return "", err
+		}
+		val, ok := v.Get(name)
+		if !ok {
+			return "", fmt.Errorf("secrets: %q is not in the vault", name)
+		}
+		return val, nil
+
+	default:
+		return ref, nil
+	}
+}
+
+// Vault opens the vault, prompting for the passphrase once. A failed open is
+// remembered so a wrong passphrase is not asked for again for every field.
+func (r *Resolver) Vault() (*Vault, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.vault != nil || r.vaultErr != nil {
+		return r.vault, r.vaultErr
+	}
+	path := r.vaultPath
+	if path == "" {
+		path = DefaultVaultPath()
+	}
+	if !Exists(path) {
+		r.vaultErr = errors.New("secrets: no vault yet; add secrets with `celeste secrets set`")
+		return nil, r.vaultErr
+	}
+	pass, err := Passphrase()
+	if err == nil {
+		r.vault, err = OpenVault(path, pass)
+	}
+	r.vaultErr = err
+	return r.vault, err
+}
+
+func (r *Resolver) runCmd(command string) (string, error) {
+	r.mu.Lock()
+	cached, ok := r.cmdCache[command]
+	r.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.Stdin = os.Stdin // password managers may prompt (gpg pinentry, pass)
+	if err := cmd.Run(); err != nil {
+		// stderr, not stdout: a failing command might still have printed
+		// part of the secret.
+		return "", fmt.Errorf("secrets: cmd %q failed: %v: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	// Only the first line counts: `pass show` prints metadata below the
+	// password.
+	val := strings.TrimSpace(strings.SplitN(stdout.String(), "\n", 2)[0])
+	if val == "" {
+		return "", fmt.Errorf("secrets: cmd %q printed nothing", command)
+	}
+
+	r.mu.Lock()
+	r.cmdCache[command] = val
+	r.mu.Unlock()
+	return val, nil
+}
@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePlainAndEnv(t *testing.T) {
+	r := NewResolver(filepath.Join(t.TempDir(), "vault.json"))
+
+	got, err := r.Resolve("sk-plain-value")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-plain-value", got)
+
+	t.Setenv("CELESTE_TEST_KEY", "from-env")
+	got, err = r.Resolve("env:CELESTE_TEST_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", got)
+
+	_, err = r.Resolve("env:CELESTE_TEST_UNSET_KEY")
+	assert.Error(t, err)
+}
+
+func TestResolveCmd(t *testing.T) {
+	r := NewResolver(filepath.Join(t.TempDir(), "vault.json"))
+
+	got, err := r.Resolve("cmd:printf 'hunter2\\nurl: example.com\\n'")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", got, "only the first line is the secret")
+
+	_, err = r.Resolve("cmd:exit 3")
+	assert.Error(t, err)
+}
+
+func TestResolveVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v, err := OpenVault(path, "pw")
+	require.NoError(t, err)
+	v.Set("xai", "xai-secret-value")
+	require.NoError(t, v.Save())
+
+	t.Setenv(PassphraseEnv, "pw")
+	r := NewResolver(path)
+	got, err := r.Resolve("vault:xai")
+	require.NoError(t, err)
+	assert.Equal(t, "xai-secret-value", got)
+
+	_, err = r.Resolve("vault:missing")
+	assert.Error(t, err)
+}
+
+func TestResolveVaultWrongPassphraseIsRemembered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v, err := OpenVault(path, "pw")
+	require.NoError(t, err)
+	v.Set("xai", "xai-secret-value")
+	require.NoError(t, v.Save())
+
+	prompts := 0
+	old := PassphraseFunc
+	PassphraseFunc = func() (string, error) { prompts++; return "nope", nil }
+	t.Cleanup(func() { PassphraseFunc = old })
+	t.Setenv(PassphraseEnv, "")
+
+	r := NewResolver(path)
+	_, err = r.Resolve("vault:xai")
+	assert.ErrorIs(t, err, ErrBadPassphrase)
+	_, err = r.Resolve("vault:other")
+	assert.ErrorIs(t, err, ErrBadPassphrase)
+	assert.Equal(t, 1, prompts, "a wrong passphrase should not be asked for per field")
+}
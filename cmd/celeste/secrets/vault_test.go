@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+
+	v, err := OpenVault(path, "correct horse")
+	require.NoError(t, err)
+	v.Set("xai", "xai-abcdef123456")
+	v.Set("venice", "vn-0987654321")
+	require.NoError(t, v.Save())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "xai-abcdef123456")
+	assert.NotContains(t, string(raw), "venice", "names are encrypted too")
+
+	reopened, err := OpenVault(path, "correct horse")
+	require.NoError(t, err)
+	got, ok := reopened.Get("xai")
+	assert.True(t, ok)
+	assert.Equal(t, "xai-abcdef123456", got)
+	assert.Equal(t, []string{"venice", "xai"}, reopened.Names())
+
+	assert.True(t, reopened.Delete("venice"))
+	assert.False(t, reopened.Delete("venice"))
+}
+
+func TestVaultWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v, err := OpenVault(path, "right")
+	require.NoError(t, err)
+	v.Set("k", "value")
+	require.NoError(t, v.Save())
+
+	_, err = OpenVault(path, "wrong")
+	assert.ErrorIs(t, err, ErrBadPassphrase)
+}
+
+func TestVaultTamperDetected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v, err := OpenVault(path, "pass")
+	require.NoError(t, err)
+	v.Set("k", "value")
+	require.NoError(t, v.Save())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// Flip a byte inside the base64 ciphertext.
+	i := strings.Index(string(raw), `"ciphertext": "`) + len(`"ciphertext": "`)
+	if raw[i] == 'A' {
+		raw[i] = 'B'
+	} else {
+		raw[i] = 'A'
+	}
+	require.NoError(t, os.WriteFile(path, raw, 0600))
+
+	_, err = OpenVault(path, "pass")
+	assert.ErrorIs(t, err, ErrBadPassphrase)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
+)
+
+// init installs the terminal passphrase prompt used when a config references
+// vault:<name> and CELESTE_VAULT_PASSPHRASE is unset.
+func init() {
+	secrets.PassphraseFunc = func() (string, error) {
+		return promptPassphrase("Vault passphrase: ", false)
+	}
+}
+
+// runSecretsCommand handles `celeste secrets <subcommand>`.
+func runSecretsCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: celeste secrets set|get|list|delete|migrate [args]")
+		os.Exit(1)
+	}
+	var err error
+	switch args[0] {
+	case "set":
+		err = runSecretsSet(args[1:])
+	case "get":
+		err = runSecretsGet(args[1:])
+	case "list", "ls":
+		err = runSecretsList()
+	case "delete", "rm":
+		err = runSecretsDelete(args[1:])
+	case "migrate":
+		err = runSecretsMigrate(args[1:])
+	default:
+		err = fmt.Errorf("unknown secrets subcommand %q. Try: set, get, list, delete, migrate", args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// openVaultForWrite opens the vault, creating it (with a confirmed
+// passphrase) if it does not exist yet.
+func openVaultForWrite() (*secrets.Vault, error) {
+	path := secrets.DefaultVaultPath()
+	if secrets.Exists(path) {
+		return secrets.Default.Vault()
+	}
+	pass := os.Getenv(secrets.PassphraseEnv)
+	if pass == "" {
+		fmt.Fprintf(os.Stderr, "Creating a new vault at %s\n", path)
+		var err error
+		if pass, err = promptPassphrase("New vault passphrase: ", true); err != nil {
+			return nil, err
+		}
+	}
+	return secrets.OpenVault(path, pass)
+}
+
+func runSecretsSet(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: celeste secrets set <name> [value]  (value is prompted for when omitted)")
+	}
+	name := args[0]
+	v, err := openVaultForWrite()
+	if err != nil {
+		return err
+	}
+	var value string
+	if len(args) == 2 {
+		value = args[1]
+	} else if value, err = readSecretValue(name); err != nil {
+		return err
+	}
+	if value == "" {
+		return errors.New("empty value; nothing stored")
+	}
+	v.Set(name, value)
+	if err := v.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Stored %q. Reference it in config as \"%s%s\".\n", name, secrets.PrefixVault, name)
+	return nil
+}
+
+func runSecretsGet(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: celeste secrets get <name|env:VAR|cmd:...>")
+	}
+	ref := args[0]
+	if !secrets.IsReference(ref) {
+		ref = secrets.PrefixVault + ref
+	}
+	val, err := secrets.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	fmt.Println(val)
+	return nil
+}
+
+func runSecretsList() error {
+	if !secrets.Exists(secrets.DefaultVaultPath()) {
+		fmt.Println("No vault yet. Add a secret with: celeste secrets set <name>")
+		return nil
+	}
+	v, err := secrets.Default.Vault()
+	if err != nil {
+		return err
+	}
+	names := v.Names()
+	if len(names) == 0 {
+		fmt.Println("Vault is empty.")
+		return nil
+	}
+	for _, name := range names {
+		val, _ := v.Get(name)
+		fmt.Printf("  %-40s %s\n", name, maskKey(val))
+	}
+	return nil
+}
+
+func runSecretsDelete(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: celeste secrets delete <name>")
+	}
+	v, err := secrets.Default.Vault()
+	if err != nil {
+		return err
+	}
+	if !v.Delete(args[0]) {
+		return fmt.Errorf("%q is not in the vault", args[0])
+	}
+	if err := v.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %q. Config entries that reference it will now load empty.\n", args[0])
+	return nil
+}
+
+func runSecretsMigrate(args []string) error {
+	fs := flag.NewFlagSet("secrets migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "list what would move without writing anything")
+	_ = fs.Parse(args)
+
+	v, err := openVaultForWrite()
+	if err != nil {
+		return err
+	}
+	res, err := config.MigrateSecrets(v, *dryRun)
+	if err != nil {
+		return err
+	}
+	if len(res.Moved) == 0 {
+		fmt.Println("No plaintext credentials found; nothing to migrate.")
+		return nil
+	}
+	verb := "Moved"
+	if *dryRun {
+		verb = "Would move"
+	}
+	fmt.Printf("%s %d credential(s) into the vault:\n", verb, len(res.Moved))
+	for _, name := range res.Moved {
+		fmt.Printf("  %s%s\n", secrets.PrefixVault, name)
+	}
+	if !*dryRun {
+		fmt.Printf("Rewrote %d config file(s) to reference the vault.\n", len(res.Files))
+	}
+	return nil
+}
+
+// promptPassphrase reads a passphrase without echo. With confirm it is asked
+// for twice, for creating a vault.
+func promptPassphrase(prompt string, confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("vault is locked and stdin is not a terminal; set %s", secrets.PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if !confirm {
+		return string(first), nil
+	}
+	fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(first) != string(second) {
+		return "", errors.New("passphrases do not match")
+	}
+	return string(first), nil
+}
+
+// readSecretValue prompts for a value without echo, or reads one line from
+// stdin when piped (`pass show x | celeste secrets set x`).
+func readSecretValue(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+	fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return strings.TrimSpace(string(b)), err
+}
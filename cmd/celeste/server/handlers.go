@@ -208,10 +208,11 @@ func runChatMode(ctx context.Context, cfg *config.Config, prompt, workspace stri
 	builtin.RegisterAll(registry, workspace, nil, nil, nil)
 
 	llmConfig := &llm.Config{
-		APIKey:  cfg.APIKey,
-		BaseURL: cfg.BaseURL,
-		Model:   cfg.Model,
-		Timeout: cfg.GetTimeout(),
+		APIKey:    cfg.APIKey,
+		BaseURL:   cfg.BaseURL,
+		Model:     cfg.Model,
+		Timeout:   cfg.GetTimeout(),
+		Fallbacks: llm.FallbacksFromConfig(cfg),
 	}
 	client := llm.NewClient(llmConfig, registry)
 
@@ -301,6 +302,7 @@ func runChatMode(ctx context.Context, cfg *config.Config, prompt, workspace stri
 						Name:             tc.Name,
 						Arguments:        tc.Arguments,
 						ThoughtSignature: tc.ThoughtSignature,
+						ReasoningState:   tc.ReasoningState,
 					})
 				}
 				return calls
@@ -621,10 +623,11 @@ func registerCelesteContentTool(s *Server) {
 
 		registry := tools.NewRegistry()
 		llmConfig := &llm.Config{
-			APIKey:  cfg.APIKey,
-			BaseURL: cfg.BaseURL,
-			Model:   cfg.Model,
-			Timeout: cfg.GetTimeout(),
+			APIKey:    cfg.APIKey,
+			BaseURL:   cfg.BaseURL,
+			Model:     cfg.Model,
+			Timeout:   cfg.GetTimeout(),
+			Fallbacks: llm.FallbacksFromConfig(cfg),
 		}
 		client := llm.NewClient(llmConfig, registry)
 
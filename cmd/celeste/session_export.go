@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/agent"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sessions"
+)
+
+// runSessionExportCommand implements `celeste sessions export <id>`. The id
+// is looked up first as a JSONL session log for the current project, then as
+// an agent run checkpoint, so one command covers both kinds of transcript.
+func runSessionExportCommand(args []string) {
+	fs := flag.NewFlagSet("sessions export", flag.ExitOnError)
+	format := fs.String("format", sessions.ExportMarkdown, "Output format: md, html or json")
+	out := fs.String("out", "", "Output file (defaults to ~/.celeste/exports/<id>.<format>)")
+	noRedact := fs.Bool("no-redact", false, "Skip the redaction pass (configured credentials, keys, tokens, emails, redact_patterns)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: celeste sessions export <id> [--format md|html|json] [--out <file>] [--no-redact]")
+		fs.PrintDefaults()
+	}
+
+	// Accept the id before or after the flags: the flag package stops at the
+	// first positional argument, so `export <id> --format html` needs help.
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+	if id == "" {
+		id = fs.Arg(0)
+	}
+	if id == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ext := strings.ToLower(*format)
+	if ext == "markdown" {
+		ext = sessions.ExportMarkdown
+	}
+	if ext != sessions.ExportMarkdown && ext != sessions.ExportHTML && ext != sessions.ExportJSON {
+		fmt.Fprintf(os.Stderr, "Unsupported format %q (want md, html or json)\n", *format)
+		os.Exit(1)
+	}
+
+	transcript, err := loadExportTranscript(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var redactor *sessions.Redactor
+	if !*noRedact {
+		// Loading the config registers its credentials with the secrets
+		// package, which the redactor builds on.
+		var patterns []string
+		if cfg, err := config.LoadNamed(configName); err == nil {
+			patterns = cfg.RedactPatterns
+		}
+		redactor, err = sessions.NewRedactor(patterns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	data, err := sessions.Export(transcript, ext, redactor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	path := *out
+	if path == "" {
+		path = filepath.Join(config.GetExportDir(), fmt.Sprintf("%s.%s", transcript.ID, ext))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating export directory: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Exported %s %s (%d entries) to %s\n", transcript.Source, transcript.ID, len(transcript.Entries), path)
+	if redactor != nil {
+		total := 0
+		for _, n := range redactor.Counts() {
+			total += n
+		}
+		fmt.Printf("Redacted %d value(s)\n", total)
+	}
+}
+
+// loadExportTranscript resolves id to a session log of the current project or,
+// failing that, to an agent run checkpoint.
+func loadExportTranscript(id string) (*sessions.Transcript, error) {
+	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return nil, fmt.Errorf("invalid session id: %s", id)
+	}
+
+	cwd, _ := os.Getwd()
+	if mgr, err := sessions.NewManager(cwd); err == nil {
+		p := filepath.Join(mgr.SessionDir(), id+".jsonl")
+		if _, err := os.Stat(p); err == nil {
+			return sessions.LoadTranscript(p)
+		}
+	}
+
+	store, err := agent.NewCheckpointStore("")
+	if err != nil {
+		return nil, err
+	}
+	state, err := store.Load(id)
+	if err != nil {
+		return nil, fmt.Errorf("no session log or agent run named %q (see `celeste resume` and `celeste agent --list-runs`)", id)
+	}
+	return agent.TranscriptFromRunState(state), nil
+}
@@ -0,0 +1,378 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
+)
+
+// Export formats accepted by Export.
+const (
+	ExportMarkdown = "md"
+	ExportHTML     = "html"
+	ExportJSON     = "json"
+)
+
+// BundleFormatVersion is bumped whenever the JSON bundle layout changes in a
+// way a consumer would notice.
+const BundleFormatVersion = 1
+
+// maxExportToolOutput caps a single tool result in the Markdown and HTML
+// renderings. The JSON bundle always carries the full text.
+const maxExportToolOutput = 10000
+
+// Transcript is the source-independent view of a conversation that the
+// exporters render. It is built from a JSONL session log by LoadTranscript or
+// from an agent run by agent.TranscriptFromRunState.
+type Transcript struct {
+	ID        string            `json:"id"`
+	Source    string            `json:"source"` // "session" or "agent_run"
+	Title     string            `json:"title,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Entries   []LogEntry        `json:"entries"`
+}
+
+// Bundle is the shareable JSON artifact: the (redacted) transcript plus
+// enough provenance for the reader to know what was stripped.
+type Bundle struct {
+	FormatVersion int            `json:"format_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Redacted      bool           `json:"redacted"`
+	Redactions    map[string]int `json:"redactions,omitempty"`
+	Transcript    *Transcript    `json:"transcript"`
+}
+
+// LoadTranscript reads a JSONL session log into a Transcript.
+func LoadTranscript(path string) (*Transcript, error) {
+	entries, err := ReadSession(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transcript{
+		ID:      strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+		Source:  "session",
+		Entries: entries,
+	}
+	for _, e := range entries {
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = e.Timestamp
+		}
+		if e.Timestamp.After(t.UpdatedAt) {
+			t.UpdatedAt = e.Timestamp
+		}
+		if t.Title == "" && e.Type == "user" {
+			t.Title = truncateTitle(e.Content, 60)
+		}
+	}
+	return t, nil
+}
+
+// Export renders t in format. When redactor is non-nil the transcript is
+// redacted first and the bundle records what was removed.
+func Export(t *Transcript, format string, redactor *Redactor) ([]byte, error) {
+	if t == nil {
+		return nil, fmt.Errorf("sessions: transcript is nil")
+	}
+	if redactor != nil {
+		t = redactor.RedactTranscript(t)
+	}
+
+	switch strings.ToLower(format) {
+	case ExportMarkdown, "markdown":
+		return []byte(RenderMarkdown(t)), nil
+	case ExportHTML:
+		return []byte(RenderHTML(t)), nil
+	case ExportJSON:
+		b := Bundle{
+			FormatVersion: BundleFormatVersion,
+			ExportedAt:    time.Now().UTC(),
+			Redacted:      redactor != nil,
+			Transcript:    t,
+		}
+		if redactor != nil {
+			b.Redactions = redactor.Counts()
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(b); err != nil {
+			return nil, fmt.Errorf("sessions: encode bundle: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("sessions: unsupported export format %q (want md, html or json)", format)
+	}
+}
+
+// RenderMarkdown renders t as a standalone Markdown document.
+func RenderMarkdown(t *Transcript) string {
+	var sb strings.Builder
+
+	title := t.Title
+	if title == "" {
+		title = "Session " + t.ID
+	}
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	fmt.Fprintf(&sb, "- **ID:** %s\n", t.ID)
+	fmt.Fprintf(&sb, "- **Source:** %s\n", t.Source)
+	if !t.CreatedAt.IsZero() {
+		fmt.Fprintf(&sb, "- **Started:** %s\n", t.CreatedAt.Format(time.RFC3339))
+	}
+	for _, k := range sortedKeys(t.Metadata) {
+		fmt.Fprintf(&sb, "- **%s:** %s\n", k, t.Metadata[k])
+	}
+	sb.WriteString("\n---\n\n")
+
+	for _, e := range t.Entries {
+		switch e.Type {
+		case "user", "assistant", "system":
+			fmt.Fprintf(&sb, "## %s%s\n\n", entryLabel(e), stamp(e.Timestamp))
+			sb.WriteString(e.Content)
+			sb.WriteString("\n\n")
+		case "thinking":
+			fmt.Fprintf(&sb, "<details>\n<summary>Thinking%s</summary>\n\n", stamp(e.Timestamp))
+			sb.WriteString(e.Content)
+			sb.WriteString("\n\n</details>\n\n")
+		case "tool_call":
+			fmt.Fprintf(&sb, "### Tool call: `%s`%s\n\n", e.ToolName, stamp(e.Timestamp))
+			writeFence(&sb, "json", prettyJSON(e.Content))
+		case "tool_result":
+			fmt.Fprintf(&sb, "### Tool result: `%s`\n\n", e.ToolName)
+			content := truncateExport(e.Content)
+			if looksLikeDiff(content) {
+				writeFence(&sb, "diff", content)
+			} else {
+				writeFence(&sb, "", content)
+			}
+		default:
+			fmt.Fprintf(&sb, "## %s%s\n\n%s\n\n", e.Type, stamp(e.Timestamp), e.Content)
+		}
+		for _, a := range e.Attachments {
+			fmt.Fprintf(&sb, "![%s](<%s>)\n\n", markdownAlt(a), a.Path)
+		}
+	}
+	return sb.String()
+}
+
+// RenderHTML renders t as a single self-contained HTML page: inline styles,
+// no scripts, no external assets, so it can be attached to a ticket as-is.
+func RenderHTML(t *Transcript) string {
+	var sb strings.Builder
+
+	title := t.Title
+	if title == "" {
+		title = "Session " + t.ID
+	}
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n", html.EscapeString(title))
+	sb.WriteString("<style>\n" + exportCSS + "</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n<table class=\"meta\">\n", html.EscapeString(title))
+	writeMetaRow(&sb, "ID", t.ID)
+	writeMetaRow(&sb, "Source", t.Source)
+	if !t.CreatedAt.IsZero() {
+		writeMetaRow(&sb, "Started", t.CreatedAt.Format(time.RFC3339))
+	}
+	for _, k := range sortedKeys(t.Metadata) {
+		writeMetaRow(&sb, k, t.Metadata[k])
+	}
+	sb.WriteString("</table>\n")
+
+	for _, e := range t.Entries {
+		switch e.Type {
+		case "user", "assistant", "system":
+			fmt.Fprintf(&sb, "<section class=\"msg %s\">\n<h2>%s<span class=\"ts\">%s</span></h2>\n<pre class=\"text\">%s</pre>\n</section>\n",
+				e.Type, entryLabel(e), html.EscapeString(stamp(e.Timestamp)), html.EscapeString(e.Content))
+		case "thinking":
+			fmt.Fprintf(&sb, "<details class=\"thinking\">\n<summary>Thinking</summary>\n<pre class=\"text\">%s</pre>\n</details>\n",
+				html.EscapeString(e.Content))
+		case "tool_call":
+			fmt.Fprintf(&sb, "<details class=\"tool\" open>\n<summary>Tool call: <code>%s</code></summary>\n<pre>%s</pre>\n</details>\n",
+				html.EscapeString(e.ToolName), html.EscapeString(prettyJSON(e.Content)))
+		case "tool_result":
+			content := truncateExport(e.Content)
+			fmt.Fprintf(&sb, "<details class=\"tool result\">\n<summary>Tool result: <code>%s</code></summary>\n", html.EscapeString(e.ToolName))
+			if looksLikeDiff(content) {
+				sb.WriteString(renderHTMLDiff(content))
+			} else {
+				fmt.Fprintf(&sb, "<pre>%s</pre>\n", html.EscapeString(content))
+			}
+			sb.WriteString("</details>\n")
+		default:
+			fmt.Fprintf(&sb, "<section class=\"msg\">\n<h2>%s</h2>\n<pre class=\"text\">%s</pre>\n</section>\n",
+				html.EscapeString(e.Type), html.EscapeString(e.Content))
+		}
+		for _, a := range e.Attachments {
+			writeHTMLAttachment(&sb, a)
+		}
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+const exportCSS = `body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;max-width:960px;margin:2em auto;padding:0 1em;background:#0f0a14;color:#e6e0ee}
+h1{color:#ff4fa3}
+h2{font-size:1em;margin:0 0 .4em;color:#b98cff}
+.ts{font-weight:normal;color:#7d7091;margin-left:.6em}
+.meta td{padding:.1em 1em .1em 0;color:#cfc6dc}
+.meta td:first-child{color:#7d7091}
+section.msg{border-left:3px solid #3b2b4f;padding:.4em 1em;margin:1em 0}
+section.user{border-color:#00d4ff}
+section.assistant{border-color:#ff4fa3}
+pre{background:#1a1224;padding:.8em;overflow-x:auto;border-radius:4px;margin:.4em 0}
+pre.text{background:none;padding:0;white-space:pre-wrap;word-wrap:break-word}
+details{margin:.6em 0 .6em 1em}
+summary{cursor:pointer;color:#b98cff}
+details.thinking summary{color:#7d7091;font-style:italic}
+.add{color:#7ee787}
+.del{color:#ff7b72}
+.hunk{color:#00d4ff}
+figure.asset{margin:.6em 0 1em 1em}
+figure.asset img{max-width:100%;border-radius:4px}
+figcaption{color:#7d7091;font-size:.9em}
+`
+
+// maxInlineAsset caps the size of an image embedded in an HTML export.
+// Larger ones are linked by path, which only resolves on the machine that
+// made them.
+const maxInlineAsset = 4 << 20
+
+// writeHTMLAttachment embeds an image attachment as a data URI so the page
+// stays self-contained.
+func writeHTMLAttachment(sb *strings.Builder, a media.Asset) {
+	caption := html.EscapeString(a.Path)
+	if a.Prompt != "" {
+		caption = html.EscapeString(a.Prompt) + "<br><code>" + caption + "</code>"
+	}
+	src := ""
+	if strings.HasPrefix(a.MIME, "image/") {
+		if data, err := os.ReadFile(a.Path); err == nil && len(data) <= maxInlineAsset {
+			src = "data:" + a.MIME + ";base64," + base64.StdEncoding.EncodeToString(data)
+		}
+	}
+	if src == "" {
+		fmt.Fprintf(sb, "<figure class=\"asset\"><figcaption>%s</figcaption></figure>\n", caption)
+		return
+	}
+	fmt.Fprintf(sb, "<figure class=\"asset\"><img src=\"%s\" alt=\"%s\"><figcaption>%s</figcaption></figure>\n",
+		src, html.EscapeString(a.Prompt), caption)
+}
+
+// markdownAlt is an attachment's prompt (or file name) made safe for
+// Markdown image alt text.
+func markdownAlt(a media.Asset) string {
+	alt := a.Prompt
+	if alt == "" {
+		alt = filepath.Base(a.Path)
+	}
+	alt = strings.NewReplacer("[", "(", "]", ")").Replace(alt)
+	return truncateTitle(alt, 80)
+}
+
+// renderHTMLDiff colours a unified diff line by line.
+func renderHTMLDiff(diff string) string {
+	var sb strings.Builder
+	sb.WriteString("<pre class=\"diff\">")
+	for _, line := range strings.Split(diff, "\n") {
+		class := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			class = "hunk"
+		case strings.HasPrefix(line, "@@"):
+			class = "hunk"
+		case strings.HasPrefix(line, "+"):
+			class = "add"
+		case strings.HasPrefix(line, "-"):
+			class = "del"
+		}
+		if class != "" {
+			fmt.Fprintf(&sb, "<span class=\"%s\">%s</span>\n", class, html.EscapeString(line))
+		} else {
+			sb.WriteString(html.EscapeString(line) + "\n")
+		}
+	}
+	sb.WriteString("</pre>\n")
+	return sb.String()
+}
+
+// looksLikeDiff reports whether s contains a unified diff hunk header, which
+// is how patch_file, git_diff and checkpoint diffs all present changes.
+func looksLikeDiff(s string) bool {
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(line, "@@ -") {
+			return true
+		}
+	}
+	return false
+}
+
+func entryLabel(e LogEntry) string {
+	switch e.Type {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	}
+	return e.Type
+}
+
+func stamp(ts time.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+	return " (" + ts.Format("2006-01-02 15:04:05") + ")"
+}
+
+// writeFence writes content inside a code fence long enough that backticks
+// in the content cannot close it early.
+func writeFence(sb *strings.Builder, lang, content string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(sb, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(content, "\n"), fence)
+}
+
+func writeMetaRow(sb *strings.Builder, k, v string) {
+	fmt.Fprintf(sb, "<tr><td>%s</td><td>%s</td></tr>\n", html.EscapeString(k), html.EscapeString(v))
+}
+
+// prettyJSON indents s when it is valid JSON and returns it unchanged
+// otherwise — tool arguments from text-parsed calls are not always JSON.
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+func truncateExport(s string) string {
+	if len(s) <= maxExportToolOutput {
+		return s
+	}
+	return s[:maxExportToolOutput] + fmt.Sprintf("\n...[truncated %d bytes]...", len(s)-maxExportToolOutput)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -49,7 +49,7 @@ func (m *Manager) StartSession() error {
 }
 
 // LogTurn writes a conversation turn. role should be one of the LogEntry.Type
-// values: "user", "assistant", "tool_call", "tool_result", "system".
+// values: "user", "assistant", "thinking", "tool_call", "tool_result", "system".
 func (m *Manager) LogTurn(role, content string) error {
 	if m.writer == nil {
 		return fmt.Errorf("sessions: no active session")
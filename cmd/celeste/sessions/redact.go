@@ -0,0 +1,140 @@
+package sessions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
+)
+
+// redactionRule is one named pattern the Redactor strips. When the pattern
+// has a capture group named "secret" only that group is replaced, so
+// `api_key = abc123…` keeps its key name and loses only the value.
+type redactionRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// builtinRedactionRules catch credentials celeste was never told about — a
+// key pasted into the chat, a token printed by a tool — and email
+// addresses. Specific credential formats come first so a key matched by its
+// provider rule is not re-labelled by the generic assignment rule further
+// down.
+var builtinRedactionRules = []redactionRule{
+	{"private_key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"anthropic_key", regexp.MustCompile(`sk-ant-[A-Za-z0-9_\-]{20,}`)},
+	{"openai_key", regexp.MustCompile(`sk-(?:proj-)?[A-Za-z0-9_\-]{20,}`)},
+	{"xai_key", regexp.MustCompile(`xai-[A-Za-z0-9]{20,}`)},
+	{"google_key", regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`)},
+	{"github_token", regexp.MustCompile(`(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}`)},
+	{"aws_key", regexp.MustCompile(`AKIA[0-9A-Z]{16}`)},
+	{"slack_token", regexp.MustCompile(`xox[abprs]-[A-Za-z0-9\-]{10,}`)},
+	{"jwt", regexp.MustCompile(`eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+	{"bearer_token", regexp.MustCompile(`(?i)bearer\s+(?P<secret>[A-Za-z0-9._~+/=\-]{16,})`)},
+	{"credential", regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|password|passwd|access[_-]?key)["']?\s*[:=]\s*["']?(?P<secret>[^\s"',;\[][^\s"',;]{7,})`)},
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+}
+
+// Redactor strips secrets and personal data from transcript text before it
+// leaves the machine. It builds on secrets.Redact, which knows every
+// credential config loading resolved, then applies the built-in rules and
+// the configured redact_patterns. It is not safe for concurrent use; create
+// one per export so Counts reflects that export alone.
+type Redactor struct {
+	rules  []redactionRule
+	counts map[string]int
+}
+
+// NewRedactor builds a Redactor from the built-in rules plus the configured
+// regular expressions in patterns, which may use a "secret" group as the
+// built-in rules do. An invalid pattern is an error rather than silently
+// skipped — a typo there would otherwise leak whatever it was meant to
+// catch.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{
+		rules:  append([]redactionRule(nil), builtinRedactionRules...),
+		counts: make(map[string]int),
+	}
+	for i, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("sessions: redact pattern %d: %w", i+1, err)
+		}
+		r.rules = append(r.rules, redactionRule{name: "custom", pattern: re})
+	}
+	return r, nil
+}
+
+// Redact returns s with every registered secret and every rule match
+// replaced by secrets.Redacted.
+func (r *Redactor) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	before := strings.Count(s, secrets.Redacted)
+	s = secrets.Redact(s)
+	if n := strings.Count(s, secrets.Redacted) - before; n > 0 {
+		r.counts["secret"] += n
+	}
+	for _, rule := range r.rules {
+		s = r.applyRule(rule, s)
+	}
+	return s
+}
+
+func (r *Redactor) applyRule(rule redactionRule, s string) string {
+	group := rule.pattern.SubexpIndex("secret")
+	matches := rule.pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if group > 0 && m[2*group] >= 0 {
+			start, end = m[2*group], m[2*group+1]
+		}
+		sb.WriteString(s[last:start])
+		sb.WriteString(secrets.Redacted)
+		last = end
+		r.counts[rule.name]++
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// Counts reports how many replacements were made so far, by rule: "secret"
+// for registered credentials, a built-in rule's name, or "custom" for
+// redact_patterns matches.
+func (r *Redactor) Counts() map[string]int {
+	out := make(map[string]int, len(r.counts))
+	for k, v := range r.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// RedactTranscript returns a copy of t with every free-text field redacted.
+// The original transcript is left untouched.
+func (r *Redactor) RedactTranscript(t *Transcript) *Transcript {
+	out := *t
+	out.Title = r.Redact(t.Title)
+	if t.Metadata != nil {
+		out.Metadata = make(map[string]string, len(t.Metadata))
+		for k, v := range t.Metadata {
+			out.Metadata[k] = r.Redact(v)
+		}
+	}
+	out.Entries = make([]LogEntry, len(t.Entries))
+	for i, e := range t.Entries {
+		e.Content = r.Redact(e.Content)
+		out.Entries[i] = e
+	}
+	return &out
+}
@@ -3,8 +3,11 @@ package sessions
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
 )
 
 // ---------- Writer tests ----------
@@ -56,6 +59,38 @@ func TestWriterAppendsEntries(t *testing.T) {
 	}
 }
 
+func TestWriterRedactsSecrets(t *testing.T) {
+	const secret = "sk-session-writer-0123456789"
+	secrets.Register(secret)
+
+	dir := t.TempDir()
+	w, err := NewSessionWriter(dir, "test-redact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []LogEntry{
+		{Type: "user", Content: "use " + secret + " for the API", Timestamp: time.Now()},
+		{Type: "tool_result", ToolName: "bash", Content: "OPENAI_API_KEY=" + secret, Timestamp: time.Now()},
+	}
+	for _, e := range entries {
+		if err := w.WriteEntry(e); err != nil {
+			t.Fatalf("WriteEntry: %v", err)
+		}
+	}
+	w.Close()
+
+	data, err := os.ReadFile(w.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Fatalf("secret written to disk:\n%s", data)
+	}
+	if !strings.Contains(string(data), "OPENAI_API_KEY="+secrets.Redacted) {
+		t.Fatalf("expected redaction marker:\n%s", data)
+	}
+}
+
 func TestWriterFiltersEphemeral(t *testing.T) {
 	dir := t.TempDir()
 	w, err := NewSessionWriter(dir, "test-ephemeral")
@@ -11,16 +11,22 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
 )
 
 // LogEntry represents a single line in the JSONL session log.
 type LogEntry struct {
-	Type      string    `json:"type"` // "user", "assistant", "tool_call", "tool_result", "system"
+	Type      string    `json:"type"` // "user", "assistant", "thinking", "tool_call", "tool_result", "system"
 	Content   string    `json:"content"`
 	Role      string    `json:"role,omitempty"`
 	ToolName  string    `json:"tool_name,omitempty"`
 	ToolID    string    `json:"tool_id,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
+	// Attachments reference generated files (images) by path; the files
+	// themselves live in the session's asset directory.
+	Attachments []media.Asset `json:"attachments,omitempty"`
 }
 
 // ephemeralTypes lists entry types that should never be persisted.
@@ -62,11 +68,13 @@ func NewSessionWriter(sessionDir string, sessionID string) (*SessionWriter, erro
 }
 
 // WriteEntry appends a single JSON line. Ephemeral types (progress, typing,
-// ping) are silently dropped.
+// ping) are silently dropped, and registered secrets are redacted from the
+// content, as they are from logs, before it reaches disk.
 func (w *SessionWriter) WriteEntry(entry LogEntry) error {
 	if ephemeralTypes[entry.Type] {
 		return nil
 	}
+	entry.Content = secrets.Redact(entry.Content)
 
 	w.mu.Lock()
 	defer w.mu.Unlock()
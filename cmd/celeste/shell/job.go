@@ -0,0 +1,194 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Job states.
+const (
+	StatusRunning = "running"
+	StatusExited  = "exited"
+	StatusKilled  = "killed"
+	// StatusTimedOut is a job killed for outliving its wall-clock limit.
+	StatusTimedOut = "timed_out"
+)
+
+// jobOutputSize is how much of a job's output is kept.
+const jobOutputSize = 256 << 10
+
+// killGrace is how long a process group has to exit after SIGTERM before
+// it gets SIGKILL.
+const killGrace = 2 * time.Second
+
+// Job is a background command whose output collects in a ring buffer.
+type Job struct {
+	ID      string
+	Command string
+	Dir     string
+	Started time.Time
+	// Timeout is the wall-clock limit after which the job is killed, 0 for
+	// none.
+	Timeout time.Duration
+
+	cmd     *exec.Cmd
+	out     *Ring
+	done    chan struct{}
+	cleanup func()
+	timer   *time.Timer
+
+	mu       sync.Mutex
+	status   string
+	exitCode int
+	ended    time.Time
+}
+
+// JobInfo is a snapshot of a job for listings.
+type JobInfo struct {
+	ID          string
+	Command     string
+	Dir         string
+	PID         int
+	Status      string
+	ExitCode    int
+	Started     time.Time
+	Elapsed     time.Duration
+	Timeout     time.Duration
+	OutputBytes int64
+}
+
+// startJob starts cmd, which runs command in dir, in the background.
+// cleanup runs once it has exited. A job still running after timeout is
+// killed; timeout <= 0 means no limit.
+func startJob(id, command, dir string, timeout time.Duration, cmd *exec.Cmd, cleanup func()) (*Job, error) {
+	if timeout < 0 {
+		timeout = 0
+	}
+	j := &Job{
+		ID:      id,
+		Command: command,
+		Dir:     dir,
+		Timeout: timeout,
+		cmd:     cmd,
+		out:     NewRing(jobOutputSize),
+		done:    make(chan struct{}),
+		cleanup: cleanup,
+		status:  StatusRunning,
+	}
+	cmd.Stdout = j.out
+	cmd.Stderr = j.out
+	// A daemon that escapes the process group can hold the output pipe
+	// open forever; don't let it keep the job "running".
+	cmd.WaitDelay = killGrace
+	newProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		return nil, err
+	}
+	j.Started = time.Now()
+	if timeout > 0 {
+		j.timer = time.AfterFunc(timeout, func() { j.stop(StatusTimedOut) })
+	}
+	go j.wait()
+	return j, nil
+}
+
+func (j *Job) wait() {
+	err := j.cmd.Wait()
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	j.cleanup()
+	j.mu.Lock()
+	j.ended = time.Now()
+	if j.status == StatusRunning {
+		j.status = StatusExited
+	}
+	j.exitCode = 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		j.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		j.exitCode = -1
+	}
+	j.mu.Unlock()
+	close(j.done)
+}
+
+// Info snapshots the job.
+func (j *Job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := JobInfo{
+		ID:          j.ID,
+		Command:     j.Command,
+		Dir:         j.Dir,
+		PID:         j.cmd.Process.Pid,
+		Status:      j.status,
+		ExitCode:    j.exitCode,
+		Started:     j.Started,
+		Timeout:     j.Timeout,
+		OutputBytes: j.out.Total(),
+	}
+	if j.ended.IsZero() {
+		info.Elapsed = time.Since(j.Started)
+	} else {
+		info.Elapsed = j.ended.Sub(j.Started)
+	}
+	return info
+}
+
+// Running reports whether the job has not exited yet.
+func (j *Job) Running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Output returns the output from offset on; see Ring.Since.
+func (j *Job) Output(offset int64) (data string, next, dropped int64) {
+	b, next, dropped := j.out.Since(offset)
+	return string(b), next, dropped
+}
+
+// Wait blocks until the job exits or ctx ends, and reports whether it
+// exited.
+func (j *Job) Wait(ctx context.Context) bool {
+	select {
+	case <-j.done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Kill stops the job's process group: SIGTERM, then SIGKILL if it is still
+// running after a grace period. It returns once the job has exited.
+func (j *Job) Kill() {
+	j.stop(StatusKilled)
+}
+
+// stop kills the job and records status as the reason.
+func (j *Job) stop(status string) {
+	j.mu.Lock()
+	if !j.ended.IsZero() || j.status != StatusRunning {
+		j.mu.Unlock()
+		<-j.done
+		return
+	}
+	j.status = status
+	j.mu.Unlock()
+	signalGroup(j.cmd, false)
+	select {
+	case <-j.done:
+	case <-time.After(killGrace):
+		signalGroup(j.cmd, true)
+		<-j.done
+	}
+}
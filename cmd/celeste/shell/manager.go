@@ -0,0 +1,261 @@
+// Package shell keeps state between the bash tool's calls: named
+// persistent shells whose cd and environment carry over, and background
+// jobs whose output collects in a ring buffer to poll later. A Manager
+// belongs to one chat session or agent run and kills everything it
+// started when that ends.
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+// Limits on what one session may keep running.
+const (
+	MaxShells      = 8
+	MaxRunningJobs = 16
+)
+
+// ErrClosed is returned once the Manager has been closed.
+var ErrClosed = errors.New("shell manager is closed")
+
+// Manager owns a session's persistent shells and background jobs.
+type Manager struct {
+	workspace string
+
+	mu      sync.Mutex
+	shells  map[string]*Shell
+	jobs    map[string]*Job
+	nextJob int
+	closed  bool
+}
+
+// NewManager creates a Manager whose shells and jobs start in workspace.
+func NewManager(workspace string) *Manager {
+	return &Manager{
+		workspace: workspace,
+		shells:    map[string]*Shell{},
+		jobs:      map[string]*Job{},
+		nextJob:   1,
+	}
+}
+
+// Run runs command in the named shell, starting the shell if needed. The
+// shell is confined by the sandbox policy in ctx when it starts. A shell
+// that times out or exits is dropped; the next Run starts a fresh one.
+func (m *Manager) Run(ctx context.Context, name, command string, timeout time.Duration) (Result, error) {
+	sh, err := m.shell(ctx, name)
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := sh.Run(ctx, command, timeout)
+	if err != nil || res.Exited {
+		m.mu.Lock()
+		if m.shells[name] == sh {
+			delete(m.shells, name)
+		}
+		m.mu.Unlock()
+	}
+	return res, err
+}
+
+func (m *Manager) shell(ctx context.Context, name string) (*Shell, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if sh, ok := m.shells[name]; ok && sh.Alive() {
+		return sh, nil
+	}
+	if len(m.shells) >= MaxShells {
+		return nil, fmt.Errorf("too many shells (%d); close one first", MaxShells)
+	}
+
+	args, bash := []string{"sh"}, false
+	if path, err := exec.LookPath("bash"); err == nil {
+		args, bash = []string{path, "--noprofile", "--norc"}, true
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = m.workspace
+	cleanup, err := sandbox.PolicyFromContext(ctx).Wrap(cmd)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := startShell(name, cmd, bash, cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("start shell %q: %w", name, err)
+	}
+	m.shells[name] = sh
+	return sh, nil
+}
+
+// Shell returns the named shell if it is running.
+func (m *Manager) Shell(name string) (*Shell, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sh, ok := m.shells[name]
+	if !ok || !sh.Alive() {
+		return nil, false
+	}
+	return sh, true
+}
+
+// Shells lists the running shells' names, sorted.
+func (m *Manager) Shells() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name, sh := range m.shells {
+		if sh.Alive() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CloseShell stops the named shell and reports whether it was running.
+func (m *Manager) CloseShell(name string) bool {
+	m.mu.Lock()
+	sh, ok := m.shells[name]
+	delete(m.shells, name)
+	m.mu.Unlock()
+	if ok {
+		sh.Close()
+	}
+	return ok
+}
+
+// Start runs command with sh in the background in dir (the workspace if
+// empty), confined by the sandbox policy in ctx. The sandbox's writable root
+// stays the workspace whatever dir is: the command cds to dir itself, so a
+// shell that wandered off cannot take write access with it. A job is killed
+// once it outlives the policy's JobTimeout, if there is one, and otherwise
+// runs until it exits, is killed or the Manager closes.
+func (m *Manager) Start(ctx context.Context, command, dir string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	running := 0
+	for _, j := range m.jobs {
+		if j.Running() {
+			running++
+		}
+	}
+	if running >= MaxRunningJobs {
+		return nil, fmt.Errorf("too many running jobs (%d); kill one first", MaxRunningJobs)
+	}
+	if dir == "" {
+		dir = m.workspace
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	if dir != m.workspace {
+		// dir arrives as $1 so it needs no quoting; shift hands the
+		// command the empty argument list it would otherwise have.
+		cmd = exec.Command("sh", "-c", "cd -- \"$1\" || exit 1; shift\n"+command, "sh", dir)
+	}
+	cmd.Dir = m.workspace
+	policy := sandbox.PolicyFromContext(ctx)
+	cleanup, err := policy.Wrap(cmd)
+	if err != nil {
+		return nil, err
+	}
+	id := fmt.Sprintf("job-%d", m.nextJob)
+	j, err := startJob(id, command, dir, policy.JobTimeout(), cmd, cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("start job: %w", err)
+	}
+	m.nextJob++
+	m.jobs[id] = j
+	return j, nil
+}
+
+// Job returns the job with id.
+func (m *Manager) Job(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	return j, ok
+}
+
+// Jobs lists every job this session started, oldest first.
+func (m *Manager) Jobs() []JobInfo {
+	m.mu.Lock()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	m.mu.Unlock()
+	infos := make([]JobInfo, len(jobs))
+	for i, j := range jobs {
+		infos[i] = j.Info()
+	}
+	sort.Slice(infos, func(a, b int) bool { return infos[a].Started.Before(infos[b].Started) })
+	return infos
+}
+
+// Kill stops the job with id and reports whether it exists.
+func (m *Manager) Kill(id string) bool {
+	j, ok := m.Job(id)
+	if ok {
+		j.Kill()
+	}
+	return ok
+}
+
+// Close stops every shell and running job and refuses new ones. It returns
+// the jobs that were still running, oldest first, so the caller can say
+// what the session left behind. It is safe to call more than once.
+func (m *Manager) Close() []JobInfo {
+	m.mu.Lock()
+	m.closed = true
+	shells := m.shells
+	m.shells = map[string]*Shell{}
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	m.mu.Unlock()
+
+	var running []JobInfo
+	for _, j := range jobs {
+		if info := j.Info(); info.Status == StatusRunning {
+			running = append(running, info)
+		}
+	}
+	sort.Slice(running, func(a, b int) bool { return running[a].Started.Before(running[b].Started) })
+
+	var wg sync.WaitGroup
+	for _, sh := range shells {
+		wg.Go(sh.Close)
+	}
+	for _, j := range jobs {
+		wg.Go(j.Kill)
+	}
+	wg.Wait()
+	return running
+}
+
+// ReportStopped writes the jobs Close stopped to w, one per line, so a
+// session does not end with work silently killed. It writes nothing for none.
+func ReportStopped(w io.Writer, jobs []JobInfo) {
+	if len(jobs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Stopped %d background job(s) still running at the end of the session:\n", len(jobs))
+	for _, j := range jobs {
+		fmt.Fprintf(w, "  %s  pid %d  %s  %s\n", j.ID, j.PID, j.Elapsed.Round(time.Second), j.Command)
+	}
+}
@@ -0,0 +1,243 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+func TestMain(m *testing.M) {
+	// Sandboxed shells and jobs re-execute the test binary as the helper.
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shells need sh")
+	}
+	dir := t.TempDir()
+	m := NewManager(dir)
+	t.Cleanup(func() { m.Close() })
+	return m, dir
+}
+
+func TestShellKeepsState(t *testing.T) {
+	m, dir := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+
+	res, err := m.Run(ctx, "dev", "cd sub && export GREETING=hi && greet() { echo \"$GREETING from $(basename $PWD)\"; }", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.ExitCode)
+
+	res, err = m.Run(ctx, "dev", "greet; false", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "hi from sub\n", res.Output)
+	assert.Equal(t, 1, res.ExitCode)
+
+	res, err = m.Run(ctx, "other", "pwd", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, dir, strings.TrimSpace(res.Output), "shells are independent")
+	assert.Equal(t, []string{"dev", "other"}, m.Shells())
+}
+
+func TestShellStdinAndSyntax(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	res, err := m.Run(ctx, "s", "cat; echo after", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", res.Output, "commands read /dev/null, not the shell's input")
+
+	if sh, ok := m.Shell("s"); !ok || !sh.bash {
+		t.Skip("syntax pre-check needs bash")
+	}
+	res, err = m.Run(ctx, "s", "echo 'unclosed", 5*time.Second)
+	require.NoError(t, err)
+	assert.NotZero(t, res.ExitCode)
+	assert.False(t, res.Exited, "a syntax error does not cost the shell")
+	assert.Contains(t, m.Shells(), "s")
+}
+
+func TestShellTimeoutAndExit(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	_, err := m.Run(ctx, "s", "export KEEP=1", 5*time.Second)
+	require.NoError(t, err)
+	res, err := m.Run(ctx, "s", "echo started; sleep 30", 300*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, res.TimedOut)
+	assert.Contains(t, res.Output, "started")
+
+	res, err = m.Run(ctx, "s", "echo ${KEEP:-gone}", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "gone\n", res.Output, "a timed-out shell is replaced")
+
+	res, err = m.Run(ctx, "s", "exit 3", 5*time.Second)
+	require.NoError(t, err)
+	assert.True(t, res.Exited)
+	assert.Equal(t, 3, res.ExitCode)
+	assert.Empty(t, m.Shells())
+}
+
+func TestJobs(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	quick, err := m.Start(ctx, "echo one; echo two >&2; exit 4", "")
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.True(t, quick.Wait(waitCtx))
+	out, next, dropped := quick.Output(0)
+	assert.Equal(t, "one\ntwo\n", out)
+	assert.Equal(t, int64(8), next)
+	assert.Zero(t, dropped)
+	info := quick.Info()
+	assert.Equal(t, StatusExited, info.Status)
+	assert.Equal(t, 4, info.ExitCode)
+
+	server, err := m.Start(ctx, "echo ready; sleep 60 & wait", "")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		out, _, _ := server.Output(0)
+		return out == "ready\n"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.True(t, server.Running())
+	assert.Equal(t, []string{"job-1", "job-2"}, []string{m.Jobs()[0].ID, m.Jobs()[1].ID})
+
+	assert.True(t, m.Kill("job-2"))
+	assert.False(t, server.Running())
+	assert.Equal(t, StatusKilled, server.Info().Status)
+	assert.False(t, m.Kill("job-9"))
+}
+
+func TestManagerClose(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	job, err := m.Start(ctx, "sleep 60", "")
+	require.NoError(t, err)
+	_, err = m.Run(ctx, "s", "true", 5*time.Second)
+	require.NoError(t, err)
+	sh, _ := m.Shell("s")
+
+	stopped := m.Close()
+	require.Len(t, stopped, 1, "the running job is reported")
+	assert.Equal(t, job.ID, stopped[0].ID)
+	assert.False(t, job.Running())
+	assert.False(t, sh.Alive())
+	assert.Empty(t, m.Close(), "nothing is left to stop")
+
+	var report strings.Builder
+	ReportStopped(&report, stopped)
+	assert.Contains(t, report.String(), "job-1")
+	assert.Contains(t, report.String(), "sleep 60")
+	_, err = m.Start(ctx, "true", "")
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = m.Run(ctx, "s", "true", time.Second)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestJobTimeout(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := sandbox.WithPolicy(context.Background(), sandbox.Policy{JobTimeoutSeconds: 1})
+
+	job, err := m.Start(ctx, "sleep 60", "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, job.Info().Timeout)
+	waitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.True(t, job.Wait(waitCtx), "a blocked job does not outlive its limit")
+	assert.Equal(t, StatusTimedOut, job.Info().Status)
+
+	// Killing it after the fact does not rewrite why it stopped.
+	job.Kill()
+	assert.Equal(t, StatusTimedOut, job.Info().Status)
+}
+
+func TestSandboxedShellAndJob(t *testing.T) {
+	if !sandbox.Probe().Sufficient() {
+		t.Skipf("sandbox unavailable: %s", sandbox.Probe())
+	}
+	m, dir := newTestManager(t)
+	outside := t.TempDir()
+	ctx := sandbox.WithPolicy(context.Background(), sandbox.Policy{Mode: sandbox.ModeRequired})
+
+	res, err := m.Run(ctx, "s", "touch ok && touch "+filepath.Join(outside, "escape"), 5*time.Second)
+	require.NoError(t, err)
+	assert.NotZero(t, res.ExitCode)
+	assert.FileExists(t, filepath.Join(dir, "ok"))
+
+	job, err := m.Start(ctx, "echo up; sleep 60 & sleep 60", "")
+	require.NoError(t, err)
+	assert.Equal(t, sandbox.DefaultJobTimeoutSeconds*time.Second, job.Info().Timeout, "a sandboxed job always has a limit")
+	assert.Eventually(t, func() bool {
+		out, _, _ := job.Output(0)
+		return out == "up\n"
+	}, 5*time.Second, 20*time.Millisecond)
+	start := time.Now()
+	job.Kill()
+	assert.False(t, job.Running(), "killing a sandboxed job stops its whole namespace")
+	assert.Less(t, time.Since(start), killGrace, "SIGTERM reaches the command, not an init that ignores it")
+	assert.NoFileExists(t, filepath.Join(outside, "escape"))
+}
+
+func TestJobRunsInShellDir(t *testing.T) {
+	m, dir := newTestManager(t)
+	ctx := context.Background()
+	if sandbox.Probe().Sufficient() {
+		// Inside a PID namespace the shell's /proc entry is the sandbox's
+		// init, so the directory has to come from the shell.
+		ctx = sandbox.WithPolicy(ctx, sandbox.Policy{Mode: sandbox.ModeRequired})
+	}
+	sub := filepath.Join(dir, "with space")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+
+	_, err := m.Run(ctx, "s", "cd 'with space'", 5*time.Second)
+	require.NoError(t, err)
+	sh, ok := m.Shell("s")
+	require.True(t, ok)
+	assert.Equal(t, sub, sh.Dir())
+
+	job, err := m.Start(ctx, `pwd; echo "args:$#"`, sh.Dir())
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.True(t, job.Wait(waitCtx))
+	out, _, _ := job.Output(0)
+	assert.Equal(t, sub+"\nargs:0\n", out)
+	assert.Equal(t, sub, job.Info().Dir)
+}
+
+func TestSandboxedJobWritableRootStaysWorkspace(t *testing.T) {
+	if !sandbox.Probe().Sufficient() {
+		t.Skipf("sandbox unavailable: %s", sandbox.Probe())
+	}
+	m, dir := newTestManager(t)
+	outside := t.TempDir()
+	ctx := sandbox.WithPolicy(context.Background(), sandbox.Policy{Mode: sandbox.ModeRequired})
+
+	_, err := m.Run(ctx, "s", "cd "+outside, 5*time.Second)
+	require.NoError(t, err)
+	sh, _ := m.Shell("s")
+	require.Equal(t, outside, sh.Dir())
+
+	job, err := m.Start(ctx, "touch escape; touch "+filepath.Join(dir, "inside"), sh.Dir())
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.True(t, job.Wait(waitCtx))
+	assert.NoFileExists(t, filepath.Join(outside, "escape"), "a job started from a shell outside the workspace cannot write there")
+	assert.FileExists(t, filepath.Join(dir, "inside"))
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// newProcessGroup starts cmd in a process group of its own, so stopping
+// it also stops whatever it spawned.
+func newProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalGroup sends sig, or SIGKILL when force is set, to cmd's process
+// group.
+func signalGroup(cmd *exec.Cmd, force bool) {
+	if cmd.Process == nil {
+		return
+	}
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, sig)
+}
@@ -0,0 +1,14 @@
+package shell
+
+import "os/exec"
+
+// newProcessGroup is a no-op on Windows; processes are stopped one by one.
+func newProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup kills cmd's process. Windows has no SIGTERM, so force is
+// implied.
+func signalGroup(cmd *exec.Cmd, force bool) {
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,67 @@
+package shell
+
+import "sync"
+
+// Ring keeps the last size bytes written to it and counts every byte ever
+// written, so readers can resume from an offset and learn how much they
+// missed.
+type Ring struct {
+	mu    sync.Mutex
+	buf   []byte
+	total int64
+}
+
+// NewRing returns a Ring holding at most size bytes.
+func NewRing(size int) *Ring {
+	return &Ring{buf: make([]byte, size)}
+}
+
+// Write appends p, overwriting the oldest bytes once the ring is full.
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(p)
+	size := len(r.buf)
+	if len(p) > size {
+		r.total += int64(len(p) - size)
+		p = p[len(p)-size:]
+	}
+	for len(p) > 0 {
+		at := int(r.total % int64(size))
+		c := copy(r.buf[at:], p)
+		r.total += int64(c)
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// Total is the number of bytes ever written.
+func (r *Ring) Total() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.total
+}
+
+// Since returns what was written from offset on, the offset to pass next
+// time, and how many bytes after offset were already overwritten.
+func (r *Ring) Since(offset int64) (data []byte, next, dropped int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	size := int64(len(r.buf))
+	start := max(r.total-size, 0)
+	if offset < start {
+		dropped = start - offset
+		offset = start
+	}
+	if offset > r.total {
+		offset = r.total
+	}
+	data = make([]byte, 0, r.total-offset)
+	for o := offset; o < r.total; {
+		at := o % size
+		end := min(size, at+(r.total-o))
+		data = append(data, r.buf[at:end]...)
+		o += end - at
+	}
+	return data, r.total, dropped
+}
@@ -0,0 +1,35 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	r := NewRing(8)
+	r.Write([]byte("hello"))
+	data, next, dropped := r.Since(0)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, int64(5), next)
+	assert.Zero(t, dropped)
+
+	r.Write([]byte(" world"))
+	data, next, dropped = r.Since(5)
+	assert.Equal(t, " world", string(data), "reads resume at the offset")
+	assert.Equal(t, int64(11), next)
+	assert.Zero(t, dropped)
+
+	data, _, dropped = r.Since(0)
+	assert.Equal(t, "lo world", string(data), "only the last 8 bytes are kept")
+	assert.Equal(t, int64(3), dropped)
+
+	r.Write([]byte("0123456789abcdef"))
+	data, next, _ = r.Since(next)
+	assert.Equal(t, "89abcdef", string(data))
+	assert.Equal(t, int64(27), r.Total())
+	assert.Equal(t, int64(27), next)
+
+	data, _, _ = r.Since(100)
+	assert.Empty(t, data)
+}
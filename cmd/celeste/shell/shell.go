@@ -0,0 +1,223 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shellOutputSize is how much of one command's output a shell keeps; the
+// tail wins, since that is where errors land.
+const shellOutputSize = 256 << 10
+
+// ErrShellExited is returned when a command is sent to a shell that has
+// exited, e.g. because a previous command ran `exit`.
+var ErrShellExited = errors.New("shell has exited")
+
+// Shell is a long-lived shell process. Commands run in it one at a time,
+// so cd, exported variables and functions carry over between them.
+type Shell struct {
+	Name    string
+	Started time.Time
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	out     *Ring
+	notify  chan struct{}
+	done    chan struct{}
+	cleanup func()
+	marker  string
+	bash    bool
+
+	runMu sync.Mutex // one command at a time
+	seq   int
+
+	dirMu sync.Mutex
+	dir   string // $PWD after the last command
+}
+
+// Result is the outcome of one command in a Shell.
+type Result struct {
+	Output    string
+	ExitCode  int
+	Truncated bool // the start of the output was dropped
+	TimedOut  bool // the shell was killed; its state is gone
+	Exited    bool // the command exited the shell
+}
+
+// startShell starts cmd, a shell reading commands from stdin.
+func startShell(name string, cmd *exec.Cmd, bash bool, cleanup func()) (*Shell, error) {
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	s := &Shell{
+		Name:    name,
+		cmd:     cmd,
+		out:     NewRing(shellOutputSize),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		cleanup: cleanup,
+		marker:  "__celeste_done_" + hex.EncodeToString(nonce),
+		bash:    bash,
+		dir:     cmd.Dir,
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	cmd.Stdout, cmd.Stderr = pw, pw
+	if s.stdin, err = cmd.StdinPipe(); err != nil {
+		pr.Close()
+		pw.Close()
+		cleanup()
+		return nil, err
+	}
+	newProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		pr.Close()
+		pw.Close()
+		cleanup()
+		return nil, err
+	}
+	pw.Close()
+	s.Started = time.Now()
+
+	go func() {
+		buf := make([]byte, 32<<10)
+		for {
+			n, err := pr.Read(buf)
+			if n > 0 {
+				s.out.Write(buf[:n])
+				select {
+				case s.notify <- struct{}{}:
+				default:
+				}
+			}
+			if err != nil {
+				pr.Close()
+				return
+			}
+		}
+	}()
+	go func() {
+		_ = cmd.Wait()
+		cleanup()
+		close(s.done)
+	}()
+	return s, nil
+}
+
+// Alive reports whether the shell process is still running.
+func (s *Shell) Alive() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Dir returns the shell's current directory as the shell itself reported it
+// after its last command. Inside a sandbox's PID namespace the process seen
+// from outside is the sandbox's init, not the shell, so its /proc entry says
+// nothing about where the shell has cd'd to.
+func (s *Shell) Dir() string {
+	s.dirMu.Lock()
+	defer s.dirMu.Unlock()
+	return s.dir
+}
+
+// Run sends command to the shell and waits for it to finish. If it runs
+// past timeout or ctx ends first, the shell is killed: a foreground
+// command cannot be interrupted without losing the shell anyway.
+func (s *Shell) Run(ctx context.Context, command string, timeout time.Duration) (Result, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if !s.Alive() {
+		return Result{}, ErrShellExited
+	}
+	if s.bash {
+		// An unclosed quote would leave the shell waiting for more input
+		// and swallow the end-of-command marker, so check syntax first.
+		if out, err := exec.Command("bash", "-n", "-c", command).CombinedOutput(); err != nil {
+			return Result{Output: string(out), ExitCode: 2}, nil
+		}
+	}
+
+	s.seq++
+	marker := fmt.Sprintf("%s_%d:", s.marker, s.seq)
+	start := s.out.Total()
+	// The group's stdin is /dev/null so a command that reads stdin cannot
+	// eat the lines that follow it. The marker line carries the exit status
+	// and then $PWD, which is how Dir stays current.
+	script := fmt.Sprintf("{ %s\n} </dev/null 2>&1\nprintf '\\n%s%%d %%s\\n' \"$?\" \"$PWD\"\n", command, marker)
+	if _, err := io.WriteString(s.stdin, script); err != nil {
+		return Result{}, ErrShellExited
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		data, _, dropped := s.out.Since(start)
+		if i := bytes.Index(data, []byte("\n"+marker)); i >= 0 {
+			rest := data[i+1+len(marker):]
+			if nl := bytes.IndexByte(rest, '\n'); nl >= 0 {
+				status, dir, _ := strings.Cut(string(rest[:nl]), " ")
+				code, _ := strconv.Atoi(status)
+				if dir != "" {
+					s.dirMu.Lock()
+					s.dir = dir
+					s.dirMu.Unlock()
+				}
+				return Result{Output: string(data[:i]), ExitCode: code, Truncated: dropped > 0}, nil
+			}
+		}
+		select {
+		case <-s.notify:
+		case <-s.done:
+			// Drain what the reader had not delivered yet, then report the
+			// exit.
+			time.Sleep(10 * time.Millisecond)
+			data, _, dropped := s.out.Since(start)
+			return Result{Output: string(data), ExitCode: s.cmd.ProcessState.ExitCode(), Truncated: dropped > 0, Exited: true}, nil
+		case <-timer.C:
+			return s.abort(start, true), nil
+		case <-ctx.Done():
+			return s.abort(start, errors.Is(ctx.Err(), context.DeadlineExceeded)), nil
+		}
+	}
+}
+
+// abort kills the shell mid-command and returns what it printed.
+func (s *Shell) abort(start int64, timedOut bool) Result {
+	s.Close()
+	data, _, dropped := s.out.Since(start)
+	return Result{Output: string(data), ExitCode: -1, Truncated: dropped > 0, TimedOut: timedOut, Exited: true}
+}
+
+// Close stops the shell and everything it started, and returns once it
+// has exited.
+func (s *Shell) Close() {
+	if !s.Alive() {
+		return
+	}
+	s.stdin.Close()
+	signalGroup(s.cmd, false)
+	select {
+	case <-s.done:
+	case <-time.After(killGrace):
+		signalGroup(s.cmd, true)
+		<-s.done
+	}
+}
@@ -0,0 +1,173 @@
+package testrun
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cargoAdapter runs cargo test and reads libtest's text output. The JSON
+// format is still nightly-only.
+type cargoAdapter struct{}
+
+// combined is true so compile errors on stderr line up with the test
+// output around them.
+func (cargoAdapter) combined() bool { return true }
+
+func (cargoAdapter) command(spec Spec, report string) []string {
+	argv := []string{"cargo", "test", "--no-fail-fast"}
+	// Arguments after "--" go to the test binaries, where filters live too.
+	var harness []string
+	for i, a := range spec.Args {
+		if a == "--" {
+			harness = append(harness, spec.Args[i+1:]...)
+			break
+		}
+		argv = append(argv, a)
+	}
+	if len(spec.Only) > 0 {
+		var names []string
+		for _, f := range spec.Only {
+			names = append(names, f.Name)
+		}
+		names = uniq(names)
+		if len(names) > 0 && len(names) == len(spec.Only) {
+			harness = append(append(harness, "--exact"), names...)
+		}
+	} else {
+		harness = append(harness, spec.Targets...)
+	}
+	if len(harness) > 0 {
+		argv = append(append(argv, "--"), harness...)
+	}
+	return argv
+}
+
+var (
+	cargoRunningRe = regexp.MustCompile(`^\s*(?:Running|Doc-tests)\s+(.+?)(?:\s+\(.*\))?$`)
+	cargoTestRe    = regexp.MustCompile(`^test (.+?) \.\.\. (ok|FAILED|ignored)`)
+	cargoSectionRe = regexp.MustCompile(`^---- (.+?) (?:stdout|stderr) ----$`)
+	cargoPanicRe   = regexp.MustCompile(`panicked at (?:'.*', )?([^\s:]+\.rs):(\d+)`)
+	cargoArrowRe   = regexp.MustCompile(`^\s*--> ([^\s:]+):(\d+)`)
+	cargoDocTestRe = regexp.MustCompile(`^(\S+\.rs) - .*\(line (\d+)\)`)
+)
+
+func (cargoAdapter) parse(r *Result, stdout, stderr []byte, report, dir string) error {
+	suite := ""
+	sawTests := false
+	failedSuite := map[string]string{} // test name → suite
+	var failedOrder []string
+	sections := map[string]*strings.Builder{}
+	var section *strings.Builder
+	var compile []string // compile error blocks
+	var block *strings.Builder
+
+	sc := bufio.NewScanner(bytes.NewReader(stdout))
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		line := strings.TrimRight(stripANSI(sc.Text()), "\r")
+
+		// Compile errors run from "error[...]:" or "error:" to a blank line.
+		if block != nil {
+			if strings.TrimSpace(line) == "" {
+				compile = append(compile, block.String())
+				block = nil
+			} else {
+				block.WriteString("\n" + line)
+			}
+			continue
+		}
+		if cargoErrorStart(line) {
+			section = nil
+			block = &strings.Builder{}
+			block.WriteString(line)
+			continue
+		}
+
+		if m := cargoRunningRe.FindStringSubmatch(line); m != nil {
+			suite = strings.TrimPrefix(m[1], "unittests ")
+			section = nil
+			continue
+		}
+		if m := cargoTestRe.FindStringSubmatch(line); m != nil {
+			sawTests = true
+			section = nil
+			switch m[2] {
+			case "ok":
+				r.Passed++
+			case "ignored":
+				r.Skipped++
+			case "FAILED":
+				r.Failed++
+				if _, dup := failedSuite[m[1]]; !dup {
+					failedOrder = append(failedOrder, m[1])
+				}
+				failedSuite[m[1]] = suite
+			}
+			continue
+		}
+		if m := cargoSectionRe.FindStringSubmatch(line); m != nil {
+			section = &strings.Builder{}
+			sections[m[1]] = section
+			continue
+		}
+		if section != nil {
+			if line == "failures:" || strings.HasPrefix(line, "test result:") {
+				section = nil
+				continue
+			}
+			section.WriteString(line + "\n")
+		}
+	}
+	if block != nil {
+		compile = append(compile, block.String())
+	}
+
+	for _, name := range failedOrder {
+		out := ""
+		if s := sections[name]; s != nil {
+			out = s.String()
+		}
+		f := Failure{Name: name, Suite: failedSuite[name], Output: trimFailure(out)}
+		if m := cargoPanicRe.FindStringSubmatch(out); m != nil {
+			f.File = relTo(dir, m[1])
+			f.Line, _ = strconv.Atoi(m[2])
+		} else if m := cargoDocTestRe.FindStringSubmatch(name); m != nil {
+			f.File = relTo(dir, m[1])
+			f.Line, _ = strconv.Atoi(m[2])
+		}
+		r.Failures = append(r.Failures, f)
+	}
+	for _, text := range compile {
+		f := Failure{Suite: "build", Output: trimFailure(text)}
+		for _, line := range strings.Split(text, "\n") {
+			if m := cargoArrowRe.FindStringSubmatch(line); m != nil {
+				f.File = relTo(dir, m[1])
+				f.Line, _ = strconv.Atoi(m[2])
+				break
+			}
+		}
+		r.Failures = append(r.Failures, f)
+	}
+	if !sawTests && len(r.Failures) == 0 && !bytes.Contains(stdout, []byte("running 0 tests")) {
+		return errors.New("cargo test reported no test results")
+	}
+	return nil
+}
+
+// cargoErrorStart reports whether line opens a compiler error, as opposed
+// to cargo's own closing summaries.
+func cargoErrorStart(line string) bool {
+	if !strings.HasPrefix(line, "error[") && !strings.HasPrefix(line, "error: ") {
+		return false
+	}
+	for _, summary := range []string{"error: test failed", "error: could not compile", "error: aborting"} {
+		if strings.HasPrefix(line, summary) {
+			return false
+		}
+	}
+	return !strings.Contains(line, " target failed") && !strings.Contains(line, " targets failed")
+}
@@ -0,0 +1,86 @@
+package testrun
+
+import "strings"
+
+// goValueFlags are go test flags whose value may be the next argument.
+var goValueFlags = map[string]bool{
+	"-run": true, "-skip": true, "-count": true, "-timeout": true, "-tags": true,
+	"-bench": true, "-benchtime": true, "-cpu": true, "-p": true, "-parallel": true,
+	"-covermode": true, "-coverpkg": true, "-coverprofile": true,
+	"-mod": true, "-ldflags": true, "-gcflags": true, "-exec": true, "-o": true,
+	"-shuffle": true,
+}
+
+// FromCommand recognizes a plain test command such as "go test ./..." or
+// "npx vitest run" and returns the Spec that runs it with a structured
+// report. Commands with shell syntax, environment assignments or runners
+// this package doesn't know return false and should run as written.
+func FromCommand(command string) (Spec, bool) {
+	if strings.ContainsAny(command, "|&;<>()$`\\\"'\n") {
+		return Spec{}, false
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return Spec{}, false
+	}
+	switch {
+	case len(fields) > 1 && fields[0] == "go" && fields[1] == "test":
+		spec := Spec{Framework: Go}
+		rest := fields[2:]
+		for i := 0; i < len(rest); i++ {
+			arg := rest[i]
+			if !strings.HasPrefix(arg, "-") {
+				spec.Targets = append(spec.Targets, arg)
+				continue
+			}
+			if arg == "-json" {
+				continue
+			}
+			spec.Args = append(spec.Args, arg)
+			if goValueFlags[arg] && i+1 < len(rest) {
+				i++
+				spec.Args = append(spec.Args, rest[i])
+			}
+		}
+		return spec, true
+	case fields[0] == "pytest":
+		return Spec{Framework: Pytest, Args: fields[1:]}, true
+	case (fields[0] == "python" || fields[0] == "python3") && len(fields) >= 3 && fields[1] == "-m" && fields[2] == "pytest":
+		return Spec{Framework: Pytest, Args: fields[3:]}, true
+	case len(fields) > 1 && fields[0] == "cargo" && fields[1] == "test":
+		var args []string
+		for _, a := range fields[2:] {
+			if a != "--no-fail-fast" {
+				args = append(args, a)
+			}
+		}
+		return Spec{Framework: Cargo, Args: args}, true
+	}
+
+	// jest and vitest, directly or through npx.
+	if fields[0] == "npx" {
+		fields = fields[1:]
+		if len(fields) > 0 && fields[0] == "--no" {
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return Spec{}, false
+	}
+	if fields[0] != Jest && fields[0] != Vitest {
+		return Spec{}, false
+	}
+	args := fields[1:]
+	if fields[0] == Vitest && len(args) > 0 && args[0] == "run" {
+		args = args[1:]
+	}
+	for _, a := range args {
+		// Watch mode never exits and vitest's other subcommands aren't
+		// test runs.
+		switch a {
+		case "--watch", "--watchAll", "watch", "dev", "bench":
+			return Spec{}, false
+		}
+	}
+	return Spec{Framework: fields[0], Args: args}, true
+}
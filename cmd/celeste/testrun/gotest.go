@@ -0,0 +1,255 @@
+package testrun
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// goAdapter runs go test -json and reads test2json events.
+type goAdapter struct{}
+
+func (goAdapter) combined() bool { return false }
+
+func (goAdapter) command(spec Spec, report string) []string {
+	argv := []string{"go", "test", "-json"}
+	argv = append(argv, spec.Args...)
+	targets := spec.Targets
+	if len(spec.Only) > 0 {
+		var pkgs, names []string
+		whole := false // a package failed outside any test, so run all of it
+		for _, f := range spec.Only {
+			pkgs = append(pkgs, f.Suite)
+			if f.Name == "" {
+				whole = true
+				continue
+			}
+			// Subtests rerun through their top-level test.
+			top, _, _ := strings.Cut(f.Name, "/")
+			names = append(names, top)
+		}
+		targets = uniq(pkgs)
+		if !whole && len(names) > 0 {
+			argv = append(argv, "-run", namesRegexp(uniq(names)))
+		}
+	}
+	if len(targets) == 0 {
+		targets = []string{"./..."}
+	}
+	return append(argv, targets...)
+}
+
+// goEvent is one line of go test -json output.
+type goEvent struct {
+	Action      string
+	Package     string
+	ImportPath  string // build-output and build-fail events
+	Test        string
+	Output      string
+	FailedBuild string
+}
+
+type goTest struct {
+	pkg, name string
+	output    strings.Builder
+}
+
+func (goAdapter) parse(r *Result, stdout, stderr []byte, report, dir string) error {
+	tests := map[[2]string]*goTest{}
+	pkgOutput := map[string]*strings.Builder{}
+	buildOutput := map[string]*strings.Builder{}
+	var failedTests []*goTest
+	var failedPkgs []string
+	pkgHasFailedTest := map[string]bool{}
+	events := 0
+
+	appendTo := func(m map[string]*strings.Builder, key, text string) {
+		b, ok := m[key]
+		if !ok {
+			b = &strings.Builder{}
+			m[key] = b
+		}
+		b.WriteString(text)
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(stdout))
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var ev goEvent
+		if json.Unmarshal(line, &ev) != nil {
+			continue
+		}
+		events++
+		switch ev.Action {
+		case "build-output":
+			appendTo(buildOutput, buildPackage(ev.ImportPath), ev.Output)
+			continue
+		case "build-fail":
+			continue
+		}
+		if ev.Test == "" {
+			switch ev.Action {
+			case "output":
+				appendTo(pkgOutput, ev.Package, ev.Output)
+			case "fail":
+				failedPkgs = append(failedPkgs, ev.Package)
+			}
+			continue
+		}
+		key := [2]string{ev.Package, ev.Test}
+		t, ok := tests[key]
+		if !ok {
+			t = &goTest{pkg: ev.Package, name: ev.Test}
+			tests[key] = t
+		}
+		switch ev.Action {
+		case "output":
+			t.output.WriteString(ev.Output)
+		case "pass":
+			r.Passed++
+		case "skip":
+			r.Skipped++
+		case "fail":
+			r.Failed++
+			failedTests = append(failedTests, t)
+			pkgHasFailedTest[ev.Package] = true
+		}
+	}
+	if events == 0 {
+		return errors.New("go test produced no JSON events")
+	}
+
+	modulePath, moduleDir := goModule(dir)
+	// A parent test fails when a subtest does; report only the subtest.
+	failedParent := map[[2]string]bool{}
+	for _, t := range failedTests {
+		if i := strings.LastIndex(t.name, "/"); i >= 0 {
+			failedParent[[2]string{t.pkg, t.name[:i]}] = true
+		}
+	}
+	for _, t := range failedTests {
+		if failedParent[[2]string{t.pkg, t.name}] {
+			continue
+		}
+		out := cleanGoOutput(t.output.String())
+		f := Failure{Name: t.name, Suite: t.pkg, Output: trimFailure(out)}
+		f.File, f.Line = goLocation(out, t.pkg, modulePath, moduleDir, dir)
+		r.Failures = append(r.Failures, f)
+	}
+
+	// Packages that failed without a failing test: build errors, panics in
+	// TestMain, timeouts.
+	for _, pkg := range uniq(failedPkgs) {
+		if pkgHasFailedTest[pkg] {
+			continue
+		}
+		var out string
+		if b := buildOutput[pkg]; b != nil {
+			out = b.String()
+		}
+		if b := pkgOutput[pkg]; b != nil {
+			out += cleanGoOutput(b.String())
+		}
+		if strings.TrimSpace(out) == "" {
+			out = string(stderr)
+		}
+		f := Failure{Suite: pkg, Output: trimFailure(out)}
+		f.File, f.Line = goLocation(out, pkg, modulePath, moduleDir, dir)
+		r.Failures = append(r.Failures, f)
+	}
+	return nil
+}
+
+// buildPackage strips the " [pkg.test]" suffix build events carry.
+func buildPackage(importPath string) string {
+	if i := strings.Index(importPath, " ["); i >= 0 {
+		return importPath[:i]
+	}
+	return importPath
+}
+
+// cleanGoOutput drops go test's progress lines.
+func cleanGoOutput(s string) string {
+	var keep []string
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "=== RUN"), strings.HasPrefix(trimmed, "=== PAUSE"),
+			strings.HasPrefix(trimmed, "=== CONT"), strings.HasPrefix(trimmed, "=== NAME"),
+			strings.HasPrefix(trimmed, "--- FAIL"), strings.HasPrefix(trimmed, "--- PASS"),
+			strings.HasPrefix(trimmed, "--- SKIP"), trimmed == "FAIL", trimmed == "PASS",
+			strings.HasPrefix(trimmed, "FAIL\t"), strings.HasPrefix(trimmed, "ok  \t"),
+			strings.HasPrefix(trimmed, "exit status "):
+			continue
+		}
+		keep = append(keep, line)
+	}
+	return strings.Join(keep, "\n")
+}
+
+var (
+	goTestFileRe = regexp.MustCompile(`([\w./\\-]*_test\.go):(\d+)`)
+	goFileRe     = regexp.MustCompile(`([\w./\\-]+\.go):(\d+)`)
+)
+
+// goLocation finds the first _test.go (or else .go) file:line in out and
+// resolves it to a workspace-relative path.
+func goLocation(out, pkg, modulePath, moduleDir, dir string) (string, int) {
+	m := goTestFileRe.FindStringSubmatch(out)
+	if m == nil {
+		for _, cand := range goFileRe.FindAllStringSubmatch(out, -1) {
+			// Skip frames in the standard library and module cache.
+			if !strings.Contains(cand[1], "/go/src/") && !strings.Contains(cand[1], "/pkg/mod/") {
+				m = cand
+				break
+			}
+		}
+	}
+	if m == nil {
+		return "", 0
+	}
+	file := m[1]
+	line, _ := strconv.Atoi(m[2])
+	if !filepath.IsAbs(file) && !strings.ContainsAny(file, `/\`) && modulePath != "" {
+		// t.Errorf prints the bare file name; it lives in the package's
+		// directory.
+		if pkg == modulePath {
+			file = filepath.Join(moduleDir, file)
+		} else if rest, ok := strings.CutPrefix(pkg, modulePath+"/"); ok {
+			file = filepath.Join(moduleDir, filepath.FromSlash(rest), file)
+		}
+	} else if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	return relTo(dir, file), line
+}
+
+// goModule returns the module path and directory for dir, looking upward
+// for go.mod.
+func goModule(dir string) (string, string) {
+	for d := dir; ; {
+		if data, err := os.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+					return strings.Trim(strings.TrimSpace(rest), `"`), d
+				}
+			}
+			return "", d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", dir
+		}
+		d = parent
+	}
+}
@@ -0,0 +1,150 @@
+package testrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jestAdapter runs jest or vitest with their JSON reporters, which share
+// jest's report shape.
+type jestAdapter struct {
+	vitest bool
+}
+
+func (jestAdapter) combined() bool { return false }
+
+func (a jestAdapter) command(spec Spec, report string) []string {
+	name := Jest
+	if a.vitest {
+		name = Vitest
+	}
+	argv := []string{"npx", "--no", name}
+	if bin := filepath.Join(spec.Dir, "node_modules", ".bin", name); fileExists(bin) {
+		argv = []string{bin}
+	}
+	if a.vitest {
+		argv = append(argv, "run", "--reporter=json", "--outputFile="+report)
+	} else {
+		argv = append(argv, "--json", "--outputFile="+report, "--testLocationInResults")
+	}
+	argv = append(argv, spec.Args...)
+	if len(spec.Only) > 0 {
+		var files, names []string
+		whole := false
+		for _, f := range spec.Only {
+			files = append(files, f.Suite)
+			if f.Name == "" {
+				whole = true
+			} else {
+				names = append(names, f.Name)
+			}
+		}
+		argv = append(argv, uniq(files)...)
+		if !whole && len(names) > 0 {
+			argv = append(argv, "-t", namesRegexp(uniq(names)))
+		}
+		return argv
+	}
+	return append(argv, spec.Targets...)
+}
+
+type jestReport struct {
+	NumPassedTests  int               `json:"numPassedTests"`
+	NumFailedTests  int               `json:"numFailedTests"`
+	NumPendingTests int               `json:"numPendingTests"`
+	NumTodoTests    int               `json:"numTodoTests"`
+	TestResults     []jestSuiteResult `json:"testResults"`
+}
+
+type jestSuiteResult struct {
+	Name             string          `json:"name"`
+	Status           string          `json:"status"`
+	Message          string          `json:"message"`
+	AssertionResults []jestAssertion `json:"assertionResults"`
+}
+
+type jestAssertion struct {
+	FullName        string        `json:"fullName"`
+	Title           string        `json:"title"`
+	Status          string        `json:"status"`
+	FailureMessages []string      `json:"failureMessages"`
+	Location        *jestLocation `json:"location"`
+}
+
+type jestLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (a jestAdapter) parse(r *Result, stdout, stderr []byte, report, dir string) error {
+	data, err := os.ReadFile(report)
+	if err != nil {
+		return fmt.Errorf("%s wrote no JSON report: %w", a.name(), err)
+	}
+	var rep jestReport
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return fmt.Errorf("parse %s report: %w", a.name(), err)
+	}
+	r.Passed = rep.NumPassedTests
+	r.Failed = rep.NumFailedTests
+	r.Skipped = rep.NumPendingTests + rep.NumTodoTests
+
+	for _, suite := range rep.TestResults {
+		file := relTo(dir, suite.Name)
+		failedAssertion := false
+		for _, as := range suite.AssertionResults {
+			if as.Status != "failed" {
+				continue
+			}
+			failedAssertion = true
+			name := as.FullName
+			if name == "" {
+				name = as.Title
+			}
+			out := stripANSI(strings.Join(as.FailureMessages, "\n"))
+			f := Failure{Name: name, Suite: file, File: file, Output: trimFailure(out)}
+			if as.Location != nil && as.Location.Line > 0 {
+				f.Line = as.Location.Line
+			} else {
+				f.Line = jsStackLine(out, suite.Name)
+			}
+			r.Failures = append(r.Failures, f)
+		}
+		// A suite that failed to load or compile has no assertions.
+		if suite.Status == "failed" && !failedAssertion {
+			out := stripANSI(suite.Message)
+			r.Failures = append(r.Failures, Failure{
+				Suite:  file,
+				File:   file,
+				Line:   jsStackLine(out, suite.Name),
+				Output: trimFailure(out),
+			})
+		}
+	}
+	return nil
+}
+
+func (a jestAdapter) name() string {
+	if a.vitest {
+		return Vitest
+	}
+	return Jest
+}
+
+// jsStackLine returns the line of the first stack frame in file.
+func jsStackLine(out, file string) int {
+	if file == "" {
+		return 0
+	}
+	re := regexp.MustCompile(regexp.QuoteMeta(file) + `:(\d+)(?::\d+)?`)
+	if m := re.FindStringSubmatch(out); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,197 @@
+package testrun
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pytestAdapter runs pytest with a JUnit XML report.
+type pytestAdapter struct{}
+
+func (pytestAdapter) combined() bool { return false }
+
+func (pytestAdapter) command(spec Spec, report string) []string {
+	argv := []string{"python3", "-m", "pytest"}
+	if _, err := exec.LookPath("pytest"); err == nil {
+		argv = []string{"pytest"}
+	}
+	// xunit1 keeps the file and line attributes on each testcase.
+	argv = append(argv, "-q", "-o", "junit_family=xunit1", "--junitxml="+report)
+	argv = append(argv, spec.Args...)
+	if len(spec.Only) > 0 {
+		for _, f := range spec.Only {
+			if id := pytestNodeID(f); id != "" {
+				argv = append(argv, id)
+			}
+		}
+		return argv
+	}
+	return append(argv, spec.Targets...)
+}
+
+// pytestNodeID rebuilds the node id pytest reports a failure under:
+// file::Class::name, or just the file for a collection error.
+func pytestNodeID(f Failure) string {
+	file := f.Suite
+	if file == "" {
+		file = f.File
+	}
+	if f.Name == "" {
+		return file
+	}
+	return file + "::" + f.Name
+}
+
+type junitSuites struct {
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      string        `xml:"line,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out"`
+	SystemErr string        `xml:"system-err"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (pytestAdapter) parse(r *Result, stdout, stderr []byte, report, dir string) error {
+	data, err := os.ReadFile(report)
+	if err != nil {
+		return fmt.Errorf("pytest wrote no JUnit report: %w", err)
+	}
+	var suites []junitSuite
+	var root junitSuites
+	if err := xml.Unmarshal(data, &root); err == nil && len(root.Suites) > 0 {
+		suites = root.Suites
+	} else {
+		var single junitSuite
+		if err := xml.Unmarshal(data, &single); err != nil {
+			return fmt.Errorf("parse JUnit report: %w", err)
+		}
+		suites = []junitSuite{single}
+	}
+	for len(suites) > 0 {
+		s := suites[0]
+		suites = append(suites[1:], s.Suites...)
+		for _, c := range s.Cases {
+			addJUnitCase(r, c, dir)
+		}
+	}
+	return nil
+}
+
+func addJUnitCase(r *Result, c junitCase, dir string) {
+	msg := c.Failure
+	if msg == nil {
+		msg = c.Error
+	}
+	switch {
+	case msg != nil:
+		r.Failed++
+	case c.Skipped != nil:
+		r.Skipped++
+		return
+	default:
+		r.Passed++
+		return
+	}
+
+	file := filepath.ToSlash(c.File)
+	var f Failure
+	if c.Classname == "" {
+		// A collection error: the dotted module is the name and there is
+		// no test.
+		if file == "" {
+			file = pytestFileFromClass(c.Name, dir)
+		}
+	} else {
+		if file == "" {
+			file = pytestFileFromClass(c.Classname, dir)
+		}
+		f.Name = c.Name
+		if class := pytestClass(c.Classname, file); class != "" {
+			f.Name = class + "::" + c.Name
+		}
+	}
+	f.Suite, f.File = file, file
+	out := msg.Text
+	if strings.TrimSpace(out) == "" {
+		out = msg.Message
+	}
+	if c.SystemErr != "" {
+		out = strings.TrimRight(out, "\n") + "\n--- stderr ---\n" + c.SystemErr
+	}
+	f.Output = trimFailure(out)
+	if loc, line := pytestLocation(out, file); loc != "" {
+		f.File, f.Line = relTo(dir, loc), line
+	} else if n, err := strconv.Atoi(c.Line); err == nil {
+		// The attribute is zero-based.
+		f.Line = n + 1
+	}
+	r.Failures = append(r.Failures, f)
+}
+
+// pytestClass returns the class part of a dotted classname such as
+// "tests.test_math.TestAdd", given the test file "tests/test_math.py".
+func pytestClass(classname, file string) string {
+	module := strings.ReplaceAll(strings.TrimSuffix(file, ".py"), "/", ".")
+	if rest, ok := strings.CutPrefix(classname, module+"."); ok {
+		return strings.ReplaceAll(rest, ".", "::")
+	}
+	return ""
+}
+
+// pytestFileFromClass guesses the test file from a dotted classname when
+// the report has no file attribute.
+func pytestFileFromClass(classname, dir string) string {
+	parts := strings.Split(classname, ".")
+	for i := len(parts); i > 0; i-- {
+		cand := strings.Join(parts[:i], "/") + ".py"
+		if fileExists(filepath.Join(dir, cand)) {
+			return cand
+		}
+	}
+	return ""
+}
+
+var pyLocRe = regexp.MustCompile(`(?m)^([^\s:][^:\n]*\.py):(\d+):`)
+
+// pytestLocation returns the last "file.py:NN:" in a traceback, preferring
+// frames in the failing test's file.
+func pytestLocation(out, file string) (string, int) {
+	var best, last []string
+	for _, m := range pyLocRe.FindAllStringSubmatch(out, -1) {
+		last = m
+		if p := filepath.ToSlash(m[1]); file != "" && (p == file || strings.HasSuffix(p, "/"+file)) {
+			best = m
+		}
+	}
+	if best == nil {
+		best = last
+	}
+	if best == nil {
+		return "", 0
+	}
+	line, _ := strconv.Atoi(best[2])
+	return best[1], line
+}
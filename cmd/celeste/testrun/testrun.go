@@ -0,0 +1,410 @@
+// Package testrun runs a project's tests and parses the results into pass,
+// fail and skip counts plus the failing tests with their file, line and a
+// trimmed slice of their output. Raw `go test ./...` output is long enough
+// that the failure the model needs is often cut off; a Result keeps just
+// the failures.
+//
+// Adapters cover go test -json, pytest (JUnit XML), jest and vitest (JSON
+// reporters) and cargo test (text output).
+package testrun
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+// Frameworks run_tests knows.
+const (
+	Go     = "go"
+	Pytest = "pytest"
+	Jest   = "jest"
+	Vitest = "vitest"
+	Cargo  = "cargo"
+)
+
+// Frameworks lists the supported framework names.
+var Frameworks = []string{Go, Pytest, Jest, Vitest, Cargo}
+
+// Result statuses.
+const (
+	StatusPassed = "passed"
+	StatusFailed = "failed"
+	// StatusError means the run failed without reporting a failing test,
+	// e.g. the runner isn't installed or the command line was wrong.
+	StatusError = "error"
+)
+
+const (
+	// MaxFailures caps the failures kept in a Result.
+	MaxFailures = 25
+	// maxFailureLines and maxFailureBytes trim each failure's output.
+	maxFailureLines = 40
+	maxFailureBytes = 3000
+	// maxOutputBytes trims the raw output kept when a run errors.
+	maxOutputBytes = 6000
+	// maxCapture caps how much of a run's output is read.
+	maxCapture = 64 << 20
+)
+
+// Failure is one failing test, or a package or file that failed without
+// a failing test, such as a build error (Name is then empty).
+type Failure struct {
+	Name   string `json:"name,omitempty"`
+	Suite  string `json:"suite,omitempty"` // Go package, test file or cargo target
+	File   string `json:"file,omitempty"`  // relative to the workspace when inside it
+	Line   int    `json:"line,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// Location returns "file:line", or "" when the file is unknown.
+func (f Failure) Location() string {
+	if f.File == "" {
+		return ""
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+// Title names the failure for summaries.
+func (f Failure) Title() string {
+	switch {
+	case f.Name != "" && f.Suite != "":
+		return f.Suite + " " + f.Name
+	case f.Name != "":
+		return f.Name
+	case f.Suite != "":
+		return f.Suite + " (no test reported)"
+	}
+	return "(unknown)"
+}
+
+// Result is a parsed test run.
+type Result struct {
+	Framework string    `json:"framework"`
+	Command   string    `json:"command"`
+	Status    string    `json:"status"`
+	Passed    int       `json:"passed"`
+	Failed    int       `json:"failed"`
+	Skipped   int       `json:"skipped"`
+	Failures  []Failure `json:"failures,omitempty"`
+	// Omitted counts failures beyond MaxFailures.
+	Omitted  int     `json:"failures_omitted,omitempty"`
+	ExitCode int     `json:"exit_code"`
+	TimedOut bool    `json:"timed_out,omitempty"`
+	Seconds  float64 `json:"seconds"`
+	// Output is the tail of the raw output, kept only when the run errored.
+	Output string `json:"output,omitempty"`
+}
+
+// OK reports whether every test passed.
+func (r *Result) OK() bool {
+	return r.Status == StatusPassed
+}
+
+// Summary renders the result as text for prompts and logs.
+func (r *Result) Summary() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s — %d passed, %d failed, %d skipped", r.Framework, r.Status, r.Passed, r.Failed, r.Skipped)
+	if r.TimedOut {
+		sb.WriteString(" (timed out)")
+	}
+	for _, f := range r.Failures {
+		sb.WriteString("\n\nFAIL ")
+		sb.WriteString(f.Title())
+		if loc := f.Location(); loc != "" {
+			sb.WriteString(" at " + loc)
+		}
+		if f.Output != "" {
+			sb.WriteString("\n" + indent(f.Output))
+		}
+	}
+	if r.Omitted > 0 {
+		fmt.Fprintf(&sb, "\n\n…and %d more failures", r.Omitted)
+	}
+	if r.Output != "" {
+		sb.WriteString("\n\n" + r.Output)
+	}
+	return sb.String()
+}
+
+// Spec describes a run.
+type Spec struct {
+	Framework string   // one of Frameworks; "" detects it from Dir
+	Dir       string   // workspace
+	Args      []string // extra runner arguments, e.g. -race or -k expr
+	Targets   []string // packages, test files or cargo test filters
+	// Only reruns just these failures from an earlier Result.
+	Only    []Failure
+	Timeout time.Duration // 0 means none
+	Policy  sandbox.Policy
+}
+
+// adapter runs and parses one framework.
+type adapter interface {
+	// command returns the argv for spec, writing any report to report.
+	command(spec Spec, report string) []string
+	// combined reports whether stdout and stderr are parsed together.
+	combined() bool
+	// parse fills r's counts and failures from the run's output.
+	parse(r *Result, stdout, stderr []byte, report, dir string) error
+}
+
+var adapters = map[string]adapter{
+	Go:     goAdapter{},
+	Pytest: pytestAdapter{},
+	Jest:   jestAdapter{vitest: false},
+	Vitest: jestAdapter{vitest: true},
+	Cargo:  cargoAdapter{},
+}
+
+// Detect guesses the framework for a workspace from its manifest files.
+func Detect(dir string) (string, error) {
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		return Go, nil
+	}
+	if fileExists(filepath.Join(dir, "Cargo.toml")) {
+		return Cargo, nil
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		switch {
+		case bytes.Contains(data, []byte(`"vitest"`)):
+			return Vitest, nil
+		case bytes.Contains(data, []byte(`"jest"`)):
+			return Jest, nil
+		}
+	}
+	for _, name := range []string{"pytest.ini", "conftest.py", "tox.ini"} {
+		if fileExists(filepath.Join(dir, name)) {
+			return Pytest, nil
+		}
+	}
+	for _, name := range []string{"pyproject.toml", "setup.cfg"} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil && bytes.Contains(data, []byte("pytest")) {
+			return Pytest, nil
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "tests", "test_*.py")); len(matches) > 0 {
+		return Pytest, nil
+	}
+	return "", fmt.Errorf("can't tell which test framework %s uses; pass framework (%s)", dir, strings.Join(Frameworks, ", "))
+}
+
+// Run runs the tests spec describes and parses the results. An error
+// means the run could not be started or its results not read at all.
+func Run(ctx context.Context, spec Spec) (*Result, error) {
+	if spec.Framework == "" {
+		fw, err := Detect(spec.Dir)
+		if err != nil {
+			return nil, err
+		}
+		spec.Framework = fw
+	}
+	a, ok := adapters[spec.Framework]
+	if !ok {
+		return nil, fmt.Errorf("unknown test framework %q (want %s)", spec.Framework, strings.Join(Frameworks, ", "))
+	}
+
+	tmp, err := os.MkdirTemp("", "celeste-tests-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	report := filepath.Join(tmp, "report")
+	argv := a.command(spec, report)
+
+	runCtx := ctx
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(runCtx, argv[0], argv[1:]...)
+	cmd.Dir = spec.Dir
+	cmd.WaitDelay = 5 * time.Second
+	cleanup, err := spec.Policy.Wrap(cmd)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	stdout := &cappedBuffer{max: maxCapture}
+	stderr := stdout
+	if !a.combined() {
+		stderr = &cappedBuffer{max: maxCapture}
+	}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	start := time.Now()
+	runErr := cmd.Run()
+	r := &Result{
+		Framework: spec.Framework,
+		Command:   shellQuote(argv),
+		Seconds:   time.Since(start).Round(10 * time.Millisecond).Seconds(),
+		TimedOut:  runCtx.Err() == context.DeadlineExceeded,
+	}
+	if cmd.ProcessState != nil {
+		r.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) && !r.TimedOut {
+		if errors.Is(runErr, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is not installed or not on PATH", argv[0])
+		}
+		return nil, runErr
+	}
+
+	parseErr := a.parse(r, stdout.Bytes(), stderr.Bytes(), report, spec.Dir)
+	finish(r, parseErr, stdout.Bytes(), stderr.Bytes(), a.combined())
+	return r, nil
+}
+
+// finish sets the status, sorts and caps the failures, and keeps raw
+// output when nothing explains a failed run.
+func finish(r *Result, parseErr error, stdout, stderr []byte, combined bool) {
+	sort.SliceStable(r.Failures, func(i, j int) bool {
+		a, b := r.Failures[i], r.Failures[j]
+		if a.Suite != b.Suite {
+			return a.Suite < b.Suite
+		}
+		return a.Name < b.Name
+	})
+	if n := len(r.Failures); n > MaxFailures {
+		r.Omitted = n - MaxFailures
+		r.Failures = r.Failures[:MaxFailures]
+	}
+	switch {
+	case r.Failed > 0 || len(r.Failures) > 0:
+		r.Status = StatusFailed
+	case r.ExitCode == 0 && !r.TimedOut && parseErr == nil:
+		r.Status = StatusPassed
+	default:
+		r.Status = StatusError
+		raw := string(stdout)
+		if !combined && len(stderr) > 0 {
+			raw = strings.TrimRight(raw, "\n") + "\n" + string(stderr)
+		}
+		r.Output = tail(strings.TrimSpace(raw), maxOutputBytes)
+		if parseErr != nil && r.Output == "" {
+			r.Output = parseErr.Error()
+		}
+	}
+}
+
+// trimFailure keeps the start and end of a failure's output, where the
+// assertion and the final error usually are.
+func trimFailure(s string) string {
+	s = strings.TrimSpace(stripANSI(s))
+	lines := strings.Split(s, "\n")
+	if len(lines) > maxFailureLines {
+		head, rest := lines[:maxFailureLines/4], lines[len(lines)-(maxFailureLines-maxFailureLines/4):]
+		omitted := len(lines) - len(head) - len(rest)
+		lines = append(append(head[:len(head):len(head)], fmt.Sprintf("… %d lines omitted …", omitted)), rest...)
+		s = strings.Join(lines, "\n")
+	}
+	if len(s) > maxFailureBytes {
+		s = s[:maxFailureBytes/3] + "\n…\n" + s[len(s)-(maxFailureBytes-maxFailureBytes/3):]
+	}
+	return s
+}
+
+// tail returns the last max bytes of s.
+func tail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[len(s)-max:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < 200 {
+		s = s[i+1:]
+	}
+	return "…\n" + s
+}
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+func stripANSI(s string) string {
+	return ansiRe.ReplaceAllString(s, "")
+}
+
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+// relTo returns path relative to dir when it lies inside it.
+func relTo(dir, path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// namesRegexp matches exactly the given names.
+func namesRegexp(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = regexp.QuoteMeta(n)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// uniq returns the distinct non-empty values, in first-seen order.
+func uniq(values []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func shellQuote(argv []string) string {
+	parts := make([]string, len(argv))
+	for i, a := range argv {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$`|&;<>()*?[]{}!#~") {
+			parts[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		} else {
+			parts[i] = a
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// cappedBuffer keeps the first max bytes written and discards the rest.
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
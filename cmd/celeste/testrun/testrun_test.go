@@ -0,0 +1,322 @@
+package testrun
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestRunGo(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not on PATH")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":       "module example.com/calc\n\ngo 1.21\n",
+		"calc/calc.go": "package calc\n\nfunc Add(a, b int) int { return a - b }\n",
+		"calc/calc_test.go": `package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if got := Add(2, 2); got != 4 {
+		t.Errorf("Add(2, 2) = %d, want 4", got)
+	}
+}
+
+func TestTable(t *testing.T) {
+	t.Run("zero", func(t *testing.T) {
+		if Add(0, 0) != 0 {
+			t.Fatal("zero")
+		}
+	})
+	t.Run("one", func(t *testing.T) {
+		if Add(1, 1) != 2 {
+			t.Fatal("one")
+		}
+	})
+}
+
+func TestSkipped(t *testing.T) { t.Skip("later") }
+`,
+		"broken/broken.go":      "package broken\n\nfunc F() int { return undefinedName }\n",
+		"broken/broken_test.go": "package broken\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) { F() }\n",
+	})
+	t.Setenv("GOTOOLCHAIN", "local")
+
+	res, err := Run(context.Background(), Spec{Dir: dir, Timeout: 2 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, Go, res.Framework)
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.Equal(t, 1, res.Passed)
+	assert.Equal(t, 1, res.Skipped)
+	require.Len(t, res.Failures, 3, res.Summary())
+
+	build := res.Failures[0]
+	assert.Equal(t, "example.com/calc/broken", build.Suite)
+	assert.Empty(t, build.Name)
+	assert.Equal(t, "broken/broken.go", build.File)
+	assert.Equal(t, 3, build.Line)
+	assert.Contains(t, build.Output, "undefined: undefinedName")
+
+	add := res.Failures[1]
+	assert.Equal(t, "TestAdd", add.Name)
+	assert.Equal(t, "calc/calc_test.go:7", add.Location())
+	assert.Equal(t, "calc_test.go:7: Add(2, 2) = 0, want 4", add.Output)
+
+	one := res.Failures[2]
+	assert.Equal(t, "TestTable/one", one.Name, "the failing parent is left out")
+	assert.Equal(t, "calc/calc_test.go:19", one.Location())
+
+	// Rerunning just the test failures skips the broken package.
+	rerun, err := Run(context.Background(), Spec{Dir: dir, Only: res.Failures[1:], Timeout: 2 * time.Minute})
+	require.NoError(t, err)
+	assert.Contains(t, rerun.Command, "-run '^(TestAdd|TestTable)$' example.com/calc/calc")
+	assert.Equal(t, 0, rerun.Skipped)
+	assert.Len(t, rerun.Failures, 2)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "calc/calc.go"), []byte("package calc\n\nfunc Add(a, b int) int { return a + b }\n"), 0644))
+	rerun, err = Run(context.Background(), Spec{Dir: dir, Only: res.Failures[1:], Timeout: 2 * time.Minute})
+	require.NoError(t, err)
+	assert.True(t, rerun.OK(), rerun.Summary())
+	assert.Equal(t, 4, rerun.Passed)
+}
+
+func TestParsePytest(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.xml")
+	writeFiles(t, dir, map[string]string{"report.xml": `<?xml version="1.0" encoding="utf-8"?>
+<testsuites><testsuite name="pytest" errors="1" failures="2" skipped="1" tests="6">
+<testcase classname="tests.test_math" name="test_add" file="tests/test_math.py" line="3" time="0.001"/>
+<testcase classname="tests.test_math.TestDivide" name="test_by_zero" file="tests/test_math.py" line="10" time="0.001">
+<failure message="ZeroDivisionError: division by zero">self = &lt;tests.test_math.TestDivide object&gt;
+
+    def test_by_zero(self):
+&gt;       assert divide(1, 0) == 0
+
+tests/test_math.py:12:
+_ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _ _
+
+    def divide(a, b):
+&gt;       return a / b
+E       ZeroDivisionError: division by zero
+
+calc.py:2: ZeroDivisionError</failure></testcase>
+<testcase classname="tests.test_math" name="test_sub" file="tests/test_math.py" line="5" time="0.001">
+<failure message="assert 1 == 2">def test_sub():
+&gt;       assert 3 - 2 == 2
+E       assert 1 == 2
+
+tests/test_math.py:7: AssertionError</failure></testcase>
+<testcase classname="tests.test_math" name="test_later" file="tests/test_math.py" line="14"><skipped message="later"/></testcase>
+<testcase classname="" name="tests.test_broken" time="0.000"><error message="collection failure">tests/test_broken.py:1: in &lt;module&gt;
+    import missing
+E   ModuleNotFoundError: No module named 'missing'</error></testcase>
+</testsuite></testsuites>`})
+	writeFiles(t, dir, map[string]string{"tests/test_broken.py": "import missing\n"})
+
+	var r Result
+	require.NoError(t, pytestAdapter{}.parse(&r, nil, nil, report, dir))
+	finish(&r, nil, nil, nil, false)
+	assert.Equal(t, 1, r.Passed)
+	assert.Equal(t, 3, r.Failed)
+	assert.Equal(t, 1, r.Skipped)
+	require.Len(t, r.Failures, 3)
+
+	assert.Equal(t, Failure{Suite: "tests/test_broken.py", File: "tests/test_broken.py", Line: 1,
+		Output: "tests/test_broken.py:1: in <module>\n    import missing\nE   ModuleNotFoundError: No module named 'missing'"}, r.Failures[0])
+	assert.Equal(t, "TestDivide::test_by_zero", r.Failures[1].Name)
+	assert.Equal(t, "tests/test_math.py:12", r.Failures[1].Location(), "the frame in the test file wins")
+	assert.Equal(t, "test_sub", r.Failures[2].Name)
+	assert.Equal(t, "tests/test_math.py:7", r.Failures[2].Location())
+
+	argv := pytestAdapter{}.command(Spec{Only: r.Failures}, "/tmp/r.xml")
+	assert.Equal(t, []string{"tests/test_broken.py", "tests/test_math.py::TestDivide::test_by_zero", "tests/test_math.py::test_sub"}, argv[len(argv)-3:])
+}
+
+func TestParseJest(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.json")
+	suite := filepath.Join(dir, "src", "sum.test.js")
+	broken := filepath.Join(dir, "src", "broken.test.ts")
+	writeFiles(t, dir, map[string]string{"report.json": `{
+  "numPassedTests": 1, "numFailedTests": 1, "numPendingTests": 1, "numTodoTests": 0,
+  "testResults": [
+    {"name": "` + suite + `", "status": "failed", "message": "",
+     "assertionResults": [
+       {"fullName": "sum adds", "title": "adds", "status": "passed", "failureMessages": []},
+       {"fullName": "sum handles negatives", "title": "handles negatives", "status": "failed",
+        "failureMessages": ["Error: \u001b[2mexpect(\u001b[22mreceived).toBe(expected)\n\nExpected: -1\nReceived: 1\n    at Object.<anonymous> (` + suite + `:9:22)"],
+        "location": null},
+       {"fullName": "sum later", "title": "later", "status": "pending", "failureMessages": []}
+     ]},
+    {"name": "` + broken + `", "status": "failed", "assertionResults": [],
+     "message": "SyntaxError: Unexpected token (3:7)\n    at ` + broken + `:3:7"}
+  ]
+}`})
+
+	var r Result
+	require.NoError(t, jestAdapter{}.parse(&r, nil, nil, report, dir))
+	finish(&r, nil, nil, nil, false)
+	assert.Equal(t, StatusFailed, r.Status)
+	assert.Equal(t, 1, r.Passed)
+	assert.Equal(t, 1, r.Skipped)
+	require.Len(t, r.Failures, 2)
+	assert.Equal(t, "src/broken.test.ts:3", r.Failures[0].Location())
+	assert.Empty(t, r.Failures[0].Name)
+	neg := r.Failures[1]
+	assert.Equal(t, "sum handles negatives", neg.Name)
+	assert.Equal(t, "src/sum.test.js:9", neg.Location())
+	assert.NotContains(t, neg.Output, "\x1b[", "colour codes are stripped")
+
+	argv := jestAdapter{vitest: true}.command(Spec{Dir: dir, Only: r.Failures[1:]}, "/tmp/r.json")
+	assert.Equal(t, []string{"npx", "--no", "vitest", "run", "--reporter=json", "--outputFile=/tmp/r.json", "src/sum.test.js", "-t", `^(sum handles negatives)$`}, argv)
+}
+
+func TestParseCargo(t *testing.T) {
+	out := `   Compiling calc v0.1.0 (/work/calc)
+    Finished ` + "`test`" + ` profile [unoptimized + debuginfo] target(s) in 0.41s
+     Running unittests src/lib.rs (target/debug/deps/calc-1234)
+
+running 3 tests
+test tests::adds ... ok
+test tests::subtracts ... FAILED
+test tests::later ... ignored
+
+failures:
+
+---- tests::subtracts stdout ----
+
+thread 'tests::subtracts' panicked at src/lib.rs:14:9:
+assertion ` + "`left == right`" + ` failed
+  left: 1
+ right: 2
+note: run with ` + "`RUST_BACKTRACE=1`" + ` environment variable to display a backtrace
+
+
+failures:
+    tests::subtracts
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out; finished in 0.00s
+
+error: test failed, to rerun pass ` + "`--lib`" + `
+   Compiling calc v0.1.0 (/work/calc)
+error[E0425]: cannot find value ` + "`y`" + ` in this scope
+ --> tests/it.rs:4:13
+  |
+4 |     assert!(y);
+  |             ^ not found in this scope
+
+error: could not compile ` + "`calc`" + ` (test "it") due to 1 previous error
+`
+	var r Result
+	require.NoError(t, cargoAdapter{}.parse(&r, []byte(out), nil, "", "/work/calc"))
+	finish(&r, nil, nil, nil, true)
+	assert.Equal(t, 1, r.Passed)
+	assert.Equal(t, 1, r.Failed)
+	assert.Equal(t, 1, r.Skipped)
+	require.Len(t, r.Failures, 2)
+	assert.Equal(t, "build", r.Failures[0].Suite)
+	assert.Equal(t, "tests/it.rs:4", r.Failures[0].Location())
+	assert.Contains(t, r.Failures[0].Output, "cannot find value `y`")
+	sub := r.Failures[1]
+	assert.Equal(t, "tests::subtracts", sub.Name)
+	assert.Equal(t, "src/lib.rs", sub.Suite)
+	assert.Equal(t, "src/lib.rs:14", sub.Location())
+	assert.True(t, strings.HasPrefix(sub.Output, "thread 'tests::subtracts' panicked"), sub.Output)
+
+	argv := cargoAdapter{}.command(Spec{Args: []string{"--release", "--", "--nocapture"}, Only: r.Failures[1:]}, "")
+	assert.Equal(t, []string{"cargo", "test", "--no-fail-fast", "--release", "--", "--nocapture", "--exact", "tests::subtracts"}, argv)
+}
+
+func TestFinishKeepsOutputOnError(t *testing.T) {
+	r := Result{ExitCode: 2}
+	finish(&r, nil, []byte("usage: pytest [options]\n"), []byte("error: unrecognized arguments: --bogus\n"), false)
+	assert.Equal(t, StatusError, r.Status)
+	assert.False(t, r.OK())
+	assert.Equal(t, "usage: pytest [options]\nerror: unrecognized arguments: --bogus", r.Output)
+
+	r = Result{}
+	for i := 0; i < MaxFailures+3; i++ {
+		r.Failures = append(r.Failures, Failure{Name: "t"})
+	}
+	finish(&r, nil, nil, nil, false)
+	assert.Len(t, r.Failures, MaxFailures)
+	assert.Equal(t, 3, r.Omitted)
+}
+
+func TestTrimFailure(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, "line")
+	}
+	out := trimFailure(strings.Join(lines, "\n"))
+	assert.Len(t, strings.Split(out, "\n"), maxFailureLines+1)
+	assert.Contains(t, out, "… 60 lines omitted …")
+}
+
+func TestFromCommand(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		want    Spec
+		ok      bool
+	}{
+		{"go test ./...", Spec{Framework: Go, Targets: []string{"./..."}}, true},
+		{"go test -race -run TestX -count=1 ./pkg/...", Spec{Framework: Go, Args: []string{"-race", "-run", "TestX", "-count=1"}, Targets: []string{"./pkg/..."}}, true},
+		{"python -m pytest -x tests", Spec{Framework: Pytest, Args: []string{"-x", "tests"}}, true},
+		{"pytest", Spec{Framework: Pytest, Args: []string{}}, true},
+		{"cargo test --workspace", Spec{Framework: Cargo, Args: []string{"--workspace"}}, true},
+		{"npx vitest run src", Spec{Framework: Vitest, Args: []string{"src"}}, true},
+		{"npx jest --ci", Spec{Framework: Jest, Args: []string{"--ci"}}, true},
+		{"npx vitest", Spec{Framework: Vitest, Args: []string{}}, true},
+		{"npx jest --watch", Spec{}, false},
+		{"go test ./... | tee out.txt", Spec{}, false},
+		{"CGO_ENABLED=0 go test ./...", Spec{}, false},
+		{"make test", Spec{}, false},
+		{"go vet ./...", Spec{}, false},
+	} {
+		got, ok := FromCommand(tc.command)
+		assert.Equal(t, tc.ok, ok, tc.command)
+		assert.Equal(t, tc.want, got, tc.command)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for files, want := range map[string]string{
+		"go.mod":         Go,
+		"Cargo.toml":     Cargo,
+		"conftest.py":    Pytest,
+		"pyproject.toml": Pytest,
+	} {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{files: "[tool.pytest.ini_options]\n"})
+		got, err := Detect(dir)
+		require.NoError(t, err, files)
+		assert.Equal(t, want, got, files)
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"package.json": `{"devDependencies": {"vitest": "^2.0.0"}}`})
+	got, err := Detect(dir)
+	require.NoError(t, err)
+	assert.Equal(t, Vitest, got)
+
+	_, err = Detect(t.TempDir())
+	assert.ErrorContains(t, err, "pass framework")
+}
@@ -0,0 +1,567 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// ApplyPatchTool applies a change spanning several files as one unit: every
+// hunk is matched against the current contents before anything is written,
+// and a failed write rolls back the files already written. A refactor that
+// stops halfway is worse than one that never started.
+type ApplyPatchTool struct {
+	BaseTool
+	editDiagnostics
+	workspace string
+	tracker   *checkpoints.FileTracker
+	snapMgr   *checkpoints.SnapshotManager
+}
+
+// NewApplyPatchTool creates an ApplyPatchTool bound to the given workspace.
+func NewApplyPatchTool(workspace string, opts ...ApplyPatchOption) *ApplyPatchTool {
+	t := &ApplyPatchTool{
+		BaseTool: BaseTool{
+			ToolName: "apply_patch",
+			ToolDescription: "Apply a change to several workspace files at once, all or nothing. Pass either a unified diff " +
+				"(patch, as from git diff; creates, deletes and renames supported) or a list of edits. Every hunk is checked " +
+				"against the current files first, tolerating shifted line numbers, whitespace differences and a little stale " +
+				"context; if any hunk fails nothing is written and the result says which. Prefer this over a run of " +
+				"patch_file calls for refactors that touch more than one file.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"patch": {
+						"type": "string",
+						"description": "Unified diff with ---/+++ headers per file and @@ hunks. Paths are relative to the workspace; a/ and b/ prefixes are stripped."
+					},
+					"edits": {
+						"type": "array",
+						"description": "Alternative to patch. Applied in order; several may target the same file.",
+						"items": {
+							"type": "object",
+							"properties": {
+								"path": {"type": "string", "description": "Relative file path inside workspace."},
+								"old_string": {"type": "string", "description": "Text to replace. Must be unique in the file unless replace_all is set."},
+								"new_string": {"type": "string", "description": "Replacement text."},
+								"replace_all": {"type": "boolean", "description": "Replace every occurrence of old_string."},
+								"content": {"type": "string", "description": "Whole new content, to create or overwrite the file. Use instead of old_string/new_string."},
+								"delete": {"type": "boolean", "description": "Delete the file."}
+							},
+							"required": ["path"]
+						}
+					},
+					"dry_run": {
+						"type": "boolean",
+						"description": "Check that everything applies and report, without writing."
+					}
+				},
+				"required": []
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			Interrupt:       tools.InterruptBlock,
+		},
+		workspace: workspace,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// ApplyPatchOption configures optional dependencies for ApplyPatchTool.
+type ApplyPatchOption func(*ApplyPatchTool)
+
+// WithApplyPatchTracker attaches a FileTracker for stale detection.
+func WithApplyPatchTracker(ft *checkpoints.FileTracker) ApplyPatchOption {
+	return func(t *ApplyPatchTool) { t.tracker = ft }
+}
+
+// WithApplyPatchSnapshots attaches a SnapshotManager for file checkpointing.
+func WithApplyPatchSnapshots(sm *checkpoints.SnapshotManager) ApplyPatchOption {
+	return func(t *ApplyPatchTool) { t.snapMgr = sm }
+}
+
+// hunkReport is the outcome of one hunk or edit.
+type hunkReport struct {
+	Hunk   int    `json:"hunk"`
+	Status string `json:"status"`           // "applied", "failed" or "skipped"
+	Line   int    `json:"line,omitempty"`   // 1-based line it applied at
+	Offset int    `json:"offset,omitempty"` // lines away from where the header said
+	Match  string `json:"match,omitempty"`  // how loosely the context matched
+	Error  string `json:"error,omitempty"`
+}
+
+// fileReport is the outcome for one file.
+type fileReport struct {
+	Path    string       `json:"path"`
+	From    string       `json:"from,omitempty"` // rename source
+	Op      string       `json:"op"`             // "modify", "create", "delete" or "rename"
+	Added   int          `json:"added"`
+	Removed int          `json:"removed"`
+	Error   string       `json:"error,omitempty"`
+	Hunks   []hunkReport `json:"hunks,omitempty"`
+}
+
+// patchFile is one file's state while a patch is staged in memory.
+type patchFile struct {
+	rel     string
+	abs     string
+	orig    string
+	existed bool
+	mode    os.FileMode
+	content string
+	exists  bool
+}
+
+func (f *patchFile) changed() bool {
+	return f.exists != f.existed || f.content != f.orig
+}
+
+// patchStage holds every touched file, in first-touched order, so the
+// whole change can be checked before any of it is written.
+type patchStage struct {
+	workspace string
+	tracker   *checkpoints.FileTracker
+	files     map[string]*patchFile
+	order     []*patchFile
+}
+
+// changedPaths returns the absolute paths of files the stage changes,
+// including deletions.
+func (s *patchStage) changedPaths() []string {
+	var paths []string
+	for _, f := range s.order {
+		if f.changed() {
+			paths = append(paths, f.abs)
+		}
+	}
+	return paths
+}
+
+// file loads path into the stage on first use.
+func (s *patchStage) file(rel string) (*patchFile, error) {
+	abs, err := resolvePath(s.workspace, rel)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := s.files[abs]; ok {
+		return f, nil
+	}
+	if s.tracker != nil {
+		if err := s.tracker.CheckStale(abs); err != nil {
+			return nil, err
+		}
+	}
+	f := &patchFile{rel: rel, abs: abs, mode: 0644}
+	info, err := os.Stat(abs)
+	switch {
+	case err == nil && info.IsDir():
+		return nil, fmt.Errorf("%s is a directory", rel)
+	case err == nil:
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, err
+		}
+		f.orig, f.content = string(data), string(data)
+		f.existed, f.exists = true, true
+		f.mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	s.files[abs] = f
+	s.order = append(s.order, f)
+	return f, nil
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	patch := getStringArg(input, "patch", "")
+	rawEdits, hasEdits := input["edits"]
+	dryRun := getBoolArg(input, "dry_run", false)
+	if (patch == "") == !hasEdits {
+		return errResult("pass exactly one of patch or edits"), nil
+	}
+
+	stage := &patchStage{workspace: t.workspace, tracker: t.tracker, files: map[string]*patchFile{}}
+	var reports []fileReport
+	var ok bool
+	if patch != "" {
+		files, err := parseUnifiedDiff(patch)
+		if err != nil {
+			return errResult(fmt.Sprintf("patch parse error: %s", err)), nil
+		}
+		reports, ok = stage.stageDiff(files)
+	} else {
+		edits, err := decodePatchEdits(rawEdits)
+		if err != nil {
+			return errResult(err.Error()), nil
+		}
+		reports, ok = stage.stageEdits(edits)
+	}
+
+	result := map[string]any{
+		"workspace": t.workspace,
+		"files":     reports,
+		"dry_run":   dryRun,
+	}
+	if !ok {
+		result["applied"] = false
+		result["message"] = "Nothing was written. Fix the failed hunks (re-read the files if they changed) and send the whole patch again."
+		return tools.ToolResult{Error: true, Content: formatResult(result), Metadata: result}, nil
+	}
+	if dryRun {
+		result["applied"] = false
+		result["message"] = "All hunks apply."
+		return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+	}
+
+	if err := t.commit(stage); err != nil {
+		result["applied"] = false
+		result["message"] = err.Error()
+		return tools.ToolResult{Error: true, Content: formatResult(result), Metadata: result}, nil
+	}
+	result["applied"] = true
+	t.attach(ctx, result, stage.changedPaths()...)
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
+
+// stageDiff applies each file section of a diff in memory.
+func (s *patchStage) stageDiff(files []fileDiff) ([]fileReport, bool) {
+	reports := make([]fileReport, 0, len(files))
+	ok := true
+	for _, fd := range files {
+		rep := fileReport{Op: "modify", Path: fd.newPath}
+		switch {
+		case fd.oldPath == devNull:
+			rep.Op = "create"
+		case fd.newPath == devNull:
+			rep.Op, rep.Path = "delete", fd.oldPath
+		case fd.oldPath != fd.newPath:
+			rep.Op, rep.From = "rename", fd.oldPath
+		}
+		if !ok {
+			// Already failed: report the rest without matching them.
+			rep.Error = "skipped after an earlier failure"
+			reports = append(reports, rep)
+			continue
+		}
+		if err := s.stageFileDiff(fd, &rep); err != nil {
+			rep.Error = err.Error()
+			ok = false
+		}
+		reports = append(reports, rep)
+	}
+	return reports, ok
+}
+
+func (s *patchStage) stageFileDiff(fd fileDiff, rep *fileReport) error {
+	srcRel := fd.oldPath
+	if rep.Op == "create" {
+		srcRel = fd.newPath
+	}
+	src, err := s.file(srcRel)
+	if err != nil {
+		return err
+	}
+	switch {
+	case rep.Op == "create" && src.exists && src.content != "":
+		return fmt.Errorf("file already exists")
+	case rep.Op != "create" && !src.exists:
+		return fmt.Errorf("file does not exist")
+	}
+
+	content, hunks, added, removed, ok := applyHunks(src.content, fd.hunks)
+	rep.Hunks, rep.Added, rep.Removed = hunks, added, removed
+	if !ok {
+		return errors.New("a hunk did not apply")
+	}
+
+	switch rep.Op {
+	case "delete":
+		// Whatever the hunks left is gone with the file.
+		src.content, src.exists = "", false
+	case "rename":
+		dst, err := s.file(fd.newPath)
+		if err != nil {
+			return err
+		}
+		if dst.exists {
+			return fmt.Errorf("rename target %s already exists", fd.newPath)
+		}
+		dst.content, dst.exists, dst.mode = content, true, src.mode
+		src.content, src.exists = "", false
+	default:
+		src.content, src.exists = content, true
+	}
+	return nil
+}
+
+// patchEdit is one entry of the edits parameter.
+type patchEdit struct {
+	Path       string  `json:"path"`
+	OldString  *string `json:"old_string"`
+	NewString  *string `json:"new_string"`
+	ReplaceAll bool    `json:"replace_all"`
+	Content    *string `json:"content"`
+	Delete     bool    `json:"delete"`
+}
+
+func decodePatchEdits(raw any) ([]patchEdit, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var edits []patchEdit
+	if err := json.Unmarshal(data, &edits); err != nil {
+		return nil, fmt.Errorf("edits must be a list of {path, old_string, new_string} objects: %s", err)
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("edits is empty")
+	}
+	for i, e := range edits {
+		kinds := 0
+		if e.OldString != nil || e.NewString != nil {
+			kinds++
+			if e.OldString == nil || e.NewString == nil {
+				return nil, fmt.Errorf("edit %d: old_string and new_string go together", i+1)
+			}
+		}
+		if e.Content != nil {
+			kinds++
+		}
+		if e.Delete {
+			kinds++
+		}
+		if strings.TrimSpace(e.Path) == "" || kinds != 1 {
+			return nil, fmt.Errorf("edit %d: needs a path and exactly one of old_string/new_string, content or delete", i+1)
+		}
+	}
+	return edits, nil
+}
+
+// stageEdits applies an edit list in memory. Each edit is one "hunk" in the
+// report of its file.
+func (s *patchStage) stageEdits(edits []patchEdit) ([]fileReport, bool) {
+	var reports []fileReport
+	index := map[string]int{} // path -> position in reports
+	ok := true
+	for _, e := range edits {
+		i, seen := index[e.Path]
+		if !seen {
+			i = len(reports)
+			index[e.Path] = i
+			reports = append(reports, fileReport{Path: e.Path, Op: "modify"})
+		}
+		rep := &reports[i]
+		hunk := hunkReport{Hunk: len(rep.Hunks) + 1}
+		if !ok {
+			hunk.Status = "skipped"
+			rep.Hunks = append(rep.Hunks, hunk)
+			continue
+		}
+		if err := s.stageEdit(e, rep, &hunk); err != nil {
+			hunk.Status = "failed"
+			hunk.Error = err.Error()
+			ok = false
+		} else {
+			hunk.Status = "applied"
+		}
+		rep.Hunks = append(rep.Hunks, hunk)
+	}
+	return reports, ok
+}
+
+func (s *patchStage) stageEdit(e patchEdit, rep *fileReport, hunk *hunkReport) error {
+	f, err := s.file(e.Path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case e.Delete:
+		if !f.exists {
+			return fmt.Errorf("file does not exist")
+		}
+		rep.Op = "delete"
+		rep.Removed += countLines(f.content)
+		f.content, f.exists = "", false
+		return nil
+	case e.Content != nil:
+		if !f.existed && rep.Op == "modify" {
+			rep.Op = "create"
+		}
+		rep.Removed += countLines(f.content)
+		rep.Added += countLines(*e.Content)
+		f.content, f.exists = *e.Content, true
+		return nil
+	}
+
+	if !f.exists {
+		return fmt.Errorf("file does not exist; use content to create it")
+	}
+	oldString, newString := *e.OldString, *e.NewString
+	if oldString == "" {
+		return fmt.Errorf("old_string is empty")
+	}
+	count := strings.Count(f.content, oldString)
+	switch {
+	case count == 1 || count > 1 && e.ReplaceAll:
+		hunk.Line = strings.Count(f.content[:strings.Index(f.content, oldString)], "\n") + 1
+		hunk.Match = matchLevelNames[matchExact]
+		if e.ReplaceAll {
+			f.content = strings.ReplaceAll(f.content, oldString, newString)
+		} else {
+			f.content = strings.Replace(f.content, oldString, newString, 1)
+		}
+		rep.Removed += count * countLines(oldString)
+		rep.Added += count * countLines(newString)
+		return nil
+	case count > 1:
+		return fmt.Errorf("old_string appears %d times; set replace_all or add context", count)
+	}
+
+	// No exact match: fall back to whole lines compared without regard to
+	// whitespace, which catches re-indented or trailing-space drift.
+	text := splitText(f.content)
+	want := splitText(oldString).lines
+	if len(want) == 0 {
+		return fmt.Errorf("old_string not found")
+	}
+	pos, level, err := findBlock(text.lines, want, 0, false)
+	if err != nil {
+		if errors.Is(err, errContextNotFound) {
+			return fmt.Errorf("old_string not found")
+		}
+		return err
+	}
+	repl := splitText(newString).lines
+	newLines := append(append(append([]string{}, text.lines[:pos]...), repl...), text.lines[pos+len(want):]...)
+	text.lines = newLines
+	f.content = text.String()
+	hunk.Line = pos + 1
+	hunk.Match = matchLevelNames[level]
+	rep.Removed += len(want)
+	rep.Added += len(repl)
+	return nil
+}
+
+func countLines(s string) int {
+	return len(splitText(s).lines)
+}
+
+// commit snapshots every changed file, then writes them. If a write fails,
+// the files already written are restored and the error says so.
+func (t *ApplyPatchTool) commit(stage *patchStage) error {
+	var changed []*patchFile
+	for _, f := range stage.order {
+		if f.changed() {
+			changed = append(changed, f)
+		}
+	}
+
+	var snapped []*patchFile
+	if t.snapMgr != nil {
+		for _, f := range changed {
+			if err := t.snapMgr.Snapshot(f.abs); err != nil {
+				_ = t.rollback(snapped, nil)
+				return fmt.Errorf("snapshot %s failed, nothing was written: %s", f.rel, err)
+			}
+			snapped = append(snapped, f)
+		}
+	}
+
+	for i, f := range changed {
+		if err := writePatched(f); err != nil {
+			if rbErr := t.rollback(snapped, changed[:i+1]); rbErr != nil {
+				return fmt.Errorf("write %s failed: %s; rollback also failed, check the workspace: %s", f.rel, err, rbErr)
+			}
+			return fmt.Errorf("write %s failed, all files were restored: %s", f.rel, err)
+		}
+	}
+
+	for _, f := range changed {
+		if !f.exists {
+			if t.tracker != nil {
+				t.tracker.ClearStale(f.abs)
+			}
+			continue
+		}
+		if filepath.Base(f.abs) == ".grimoire" {
+			stampGrimoireMetadata(f.abs)
+		}
+		if t.tracker != nil {
+			_ = t.tracker.RecordRead(f.abs)
+		}
+	}
+	return nil
+}
+
+// writePatched is writePatchedFile; tests swap it to fail a write midway.
+var writePatched = writePatchedFile
+
+// writePatchedFile writes or deletes f. Content goes to a temp file that is
+// renamed over the target, so a failed write never leaves a file half
+// written.
+func writePatchedFile(f *patchFile) error {
+	if !f.exists {
+		if err := os.Remove(f.abs); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	dir := filepath.Dir(f.abs)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(f.abs)+".celeste-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(f.content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(f.mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.abs)
+}
+
+// rollback restores written files from their snapshots, or from the staged
+// originals when there are no snapshots, and drops the snapshots taken for
+// this patch so /undo does not replay them.
+func (t *ApplyPatchTool) rollback(snapped, written []*patchFile) error {
+	var errs []error
+	restored := map[*patchFile]bool{}
+	for i := len(snapped) - 1; i >= 0; i-- {
+		f := snapped[i]
+		if err := t.snapMgr.Revert(f.abs); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.rel, err))
+			continue
+		}
+		restored[f] = true
+	}
+	for _, f := range written {
+		if restored[f] {
+			continue
+		}
+		orig := *f
+		orig.content, orig.exists = f.orig, f.existed
+		if err := writePatchedFile(&orig); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.rel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
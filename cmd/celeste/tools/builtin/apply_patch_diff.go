@@ -0,0 +1,409 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// devNull is how a unified diff names the missing side of a created or
+// deleted file.
+const devNull = "/dev/null"
+
+// maxHunkFuzz is how many leading and trailing context lines a hunk may drop
+// to find a match, like patch's default fuzz factor.
+const maxHunkFuzz = 2
+
+// fileDiff is one file's section of a unified diff.
+type fileDiff struct {
+	oldPath string
+	newPath string
+	hunks   []diffHunk
+}
+
+// diffHunk is one @@ section. Lines keep their ' ', '-' or '+' prefix as kind.
+type diffHunk struct {
+	oldStart int  // 1-based; for a pure insertion, the line it goes after
+	oldCount int  // old-side length from the header
+	hasPos   bool // false for a bare "@@" header
+	lines    []hunkLine
+	noEOLOld bool // "\ No newline at end of file" after the old side's last line
+	noEOLNew bool // ... after the new side's last line
+}
+
+type hunkLine struct {
+	kind byte
+	text string
+	bare bool // an empty line with no prefix, which editors make of " "
+}
+
+// errContextNotFound means a hunk's old lines occur nowhere in the file.
+var errContextNotFound = errors.New("context not found")
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnifiedDiff splits a unified diff (git or plain diff -u) into files.
+// Line counts in hunk headers are a hint, not a contract: models often get
+// them wrong, so hunk bodies extend to the next header.
+func parseUnifiedDiff(patch string) ([]fileDiff, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []fileDiff
+	var cur *fileDiff
+	var hunk *diffHunk
+	lastKind := byte(0)
+
+	finishHunk := func() {
+		if hunk == nil {
+			return
+		}
+		// Blank lines between sections are separators, not context.
+		for n := len(hunk.lines); n > 0 && hunk.lines[n-1].bare; n-- {
+			hunk.lines = hunk.lines[:n-1]
+		}
+		cur.hunks = append(cur.hunks, *hunk)
+		hunk = nil
+	}
+	startFile := func() {
+		finishHunk()
+		files = append(files, fileDiff{})
+		cur = &files[len(files)-1]
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			startFile()
+			if a, b, ok := splitGitPaths(strings.TrimPrefix(line, "diff --git ")); ok {
+				cur.oldPath, cur.newPath = a, b
+			}
+			continue
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			// A new file section unless it directly follows a diff --git line.
+			if cur == nil || hunk != nil || len(cur.hunks) > 0 {
+				startFile()
+			}
+			cur.oldPath = cleanDiffPath(line[4:])
+			cur.newPath = cleanDiffPath(lines[i+1][4:])
+			i++
+			continue
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+			}
+			finishHunk()
+			hunk = &diffHunk{}
+			if m := hunkHeaderRe.FindStringSubmatch(line); m != nil {
+				hunk.oldStart, _ = strconv.Atoi(m[1])
+				hunk.oldCount = 1
+				if m[2] != "" {
+					hunk.oldCount, _ = strconv.Atoi(m[2])
+				}
+				hunk.hasPos = true
+			}
+			continue
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			return nil, fmt.Errorf("line %d: binary patches are not supported", i+1)
+		}
+
+		if hunk == nil {
+			// Extended git headers carry renames, creations and deletions.
+			if cur != nil {
+				switch {
+				case strings.HasPrefix(line, "rename from "):
+					cur.oldPath = cleanDiffPath(strings.TrimPrefix(line, "rename from "))
+				case strings.HasPrefix(line, "rename to "):
+					cur.newPath = cleanDiffPath(strings.TrimPrefix(line, "rename to "))
+				case strings.HasPrefix(line, "new file mode"):
+					cur.oldPath = devNull
+				case strings.HasPrefix(line, "deleted file mode"):
+					cur.newPath = devNull
+				}
+			}
+			continue
+		}
+
+		switch {
+		case line == "":
+			hunk.lines = append(hunk.lines, hunkLine{kind: ' ', bare: true})
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			hunk.lines = append(hunk.lines, hunkLine{kind: line[0], text: line[1:]})
+			lastKind = line[0]
+			continue
+		case line[0] == '\\':
+			// "\ No newline at end of file" applies to the line before it.
+			switch lastKind {
+			case '-':
+				hunk.noEOLOld = true
+			case '+':
+				hunk.noEOLNew = true
+			default:
+				hunk.noEOLOld, hunk.noEOLNew = true, true
+			}
+		default:
+			// Commentary between sections ends the hunk.
+			finishHunk()
+		}
+		lastKind = 0
+	}
+	finishHunk()
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file headers found; a unified diff needs ---/+++ lines before each file's hunks")
+	}
+	for _, f := range files {
+		if f.oldPath == "" || f.newPath == "" {
+			return nil, fmt.Errorf("file section without both --- and +++ paths")
+		}
+		if f.oldPath == devNull && f.newPath == devNull {
+			return nil, fmt.Errorf("file section with /dev/null on both sides")
+		}
+	}
+	return files, nil
+}
+
+// splitGitPaths parses "a/x b/y" from a diff --git line.
+func splitGitPaths(s string) (string, string, bool) {
+	i := strings.LastIndex(s, " b/")
+	if i < 0 {
+		return "", "", false
+	}
+	return cleanDiffPath(s[:i]), cleanDiffPath(s[i+1:]), true
+}
+
+// cleanDiffPath strips the a/ or b/ prefix, quotes and trailing timestamp from
+// a diff header path.
+func cleanDiffPath(p string) string {
+	if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+		p = p[:tab]
+	}
+	p = strings.TrimSpace(p)
+	if unq, err := strconv.Unquote(p); err == nil {
+		p = unq
+	}
+	if p == devNull {
+		return p
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		p = p[2:]
+	}
+	return p
+}
+
+// textLines is file content split for hunk matching: lines without their
+// terminators, plus what is needed to join them back unchanged.
+type textLines struct {
+	lines    []string
+	eol      string
+	trailing bool // content ended with a newline
+}
+
+func splitText(s string) textLines {
+	t := textLines{eol: "\n", trailing: true}
+	if strings.Contains(s, "\r\n") {
+		t.eol = "\r\n"
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+	}
+	if s == "" {
+		return t
+	}
+	t.trailing = strings.HasSuffix(s, "\n")
+	t.lines = strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	return t
+}
+
+func (t textLines) String() string {
+	if len(t.lines) == 0 {
+		return ""
+	}
+	s := strings.Join(t.lines, t.eol)
+	if t.trailing {
+		s += t.eol
+	}
+	return s
+}
+
+// Match levels, from strictest to loosest.
+const (
+	matchExact = iota
+	matchTrailingSpace
+	matchWhitespace
+)
+
+var matchLevelNames = []string{"exact", "trailing whitespace ignored", "whitespace ignored"}
+
+func normalizeLine(s string, level int) string {
+	switch level {
+	case matchTrailingSpace:
+		return strings.TrimRight(s, " \t\r")
+	case matchWhitespace:
+		return strings.Join(strings.Fields(s), " ")
+	}
+	return s
+}
+
+// findBlock finds where want occurs in lines at the strictest level that
+// matches anything. With a known position the nearest occurrence wins;
+// without one the occurrence must be unique.
+func findBlock(lines, want []string, expected int, hasPos bool) (pos, level int, err error) {
+	for level = matchExact; level <= matchWhitespace; level++ {
+		var found []int
+		for i := 0; i+len(want) <= len(lines); i++ {
+			ok := true
+			for k := range want {
+				if normalizeLine(lines[i+k], level) != normalizeLine(want[k], level) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				found = append(found, i)
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		if !hasPos && len(found) > 1 {
+			return 0, level, fmt.Errorf("context matches %d places (lines %d and %d, ...); add line numbers or more context", len(found), found[0]+1, found[1]+1)
+		}
+		best := found[0]
+		for _, f := range found[1:] {
+			if abs(f-expected) < abs(best-expected) {
+				best = f
+			}
+		}
+		return best, level, nil
+	}
+	return 0, 0, errContextNotFound
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// trimContext drops up to n context lines from each end of h's lines, and
+// reports how many went from the front.
+func trimContext(lines []hunkLine, n int) ([]hunkLine, int) {
+	front := 0
+	for front < n && front < len(lines) && lines[front].kind == ' ' {
+		front++
+	}
+	lines = lines[front:]
+	back := 0
+	for back < n && back < len(lines) && lines[len(lines)-1-back].kind == ' ' {
+		back++
+	}
+	return lines[:len(lines)-back], front
+}
+
+// applyHunks applies hunks in order to content. It stops at the first hunk
+// that does not apply; reports cover the hunks tried. added and removed
+// count changed lines.
+func applyHunks(content string, hunks []diffHunk) (out string, reports []hunkReport, added, removed int, ok bool) {
+	text := splitText(content)
+	delta := 0 // how far earlier hunks moved the lines below them
+	for i, h := range hunks {
+		rep := hunkReport{Hunk: i + 1}
+		expected := max(h.oldStart-1, 0) + delta
+		if h.hasPos && h.oldCount == 0 {
+			// "-5,0" inserts after line 5.
+			expected = h.oldStart + delta
+		}
+
+		var pos, level, fuzz, front int
+		var body []hunkLine
+		var err error
+		for fuzz = 0; fuzz <= maxHunkFuzz; fuzz++ {
+			var trimmed []hunkLine
+			trimmed, front = trimContext(h.lines, fuzz)
+			if fuzz > 0 && len(trimmed) == len(h.lines) {
+				break // no context left to drop
+			}
+			old := hunkSide(trimmed, '+')
+			if len(old) == 0 {
+				if fuzz > 0 {
+					break // nothing left to anchor on
+				}
+				// Pure insertion: trust the header.
+				pos, level, body, err = min(expected, len(text.lines)), matchExact, trimmed, nil
+				break
+			}
+			pos, level, err = findBlock(text.lines, old, expected+front, h.hasPos)
+			if err == nil {
+				body = trimmed
+				break
+			}
+		}
+		if err != nil || body == nil && len(hunkSide(h.lines, '+')) > 0 {
+			if err == nil {
+				err = errContextNotFound
+			}
+			rep.Status = "failed"
+			rep.Error = err.Error()
+			if h.hasPos {
+				rep.Error += fmt.Sprintf(" near line %d", h.oldStart)
+			}
+			reports = append(reports, rep)
+			return "", reports, added, removed, false
+		}
+
+		// Context lines keep the file's text; only - and + lines change.
+		oldLen := 0
+		var repl []string
+		for _, l := range body {
+			switch l.kind {
+			case ' ':
+				repl = append(repl, text.lines[pos+oldLen])
+				oldLen++
+			case '-':
+				oldLen++
+				removed++
+			case '+':
+				repl = append(repl, l.text)
+				added++
+			}
+		}
+		atEOF := pos+oldLen == len(text.lines)
+		wasEmpty := len(text.lines) == 0
+		newLines := make([]string, 0, len(text.lines)-oldLen+len(repl))
+		newLines = append(newLines, text.lines[:pos]...)
+		newLines = append(newLines, repl...)
+		newLines = append(newLines, text.lines[pos+oldLen:]...)
+		text.lines = newLines
+		if atEOF {
+			switch {
+			case h.noEOLNew:
+				text.trailing = false
+			case h.noEOLOld || wasEmpty:
+				text.trailing = true
+			}
+		}
+
+		rep.Status = "applied"
+		rep.Line = pos + 1
+		rep.Offset = pos - (expected + front)
+		rep.Match = matchLevelNames[level]
+		if fuzz > 0 {
+			rep.Match += fmt.Sprintf(", fuzz %d", fuzz)
+		}
+		reports = append(reports, rep)
+		delta += len(repl) - oldLen
+	}
+	return text.String(), reports, added, removed, true
+}
+
+// hunkSide returns the old (skip '+') or new (skip '-') side of a hunk.
+func hunkSide(lines []hunkLine, skip byte) []string {
+	var out []string
+	for _, l := range lines {
+		if l.kind != skip {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
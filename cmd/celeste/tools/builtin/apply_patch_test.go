@@ -0,0 +1,277 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func applyPatch(t *testing.T, tool *ApplyPatchTool, args map[string]any) (map[string]any, bool) {
+	t.Helper()
+	res, err := tool.Execute(context.Background(), args, nil)
+	require.NoError(t, err)
+	return decodeResult(t, res), !res.Error
+}
+
+// hunkAt digs files[i].hunks[j] out of a decoded result.
+func hunkAt(t *testing.T, data map[string]any, i, j int) map[string]any {
+	t.Helper()
+	files := data["files"].([]any)
+	hunks := files[i].(map[string]any)["hunks"].([]any)
+	return hunks[j].(map[string]any)
+}
+
+func TestApplyPatch_MultiFileDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		// Two lines were added at the top since the diff was made, and the
+		// context line has trailing whitespace the diff lacks.
+		"pkg/a.go": "// added\n// added\npackage pkg\n\nfunc A() int {   \n\treturn 1\n}\n",
+		"pkg/b.go": "package pkg\n\nfunc B() int { return A() }\n",
+	})
+	patch := `diff --git a/pkg/a.go b/pkg/a.go
+--- a/pkg/a.go
++++ b/pkg/a.go
+@@ -3,3 +3,3 @@ package pkg
+ func A() int {
+-	return 1
++	return 2
+ }
+--- a/pkg/b.go
++++ b/pkg/b.go
+@@ -3 +3,2 @@
+-func B() int { return A() }
++func B() int { return A() + 1 }
++func C() int { return 3 }
+`
+	data, ok := applyPatch(t, NewApplyPatchTool(dir), map[string]any{"patch": patch})
+	require.True(t, ok, data)
+	assert.Equal(t, true, data["applied"])
+
+	assert.Equal(t, "// added\n// added\npackage pkg\n\nfunc A() int {   \n\treturn 2\n}\n", mustRead(t, filepath.Join(dir, "pkg/a.go")))
+	assert.Equal(t, "package pkg\n\nfunc B() int { return A() + 1 }\nfunc C() int { return 3 }\n", mustRead(t, filepath.Join(dir, "pkg/b.go")))
+
+	first := hunkAt(t, data, 0, 0)
+	assert.Equal(t, "applied", first["status"])
+	assert.Equal(t, float64(5), first["line"])
+	assert.Equal(t, float64(2), first["offset"])
+	assert.Equal(t, "trailing whitespace ignored", first["match"])
+	assert.Equal(t, "exact", hunkAt(t, data, 1, 0)["match"])
+}
+
+func TestApplyPatch_FailedHunkWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt": "one\ntwo\nthree\n",
+		"b.txt": "alpha\nbeta\n",
+	})
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+--- a/b.txt
++++ b/b.txt
+@@ -1,2 +1,2 @@
+ alpha
+-gamma
++delta
+`
+	data, ok := applyPatch(t, NewApplyPatchTool(dir), map[string]any{"patch": patch})
+	require.False(t, ok)
+	assert.Equal(t, false, data["applied"])
+	assert.Equal(t, "failed", hunkAt(t, data, 1, 0)["status"])
+	assert.Contains(t, hunkAt(t, data, 1, 0)["error"], "context not found")
+	assert.Equal(t, "one\ntwo\nthree\n", mustRead(t, filepath.Join(dir, "a.txt")))
+}
+
+func TestApplyPatch_FuzzDropsStaleContext(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"f.txt": "header changed\nkeep\nold\nkeep2\n"})
+	patch := `--- a/f.txt
++++ b/f.txt
+@@ -1,4 +1,4 @@
+ header
+ keep
+-old
++new
+ keep2
+`
+	data, ok := applyPatch(t, NewApplyPatchTool(dir), map[string]any{"patch": patch})
+	require.True(t, ok, data)
+	assert.Equal(t, "exact, fuzz 1", hunkAt(t, data, 0, 0)["match"])
+	assert.Equal(t, "header changed\nkeep\nnew\nkeep2\n", mustRead(t, filepath.Join(dir, "f.txt")))
+}
+
+func TestApplyPatch_CreateDeleteRename(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"old.txt":  "bye\n",
+		"from.txt": "x\ny\n",
+	})
+	patch := `diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/from.txt b/sub/to.txt
+similarity index 50%
+rename from from.txt
+rename to sub/to.txt
+--- a/from.txt
++++ b/sub/to.txt
+@@ -1,2 +1,2 @@
+ x
+-y
++z
+\ No newline at end of file
+`
+	data, ok := applyPatch(t, NewApplyPatchTool(dir), map[string]any{"patch": patch})
+	require.True(t, ok, data)
+	assert.Equal(t, "hello\nworld\n", mustRead(t, filepath.Join(dir, "new.txt")))
+	assert.NoFileExists(t, filepath.Join(dir, "old.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "from.txt"))
+	assert.Equal(t, "x\nz", mustRead(t, filepath.Join(dir, "sub/to.txt")))
+}
+
+func TestApplyPatch_RefusesStaleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "one\n", "b.txt": "two\n"})
+	tracker := checkpoints.NewFileTracker()
+	b := filepath.Join(dir, "b.txt")
+	require.NoError(t, tracker.RecordRead(b))
+	require.NoError(t, os.WriteFile(b, []byte("changed elsewhere\n"), 0644))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(b, later, later))
+
+	data, ok := applyPatch(t, NewApplyPatchTool(dir, WithApplyPatchTracker(tracker)), map[string]any{
+		"edits": []any{
+			map[string]any{"path": "a.txt", "old_string": "one", "new_string": "ONE"},
+			map[string]any{"path": "b.txt", "old_string": "two", "new_string": "TWO"},
+		},
+	})
+	require.False(t, ok)
+	assert.Contains(t, hunkAt(t, data, 1, 0)["error"], "modified externally")
+	assert.Equal(t, "one\n", mustRead(t, filepath.Join(dir, "a.txt")))
+}
+
+func TestApplyPatch_Edits(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.py":    "def f():\n    return 1\n\ndef g():\n    return 1\n",
+		"gone.md": "x\n",
+	})
+	tool := NewApplyPatchTool(dir)
+
+	data, ok := applyPatch(t, tool, map[string]any{
+		"edits": []any{
+			map[string]any{"path": "a.py", "old_string": "return 1", "new_string": "return 2"},
+		},
+	})
+	require.False(t, ok)
+	assert.Contains(t, hunkAt(t, data, 0, 0)["error"], "appears 2 times")
+
+	data, ok = applyPatch(t, tool, map[string]any{
+		"edits": []any{
+			// Indented differently from the file: matched ignoring whitespace.
+			map[string]any{"path": "a.py", "old_string": "def g():\n  return 1", "new_string": "def g():\n    return 3"},
+			map[string]any{"path": "a.py", "old_string": "return 1", "new_string": "return 2"},
+			map[string]any{"path": "new/b.py", "content": "print('b')\n"},
+			map[string]any{"path": "gone.md", "delete": true},
+		},
+	})
+	require.True(t, ok, data)
+	assert.Equal(t, "whitespace ignored", hunkAt(t, data, 0, 0)["match"])
+	assert.Equal(t, "def f():\n    return 2\n\ndef g():\n    return 3\n", mustRead(t, filepath.Join(dir, "a.py")))
+	assert.Equal(t, "print('b')\n", mustRead(t, filepath.Join(dir, "new/b.py")))
+	assert.NoFileExists(t, filepath.Join(dir, "gone.md"))
+}
+
+func TestApplyPatch_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "one\n"})
+	data, ok := applyPatch(t, NewApplyPatchTool(dir), map[string]any{
+		"edits":   []any{map[string]any{"path": "a.txt", "old_string": "one", "new_string": "two"}},
+		"dry_run": true,
+	})
+	require.True(t, ok)
+	assert.Equal(t, false, data["applied"])
+	assert.Equal(t, "one\n", mustRead(t, filepath.Join(dir, "a.txt")))
+}
+
+func TestApplyPatch_RollbackOnWriteFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "one\n", "b.txt": "two\n"})
+	snaps := checkpoints.NewSnapshotManager("apply-patch-test")
+
+	orig := writePatched
+	t.Cleanup(func() { writePatched = orig })
+	writePatched = func(f *patchFile) error {
+		if filepath.Base(f.abs) == "c.txt" {
+			return errors.New("disk full")
+		}
+		return orig(f)
+	}
+
+	data, ok := applyPatch(t, NewApplyPatchTool(dir, WithApplyPatchSnapshots(snaps)), map[string]any{
+		"edits": []any{
+			map[string]any{"path": "a.txt", "old_string": "one", "new_string": "ONE"},
+			map[string]any{"path": "b.txt", "delete": true},
+			map[string]any{"path": "c.txt", "content": "three\n"},
+		},
+	})
+	require.False(t, ok)
+	assert.Contains(t, data["message"], "all files were restored")
+	assert.Equal(t, "one\n", mustRead(t, filepath.Join(dir, "a.txt")))
+	assert.Equal(t, "two\n", mustRead(t, filepath.Join(dir, "b.txt")))
+	assert.NoFileExists(t, filepath.Join(dir, "c.txt"))
+	assert.Empty(t, snaps.GetChanges(), "rolled-back snapshots should be dropped")
+}
+
+func TestApplyPatch_SnapshotsForUndo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "one\n"})
+	snaps := checkpoints.NewSnapshotManager("apply-patch-test")
+
+	_, ok := applyPatch(t, NewApplyPatchTool(dir, WithApplyPatchSnapshots(snaps)), map[string]any{
+		"edits": []any{map[string]any{"path": "a.txt", "old_string": "one", "new_string": "two"}},
+	})
+	require.True(t, ok)
+	path, err := snaps.RevertLast()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "a.txt"), path)
+	assert.Equal(t, "one\n", mustRead(t, path))
+}
+
+func TestParseUnifiedDiff_RejectsHeaderlessHunks(t *testing.T) {
+	_, err := parseUnifiedDiff("@@ -1 +1 @@\n-a\n+b\n")
+	assert.Error(t, err)
+}
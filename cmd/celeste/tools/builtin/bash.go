@@ -8,6 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/shell"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
 )
 
@@ -17,11 +20,23 @@ const maxCommandOutput = 64_000
 type BashTool struct {
 	BaseTool
 	workspace string
+	shells    *shell.Manager // persistent shells and background jobs; nil = one-shot only
+}
+
+// BashOption configures optional dependencies for BashTool.
+type BashOption func(*BashTool)
+
+// WithShellManager enables the shell and background parameters, backed by
+// m's persistent shells and jobs.
+func WithShellManager(m *shell.Manager) BashOption {
+	return func(t *BashTool) {
+		t.shells = m
+	}
 }
 
 // NewBashTool creates a BashTool bound to the given workspace directory.
-func NewBashTool(workspace string) *BashTool {
-	return &BashTool{
+func NewBashTool(workspace string, opts ...BashOption) *BashTool {
+	t := &BashTool{
 		BaseTool: BaseTool{
 			ToolName:        "bash",
 			ToolDescription: "Execute a shell command from workspace root and return combined output.",
@@ -46,6 +61,39 @@ func NewBashTool(workspace string) *BashTool {
 		},
 		workspace: workspace,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.shells != nil {
+		t.ToolDescription = "Execute a shell command from workspace root and return combined output. " +
+			"Pass shell to run it in a named persistent shell, where cd, exported variables and functions carry over " +
+			"between calls. Pass background=true for servers, watchers and long builds: it returns a job id at once; " +
+			"read the output with job_output and stop it with job_kill. Under a sandbox a job is killed once it " +
+			"outlives the job time limit, and jobs still running when the session ends are killed."
+		t.ToolParameters = json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"command": {
+					"type": "string",
+					"description": "Shell command to execute."
+				},
+				"timeout_seconds": {
+					"type": "number",
+					"description": "Execution timeout in seconds. Defaults to 20. Ignored with background."
+				},
+				"shell": {
+					"type": "string",
+					"description": "Name of a persistent shell to run in, e.g. \"dev\". Started on first use. A command that times out kills the shell and its state."
+				},
+				"background": {
+					"type": "boolean",
+					"description": "Start the command as a background job and return its id without waiting. Runs in the named shell's current directory when shell is also given."
+				}
+			},
+			"required": ["command"]
+		}`)
+	}
+	return t
 }
 
 func (t *BashTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
@@ -61,6 +109,9 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, progress c
 	if reason := checkDangerousCommand(command); reason != "" {
 		return tools.ToolResult{Error: true, Content: reason}, nil
 	}
+	if reason := checkGitRewrite(command, permissions.GitPolicyFromContext(ctx)); reason != "" {
+		return tools.ToolResult{Error: true, Content: reason}, nil
+	}
 
 	timeoutSeconds := getIntArg(input, "timeout_seconds", 20)
 	if timeoutSeconds <= 0 {
@@ -70,11 +121,31 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, progress c
 		timeoutSeconds = 300
 	}
 
-	cmdCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	policy := sandbox.PolicyFromContext(ctx)
+	timeout := policy.Timeout(time.Duration(timeoutSeconds) * time.Second)
+
+	shellName := strings.TrimSpace(getStringArg(input, "shell", ""))
+	background := getBoolArg(input, "background", false)
+	if shellName != "" || background {
+		if t.shells == nil {
+			return tools.ToolResult{Error: true, Content: "persistent shells and background jobs are not available here; run the command without shell or background"}, nil
+		}
+		if background {
+			return t.startJob(ctx, command, shellName)
+		}
+		return t.runInShell(ctx, shellName, command, timeout)
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(cmdCtx, "sh", "-c", command)
 	cmd.Dir = t.workspace
+	cleanup, err := policy.Wrap(cmd)
+	if err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+	defer cleanup()
 	output, err := cmd.CombinedOutput()
 
 	outputStr := string(output)
@@ -99,6 +170,9 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, progress c
 		"truncated": truncated,
 		"timed_out": timedOut,
 	}
+	if policy.Enabled() {
+		result["sandbox"] = policy.Describe()
+	}
 	if err != nil {
 		result["error"] = err.Error()
 	}
@@ -110,6 +184,70 @@ func (t *BashTool) Execute(ctx context.Context, input map[string]any, progress c
 	}, nil
 }
 
+// runInShell runs command in the named persistent shell.
+func (t *BashTool) runInShell(ctx context.Context, name, command string, timeout time.Duration) (tools.ToolResult, error) {
+	res, err := t.shells.Run(ctx, name, command, timeout)
+	if err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+	output := res.Output
+	truncated := res.Truncated
+	if len(output) > maxCommandOutput {
+		// Keep the end, where a build or test run reports what failed.
+		output = output[len(output)-maxCommandOutput:]
+		truncated = true
+	}
+	result := map[string]any{
+		"command":   command,
+		"shell":     name,
+		"exit_code": res.ExitCode,
+		"output":    output,
+		"truncated": truncated,
+		"timed_out": res.TimedOut,
+	}
+	if res.Exited {
+		result["shell_reset"] = true
+		if res.TimedOut {
+			result["note"] = "The command timed out, so the shell was killed and its directory and variables are gone. " +
+				"Start long-running commands with background=true."
+		} else {
+			result["note"] = "The shell exited; the next command starts a fresh one."
+		}
+	}
+	if policy := sandbox.PolicyFromContext(ctx); policy.Enabled() {
+		result["sandbox"] = policy.Describe()
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
+
+// startJob starts command as a background job, in the named shell's
+// current directory if one is given.
+func (t *BashTool) startJob(ctx context.Context, command, shellName string) (tools.ToolResult, error) {
+	dir := ""
+	if shellName != "" {
+		if sh, ok := t.shells.Shell(shellName); ok {
+			dir = sh.Dir()
+		}
+	}
+	job, err := t.shells.Start(ctx, command, dir)
+	if err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+	info := job.Info()
+	result := map[string]any{
+		"command": command,
+		"job_id":  info.ID,
+		"pid":     info.PID,
+		"dir":     info.Dir,
+		"status":  info.Status,
+		"note":    "Read its output with job_output (action poll or wait) and stop it with job_kill.",
+	}
+	if info.Timeout > 0 {
+		result["timeout_seconds"] = int(info.Timeout.Seconds())
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
+
 // formatResult marshals a result map to JSON for ToolResult.Content.
 func formatResult(result map[string]any) string {
 	data, err := json.Marshal(result)
@@ -3,14 +3,26 @@ package builtin
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp/lsptest"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
 )
 
+func TestMain(m *testing.M) {
+	// A sandboxed bash command re-executes the test binary as the helper.
+	sandbox.Init()
+	// So does the stand-in language server for the lsp tests.
+	lsptest.Init()
+	os.Exit(m.Run())
+}
+
 func TestBashToolName(t *testing.T) {
 	bt := NewBashTool("/tmp")
 	assert.Equal(t, "bash", bt.Name())
@@ -40,6 +52,28 @@ func TestBashToolExecuteSimpleCommand(t *testing.T) {
 	assert.Equal(t, float64(0), data["exit_code"])
 }
 
+func TestBashToolSandboxed(t *testing.T) {
+	if !sandbox.Probe().Sufficient() {
+		t.Skipf("sandbox unavailable: %s", sandbox.Probe())
+	}
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	ctx := sandbox.WithPolicy(context.Background(), sandbox.Policy{Mode: sandbox.ModeRequired})
+
+	bt := NewBashTool(workspace)
+	result, err := bt.Execute(ctx, map[string]any{
+		"command": "echo ok > in.txt && echo bad > " + filepath.Join(outside, "out.txt"),
+	}, nil)
+	require.NoError(t, err)
+
+	var data map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content), &data))
+	assert.NotEqual(t, float64(0), data["exit_code"])
+	assert.Contains(t, data["sandbox"], "landlock")
+	assert.FileExists(t, filepath.Join(workspace, "in.txt"))
+	assert.NoFileExists(t, filepath.Join(outside, "out.txt"))
+}
+
 func TestBashToolSudoBlocking(t *testing.T) {
 	bt := NewBashTool("/tmp")
 	result, err := bt.Execute(context.Background(), map[string]any{
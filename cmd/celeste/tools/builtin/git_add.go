@@ -0,0 +1,78 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// GitAddTool stages files for the next commit.
+type GitAddTool struct {
+	BaseTool
+	workspace string
+}
+
+// NewGitAddTool creates a GitAddTool bound to the given workspace.
+func NewGitAddTool(workspace string) *GitAddTool {
+	return &GitAddTool{
+		BaseTool: BaseTool{
+			ToolName:        "git_add",
+			ToolDescription: "Stage files for the next git commit, including deletions. Pass paths, or all=true to stage every change in the workspace.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"paths": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Files or directories to stage."
+					},
+					"all": {
+						"type": "boolean",
+						"description": "Stage all changes, including untracked files."
+					}
+				},
+				"required": []
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			Interrupt:       tools.InterruptBlock,
+		},
+		workspace: workspace,
+	}
+}
+
+func (t *GitAddTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	paths, err := gitPathArgs(t.workspace, stringListArg(input, "paths"))
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	args := []string{"add", "--"}
+	switch {
+	case getBoolArg(input, "all", false):
+		args = []string{"add", "--all"}
+	case len(paths) == 0:
+		return errResult("pass paths to stage, or all=true"), nil
+	default:
+		args = append(args, paths...)
+	}
+	if _, err := runGit(ctx, t.workspace, "", args...); err != nil {
+		return errResult(err.Error()), nil
+	}
+
+	staged, err := stagedFiles(ctx, t.workspace)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	result := map[string]any{"staged": staged}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
+
+// stagedFiles lists the paths in the index that differ from HEAD.
+func stagedFiles(ctx context.Context, workspace string) ([]string, error) {
+	out, err := runGit(ctx, workspace, "", "diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{}, gitLines(out)...), nil
+}
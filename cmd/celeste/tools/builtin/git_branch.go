@@ -0,0 +1,156 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// GitBranchTool lists, creates, switches, renames and deletes branches.
+type GitBranchTool struct {
+	BaseTool
+	workspace string
+}
+
+// NewGitBranchTool creates a GitBranchTool bound to the given workspace.
+func NewGitBranchTool(workspace string) *GitBranchTool {
+	return &GitBranchTool{
+		BaseTool: BaseTool{
+			ToolName:        "git_branch",
+			ToolDescription: "List, create, switch, rename or delete git branches. Deleting an unmerged branch or overwriting one rewrites history and is refused unless permitted.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"action": {
+						"type": "string",
+						"enum": ["list", "create", "switch", "rename", "delete"],
+						"description": "What to do (default list)."
+					},
+					"name": {
+						"type": "string",
+						"description": "Branch to create, switch to, rename or delete."
+					},
+					"new_name": {
+						"type": "string",
+						"description": "New name for rename."
+					},
+					"start_point": {
+						"type": "string",
+						"description": "Commit or branch to create the branch from (default HEAD)."
+					},
+					"switch": {
+						"type": "boolean",
+						"description": "Switch to the branch after creating it."
+					},
+					"force": {
+						"type": "boolean",
+						"description": "Delete even if unmerged. Refused unless history rewrite is permitted."
+					}
+				},
+				"required": []
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			Interrupt:       tools.InterruptBlock,
+		},
+		workspace: workspace,
+	}
+}
+
+func (t *GitBranchTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	action := getStringArg(input, "action", "list")
+	name := getStringArg(input, "name", "")
+	if action != "list" {
+		if name == "" {
+			return errResult(fmt.Sprintf("action %q needs name", action)), nil
+		}
+		if err := t.checkName(ctx, "name", name); err != nil {
+			return errResult(err.Error()), nil
+		}
+	}
+
+	var err error
+	switch action {
+	case "list":
+		return t.list(ctx)
+	case "create":
+		start := getStringArg(input, "start_point", "")
+		if err := checkGitRef("start_point", start); err != nil {
+			return errResult(err.Error()), nil
+		}
+		args := []string{"branch", "--", name}
+		if getBoolArg(input, "switch", false) {
+			args = []string{"switch", "-c", name}
+		}
+		if start != "" {
+			args = append(args, start)
+		}
+		_, err = runGit(ctx, t.workspace, "", args...)
+	case "switch":
+		_, err = runGit(ctx, t.workspace, "", "switch", name)
+	case "rename":
+		newName := getStringArg(input, "new_name", "")
+		if newName == "" {
+			return errResult("rename needs new_name"), nil
+		}
+		if err := t.checkName(ctx, "new_name", newName); err != nil {
+			return errResult(err.Error()), nil
+		}
+		_, err = runGit(ctx, t.workspace, "", "branch", "-m", "--", name, newName)
+	case "delete":
+		flag := "-d"
+		if getBoolArg(input, "force", false) {
+			if !permissions.GitPolicyFromContext(ctx).AllowHistoryRewrite {
+				return errResult("force-deleting a branch can lose commits and is not permitted; " + rewriteHint), nil
+			}
+			flag = "-D"
+		}
+		_, err = runGit(ctx, t.workspace, "", "branch", flag, "--", name)
+	default:
+		return errResult(fmt.Sprintf("unknown action %q (use list, create, switch, rename or delete)", action)), nil
+	}
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	return t.list(ctx)
+}
+
+// checkName rejects names git would read as options or refuse as branches.
+func (t *GitBranchTool) checkName(ctx context.Context, field, name string) error {
+	if err := checkGitRef(field, name); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, t.workspace, "", "check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("%s %q is not a valid branch name", field, name)
+	}
+	return nil
+}
+
+func (t *GitBranchTool) list(ctx context.Context) (tools.ToolResult, error) {
+	out, err := runGit(ctx, t.workspace, "", "branch", "--format=%(HEAD)\x1f%(refname:short)\x1f%(objectname:short)\x1f%(upstream:short)")
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	current := ""
+	branches := make([]map[string]any, 0)
+	for _, line := range gitLines(out) {
+		parts := strings.SplitN(line, "\x1f", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		branch := map[string]any{"name": parts[1], "commit": parts[2]}
+		if parts[3] != "" {
+			branch["upstream"] = parts[3]
+		}
+		if parts[0] == "*" {
+			current = parts[1]
+		}
+		branches = append(branches, branch)
+	}
+	result := map[string]any{"current": current, "branches": branches}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
@@ -0,0 +1,187 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/hooks"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// CommitMetadataKey is the tool result metadata key git_commit records the
+// new commit under.
+const CommitMetadataKey = "commit"
+
+// GitCommit describes a commit made by git_commit.
+type GitCommit struct {
+	Hash    string   `json:"hash"`
+	Branch  string   `json:"branch"`
+	Subject string   `json:"subject"`
+	Message string   `json:"message"`
+	Files   []string `json:"files"`
+	Amend   bool     `json:"amend,omitempty"`
+}
+
+// CommitFrom returns the commit recorded in tool result metadata. It accepts
+// the GitCommit git_commit stores and the map[string]any the same value
+// becomes after a JSON round trip.
+func CommitFrom(metadata map[string]any) (GitCommit, bool) {
+	switch v := metadata[CommitMetadataKey].(type) {
+	case GitCommit:
+		return v, true
+	case map[string]any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return GitCommit{}, false
+		}
+		var c GitCommit
+		if json.Unmarshal(raw, &c) != nil || c.Hash == "" {
+			return GitCommit{}, false
+		}
+		return c, true
+	default:
+		return GitCommit{}, false
+	}
+}
+
+// GitCommitTool commits the staged changes after checking the message
+// against the project's commit convention and running PreCommit hooks.
+type GitCommitTool struct {
+	BaseTool
+	workspace  string
+	convention CommitConvention
+	preCommit  *hooks.Executor
+}
+
+const gitCommitDescription = "Commit the staged changes. Pass paths to stage them first. The message is checked against the project's commit convention and PreCommit hooks run before the commit is made."
+
+// NewGitCommitTool creates a GitCommitTool bound to the given workspace.
+func NewGitCommitTool(workspace string) *GitCommitTool {
+	return &GitCommitTool{
+		BaseTool: BaseTool{
+			ToolName:        "git_commit",
+			ToolDescription: gitCommitDescription,
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"message": {
+						"type": "string",
+						"description": "Commit message: a subject line, then a blank line and an optional body."
+					},
+					"paths": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Files to stage before committing."
+					},
+					"amend": {
+						"type": "boolean",
+						"description": "Replace the last commit. Rewrites history, so it is refused unless permitted."
+					},
+					"allow_empty": {
+						"type": "boolean",
+						"description": "Allow a commit with no changes."
+					}
+				},
+				"required": ["message"]
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			Interrupt:       tools.InterruptBlock,
+			RequiredFields:  []string{"message"},
+		},
+		workspace: workspace,
+	}
+}
+
+// ConfigureGitCommit sets the commit convention and PreCommit hooks on the
+// registered git_commit tool. preCommit may be nil.
+func ConfigureGitCommit(registry *tools.Registry, convention CommitConvention, preCommit *hooks.Executor) {
+	tool, ok := registry.Get("git_commit")
+	if !ok {
+		return
+	}
+	if t, ok := tool.(*GitCommitTool); ok {
+		t.convention = convention
+		t.preCommit = preCommit
+		t.ToolDescription = gitCommitDescription
+		if desc := convention.Describe(); desc != "" {
+			t.ToolDescription += " Convention: " + desc + "."
+		}
+	}
+}
+
+func (t *GitCommitTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	if err := t.ValidateInput(input); err != nil {
+		return errResult(err.Error()), nil
+	}
+	message := strings.TrimSpace(getStringArg(input, "message", ""))
+	amend := getBoolArg(input, "amend", false)
+	allowEmpty := getBoolArg(input, "allow_empty", false)
+
+	if amend && !permissions.GitPolicyFromContext(ctx).AllowHistoryRewrite {
+		return errResult("git commit --amend rewrites history and is not permitted; " + rewriteHint), nil
+	}
+	if err := t.convention.Check(message); err != nil {
+		msg := "commit message rejected: " + err.Error()
+		if desc := t.convention.Describe(); desc != "" {
+			msg += " (convention: " + desc + ")"
+		}
+		return errResult(msg), nil
+	}
+
+	paths, err := gitPathArgs(t.workspace, stringListArg(input, "paths"))
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	if len(paths) > 0 {
+		if _, err := runGit(ctx, t.workspace, "", append([]string{"add", "--"}, paths...)...); err != nil {
+			return errResult(err.Error()), nil
+		}
+	}
+	staged, err := stagedFiles(ctx, t.workspace)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	if len(staged) == 0 && !amend && !allowEmpty {
+		return errResult("nothing staged to commit; stage files with git_add or pass paths"), nil
+	}
+
+	if t.preCommit != nil {
+		res, err := t.preCommit.RunPreCommit(message, staged)
+		if err != nil {
+			return errResult(fmt.Sprintf("PreCommit hook failed: %v", err)), nil
+		}
+		if res.Decision == "block" {
+			return errResult("PreCommit hook blocked the commit: " + res.Output), nil
+		}
+	}
+
+	args := []string{"commit", "-F", "-"}
+	if amend {
+		args = append(args, "--amend")
+	}
+	if allowEmpty {
+		args = append(args, "--allow-empty")
+	}
+	if t.convention.Signoff {
+		args = append(args, "--signoff")
+	}
+	if _, err := runGit(ctx, t.workspace, message+"\n", args...); err != nil {
+		return errResult(err.Error()), nil
+	}
+
+	commit := GitCommit{Message: message, Amend: amend}
+	commit.Subject, _, _ = strings.Cut(message, "\n")
+	if commit.Hash, err = runGit(ctx, t.workspace, "", "rev-parse", "HEAD"); err != nil {
+		return errResult(err.Error()), nil
+	}
+	commit.Branch, _ = runGit(ctx, t.workspace, "", "rev-parse", "--abbrev-ref", "HEAD")
+	files, _ := runGit(ctx, t.workspace, "", "diff-tree", "--no-commit-id", "--name-only", "-r", "--root", "HEAD")
+	commit.Files = append([]string{}, gitLines(files)...)
+
+	result := map[string]any{CommitMetadataKey: commit}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
@@ -0,0 +1,250 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/hooks"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+func TestCommitConventionFromGrimoire(t *testing.T) {
+	g := parseGrimoire(t, "## Settings\n- commit_style: conventional\n- commit_types: feat, fix\n- commit_max_subject: 30\n- commit_pattern: \\(core\\)\n")
+	c, err := CommitConventionFromGrimoire(g)
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Check("fix(core): handle nil\n\nLonger body."))
+	assert.ErrorContains(t, c.Check("Fix the parser"), "not a conventional commit")
+	assert.ErrorContains(t, c.Check("docs(core): readme"), `commit type "docs" is not one of feat, fix`)
+	assert.ErrorContains(t, c.Check("fix(core): x\nbody"), "blank line")
+	assert.ErrorContains(t, c.Check("fix(core): a subject that is far too long"), "limit is 30")
+	assert.ErrorContains(t, c.Check("fix(cli): x"), "commit_pattern")
+	assert.ErrorContains(t, c.Check("  "), "empty")
+
+	_, err = CommitConventionFromGrimoire(parseGrimoire(t, "## Settings\n- commit_style: emoji\n"))
+	assert.Error(t, err)
+
+	var none CommitConvention
+	assert.NoError(t, none.Check("anything goes"))
+	assert.Empty(t, none.Describe())
+}
+
+func TestGitCommitTool(t *testing.T) {
+	dir := initGitRepo(t)
+	tool := NewGitCommitTool(dir)
+	assert.False(t, tool.IsReadOnly())
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, map[string]any{"message": "add a"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "nothing staged")
+
+	registry := tools.NewRegistry()
+	registry.Register(tool)
+	convention, err := CommitConventionFromGrimoire(parseGrimoire(t, "## Settings\n- commit_style: conventional\n- commit_signoff: true\n"))
+	require.NoError(t, err)
+	preCommit := hooks.NewExecutor([]hooks.Hook{{
+		Event:   "PreCommit",
+		Tool:    "*",
+		Command: `case "$CELESTE_COMMIT_FILES" in *secret*) echo "no secrets"; exit 1;; esac`,
+		Timeout: 5,
+	}}, dir)
+	ConfigureGitCommit(registry, convention, preCommit)
+	assert.Contains(t, tool.Description(), "conventional commits")
+
+	writeFiles(t, dir, map[string]string{"a.txt": "a\n", "secret.txt": "key\n"})
+
+	res, err = tool.Execute(ctx, map[string]any{"message": "add a", "paths": []any{"a.txt"}}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "not a conventional commit")
+
+	res, err = tool.Execute(ctx, map[string]any{"message": "chore: add secret", "paths": []any{"secret.txt"}}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "PreCommit hook blocked the commit: no secrets")
+	restore := exec.Command("git", "reset", "-q", "--", "secret.txt")
+	restore.Dir = dir
+	require.NoError(t, restore.Run())
+
+	res, err = tool.Execute(ctx, map[string]any{"message": "feat: add a\n\nWith a body.", "paths": []any{"a.txt"}}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	commit, ok := CommitFrom(res.Metadata)
+	require.True(t, ok)
+	assert.Equal(t, "feat: add a", commit.Subject)
+	assert.Equal(t, []string{"a.txt"}, commit.Files)
+	assert.Len(t, commit.Hash, 40)
+	assert.NotEmpty(t, commit.Branch)
+
+	var roundTrip map[string]any
+	require.NoError(t, json.Unmarshal([]byte(res.Content), &roundTrip))
+	fromJSON, ok := CommitFrom(roundTrip)
+	require.True(t, ok)
+	assert.Equal(t, commit, fromJSON)
+
+	log := exec.Command("git", "log", "-1", "--format=%B")
+	log.Dir = dir
+	out, err := log.Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "With a body.")
+	assert.Contains(t, string(out), "Signed-off-by: Test User")
+
+	// Amending rewrites history and needs the git policy.
+	res, err = tool.Execute(ctx, map[string]any{"message": "feat: add a, amended", "amend": true}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "allow_history_rewrite")
+
+	allowed := permissions.WithGitPolicy(ctx, permissions.GitPolicy{AllowHistoryRewrite: true})
+	res, err = tool.Execute(allowed, map[string]any{"message": "feat: add a, amended", "amend": true}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	amended, _ := CommitFrom(res.Metadata)
+	assert.True(t, amended.Amend)
+	assert.NotEqual(t, commit.Hash, amended.Hash)
+}
+
+func TestGitDiffAndAddTools(t *testing.T) {
+	dir := initGitRepo(t)
+	ctx := context.Background()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello\nworld\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new\n"), 0644))
+
+	diff := NewGitDiffTool(dir)
+	assert.True(t, diff.IsReadOnly())
+	res, err := diff.Execute(ctx, map[string]any{}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	data := decodeResult(t, res)
+	assert.Equal(t, []any{map[string]any{"path": "file.txt", "added": float64(1), "deleted": float64(0)}}, data["files"])
+	assert.Contains(t, data["diff"], "+world")
+
+	res, err = NewGitAddTool(dir).Execute(ctx, map[string]any{"all": true}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Equal(t, []any{"file.txt", "new.txt"}, decodeResult(t, res)["staged"])
+
+	res, err = diff.Execute(ctx, map[string]any{"staged": true, "stat": true}, nil)
+	require.NoError(t, err)
+	data = decodeResult(t, res)
+	assert.Len(t, data["files"], 2)
+	assert.Nil(t, data["diff"])
+
+	res, err = diff.Execute(ctx, map[string]any{"ref": "--output=/tmp/x"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+
+	res, err = NewGitAddTool(dir).Execute(ctx, map[string]any{"paths": []any{"../outside"}}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+}
+
+func TestGitBranchTool(t *testing.T) {
+	dir := initGitRepo(t)
+	ctx := context.Background()
+	tool := NewGitBranchTool(dir)
+
+	res, err := tool.Execute(ctx, map[string]any{"action": "create", "name": "feature", "switch": true}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Equal(t, "feature", decodeResult(t, res)["current"])
+
+	res, err = tool.Execute(ctx, map[string]any{"action": "create", "name": "bad..name"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+
+	res, err = tool.Execute(ctx, map[string]any{"action": "rename", "name": "feature", "new_name": "feature-2"}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Equal(t, "feature-2", decodeResult(t, res)["current"])
+
+	writeFiles(t, dir, map[string]string{"f.txt": "f\n"})
+	for _, args := range [][]string{{"add", "f.txt"}, {"commit", "-m", "on feature"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		require.NoError(t, cmd.Run())
+	}
+	base := strings.TrimSpace(runGitOutput(t, dir, "rev-parse", "--abbrev-ref", "@{-1}"))
+	res, err = tool.Execute(ctx, map[string]any{"action": "switch", "name": base}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+
+	res, err = tool.Execute(ctx, map[string]any{"action": "delete", "name": "feature-2"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error, "unmerged branch")
+
+	res, err = tool.Execute(ctx, map[string]any{"action": "delete", "name": "feature-2", "force": true}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "allow_history_rewrite")
+
+	allowed := permissions.WithGitPolicy(ctx, permissions.GitPolicy{AllowHistoryRewrite: true})
+	res, err = tool.Execute(allowed, map[string]any{"action": "delete", "name": "feature-2", "force": true}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Len(t, decodeResult(t, res)["branches"], 1)
+}
+
+func TestGitStashAndRestoreTools(t *testing.T) {
+	dir := initGitRepo(t)
+	ctx := context.Background()
+	stash := NewGitStashTool(dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("changed\n"), 0644))
+
+	res, err := stash.Execute(ctx, map[string]any{"action": "push", "message": "wip"}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	stashes := decodeResult(t, res)["stashes"].([]any)
+	require.Len(t, stashes, 1)
+	assert.Contains(t, stashes[0].(map[string]any)["message"], "wip")
+	assert.Equal(t, "hello\n", mustRead(t, filepath.Join(dir, "file.txt")))
+
+	res, err = stash.Execute(ctx, map[string]any{"action": "show"}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, decodeResult(t, res)["diff"], "+changed")
+
+	res, err = stash.Execute(ctx, map[string]any{"action": "pop"}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Empty(t, decodeResult(t, res)["stashes"])
+	assert.Equal(t, "changed\n", mustRead(t, filepath.Join(dir, "file.txt")))
+
+	restore := NewGitRestoreTool(dir)
+	res, err = restore.Execute(ctx, map[string]any{"paths": []any{"file.txt"}}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Equal(t, []any{"file.txt"}, decodeResult(t, res)["restored"])
+	assert.Equal(t, "hello\n", mustRead(t, filepath.Join(dir, "file.txt")))
+
+	res, err = restore.Execute(ctx, map[string]any{"paths": []any{"file.txt"}, "source": "--help"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+}
+
+func parseGrimoire(t *testing.T, content string) *grimoire.Grimoire {
+	t.Helper()
+	g, err := grimoire.Parse(content, t.TempDir())
+	require.NoError(t, err)
+	return g
+}
+
+func runGitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	return string(out)
+}
@@ -0,0 +1,130 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+)
+
+// defaultCommitTypes are the conventional commit types allowed when the
+// grimoire sets commit_style: conventional without commit_types.
+var defaultCommitTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+var conventionalSubjectRe = regexp.MustCompile(`^([a-z]+)(\([^()]+\))?(!)?: \S`)
+
+// CommitConvention is the commit-message policy git_commit enforces. The
+// zero value only requires a non-empty message.
+type CommitConvention struct {
+	Style      string         // "conventional" or ""
+	Types      []string       // allowed types for the conventional style
+	Pattern    *regexp.Regexp // the subject line must match, if set
+	MaxSubject int            // subject length limit, 0 for none
+	Signoff    bool           // add a Signed-off-by trailer
+}
+
+// CommitConventionFromGrimoire reads the commit_* keys from the grimoire's
+// ## Settings:
+//
+//   - commit_style: conventional
+//   - commit_types: feat, fix, docs
+//   - commit_pattern: ^[A-Z]+-\d+ (regexp for the subject line)
+//   - commit_max_subject: 72
+//   - commit_signoff: true
+//
+// g may be nil.
+func CommitConventionFromGrimoire(g *grimoire.Grimoire) (CommitConvention, error) {
+	var c CommitConvention
+	if g == nil {
+		return c, nil
+	}
+	switch style := strings.ToLower(g.Setting("commit_style")); style {
+	case "", "none", "free":
+	case "conventional":
+		c.Style = style
+		c.Types = defaultCommitTypes
+	default:
+		return c, fmt.Errorf("unknown commit_style %q (use conventional)", style)
+	}
+	if types := g.Setting("commit_types"); types != "" {
+		c.Types = nil
+		for _, t := range strings.FieldsFunc(types, func(r rune) bool { return r == ',' || r == ' ' }) {
+			c.Types = append(c.Types, strings.ToLower(t))
+		}
+	}
+	if p := g.Setting("commit_pattern"); p != "" {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return c, fmt.Errorf("commit_pattern: %w", err)
+		}
+		c.Pattern = re
+	}
+	if n := g.Setting("commit_max_subject"); n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil || v < 0 {
+			return c, fmt.Errorf("commit_max_subject %q is not a length", n)
+		}
+		c.MaxSubject = v
+	}
+	if s := g.Setting("commit_signoff"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return c, fmt.Errorf("commit_signoff %q is not true or false", s)
+		}
+		c.Signoff = v
+	}
+	return c, nil
+}
+
+// Check returns why message breaks the convention, or nil.
+func (c CommitConvention) Check(message string) error {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return errors.New("commit message is empty")
+	}
+	lines := strings.Split(message, "\n")
+	subject := strings.TrimSpace(lines[0])
+
+	if c.Style == "conventional" {
+		m := conventionalSubjectRe.FindStringSubmatch(subject)
+		if m == nil {
+			return fmt.Errorf("subject %q is not a conventional commit (type(scope): description)", subject)
+		}
+		if len(c.Types) > 0 && !slices.Contains(c.Types, m[1]) {
+			return fmt.Errorf("commit type %q is not one of %s", m[1], strings.Join(c.Types, ", "))
+		}
+		if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+			return errors.New("the subject must be followed by a blank line before the body")
+		}
+	}
+	if c.MaxSubject > 0 && len([]rune(subject)) > c.MaxSubject {
+		return fmt.Errorf("subject is %d characters; the limit is %d", len([]rune(subject)), c.MaxSubject)
+	}
+	if c.Pattern != nil && !c.Pattern.MatchString(subject) {
+		return fmt.Errorf("subject %q does not match commit_pattern %s", subject, c.Pattern)
+	}
+	return nil
+}
+
+// Describe summarises the convention for the tool description and errors,
+// or returns "" when there is none.
+func (c CommitConvention) Describe() string {
+	var parts []string
+	if c.Style == "conventional" {
+		parts = append(parts, "conventional commits (type(scope): description) with types "+strings.Join(c.Types, ", "))
+	}
+	if c.Pattern != nil {
+		parts = append(parts, "subject matching "+c.Pattern.String())
+	}
+	if c.MaxSubject > 0 {
+		parts = append(parts, fmt.Sprintf("subject at most %d characters", c.MaxSubject))
+	}
+	if c.Signoff {
+		parts = append(parts, "Signed-off-by trailer added automatically")
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,120 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// gitDiffMaxBytes caps the diff text returned to the model; the per-file
+// numstat is always complete.
+const gitDiffMaxBytes = 64 * 1024
+
+// GitDiffTool shows unstaged, staged or ref-to-ref changes in the workspace.
+type GitDiffTool struct {
+	BaseTool
+	workspace string
+}
+
+// NewGitDiffTool creates a GitDiffTool bound to the given workspace.
+func NewGitDiffTool(workspace string) *GitDiffTool {
+	return &GitDiffTool{
+		BaseTool: BaseTool{
+			ToolName:        "git_diff",
+			ToolDescription: "Show git changes in the workspace: unstaged by default, staged with staged=true, or against a ref or range such as 'main' or 'HEAD~3..HEAD'.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"staged": {
+						"type": "boolean",
+						"description": "Show changes staged for the next commit."
+					},
+					"ref": {
+						"type": "string",
+						"description": "Commit, branch or range to diff against."
+					},
+					"paths": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Limit the diff to these files or directories."
+					},
+					"stat": {
+						"type": "boolean",
+						"description": "Only list changed files with line counts."
+					},
+					"context": {
+						"type": "number",
+						"description": "Lines of context around each change (default 3)."
+					}
+				},
+				"required": []
+			}`),
+			ReadOnly:        true,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptCancel,
+		},
+		workspace: workspace,
+	}
+}
+
+func (t *GitDiffTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if getBoolArg(input, "staged", false) {
+		args = append(args, "--cached")
+	}
+	if n := getIntArg(input, "context", -1); n >= 0 {
+		args = append(args, "-U"+strconv.Itoa(n))
+	}
+	if ref := getStringArg(input, "ref", ""); ref != "" {
+		if err := checkGitRef("ref", ref); err != nil {
+			return errResult(err.Error()), nil
+		}
+		args = append(args, ref)
+	}
+	paths, err := gitPathArgs(t.workspace, stringListArg(input, "paths"))
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	pathArgs := append([]string{"--"}, paths...)
+
+	numstat, err := runGit(ctx, t.workspace, "", append(append(append([]string{}, args...), "--numstat"), pathArgs...)...)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	files := make([]map[string]any, 0)
+	for _, line := range gitLines(numstat) {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		file := map[string]any{"path": parts[2]}
+		if parts[0] == "-" {
+			file["binary"] = true
+		} else {
+			file["added"], _ = strconv.Atoi(parts[0])
+			file["deleted"], _ = strconv.Atoi(parts[1])
+		}
+		files = append(files, file)
+	}
+
+	result := map[string]any{"files": files}
+	if !getBoolArg(input, "stat", false) && len(files) > 0 {
+		diff, err := runGit(ctx, t.workspace, "", append(args, pathArgs...)...)
+		if err != nil {
+			return errResult(err.Error()), nil
+		}
+		if len(diff) > gitDiffMaxBytes {
+			cut := strings.LastIndexByte(diff[:gitDiffMaxBytes], '\n')
+			if cut < 0 {
+				cut = gitDiffMaxBytes
+			}
+			diff = diff[:cut]
+			result["truncated"] = true
+		}
+		result["diff"] = diff
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
@@ -0,0 +1,134 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// GitRestoreTool discards changes to files with git restore.
+type GitRestoreTool struct {
+	BaseTool
+	workspace string
+	snapMgr   *checkpoints.SnapshotManager
+}
+
+// NewGitRestoreTool creates a GitRestoreTool bound to the given workspace.
+func NewGitRestoreTool(workspace string, opts ...GitRestoreOption) *GitRestoreTool {
+	t := &GitRestoreTool{
+		BaseTool: BaseTool{
+			ToolName:        "git_restore",
+			ToolDescription: "Discard changes to files with git restore: unstage them (staged=true), reset their contents (worktree, the default), or both. source restores the files as of a commit.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"paths": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Files or directories to restore."
+					},
+					"staged": {
+						"type": "boolean",
+						"description": "Unstage the files (restore the index)."
+					},
+					"worktree": {
+						"type": "boolean",
+						"description": "Reset the working tree files (default true unless staged is set)."
+					},
+					"source": {
+						"type": "string",
+						"description": "Commit to restore from (default the index, or HEAD with staged)."
+					}
+				},
+				"required": ["paths"]
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			Interrupt:       tools.InterruptBlock,
+			RequiredFields:  []string{"paths"},
+		},
+		workspace: workspace,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// GitRestoreOption configures optional dependencies for GitRestoreTool.
+type GitRestoreOption func(*GitRestoreTool)
+
+// WithGitRestoreSnapshots attaches a SnapshotManager so discarded working
+// tree changes can be reverted.
+func WithGitRestoreSnapshots(sm *checkpoints.SnapshotManager) GitRestoreOption {
+	return func(t *GitRestoreTool) {
+		t.snapMgr = sm
+	}
+}
+
+func (t *GitRestoreTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	paths, err := gitPathArgs(t.workspace, stringListArg(input, "paths"))
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	if len(paths) == 0 {
+		return errResult("missing required field: paths"), nil
+	}
+	staged := getBoolArg(input, "staged", false)
+	worktree := getBoolArg(input, "worktree", !staged)
+	if !staged && !worktree {
+		return errResult("nothing to restore: set staged, worktree or both"), nil
+	}
+	source := getStringArg(input, "source", "")
+	if err := checkGitRef("source", source); err != nil {
+		return errResult(err.Error()), nil
+	}
+
+	args := []string{"restore"}
+	if staged {
+		args = append(args, "--staged")
+	}
+	if worktree {
+		args = append(args, "--worktree")
+	}
+	if source != "" {
+		args = append(args, "--source="+source)
+	}
+	args = append(append(args, "--"), paths...)
+
+	// List what will change first: the snapshots keep the discarded
+	// working tree contents, and the result reports the files.
+	diffArgs := []string{"diff", "--name-only", "--relative"}
+	switch {
+	case source != "" && !worktree:
+		diffArgs = append(diffArgs, "--cached", source)
+	case source != "":
+		diffArgs = append(diffArgs, source)
+	case staged && worktree:
+		diffArgs = append(diffArgs, "HEAD")
+	case staged:
+		diffArgs = append(diffArgs, "--cached")
+	}
+	out, err := runGit(ctx, t.workspace, "", append(append(diffArgs, "--"), paths...)...)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	changed := append([]string{}, gitLines(out)...)
+	if worktree && t.snapMgr != nil {
+		for _, f := range changed {
+			if err := t.snapMgr.Snapshot(filepath.Join(t.workspace, filepath.FromSlash(f))); err != nil {
+				return errResult(fmt.Sprintf("snapshot failed: %s", err)), nil
+			}
+		}
+	}
+
+	if _, err := runGit(ctx, t.workspace, "", args...); err != nil {
+		return errResult(err.Error()), nil
+	}
+	result := map[string]any{"restored": changed, "staged": staged, "worktree": worktree}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
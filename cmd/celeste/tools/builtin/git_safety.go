@@ -0,0 +1,212 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+)
+
+// gitTimeout bounds one git invocation from the git tools. Commits can
+// run the repository's own hooks, so it is generous.
+const gitTimeout = 2 * time.Minute
+
+// runGit runs git in dir under the sandbox policy from ctx and returns its
+// stdout with trailing whitespace removed. Paths in the output are not
+// quoted. On failure the error carries git's stderr.
+func runGit(ctx context.Context, dir, stdin string, args ...string) (string, error) {
+	policy := sandbox.PolicyFromContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, policy.Timeout(gitTimeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-c", "core.quotePath=false"}, args...)...)
+	cmd.Dir = dir
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	cleanup, err := policy.Wrap(cmd)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("git is not installed or not on PATH")
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimRight(stdout.String(), " \t\r\n"), nil
+}
+
+// gitLines splits git output into non-empty lines.
+func gitLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// checkGitRef rejects refs that git would read as options.
+func checkGitRef(field, ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("%s %q must not start with '-'", field, ref)
+	}
+	return nil
+}
+
+// gitPathArgs confines paths to the workspace and returns them relative
+// to it, ready to follow "--".
+func gitPathArgs(workspace string, paths []string) ([]string, error) {
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		abs, err := resolvePath(workspace, p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, relPath(workspace, abs))
+	}
+	return out, nil
+}
+
+const rewriteHint = `set "git": {"allow_history_rewrite": true} in ~/.celeste/permissions.json to allow it`
+
+var (
+	shellSeparatorRe = regexp.MustCompile(`&&|\|\||[;|\n&()]`)
+	gitRevisionRe    = regexp.MustCompile(`^(HEAD|@|ORIG_HEAD|FETCH_HEAD)?([~^]\d*)+$|^[0-9a-f]{7,40}$|^(HEAD|@)@\{\d+\}$`)
+)
+
+// checkGitRewrite inspects a shell command for git invocations that
+// rewrite history or force-push, and returns why it is refused under
+// policy, or "" when it is allowed. It only sees the git commands written
+// out in the command line, so it is a rail against accidents, not a
+// sandbox.
+func checkGitRewrite(command string, policy permissions.GitPolicy) string {
+	for _, segment := range shellSeparatorRe.Split(command, -1) {
+		fields := strings.Fields(segment)
+		i := 0
+		for i < len(fields) && fields[i] != "git" {
+			i++
+		}
+		if i == len(fields) {
+			continue
+		}
+		// Skip git's global options: -C dir, -c key=value, --no-pager...
+		i++
+		for i < len(fields) && strings.HasPrefix(fields[i], "-") {
+			if fields[i] == "-C" || fields[i] == "-c" {
+				i++
+			}
+			i++
+		}
+		if i >= len(fields) {
+			continue
+		}
+		sub, args := fields[i], fields[i+1:]
+		if sub == "push" {
+			if !policy.AllowForcePush && isForcePush(args) {
+				return `git force push is not permitted; set "git": {"allow_force_push": true} in ~/.celeste/permissions.json to allow it`
+			}
+			continue
+		}
+		if !policy.AllowHistoryRewrite {
+			if what := gitRewrite(sub, args); what != "" {
+				return what + " rewrites history and is not permitted; " + rewriteHint
+			}
+		}
+	}
+	return ""
+}
+
+func isForcePush(args []string) bool {
+	for _, a := range args {
+		switch {
+		case a == "-f", a == "--force", strings.HasPrefix(a, "--force-with-lease"), a == "--force-if-includes",
+			a == "--mirror":
+			return true
+		case strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") && strings.Contains(a, "f"):
+			return true // combined short flags such as -uf
+		case strings.HasPrefix(a, "+"):
+			return true // +refspec
+		}
+	}
+	return false
+}
+
+// gitRewrite names the history rewrite git sub args performs, or "".
+func gitRewrite(sub string, args []string) string {
+	has := func(flags ...string) bool {
+		for _, a := range args {
+			for _, f := range flags {
+				if a == f || strings.HasPrefix(a, f+"=") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	switch sub {
+	case "commit":
+		if has("--amend") {
+			return "git commit --amend"
+		}
+	case "rebase":
+		if !has("--abort", "--quit") {
+			return "git rebase"
+		}
+	case "reset":
+		// Moving the branch is the rewrite; "git reset --hard" alone only
+		// discards uncommitted changes. With a mode flag every positional
+		// argument is a commit; without one it could be a path.
+		moded := has("--hard", "--soft", "--keep", "--merge", "--mixed")
+		for _, a := range args {
+			if a == "--" {
+				break
+			}
+			if strings.HasPrefix(a, "-") || a == "HEAD" || a == "@" {
+				continue
+			}
+			if moded || gitRevisionRe.MatchString(a) {
+				return "git reset " + a
+			}
+		}
+	case "filter-branch", "filter-repo", "replace", "update-ref":
+		return "git " + sub
+	case "reflog":
+		if len(args) > 0 && (args[0] == "expire" || args[0] == "delete") {
+			return "git reflog " + args[0]
+		}
+	case "branch":
+		if has("-D", "-M", "-f", "--force") {
+			return "git branch " + strings.Join(args, " ")
+		}
+	case "checkout":
+		if has("-B") {
+			return "git checkout -B"
+		}
+	case "switch":
+		if has("-C", "--force-create") {
+			return "git switch -C"
+		}
+	}
+	return ""
+}
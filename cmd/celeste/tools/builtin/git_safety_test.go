@@ -0,0 +1,62 @@
+package builtin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+)
+
+func TestCheckGitRewrite(t *testing.T) {
+	refused := []string{
+		"git push --force origin main",
+		"git push -f",
+		"git push origin +main",
+		"git push --force-with-lease",
+		"cd repo && git -C sub push -uf origin main",
+		"git commit --amend --no-edit",
+		"git rebase -i HEAD~3",
+		"git reset --hard HEAD~1",
+		"git reset --soft abc1234",
+		"git reset HEAD^",
+		"git filter-branch --tree-filter 'rm secret' HEAD",
+		"git reflog expire --expire=now --all",
+		"git branch -D feature",
+		"git checkout -B main",
+		"true; git --no-pager update-ref refs/heads/main HEAD~2",
+	}
+	for _, cmd := range refused {
+		assert.NotEmpty(t, checkGitRewrite(cmd, permissions.GitPolicy{}), cmd)
+	}
+
+	allowed := []string{
+		"git push origin main",
+		"git push -u origin feature",
+		"git commit -m 'fix: thing'",
+		"git rebase --abort",
+		"git reset --hard",
+		"git reset HEAD file.txt",
+		"git reset -- file.txt",
+		"git branch -d merged",
+		"git status",
+	}
+	for _, cmd := range allowed {
+		assert.Empty(t, checkGitRewrite(cmd, permissions.GitPolicy{}), cmd)
+	}
+
+	assert.Contains(t, checkGitRewrite("git push -f", permissions.GitPolicy{AllowHistoryRewrite: true}), "allow_force_push")
+	assert.Empty(t, checkGitRewrite("git push -f", permissions.GitPolicy{AllowForcePush: true}))
+	assert.Empty(t, checkGitRewrite("git rebase main", permissions.GitPolicy{AllowHistoryRewrite: true}))
+	assert.Contains(t, checkGitRewrite("git rebase main", permissions.GitPolicy{AllowForcePush: true}), "allow_history_rewrite")
+}
+
+func TestBashToolRefusesGitRewrite(t *testing.T) {
+	tool := NewBashTool(t.TempDir())
+	res, err := tool.Execute(context.Background(), map[string]any{"command": "git commit --amend -m x"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "rewrites history")
+}
@@ -0,0 +1,122 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// GitStashTool saves and restores uncommitted changes with git stash.
+type GitStashTool struct {
+	BaseTool
+	workspace string
+}
+
+// NewGitStashTool creates a GitStashTool bound to the given workspace.
+func NewGitStashTool(workspace string) *GitStashTool {
+	return &GitStashTool{
+		BaseTool: BaseTool{
+			ToolName:        "git_stash",
+			ToolDescription: "Set uncommitted changes aside with git stash and bring them back later. Actions: push, list, show, apply, pop, drop.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"action": {
+						"type": "string",
+						"enum": ["push", "list", "show", "apply", "pop", "drop"],
+						"description": "What to do (default list)."
+					},
+					"message": {
+						"type": "string",
+						"description": "Description for push."
+					},
+					"include_untracked": {
+						"type": "boolean",
+						"description": "Also stash untracked files on push."
+					},
+					"paths": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Only stash these files on push."
+					},
+					"index": {
+						"type": "number",
+						"description": "Stash entry for show, apply, pop and drop (default 0, the latest)."
+					}
+				},
+				"required": []
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			Interrupt:       tools.InterruptBlock,
+		},
+		workspace: workspace,
+	}
+}
+
+func (t *GitStashTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	action := getStringArg(input, "action", "list")
+	index := getIntArg(input, "index", 0)
+	if index < 0 {
+		return errResult("index must not be negative"), nil
+	}
+	entry := fmt.Sprintf("stash@{%d}", index)
+
+	var (
+		out string
+		err error
+	)
+	switch action {
+	case "list":
+	case "push":
+		args := []string{"stash", "push"}
+		if msg := getStringArg(input, "message", ""); msg != "" {
+			args = append(args, "--message", msg)
+		}
+		if getBoolArg(input, "include_untracked", false) {
+			args = append(args, "--include-untracked")
+		}
+		paths, perr := gitPathArgs(t.workspace, stringListArg(input, "paths"))
+		if perr != nil {
+			return errResult(perr.Error()), nil
+		}
+		if len(paths) > 0 {
+			args = append(append(args, "--"), paths...)
+		}
+		out, err = runGit(ctx, t.workspace, "", args...)
+	case "show":
+		out, err = runGit(ctx, t.workspace, "", "stash", "show", "--stat", "--patch", "--no-color", entry)
+		if err == nil {
+			result := map[string]any{"stash": entry, "diff": out}
+			if len(out) > gitDiffMaxBytes {
+				result["diff"], result["truncated"] = out[:gitDiffMaxBytes], true
+			}
+			return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+		}
+	case "apply", "pop", "drop":
+		out, err = runGit(ctx, t.workspace, "", "stash", action, entry)
+	default:
+		return errResult(fmt.Sprintf("unknown action %q (use push, list, show, apply, pop or drop)", action)), nil
+	}
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+
+	list, err := runGit(ctx, t.workspace, "", "stash", "list", "--format=%gd\x1f%s")
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	stashes := make([]map[string]string, 0)
+	for _, line := range gitLines(list) {
+		ref, msg, _ := strings.Cut(line, "\x1f")
+		stashes = append(stashes, map[string]string{"stash": ref, "message": msg})
+	}
+	result := map[string]any{"stashes": stashes}
+	if out != "" {
+		result["output"] = out
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
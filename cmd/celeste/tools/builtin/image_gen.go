@@ -0,0 +1,197 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// RegisterImageTools registers generate_image and edit_image, which write
+// into store. Relative image paths resolve against workspace.
+func RegisterImageTools(registry *tools.Registry, gen media.ImageGenerator, store *media.Store, workspace string) {
+	registry.RegisterWithModes(NewGenerateImageTool(gen, store), tools.ModeChat, tools.ModeClaw, tools.ModeAgent)
+	registry.RegisterWithModes(NewEditImageTool(gen, store, workspace), tools.ModeChat, tools.ModeClaw, tools.ModeAgent)
+}
+
+// GenerateImageTool creates images from a prompt.
+type GenerateImageTool struct {
+	BaseTool
+	gen   media.ImageGenerator
+	store *media.Store
+}
+
+// NewGenerateImageTool creates a GenerateImageTool.
+func NewGenerateImageTool(gen media.ImageGenerator, store *media.Store) *GenerateImageTool {
+	return &GenerateImageTool{
+		BaseTool: BaseTool{
+			ToolName:        "generate_image",
+			ToolDescription: "Generate images from a text prompt. The images are saved with the session's assets and shown to the user; the result lists their file paths, which edit_image accepts.",
+			ToolParameters: mustJSON(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"prompt": map[string]any{
+						"type":        "string",
+						"description": "What the image should show, in detail",
+					},
+					"size": map[string]any{
+						"type":        "string",
+						"description": "WIDTHxHEIGHT, e.g. 1024x1024 or 1536x1024 (default: the backend's)",
+					},
+					"count": map[string]any{
+						"type":        "integer",
+						"description": "Number of variants, 1-4 (default: 1)",
+					},
+					"negative_prompt": map[string]any{
+						"type":        "string",
+						"description": "What to keep out of the image, where the backend supports it",
+					},
+					"model": map[string]any{
+						"type":        "string",
+						"description": "Image model (default: the configured one)",
+					},
+				},
+				"required": []string{"prompt"},
+			}),
+			ReadOnly:        false,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptCancel,
+			RequiredFields:  []string{"prompt"},
+		},
+		gen:   gen,
+		store: store,
+	}
+}
+
+func (t *GenerateImageTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	req := media.ImageRequest{
+		Prompt:         getStringArg(input, "prompt", ""),
+		Model:          getStringArg(input, "model", ""),
+		Size:           getStringArg(input, "size", ""),
+		Count:          min(max(getIntArg(input, "count", 1), 1), 4),
+		NegativePrompt: getStringArg(input, "negative_prompt", ""),
+	}
+	images, err := t.gen.Generate(ctx, req)
+	if err != nil {
+		return tools.ToolResult{}, fmt.Errorf("image generation failed: %w", err)
+	}
+	return saveImageResult(t.store, images, media.Asset{Prompt: req.Prompt, Backend: t.gen.Name(), Model: req.Model})
+}
+
+// EditImageTool changes an existing image, optionally only where a mask
+// allows.
+type EditImageTool struct {
+	BaseTool
+	gen       media.ImageGenerator
+	store     *media.Store
+	workspace string
+}
+
+// NewEditImageTool creates an EditImageTool.
+func NewEditImageTool(gen media.ImageGenerator, store *media.Store, workspace string) *EditImageTool {
+	return &EditImageTool{
+		BaseTool: BaseTool{
+			ToolName:        "edit_image",
+			ToolDescription: "Edit an existing PNG image as a prompt describes, e.g. one generate_image made. With a mask (a PNG of the same size), only the mask's fully transparent area changes. The edited image is saved as a new asset; the original is untouched.",
+			ToolParameters: mustJSON(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"image_path": map[string]any{
+						"type":        "string",
+						"description": "Path to the PNG to edit",
+					},
+					"prompt": map[string]any{
+						"type":        "string",
+						"description": "The change to make, or the full desired result",
+					},
+					"mask_path": map[string]any{
+						"type":        "string",
+						"description": "Optional PNG mask; transparent pixels mark the area to change",
+					},
+					"size": map[string]any{
+						"type":        "string",
+						"description": "Output WIDTHxHEIGHT (default: the backend's)",
+					},
+				},
+				"required": []string{"image_path", "prompt"},
+			}),
+			ReadOnly:        false,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptCancel,
+			RequiredFields:  []string{"image_path", "prompt"},
+		},
+		gen:       gen,
+		store:     store,
+		workspace: workspace,
+	}
+}
+
+func (t *EditImageTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	imagePath := t.path(getStringArg(input, "image_path", ""))
+	image, err := os.ReadFile(imagePath)
+	if err != nil {
+		return tools.ToolResult{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	req := media.ImageRequest{
+		Prompt: getStringArg(input, "prompt", ""),
+		Size:   getStringArg(input, "size", ""),
+		Image:  image,
+	}
+	if maskPath := getStringArg(input, "mask_path", ""); maskPath != "" {
+		if req.Mask, err = os.ReadFile(t.path(maskPath)); err != nil {
+			return tools.ToolResult{}, fmt.Errorf("failed to read mask: %w", err)
+		}
+	}
+	images, err := t.gen.Edit(ctx, req)
+	if err != nil {
+		return tools.ToolResult{}, fmt.Errorf("image edit failed: %w", err)
+	}
+	return saveImageResult(t.store, images, media.Asset{Prompt: req.Prompt, Backend: t.gen.Name(), Source: imagePath})
+}
+
+// path expands ~ and resolves relative paths against the workspace. Like
+// upscale_image, reading outside the workspace is allowed: source images
+// often live in ~/Pictures or the session's asset directory.
+func (t *EditImageTool) path(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[2:])
+		}
+	}
+	if !filepath.IsAbs(p) && t.workspace != "" {
+		p = filepath.Join(t.workspace, p)
+	}
+	return p
+}
+
+// saveImageResult stores images and reports their paths, with the assets
+// under media.MetadataKey for the chat UI and session log.
+func saveImageResult(store *media.Store, images []media.Image, ref media.Asset) (tools.ToolResult, error) {
+	assets := make([]media.Asset, 0, len(images))
+	paths := make([]string, 0, len(images))
+	for _, img := range images {
+		a := ref
+		if img.RevisedPrompt != "" {
+			a.Prompt = img.RevisedPrompt
+		}
+		saved, err := store.Save(img.Data, a)
+		if err != nil {
+			return tools.ToolResult{}, err
+		}
+		assets = append(assets, saved)
+		paths = append(paths, saved.Path)
+	}
+	result, err := resultFromMap(map[string]any{
+		"message": fmt.Sprintf("Saved %d image(s); the user can see them.", len(assets)),
+		"paths":   paths,
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Metadata = map[string]any{media.MetadataKey: assets}
+	return result, nil
+}
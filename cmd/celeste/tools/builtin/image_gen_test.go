@@ -0,0 +1,65 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media/mediatest"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+func TestImageTools_GenerateThenEdit(t *testing.T) {
+	srv := mediatest.NewServer()
+	defer srv.Close()
+	gen, err := media.NewImageGenerator(media.ImageBackend{Name: "openai", APIKey: "k", BaseURL: srv.BaseURL()})
+	require.NoError(t, err)
+	workspace := t.TempDir()
+	store := media.NewStore(filepath.Join(t.TempDir(), "assets"))
+
+	registry := tools.NewRegistry()
+	RegisterImageTools(registry, gen, store, workspace)
+
+	genTool, ok := registry.Get("generate_image")
+	require.True(t, ok)
+	res, err := genTool.Execute(context.Background(), map[string]any{"prompt": "a lighthouse", "size": "16x16", "count": float64(2)}, nil)
+	require.NoError(t, err)
+	assets := media.AssetsFrom(res.Metadata)
+	require.Len(t, assets, 2)
+	assert.Equal(t, "openai", assets[0].Backend)
+	assert.Equal(t, 16, assets[0].Width)
+	assert.Equal(t, store.Dir(), filepath.Dir(assets[0].Path))
+
+	var content struct {
+		Paths []string `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(res.Content), &content))
+	assert.Equal(t, []string{assets[0].Path, assets[1].Path}, content.Paths)
+
+	// Edit the first variant through a workspace-relative copy.
+	data, err := os.ReadFile(assets[0].Path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "in.png"), data, 0644))
+	editTool, _ := registry.Get("edit_image")
+	res, err = editTool.Execute(context.Background(), map[string]any{"image_path": "in.png", "prompt": "add fog"}, nil)
+	require.NoError(t, err)
+	edited := media.AssetsFrom(res.Metadata)
+	require.Len(t, edited, 1)
+	assert.Equal(t, filepath.Join(workspace, "in.png"), edited[0].Source)
+	assert.True(t, srv.Requests()[1].Edit)
+}
+
+func TestEditImageTool_MissingImage(t *testing.T) {
+	srv := mediatest.NewServer()
+	defer srv.Close()
+	gen, _ := media.NewImageGenerator(media.ImageBackend{Name: "openai", APIKey: "k", BaseURL: srv.BaseURL()})
+	tool := NewEditImageTool(gen, media.NewStore(t.TempDir()), t.TempDir())
+	_, err := tool.Execute(context.Background(), map[string]any{"image_path": "nope.png", "prompt": "x"}, nil)
+	assert.Error(t, err)
+	assert.Empty(t, srv.Requests())
+}
@@ -0,0 +1,213 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/shell"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+const (
+	defaultJobWaitSeconds = 30
+	maxJobWaitSeconds     = 300
+)
+
+// JobOutputTool lists background jobs and reads their output.
+type JobOutputTool struct {
+	BaseTool
+	shells *shell.Manager
+}
+
+// NewJobOutputTool creates a JobOutputTool over m's jobs.
+func NewJobOutputTool(m *shell.Manager) *JobOutputTool {
+	return &JobOutputTool{
+		BaseTool: BaseTool{
+			ToolName: "job_output",
+			ToolDescription: "Inspect background jobs started with bash background=true. " +
+				"list shows every job and persistent shell; poll returns a job's new output at once; " +
+				"wait blocks until the job exits or the timeout passes. Pass next_offset back as since to read only new output.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"action": {
+						"type": "string",
+						"enum": ["list", "poll", "wait"],
+						"description": "What to do. Defaults to poll when id is given, list otherwise."
+					},
+					"id": {
+						"type": "string",
+						"description": "Job id, e.g. \"job-1\". Required for poll and wait."
+					},
+					"since": {
+						"type": "integer",
+						"description": "Output offset to read from; use next_offset from the previous call. Defaults to 0."
+					},
+					"timeout_seconds": {
+						"type": "number",
+						"description": "How long wait blocks. Defaults to 30, at most 300."
+					}
+				},
+				"required": []
+			}`),
+			ReadOnly:        true,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptCancel,
+		},
+		shells: m,
+	}
+}
+
+func (t *JobOutputTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	id := strings.TrimSpace(getStringArg(input, "id", ""))
+	action := strings.TrimSpace(getStringArg(input, "action", ""))
+	if action == "" {
+		action = "list"
+		if id != "" {
+			action = "poll"
+		}
+	}
+
+	switch action {
+	case "list":
+		return t.list(), nil
+	case "poll", "wait":
+	default:
+		return tools.ToolResult{Error: true, Content: fmt.Sprintf("unknown action %q (want list, poll or wait)", action)}, nil
+	}
+
+	if id == "" {
+		return tools.ToolResult{Error: true, Content: "id is required for " + action}, nil
+	}
+	job, ok := t.shells.Job(id)
+	if !ok {
+		return tools.ToolResult{Error: true, Content: fmt.Sprintf("no job %q; use action list to see jobs", id)}, nil
+	}
+
+	if action == "wait" {
+		seconds := getIntArg(input, "timeout_seconds", defaultJobWaitSeconds)
+		if seconds <= 0 {
+			seconds = defaultJobWaitSeconds
+		}
+		if seconds > maxJobWaitSeconds {
+			seconds = maxJobWaitSeconds
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+		job.Wait(waitCtx)
+		cancel()
+	}
+
+	output, next, dropped := job.Output(int64(getIntArg(input, "since", 0)))
+	truncated := false
+	if len(output) > maxCommandOutput {
+		// Skip ahead rather than cut the end: the next poll resumes at next.
+		dropped += int64(len(output) - maxCommandOutput)
+		output = output[len(output)-maxCommandOutput:]
+		truncated = true
+	}
+	info := job.Info()
+	result := jobResult(info)
+	result["output"] = output
+	result["next_offset"] = next
+	result["dropped_bytes"] = dropped
+	result["truncated"] = truncated
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
+
+func (t *JobOutputTool) list() tools.ToolResult {
+	infos := t.shells.Jobs()
+	jobs := make([]map[string]any, 0, len(infos))
+	for _, info := range infos {
+		jobs = append(jobs, jobResult(info))
+	}
+	shells := t.shells.Shells()
+	if shells == nil {
+		shells = []string{}
+	}
+	result := map[string]any{
+		"jobs":   jobs,
+		"shells": shells,
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}
+}
+
+func jobResult(info shell.JobInfo) map[string]any {
+	result := map[string]any{
+		"id":              info.ID,
+		"command":         info.Command,
+		"dir":             info.Dir,
+		"pid":             info.PID,
+		"status":          info.Status,
+		"elapsed_seconds": int(info.Elapsed.Seconds()),
+		"output_bytes":    info.OutputBytes,
+	}
+	if info.Timeout > 0 {
+		result["timeout_seconds"] = int(info.Timeout.Seconds())
+	}
+	if info.Status != shell.StatusRunning {
+		result["exit_code"] = info.ExitCode
+	}
+	return result
+}
+
+// JobKillTool stops a background job or closes a persistent shell.
+type JobKillTool struct {
+	BaseTool
+	shells *shell.Manager
+}
+
+// NewJobKillTool creates a JobKillTool over m's jobs and shells.
+func NewJobKillTool(m *shell.Manager) *JobKillTool {
+	return &JobKillTool{
+		BaseTool: BaseTool{
+			ToolName:        "job_kill",
+			ToolDescription: "Stop a background job (SIGTERM, then SIGKILL after 2s) or close a persistent shell, along with everything it started.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"id": {
+						"type": "string",
+						"description": "Job id to kill, e.g. \"job-1\"."
+					},
+					"shell": {
+						"type": "string",
+						"description": "Name of a persistent shell to close."
+					}
+				},
+				"required": []
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptBlock,
+		},
+		shells: m,
+	}
+}
+
+func (t *JobKillTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	id := strings.TrimSpace(getStringArg(input, "id", ""))
+	name := strings.TrimSpace(getStringArg(input, "shell", ""))
+	switch {
+	case id != "" && name != "":
+		return tools.ToolResult{Error: true, Content: "pass either id or shell, not both"}, nil
+	case id != "":
+		job, ok := t.shells.Job(id)
+		if !ok {
+			return tools.ToolResult{Error: true, Content: fmt.Sprintf("no job %q", id)}, nil
+		}
+		job.Kill()
+		result := jobResult(job.Info())
+		return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+	case name != "":
+		if !t.shells.CloseShell(name) {
+			return tools.ToolResult{Error: true, Content: fmt.Sprintf("no shell %q", name)}, nil
+		}
+		result := map[string]any{"shell": name, "status": "closed"}
+		return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+	default:
+		return tools.ToolResult{Error: true, Content: "id or shell is required"}, nil
+	}
+}
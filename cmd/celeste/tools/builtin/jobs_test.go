@@ -0,0 +1,143 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/shell"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+func decodeResult(t *testing.T, result tools.ToolResult) map[string]any {
+	t.Helper()
+	var data map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content), &data), result.Content)
+	return data
+}
+
+func newShellManager(t *testing.T, workspace string) *shell.Manager {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("persistent shells need a POSIX shell")
+	}
+	m := shell.NewManager(workspace)
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestBashToolWithoutManagerRejectsShell(t *testing.T) {
+	bt := NewBashTool(t.TempDir())
+	result, err := bt.Execute(context.Background(), map[string]any{
+		"command": "echo hi",
+		"shell":   "dev",
+	}, nil)
+	require.NoError(t, err)
+	assert.True(t, result.Error)
+	assert.NotContains(t, string(bt.Parameters()), `"background"`)
+}
+
+func TestBashToolPersistentShell(t *testing.T) {
+	workspace := t.TempDir()
+	bt := NewBashTool(workspace, WithShellManager(newShellManager(t, workspace)))
+	assert.Contains(t, string(bt.Parameters()), `"background"`)
+
+	result, err := bt.Execute(context.Background(), map[string]any{
+		"command": "mkdir sub && cd sub && export GREETING=hi",
+		"shell":   "dev",
+	}, nil)
+	require.NoError(t, err)
+	require.False(t, result.Error, result.Content)
+
+	result, err = bt.Execute(context.Background(), map[string]any{
+		"command": `echo "$GREETING from $(basename "$PWD")"`,
+		"shell":   "dev",
+	}, nil)
+	require.NoError(t, err)
+	data := decodeResult(t, result)
+	assert.Equal(t, "hi from sub\n", data["output"])
+	assert.Equal(t, float64(0), data["exit_code"])
+	assert.Nil(t, data["shell_reset"])
+}
+
+func TestBashToolShellTimeoutResets(t *testing.T) {
+	workspace := t.TempDir()
+	bt := NewBashTool(workspace, WithShellManager(newShellManager(t, workspace)))
+
+	result, err := bt.Execute(context.Background(), map[string]any{
+		"command":         "sleep 30",
+		"shell":           "dev",
+		"timeout_seconds": 1,
+	}, nil)
+	require.NoError(t, err)
+	data := decodeResult(t, result)
+	assert.Equal(t, true, data["timed_out"])
+	assert.Equal(t, true, data["shell_reset"])
+	assert.Contains(t, data["note"], "background=true")
+}
+
+func TestJobTools(t *testing.T) {
+	workspace := t.TempDir()
+	m := newShellManager(t, workspace)
+	bt := NewBashTool(workspace, WithShellManager(m))
+	outputTool := NewJobOutputTool(m)
+	killTool := NewJobKillTool(m)
+	ctx := context.Background()
+
+	result, err := bt.Execute(ctx, map[string]any{
+		"command":    "echo first; sleep 0.2; echo second",
+		"background": true,
+	}, nil)
+	require.NoError(t, err)
+	data := decodeResult(t, result)
+	id, _ := data["job_id"].(string)
+	require.Equal(t, "job-1", id)
+	assert.Equal(t, shell.StatusRunning, data["status"])
+
+	result, err = outputTool.Execute(ctx, map[string]any{"id": id, "action": "wait", "timeout_seconds": 10}, nil)
+	require.NoError(t, err)
+	data = decodeResult(t, result)
+	assert.Equal(t, shell.StatusExited, data["status"])
+	assert.Equal(t, float64(0), data["exit_code"])
+	assert.Equal(t, "first\nsecond\n", data["output"])
+
+	// Reading from next_offset returns nothing new.
+	result, err = outputTool.Execute(ctx, map[string]any{"id": id, "since": data["next_offset"]}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", decodeResult(t, result)["output"])
+
+	result, err = bt.Execute(ctx, map[string]any{"command": "sleep 30", "background": true}, nil)
+	require.NoError(t, err)
+	sleeper := decodeResult(t, result)["job_id"]
+
+	result, err = outputTool.Execute(ctx, map[string]any{}, nil)
+	require.NoError(t, err)
+	jobs, _ := decodeResult(t, result)["jobs"].([]any)
+	assert.Len(t, jobs, 2)
+
+	result, err = killTool.Execute(ctx, map[string]any{"id": sleeper}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, shell.StatusKilled, decodeResult(t, result)["status"])
+
+	result, err = killTool.Execute(ctx, map[string]any{"id": "job-99"}, nil)
+	require.NoError(t, err)
+	assert.True(t, result.Error)
+}
+
+func TestRegisterShellTools(t *testing.T) {
+	registry := tools.NewRegistry()
+	workspace := t.TempDir()
+	RegisterAll(registry, workspace, nil, nil, nil)
+	RegisterShellTools(registry, workspace, shell.NewManager(workspace))
+
+	for _, name := range []string{"job_output", "job_kill"} {
+		_, ok := registry.Get(name)
+		assert.True(t, ok, name)
+	}
+	bash, ok := registry.Get("bash")
+	require.True(t, ok)
+	assert.Contains(t, string(bash.Parameters()), `"shell"`)
+}
@@ -0,0 +1,400 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// editDiagnostics is embedded in the edit tools. Once RegisterLSPTools
+// gives it a manager, each successful edit sends the touched files to
+// their language servers and the tool result carries what they report.
+type editDiagnostics struct {
+	lsp *lsp.Manager
+}
+
+func (d *editDiagnostics) setLSP(m *lsp.Manager) {
+	d.lsp = m
+}
+
+// attach adds the language servers' diagnostics for paths (absolute) to
+// result. Results gain nothing when no server handles any of the paths.
+func (d *editDiagnostics) attach(ctx context.Context, result map[string]any, paths ...string) {
+	if !d.lsp.Enabled() {
+		return
+	}
+	handled := false
+	for _, p := range paths {
+		if d.lsp.Handles(p) {
+			handled = true
+			break
+		}
+	}
+	if !handled {
+		return
+	}
+
+	report := d.lsp.Diagnose(ctx, paths...)
+	if len(report.Errors) > 0 {
+		result["lsp_errors"] = report.Errors
+	}
+	if len(report.Errors) > 0 && len(report.Diagnostics) == 0 && len(report.Pending) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(report.Diagnostics))
+	for _, diag := range report.Diagnostics {
+		line := diag.String()
+		if diag.New {
+			line += " [new]"
+		}
+		lines = append(lines, line)
+	}
+	result["diagnostics"] = lines
+	if report.Omitted > 0 {
+		result["diagnostics_omitted"] = report.Omitted
+	}
+	if len(report.Pending) > 0 {
+		result["diagnostics_pending"] = report.Pending
+		result["diagnostics_note"] = "The language server had not finished checking these files; run a build or edit again to see their diagnostics."
+	}
+}
+
+// RegisterLSPTools turns on edit diagnostics for the registered edit tools
+// and registers lsp_hover, lsp_definition and lsp_rename. It does nothing
+// when m has no servers configured. The caller owns m and must Close it
+// when the session ends.
+func RegisterLSPTools(registry *tools.Registry, workspace string, m *lsp.Manager) {
+	if !m.Enabled() {
+		return
+	}
+	for _, name := range []string{"write_file", "patch_file", "splice_file", "apply_patch"} {
+		if tool, ok := registry.Get(name); ok {
+			if d, ok := tool.(interface{ setLSP(*lsp.Manager) }); ok {
+				d.setLSP(m)
+			}
+		}
+	}
+
+	// Renames go through apply_patch's staging so they get the same stale
+	// checks, snapshots and rollback as any other multi-file edit.
+	var patcher *ApplyPatchTool
+	if tool, ok := registry.Get("apply_patch"); ok {
+		patcher, _ = tool.(*ApplyPatchTool)
+	}
+	if patcher == nil {
+		patcher = NewApplyPatchTool(workspace)
+		patcher.setLSP(m)
+	}
+
+	registry.RegisterWithModes(NewLSPHoverTool(workspace, m), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+	registry.RegisterWithModes(NewLSPDefinitionTool(workspace, m), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+	registry.RegisterWithModes(NewLSPRenameTool(workspace, m, patcher), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+}
+
+// lspPositionProperties are the JSON schema properties shared by the lsp_*
+// tools.
+const lspPositionProperties = `
+					"path": {
+						"type": "string",
+						"description": "Relative file path inside workspace."
+					},
+					"line": {
+						"type": "integer",
+						"description": "1-based line number, as shown by read_file."
+					},
+					"column": {
+						"type": "integer",
+						"description": "1-based character column of the symbol. Optional when symbol is given."
+					},
+					"symbol": {
+						"type": "string",
+						"description": "The identifier on that line; used to find the column when column is omitted."
+					}`
+
+// lspPosition resolves the path, line and column arguments of an lsp_* tool.
+func lspPosition(workspace string, input map[string]any) (abs string, line, column int, err error) {
+	abs, err = resolvePath(workspace, getStringArg(input, "path", ""))
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("path error: %s", err)
+	}
+	line = getIntArg(input, "line", 0)
+	if line < 1 {
+		return "", 0, 0, fmt.Errorf("line must be a 1-based line number")
+	}
+	column = getIntArg(input, "column", 0)
+	if column >= 1 {
+		return abs, line, column, nil
+	}
+
+	symbol := strings.TrimSpace(getStringArg(input, "symbol", ""))
+	if symbol == "" {
+		return "", 0, 0, fmt.Errorf("pass column or symbol")
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	lines := strings.Split(string(data), "\n")
+	if line > len(lines) {
+		return "", 0, 0, fmt.Errorf("line %d is out of range (file has %d lines)", line, len(lines))
+	}
+	idx := strings.Index(lines[line-1], symbol)
+	if idx < 0 {
+		return "", 0, 0, fmt.Errorf("%q does not appear on line %d", symbol, line)
+	}
+	return abs, line, utf8.RuneCountInString(lines[line-1][:idx]) + 1, nil
+}
+
+// relPath returns abs relative to workspace when it is inside it.
+func relPath(workspace, abs string) string {
+	if rel, err := filepath.Rel(workspace, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return abs
+}
+
+// LSPHoverTool shows the language server's hover for a symbol.
+type LSPHoverTool struct {
+	BaseTool
+	workspace string
+	lsp       *lsp.Manager
+}
+
+// NewLSPHoverTool creates an LSPHoverTool using m's servers.
+func NewLSPHoverTool(workspace string, m *lsp.Manager) *LSPHoverTool {
+	return &LSPHoverTool{
+		BaseTool: BaseTool{
+			ToolName: "lsp_hover",
+			ToolDescription: "Ask the language server what a symbol is: its type, signature and documentation. " +
+				"Point at it by path, line and either column or the symbol's name.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {` + lspPositionProperties + `
+				},
+				"required": ["path", "line"]
+			}`),
+			ReadOnly:        true,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptCancel,
+			RequiredFields:  []string{"path", "line"},
+		},
+		workspace: workspace,
+		lsp:       m,
+	}
+}
+
+func (t *LSPHoverTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	if err := t.ValidateInput(input); err != nil {
+		return errResult(err.Error()), nil
+	}
+	abs, line, column, err := lspPosition(t.workspace, input)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	text, err := t.lsp.Hover(ctx, abs, line, column)
+	if err != nil {
+		return errResult(fmt.Sprintf("hover failed: %s", err)), nil
+	}
+	result := map[string]any{
+		"path":   relPath(t.workspace, abs),
+		"line":   line,
+		"column": column,
+		"hover":  text,
+	}
+	if text == "" {
+		result["note"] = "The language server has nothing to show there; check the line and column point at an identifier."
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
+
+// LSPDefinitionTool jumps to where a symbol is defined.
+type LSPDefinitionTool struct {
+	BaseTool
+	workspace string
+	lsp       *lsp.Manager
+}
+
+// NewLSPDefinitionTool creates an LSPDefinitionTool using m's servers.
+func NewLSPDefinitionTool(workspace string, m *lsp.Manager) *LSPDefinitionTool {
+	return &LSPDefinitionTool{
+		BaseTool: BaseTool{
+			ToolName: "lsp_definition",
+			ToolDescription: "Ask the language server where a symbol is defined. Returns each definition's file, " +
+				"line and column with the line's text. Point at the symbol by path, line and either column or its name.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {` + lspPositionProperties + `
+				},
+				"required": ["path", "line"]
+			}`),
+			ReadOnly:        true,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptCancel,
+			RequiredFields:  []string{"path", "line"},
+		},
+		workspace: workspace,
+		lsp:       m,
+	}
+}
+
+func (t *LSPDefinitionTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	if err := t.ValidateInput(input); err != nil {
+		return errResult(err.Error()), nil
+	}
+	abs, line, column, err := lspPosition(t.workspace, input)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	locs, err := t.lsp.Definition(ctx, abs, line, column)
+	if err != nil {
+		return errResult(fmt.Sprintf("definition lookup failed: %s", err)), nil
+	}
+	definitions := make([]map[string]any, 0, len(locs))
+	for _, loc := range locs {
+		def := map[string]any{
+			"path":   relPath(t.workspace, loc.Path),
+			"line":   loc.Line,
+			"column": loc.Column,
+		}
+		if data, err := os.ReadFile(loc.Path); err == nil {
+			if lines := strings.Split(string(data), "\n"); loc.Line <= len(lines) {
+				def["text"] = strings.TrimSpace(lines[loc.Line-1])
+			}
+		}
+		definitions = append(definitions, def)
+	}
+	result := map[string]any{
+		"path":        relPath(t.workspace, abs),
+		"line":        line,
+		"column":      column,
+		"definitions": definitions,
+	}
+	if len(definitions) == 0 {
+		result["note"] = "No definition found; check the line and column point at an identifier."
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
+
+// LSPRenameTool renames a symbol everywhere the language server knows it
+// is used.
+type LSPRenameTool struct {
+	BaseTool
+	editDiagnostics
+	workspace string
+	lsp       *lsp.Manager
+	patcher   *ApplyPatchTool
+}
+
+// NewLSPRenameTool creates an LSPRenameTool that asks m's servers for the
+// edits and writes them through patcher.
+func NewLSPRenameTool(workspace string, m *lsp.Manager, patcher *ApplyPatchTool) *LSPRenameTool {
+	return &LSPRenameTool{
+		BaseTool: BaseTool{
+			ToolName: "lsp_rename",
+			ToolDescription: "Rename a symbol across the workspace using the language server, so every reference is " +
+				"updated and nothing that merely shares the name is touched. All files change or none do. " +
+				"Point at the symbol by path, line and either column or its current name.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {` + lspPositionProperties + `,
+					"new_name": {
+						"type": "string",
+						"description": "The symbol's new name."
+					},
+					"dry_run": {
+						"type": "boolean",
+						"description": "List the edits without writing anything. Defaults to false."
+					}
+				},
+				"required": ["path", "line", "new_name"]
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			Interrupt:       tools.InterruptBlock,
+			RequiredFields:  []string{"path", "line", "new_name"},
+		},
+		editDiagnostics: editDiagnostics{lsp: m},
+		workspace:       workspace,
+		lsp:             m,
+		patcher:         patcher,
+	}
+}
+
+func (t *LSPRenameTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	if err := t.ValidateInput(input); err != nil {
+		return errResult(err.Error()), nil
+	}
+	newName := strings.TrimSpace(getStringArg(input, "new_name", ""))
+	if newName == "" {
+		return errResult("new_name is empty"), nil
+	}
+	dryRun := getBoolArg(input, "dry_run", false)
+	abs, line, column, err := lspPosition(t.workspace, input)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+	edits, err := t.lsp.Rename(ctx, abs, line, column, newName)
+	if err != nil {
+		return errResult(fmt.Sprintf("rename failed: %s", err)), nil
+	}
+
+	paths := make([]string, 0, len(edits))
+	for p := range edits {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	stage := &patchStage{workspace: t.workspace, tracker: t.patcher.tracker, files: map[string]*patchFile{}}
+	files := make([]map[string]any, 0, len(paths))
+	total := 0
+	for _, p := range paths {
+		rel := relPath(t.workspace, p)
+		if filepath.IsAbs(rel) {
+			return errResult(fmt.Sprintf("rename would edit %s, outside the workspace; nothing was written", p)), nil
+		}
+		f, err := stage.file(rel)
+		if err != nil {
+			return errResult(fmt.Sprintf("%s: %s; nothing was written", rel, err)), nil
+		}
+		content, err := lsp.ApplyEdits(f.content, edits[p])
+		if err != nil {
+			return errResult(fmt.Sprintf("%s: %s; nothing was written", rel, err)), nil
+		}
+		f.content = content
+		files = append(files, map[string]any{"path": rel, "edits": len(edits[p])})
+		total += len(edits[p])
+	}
+
+	result := map[string]any{
+		"workspace": t.workspace,
+		"new_name":  newName,
+		"files":     files,
+		"edits":     total,
+		"dry_run":   dryRun,
+	}
+	if total == 0 {
+		result["applied"] = false
+		result["message"] = "The language server returned no edits."
+		return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+	}
+	if dryRun {
+		result["applied"] = false
+		return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+	}
+	if err := t.patcher.commit(stage); err != nil {
+		result["applied"] = false
+		result["message"] = err.Error()
+		return tools.ToolResult{Error: true, Content: formatResult(result), Metadata: result}, nil
+	}
+	result["applied"] = true
+	t.attach(ctx, result, paths...)
+	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
+}
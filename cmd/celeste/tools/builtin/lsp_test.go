@@ -0,0 +1,120 @@
+package builtin
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/lsp/lsptest"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// newLSPRegistry registers the dev tools for a fresh workspace with the
+// stand-in language server handling .go files.
+func newLSPRegistry(t *testing.T, script lsptest.Script) (*tools.Registry, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m := lsp.NewManager(dir, []lsp.ServerConfig{lsptest.Config(t, script, ".go")}, sandbox.Policy{})
+	t.Cleanup(m.Close)
+	registry := tools.NewRegistry()
+	RegisterAll(registry, dir, nil, nil, nil)
+	RegisterLSPTools(registry, dir, m)
+	return registry, dir
+}
+
+func execTool(t *testing.T, registry *tools.Registry, name string, args map[string]any) (map[string]any, bool) {
+	t.Helper()
+	tool, ok := registry.Get(name)
+	require.True(t, ok, name)
+	res, err := tool.Execute(context.Background(), args, nil)
+	require.NoError(t, err)
+	return decodeResult(t, res), !res.Error
+}
+
+func TestEditToolsAttachDiagnostics(t *testing.T) {
+	registry, dir := newLSPRegistry(t, lsptest.Script{Diagnostics: []lsptest.Rule{
+		{Match: "undefinedFn", Message: "undefined: undefinedFn"},
+	}})
+
+	data, ok := execTool(t, registry, "write_file", map[string]any{
+		"path":    "main.go",
+		"content": "package main\n\nfunc main() {\n\tundefinedFn()\n}\n",
+	})
+	require.True(t, ok, data)
+	assert.Equal(t, []any{"main.go:4:2: error: undefined: undefinedFn (lsptest) [new]"}, data["diagnostics"])
+
+	data, ok = execTool(t, registry, "patch_file", map[string]any{
+		"path":       "main.go",
+		"old_string": "undefinedFn()",
+		"new_string": "println()",
+	})
+	require.True(t, ok, data)
+	assert.Equal(t, []any{}, data["diagnostics"], "a clean file reports an empty list")
+
+	data, ok = execTool(t, registry, "apply_patch", map[string]any{
+		"edits": []any{
+			map[string]any{"path": "main.go", "old_string": "println()", "new_string": "undefinedFn()"},
+			map[string]any{"path": "README.md", "content": "# hi\n"},
+		},
+	})
+	require.True(t, ok, data)
+	assert.Len(t, data["diagnostics"], 1)
+
+	// Files no server handles get no diagnostics key at all.
+	data, ok = execTool(t, registry, "write_file", map[string]any{"path": "notes.txt", "content": "hi\n"})
+	require.True(t, ok, data)
+	assert.NotContains(t, data, "diagnostics")
+	assert.Equal(t, "package main\n\nfunc main() {\n\tundefinedFn()\n}\n", mustRead(t, filepath.Join(dir, "main.go")))
+}
+
+func TestLSPTools(t *testing.T) {
+	registry, dir := newLSPRegistry(t, lsptest.Script{Hover: map[string]string{"Add": "func Add(a, b int) int"}})
+	writeFiles(t, dir, map[string]string{
+		"math.go": "package calc\n\nfunc Add(a, b int) int { return a + b }\n",
+		"use.go":  "package calc\n\nvar total = Add(1, 2)\n",
+	})
+
+	data, ok := execTool(t, registry, "lsp_hover", map[string]any{"path": "use.go", "line": 3, "symbol": "Add"})
+	require.True(t, ok, data)
+	assert.Equal(t, "func Add(a, b int) int", data["hover"])
+	assert.Equal(t, float64(13), data["column"])
+
+	data, ok = execTool(t, registry, "lsp_definition", map[string]any{"path": "use.go", "line": 3, "column": 14})
+	require.True(t, ok, data)
+	defs := data["definitions"].([]any)
+	require.Len(t, defs, 1)
+	def := defs[0].(map[string]any)
+	assert.Equal(t, "math.go", def["path"])
+	assert.Equal(t, float64(3), def["line"])
+	assert.Equal(t, "func Add(a, b int) int { return a + b }", def["text"])
+
+	data, ok = execTool(t, registry, "lsp_rename", map[string]any{"path": "use.go", "line": 3, "symbol": "Add", "new_name": "Sum", "dry_run": true})
+	require.True(t, ok, data)
+	assert.Equal(t, float64(2), data["edits"])
+	assert.Equal(t, "package calc\n\nvar total = Add(1, 2)\n", mustRead(t, filepath.Join(dir, "use.go")))
+
+	data, ok = execTool(t, registry, "lsp_rename", map[string]any{"path": "use.go", "line": 3, "symbol": "Add", "new_name": "Sum"})
+	require.True(t, ok, data)
+	assert.Equal(t, true, data["applied"])
+	assert.Equal(t, "package calc\n\nfunc Sum(a, b int) int { return a + b }\n", mustRead(t, filepath.Join(dir, "math.go")))
+	assert.Equal(t, "package calc\n\nvar total = Sum(1, 2)\n", mustRead(t, filepath.Join(dir, "use.go")))
+
+	hover, _ := registry.Get("lsp_hover")
+	res, err := hover.Execute(context.Background(), map[string]any{"path": "use.go", "line": 3, "symbol": "Missing"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "does not appear on line 3")
+}
+
+func TestRegisterLSPToolsWithoutServers(t *testing.T) {
+	registry := tools.NewRegistry()
+	dir := t.TempDir()
+	RegisterAll(registry, dir, nil, nil, nil)
+	RegisterLSPTools(registry, dir, lsp.NewManager(dir, nil, sandbox.Policy{}))
+	_, ok := registry.Get("lsp_hover")
+	assert.False(t, ok)
+}
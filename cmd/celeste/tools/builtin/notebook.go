@@ -0,0 +1,297 @@
+package builtin
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// notebook is a Jupyter notebook decoded only as far as cell editing needs.
+// Everything else — nbformat versions, notebook and cell metadata, cell ids,
+// attachments, unknown keys — stays raw JSON so it round-trips untouched.
+type notebook struct {
+	fields map[string]json.RawMessage // top-level keys except "cells"
+	cells  []notebookCell
+}
+
+// notebookCell is one cell, keyed like the nbformat JSON.
+type notebookCell map[string]json.RawMessage
+
+// isNotebookPath reports whether path names a Jupyter notebook.
+func isNotebookPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// loadNotebook reads and decodes the notebook at path.
+func loadNotebook(path string) (*notebook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseNotebook(data)
+}
+
+func parseNotebook(data []byte) (*notebook, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("not a valid notebook: %w", err)
+	}
+	if _, ok := fields["nbformat"]; !ok {
+		return nil, fmt.Errorf("not a valid notebook: missing nbformat")
+	}
+	nb := &notebook{fields: fields}
+	if raw, ok := fields["cells"]; ok {
+		if err := json.Unmarshal(raw, &nb.cells); err != nil {
+			return nil, fmt.Errorf("not a valid notebook: cells: %w", err)
+		}
+	}
+	delete(nb.fields, "cells")
+	return nb, nil
+}
+
+// newNotebook returns an empty nbformat 4.5 notebook with a Python kernel.
+func newNotebook() *notebook {
+	return &notebook{fields: map[string]json.RawMessage{
+		"metadata": json.RawMessage(`{"kernelspec":{"display_name":"Python 3","language":"python","name":"python3"},` +
+			`"language_info":{"name":"python"}}`),
+		"nbformat":       json.RawMessage(`4`),
+		"nbformat_minor": json.RawMessage(`5`),
+	}}
+}
+
+// marshal encodes the notebook the way Jupyter writes it: sorted keys,
+// one-space indent, no HTML escaping and a trailing newline.
+func (nb *notebook) marshal() ([]byte, error) {
+	fields := make(map[string]any, len(nb.fields)+1)
+	for k, v := range nb.fields {
+		fields[k] = v
+	}
+	cells := nb.cells
+	if cells == nil {
+		cells = []notebookCell{}
+	}
+	fields["cells"] = cells
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(fields); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// notebookJSON marshals v without HTML escaping. marshal copies raw values
+// as they are, so a "<" escaped here would reach the file as "\u003c".
+func notebookJSON(v any) json.RawMessage {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// version returns nbformat and nbformat_minor.
+func (nb *notebook) version() (int, int) {
+	var major, minor int
+	_ = json.Unmarshal(nb.fields["nbformat"], &major)
+	_ = json.Unmarshal(nb.fields["nbformat_minor"], &minor)
+	return major, minor
+}
+
+// language returns the kernel language, or "" when the metadata has none.
+func (nb *notebook) language() string {
+	var meta struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	}
+	_ = json.Unmarshal(nb.fields["metadata"], &meta)
+	if meta.LanguageInfo.Name != "" {
+		return meta.LanguageInfo.Name
+	}
+	return meta.Kernelspec.Language
+}
+
+// newCell builds a cell of cellType holding source. Code cells start with no
+// outputs; nbformat 4.5 and later also need a cell id.
+func (nb *notebook) newCell(cellType, source string) notebookCell {
+	c := notebookCell{
+		"cell_type": notebookJSON(cellType),
+		"metadata":  json.RawMessage(`{}`),
+	}
+	c.setSource(source)
+	if cellType == "code" {
+		c["outputs"] = json.RawMessage(`[]`)
+		c["execution_count"] = json.RawMessage(`null`)
+	}
+	if major, minor := nb.version(); major > 4 || (major == 4 && minor >= 5) {
+		c["id"] = notebookJSON(nb.newCellID())
+	}
+	return c
+}
+
+// newCellID returns an id in nbformat's style (8 hex digits) that no other
+// cell uses.
+func (nb *notebook) newCellID() string {
+	used := make(map[string]bool, len(nb.cells))
+	for _, c := range nb.cells {
+		used[c.str("id")] = true
+	}
+	for {
+		b := make([]byte, 4)
+		_, _ = rand.Read(b)
+		if id := hex.EncodeToString(b); !used[id] {
+			return id
+		}
+	}
+}
+
+func (c notebookCell) str(key string) string {
+	var s string
+	_ = json.Unmarshal(c[key], &s)
+	return s
+}
+
+func (c notebookCell) cellType() string { return c.str("cell_type") }
+
+// source returns the cell source, which nbformat stores as a string or a
+// list of lines.
+func (c notebookCell) source() string {
+	return multilineString(c["source"])
+}
+
+// setSource stores source as a list of lines, as Jupyter does.
+func (c notebookCell) setSource(source string) {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if lines == nil {
+		lines = []string{}
+	}
+	c["source"] = notebookJSON(lines)
+}
+
+// setType converts the cell to cellType, adding or dropping the code-only
+// fields.
+func (c notebookCell) setType(cellType string) {
+	if c.cellType() == cellType {
+		return
+	}
+	c["cell_type"] = notebookJSON(cellType)
+	if cellType == "code" {
+		c["outputs"] = json.RawMessage(`[]`)
+		c["execution_count"] = json.RawMessage(`null`)
+	} else {
+		delete(c, "outputs")
+		delete(c, "execution_count")
+	}
+}
+
+// clearOutputs empties a code cell's outputs and execution count. It reports
+// whether there was anything to clear.
+func (c notebookCell) clearOutputs() bool {
+	if c.cellType() != "code" {
+		return false
+	}
+	var outputs []json.RawMessage
+	_ = json.Unmarshal(c["outputs"], &outputs)
+	had := len(outputs) > 0 || string(c["execution_count"]) != "null"
+	c["outputs"] = json.RawMessage(`[]`)
+	c["execution_count"] = json.RawMessage(`null`)
+	return had
+}
+
+// notebookOutput is the part of an nbformat output renderCell shows.
+type notebookOutput struct {
+	OutputType string                     `json:"output_type"`
+	Text       json.RawMessage            `json:"text"`
+	Data       map[string]json.RawMessage `json:"data"`
+	Ename      string                     `json:"ename"`
+	Evalue     string                     `json:"evalue"`
+}
+
+// renderCell writes cell i as a header line and its source, followed by its
+// outputs cut to maxOutput characters when includeOutputs is set.
+func renderCell(b *strings.Builder, i int, c notebookCell, includeOutputs bool, maxOutput int) {
+	header := fmt.Sprintf("[%d] %s", i, c.cellType())
+	if c.cellType() == "code" {
+		if n := string(c["execution_count"]); n != "" && n != "null" {
+			header += fmt.Sprintf(" (execution_count %s)", n)
+		}
+	}
+	b.WriteString(header)
+	b.WriteByte('\n')
+	if src := c.source(); src != "" {
+		b.WriteString(strings.TrimSuffix(src, "\n"))
+		b.WriteByte('\n')
+	}
+	if !includeOutputs || c.cellType() != "code" {
+		return
+	}
+	var outputs []notebookOutput
+	_ = json.Unmarshal(c["outputs"], &outputs)
+	if len(outputs) == 0 {
+		return
+	}
+	var out strings.Builder
+	for _, o := range outputs {
+		out.WriteString(renderOutput(o))
+	}
+	b.WriteString("--- output ---\n")
+	b.WriteString(truncateRunes(strings.TrimSuffix(out.String(), "\n"), maxOutput))
+	b.WriteByte('\n')
+}
+
+func renderOutput(o notebookOutput) string {
+	switch o.OutputType {
+	case "stream":
+		return multilineString(o.Text)
+	case "error":
+		return o.Ename + ": " + o.Evalue + "\n"
+	case "execute_result", "display_data":
+		if text, ok := o.Data["text/plain"]; ok {
+			return strings.TrimSuffix(multilineString(text), "\n") + "\n"
+		}
+		var kinds []string
+		for mime := range o.Data {
+			kinds = append(kinds, mime)
+		}
+		sort.Strings(kinds)
+		return "[" + strings.Join(kinds, ", ") + "]\n"
+	}
+	return ""
+}
+
+// truncateRunes cuts s to max characters, noting how many were dropped.
+func truncateRunes(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max]) + fmt.Sprintf("… [%d more characters]", len(runes)-max)
+}
+
+// multilineString decodes an nbformat multiline string: a string or a list
+// of lines.
+func multilineString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var lines []string
+	_ = json.Unmarshal(raw, &lines)
+	return strings.Join(lines, "")
+}
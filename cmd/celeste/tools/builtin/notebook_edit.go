@@ -0,0 +1,269 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// NotebookEditTool edits Jupyter notebooks cell by cell, keeping nbformat
+// metadata and untouched cells exactly as they were.
+type NotebookEditTool struct {
+	BaseTool
+	workspace string
+	tracker   *checkpoints.FileTracker
+	snapMgr   *checkpoints.SnapshotManager
+}
+
+// NewNotebookEditTool creates a NotebookEditTool bound to the given workspace directory.
+// Optional dependencies can be provided for stale detection and file checkpointing.
+func NewNotebookEditTool(workspace string, opts ...NotebookEditOption) *NotebookEditTool {
+	t := &NotebookEditTool{
+		BaseTool: BaseTool{
+			ToolName: "notebook_edit",
+			ToolDescription: "Edit a Jupyter notebook (.ipynb) by cell: insert, replace, delete or move a cell, or clear outputs. " +
+				"Cell indices are the ones notebook_read shows. Never edit notebook JSON with patch_file or write_file.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {
+						"type": "string",
+						"description": "Relative notebook path inside workspace. Inserting into a missing notebook creates it."
+					},
+					"operation": {
+						"type": "string",
+						"enum": ["insert", "replace", "delete", "move", "clear_outputs"],
+						"description": "insert a new cell at index; replace the source (and optionally type) of the cell at index, clearing its outputs; delete the cell at index; move the cell at index to to; clear_outputs of the cell at index, or of every cell when index is omitted."
+					},
+					"index": {
+						"type": "number",
+						"description": "0-based cell index. For insert, the position of the new cell; defaults to the end."
+					},
+					"to": {
+						"type": "number",
+						"description": "For move: the cell's new 0-based index."
+					},
+					"cell_type": {
+						"type": "string",
+						"enum": ["code", "markdown", "raw"],
+						"description": "For insert (default code) and replace (default unchanged)."
+					},
+					"source": {
+						"type": "string",
+						"description": "For insert and replace: the cell's source code or markdown."
+					}
+				},
+				"required": ["path", "operation"]
+			}`),
+			ReadOnly:        false,
+			ConcurrencySafe: false,
+			RequiredFields:  []string{"path", "operation"},
+		},
+		workspace: workspace,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// NotebookEditOption configures optional dependencies for NotebookEditTool.
+type NotebookEditOption func(*NotebookEditTool)
+
+// WithNotebookEditTracker attaches a FileTracker for stale detection.
+func WithNotebookEditTracker(ft *checkpoints.FileTracker) NotebookEditOption {
+	return func(t *NotebookEditTool) {
+		t.tracker = ft
+	}
+}
+
+// WithNotebookEditSnapshots attaches a SnapshotManager for file checkpointing.
+func WithNotebookEditSnapshots(sm *checkpoints.SnapshotManager) NotebookEditOption {
+	return func(t *NotebookEditTool) {
+		t.snapMgr = sm
+	}
+}
+
+func (t *NotebookEditTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	if err := t.ValidateInput(input); err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+
+	path := getStringArg(input, "path", "")
+	op := getStringArg(input, "operation", "")
+	if !isNotebookPath(path) {
+		return tools.ToolResult{Error: true, Content: fmt.Sprintf("%s is not a notebook (.ipynb) — use patch_file", path)}, nil
+	}
+	targetPath, err := resolvePath(t.workspace, path)
+	if err != nil {
+		return tools.ToolResult{Error: true, Content: fmt.Sprintf("path error: %s", err)}, nil
+	}
+
+	// Check for stale reads before editing
+	if t.tracker != nil {
+		if err := t.tracker.CheckStale(targetPath); err != nil {
+			return tools.ToolResult{Error: true, Content: err.Error()}, nil
+		}
+	}
+
+	nb, err := loadNotebook(targetPath)
+	created := false
+	if os.IsNotExist(err) && op == "insert" {
+		nb, created = newNotebook(), true
+	} else if err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+
+	result := map[string]any{
+		"path":      path,
+		"workspace": t.workspace,
+		"operation": op,
+	}
+	if err := t.apply(nb, op, input, result); err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+
+	data, err := nb.marshal()
+	if err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+
+	// Snapshot before writing
+	if t.snapMgr != nil {
+		if err := t.snapMgr.Snapshot(targetPath); err != nil {
+			return tools.ToolResult{Error: true, Content: fmt.Sprintf("snapshot failed: %s", err)}, nil
+		}
+	}
+	if created {
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return tools.ToolResult{Error: true, Content: err.Error()}, nil
+		}
+	}
+	if err := os.WriteFile(targetPath, data, 0644); err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+
+	// Record new mtime after the edit
+	if t.tracker != nil {
+		_ = t.tracker.RecordRead(targetPath)
+	}
+
+	result["total_cells"] = len(nb.cells)
+	if created {
+		result["created"] = true
+	}
+	return tools.ToolResult{
+		Content:  formatResult(result),
+		Metadata: result,
+	}, nil
+}
+
+// apply performs op on nb, noting what changed in result.
+func (t *NotebookEditTool) apply(nb *notebook, op string, input map[string]any, result map[string]any) error {
+	n := len(nb.cells)
+	_, hasIndex := input["index"]
+	index := getIntArg(input, "index", -1)
+	cellAt := func() error {
+		if !hasIndex {
+			return fmt.Errorf("index is required for %s", op)
+		}
+		if index < 0 || index >= n {
+			return fmt.Errorf("index %d out of range: notebook has %d cells", index, n)
+		}
+		result["index"] = index
+		return nil
+	}
+	cellType := getStringArg(input, "cell_type", "")
+	switch cellType {
+	case "", "code", "markdown", "raw":
+	default:
+		return fmt.Errorf("unknown cell_type %q: use code, markdown or raw", cellType)
+	}
+	_, hasSource := input["source"]
+	source := getStringArg(input, "source", "")
+
+	switch op {
+	case "insert":
+		if !hasIndex {
+			index = n
+		}
+		if index < 0 || index > n {
+			return fmt.Errorf("index %d out of range: insert position must be 0-%d", index, n)
+		}
+		if cellType == "" {
+			cellType = "code"
+		}
+		cell := nb.newCell(cellType, source)
+		nb.cells = append(nb.cells[:index], append([]notebookCell{cell}, nb.cells[index:]...)...)
+		result["index"] = index
+		result["cell_type"] = cellType
+
+	case "replace":
+		if err := cellAt(); err != nil {
+			return err
+		}
+		if !hasSource && cellType == "" {
+			return fmt.Errorf("replace needs source, cell_type or both")
+		}
+		cell := nb.cells[index]
+		if cellType != "" {
+			cell.setType(cellType)
+		}
+		if hasSource {
+			cell.setSource(source)
+			// Outputs no longer match the new source.
+			cell.clearOutputs()
+		}
+		result["cell_type"] = cell.cellType()
+
+	case "delete":
+		if err := cellAt(); err != nil {
+			return err
+		}
+		result["cell_type"] = nb.cells[index].cellType()
+		nb.cells = append(nb.cells[:index], nb.cells[index+1:]...)
+
+	case "move":
+		if err := cellAt(); err != nil {
+			return err
+		}
+		if _, ok := input["to"]; !ok {
+			return fmt.Errorf("to is required for move")
+		}
+		to := getIntArg(input, "to", 0)
+		if to < 0 || to >= n {
+			return fmt.Errorf("to %d out of range: notebook has %d cells", to, n)
+		}
+		cell := nb.cells[index]
+		nb.cells = append(nb.cells[:index], nb.cells[index+1:]...)
+		nb.cells = append(nb.cells[:to], append([]notebookCell{cell}, nb.cells[to:]...)...)
+		result["to"] = to
+
+	case "clear_outputs":
+		cleared := 0
+		if hasIndex {
+			if err := cellAt(); err != nil {
+				return err
+			}
+			if nb.cells[index].clearOutputs() {
+				cleared++
+			}
+		} else {
+			for _, cell := range nb.cells {
+				if cell.clearOutputs() {
+					cleared++
+				}
+			}
+		}
+		result["cleared_cells"] = cleared
+
+	default:
+		return fmt.Errorf("unknown operation %q: use insert, replace, delete, move or clear_outputs", op)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// defaultNotebookOutputChars bounds each cell's rendered outputs.
+const defaultNotebookOutputChars = 1000
+
+// NotebookReadTool renders a Jupyter notebook as numbered cells instead of
+// the raw nbformat JSON.
+type NotebookReadTool struct {
+	BaseTool
+	workspace string
+	tracker   *checkpoints.FileTracker
+}
+
+// NewNotebookReadTool creates a NotebookReadTool bound to the given workspace directory.
+// An optional FileTracker records mtimes after each read for stale detection.
+func NewNotebookReadTool(workspace string, opts ...NotebookReadOption) *NotebookReadTool {
+	t := &NotebookReadTool{
+		BaseTool: BaseTool{
+			ToolName: "notebook_read",
+			ToolDescription: "Read a Jupyter notebook (.ipynb) as numbered cells with their type, execution count and truncated outputs. " +
+				"Use this instead of read_file for notebooks, and notebook_edit to change them.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {
+						"type": "string",
+						"description": "Relative notebook path inside workspace."
+					},
+					"start_cell": {
+						"type": "number",
+						"description": "0-based index of the first cell to show. Defaults to 0."
+					},
+					"end_cell": {
+						"type": "number",
+						"description": "0-based inclusive index of the last cell to show. Defaults to the last cell."
+					},
+					"include_outputs": {
+						"type": "boolean",
+						"description": "Show code cell outputs. Defaults to true."
+					},
+					"max_output_chars": {
+						"type": "number",
+						"description": "Characters of output to show per cell. Defaults to 1000."
+					}
+				},
+				"required": ["path"]
+			}`),
+			ReadOnly:        true,
+			ConcurrencySafe: true,
+			RequiredFields:  []string{"path"},
+		},
+		workspace: workspace,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// NotebookReadOption configures optional dependencies for NotebookReadTool.
+type NotebookReadOption func(*NotebookReadTool)
+
+// WithNotebookReadTracker attaches a FileTracker for stale detection.
+func WithNotebookReadTracker(ft *checkpoints.FileTracker) NotebookReadOption {
+	return func(t *NotebookReadTool) {
+		t.tracker = ft
+	}
+}
+
+func (t *NotebookReadTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	if err := t.ValidateInput(input); err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+
+	path := getStringArg(input, "path", "")
+	if !isNotebookPath(path) {
+		return tools.ToolResult{Error: true, Content: fmt.Sprintf("%s is not a notebook (.ipynb) — use read_file", path)}, nil
+	}
+	targetPath, err := resolvePath(t.workspace, path)
+	if err != nil {
+		return tools.ToolResult{Error: true, Content: fmt.Sprintf("path error: %s", err)}, nil
+	}
+
+	nb, err := loadNotebook(targetPath)
+	if err != nil {
+		return tools.ToolResult{Error: true, Content: err.Error()}, nil
+	}
+
+	total := len(nb.cells)
+	start := getIntArg(input, "start_cell", 0)
+	if start < 0 {
+		start = 0
+	}
+	if total > 0 && start >= total {
+		return tools.ToolResult{Error: true, Content: fmt.Sprintf("start_cell %d out of range: %s has %d cells", start, path, total)}, nil
+	}
+	end := getIntArg(input, "end_cell", total-1)
+	if end < 0 || end >= total {
+		end = total - 1
+	}
+	includeOutputs := getBoolArg(input, "include_outputs", true)
+	maxOutput := getIntArg(input, "max_output_chars", defaultNotebookOutputChars)
+
+	// Render whole cells until the byte budget runs out, so a notebook full of
+	// large cells pages instead of producing an oversized result.
+	var b strings.Builder
+	last := start - 1
+	for i := start; i <= end; i++ {
+		var cell strings.Builder
+		renderCell(&cell, i, nb.cells[i], includeOutputs, maxOutput)
+		if i > start && b.Len()+cell.Len() > defaultMaxResultBytes {
+			break
+		}
+		if i > start {
+			b.WriteByte('\n')
+		}
+		b.WriteString(cell.String())
+		last = i
+	}
+
+	// Record mtime for stale detection
+	if t.tracker != nil {
+		_ = t.tracker.RecordRead(targetPath)
+	}
+
+	major, minor := nb.version()
+	result := map[string]any{
+		"path":        path,
+		"workspace":   t.workspace,
+		"nbformat":    fmt.Sprintf("%d.%d", major, minor),
+		"language":    nb.language(),
+		"total_cells": total,
+		"start_cell":  start,
+		"end_cell":    last,
+		"content":     b.String(),
+	}
+	if last < end {
+		result["truncated"] = true
+		result["next_start_cell"] = last + 1
+		result["hint"] = "Notebook is large; only part was returned. Re-run notebook_read with start_cell=next_start_cell to page."
+	}
+
+	return tools.ToolResult{
+		Content:  formatResult(result),
+		Metadata: result,
+	}, nil
+}
@@ -0,0 +1,243 @@
+package builtin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
+)
+
+// sampleNotebook is laid out the way Jupyter writes notebooks, so an edit
+// that touches nothing else must leave the other bytes as they were.
+const sampleNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "id": "intro",
+   "metadata": {},
+   "source": [
+    "# Sales <2024> & more"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 3,
+   "id": "load",
+   "metadata": {
+    "tags": [
+     "parameters"
+    ]
+   },
+   "outputs": [
+    {
+     "name": "stdout",
+     "output_type": "stream",
+     "text": [
+      "loaded 1200 rows\n"
+     ]
+    },
+    {
+     "data": {
+      "image/png": "iVBORw0KGgo=",
+      "text/plain": [
+       "<Figure size 640x480>"
+      ]
+     },
+     "metadata": {},
+     "output_type": "display_data"
+    }
+   ],
+   "source": [
+    "import pandas as pd\n",
+    "df = pd.read_csv(\"sales.csv\")"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 4,
+   "id": "fail",
+   "metadata": {},
+   "outputs": [
+    {
+     "ename": "KeyError",
+     "evalue": "'region'",
+     "output_type": "error",
+     "traceback": []
+    }
+   ],
+   "source": [
+    "df[\"region\"]"
+   ]
+  }
+ ],
+ "metadata": {
+  "kernelspec": {
+   "display_name": "Python 3",
+   "language": "python",
+   "name": "python3"
+  },
+  "language_info": {
+   "name": "python",
+   "version": "3.12.1"
+  }
+ },
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func writeNotebook(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sales.ipynb")
+	require.NoError(t, os.WriteFile(path, []byte(sampleNotebook), 0644))
+	return dir, path
+}
+
+func TestNotebook_RoundTripIsByteIdentical(t *testing.T) {
+	nb, err := parseNotebook([]byte(sampleNotebook))
+	require.NoError(t, err)
+	out, err := nb.marshal()
+	require.NoError(t, err)
+	assert.Equal(t, sampleNotebook, string(out))
+}
+
+func TestNotebookReadTool(t *testing.T) {
+	dir, _ := writeNotebook(t)
+	tool := NewNotebookReadTool(dir)
+
+	res, err := tool.Execute(context.Background(), map[string]any{"path": "sales.ipynb", "max_output_chars": 30}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	meta := res.Metadata
+	assert.Equal(t, 3, meta["total_cells"])
+	assert.Equal(t, "4.5", meta["nbformat"])
+	assert.Equal(t, "python", meta["language"])
+	content := meta["content"].(string)
+	assert.Contains(t, content, "[0] markdown\n# Sales <2024> & more\n")
+	assert.Contains(t, content, "[1] code (execution_count 3)\nimport pandas as pd\ndf = pd.read_csv(\"sales.csv\")\n--- output ---\nloaded 1200 rows\n<Figure size … [8 more characters]\n")
+	assert.Contains(t, content, "--- output ---\nKeyError: 'region'")
+
+	res, err = tool.Execute(context.Background(), map[string]any{"path": "sales.ipynb", "start_cell": 2, "include_outputs": false}, nil)
+	require.NoError(t, err)
+	content = res.Metadata["content"].(string)
+	assert.Equal(t, "[2] code (execution_count 4)\ndf[\"region\"]\n", content)
+
+	res, err = tool.Execute(context.Background(), map[string]any{"path": "notes.txt"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+}
+
+func editNotebook(t *testing.T, tool *NotebookEditTool, input map[string]any) map[string]any {
+	t.Helper()
+	input["path"] = "sales.ipynb"
+	res, err := tool.Execute(context.Background(), input, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	return res.Metadata
+}
+
+func TestNotebookEditTool_Operations(t *testing.T) {
+	dir, path := writeNotebook(t)
+	tool := NewNotebookEditTool(dir)
+
+	editNotebook(t, tool, map[string]any{"operation": "insert", "index": float64(1), "source": "import numpy as np\nnp.__version__\n"})
+	nb, err := loadNotebook(path)
+	require.NoError(t, err)
+	require.Len(t, nb.cells, 4)
+	inserted := nb.cells[1]
+	assert.Equal(t, "code", inserted.cellType())
+	assert.JSONEq(t, `["import numpy as np\n", "np.__version__\n"]`, string(inserted["source"]), "sources are stored as lines")
+	assert.Equal(t, "null", string(inserted["execution_count"]))
+	assert.Len(t, inserted.str("id"), 8, "nbformat 4.5 cells need an id")
+
+	editNotebook(t, tool, map[string]any{"operation": "replace", "index": float64(2), "source": "df = pd.read_parquet(\"sales.parquet\")"})
+	editNotebook(t, tool, map[string]any{"operation": "move", "index": float64(3), "to": float64(0)})
+	out := editNotebook(t, tool, map[string]any{"operation": "delete", "index": float64(2)})
+	assert.Equal(t, 3, out["total_cells"])
+
+	nb, err = loadNotebook(path)
+	require.NoError(t, err)
+	var ids []string
+	for _, c := range nb.cells {
+		ids = append(ids, c.str("id"))
+	}
+	assert.Equal(t, []string{"fail", "intro", "load"}, ids)
+	load := nb.cells[2]
+	assert.Equal(t, "df = pd.read_parquet(\"sales.parquet\")", load.source())
+	assert.Equal(t, "[]", string(load["outputs"]), "replacing source clears stale outputs")
+	assert.JSONEq(t, `{"tags": ["parameters"]}`, string(load["metadata"]), "cell metadata survives a replace")
+
+	out = editNotebook(t, tool, map[string]any{"operation": "clear_outputs"})
+	assert.Equal(t, 1, out["cleared_cells"])
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "\"version\": \"3.12.1\"", "notebook metadata is preserved")
+	assert.Contains(t, string(data), "# Sales <2024> & more", "no HTML escaping")
+	assert.True(t, strings.HasSuffix(string(data), "}\n"))
+}
+
+func TestNotebookEditTool_Errors(t *testing.T) {
+	dir, _ := writeNotebook(t)
+	tool := NewNotebookEditTool(dir)
+	for name, input := range map[string]map[string]any{
+		"not a notebook":     {"path": "main.py", "operation": "insert"},
+		"unknown operation":  {"path": "sales.ipynb", "operation": "run"},
+		"index out of range": {"path": "sales.ipynb", "operation": "delete", "index": float64(3)},
+		"missing index":      {"path": "sales.ipynb", "operation": "replace", "source": "x"},
+		"missing to":         {"path": "sales.ipynb", "operation": "move", "index": float64(0)},
+		"bad cell type":      {"path": "sales.ipynb", "operation": "insert", "cell_type": "sql"},
+		"missing notebook":   {"path": "other.ipynb", "operation": "delete", "index": float64(0)},
+	} {
+		res, err := tool.Execute(context.Background(), input, nil)
+		require.NoError(t, err, name)
+		assert.True(t, res.Error, name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "sales.ipynb"))
+	require.NoError(t, err)
+	assert.Equal(t, sampleNotebook, string(data), "failed edits leave the notebook alone")
+}
+
+func TestNotebookEditTool_CreatesNotebook(t *testing.T) {
+	dir := t.TempDir()
+	tool := NewNotebookEditTool(dir)
+	res, err := tool.Execute(context.Background(), map[string]any{
+		"path": "new/scratch.ipynb", "operation": "insert", "cell_type": "markdown", "source": "# Scratch",
+	}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Equal(t, true, res.Metadata["created"])
+
+	nb, err := loadNotebook(filepath.Join(dir, "new", "scratch.ipynb"))
+	require.NoError(t, err)
+	require.Len(t, nb.cells, 1)
+	assert.Equal(t, "markdown", nb.cells[0].cellType())
+	assert.Equal(t, "python", nb.language())
+}
+
+func TestNotebookEditTool_SnapshotsAndStaleReads(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, path := writeNotebook(t)
+	tracker := checkpoints.NewFileTracker()
+	snaps := checkpoints.NewSnapshotManager("notebook-test")
+	reader := NewNotebookReadTool(dir, WithNotebookReadTracker(tracker))
+	editor := NewNotebookEditTool(dir, WithNotebookEditTracker(tracker), WithNotebookEditSnapshots(snaps))
+
+	_, err := reader.Execute(context.Background(), map[string]any{"path": "sales.ipynb"}, nil)
+	require.NoError(t, err)
+	editNotebook(t, editor, map[string]any{"operation": "clear_outputs"})
+	require.NoError(t, snaps.Revert(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, sampleNotebook, string(data), "the snapshot restores the original notebook")
+
+	res, err := editor.Execute(context.Background(), map[string]any{"path": "sales.ipynb", "operation": "clear_outputs"}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error, "an edit after an outside change must re-read first")
+}
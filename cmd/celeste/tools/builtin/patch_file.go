@@ -20,6 +20,7 @@ const maxPatchLiteralBytes = 16 * 1024
 // PatchFileTool performs surgical string replacements in workspace files.
 type PatchFileTool struct {
 	BaseTool
+	editDiagnostics
 	workspace string
 	tracker   *checkpoints.FileTracker
 	snapMgr   *checkpoints.SnapshotManager
@@ -175,6 +176,7 @@ func (t *PatchFileTool) Execute(ctx context.Context, input map[string]any, progr
 		"replacements": count,
 		"replace_all":  replaceAll,
 	}
+	t.attach(ctx, result, targetPath)
 
 	return tools.ToolResult{
 		Content:  formatResult(result),
@@ -167,6 +167,9 @@ func (t *ReadFileTool) Execute(ctx context.Context, input map[string]any, progre
 		result["next_offset_line"] = returnedEndLine + 1
 		result["hint"] = "File is large or generated; only part was returned. Re-run read_file with start_line=next_offset_line to page, or use `search` for targeted lookups."
 	}
+	if isNotebookPath(targetPath) {
+		result["hint"] = "This is a Jupyter notebook: use notebook_read to see its cells and notebook_edit to change them. Editing the raw JSON corrupts notebooks."
+	}
 
 	return tools.ToolResult{
 		Content:  formatResult(result),
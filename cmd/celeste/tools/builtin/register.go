@@ -4,6 +4,7 @@ import (
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/codegraph"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/shell"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
 )
 
@@ -16,17 +17,27 @@ func RegisterAll(registry *tools.Registry, workspace string, configLoader Config
 		var writeOpts []WriteFileOption
 		var patchOpts []PatchFileOption
 		var spliceOpts []SpliceFileOption
+		var applyOpts []ApplyPatchOption
+		var restoreOpts []GitRestoreOption
+		var nbReadOpts []NotebookReadOption
+		var nbEditOpts []NotebookEditOption
 
 		if tracker != nil {
 			readOpts = append(readOpts, WithReadFileTracker(tracker))
 			writeOpts = append(writeOpts, WithWriteFileTracker(tracker))
 			patchOpts = append(patchOpts, WithPatchFileTracker(tracker))
 			spliceOpts = append(spliceOpts, WithSpliceFileTracker(tracker))
+			applyOpts = append(applyOpts, WithApplyPatchTracker(tracker))
+			nbReadOpts = append(nbReadOpts, WithNotebookReadTracker(tracker))
+			nbEditOpts = append(nbEditOpts, WithNotebookEditTracker(tracker))
 		}
 		if snapshots != nil {
 			writeOpts = append(writeOpts, WithWriteFileSnapshots(snapshots))
 			patchOpts = append(patchOpts, WithPatchFileSnapshots(snapshots))
 			spliceOpts = append(spliceOpts, WithSpliceFileSnapshots(snapshots))
+			applyOpts = append(applyOpts, WithApplyPatchSnapshots(snapshots))
+			restoreOpts = append(restoreOpts, WithGitRestoreSnapshots(snapshots))
+			nbEditOpts = append(nbEditOpts, WithNotebookEditSnapshots(snapshots))
 		}
 
 		registry.RegisterWithModes(NewBashTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
@@ -34,12 +45,25 @@ func RegisterAll(registry *tools.Registry, workspace string, configLoader Config
 		registry.RegisterWithModes(NewWriteFileTool(workspace, writeOpts...), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 		registry.RegisterWithModes(NewPatchFileTool(workspace, patchOpts...), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 		registry.RegisterWithModes(NewSpliceFileTool(workspace, spliceOpts...), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewApplyPatchTool(workspace, applyOpts...), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 		registry.RegisterWithModes(NewListFilesTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 		registry.RegisterWithModes(NewSearchTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewRunTestsTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewNotebookReadTool(workspace, nbReadOpts...), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewNotebookEditTool(workspace, nbEditOpts...), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 
 		// Git tools — available in all modes (read-only, always useful)
 		registry.RegisterWithModes(NewGitStatusTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 		registry.RegisterWithModes(NewGitLogTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewGitDiffTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+
+		// Git write tools — commits go through ConfigureGitCommit's convention
+		// and PreCommit hooks; rewrites need the permissions git policy.
+		registry.RegisterWithModes(NewGitAddTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewGitCommitTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewGitBranchTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewGitStashTool(workspace), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+		registry.RegisterWithModes(NewGitRestoreTool(workspace, restoreOpts...), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 	}
 
 	// Skill tools that require config — Chat and Claw only
@@ -62,6 +86,7 @@ func RegisterAll(registry *tools.Registry, workspace string, configLoader Config
 	// Web tools — available in Agent, Claw, Chat
 	registry.RegisterWithModes(NewWebSearchTool(), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 	registry.RegisterWithModes(NewWebFetchTool(), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+	registry.RegisterWithModes(NewWebResearchTool(), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 
 	// Config-free skill tools — Chat and Claw only
 	registry.RegisterWithModes(NewCurrencyTool(), tools.ModeChat, tools.ModeClaw)
@@ -110,6 +135,15 @@ func RegisterCodeGraphTools(registry *tools.Registry, indexer *codegraph.Indexer
 	registry.RegisterWithModes(NewCodeSnapshotTool(indexer), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
 }
 
+// RegisterShellTools swaps in a bash tool backed by m's persistent shells and
+// background jobs, and registers the job tools. The caller owns m and must
+// Close it when the session ends.
+func RegisterShellTools(registry *tools.Registry, workspace string, m *shell.Manager) {
+	registry.RegisterWithModes(NewBashTool(workspace, WithShellManager(m)), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+	registry.RegisterWithModes(NewJobOutputTool(m), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+	registry.RegisterWithModes(NewJobKillTool(m), tools.ModeAgent, tools.ModeClaw, tools.ModeChat)
+}
+
 // RegisterCollectionsTools registers collections search if active collections exist.
 func RegisterCollectionsTools(registry *tools.Registry, cfg *config.Config) {
 	if cfg.Collections != nil && len(cfg.Collections.ActiveCollections) > 0 && cfg.APIKey != "" {
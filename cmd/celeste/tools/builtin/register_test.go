@@ -10,7 +10,7 @@ import (
 func TestRegisterAll_DevToolsOnly(t *testing.T) {
 	registry := tools.NewRegistry()
 	RegisterAll(registry, t.TempDir(), nil, nil, nil)
-	// 6 dev tools + 2 web tools + 14 config-free skills = 22
+	// Dev tools, web tools and the config-free skills; the exact set grows.
 	assert.True(t, registry.Count() > 6, "expected more than 6 tools, got %d", registry.Count())
 	bash, ok := registry.Get("bash")
 	assert.True(t, ok)
@@ -78,9 +78,9 @@ func TestRegisterReadOnlyDevTools(t *testing.T) {
 func TestToolCount(t *testing.T) {
 	registry := tools.NewRegistry()
 	RegisterAll(registry, t.TempDir(), nil, nil, nil)
-	// 7 dev tools (incl. splice_file) + 2 git tools + 2 web tools + 1 save_memory + 14 config-free skills + 1 todo + 1 tts + 1 audio_render + 1 ask + 1 find_tools = 31
+	// 11 dev tools (incl. splice_file, apply_patch, run_tests, notebook_read, notebook_edit) + 8 git tools + 3 web tools + 1 save_memory + 14 config-free skills + 1 todo + 1 tts + 1 audio_render + 1 ask + 1 find_tools = 42
 	// (config-dependent and code graph tools not registered when configLoader/indexer is nil)
-	assert.Equal(t, 31, registry.Count(), "expected 31 tools without configLoader")
+	assert.Equal(t, 42, registry.Count(), "expected 42 tools without configLoader")
 }
 
 // countingConfigLoader satisfies ConfigLoader so the config-gated tools
@@ -103,15 +103,17 @@ func (countingConfigLoader) GetWalletSecurityConfig() (WalletSecuritySettingsCon
 
 // The counts the README and docs/ advertise. RegisterAll is the always-on set;
 // codegraph registers only once a project is indexed (main.go:398) and
-// collections only when active collections exist. 40 + 6 + 1 = the 47 the docs
-// quote as the full surface. RegisterReadOnlyDevTools is deliberately excluded:
+// collections only when active collections exist, and RegisterShellTools adds
+// the 2 job tools in every chat and agent session; RegisterLSPTools adds 3
+// when a language server is configured. 51 + 2 + 6 + 3 + 1 = the 63 the
+// docs quote as the full surface. RegisterReadOnlyDevTools is deliberately excluded:
 // it is a separate entry point that re-registers three tools RegisterAll
 // already provides, so it contributes no distinct tools.
 //
 // These had drifted to a documented 45 against a real 40/47 because nothing
 // asserted them.
 const (
-	docsCoreToolCount      = 40
+	docsCoreToolCount      = 51
 	docsCodegraphToolCount = 6
 )
 
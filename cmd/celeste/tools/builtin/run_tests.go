@@ -0,0 +1,207 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/testrun"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// RunTestsTool runs the workspace's tests through a framework adapter and
+// returns counts and the failing tests instead of raw runner output.
+type RunTestsTool struct {
+	BaseTool
+	workspace string
+
+	mu   sync.Mutex
+	last *testRun // the previous run, for rerun_failed
+}
+
+type testRun struct {
+	spec   testrun.Spec // without Only, so a rerun of a rerun narrows from the same base
+	result *testrun.Result
+}
+
+// NewRunTestsTool creates a RunTestsTool bound to the given workspace.
+func NewRunTestsTool(workspace string) *RunTestsTool {
+	return &RunTestsTool{
+		BaseTool: BaseTool{
+			ToolName: "run_tests",
+			ToolDescription: "Run the project's tests and get pass/fail/skip counts plus each failing test's name, file:line " +
+				"and trimmed output. Supports go test, pytest, jest, vitest and cargo test; the framework is detected " +
+				"from the workspace unless given. Prefer this over running tests through bash: raw output is often " +
+				"truncated before the failure. Set rerun_failed to run only the failures from the previous run.",
+			ToolParameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"framework": {
+						"type": "string",
+						"enum": ["auto", "go", "pytest", "jest", "vitest", "cargo"],
+						"description": "Test framework. Defaults to auto-detection."
+					},
+					"targets": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "What to run: Go packages (./pkg/...), test files or node ids, or cargo test filters. Defaults to everything."
+					},
+					"args": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Extra runner arguments, e.g. [\"-race\"] or [\"-k\", \"expr\"]."
+					},
+					"rerun_failed": {
+						"type": "boolean",
+						"description": "Rerun only the tests that failed in the previous run_tests call. targets and args are taken from that run."
+					},
+					"timeout_seconds": {
+						"type": "integer",
+						"description": "Timeout in seconds. Defaults to 300, max 1800."
+					}
+				},
+				"required": []
+			}`),
+			Interrupt: tools.InterruptCancel,
+		},
+		workspace: workspace,
+	}
+}
+
+func (t *RunTestsTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	timeoutSeconds := getIntArg(input, "timeout_seconds", 300)
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = 300
+	}
+	if timeoutSeconds > 1800 {
+		timeoutSeconds = 1800
+	}
+	policy := sandbox.PolicyFromContext(ctx)
+
+	framework := getStringArg(input, "framework", "")
+	if framework == "auto" {
+		framework = ""
+	}
+	rerun := getBoolArg(input, "rerun_failed", false)
+
+	var spec testrun.Spec
+	note := ""
+	if rerun {
+		t.mu.Lock()
+		last := t.last
+		t.mu.Unlock()
+		switch {
+		case last == nil:
+			return errResult("rerun_failed: there is no previous run_tests call to rerun"), nil
+		case framework != "" && framework != last.spec.Framework:
+			return errResult(fmt.Sprintf("rerun_failed: the previous run used %s, not %s", last.spec.Framework, framework)), nil
+		case len(last.result.Failures) == 0:
+			return errResult("rerun_failed: the previous run had no failures"), nil
+		}
+		spec = last.spec
+		spec.Only = last.result.Failures
+		if last.result.Omitted > 0 {
+			note = fmt.Sprintf("only the first %d failures of the previous run were rerun; %d more were not", len(spec.Only), last.result.Omitted)
+		}
+	} else {
+		spec = testrun.Spec{
+			Framework: framework,
+			Dir:       t.workspace,
+			Args:      stringListArg(input, "args"),
+			Targets:   stringListArg(input, "targets"),
+		}
+		if spec.Framework == "" {
+			fw, err := testrun.Detect(t.workspace)
+			if err != nil {
+				return errResult(err.Error()), nil
+			}
+			spec.Framework = fw
+		}
+	}
+	spec.Timeout = policy.Timeout(time.Duration(timeoutSeconds) * time.Second)
+	spec.Policy = policy
+
+	if progress != nil {
+		progress <- tools.ProgressEvent{ToolName: t.ToolName, Message: "Running " + spec.Framework + " tests", Percent: -1}
+	}
+	res, err := testrun.Run(ctx, spec)
+	if err != nil {
+		return errResult(err.Error()), nil
+	}
+
+	base := spec
+	base.Only = nil
+	t.mu.Lock()
+	t.last = &testRun{spec: base, result: res}
+	t.mu.Unlock()
+
+	result := testResultMap(res)
+	if rerun {
+		result["rerun"] = true
+	}
+	if note != "" {
+		result["note"] = note
+	}
+	return tools.ToolResult{Content: formatResult(result), Metadata: result, Error: res.Status == testrun.StatusError}, nil
+}
+
+// testResultMap flattens a test result for the model, with each failure's
+// file and line joined as "location".
+func testResultMap(res *testrun.Result) map[string]any {
+	failures := make([]map[string]any, 0, len(res.Failures))
+	for _, f := range res.Failures {
+		entry := map[string]any{"name": f.Title()}
+		if loc := f.Location(); loc != "" {
+			entry["location"] = loc
+		}
+		if f.Output != "" {
+			entry["output"] = f.Output
+		}
+		failures = append(failures, entry)
+	}
+	result := map[string]any{
+		"framework": res.Framework,
+		"command":   res.Command,
+		"status":    res.Status,
+		"passed":    res.Passed,
+		"failed":    res.Failed,
+		"skipped":   res.Skipped,
+		"failures":  failures,
+		"exit_code": res.ExitCode,
+		"seconds":   res.Seconds,
+	}
+	if res.Omitted > 0 {
+		result["failures_omitted"] = res.Omitted
+	}
+	if res.TimedOut {
+		result["timed_out"] = true
+	}
+	if res.Output != "" {
+		result["output"] = res.Output
+	}
+	return result
+}
+
+// stringListArg reads a list of strings, also accepting a single string
+// of space-separated values.
+func stringListArg(args map[string]any, key string) []string {
+	switch v := args[key].(type) {
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return v
+	case string:
+		return strings.Fields(v)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package builtin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTestsTool(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not on PATH")
+	}
+	t.Setenv("GOTOOLCHAIN", "local")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":        "module example.com/m\n\ngo 1.21\n",
+		"m.go":          "package m\n\nfunc Double(n int) int { return n + 2 }\n",
+		"m_test.go":     "package m\n\nimport \"testing\"\n\nfunc TestDouble(t *testing.T) {\n\tif Double(3) != 6 {\n\t\tt.Fatalf(\"Double(3) = %d\", Double(3))\n\t}\n}\n\nfunc TestZero(t *testing.T) {\n\tif Double(0) != 0 {\n\t\tt.Skip(\"known\")\n\t}\n}\n",
+		"ok/ok_test.go": "package ok\n\nimport \"testing\"\n\nfunc TestOK(t *testing.T) {}\n",
+	})
+	tool := NewRunTestsTool(dir)
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, map[string]any{"rerun_failed": true}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, res.Content, "no previous run_tests call")
+
+	res, err = tool.Execute(ctx, map[string]any{}, nil)
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	data := decodeResult(t, res)
+	assert.Equal(t, "go", data["framework"])
+	assert.Equal(t, "failed", data["status"])
+	assert.Equal(t, float64(1), data["passed"])
+	assert.Equal(t, float64(1), data["failed"])
+	assert.Equal(t, float64(1), data["skipped"])
+	assert.Equal(t, []any{map[string]any{
+		"name":     "example.com/m TestDouble",
+		"location": "m_test.go:7",
+		"output":   "m_test.go:7: Double(3) = 5",
+	}}, data["failures"])
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte("package m\n\nfunc Double(n int) int { return n * 2 }\n"), 0644))
+	res, err = tool.Execute(ctx, map[string]any{"rerun_failed": true}, nil)
+	require.NoError(t, err)
+	data = decodeResult(t, res)
+	assert.Equal(t, "passed", data["status"])
+	assert.Equal(t, true, data["rerun"])
+	assert.Equal(t, float64(1), data["passed"], "only TestDouble ran")
+	assert.Contains(t, data["command"], "-run '^(TestDouble)$' example.com/m")
+
+	res, err = tool.Execute(ctx, map[string]any{"rerun_failed": true}, nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "no failures")
+}
@@ -18,6 +18,7 @@ import (
 // silent-corruption vector. It is the deterministic counterpart to patch_file.
 type SpliceFileTool struct {
 	BaseTool
+	editDiagnostics
 	workspace string
 	tracker   *checkpoints.FileTracker
 	snapMgr   *checkpoints.SnapshotManager
@@ -185,6 +186,11 @@ func (t *SpliceFileTool) Execute(ctx context.Context, input map[string]any, prog
 		"dest_bytes":         len(newDest),
 		"routed_through_llm": false,
 	}
+	if sameFile {
+		t.attach(ctx, result, destPath)
+	} else {
+		t.attach(ctx, result, sourcePath, destPath)
+	}
 	return tools.ToolResult{Content: formatResult(result), Metadata: result}, nil
 }
 
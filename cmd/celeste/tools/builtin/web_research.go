@@ -0,0 +1,122 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/research"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+// WebResearchTool searches the web, reads the top results and returns the
+// passages that answer the query, numbered for citation.
+type WebResearchTool struct {
+	BaseTool
+	pipeline research.Pipeline
+
+	mu          sync.Mutex
+	searchCount int
+}
+
+// NewWebResearchTool creates a WebResearchTool that searches DuckDuckGo and
+// caches pages under ~/.celeste/cache/web. ConfigureResearch changes both.
+func NewWebResearchTool() *WebResearchTool {
+	cache, _ := research.DefaultCache() // nil without a home directory: no caching
+	return &WebResearchTool{
+		BaseTool: BaseTool{
+			ToolName: "web_research",
+			ToolDescription: "Research a question on the web: searches, fetches the top results concurrently, keeps each page's main content " +
+				"(no menus or cookie banners) and returns the passages most relevant to the query with numbered sources. " +
+				"Cite passages by their source number, e.g. [2]. Pages are cached, so repeating a query is cheap. Limited to 10 queries per session.",
+			ToolParameters: mustJSON(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{
+						"type":        "string",
+						"description": "The question or search query.",
+					},
+					"max_pages": map[string]any{
+						"type":        "integer",
+						"description": fmt.Sprintf("Search results to read (1-10, default %d).", research.DefaultMaxPages),
+					},
+					"max_passages": map[string]any{
+						"type":        "integer",
+						"description": fmt.Sprintf("Passages to return (default %d).", research.DefaultMaxPassages),
+					},
+				},
+				"required": []string{"query"},
+			}),
+			ReadOnly:        true,
+			ConcurrencySafe: true,
+			Interrupt:       tools.InterruptCancel,
+			RequiredFields:  []string{"query"},
+		},
+		pipeline: research.Pipeline{
+			Backend: &research.DuckDuckGo{Client: &http.Client{Timeout: 15 * time.Second}},
+			Fetcher: research.NewFetcher(cache),
+		},
+	}
+}
+
+// ConfigureResearch points web_search and web_research at backend and
+// web_research's page fetches at cache. A nil cache keeps the default.
+func ConfigureResearch(registry *tools.Registry, backend research.Backend, cache *research.Cache) {
+	if tool, ok := registry.Get("web_search"); ok {
+		if t, ok := tool.(*WebSearchTool); ok {
+			t.backend = backend
+		}
+	}
+	if tool, ok := registry.Get("web_research"); ok {
+		if t, ok := tool.(*WebResearchTool); ok {
+			t.pipeline.Backend = backend
+			if cache != nil {
+				t.pipeline.Fetcher = research.NewFetcher(cache)
+			}
+		}
+	}
+}
+
+func (t *WebResearchTool) Execute(ctx context.Context, input map[string]any, progress chan<- tools.ProgressEvent) (tools.ToolResult, error) {
+	query := getStringArg(input, "query", "")
+	if query == "" {
+		return resultFromMap(formatErrorResponse("validation_error", "query is required", "", nil))
+	}
+
+	t.mu.Lock()
+	if t.searchCount >= maxSearchesPerSession {
+		t.mu.Unlock()
+		return resultFromMap(formatErrorResponse(
+			"rate_limit",
+			fmt.Sprintf("Research limit reached (%d/%d per session)", maxSearchesPerSession, maxSearchesPerSession),
+			"Answer from the sources already gathered.",
+			nil,
+		))
+	}
+	t.searchCount++
+	t.mu.Unlock()
+
+	pipeline := t.pipeline
+	pipeline.MaxPages = getIntArg(input, "max_pages", 0)
+	pipeline.MaxPassages = getIntArg(input, "max_passages", 0)
+	if progress != nil {
+		progress <- tools.ProgressEvent{ToolName: t.ToolName, Message: fmt.Sprintf("Researching %q via %s", query, pipeline.Backend.Name()), Percent: -1}
+	}
+	report, err := pipeline.Run(ctx, query)
+	if err != nil {
+		return resultFromMap(formatErrorResponse("api_error", err.Error(), "The search service may be temporarily unavailable.", nil))
+	}
+	note := "Cite passages by source id, e.g. [1]."
+	if len(report.Passages) == 0 {
+		note = "No passage in the fetched pages matches the query. Try different terms."
+	}
+	return resultFromMap(map[string]any{
+		"query":    report.Query,
+		"backend":  report.Backend,
+		"sources":  report.Sources,
+		"passages": report.Passages,
+		"note":     note,
+	})
+}
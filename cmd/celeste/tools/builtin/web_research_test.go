@@ -0,0 +1,48 @@
+package builtin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/research"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools"
+)
+
+func TestWebResearchTool_LocalBackend(t *testing.T) {
+	cache := research.NewCache(t.TempDir())
+	require.NoError(t, cache.Put(&research.Page{
+		URL:       "http://127.0.0.1:1/retries",
+		Title:     "Configuring retries",
+		Content:   "# Retries\n\nSet max_retries in the client options to change the retry limit.",
+		FetchedAt: time.Now(),
+	}))
+	backend, err := research.NewBackend(research.BackendConfig{Name: research.BackendLocal}, cache)
+	require.NoError(t, err)
+
+	registry := tools.NewRegistry()
+	registry.Register(NewWebSearchTool())
+	registry.Register(NewWebResearchTool())
+	ConfigureResearch(registry, backend, cache)
+
+	res, err := registry.Execute(context.Background(), "web_research", map[string]any{"query": "retry limit"})
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	out := decodeResult(t, res)
+	assert.Equal(t, "local", out["backend"])
+	sources := out["sources"].([]any)
+	require.Len(t, sources, 1)
+	assert.Equal(t, "http://127.0.0.1:1/retries", sources[0].(map[string]any)["url"])
+	assert.Equal(t, true, sources[0].(map[string]any)["from_cache"])
+	passages := out["passages"].([]any)
+	require.Len(t, passages, 1)
+	assert.Equal(t, float64(1), passages[0].(map[string]any)["source"])
+	assert.Equal(t, "Retries", passages[0].(map[string]any)["heading"])
+
+	res, err = registry.Execute(context.Background(), "web_search", map[string]any{"query": "retry limit"})
+	require.NoError(t, err)
+	assert.Contains(t, res.Content, "Configuring retries", "web_search uses the configured backend too")
+}
@@ -15,6 +15,7 @@ import (
 // WriteFileTool writes text files to the workspace.
 type WriteFileTool struct {
 	BaseTool
+	editDiagnostics
 	workspace string
 	tracker   *checkpoints.FileTracker
 	snapMgr   *checkpoints.SnapshotManager
@@ -145,6 +146,7 @@ func (t *WriteFileTool) Execute(ctx context.Context, input map[string]any, progr
 		"bytes_written": bytesWritten,
 		"append":        appendMode,
 	}
+	t.attach(ctx, result, targetPath)
 
 	return tools.ToolResult{
 		Content:  formatResult(result),
@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCachedResultBytes is the largest result the cache will stand in for.
+// Bigger results may have been trimmed on the way to the model, so a
+// reference to them could point at text the model never saw.
+const maxCachedResultBytes = 48 * 1024
+
+// cachePathKeys are the input keys whose values name files or directories.
+var cachePathKeys = []string{"path", "paths", "file", "files", "source", "dest"}
+
+// ResultCache spares the model results it has already seen. When a
+// read-only tool is called again with the same input and returns the same
+// content, the registry sends a short "unchanged since turn N" reference
+// instead. If the input names only regular files and their mtimes and sizes
+// are unchanged, and no mutating tool has run since, the tool is not run at
+// all.
+//
+// A mutating tool call forgets the entries whose paths it names, or every
+// entry when its paths are unknown, so the next read sends the result in
+// full. Callers must Reset the cache when earlier results leave the
+// conversation, for example on compaction.
+type ResultCache struct {
+	mu        sync.Mutex
+	workspace string
+	turn      int
+	gen       int // bumped by every mutating call
+	entries   map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	turn   int
+	hash   [sha256.Size]byte
+	deps   []string
+	stamps []fileStamp // nil unless every dep is a regular file
+	gen    int
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewResultCache creates a cache that resolves relative paths in tool input
+// against workspace.
+func NewResultCache(workspace string) *ResultCache {
+	return &ResultCache{workspace: workspace, entries: make(map[string]*cacheEntry)}
+}
+
+// SetTurn sets the turn that new results are recorded under.
+func (c *ResultCache) SetTurn(turn int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.turn = turn
+}
+
+// Reset forgets every result.
+func (c *ResultCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.gen++
+}
+
+// lookup returns the reference for a read-only call that can skip running.
+func (c *ResultCache) lookup(name string, input map[string]any) (ToolResult, bool) {
+	key, ok := cacheKey(name, input)
+	if !ok {
+		return ToolResult{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[key]
+	if e == nil || e.gen != c.gen || e.stamps == nil || !stampsMatch(e.deps, e.stamps) {
+		return ToolResult{}, false
+	}
+	return unchangedResult(name, e.turn), true
+}
+
+// record stores a read-only tool's result and returns what to send: the
+// result itself, or a reference when an identical one was sent before.
+func (c *ResultCache) record(name string, input map[string]any, result ToolResult) ToolResult {
+	key, ok := cacheKey(name, input)
+	if !ok {
+		return result
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if result.Error {
+		return result
+	}
+	if len(result.Content) > maxCachedResultBytes {
+		delete(c.entries, key)
+		return result
+	}
+	hash := sha256.Sum256([]byte(result.Content))
+	deps := c.deps(input)
+	stamps := statFiles(deps)
+	if e := c.entries[key]; e != nil && e.hash == hash {
+		e.stamps, e.gen = stamps, c.gen
+		return unchangedResult(name, e.turn)
+	}
+	c.entries[key] = &cacheEntry{turn: c.turn, hash: hash, deps: deps, stamps: stamps, gen: c.gen}
+	return result
+}
+
+// invalidate forgets the entries a mutating call may have changed.
+func (c *ResultCache) invalidate(input map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	paths := c.inputPaths(input)
+	if len(paths) == 0 {
+		c.entries = make(map[string]*cacheEntry)
+		return
+	}
+	for key, e := range c.entries {
+		if pathsOverlap(e.deps, paths) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// deps returns the absolute paths a read-only call depends on: the paths in
+// its input, or the whole workspace.
+func (c *ResultCache) deps(input map[string]any) []string {
+	if paths := c.inputPaths(input); len(paths) > 0 {
+		return paths
+	}
+	return []string{filepath.Clean(c.workspace)}
+}
+
+func (c *ResultCache) inputPaths(input map[string]any) []string {
+	var paths []string
+	for _, p := range pathValues(input) {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(c.workspace, p)
+		}
+		paths = append(paths, filepath.Clean(p))
+	}
+	return paths
+}
+
+func pathValues(input map[string]any) []string {
+	var out []string
+	for _, key := range cachePathKeys {
+		switch v := input[key].(type) {
+		case string:
+			if v != "" {
+				out = append(out, v)
+			}
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok && s != "" {
+					out = append(out, s)
+				}
+			}
+		case []string:
+			out = append(out, v...)
+		}
+	}
+	return out
+}
+
+// cacheKey is the tool name and its input with path values cleaned. Map
+// keys marshal in sorted order, so equal inputs give equal keys.
+func cacheKey(name string, input map[string]any) (string, bool) {
+	normalized := make(map[string]any, len(input))
+	for k, v := range input {
+		normalized[k] = v
+	}
+	for _, key := range cachePathKeys {
+		if s, ok := normalized[key].(string); ok && s != "" {
+			normalized[key] = filepath.Clean(s)
+		}
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", false
+	}
+	return name + "\x00" + string(data), true
+}
+
+func statFiles(paths []string) []fileStamp {
+	stamps := make([]fileStamp, 0, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		stamps = append(stamps, fileStamp{size: info.Size(), modTime: info.ModTime()})
+	}
+	return stamps
+}
+
+func stampsMatch(paths []string, stamps []fileStamp) bool {
+	current := statFiles(paths)
+	if len(current) != len(stamps) {
+		return false
+	}
+	for i := range stamps {
+		if current[i].size != stamps[i].size || !current[i].modTime.Equal(stamps[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathsOverlap reports whether any path in a equals, contains or lies
+// inside any path in b.
+func pathsOverlap(a, b []string) bool {
+	within := func(p, dir string) bool {
+		return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if within(x, y) || within(y, x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unchangedResult(name string, turn int) ToolResult {
+	return ToolResult{
+		Content:  fmt.Sprintf("[unchanged since turn %d: %s returned the same result for these arguments then; refer to that output]", turn, name),
+		Metadata: map[string]any{"unchanged_since_turn": turn},
+	}
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheRegistry registers a read-only read_file that counts its runs, a
+// read-only list_files, and a mutating write_file.
+func cacheRegistry(t *testing.T, workspace string) (*Registry, *ResultCache, *int) {
+	t.Helper()
+	runs := 0
+	r := NewRegistry()
+	r.Register(&mockTool{name: "read_file", readOnly: true,
+		executeFunc: func(ctx context.Context, input map[string]any, progress chan<- ProgressEvent) (ToolResult, error) {
+			runs++
+			data, err := os.ReadFile(filepath.Join(workspace, input["path"].(string)))
+			if err != nil {
+				return ToolResult{Content: err.Error(), Error: true}, nil
+			}
+			return ToolResult{Content: string(data)}, nil
+		}})
+	r.Register(&mockTool{name: "list_files", readOnly: true,
+		executeFunc: func(ctx context.Context, input map[string]any, progress chan<- ProgressEvent) (ToolResult, error) {
+			entries, _ := os.ReadDir(workspace)
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			return ToolResult{Content: strings.Join(names, "\n")}, nil
+		}})
+	r.Register(&mockTool{name: "write_file",
+		executeFunc: func(ctx context.Context, input map[string]any, progress chan<- ProgressEvent) (ToolResult, error) {
+			path := filepath.Join(workspace, input["path"].(string))
+			return ToolResult{}, os.WriteFile(path, []byte(input["content"].(string)), 0644)
+		}})
+	cache := NewResultCache(workspace)
+	r.SetResultCache(cache)
+	return r, cache, &runs
+}
+
+func TestResultCache_ReadFile(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "a.txt"), []byte("alpha"), 0644))
+	r, cache, runs := cacheRegistry(t, workspace)
+	ctx := context.Background()
+
+	cache.SetTurn(1)
+	res, err := r.Execute(ctx, "read_file", map[string]any{"path": "a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", res.Content)
+
+	cache.SetTurn(3)
+	res, err = r.Execute(ctx, "read_file", map[string]any{"path": "./a.txt"})
+	require.NoError(t, err)
+	assert.Contains(t, res.Content, "unchanged since turn 1")
+	assert.Equal(t, map[string]any{"unchanged_since_turn": 1}, res.Metadata)
+	assert.Equal(t, 1, *runs, "an unchanged file is not read again")
+
+	res, err = r.Execute(ctx, "read_file", map[string]any{"path": "a.txt", "limit": 10})
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", res.Content, "different arguments are a different entry")
+
+	_, err = r.Execute(ctx, "write_file", map[string]any{"path": "a.txt", "content": "beta"})
+	require.NoError(t, err)
+	cache.SetTurn(4)
+	res, err = r.Execute(ctx, "read_file", map[string]any{"path": "a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "beta", res.Content, "a write to the path sends the result in full")
+
+	// A change made outside the tools is caught by the mtime and size.
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "a.txt"), []byte("gamma!"), 0644))
+	cache.SetTurn(5)
+	res, err = r.Execute(ctx, "read_file", map[string]any{"path": "a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "gamma!", res.Content)
+
+	res, err = r.Execute(ctx, "read_file", map[string]any{"path": "missing.txt"})
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	res, err = r.Execute(ctx, "read_file", map[string]any{"path": "missing.txt"})
+	require.NoError(t, err)
+	assert.True(t, res.Error, "errors are never replaced by a reference")
+}
+
+func TestResultCache_DirectoryResults(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "a.txt"), []byte("alpha"), 0644))
+	r, cache, _ := cacheRegistry(t, workspace)
+	ctx := context.Background()
+
+	cache.SetTurn(1)
+	res, err := r.Execute(ctx, "list_files", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", res.Content)
+
+	cache.SetTurn(2)
+	res, err = r.Execute(ctx, "list_files", nil)
+	require.NoError(t, err)
+	assert.Contains(t, res.Content, "unchanged since turn 1", "same listing, so a reference")
+
+	_, err = r.Execute(ctx, "write_file", map[string]any{"path": "b.txt", "content": "b"})
+	require.NoError(t, err)
+	cache.SetTurn(3)
+	res, err = r.Execute(ctx, "list_files", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt\nb.txt", res.Content, "a write inside the workspace forgets the listing")
+
+	cache.Reset()
+	res, err = r.Execute(ctx, "list_files", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt\nb.txt", res.Content, "Reset sends everything in full again")
+}
+
+func TestResultCache_LargeResultsAreNotReferenced(t *testing.T) {
+	workspace := t.TempDir()
+	big := strings.Repeat("x", maxCachedResultBytes+1)
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "big.txt"), []byte(big), 0644))
+	r, _, runs := cacheRegistry(t, workspace)
+
+	for i := 0; i < 2; i++ {
+		res, err := r.Execute(context.Background(), "read_file", map[string]any{"path": "big.txt"})
+		require.NoError(t, err)
+		assert.Equal(t, big, res.Content)
+	}
+	assert.Equal(t, 2, *runs)
+}
+
+func TestPathsOverlap(t *testing.T) {
+	assert.True(t, pathsOverlap([]string{"/ws/a.txt"}, []string{"/ws/a.txt"}))
+	assert.True(t, pathsOverlap([]string{"/ws"}, []string{"/ws/sub/a.txt"}))
+	assert.True(t, pathsOverlap([]string{"/ws/sub/a.txt"}, []string{"/ws/sub"}))
+	assert.False(t, pathsOverlap([]string{"/ws/a.txt"}, []string{"/ws/ab.txt"}))
+	assert.False(t, pathsOverlap([]string{"/ws/sub"}, []string{"/ws/subdir"}))
+}
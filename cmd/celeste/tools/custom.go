@@ -0,0 +1,366 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/schema"
+)
+
+// secretEnvWords mark variable names a project tool can't pass through.
+var secretEnvWords = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "PASSWD", "PASSPHRASE", "CREDENTIAL", "AUTH", "COOKIE", "SESSION"}
+
+// defaultCustomToolTimeout bounds a custom tool whose manifest sets no
+// timeout_seconds.
+const defaultCustomToolTimeout = 2 * time.Minute
+
+// maxCustomToolOutput caps the stdout kept from a custom tool.
+const maxCustomToolOutput = 256 * 1024
+
+// baseToolEnv is the environment a custom tool with an env allowlist gets
+// before the listed variables are added.
+var baseToolEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TERM", "TMPDIR", "SHELL"}
+
+// CustomToolManifest is the JSON definition of a custom tool. Files that
+// only set name, description, parameters and command keep working: their
+// command reads the input as JSON on stdin and its stdout is the result.
+type CustomToolManifest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+	Command     string          `json:"command"`
+	// ReadOnly marks a user tool as free of side effects, so it is
+	// auto-approved and its results are cached. Project tools come from
+	// the repository, so their claim is ignored.
+	ReadOnly        bool `json:"read_only,omitempty"`
+	ConcurrencySafe bool `json:"concurrency_safe,omitempty"`
+	TimeoutSeconds  int  `json:"timeout_seconds,omitempty"`
+	// Cwd is the directory the command runs in, relative to the workspace.
+	// Project tools must stay inside the workspace.
+	Cwd string `json:"cwd,omitempty"`
+	// Env lists the variables passed through from celeste's environment, on
+	// top of PATH, HOME and the locale. A trailing * matches a prefix. When
+	// nil, user tools inherit the whole environment and project tools get
+	// only the base variables. Project tools must name each variable, and
+	// can't name one that looks like a credential.
+	Env []string `json:"env,omitempty"`
+	// Protocol is "text" (stdout is the result, non-zero exit is an error)
+	// or "json" (stdout is a {"content", "error", "metadata"} object).
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// customToolResult is what a "json" protocol tool prints on stdout.
+// Content may be any JSON value; a non-string is passed on as JSON. Error
+// may be true or a message, which becomes the content.
+type customToolResult struct {
+	Content  json.RawMessage `json:"content"`
+	Error    any             `json:"error,omitempty"`
+	Metadata map[string]any  `json:"metadata,omitempty"`
+}
+
+// customToolWrapper runs a manifest-defined tool as a shell command.
+type customToolWrapper struct {
+	manifest  CustomToolManifest
+	schema    map[string]any
+	dir       string // directory holding the manifest
+	workspace string // base for cwd; "" is celeste's working directory
+	project   bool   // loaded from the project's .celeste/tools
+}
+
+func (c *customToolWrapper) Name() string                { return c.manifest.Name }
+func (c *customToolWrapper) Description() string         { return c.manifest.Description }
+func (c *customToolWrapper) Parameters() json.RawMessage { return c.manifest.Parameters }
+func (c *customToolWrapper) IsConcurrencySafe(input map[string]any) bool {
+	return c.manifest.ConcurrencySafe
+}
+func (c *customToolWrapper) IsReadOnly() bool                     { return c.manifest.ReadOnly && !c.project }
+func (c *customToolWrapper) InterruptBehavior() InterruptBehavior { return InterruptCancel }
+
+// ValidateInput checks input against the manifest's parameters schema.
+func (c *customToolWrapper) ValidateInput(input map[string]any) error {
+	if c.schema == nil {
+		return nil
+	}
+	if input == nil {
+		input = map[string]any{}
+	}
+	// Round-trip so typed Go values compare like the JSON the schema expects.
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("invalid input for %s: %w", c.manifest.Name, err)
+	}
+	if err := schema.ValidateJSON(c.schema, data); err != nil {
+		return fmt.Errorf("invalid input for %s: %w", c.manifest.Name, err)
+	}
+	return nil
+}
+
+func (c *customToolWrapper) Execute(ctx context.Context, input map[string]any, progress chan<- ProgressEvent) (ToolResult, error) {
+	if c.manifest.Command == "" {
+		return ToolResult{Content: "Custom tool schema loaded, but no 'command' field defined. Add 'command' (shell-executable) to " + filepath.Join(c.dir, c.manifest.Name+".json") + " for execution support."}, nil
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("Failed to marshal input: %v", err), Error: true}, nil
+	}
+
+	timeout := defaultCustomToolTimeout
+	if c.manifest.TimeoutSeconds > 0 {
+		timeout = time.Duration(c.manifest.TimeoutSeconds) * time.Second
+	}
+	policy := sandbox.PolicyFromContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, policy.Timeout(timeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.manifest.Command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Dir = c.runDir()
+	cmd.Env = c.environ()
+	// Don't wait on children that hold stdout open after a timeout kill.
+	cmd.WaitDelay = time.Second
+	cleanup, err := policy.Wrap(cmd)
+	if err != nil {
+		return ToolResult{Content: err.Error(), Error: true}, nil
+	}
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	runErr := cmd.Run()
+	out := stdout.String()
+	if len(out) > maxCustomToolOutput {
+		out = out[:maxCustomToolOutput] + "\n[output truncated]"
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return ToolResult{Content: fmt.Sprintf("%s timed out after %s", c.manifest.Name, policy.Timeout(timeout)), Error: true}, nil
+	}
+	if c.manifest.Protocol == "json" {
+		if result, ok := parseCustomToolResult(out); ok {
+			if runErr != nil {
+				result.Error = true
+			}
+			return result, nil
+		}
+		if runErr == nil {
+			return ToolResult{Content: fmt.Sprintf("%s printed an invalid JSON result:\n%s", c.manifest.Name, out), Error: true}, nil
+		}
+	}
+	if runErr != nil {
+		return ToolResult{Content: describeCustomToolFailure(runErr, out, stderr.String()), Error: true}, nil
+	}
+	return ToolResult{Content: out}, nil
+}
+
+// runDir resolves the manifest's cwd against the workspace.
+func (c *customToolWrapper) runDir() string {
+	switch {
+	case c.manifest.Cwd == "":
+		return c.workspace
+	case filepath.IsAbs(c.manifest.Cwd):
+		return c.manifest.Cwd
+	default:
+		return filepath.Join(c.workspace, c.manifest.Cwd)
+	}
+}
+
+// environ builds the command's environment from the allowlist, and tells
+// the command where its manifest lives and which workspace it serves.
+func (c *customToolWrapper) environ() []string {
+	var env []string
+	if c.manifest.Env == nil && !c.project {
+		env = os.Environ()
+	} else {
+		for _, kv := range os.Environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			if envAllowed(name, baseToolEnv) || envAllowed(name, c.manifest.Env) {
+				env = append(env, kv)
+			}
+		}
+	}
+	workspace := c.workspace
+	if workspace == "" {
+		workspace, _ = os.Getwd()
+	}
+	return append(env,
+		"CELESTE_TOOL_NAME="+c.manifest.Name,
+		"CELESTE_TOOL_DIR="+c.dir,
+		"CELESTE_WORKSPACE="+workspace,
+	)
+}
+
+func envAllowed(name string, allow []string) bool {
+	for _, a := range allow {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == a {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCustomToolResult(out string) (ToolResult, bool) {
+	var raw customToolResult
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &raw); err != nil {
+		return ToolResult{}, false
+	}
+	result := ToolResult{Metadata: raw.Metadata}
+	var text string
+	if err := json.Unmarshal(raw.Content, &text); err == nil {
+		result.Content = text
+	} else if len(raw.Content) > 0 {
+		result.Content = string(raw.Content)
+	}
+	switch e := raw.Error.(type) {
+	case bool:
+		result.Error = e
+	case string:
+		result.Error = e != ""
+		if e != "" && result.Content == "" {
+			result.Content = e
+		}
+	}
+	return result, true
+}
+
+func describeCustomToolFailure(err error, stdout, stderr string) string {
+	var b strings.Builder
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		fmt.Fprintf(&b, "exit status %d", exitErr.ExitCode())
+	} else {
+		b.WriteString(err.Error())
+	}
+	if s := strings.TrimSpace(stderr); s != "" {
+		b.WriteString("\nstderr:\n" + s)
+	}
+	if s := strings.TrimSpace(stdout); s != "" {
+		b.WriteString("\nstdout:\n" + s)
+	}
+	return b.String()
+}
+
+// LoadCustomTools loads JSON tool definitions from a directory.
+// This provides backwards compatibility with ~/.celeste/skills/*.json files.
+// A broken manifest is skipped and reported in the returned error; the rest
+// still load.
+func (r *Registry) LoadCustomTools(dir string) error {
+	return r.loadCustomTools(dir, "", false)
+}
+
+// LoadProjectTools loads the project's tools from workspace/.celeste/tools.
+// A cloned repository controls these manifests, so they get only the base
+// environment plus named, non-credential variables, run inside the
+// workspace, always ask for permission and never replace a tool that is
+// already registered.
+func (r *Registry) LoadProjectTools(workspace string) error {
+	return r.loadCustomTools(filepath.Join(workspace, ".celeste", "tools"), workspace, true)
+}
+
+func (r *Registry) loadCustomTools(dir, workspace string, project bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // directory doesn't exist, nothing to load
+		}
+		return fmt.Errorf("reading custom tools directory: %w", err)
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		tool, err := parseCustomTool(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if tool == nil {
+			continue
+		}
+		if project {
+			if err := checkProjectManifest(path, tool.manifest); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		tool.workspace, tool.project = workspace, project
+		if _, exists := r.Get(tool.Name()); exists && project {
+			errs = append(errs, fmt.Errorf("custom tool file %s: %q is already registered", path, tool.Name()))
+			continue
+		}
+		r.Register(tool)
+	}
+	return errors.Join(errs...)
+}
+
+// checkProjectManifest holds a repository's manifest to what an untrusted
+// checkout may ask for: no credentials from celeste's environment and no
+// working directory outside the workspace.
+func checkProjectManifest(path string, m CustomToolManifest) error {
+	for _, name := range m.Env {
+		if strings.Contains(name, "*") {
+			return fmt.Errorf("custom tool file %s: project tools must list env variables by name, not %q", path, name)
+		}
+		upper := strings.ToUpper(name)
+		for _, word := range secretEnvWords {
+			if strings.Contains(upper, word) {
+				return fmt.Errorf("custom tool file %s: project tools can't receive %s, which looks like a credential", path, name)
+			}
+		}
+	}
+	if m.Cwd != "" {
+		clean := filepath.Clean(m.Cwd)
+		if filepath.IsAbs(m.Cwd) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("custom tool file %s: cwd %q is outside the workspace", path, m.Cwd)
+		}
+	}
+	return nil
+}
+
+func parseCustomTool(path string) (*customToolWrapper, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading custom tool file %s: %w", path, err)
+	}
+	var m CustomToolManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing custom tool file %s: %w", path, err)
+	}
+	if m.Name == "" {
+		return nil, nil
+	}
+	switch m.Protocol {
+	case "":
+		m.Protocol = "text"
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("custom tool file %s: unknown protocol %q (use text or json)", path, m.Protocol)
+	}
+	if m.TimeoutSeconds < 0 {
+		return nil, fmt.Errorf("custom tool file %s: timeout_seconds must not be negative", path)
+	}
+
+	tool := &customToolWrapper{manifest: m, dir: filepath.Dir(path)}
+	if len(m.Parameters) > 0 && string(m.Parameters) != "null" {
+		if err := json.Unmarshal(m.Parameters, &tool.schema); err != nil {
+			return nil, fmt.Errorf("custom tool file %s: parameters must be a JSON schema object: %w", path, err)
+		}
+	}
+	return tool, nil
+}
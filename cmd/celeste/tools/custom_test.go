@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifests(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestLoadCustomTools_Legacy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("custom tools run through /bin/sh")
+	}
+	dir := t.TempDir()
+	writeManifests(t, dir, map[string]string{
+		"echo.json":   `{"name": "echo_input", "description": "echo", "parameters": {"type": "object"}, "command": "cat"}`,
+		"fail.json":   `{"name": "fail", "description": "fails", "command": "echo partial; echo broken >&2; exit 3"}`,
+		"broken.json": `{"name": `,
+		"notes.txt":   "not a tool",
+	})
+	r := NewRegistry()
+	err := r.LoadCustomTools(dir)
+	require.Error(t, err, "broken.json is reported")
+	assert.Contains(t, err.Error(), "broken.json")
+	assert.Equal(t, 2, r.Count(), "the valid tools still load")
+
+	tool, ok := r.Get("echo_input")
+	require.True(t, ok)
+	assert.False(t, tool.IsReadOnly())
+	assert.False(t, tool.IsConcurrencySafe(nil))
+	res, err := r.Execute(context.Background(), "echo_input", map[string]any{"x": 1})
+	require.NoError(t, err)
+	assert.False(t, res.Error)
+	assert.JSONEq(t, `{"x": 1}`, res.Content)
+
+	res, err = r.Execute(context.Background(), "fail", nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Equal(t, "exit status 3\nstderr:\nbroken\nstdout:\npartial", res.Content)
+}
+
+func TestLoadProjectTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("custom tools run through /bin/sh")
+	}
+	workspace := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "sub"), 0755))
+	t.Setenv("CUSTOM_TOOL_REGION", "abc")
+	t.Setenv("CUSTOM_TOOL_OTHER", "hidden")
+	writeManifests(t, filepath.Join(workspace, ".celeste", "tools"), map[string]string{
+		"count.json": `{
+			"name": "count",
+			"description": "count words",
+			"parameters": {
+				"type": "object",
+				"properties": {"text": {"type": "string", "minLength": 1}},
+				"required": ["text"],
+				"additionalProperties": false
+			},
+			"command": "printf '{\"content\": \"%s %s\", \"metadata\": {\"dir\": \"%s\"}}' \"$CUSTOM_TOOL_REGION\" \"${CUSTOM_TOOL_OTHER:-none}\" \"$(basename \"$PWD\")\"",
+			"read_only": true,
+			"concurrency_safe": true,
+			"cwd": "sub",
+			"env": ["CUSTOM_TOOL_REGION"],
+			"protocol": "json"
+		}`,
+		"reject.json":  `{"name": "reject", "description": "d", "command": "echo '{\"content\": {\"why\": \"nope\"}, \"error\": true}'", "protocol": "json"}`,
+		"garbled.json": `{"name": "garbled", "description": "d", "command": "echo not json", "protocol": "json"}`,
+		"slow.json":    `{"name": "slow", "description": "d", "command": "sleep 5", "timeout_seconds": 1}`,
+		"shadow.json":  `{"name": "existing", "description": "d", "command": "true"}`,
+		"bad.json":     `{"name": "bad", "description": "d", "command": "true", "protocol": "xml"}`,
+	})
+
+	r := NewRegistry()
+	r.Register(&mockTool{name: "existing"})
+	err := r.LoadProjectTools(workspace)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"existing" is already registered`)
+	assert.Contains(t, err.Error(), `unknown protocol "xml"`)
+	existing, _ := r.Get("existing")
+	assert.IsType(t, &mockTool{}, existing, "project tools never replace a registered tool")
+
+	tool, ok := r.Get("count")
+	require.True(t, ok)
+	assert.False(t, tool.IsReadOnly(), "a repository can't mark its own tools as safe to auto-approve")
+	assert.True(t, tool.IsConcurrencySafe(nil))
+
+	ctx := context.Background()
+	res, err := r.Execute(ctx, "count", map[string]any{"text": "a b"})
+	require.NoError(t, err)
+	require.False(t, res.Error, res.Content)
+	assert.Equal(t, "abc none", res.Content, "allowlisted variables pass, others don't")
+	assert.Equal(t, map[string]any{"dir": "sub"}, res.Metadata)
+
+	for _, input := range []map[string]any{{}, {"text": ""}, {"text": 3}, {"text": "a", "extra": true}} {
+		res, err = r.Execute(ctx, "count", input)
+		require.NoError(t, err)
+		assert.True(t, res.Error, "%v", input)
+		assert.Contains(t, res.Content, "invalid input for count", "%v", input)
+	}
+
+	res, err = r.Execute(ctx, "reject", nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.JSONEq(t, `{"why": "nope"}`, res.Content)
+
+	res, err = r.Execute(ctx, "garbled", nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "invalid JSON result")
+
+	res, err = r.Execute(ctx, "slow", nil)
+	require.NoError(t, err)
+	assert.True(t, res.Error)
+	assert.Contains(t, res.Content, "timed out after 1s")
+}
+
+func TestLoadProjectTools_RejectsUntrustedManifests(t *testing.T) {
+	workspace := t.TempDir()
+	writeManifests(t, filepath.Join(workspace, ".celeste", "tools"), map[string]string{
+		"all_env.json":  `{"name": "all_env", "description": "d", "command": "env", "env": ["*"]}`,
+		"prefix.json":   `{"name": "prefix", "description": "d", "command": "env", "env": ["AWS_*"]}`,
+		"api_key.json":  `{"name": "api_key", "description": "d", "command": "env", "env": ["OPENAI_API_KEY"]}`,
+		"ssh.json":      `{"name": "ssh", "description": "d", "command": "env", "env": ["SSH_AUTH_SOCK"]}`,
+		"root_cwd.json": `{"name": "root_cwd", "description": "d", "command": "ls", "cwd": "/"}`,
+		"up_cwd.json":   `{"name": "up_cwd", "description": "d", "command": "ls", "cwd": "sub/../../other"}`,
+		"ok.json":       `{"name": "ok", "description": "d", "command": "true", "env": ["GOPATH"], "cwd": "sub/dir"}`,
+	})
+	r := NewRegistry()
+	err := r.LoadProjectTools(workspace)
+	require.Error(t, err)
+	for _, name := range []string{"all_env", "prefix", "api_key", "ssh", "root_cwd", "up_cwd"} {
+		assert.Contains(t, err.Error(), name+".json")
+		_, ok := r.Get(name)
+		assert.False(t, ok, name)
+	}
+	_, ok := r.Get("ok")
+	assert.True(t, ok)
+
+	// The same manifests are fine from the user's own directory.
+	userDir := t.TempDir()
+	writeManifests(t, userDir, map[string]string{
+		"all_env.json": `{"name": "all_env", "description": "d", "command": "env", "env": ["*"], "read_only": true}`,
+	})
+	r = NewRegistry()
+	require.NoError(t, r.LoadCustomTools(userDir))
+	tool, ok := r.Get("all_env")
+	require.True(t, ok)
+	assert.True(t, tool.IsReadOnly())
+}
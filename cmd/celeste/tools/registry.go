@@ -1,19 +1,18 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"slices"
 	"sort"
 	"strings"
 	"sync"
 
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/sandbox"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
 )
 
 // PermissionRequest describes a pending tool invocation that requires user approval.
@@ -137,6 +136,7 @@ type Registry struct {
 	hooks    HookRunner               // optional, nil = no hooks
 	promptFn PromptFunc               // optional; nil = deny on Ask
 	askFn    AskFunc                  // optional; nil = Ask returns an error (headless)
+	cache    *ResultCache             // optional; nil = results always sent in full
 
 	// Dynamic tool discovery (opt-in via SetDiscoveryMode).
 	hidden        map[string]bool // tools hidden from the prompt until activated
@@ -325,6 +325,7 @@ func (r *Registry) ExecuteWithProgress(ctx context.Context, name string, input m
 	r.mu.RLock()
 	checker := r.checker
 	prompt := r.promptFn
+	cache := r.cache
 	r.mu.RUnlock()
 
 	if checker != nil {
@@ -407,7 +408,31 @@ func (r *Registry) ExecuteWithProgress(ctx context.Context, name string, input m
 		}
 	}
 
+	// Tools that run commands confine them with the configured sandbox and
+	// git policy.
+	if checker != nil {
+		ctx = sandbox.WithPolicy(ctx, checker.Sandbox())
+		ctx = permissions.WithGitPolicy(ctx, checker.Git())
+	}
+
+	if cache != nil && tool.IsReadOnly() {
+		if cached, ok := cache.lookup(name, input); ok {
+			return cached, nil
+		}
+	}
+
 	result, err := tool.Execute(ctx, input, progress)
+	// Strip known credentials before the output reaches the model, the
+	// session file, or the screen — `cat .env` should not leak a key.
+	result.Content = secrets.Redact(result.Content)
+
+	if cache != nil {
+		if !tool.IsReadOnly() {
+			cache.invalidate(input)
+		} else if err == nil {
+			result = cache.record(name, input, result)
+		}
+	}
 
 	// Post-tool hook (fire-and-forget, does not block result)
 	if r.hooks != nil && err == nil {
@@ -428,6 +453,14 @@ func (r *Registry) SetPermissionChecker(checker *permissions.Checker) {
 	r.checker = checker
 }
 
+// SetResultCache sets the cache that replaces repeated read-only results
+// with a reference to the turn that first returned them. nil disables it.
+func (r *Registry) SetResultCache(cache *ResultCache) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = cache
+}
+
 // SetHookRunner sets the hook runner used for pre/post tool hooks.
 // If runner is nil, no hooks are executed (default behavior).
 func (r *Registry) SetHookRunner(runner HookRunner) {
@@ -474,84 +507,3 @@ func (r *Registry) Count() int {
 	defer r.mu.RUnlock()
 	return len(r.tools)
 }
-
-// customToolWrapper wraps a JSON-defined custom tool.
-type customToolWrapper struct {
-	name        string
-	description string
-	params      json.RawMessage
-	command     string
-}
-
-func (c *customToolWrapper) Name() string                                { return c.name }
-func (c *customToolWrapper) Description() string                         { return c.description }
-func (c *customToolWrapper) Parameters() json.RawMessage                 { return c.params }
-func (c *customToolWrapper) IsConcurrencySafe(input map[string]any) bool { return false }
-func (c *customToolWrapper) IsReadOnly() bool                            { return false }
-func (c *customToolWrapper) ValidateInput(input map[string]any) error    { return nil }
-func (c *customToolWrapper) InterruptBehavior() InterruptBehavior        { return InterruptCancel }
-func (c *customToolWrapper) Execute(ctx context.Context, input map[string]any, progress chan<- ProgressEvent) (ToolResult, error) {
-	if c.command == "" {
-		return ToolResult{Content: "Custom tool schema loaded, but no 'command' field defined. Add 'command' (shell-executable) to ~/.celeste/skills/" + c.name + ".json for execution support."}, nil
-	}
-
-	data, err := json.Marshal(input)
-	if err != nil {
-		return ToolResult{Content: fmt.Sprintf("Failed to marshal input: %v", err), Error: true}, nil
-	}
-
-	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.command)
-	cmd.Stdin = bytes.NewBuffer(data)
-
-	output, err := cmd.Output()
-	if err != nil {
-		return ToolResult{Content: fmt.Sprintf("Command '%s' failed: %v\nOutput:\n%s", c.command, err, string(output)), Error: true}, nil
-	}
-
-	return ToolResult{Content: string(output)}, nil
-}
-
-// LoadCustomTools loads JSON tool definitions from a directory.
-// This provides backwards compatibility with ~/.celeste/skills/*.json files.
-func (r *Registry) LoadCustomTools(dir string) error {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // directory doesn't exist, nothing to load
-		}
-		return fmt.Errorf("reading custom tools directory: %w", err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
-		path := filepath.Join(dir, entry.Name())
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("reading custom tool file %s: %w", path, err)
-		}
-
-		var def struct {
-			Name        string          `json:"name"`
-			Description string          `json:"description"`
-			Parameters  json.RawMessage `json:"parameters"`
-			Command     string          `json:"command"`
-		}
-		if err := json.Unmarshal(data, &def); err != nil {
-			return fmt.Errorf("parsing custom tool file %s: %w", path, err)
-		}
-
-		if def.Name == "" {
-			continue
-		}
-
-		r.Register(&customToolWrapper{
-			name:        def.Name,
-			description: def.Description,
-			params:      def.Parameters,
-			command:     def.Command,
-		})
-	}
-	return nil
-}
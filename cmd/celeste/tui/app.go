@@ -21,6 +21,7 @@ import (
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/commands"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/config"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/grimoire"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/permissions"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/providers"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/tools/mcp"
@@ -63,6 +64,10 @@ type AppModel struct {
 	codeGraphSummary string // Code graph stats for /index command
 	runtimeMode      string // Runtime orchestration mode (classic or claw)
 
+	// pendingAttachments are images tools produced this turn; they attach
+	// to the turn's final assistant message when it is committed.
+	pendingAttachments []media.Asset
+
 	// Simulated typing state
 	typingContent string // Full content to type
 	typingPos     int    // Current position in content
@@ -155,6 +160,7 @@ type AppModel struct {
 	streamStart   time.Time
 	lastMsgInTok  int
 	lastMsgOutTok int
+	lastMsgCached int // of lastMsgInTok, tokens read from the prompt cache
 }
 
 type pendingToolCall struct {
@@ -208,6 +214,34 @@ type SubagentKiller interface {
 	KillSubagent(id string) bool
 }
 
+// JobInfo is a TUI-facing view of a background shell job.
+type JobInfo struct {
+	ID       string
+	Command  string
+	PID      int
+	Status   string // "running", "exited", "killed", "timed_out"
+	ExitCode int
+	Elapsed  time.Duration
+}
+
+// JobLister is an optional extension for the /jobs command.
+type JobLister interface {
+	ListJobs() []JobInfo
+	ListShells() []string
+}
+
+// JobKiller is an optional extension for /jobs kill <id>.
+type JobKiller interface {
+	KillJob(id string) bool
+}
+
+// jobKilledMsg reports the end of a /jobs kill, which can take a couple of
+// seconds while the job's processes shut down.
+type jobKilledMsg struct {
+	id    string
+	found bool
+}
+
 // PromptRefresher is an optional extension to reload the system prompt
 // mid-session (after /confirm, /user, or other prompt-affecting changes).
 type PromptRefresher interface {
@@ -220,6 +254,19 @@ type EndpointSwitcher interface {
 	ChangeModel(model string) error
 }
 
+// WorkspaceCheckpointer is an optional extension for /diff and /undo backed
+// by whole-workspace checkpoints taken at every user turn.
+type WorkspaceCheckpointer interface {
+	WorkspaceChanges() (string, error)
+	UndoTurn() (string, error)
+}
+
+// MemoryExplainer is an optional extension for /memories why: it explains
+// which project memories were attached to the last request and why.
+type MemoryExplainer interface {
+	ExplainMemories() string
+}
+
 // ThinkingConfigSetter interface for clients that support extended thinking / reasoning effort.
 type ThinkingConfigSetter interface {
 	SetThinkingLevel(level string)
@@ -240,6 +287,34 @@ type VeniceConfigData struct {
 	ImageModel string // Image generation model
 }
 
+// assetStore returns the directory generated images for this session are
+// saved in.
+func (m AppModel) assetStore() *media.Store {
+	id := "tui"
+	if cs, ok := m.currentSession.(*config.Session); ok && cs.ID != "" {
+		id = cs.ID
+	}
+	store, err := media.SessionStore(id)
+	if err != nil {
+		return media.NewStore(filepath.Join(os.TempDir(), "celeste-assets", id))
+	}
+	return store
+}
+
+// generateSessionImage runs an image: media command through Venice and
+// saves the first image into store.
+func generateSessionImage(store *media.Store, cfg venice.Config, prompt string, params map[string]interface{}) (media.Asset, error) {
+	gen, err := media.NewImageGenerator(media.ImageBackend{Name: "venice", APIKey: cfg.APIKey, BaseURL: cfg.BaseURL, Model: cfg.Model})
+	if err != nil {
+		return media.Asset{}, err
+	}
+	images, err := gen.Generate(context.Background(), media.ImageRequest{Prompt: prompt, Count: 1, Extra: params})
+	if err != nil {
+		return media.Asset{}, err
+	}
+	return store.Save(images[0].Data, media.Asset{Prompt: prompt, Backend: gen.Name(), Model: cfg.Model})
+}
+
 // loadVeniceConfig loads Venice configuration from ~/.celeste/skills.json.
 func loadVeniceConfig() (VeniceConfigData, error) {
 	// Load skills config
@@ -888,20 +963,47 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 
 			case "diff":
+				if cp, ok := m.llmClient.(WorkspaceCheckpointer); ok {
+					summary, err := cp.WorkspaceChanges()
+					if err != nil {
+						summary = "Session changes: " + err.Error()
+					}
+					m.chat = m.chat.AddSystemMessage(summary)
+					return m, nil
+				}
 				m.chat = m.chat.AddSystemMessage("Session changes:\n(File checkpointing shows diffs of modified files)\nNo changes tracked in this session yet.")
 				return m, nil
 
 			case "undo":
+				if cp, ok := m.llmClient.(WorkspaceCheckpointer); ok {
+					result, err := cp.UndoTurn()
+					if err != nil {
+						result = "Undo failed: " + err.Error()
+					}
+					m.chat = m.chat.AddSystemMessage(result)
+					return m, nil
+				}
 				m.chat = m.chat.AddSystemMessage("Undo: reverting last file modification...\nNo checkpoints available in this session.")
 				return m, nil
 
 			case "memories":
+				if len(cmd.Args) > 0 && cmd.Args[0] == "why" {
+					if ex, ok := m.llmClient.(MemoryExplainer); ok {
+						m.chat = m.chat.AddSystemMessage(ex.ExplainMemories())
+					} else {
+						m.chat = m.chat.AddSystemMessage("/memories why is unavailable for this client.")
+					}
+					return m, nil
+				}
 				cwd, _ := os.Getwd()
 				m.viewMode = "memories"
 				model := NewMemoryManagerModel(cwd)
 				m.memoryManager = &model
 				return m, nil
 
+			case "preview":
+				return m.previewCmd(cmd.Args)
+
 			case "costs":
 				m.chat = m.chat.AddSystemMessage(fmt.Sprintf("Session Costs:\n  Tokens: %d used / %d limit\n  Turns: %d\n  Compactions: %d\n\nFor detailed cost breakdown: `celeste costs`",
 					m.contextBar.usedTokens, m.contextBar.maxTokens, m.contextBar.turnCount, m.contextBar.compactCount))
@@ -1298,6 +1400,68 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.chat = m.chat.AddSystemMessage(sb.String())
 				return m, nil
 
+			case "jobs":
+				// /jobs kill <id> — stop a background job and its process group
+				if len(cmd.Args) >= 2 && strings.ToLower(cmd.Args[0]) == "kill" {
+					id := cmd.Args[1]
+					killer, ok := m.llmClient.(JobKiller)
+					if !ok {
+						m.chat = m.chat.AddSystemMessage("Job kill not available.")
+						return m, nil
+					}
+					m.chat = m.chat.AddSystemMessage(fmt.Sprintf("Stopping %s...", id))
+					return m, func() tea.Msg {
+						return jobKilledMsg{id: id, found: killer.KillJob(id)}
+					}
+				}
+
+				lister, ok := m.llmClient.(JobLister)
+				if !ok {
+					m.chat = m.chat.AddSystemMessage("Background jobs not available.")
+					return m, nil
+				}
+				jobs := lister.ListJobs()
+				shells := lister.ListShells()
+				if len(jobs) == 0 && len(shells) == 0 {
+					m.chat = m.chat.AddSystemMessage("No background jobs or shells this session.")
+					return m, nil
+				}
+				var sb strings.Builder
+				if len(jobs) > 0 {
+					sb.WriteString("Background jobs:\n")
+					for _, j := range jobs {
+						icon := "▶"
+						state := j.Status
+						switch j.Status {
+						case "exited":
+							icon = "✓"
+							if j.ExitCode != 0 {
+								icon = "✗"
+							}
+							state = fmt.Sprintf("exited %d", j.ExitCode)
+						case "killed":
+							icon = "■"
+						case "timed_out":
+							icon = "⏱"
+							state = "timed out"
+						}
+						command := j.Command
+						if len(command) > 60 {
+							command = command[:57] + "..."
+						}
+						sb.WriteString(fmt.Sprintf("  %s %s  %s  pid %d  %s  %s\n",
+							icon, j.ID, state, j.PID, j.Elapsed.Round(time.Second), command))
+					}
+				}
+				if len(shells) > 0 {
+					sb.WriteString(fmt.Sprintf("Shells: %s\n", strings.Join(shells, ", ")))
+				}
+				if len(jobs) > 0 {
+					sb.WriteString("\nStop one with: /jobs kill <id>\n")
+				}
+				m.chat = m.chat.AddSystemMessage(sb.String())
+				return m, nil
+
 			case "graph":
 				if m.codeGraphIndexer == nil {
 					m.chat = m.chat.AddSystemMessage("No code graph loaded.\nRun `celeste index` to build one, then relaunch.")
@@ -1676,6 +1840,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.streamStart = time.Now()
 		m.lastMsgInTok = 0
 		m.lastMsgOutTok = 0
+		m.lastMsgCached = 0
 
 		// Send to LLM and start animation
 		if m.llmClient != nil {
@@ -1690,6 +1855,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case GenerateMediaMsg:
 		// Generate media asynchronously via Venice.ai
 		LogInfo(fmt.Sprintf("→ Starting %s generation with prompt: '%s'", msg.MediaType, msg.Prompt))
+		store := m.assetStore()
 		cmds = append(cmds, func() tea.Msg {
 			// Load Venice config from skills.json
 			LogInfo("Loading Venice config from skills.json")
@@ -1730,7 +1896,14 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			LogInfo(fmt.Sprintf("Calling Venice.ai API for %s generation", msg.MediaType))
 			switch msg.MediaType {
 			case "image":
-				response, genErr = venice.GenerateImage(config, msg.Prompt, msg.Params)
+				// Images go through the media package so they land in the
+				// session's assets and show up in the transcript.
+				asset, err := generateSessionImage(store, config, msg.Prompt, msg.Params)
+				if err != nil {
+					LogInfo(fmt.Sprintf("❌ Media generation error: %v", err))
+					return MediaResultMsg{Success: false, Error: err.Error(), MediaType: msg.MediaType}
+				}
+				return MediaResultMsg{Success: true, Path: asset.Path, MediaType: msg.MediaType, Assets: []media.Asset{asset}}
 			case "video":
 				response, genErr = venice.GenerateVideo(config, msg.Prompt, msg.Params)
 			case "image-to-video":
@@ -1779,6 +1952,11 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		})
 
+	case previewDoneMsg:
+		if msg.err != nil {
+			LogInfo(fmt.Sprintf("preview: %v", msg.err))
+		}
+
 	case MediaResultMsg:
 		// Handle media generation result
 		LogInfo(fmt.Sprintf("Received MediaResultMsg: success=%v, mediaType=%s", msg.Success, msg.MediaType))
@@ -1797,6 +1975,12 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Update the last assistant message with the result
 			m.chat = m.chat.SetLastAssistantContent(resultText)
+			m.chat = m.chat.AttachToLastAssistant(msg.Assets)
+			if configSession, ok := m.currentSession.(*config.Session); ok && len(configSession.Messages) > 0 {
+				last := &configSession.Messages[len(configSession.Messages)-1]
+				last.Content = resultText
+				last.Attachments = append(last.Attachments, msg.Assets...)
+			}
 			m.status = m.status.SetText(fmt.Sprintf("✓ %s complete", msg.MediaType))
 
 			// Persist media generation result
@@ -1859,6 +2043,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Usage != nil && (msg.Usage.PromptTokens > 0 || msg.Usage.CompletionTokens > 0) {
 			m.lastMsgInTok = msg.Usage.PromptTokens
 			m.lastMsgOutTok = msg.Usage.CompletionTokens
+			m.lastMsgCached = msg.Usage.CachedTokens
 			if m.contextTracker != nil {
 				m.contextTracker.UpdateTokens(
 					msg.Usage.PromptTokens,
@@ -1990,6 +2175,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.streamStart = time.Now()
 			m.lastMsgInTok = 0
 			m.lastMsgOutTok = 0
+			m.lastMsgCached = 0
 			// Initialise run-level accumulators on the first turn.
 			if msg.Turn <= 1 {
 				m.agentInputTokens = 0
@@ -2014,9 +2200,10 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			entry := fmt.Sprintf("⚙  %s", msg.Text)
 			// First tool call of each turn carries per-turn stats.
 			if msg.InputTokens > 0 || msg.Duration > 0 {
-				entry += " " + formatOrchestratorStats(msg.Duration, msg.InputTokens, msg.OutputTokens)
+				entry += " " + formatTurnStats(msg.Duration, msg.InputTokens, msg.OutputTokens, msg.CachedTokens)
 				m.lastMsgInTok = msg.InputTokens
 				m.lastMsgOutTok = msg.OutputTokens
+				m.lastMsgCached = msg.CachedTokens
 				m.agentInputTokens += msg.InputTokens
 				m.agentOutputTokens += msg.OutputTokens
 			}
@@ -2032,6 +2219,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if msg.InputTokens > 0 || msg.OutputTokens > 0 {
 				m.lastMsgInTok = msg.InputTokens
 				m.lastMsgOutTok = msg.OutputTokens
+				m.lastMsgCached = msg.CachedTokens
 				m.agentInputTokens += msg.InputTokens
 				m.agentOutputTokens += msg.OutputTokens
 			}
@@ -2211,6 +2399,14 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Batch(cmds...)
 
+	case jobKilledMsg:
+		if msg.found {
+			m.chat = m.chat.AddSystemMessage(fmt.Sprintf("Stopped %s.", msg.id))
+		} else {
+			m.chat = m.chat.AddSystemMessage(fmt.Sprintf("No job %q.", msg.id))
+		}
+		return m, nil
+
 	case AgentCommandResultMsg:
 		m.streaming = false
 		m.status = m.status.SetStreaming(false)
@@ -2293,6 +2489,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.skills = m.skills.SetCompleted(msg.Name)
 			m.chat = m.chat.UpdateFunctionResult(msg.Name, msg.Result)
+			m.pendingAttachments = append(m.pendingAttachments, media.AssetsFrom(msg.Metadata)...)
 
 			// Handle NSFW mode toggle
 			if msg.Name == "nsfw_mode" && strings.Contains(msg.Result, "enabled") {
@@ -2494,17 +2691,20 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// updateContent() renders markdown on this pass.
 				m.chat = m.chat.SetTypingActive(false)
 				m.chat = m.chat.SetLastAssistantContent(m.typingContent)
+				m.chat = m.chat.AttachToLastAssistant(m.pendingAttachments)
 
 				// Add assistant message to session for persistence
 				if m.currentSession != nil {
 					if configSession, ok := m.currentSession.(*config.Session); ok {
 						configSession.Messages = append(configSession.Messages, config.SessionMessage{
-							Role:      "assistant",
-							Content:   m.typingContent,
-							Timestamp: time.Now(),
+							Role:        "assistant",
+							Content:     m.typingContent,
+							Timestamp:   time.Now(),
+							Attachments: m.pendingAttachments,
 						})
 					}
 				}
+				m.pendingAttachments = nil
 
 				typedContent := m.typingContent
 				m.typingContent = ""
@@ -2529,7 +2729,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						formatOrchestratorTokens(inTok),
 						formatOrchestratorTokens(outTok))
 				} else {
-					statsStr = formatOrchestratorStats(elapsed, inTok, outTok)
+					statsStr = formatTurnStats(elapsed, inTok, outTok, m.lastMsgCached)
 				}
 				if statsStr != "" {
 					m.status = m.status.SetText("Ready " + statsStr)
@@ -2982,6 +3182,7 @@ func (m AppModel) buildToolFollowUpCmds() (AppModel, []tea.Cmd) {
 	m.streamStart = time.Now()
 	m.lastMsgInTok = 0
 	m.lastMsgOutTok = 0
+	m.lastMsgCached = 0
 
 	toolsToSend := m.getToolsForDispatch()
 	return m, []tea.Cmd{
@@ -3130,7 +3331,7 @@ func (m AppModel) WithMessages(messages []ChatMessage) AppModel {
 		case "user":
 			m.chat = m.chat.AddUserMessage(msg.Content)
 		case "assistant":
-			m.chat = m.chat.AddAssistantMessage(msg.Content)
+			m.chat = m.chat.AddAssistantMessage(msg.Content).AttachToLastAssistant(msg.Attachments)
 		case "tool":
 			m.chat = m.chat.AddToolResult(msg.ToolCallID, msg.Name, msg.Content)
 		}
@@ -3173,6 +3374,13 @@ func (m AppModel) WithEndpoint(endpoint string) AppModel {
 
 // formatOrchestratorStats formats per-turn timing and token counts for the action feed.
 func formatOrchestratorStats(d time.Duration, inputTok, outputTok int) string {
+	return formatTurnStats(d, inputTok, outputTok, 0)
+}
+
+// formatTurnStats is formatOrchestratorStats plus the share of input tokens
+// the provider served from its prompt cache, when it reported any
+// (e.g. "(2.1s · ↑52.3k ↓310 · 94% cached)").
+func formatTurnStats(d time.Duration, inputTok, outputTok, cachedTok int) string {
 	var parts []string
 	if d >= time.Millisecond {
 		if d < time.Second {
@@ -3186,6 +3394,9 @@ func formatOrchestratorStats(d time.Duration, inputTok, outputTok int) string {
 	if inputTok > 0 || outputTok > 0 {
 		parts = append(parts, fmt.Sprintf("↑%s ↓%s", formatOrchestratorTokens(inputTok), formatOrchestratorTokens(outputTok)))
 	}
+	if cachedTok > 0 && inputTok > 0 {
+		parts = append(parts, fmt.Sprintf("%d%% cached", cachedTok*100/inputTok))
+	}
 	if len(parts) == 0 {
 		return ""
 	}
@@ -3345,7 +3556,7 @@ func (m AppModel) handleSessionAction(action *commands.SessionAction) AppModel {
 							case "user":
 								m.chat = m.chat.AddUserMessage(msg.Content)
 							case "assistant":
-								m.chat = m.chat.AddAssistantMessage(msg.Content)
+								m.chat = m.chat.AddAssistantMessage(msg.Content).AttachToLastAssistant(msg.Attachments)
 							}
 						}
 					}
@@ -3504,7 +3715,7 @@ func (m AppModel) handleSessionAction(action *commands.SessionAction) AppModel {
 							case "user":
 								m.chat = m.chat.AddUserMessage(msg.Content)
 							case "assistant":
-								m.chat = m.chat.AddAssistantMessage(msg.Content)
+								m.chat = m.chat.AddAssistantMessage(msg.Content).AttachToLastAssistant(msg.Attachments)
 							}
 						}
 
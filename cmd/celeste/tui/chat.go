@@ -10,6 +10,7 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
 )
 
 // ChatModel represents the chat panel with scrollable messages.
@@ -203,6 +204,36 @@ func (m ChatModel) AppendToLastAssistant(content string) ChatModel {
 	return m
 }
 
+// AttachToLastAssistant adds assets (generated images) to the last
+// assistant message, which lists them under its text.
+func (m ChatModel) AttachToLastAssistant(assets []media.Asset) ChatModel {
+	if len(assets) == 0 {
+		return m
+	}
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == "assistant" {
+			m.messages[i].Attachments = append(m.messages[i].Attachments, assets...)
+			break
+		}
+	}
+	m.updateContent()
+	if !m.userScrolled {
+		m.viewport.GotoBottom()
+	}
+	return m
+}
+
+// LastAttachments returns the attachments of the most recent message that
+// has any.
+func (m ChatModel) LastAttachments() []media.Asset {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if len(m.messages[i].Attachments) > 0 {
+			return m.messages[i].Attachments
+		}
+	}
+	return nil
+}
+
 // SetTypingActive marks whether the typing animation is running.
 // When true, the last assistant message skips Glamour markdown rendering
 // so that ANSI-styled corruption glyphs at the cursor don't break the layout.
@@ -375,7 +406,21 @@ func (m ChatModel) renderMessageOpt(msg ChatMessage, width int, skipMarkdown boo
 		styledContent = contentStyle.Render(wrapText(msg.Content, width-2))
 	}
 
-	return lipgloss.JoinVertical(lipgloss.Left, header, styledContent)
+	parts := []string{header, styledContent}
+	for _, a := range msg.Attachments {
+		parts = append(parts, renderAttachment(a, width-2))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// renderAttachment is the one-line caption shown for an attached asset.
+// The alt screen can't hold inline images; /preview displays them.
+func renderAttachment(a media.Asset, width int) string {
+	caption := "🖼 " + a.Path
+	if a.Width > 0 && a.Height > 0 {
+		caption += fmt.Sprintf(" · %d×%d", a.Width, a.Height)
+	}
+	return SystemMessageStyle.Render(wrapText(caption, width))
 }
 
 // renderFunctionCall renders a function call display.
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
 )
 
 func TestGetLLMMessages_FiltersSystemMessages(t *testing.T) {
@@ -39,3 +40,21 @@ func TestGetLLMMessages_OnlySystemMessages(t *testing.T) {
 	llm := m.GetLLMMessages()
 	require.Empty(t, llm, "should return empty slice when only system messages exist")
 }
+
+func TestAttachToLastAssistant(t *testing.T) {
+	m := NewChatModel()
+	m = m.AddAssistantMessage("first")
+	m = m.AddUserMessage("draw a fox")
+	m = m.AddAssistantMessage("here it is")
+	m = m.AddSystemMessage("note")
+
+	assets := []media.Asset{{Path: "/tmp/001-ab.png", Width: 64, Height: 32}}
+	m = m.AttachToLastAssistant(assets)
+	msgs := m.GetMessages()
+	assert.Empty(t, msgs[0].Attachments)
+	assert.Equal(t, assets, msgs[2].Attachments)
+	assert.Equal(t, assets, m.LastAttachments())
+
+	out := m.renderMessageOpt(msgs[2], 80, true)
+	assert.Contains(t, out, "/tmp/001-ab.png · 64×32")
+}
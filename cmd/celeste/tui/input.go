@@ -14,8 +14,8 @@ import (
 var knownCommands = []string{
 	"agent", "agents", "clear", "collections", "config", "confirm", "context",
 	"costs", "diff", "effort", "endpoint", "export", "graph", "grimoire",
-	"help", "index", "mcp", "memories", "menu", "model", "nsfw", "orch",
-	"orchestrate", "persona", "plan", "providers", "safe", "session",
+	"help", "index", "jobs", "mcp", "memories", "menu", "model", "nsfw", "orch",
+	"orchestrate", "persona", "plan", "preview", "providers", "safe", "session",
 	"set-model", "skills", "stats", "tools", "undo", "user", "voice",
 }
 
@@ -34,6 +34,7 @@ var knownSubcommands = map[string][]string{
 	"session": {"list", "load", "delete", "clear"},
 	"agent":   {"list-runs", "resume", "kill"},
 	"agents":  {"resume", "kill"},
+	"jobs":    {"kill"},
 	"plan":    {"show"},
 }
 
@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/secrets"
 )
 
 var (
@@ -58,7 +60,7 @@ func LogInfo(msg string) {
 		return
 	}
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	fmt.Fprintf(logFile, "[%s] INFO: %s\n", timestamp, msg)
+	fmt.Fprintf(logFile, "[%s] INFO: %s\n", timestamp, secrets.Redact(msg))
 }
 
 // LogSkillCall logs when a skill/function is called by the LLM.
@@ -68,7 +70,7 @@ func LogSkillCall(name string, args map[string]any) {
 	}
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	fmt.Fprintf(logFile, "[%s] SKILL_CALL: %s\n", timestamp, name)
-	fmt.Fprintf(logFile, "  Arguments: %v\n", args)
+	fmt.Fprintf(logFile, "  Arguments: %s\n", secrets.Redact(fmt.Sprintf("%v", args)))
 }
 
 // LogSkillResult logs the result of a skill execution.
@@ -78,10 +80,10 @@ func LogSkillResult(name string, result string, err error) {
 	}
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	if err != nil {
-		fmt.Fprintf(logFile, "[%s] SKILL_ERROR: %s - %v\n", timestamp, name, err)
+		fmt.Fprintf(logFile, "[%s] SKILL_ERROR: %s - %s\n", timestamp, name, secrets.Redact(err.Error()))
 	} else {
 		// Truncate result for log
-		resultTrunc := result
+		resultTrunc := secrets.Redact(result)
 		if len(resultTrunc) > 200 {
 			resultTrunc = resultTrunc[:200] + "..."
 		}
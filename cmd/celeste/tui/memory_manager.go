@@ -9,12 +9,15 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/codegraph"
 	"github.com/whykusanagi/celeste-cli/cmd/celeste/memories"
 )
 
 // MemoryManagerModel is the interactive TUI for managing project memories.
 type MemoryManagerModel struct {
 	store     *memories.Store
+	workspace string
+	results   map[string]*memories.VerificationResult // last verification, by memory name
 	memories  []*memories.Memory
 	cursor    int
 	scroll    int
@@ -30,8 +33,11 @@ func NewMemoryManagerModel(workspace string) MemoryManagerModel {
 	store := memories.NewStore(workspace)
 	mems, _ := store.List()
 
-	// Sort: stale first, then by type, then by name
+	// Sort: pending conflicts first, then stale, then by type, then by name
 	sort.Slice(mems, func(i, j int) bool {
+		if (mems[i].Conflicts != "") != (mems[j].Conflicts != "") {
+			return mems[i].Conflicts != ""
+		}
 		iDays, _ := memories.CheckStaleness(mems[i])
 		jDays, _ := memories.CheckStaleness(mems[j])
 		if iDays != jDays {
@@ -45,6 +51,7 @@ func NewMemoryManagerModel(workspace string) MemoryManagerModel {
 
 	return MemoryManagerModel{
 		store:     store,
+		workspace: workspace,
 		memories:  mems,
 		confirmed: -1,
 		expanded:  -1,
@@ -124,7 +131,7 @@ func (m MemoryManagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.message = "Press D again to confirm delete"
 			}
 
-		case "p":
+		case "p", "P":
 			// Purge all stale memories (> 30 days)
 			m.confirmed = -1
 			purged := 0
@@ -149,6 +156,49 @@ func (m MemoryManagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				m.message = "No stale memories to purge"
 			}
+
+		case "v", "V":
+			// Check every memory's code references against the workspace.
+			m.confirmed = -1
+			m.verifyAll()
+
+		case "f", "F":
+			// Rewrite missing references to the suggested new names.
+			if len(m.memories) == 0 {
+				return m, nil
+			}
+			m.confirmed = -1
+			m.fixCurrent()
+
+		case "a", "b", "x", "A", "B", "X":
+			// Settle a contradiction flagged by memory extraction.
+			if len(m.memories) == 0 || m.memories[m.cursor].Conflicts == "" {
+				return m, nil
+			}
+			m.confirmed = -1
+			mem := m.memories[m.cursor]
+			resolution := map[string]string{
+				"a": memories.ResolveReplace,
+				"b": memories.ResolveKeepBoth,
+				"x": memories.ResolveDiscard,
+			}[strings.ToLower(msg.String())]
+			old := mem.Conflicts
+			if err := memories.ResolveConflict(m.store, mem.Name, resolution); err != nil {
+				m.message = fmt.Sprintf("Resolve failed: %v", err)
+				return m, nil
+			}
+			switch resolution {
+			case memories.ResolveReplace:
+				mem.Conflicts = ""
+				m.removeNamed(old)
+				m.message = fmt.Sprintf("Replaced %s with %s", old, mem.Name)
+			case memories.ResolveKeepBoth:
+				mem.Conflicts = ""
+				m.message = fmt.Sprintf("Kept both %s and %s", mem.Name, old)
+			case memories.ResolveDiscard:
+				m.removeNamed(mem.Name)
+				m.message = fmt.Sprintf("Discarded %s, kept %s", mem.Name, old)
+			}
 		}
 
 	case tea.WindowSizeMsg:
@@ -159,6 +209,105 @@ func (m MemoryManagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// newVerifier builds a verifier that uses the project's code graph index when
+// one has been built. The returned func closes the index.
+func (m *MemoryManagerModel) newVerifier() (*memories.Verifier, func()) {
+	indexPath := codegraph.DefaultIndexPath(m.workspace)
+	if _, err := os.Stat(indexPath); err == nil {
+		if graph, err := codegraph.NewStore(indexPath); err == nil {
+			return memories.NewVerifier(m.workspace, graph), func() { graph.Close() }
+		}
+	}
+	return memories.NewVerifier(m.workspace, nil), func() {}
+}
+
+// verifyAll checks every memory's code references and records the outcome.
+func (m *MemoryManagerModel) verifyAll() {
+	v, done := m.newVerifier()
+	defer done()
+	results, err := v.VerifyStore(m.store)
+	if err != nil {
+		m.message = fmt.Sprintf("Verify failed: %v", err)
+		return
+	}
+
+	m.results = make(map[string]*memories.VerificationResult, len(results))
+	counts := make(map[string]int)
+	for _, res := range results {
+		m.results[res.Memory.Name] = res
+		counts[res.Status]++
+		if mem := m.findNamed(res.Memory.Name); mem != nil {
+			mem.Status, mem.VerifiedAt, mem.MissingRefs = res.Memory.Status, res.Memory.VerifiedAt, res.Memory.MissingRefs
+		}
+	}
+	m.message = fmt.Sprintf("Verified %d memories: %d ok, %d stale, %d broken, %d without code references",
+		len(results), counts[memories.StatusVerified], counts[memories.StatusStale], counts[memories.StatusBroken], counts[""])
+}
+
+// fixCurrent applies the verifier's suggested replacements to the memory
+// under the cursor and re-checks it.
+func (m *MemoryManagerModel) fixCurrent() {
+	mem := m.memories[m.cursor]
+	res := m.results[mem.Name]
+	if res == nil {
+		m.message = "Press V to verify first"
+		return
+	}
+	res.Memory = mem
+	if !memories.ApplyFixes(res) {
+		m.message = fmt.Sprintf("No suggested fixes for %s — press D to delete it instead", mem.Name)
+		return
+	}
+
+	v, done := m.newVerifier()
+	defer done()
+	res = v.Verify(mem)
+	v.Apply(res)
+	m.results[mem.Name] = res
+	if err := m.store.Save(mem); err != nil {
+		m.message = fmt.Sprintf("Save failed: %v", err)
+		return
+	}
+	indexPath := filepath.Join(m.store.BaseDir(), "MEMORY.md")
+	if idx, err := memories.LoadIndex(indexPath); err == nil {
+		for _, e := range idx.Entries() {
+			if e.Name == mem.Name && e.Description != mem.Description {
+				e.Description = mem.Description
+				_ = idx.Add(e)
+				_ = idx.Save()
+			}
+		}
+	}
+	m.message = fmt.Sprintf("Fixed %s: now %s", mem.Name, res.Status)
+}
+
+// removeNamed drops a memory from the list and keeps the cursor in range.
+func (m *MemoryManagerModel) removeNamed(name string) {
+	for i, mem := range m.memories {
+		if mem.Name == name {
+			m.memories = append(m.memories[:i], m.memories[i+1:]...)
+			if i < m.cursor {
+				m.cursor--
+			}
+			break
+		}
+	}
+	if m.cursor >= len(m.memories) && m.cursor > 0 {
+		m.cursor = len(m.memories) - 1
+	}
+	m.expanded = -1
+}
+
+// findNamed returns the loaded memory with the given name, or nil.
+func (m MemoryManagerModel) findNamed(name string) *memories.Memory {
+	for _, mem := range m.memories {
+		if mem.Name == name {
+			return mem
+		}
+	}
+	return nil
+}
+
 func (m MemoryManagerModel) maxVisible() int {
 	v := m.height - 10
 	if v < 5 {
@@ -183,7 +332,11 @@ func (m MemoryManagerModel) View() string {
 	totalSize := 0
 	typeCount := make(map[string]int)
 	staleCount := 0
+	conflictCount := 0
 	for _, mem := range m.memories {
+		if mem.Conflicts != "" {
+			conflictCount++
+		}
 		totalSize += len(mem.Content)
 		typeCount[mem.Type]++
 		days, _ := memories.CheckStaleness(mem)
@@ -197,6 +350,9 @@ func (m MemoryManagerModel) View() string {
 	if staleCount > 0 {
 		stats += fmt.Sprintf("  ⚠ %d stale", staleCount)
 	}
+	if conflictCount > 0 {
+		stats += fmt.Sprintf("  ⚡ %d to review", conflictCount)
+	}
 	sb.WriteString(memStatsStyle.Render(stats))
 	sb.WriteString("\n\n")
 
@@ -227,11 +383,24 @@ func (m MemoryManagerModel) View() string {
 			age = fmt.Sprintf(" %dd", days)
 		}
 
-		// Staleness marker
+		// Staleness / conflict marker
 		staleMarker := ""
 		if warning != "" {
 			staleMarker = " ⚠"
 		}
+		if mem.Conflicts != "" {
+			staleMarker += " ⚡"
+		}
+		switch mem.Status {
+		case memories.StatusVerified:
+			staleMarker += " ✓"
+		case memories.StatusStale:
+			staleMarker += " ⚠ refs"
+		case memories.StatusBroken:
+			staleMarker += " ✗ broken"
+		case memories.StatusUnverified:
+			staleMarker += " ? refs"
+		}
 
 		line := fmt.Sprintf(" %s %-30s  %s%s%s",
 			badge, truncateStr(mem.Name, 30), truncateStr(mem.Description, 40), age, staleMarker)
@@ -242,13 +411,47 @@ func (m MemoryManagerModel) View() string {
 			} else {
 				sb.WriteString(memCursorStyle.Width(width - 2).Render(line))
 			}
-		} else if warning != "" {
+		} else if mem.Conflicts != "" {
+			sb.WriteString(memConflictStyle.Render(line))
+		} else if mem.Status == memories.StatusBroken {
+			sb.WriteString(memBrokenStyle.Render(line))
+		} else if warning != "" || mem.Status == memories.StatusStale || mem.Status == memories.StatusUnverified {
 			sb.WriteString(memStaleStyle.Render(line))
 		} else {
 			sb.WriteString(memItemStyle.Render(line))
 		}
 		sb.WriteString("\n")
 
+		// A flagged memory shows what it contradicts, so the choice can be
+		// made without leaving the list.
+		if isCursor && mem.Conflicts != "" {
+			other := "(no longer exists)"
+			if o := m.findNamed(mem.Conflicts); o != nil {
+				other = o.Description
+			}
+			sb.WriteString(memConflictStyle.Render(fmt.Sprintf("    ⚡ contradicts %s: %s", mem.Conflicts, truncateStr(other, 70))))
+			sb.WriteString("\n")
+		}
+
+		// References verification could not resolve, with the likely new
+		// name where one was found.
+		if isCursor && len(mem.MissingRefs) > 0 {
+			suggestions := make(map[string]string)
+			if res := m.results[mem.Name]; res != nil {
+				for _, ref := range res.Missing() {
+					suggestions[ref.Value] = ref.Suggestion
+				}
+			}
+			for _, ref := range mem.MissingRefs {
+				note := "    ✗ " + ref + " not found"
+				if sug := suggestions[ref]; sug != "" {
+					note += " → " + sug + "?"
+				}
+				sb.WriteString(memBrokenStyle.Render(note))
+				sb.WriteString("\n")
+			}
+		}
+
 		// Show content: full if expanded, one-line preview if just cursor
 		if i == m.expanded && mem.Content != "" {
 			// Full content view
@@ -280,7 +483,13 @@ func (m MemoryManagerModel) View() string {
 
 	// Footer
 	sb.WriteString("\n\n")
-	footer := " [↑/↓] Navigate  [Enter] View  [D] Delete  [P] Purge stale (>30d)  [Q/Esc] Back"
+	footer := " [↑/↓] Navigate  [Enter] View  [V] Verify  [D] Delete  [P] Purge stale (>30d)  [Q/Esc] Back"
+	if len(m.memories) > 0 && len(m.memories[m.cursor].MissingRefs) > 0 {
+		footer = " [↑/↓] Navigate  [Enter] View  [V] Verify  [F] Apply fixes  [D] Delete  [Q/Esc] Back"
+	}
+	if len(m.memories) > 0 && m.memories[m.cursor].Conflicts != "" {
+		footer = " [A] Accept new (replace old)  [B] Keep both  [X] Discard new  [Q/Esc] Back"
+	}
 	sb.WriteString(memFooterStyle.Render(footer))
 
 	return sb.String()
@@ -334,6 +543,12 @@ var (
 	memStaleStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#7a7085"))
 
+	memConflictStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#f97316"))
+
+	memBrokenStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ef4444"))
+
 	memPreviewStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#5a4575")).
 			Italic(true)
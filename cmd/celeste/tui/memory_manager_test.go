@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/memories"
+)
+
+func TestMemoryManager_ConflictKeysAcceptUppercase(t *testing.T) {
+	// The footer shows [A] [B] [X], so a shifted key must work as well.
+	for key, remaining := range map[rune][]string{
+		'A': {"new"},
+		'B': {"new", "old"},
+		'X': {"old"},
+	} {
+		store := memories.NewStoreWithBase(t.TempDir())
+		require.NoError(t, store.Save(memories.NewMemory("old", "Old fact", "project", "", "old")))
+		mem := memories.NewMemory("new", "New fact", "project", "", "new")
+		mem.Conflicts = "old"
+		require.NoError(t, store.Save(mem))
+
+		m := MemoryManagerModel{store: store, memories: []*memories.Memory{mem}, confirmed: -1, expanded: -1}
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+
+		list, err := store.List()
+		require.NoError(t, err)
+		var names []string
+		for _, l := range list {
+			names = append(names, l.Name)
+			assert.Empty(t, l.Conflicts, "key %c", key)
+		}
+		assert.ElementsMatch(t, remaining, names, "key %c", key)
+	}
+}
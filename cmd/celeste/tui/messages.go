@@ -7,6 +7,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
 )
 
 // ChatMessage represents a message in the conversation.
@@ -18,6 +19,14 @@ type ChatMessage struct {
 	ToolCalls  []ToolCallInfo // For assistant messages, the tool calls that were made
 	Timestamp  time.Time      // When the message was created
 	Metadata   map[string]any // Optional metadata (e.g. image data from tool results)
+	// Attachments are files generated for this message, such as images
+	// from generate_image. They are shown with the message and kept in
+	// session logs by reference; backends do not send them.
+	Attachments []media.Asset
+	// Thinking is the reasoning the model streamed before an assistant
+	// reply. Like Attachments it is kept for display and exports only;
+	// backends do not send it.
+	Thinking string
 }
 
 // ToolCallInfo represents a tool call in an assistant message.
@@ -31,6 +40,11 @@ type ToolCallInfo struct {
 	// It lives on the message rather than in a backend-local cache so it
 	// survives checkpointing and history replay; empty for every other provider.
 	ThoughtSignature []byte
+	// ReasoningState holds the OpenAI Responses API reasoning items (JSON)
+	// that led to this call, set on the first call of a turn. Sending them
+	// back with the tool results is what lets a reasoning model continue its
+	// chain of thought instead of starting over.
+	ReasoningState []byte
 }
 
 // FunctionCall represents a tool/function call from the LLM.
@@ -64,6 +78,7 @@ type TokenUsage struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
+	CachedTokens     int // Prompt tokens served from the provider's prompt cache
 }
 
 // StreamStartMsg carries the context cancel function so the TUI can cancel
@@ -162,6 +177,7 @@ type MediaResultMsg struct {
 	Path      string
 	Error     string
 	MediaType string
+	Assets    []media.Asset // saved images, attached to the reply
 }
 
 // ShowSelectorMsg triggers the interactive selector.
@@ -209,6 +225,7 @@ type AgentProgressMsg struct {
 	// Per-turn stats — populated on ProgressResponse and ProgressComplete kinds.
 	InputTokens  int
 	OutputTokens int
+	CachedTokens int // input tokens served from the provider's prompt cache
 	Duration     time.Duration
 	Ch           <-chan AgentProgressMsg
 }
@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/media"
+)
+
+// previewDoneMsg is sent when /preview hands the screen back.
+type previewDoneMsg struct{ err error }
+
+// imagePreview shows assets with the terminal's image protocol. It runs via
+// tea.Exec, which releases the alt screen so the images land in the normal
+// terminal, and waits for Enter before the TUI redraws over them.
+type imagePreview struct {
+	assets   []media.Asset
+	protocol media.Protocol
+	cols     int
+
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+func (p *imagePreview) SetStdin(r io.Reader)  { p.stdin = r }
+func (p *imagePreview) SetStdout(w io.Writer) { p.stdout = w }
+func (p *imagePreview) SetStderr(w io.Writer) { p.stderr = w }
+
+func (p *imagePreview) Run() error {
+	for _, a := range p.assets {
+		fmt.Fprintln(p.stdout, a.Path)
+		data, err := os.ReadFile(a.Path)
+		if err != nil {
+			fmt.Fprintf(p.stdout, "  (unreadable: %v)\n", err)
+			continue
+		}
+		if err := media.WritePreview(p.stdout, data, p.protocol, p.cols); err != nil {
+			fmt.Fprintf(p.stdout, "  (preview failed: %v)\n", err)
+		}
+	}
+	fmt.Fprint(p.stdout, "\nPress Enter to return to chat...")
+	_, err := bufio.NewReader(p.stdin).ReadString('\n')
+	return err
+}
+
+// previewCmd runs /preview [n]: the last message's attachments, or only the
+// nth of them.
+func (m AppModel) previewCmd(args []string) (AppModel, tea.Cmd) {
+	assets := m.chat.LastAttachments()
+	if len(assets) == 0 {
+		m.chat = m.chat.AddSystemMessage("No images in this session yet.")
+		return m, nil
+	}
+	protocol := media.DetectProtocol()
+	if protocol == media.ProtocolNone {
+		m.chat = m.chat.AddSystemMessage("This terminal has no inline image support (kitty, iTerm2 or sixel). " +
+			"Set CELESTE_IMAGE_PROTOCOL if it does; the images are at:\n" + assetPaths(assets))
+		return m, nil
+	}
+	if len(args) > 0 {
+		var n int
+		if _, err := fmt.Sscanf(args[0], "%d", &n); err != nil || n < 1 || n > len(assets) {
+			m.chat = m.chat.AddSystemMessage(fmt.Sprintf("Usage: /preview [1-%d]", len(assets)))
+			return m, nil
+		}
+		assets = assets[n-1 : n]
+	}
+	p := &imagePreview{assets: assets, protocol: protocol, cols: min(max(m.width/2, 20), 80)}
+	return m, tea.Exec(p, func(err error) tea.Msg { return previewDoneMsg{err: err} })
+}
+
+func assetPaths(assets []media.Asset) string {
+	var s string
+	for _, a := range assets {
+		s += "  " + a.Path + "\n"
+	}
+	return s
+}
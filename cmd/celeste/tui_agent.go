@@ -35,6 +35,7 @@ type agentRunnerAPI interface {
 	ListRuns(limit int) ([]agent.RunSummary, error)
 	Resume(ctx context.Context, runID string) (*agent.RunState, error)
 	RunGoal(ctx context.Context, goal string) (*agent.RunState, error)
+	Close()
 }
 
 var newAgentRunnerForTUI = func(cfg *config.Config, options agent.Options, out io.Writer, errOut io.Writer) (agentRunnerAPI, error) {
@@ -132,6 +133,7 @@ func (a *TUIClientAdapter) runGoalWithProgress(args []string) tea.Cmd {
 					msg.Duration = stats.Elapsed
 					msg.InputTokens = stats.InputTokens
 					msg.OutputTokens = stats.OutputTokens
+					msg.CachedTokens = stats.CachedTokens
 					turnStatsEmitted[turn] = true
 					delete(turnStatsMap, turn)
 				}
@@ -142,6 +144,7 @@ func (a *TUIClientAdapter) runGoalWithProgress(args []string) tea.Cmd {
 					msg.Duration = stats.Elapsed
 					msg.InputTokens = stats.InputTokens
 					msg.OutputTokens = stats.OutputTokens
+					msg.CachedTokens = stats.CachedTokens
 					delete(turnStatsMap, turn)
 				}
 			}
@@ -154,6 +157,7 @@ func (a *TUIClientAdapter) runGoalWithProgress(args []string) tea.Cmd {
 			ch <- tui.AgentProgressMsg{Kind: tui.AgentProgressError, Text: err.Error()}
 			return
 		}
+		defer runner.Close()
 
 		goal := strings.TrimSpace(strings.Join(args, " "))
 		state, runErr := runner.RunGoal(context.Background(), goal)
@@ -201,6 +205,7 @@ func (a *TUIClientAdapter) executeAgentCommand(args []string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("create agent runner: %w", err)
 	}
+	defer runner.Close()
 
 	sub := strings.ToLower(strings.TrimSpace(args[0]))
 	ctx := context.Background()
@@ -34,6 +34,8 @@ func (f *fakeAgentRunner) Resume(ctx context.Context, runID string) (*agent.RunS
 	return nil, errors.New("not implemented")
 }
 
+func (f *fakeAgentRunner) Close() {}
+
 func (f *fakeAgentRunner) RunGoal(ctx context.Context, goal string) (*agent.RunState, error) {
 	if f.runGoalFn != nil {
 		return f.runGoalFn(ctx, goal)
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/checkpoints"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// shadowTurns records the workspace checkpoint taken at the start of each
+// user turn in the chat TUI, which is what /diff and /undo work against.
+type shadowTurns struct {
+	repo    *checkpoints.ShadowRepo
+	session string
+	mu      sync.Mutex
+	ids     []int // checkpoint ID at the start of each user turn, oldest first
+}
+
+// checkpointUserTurn snapshots the workspace when messages ends with a fresh
+// user message. Tool-loop follow-ups end with a tool result and are skipped,
+// so one user turn maps to exactly one checkpoint.
+func (s *shadowTurns) checkpointUserTurn(messages []tui.ChatMessage) {
+	if s == nil || len(messages) == 0 || messages[len(messages)-1].Role != "user" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label := strings.ReplaceAll(strings.TrimSpace(messages[len(messages)-1].Content), "\n", " ")
+	if len(label) > 60 {
+		label = label[:60] + "..."
+	}
+	cp, err := s.repo.Checkpoint(s.session, len(s.ids)+1, label)
+	if err != nil {
+		tui.LogInfo(fmt.Sprintf("shadow checkpoint failed: %v", err))
+		return
+	}
+	if len(cp.Skipped) > 0 {
+		tui.LogInfo(fmt.Sprintf("shadow checkpoint #%d left out nested git repositories: %s", cp.ID, strings.Join(cp.Skipped, ", ")))
+	}
+	s.ids = append(s.ids, cp.ID)
+}
+
+// errShadowOff explains how to enable the feature /diff and /undo rely on.
+var errShadowOff = fmt.Errorf("workspace checkpoints are off — set \"checkpoint_mode\": \"shadow\" in your config to track every change, including bash side effects")
+
+// WorkspaceChanges implements tui.WorkspaceCheckpointer: a per-file summary of
+// everything changed on disk since the first turn of this session.
+func (a *TUIClientAdapter) WorkspaceChanges() (string, error) {
+	s := a.shadow
+	if s == nil {
+		return "", errShadowOff
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ids) == 0 {
+		return "No turns checkpointed in this session yet.", nil
+	}
+
+	changes, err := s.repo.Changes(s.ids[0], 0)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) == 0 {
+		return "No workspace changes since this session started.", nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Workspace changes since this session started (%d files):\n", len(changes))
+	for _, c := range changes {
+		marker := "M"
+		if c.IsNew {
+			marker = "A"
+		} else if _, err := os.Stat(c.Path); os.IsNotExist(err) {
+			marker = "D"
+		}
+		fmt.Fprintf(&sb, "  %s %s (+%d -%d)\n", marker, relToWorkspace(s.repo.Workspace(), c.Path), c.Insertions, c.Deletions)
+	}
+	sb.WriteString("\nUse /undo to rewind the last turn, or `celeste revert --list` for every checkpoint.")
+	return sb.String(), nil
+}
+
+// UndoTurn implements tui.WorkspaceCheckpointer: it restores the workspace to
+// how it was before the most recent user turn and forgets that turn.
+func (a *TUIClientAdapter) UndoTurn() (string, error) {
+	s := a.shadow
+	if s == nil {
+		return "", errShadowOff
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ids) == 0 {
+		return "Nothing to undo — no turns checkpointed in this session yet.", nil
+	}
+
+	id := s.ids[len(s.ids)-1]
+	changes, err := s.repo.Changes(id, 0)
+	if err != nil {
+		return "", err
+	}
+	safety, err := s.repo.Restore(id)
+	if err != nil {
+		return "", err
+	}
+	s.ids = s.ids[:len(s.ids)-1]
+	return fmt.Sprintf("Restored workspace to checkpoint #%d (%d files reverted).\nThe pre-undo state is saved as checkpoint #%d: `celeste revert --to %d` brings it back.",
+		id, len(changes), safety.ID, safety.ID), nil
+}
+
+func relToWorkspace(workspace, path string) string {
+	if rel := strings.TrimPrefix(path, workspace+string(os.PathSeparator)); rel != path {
+		return rel
+	}
+	return path
+}
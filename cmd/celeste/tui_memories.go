@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/memories"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+// memoryContextTag wraps retrieved memories inside the user message they
+// were retrieved for. Backends drop mid-conversation system messages, so the
+// block rides along with the request it belongs to.
+const memoryContextTag = "project-memories"
+
+// maxRecallFiles caps how many recently touched paths join the query.
+const maxRecallFiles = 10
+
+// memoryRecall attaches the project memories relevant to each user turn to
+// the outgoing request, instead of loading the whole index into the system
+// prompt.
+type memoryRecall struct {
+	store    *memories.Store
+	opts     memories.RetrieveOptions
+	verifier *memories.Verifier // optional; checks selected memories against the code before use
+
+	mu       sync.Mutex
+	lastUser string              // user message the cached retrieval belongs to
+	last     *memories.Retrieval // most recent retrieval, for /memories why
+}
+
+// inject returns messages with the relevant memories prepended to the most
+// recent user message. Retrieval runs once per user message; tool-loop
+// follow-ups in the same turn reuse it so the request prefix stays stable.
+// The caller's slice is never modified.
+func (r *memoryRecall) inject(messages []tui.ChatMessage) []tui.ChatMessage {
+	if r == nil {
+		return messages
+	}
+	userIdx := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			userIdx = i
+			break
+		}
+	}
+	if userIdx < 0 {
+		return messages
+	}
+
+	r.mu.Lock()
+	if r.last == nil || r.lastUser != messages[userIdx].Content {
+		mems, err := r.store.List()
+		if err != nil {
+			tui.LogInfo(fmt.Sprintf("memory retrieval skipped: %v", err))
+		}
+		opts := r.opts
+		opts.Files = recentToolPaths(messages, maxRecallFiles)
+		r.last = memories.NewRetriever(mems).Retrieve(messages[userIdx].Content, opts)
+		r.verifySelected()
+		r.lastUser = messages[userIdx].Content
+		tui.LogInfo(fmt.Sprintf("memory retrieval: %d of %d memories injected (~%d tokens)",
+			len(r.last.Selected()), r.last.Total, r.last.Tokens))
+	}
+	block := r.last.Render()
+	r.mu.Unlock()
+
+	if block == "" {
+		return messages
+	}
+	out := make([]tui.ChatMessage, len(messages))
+	copy(out, messages)
+	out[userIdx].Content = fmt.Sprintf("<%s>\n%s</%s>\n\n%s", memoryContextTag, block, memoryContextTag, out[userIdx].Content)
+	return out
+}
+
+// verifySelected checks the memories picked for injection that have not
+// been verified recently, saving the outcome. A memory whose references all
+// fail to resolve is recorded as unverified rather than broken: the check
+// is a heuristic, so it stays injected, with a note, until the user runs
+// `celeste memories verify` or verifies from the memory manager.
+func (r *memoryRecall) verifySelected() {
+	if r.verifier == nil {
+		return
+	}
+	for _, m := range r.last.Selected() {
+		if m.VerifiedAt != "" && !memories.ShouldVerify(m) {
+			continue
+		}
+		res := r.verifier.Verify(m)
+		if res.Status == "" {
+			continue
+		}
+		res.Unconfirmed()
+		r.verifier.Apply(res)
+		if err := r.store.Save(m); err != nil {
+			tui.LogInfo(fmt.Sprintf("memory verification not saved for %s: %v", m.Name, err))
+		}
+		if res.Status == memories.StatusUnverified {
+			tui.LogInfo(fmt.Sprintf("memory %s unverified: none of its code references resolve", m.Name))
+		}
+	}
+}
+
+// ExplainMemories implements tui.MemoryExplainer for /memories why.
+func (a *TUIClientAdapter) ExplainMemories() string {
+	r := a.recall
+	if r == nil {
+		return "Memory retrieval is unavailable for this client."
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last == nil {
+		return "No request sent yet — memories are retrieved when you send a message."
+	}
+	return r.last.Explain()
+}
+
+// recentToolPaths collects file paths from the most recent tool calls, newest
+// first, so memories about the files being worked on rank higher.
+func recentToolPaths(messages []tui.ChatMessage, limit int) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for i := len(messages) - 1; i >= 0 && len(paths) < limit; i-- {
+		for _, tc := range messages[i].ToolCalls {
+			var args map[string]any
+			if json.Unmarshal([]byte(tc.Arguments), &args) != nil {
+				continue
+			}
+			for _, key := range []string{"path", "file_path", "file"} {
+				p, _ := args[key].(string)
+				p = strings.TrimSpace(p)
+				if p != "" && !seen[p] && len(paths) < limit {
+					seen[p] = true
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/memories"
+	"github.com/whykusanagi/celeste-cli/cmd/celeste/tui"
+)
+
+func TestMemoryRecallInjectsIntoLastUserMessage(t *testing.T) {
+	store := memories.NewStoreWithBase(t.TempDir())
+	require.NoError(t, store.Save(memories.NewMemory("goose-migrations", "Run goose migrations before tests", "project", "", "")))
+	require.NoError(t, store.Save(memories.NewMemory("release-notes", "Changelog lives in docs/CHANGELOG.md", "project", "", "")))
+
+	recall := &memoryRecall{store: store}
+	messages := []tui.ChatMessage{
+		{Role: "user", Content: "why do the migrations fail?"},
+		{Role: "assistant", ToolCalls: []tui.ToolCallInfo{{ID: "c1", Name: "read_file", Arguments: `{"path":"db/goose.go"}`}}},
+		{Role: "tool", ToolCallID: "c1", Content: "package db"},
+	}
+
+	out := recall.inject(messages)
+	assert.Equal(t, "why do the migrations fail?", messages[0].Content, "caller's slice must not change")
+	assert.True(t, strings.HasPrefix(out[0].Content, "<project-memories>"))
+	assert.Contains(t, out[0].Content, "goose-migrations")
+	assert.NotContains(t, out[0].Content, "release-notes")
+
+	explain := (&TUIClientAdapter{recall: recall}).ExplainMemories()
+	assert.Contains(t, explain, "Injected 1 of 2 memories")
+	assert.Contains(t, explain, "db/goose.go")
+}
+
+func TestMemoryRecallMarksUnresolvedMemoriesUnverified(t *testing.T) {
+	store := memories.NewStoreWithBase(t.TempDir())
+	require.NoError(t, store.Save(memories.NewMemory("deploy-script", "Deploys run through scripts/deploy.sh", "project", "", "")))
+	require.NoError(t, store.Save(memories.NewMemory("vendor-names", "Deploy notes for PayPal and GitHub",
+		"project", "", "The on_call rotation owns the deploy_window; ask the MacBook folks.")))
+
+	recall := &memoryRecall{store: store, verifier: memories.NewVerifier(t.TempDir(), nil)}
+	out := recall.inject([]tui.ChatMessage{{Role: "user", Content: "how do deploys run?"}})
+	assert.Contains(t, out[0].Content, "deploy-script", "an automatic check never drops a memory")
+	assert.Contains(t, out[0].Content, "possibly outdated, these were not found in the code: scripts/deploy.sh")
+
+	mem, err := store.Load("deploy-script")
+	require.NoError(t, err)
+	assert.Equal(t, memories.StatusUnverified, mem.Status)
+	assert.Equal(t, []string{"scripts/deploy.sh"}, mem.MissingRefs)
+
+	prose, err := store.Load("vendor-names")
+	require.NoError(t, err)
+	assert.Empty(t, prose.Status, "prose names no code references")
+	assert.Contains(t, out[0].Content, "vendor-names")
+}
@@ -5,8 +5,10 @@ package venice
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -39,8 +41,48 @@ type MediaResponse struct {
 	MediaType string `json:"media_type"`
 }
 
-// GenerateImage generates an image using Venice.ai.
+// APIError is a non-200 reply from the Venice API.
+type APIError struct {
+	Status int
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.Status, e.Body)
+}
+
+// GenerateImage generates an image using Venice.ai and saves the first
+// variant to the downloads directory.
 func GenerateImage(config Config, prompt string, params map[string]interface{}) (*MediaResponse, error) {
+	images, err := GenerateImageData(context.Background(), config, prompt, params)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return &MediaResponse{Success: false, Error: apiErr.Error(), MediaType: "image"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return &MediaResponse{
+			Success:   false,
+			Error:     "No image data in response",
+			MediaType: "image",
+		}, nil
+	}
+	path, err := saveImage(images[0], "image")
+	if err != nil {
+		return nil, fmt.Errorf("failed to save image: %w", err)
+	}
+	return &MediaResponse{
+		Success:   true,
+		Path:      path,
+		MediaType: "image",
+	}, nil
+}
+
+// GenerateImageData calls Venice's /image/generate and returns the decoded
+// images, one per variant.
+func GenerateImageData(ctx context.Context, config Config, prompt string, params map[string]interface{}) ([][]byte, error) {
 	// Use Venice's full-featured image generation endpoint
 	url := config.BaseURL + "/image/generate"
 
@@ -51,7 +93,7 @@ func GenerateImage(config Config, prompt string, params map[string]interface{})
 		// If no model specified or using chat model, default to image generation model
 		model = "lustify-sdxl"
 	}
-	if m, ok := params["model"].(string); ok {
+	if m, ok := params["model"].(string); ok && m != "" {
 		model = m
 	}
 
@@ -134,67 +176,86 @@ func GenerateImage(config Config, prompt string, params map[string]interface{})
 		payload["seed"] = seed
 	}
 
+	body, _, err := postJSON(ctx, config, url, payload, 120*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse response - Venice /image/generate returns {"id": "...", "images": ["base64..."]}
+	var result struct {
+		Images []string `json:"images"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	images := make([][]byte, 0, len(result.Images))
+	for _, b64 := range result.Images {
+		data, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64: %w", err)
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// EditImage calls Venice's /image/edit, which changes image as prompt
+// describes. Venice edits take no mask.
+func EditImage(ctx context.Context, config Config, image []byte, prompt string) ([]byte, error) {
+	payload := map[string]interface{}{
+		"prompt": prompt,
+		"image":  base64.StdEncoding.EncodeToString(image),
+	}
+	body, contentType, err := postJSON(ctx, config, config.BaseURL+"/image/edit", payload, 120*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		return body, nil
+	}
+	var result struct {
+		Images []string `json:"images"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Images) == 0 {
+		return nil, fmt.Errorf("no image data in response")
+	}
+	return base64.StdEncoding.DecodeString(result.Images[0])
+}
+
+// postJSON POSTs payload and returns the body and content type of a 200
+// reply, or an *APIError.
+func postJSON(ctx context.Context, config Config, url string, payload any, timeout time.Duration) ([]byte, string, error) {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode request: %w", err)
+		return nil, "", fmt.Errorf("failed to encode request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+config.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 120 * time.Second}
+	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
 	}
-
 	if resp.StatusCode != 200 {
-		return &MediaResponse{
-			Success:   false,
-			Error:     fmt.Sprintf("API error (status %d): %s", resp.StatusCode, string(body)),
-			MediaType: "image",
-		}, nil
-	}
-
-	// Parse response - Venice /image/generate returns {"id": "...", "images": ["base64..."]}
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Extract base64 images from response
-	if images, ok := result["images"].([]interface{}); ok && len(images) > 0 {
-		// Venice returns base64 strings directly in the images array
-		if b64, ok := images[0].(string); ok {
-			// Save to file
-			path, err := saveBase64Image(b64, "image")
-			if err != nil {
-				return nil, fmt.Errorf("failed to save image: %w", err)
-			}
-			return &MediaResponse{
-				Success:   true,
-				Path:      path,
-				MediaType: "image",
-			}, nil
-		}
+		return nil, "", &APIError{Status: resp.StatusCode, Body: string(body)}
 	}
-
-	return &MediaResponse{
-		Success:   false,
-		Error:     "No image data in response",
-		MediaType: "image",
-	}, nil
+	return body, resp.Header.Get("Content-Type"), nil
 }
 
 // UpscaleImage upscales an image using Venice.ai.
@@ -470,7 +531,11 @@ func saveBase64Image(b64 string, prefix string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to decode base64: %w", err)
 	}
+	return saveImage(imgData, prefix)
+}
 
+// saveImage writes image data to the downloads directory.
+func saveImage(imgData []byte, prefix string) (string, error) {
 	// Get output directory from config or use default
 	outputDir := getDownloadsDir()
 	if err := os.MkdirAll(outputDir, 0755); err != nil {